@@ -9,6 +9,7 @@ import (
 
 	cfg "hk4e/common/config"
 	"hk4e/gm/app"
+	"hk4e/pkg/metrics"
 	"hk4e/pkg/statsviz_serve"
 )
 
@@ -22,6 +23,9 @@ func main() {
 		_ = statsviz_serve.Serve("0.0.0.0:7890")
 	}()
 	cfg.InitConfig(*config)
+	if cfg.GetConfig().Hk4e.MetricsAddr != "" {
+		metrics.ServeAsync(cfg.GetConfig().Hk4e.MetricsAddr)
+	}
 	err := app.Run(context.TODO())
 	if err != nil {
 		fmt.Println(err)
@@ -9,6 +9,7 @@ import (
 
 	cfg "hk4e/common/config"
 	"hk4e/multi/app"
+	"hk4e/pkg/metrics"
 	"hk4e/pkg/statsviz_serve"
 )
 
@@ -25,6 +26,9 @@ func main() {
 	}()
 	app.APPVERSION = VERSION
 	cfg.InitConfig(*config)
+	if cfg.GetConfig().Hk4e.MetricsAddr != "" {
+		metrics.ServeAsync(cfg.GetConfig().Hk4e.MetricsAddr)
+	}
 	err := app.Run(context.TODO())
 	if err != nil {
 		fmt.Println(err)
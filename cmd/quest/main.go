@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	cfg "hk4e/common/config"
+	"hk4e/gdconf"
+	"hk4e/gs/game"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 任务引擎覆盖率报告
+// 加载配置表 统计全部任务的领取条件 完成条件 执行类型的实现情况 并在虚拟玩家身上模拟全部任务链
+// 输出内容稳定排序 不包含时间等信息 可以重定向到文件后在不同提交间diff
+
+var (
+	config   = flag.String("config", "application.toml", "config file")
+	out      = flag.String("out", "", "report output file, default stdout")
+	mainOnly = flag.Bool("main", false, "only output main quest chain")
+)
+
+func main() {
+	flag.Parse()
+	cfg.InitConfig(*config)
+	logger.InitLogger(&logger.Config{
+		AppName:      "quest",
+		Level:        logger.ParseLevel(cfg.GetConfig().Logger.Level),
+		TrackLine:    cfg.GetConfig().Logger.TrackLine,
+		TrackThread:  cfg.GetConfig().Logger.TrackThread,
+		EnableFile:   false,
+		DisableColor: cfg.GetConfig().Logger.DisableColor,
+	})
+	defer logger.CloseLogger()
+	gdconf.InitGameDataConfig()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		w = file
+	}
+	game.RegQuestHandler()
+	report := game.BuildQuestCoverageReport()
+	err := report.WriteText(w, *mainOnly)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
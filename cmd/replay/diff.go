@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"hk4e/protocol/cmd"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 服务器响应对比
+// 每个窗口内按协议号分组 按顺序一一配对 配对后按字段对比
+
+const diffFieldMaxLine = 20 // 单个协议最多输出的字段差异行数
+
+type DiffResult struct {
+	Match   int
+	Diff    int
+	Missing int
+	Extra   int
+}
+
+func (r *DiffResult) IsOk() bool {
+	return r.Diff == 0 && r.Missing == 0 && r.Extra == 0
+}
+
+func (r *DiffResult) String() string {
+	return fmt.Sprintf("match: %v, diff: %v, missing: %v, extra: %v", r.Match, r.Diff, r.Missing, r.Extra)
+}
+
+type Differ struct {
+	cmdProtoMap    *cmd.CmdProtoMap
+	ignoreCmdMap   map[uint16]bool
+	ignoreFieldMap map[string]bool
+}
+
+func NewDiffer(cmdProtoMap *cmd.CmdProtoMap, ignoreCmdList []string, ignoreFieldList []string) *Differ {
+	d := new(Differ)
+	d.cmdProtoMap = cmdProtoMap
+	d.ignoreCmdMap = make(map[uint16]bool)
+	for _, cmdName := range ignoreCmdList {
+		cmdId := cmdProtoMap.GetCmdIdByCmdName(cmdName)
+		if cmdId == 0 {
+			continue
+		}
+		d.ignoreCmdMap[cmdId] = true
+	}
+	d.ignoreFieldMap = make(map[string]bool)
+	for _, field := range ignoreFieldList {
+		d.ignoreFieldMap[field] = true
+	}
+	return d
+}
+
+// DiffWindow 对比一个窗口内抓包的响应和回放的响应 返回差异描述
+func (d *Differ) DiffWindow(captureList []*ReplayMsg, replayList []*ReplayMsg, result *DiffResult) []string {
+	captureMap := d.groupByCmdId(captureList)
+	replayMap := d.groupByCmdId(replayList)
+	cmdIdList := make([]uint16, 0)
+	for cmdId := range captureMap {
+		cmdIdList = append(cmdIdList, cmdId)
+	}
+	for cmdId := range replayMap {
+		if _, exist := captureMap[cmdId]; !exist {
+			cmdIdList = append(cmdIdList, cmdId)
+		}
+	}
+	sort.Slice(cmdIdList, func(i, j int) bool {
+		return cmdIdList[i] < cmdIdList[j]
+	})
+	lineList := make([]string, 0)
+	for _, cmdId := range cmdIdList {
+		cmdName := d.cmdProtoMap.GetCmdNameByCmdId(cmdId)
+		captureMsgList := captureMap[cmdId]
+		replayMsgList := replayMap[cmdId]
+		for i := 0; i < len(captureMsgList) || i < len(replayMsgList); i++ {
+			if i >= len(replayMsgList) {
+				result.Missing++
+				lineList = append(lineList, fmt.Sprintf("- %v #%v missing", cmdName, i))
+				continue
+			}
+			if i >= len(captureMsgList) {
+				result.Extra++
+				lineList = append(lineList, fmt.Sprintf("+ %v #%v extra", cmdName, i))
+				continue
+			}
+			fieldLineList := d.DiffMessage(captureMsgList[i].Message, replayMsgList[i].Message)
+			if len(fieldLineList) == 0 {
+				result.Match++
+				continue
+			}
+			result.Diff++
+			lineList = append(lineList, fmt.Sprintf("~ %v #%v", cmdName, i))
+			for _, fieldLine := range fieldLineList {
+				lineList = append(lineList, "    "+fieldLine)
+			}
+		}
+	}
+	return lineList
+}
+
+func (d *Differ) groupByCmdId(msgList []*ReplayMsg) map[uint16][]*ReplayMsg {
+	msgMap := make(map[uint16][]*ReplayMsg)
+	for _, msg := range msgList {
+		if d.ignoreCmdMap[msg.CmdId] {
+			continue
+		}
+		msgMap[msg.CmdId] = append(msgMap[msg.CmdId], msg)
+	}
+	return msgMap
+}
+
+// DiffMessage 按字段对比两个协议 忽略指定名字的字段
+func (d *Differ) DiffMessage(captureMsg pb.Message, replayMsg pb.Message) []string {
+	captureValue := messageToValue(captureMsg)
+	replayValue := messageToValue(replayMsg)
+	lineList := make([]string, 0)
+	d.diffValue("", captureValue, replayValue, &lineList)
+	if len(lineList) > diffFieldMaxLine {
+		more := len(lineList) - diffFieldMaxLine
+		lineList = append(lineList[:diffFieldMaxLine], fmt.Sprintf("... %v more", more))
+	}
+	return lineList
+}
+
+func messageToValue(msg pb.Message) any {
+	if msg == nil {
+		return nil
+	}
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	var value any = nil
+	_ = json.Unmarshal(data, &value)
+	return value
+}
+
+func (d *Differ) diffValue(path string, captureValue any, replayValue any, lineList *[]string) {
+	switch captureObj := captureValue.(type) {
+	case map[string]any:
+		replayObj, ok := replayValue.(map[string]any)
+		if !ok {
+			break
+		}
+		keyList := make([]string, 0, len(captureObj))
+		for key := range captureObj {
+			keyList = append(keyList, key)
+		}
+		for key := range replayObj {
+			if _, exist := captureObj[key]; !exist {
+				keyList = append(keyList, key)
+			}
+		}
+		sort.Strings(keyList)
+		for _, key := range keyList {
+			if d.ignoreFieldMap[key] {
+				continue
+			}
+			subPath := key
+			if path != "" {
+				subPath = path + "." + key
+			}
+			d.diffValue(subPath, captureObj[key], replayObj[key], lineList)
+		}
+		return
+	case []any:
+		replayArr, ok := replayValue.([]any)
+		if !ok {
+			break
+		}
+		if len(captureObj) != len(replayArr) {
+			*lineList = append(*lineList, fmt.Sprintf("%v: len %v -> %v", path, len(captureObj), len(replayArr)))
+		}
+		for i := 0; i < len(captureObj) && i < len(replayArr); i++ {
+			d.diffValue(path+"["+strconv.Itoa(i)+"]", captureObj[i], replayArr[i], lineList)
+		}
+		return
+	}
+	captureStr := valueToString(captureValue)
+	replayStr := valueToString(replayValue)
+	if captureStr != replayStr {
+		*lineList = append(*lineList, fmt.Sprintf("%v: %v -> %v", path, shortString(captureStr), shortString(replayStr)))
+	}
+}
+
+func valueToString(value any) string {
+	if value == nil {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func shortString(str string) string {
+	if len(str) > 64 {
+		return str[:64] + "..."
+	}
+	return str
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	cfg "hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/common/rpc"
+	"hk4e/gate/client_proto"
+	"hk4e/node/api"
+	"hk4e/pkg/capture"
+	"hk4e/protocol/cmd"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 抓包回放工具
+// 读取网关抓包文件 作为虚拟网关通过消息队列将客户端请求回放到GS 并对比GS的响应与抓包中的响应
+// GS开启回放模式(replay_mode_enable)时 时间按抓包时间线推进并使用固定的随机种子 多次回放结果一致
+// 用法: replay -config application.toml -file 10001_1_20240101000000.cap [-uid 10001] [-gs appid] [-seed 1]
+
+var (
+	config      = flag.String("config", "application.toml", "config file")
+	file        = flag.String("file", "", "capture file")
+	uid         = flag.Uint("uid", 0, "replay uid, use uid in capture file if 0")
+	gsAppId     = flag.String("gs", "", "gs appid, use min load gs if empty")
+	seed        = flag.Int64("seed", 1, "gs random seed in replay mode")
+	speed       = flag.Float64("speed", 1.0, "replay speed, no wait if 0")
+	maxIdle     = flag.Duration("max_idle", time.Second*5, "max wait time between two request")
+	settle      = flag.Duration("settle", time.Millisecond*300, "window end after no rsp for settle time")
+	settleLimit = flag.Duration("settle_limit", time.Second*5, "max wait time of one window")
+	ignoreCmd   = flag.String("ignore_cmd", "PlayerTimeNotify,PlayerGameTimeNotify,SceneTimeNotify,ServerTimeNotify,WorldPlayerRTTNotify", "ignore cmd name list")
+	ignoreField = flag.String("ignore_field", "client_time,server_time,scene_time,timestamp,server_game_time", "ignore field name list")
+)
+
+func main() {
+	flag.Parse()
+	if *file == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.InitConfig(*config)
+	logger.InitLogger(&logger.Config{
+		AppName:      "replay",
+		Level:        logger.ParseLevel(cfg.GetConfig().Logger.Level),
+		TrackLine:    cfg.GetConfig().Logger.TrackLine,
+		TrackThread:  cfg.GetConfig().Logger.TrackThread,
+		EnableFile:   false,
+		DisableColor: cfg.GetConfig().Logger.DisableColor,
+		EnableJson:   false,
+	})
+	defer logger.CloseLogger()
+	ok, err := run()
+	if err != nil {
+		logger.Error("replay error: %v", err)
+		logger.CloseLogger()
+		os.Exit(1)
+	}
+	if !ok {
+		logger.CloseLogger()
+		os.Exit(2)
+	}
+}
+
+func run() (bool, error) {
+	head, frameList, err := capture.ReadAll(*file)
+	if err != nil {
+		return false, err
+	}
+	// 抓包帧的协议格式与抓包时网关的配置保持一致
+	cfg.GetConfig().Hk4e.ClientProtoProxyEnable = head.Flag&capture.FlagClientProto != 0
+	cfg.GetConfig().Hk4e.TrackPacket = false
+	serverCmdProtoMap := cmd.NewCmdProtoMap()
+	var clientCmdProtoMap *client_proto.ClientCmdProtoMap = nil
+	if cfg.GetConfig().Hk4e.ClientProtoProxyEnable {
+		clientCmdProtoMap = client_proto.NewClientCmdProtoMap()
+	}
+	frameMsgList := DecodeCapture(head, frameList, serverCmdProtoMap, clientCmdProtoMap)
+	replayUid := uint32(*uid)
+	if replayUid == 0 {
+		replayUid = GetCaptureUid(head, frameMsgList)
+	}
+	if replayUid == 0 {
+		return false, ErrReplayNoUid
+	}
+	windowList := BuildWindowList(frameMsgList)
+	logger.Warn("load capture file, uid: %v, sessionId: %v, start time: %v, frame: %v, window: %v",
+		head.Uid, head.SessionId, time.UnixMilli(head.StartTime).Format(time.DateTime), len(frameList), len(windowList))
+
+	discoveryClient, err := rpc.NewDiscoveryClient()
+	if err != nil {
+		return false, err
+	}
+	if *gsAppId == "" {
+		rsp, err := discoveryClient.GetServerAppId(context.TODO(), &api.GetServerAppIdReq{ServerType: api.GS})
+		if err != nil {
+			return false, err
+		}
+		*gsAppId = rsp.AppId
+	}
+	// 以网关身份接收GS的响应 不注册到节点服务器 不会被分配客户端连接
+	appId := fmt.Sprintf("%08x", rand.Uint32())
+	messageQueue := mq.NewMessageQueue(api.GATE, appId, discoveryClient)
+	if messageQueue == nil {
+		return false, fmt.Errorf("create message queue fail")
+	}
+	defer messageQueue.Close()
+	logger.Warn("replay start, uid: %v, gs appid: %v, replay appid: %v", replayUid, *gsAppId, appId)
+
+	replayer := NewReplayer(&ReplayConfig{
+		Uid:         replayUid,
+		GsAppId:     *gsAppId,
+		StartTime:   head.StartTime,
+		Seed:        *seed,
+		Speed:       *speed,
+		MaxIdle:     *maxIdle,
+		SettleTime:  *settle,
+		SettleLimit: *settleLimit,
+	}, messageQueue, windowList)
+	replayNum := replayer.Run()
+
+	differ := NewDiffer(serverCmdProtoMap, splitList(*ignoreCmd), splitList(*ignoreField))
+	result := new(DiffResult)
+	for i, window := range windowList[:replayNum] {
+		lineList := differ.DiffWindow(window.CaptureList, window.ReplayList, result)
+		if len(lineList) == 0 {
+			continue
+		}
+		reqNameList := make([]string, 0, len(window.ReqList))
+		for _, req := range window.ReqList {
+			reqNameList = append(reqNameList, serverCmdProtoMap.GetCmdNameByCmdId(req.CmdId))
+		}
+		fmt.Printf("[window %v] %v %v\n", i, window.Time, strings.Join(reqNameList, ","))
+		for _, line := range lineList {
+			fmt.Println("  " + line)
+		}
+	}
+	fmt.Printf("replay window: %v/%v, %v\n", replayNum, len(windowList), result)
+	return result.IsOk() && replayNum == len(windowList), nil
+}
+
+func splitList(str string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		list = append(list, item)
+	}
+	return list
+}
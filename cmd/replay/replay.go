@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"hk4e/common/mq"
+	"hk4e/gate/client_proto"
+	gatenet "hk4e/gate/net"
+	"hk4e/pkg/capture"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 抓包回放
+// 作为一个虚拟的网关 将抓包中客户端发出的协议通过消息队列按抓包时间线依次发给GS
+// 以客户端发包划分窗口 窗口内收到的GS响应与抓包中同一窗口的响应进行对比
+// GS开启回放模式时 回放开始会重置GS的时间与随机种子 每个窗口发包前将GS的时间推进到抓包时间线上的对应时间
+// 同一份抓包以相同的种子多次回放结果一致 GS未开启回放模式时仍然使用自身的真实时间和随机源
+
+var ErrReplayNoUid = errors.New("can not find uid in capture file")
+
+type ReplayMsg struct {
+	Dir       uint8
+	Time      time.Duration // 虚拟时间 相对抓包开始时间
+	CmdId     uint16
+	ClientSeq uint32
+	Message   pb.Message
+}
+
+// ReplayWindow 一次客户端发包以及到下一次客户端发包之前服务器的全部响应
+type ReplayWindow struct {
+	Time        time.Duration
+	ReqList     []*ReplayMsg // 同一个抓包帧内的请求 聚合消息会拆分成多个
+	CaptureList []*ReplayMsg // 抓包中的服务器响应
+	ReplayList  []*ReplayMsg // 回放时的服务器响应
+}
+
+// DecodeCapture 解码抓包帧 抓包帧为协议解码前的数据 与网关使用同样的解码流程
+func DecodeCapture(head *capture.Head, frameList []*capture.Frame,
+	serverCmdProtoMap *cmd.CmdProtoMap, clientCmdProtoMap *client_proto.ClientCmdProtoMap) [][]*ReplayMsg {
+	frameMsgList := make([][]*ReplayMsg, 0, len(frameList))
+	for _, frame := range frameList {
+		kcpMsg := &gatenet.KcpMsg{
+			SessionId: head.SessionId,
+			CmdId:     frame.CmdId,
+			HeadData:  frame.HeadData,
+			ProtoData: frame.ProtoData,
+		}
+		protoMsgList := gatenet.ProtoDecode(kcpMsg, serverCmdProtoMap, clientCmdProtoMap)
+		msgList := make([]*ReplayMsg, 0, len(protoMsgList))
+		for _, protoMsg := range protoMsgList {
+			msg := &ReplayMsg{
+				Dir:     frame.Dir,
+				Time:    frame.Time,
+				CmdId:   protoMsg.CmdId,
+				Message: protoMsg.PayloadMessage,
+			}
+			if protoMsg.HeadMessage != nil {
+				msg.ClientSeq = protoMsg.HeadMessage.ClientSequenceId
+			}
+			msgList = append(msgList, msg)
+		}
+		frameMsgList = append(frameMsgList, msgList)
+	}
+	return frameMsgList
+}
+
+// GetCaptureUid 抓包开始时会话未登录则从网关登录响应中获取uid
+func GetCaptureUid(head *capture.Head, frameMsgList [][]*ReplayMsg) uint32 {
+	if head.Uid != 0 {
+		return head.Uid
+	}
+	for _, msgList := range frameMsgList {
+		for _, msg := range msgList {
+			if msg.CmdId != cmd.GetPlayerTokenRsp {
+				continue
+			}
+			rsp, ok := msg.Message.(*proto.GetPlayerTokenRsp)
+			if ok && rsp.Uid != 0 {
+				return rsp.Uid
+			}
+		}
+	}
+	return 0
+}
+
+// BuildWindowList 按客户端发包划分窗口 网关本地处理的协议不参与回放
+func BuildWindowList(frameMsgList [][]*ReplayMsg) []*ReplayWindow {
+	windowList := make([]*ReplayWindow, 0)
+	for _, msgList := range frameMsgList {
+		var window *ReplayWindow = nil
+		for _, msg := range msgList {
+			if msg.CmdId == cmd.GetPlayerTokenReq || msg.CmdId == cmd.GetPlayerTokenRsp {
+				continue
+			}
+			switch msg.Dir {
+			case capture.DirRecv:
+				if window == nil {
+					window = &ReplayWindow{Time: msg.Time}
+					windowList = append(windowList, window)
+				}
+				window.ReqList = append(window.ReqList, msg)
+			case capture.DirSend:
+				if len(windowList) == 0 {
+					continue
+				}
+				last := windowList[len(windowList)-1]
+				last.CaptureList = append(last.CaptureList, msg)
+			}
+		}
+	}
+	return windowList
+}
+
+// VirtualClock 回放虚拟时钟
+// 虚拟时间按抓包时间线推进 实际等待时间按倍速缩放 并压缩过长的空闲时间
+// 只控制回放工具自身的发包节奏 GS的时间通过时间同步通知按抓包时间线推进 不受倍速和压缩空闲时间的影响
+type VirtualClock struct {
+	speed       float64       // 回放倍速 为0则不等待
+	maxIdle     time.Duration // 两次发包之间的最大实际等待时间
+	virtualTime time.Duration
+	realTime    time.Time
+}
+
+func NewVirtualClock(speed float64, maxIdle time.Duration, now time.Time) *VirtualClock {
+	return &VirtualClock{
+		speed:       speed,
+		maxIdle:     maxIdle,
+		virtualTime: 0,
+		realTime:    now,
+	}
+}
+
+// Advance 推进虚拟时间 返回需要实际等待的时间
+func (v *VirtualClock) Advance(to time.Duration, now time.Time) time.Duration {
+	if to <= v.virtualTime {
+		return 0
+	}
+	gap := to - v.virtualTime
+	v.virtualTime = to
+	if v.speed <= 0 {
+		v.realTime = now
+		return 0
+	}
+	wait := time.Duration(float64(gap) / v.speed)
+	if v.maxIdle > 0 && wait > v.maxIdle {
+		wait = v.maxIdle
+	}
+	// 扣除上一次推进以来已经消耗的实际时间
+	wait -= now.Sub(v.realTime)
+	if wait < 0 {
+		wait = 0
+	}
+	v.realTime = now.Add(wait)
+	return wait
+}
+
+type ReplayConfig struct {
+	Uid         uint32
+	GsAppId     string
+	StartTime   int64 // 抓包开始时间 毫秒时间戳
+	Seed        int64 // GS回放模式使用的随机种子
+	Speed       float64
+	MaxIdle     time.Duration
+	SettleTime  time.Duration // 窗口内无新响应多久后认为响应结束
+	SettleLimit time.Duration // 窗口最长等待时间
+}
+
+type Replayer struct {
+	cfg          *ReplayConfig
+	messageQueue *mq.MessageQueue
+	clock        *VirtualClock
+	windowList   []*ReplayWindow
+	lock         sync.Mutex
+	windowIndex  int
+	lastRecvTime time.Time
+	isKick       bool
+	closeChan    chan struct{}
+}
+
+func NewReplayer(cfg *ReplayConfig, messageQueue *mq.MessageQueue, windowList []*ReplayWindow) *Replayer {
+	r := new(Replayer)
+	r.cfg = cfg
+	r.messageQueue = messageQueue
+	r.clock = NewVirtualClock(cfg.Speed, cfg.MaxIdle, time.Now())
+	r.windowList = windowList
+	r.windowIndex = -1
+	r.closeChan = make(chan struct{})
+	return r
+}
+
+// Run 回放全部窗口 返回实际回放的窗口数
+func (r *Replayer) Run() int {
+	go r.recvHandle()
+	defer close(r.closeChan)
+	r.sendClock(0, true)
+	replayNum := 0
+	for i, window := range r.windowList {
+		wait := r.clock.Advance(window.Time, time.Now())
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		r.lock.Lock()
+		r.windowIndex = i
+		r.lastRecvTime = time.Now()
+		isKick := r.isKick
+		r.lock.Unlock()
+		if isKick {
+			logger.Warn("player has been kick, stop replay, window: %v", i)
+			break
+		}
+		r.sendClock(window.Time, false)
+		stop := false
+		for _, req := range window.ReqList {
+			if req.CmdId == cmd.PlayerForceExitReq {
+				stop = true
+				break
+			}
+			r.sendReq(req)
+		}
+		replayNum++
+		if stop {
+			break
+		}
+		r.waitSettle(i)
+	}
+	r.sendOffline()
+	return replayNum
+}
+
+func (r *Replayer) sendReq(req *ReplayMsg) {
+	gameMsg := &mq.GameMsg{
+		UserId:    r.cfg.Uid,
+		CmdId:     req.CmdId,
+		ClientSeq: req.ClientSeq,
+	}
+	switch req.CmdId {
+	case cmd.PlayerLoginReq:
+		// 与网关一致 不允许客户端指定登录目标
+		loginReq := pb.Clone(req.Message).(*proto.PlayerLoginReq)
+		loginReq.TargetUid = 0
+		loginReq.TargetHomeOwnerUid = 0
+		gameMsg.PayloadMessage = loginReq
+	default:
+		payloadMessageData, err := pb.Marshal(req.Message)
+		if err != nil {
+			logger.Error("marshal replay msg error: %v, cmdId: %v", err, req.CmdId)
+			return
+		}
+		gameMsg.PayloadMessageData = payloadMessageData
+	}
+	r.messageQueue.SendToGs(r.cfg.GsAppId, &mq.NetMsg{
+		MsgType: mq.MsgTypeGame,
+		EventId: mq.NormalMsg,
+		GameMsg: gameMsg,
+	})
+}
+
+// sendClock 将GS的时间同步到抓包时间线上的时间 GS未开启回放模式时忽略
+func (r *Replayer) sendClock(virtualTime time.Duration, isStart bool) {
+	r.messageQueue.SendToGs(r.cfg.GsAppId, &mq.NetMsg{
+		MsgType:   mq.MsgTypeServer,
+		EventId:   mq.ServerReplayClockNotify,
+		ServerMsg: &mq.ServerMsg{ReplayClock: NewReplayClockInfo(r.cfg, virtualTime, isStart)},
+	})
+}
+
+// NewReplayClockInfo 抓包时间线上的时间同步信息 回放开始时携带随机种子
+func NewReplayClockInfo(cfg *ReplayConfig, virtualTime time.Duration, isStart bool) *mq.ReplayClockInfo {
+	info := &mq.ReplayClockInfo{
+		Time:    cfg.StartTime + virtualTime.Milliseconds(),
+		IsStart: isStart,
+	}
+	if isStart {
+		info.Seed = cfg.Seed
+	}
+	return info
+}
+
+func (r *Replayer) sendOffline() {
+	r.messageQueue.SendToGs(r.cfg.GsAppId, &mq.NetMsg{
+		MsgType:     mq.MsgTypeConnCtrl,
+		EventId:     mq.UserOfflineNotify,
+		ConnCtrlMsg: &mq.ConnCtrlMsg{UserId: r.cfg.Uid},
+	})
+}
+
+// waitSettle 等待当前窗口的响应结束 保证响应归属到正确的窗口
+func (r *Replayer) waitSettle(windowIndex int) {
+	startTime := time.Now()
+	for {
+		time.Sleep(time.Millisecond * 10)
+		now := time.Now()
+		r.lock.Lock()
+		lastRecvTime := r.lastRecvTime
+		isKick := r.isKick
+		r.lock.Unlock()
+		if isKick || now.Sub(lastRecvTime) >= r.cfg.SettleTime {
+			return
+		}
+		if r.cfg.SettleLimit > 0 && now.Sub(startTime) >= r.cfg.SettleLimit {
+			logger.Warn("wait window settle timeout, window: %v", windowIndex)
+			return
+		}
+	}
+}
+
+func (r *Replayer) recvHandle() {
+	for {
+		select {
+		case <-r.closeChan:
+			return
+		case netMsg := <-r.messageQueue.GetNetMsg():
+			switch netMsg.MsgType {
+			case mq.MsgTypeGame:
+				if netMsg.EventId != mq.NormalMsg || netMsg.GameMsg.UserId != r.cfg.Uid {
+					continue
+				}
+				r.onRsp(netMsg.GameMsg)
+			case mq.MsgTypeConnCtrl:
+				if netMsg.EventId != mq.KickPlayerNotify || netMsg.ConnCtrlMsg.KickUserId != r.cfg.Uid {
+					continue
+				}
+				logger.Warn("player kick by server, reason: %v", netMsg.ConnCtrlMsg.KickReason)
+				r.lock.Lock()
+				r.isKick = true
+				r.lock.Unlock()
+			}
+		}
+	}
+}
+
+func (r *Replayer) onRsp(gameMsg *mq.GameMsg) {
+	if gameMsg.CmdId == cmd.PlayerLoginRsp {
+		rsp, ok := gameMsg.PayloadMessage.(*proto.PlayerLoginRsp)
+		if ok && rsp.Retcode == 0 {
+			// 与网关一致 登录成功后通知GS玩家各个服务器的appid
+			r.messageQueue.SendToGs(r.cfg.GsAppId, &mq.NetMsg{
+				MsgType:   mq.MsgTypeServer,
+				EventId:   mq.ServerAppidBindNotify,
+				ServerMsg: &mq.ServerMsg{UserId: r.cfg.Uid},
+			})
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lastRecvTime = time.Now()
+	if r.windowIndex < 0 {
+		return
+	}
+	window := r.windowList[r.windowIndex]
+	window.ReplayList = append(window.ReplayList, &ReplayMsg{
+		Dir:       capture.DirSend,
+		Time:      window.Time,
+		CmdId:     gameMsg.CmdId,
+		ClientSeq: gameMsg.ClientSeq,
+		Message:   gameMsg.PayloadMessage,
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	cfg "hk4e/common/config"
+	"hk4e/pkg/capture"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger(nil)
+	cfg.CONF = &cfg.Config{}
+	m.Run()
+}
+
+func newTestFrame(t *testing.T, dir uint8, ms int64, cmdId uint16, clientSeq uint32, msg pb.Message) *capture.Frame {
+	headData, err := pb.Marshal(&proto.PacketHead{ClientSequenceId: clientSeq})
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoData, err := pb.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &capture.Frame{Dir: dir, Time: time.Duration(ms) * time.Millisecond, CmdId: cmdId, HeadData: headData, ProtoData: protoData}
+}
+
+func TestBuildWindowList(t *testing.T) {
+	frameList := []*capture.Frame{
+		newTestFrame(t, capture.DirRecv, 0, cmd.GetPlayerTokenReq, 1, &proto.GetPlayerTokenReq{}),
+		newTestFrame(t, capture.DirSend, 10, cmd.GetPlayerTokenRsp, 1, &proto.GetPlayerTokenRsp{Uid: 10001}),
+		newTestFrame(t, capture.DirRecv, 20, cmd.PlayerLoginReq, 2, &proto.PlayerLoginReq{TargetUid: 10002}),
+		newTestFrame(t, capture.DirSend, 30, cmd.PlayerDataNotify, 0, &proto.PlayerDataNotify{NickName: "a"}),
+		newTestFrame(t, capture.DirSend, 40, cmd.PlayerLoginRsp, 2, &proto.PlayerLoginRsp{}),
+		newTestFrame(t, capture.DirRecv, 1000, cmd.PingReq, 3, &proto.PingReq{ClientTime: 100}),
+		newTestFrame(t, capture.DirSend, 1010, cmd.PingRsp, 3, &proto.PingRsp{ClientTime: 100}),
+	}
+	serverCmdProtoMap := cmd.NewCmdProtoMap()
+	frameMsgList := DecodeCapture(&capture.Head{}, frameList, serverCmdProtoMap, nil)
+	if GetCaptureUid(&capture.Head{}, frameMsgList) != 10001 {
+		t.Fatal("uid should get from gate login rsp")
+	}
+	// 网关本地处理的登录协议不参与回放
+	windowList := BuildWindowList(frameMsgList)
+	if len(windowList) != 2 {
+		t.Fatalf("window num error: %v", len(windowList))
+	}
+	window := windowList[0]
+	if window.Time != time.Millisecond*20 || len(window.ReqList) != 1 || window.ReqList[0].CmdId != cmd.PlayerLoginReq ||
+		window.ReqList[0].ClientSeq != 2 || len(window.CaptureList) != 2 {
+		t.Fatalf("window error: %+v", window)
+	}
+	if windowList[1].ReqList[0].CmdId != cmd.PingReq || len(windowList[1].CaptureList) != 1 {
+		t.Fatalf("window error: %+v", windowList[1])
+	}
+}
+
+func TestVirtualClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := NewVirtualClock(2, time.Second*5, now)
+	if clock.Advance(time.Second*2, now) != time.Second {
+		t.Fatal("wait time should scale by speed")
+	}
+	now = now.Add(time.Second)
+	// 等待响应消耗的时间从下一次等待中扣除
+	now = now.Add(time.Millisecond * 300)
+	if clock.Advance(time.Second*3, now) != time.Millisecond*200 {
+		t.Fatal("wait time should exclude elapsed time")
+	}
+	now = now.Add(time.Millisecond * 200)
+	// 空闲时间压缩
+	if clock.Advance(time.Hour, now) != time.Second*5 {
+		t.Fatal("idle time should compress")
+	}
+	if clock.Advance(time.Minute, now) != 0 {
+		t.Fatal("clock should not go back")
+	}
+	clock = NewVirtualClock(0, 0, now)
+	if clock.Advance(time.Hour, now) != 0 {
+		t.Fatal("speed 0 should not wait")
+	}
+}
+
+func TestNewReplayClockInfo(t *testing.T) {
+	cfg := &ReplayConfig{StartTime: 1700000000000, Seed: 7}
+	info := NewReplayClockInfo(cfg, 0, true)
+	if info.Time != cfg.StartTime || !info.IsStart || info.Seed != cfg.Seed {
+		t.Fatalf("start clock error: %+v", info)
+	}
+	// 倍速与空闲压缩不影响同步给GS的时间
+	info = NewReplayClockInfo(cfg, time.Hour, false)
+	if info.Time != cfg.StartTime+time.Hour.Milliseconds() || info.IsStart || info.Seed != 0 {
+		t.Fatalf("window clock error: %+v", info)
+	}
+}
+
+func TestDiffWindow(t *testing.T) {
+	differ := NewDiffer(cmd.NewCmdProtoMap(), []string{"PlayerTimeNotify"}, []string{"client_time"})
+	captureList := []*ReplayMsg{
+		{CmdId: cmd.PingRsp, Message: &proto.PingRsp{ClientTime: 1, Seq: 1}},
+		{CmdId: cmd.PlayerDataNotify, Message: &proto.PlayerDataNotify{NickName: "a", PropMap: map[uint32]*proto.PropValue{1: {Type: 1, Val: 1}}}},
+		{CmdId: cmd.PlayerTimeNotify, Message: &proto.PlayerTimeNotify{}},
+		{CmdId: cmd.PlayerLoginRsp, Message: &proto.PlayerLoginRsp{}},
+	}
+	replayList := []*ReplayMsg{
+		{CmdId: cmd.PlayerDataNotify, Message: &proto.PlayerDataNotify{NickName: "b", PropMap: map[uint32]*proto.PropValue{1: {Type: 1, Val: 2}}}},
+		{CmdId: cmd.PingRsp, Message: &proto.PingRsp{ClientTime: 2, Seq: 1}},
+		{CmdId: cmd.PingRsp, Message: &proto.PingRsp{ClientTime: 3, Seq: 2}},
+	}
+	result := new(DiffResult)
+	lineList := differ.DiffWindow(captureList, replayList, result)
+	// 忽略的字段和协议不参与对比 同协议按顺序配对
+	if result.Match != 1 || result.Diff != 1 || result.Missing != 1 || result.Extra != 1 || result.IsOk() {
+		t.Fatalf("diff result error: %v, %v", result, lineList)
+	}
+	// 按协议号顺序输出
+	expectList := []string{
+		"+ PingRsp #1 extra",
+		"- PlayerLoginRsp #0 missing",
+		"~ PlayerDataNotify #0",
+		"    nick_name: \"a\" -> \"b\"",
+		"    prop_map.1.val: \"1\" -> \"2\"",
+	}
+	if len(lineList) != len(expectList) {
+		t.Fatalf("diff line error: %q", lineList)
+	}
+	for i := range lineList {
+		if lineList[i] != expectList[i] {
+			t.Fatalf("diff line error: %q", lineList)
+		}
+	}
+}
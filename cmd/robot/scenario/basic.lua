@@ -0,0 +1,43 @@
+-- 基础功能回归场景
+-- 在robot配置中设置scenario_file = "./scenario/basic.lua"后启动robot执行
+
+return {
+    name = "basic",
+    robot_num = 10,
+    batch_num = 5,
+    loop = 1,
+    step_list = {
+        { action = "login", timeout = 30000 },
+        { name = "level", action = "gm", msg = "player level 60" },
+        { name = "add_item", action = "gm", msg = "item add 224 10" },
+        {
+            action = "wait",
+            cmd = "StoreItemChangeNotify",
+            match = function(msg, robot)
+                for _, item in ipairs(msg.item_list) do
+                    if item.item_id == 224 then
+                        return true
+                    end
+                end
+                return false
+            end,
+        },
+        { name = "gacha", action = "gacha", schedule_id = 813, times = 10, assert = { gacha_times = 10 } },
+        { name = "teleport", action = "teleport", scene_id = 3, pos = { x = 2747, y = 194, z = -1719 } },
+        { name = "chat", action = "chat", text = "hello from {account}" },
+        {
+            name = "chat_notify",
+            action = "wait",
+            cmd = "PlayerChatNotify",
+            match = function(msg, robot)
+                return msg.chat_info.uid == robot.uid
+            end,
+            assert = function(msg, robot)
+                return msg.chat_info.text == "hello from " .. robot.account, "chat text error"
+            end,
+        },
+        { action = "send", cmd = "GetPlayerFriendListReq", req = {}, rsp = "GetPlayerFriendListRsp" },
+        { action = "sleep", time = 1000 },
+        { action = "logout" },
+    },
+}
@@ -16,7 +16,9 @@ import (
 	gsapp "hk4e/gs/app"
 	multiapp "hk4e/multi/app"
 	nodeapp "hk4e/node/app"
+	"hk4e/pkg/metrics"
 	"hk4e/pkg/statsviz_serve"
+	"hk4e/pkg/trace"
 
 	"github.com/flswld/halo/logger"
 )
@@ -31,6 +33,9 @@ func main() {
 		_ = statsviz_serve.Serve("0.0.0.0:4567")
 	}()
 	cfg.InitConfig(*config)
+	if cfg.GetConfig().Hk4e.MetricsAddr != "" {
+		metrics.ServeAsync(cfg.GetConfig().Hk4e.MetricsAddr)
+	}
 
 	logger.InitLogger(&logger.Config{
 		AppName:      "standalone",
@@ -41,6 +46,17 @@ func main() {
 		DisableColor: cfg.GetConfig().Logger.DisableColor,
 		EnableJson:   cfg.GetConfig().Logger.EnableJson,
 	})
+	err := trace.InitTracer(&trace.Config{
+		ServiceName: "standalone",
+		Exporter:    cfg.GetConfig().Trace.Exporter,
+		FilePath:    cfg.GetConfig().Trace.FilePath,
+		SampleUid:   cfg.GetConfig().Trace.SampleUid,
+		SampleRate:  cfg.GetConfig().Trace.SampleRate,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer trace.CloseTracer()
 	logger.Warn("standalone start")
 	defer func() {
 		logger.Warn("standalone exit")
@@ -16,29 +16,59 @@ type Config struct {
 	Database  Database  `toml:"database"`
 	Redis     Redis     `toml:"redis"`
 	MQ        MQ        `toml:"mq"`
+	Trace     Trace     `toml:"trace"`
 }
 
 // Hk4e 原神服务器
 type Hk4e struct {
-	DispatchHttpPort        int32  `toml:"dispatch_http_port"`         // dispatch的http端口
-	GmHttpPort              int32  `toml:"gm_http_port"`               // gm的http端口
-	KcpAddr                 string `toml:"kcp_addr"`                   // kcp地址 该地址只用来注册到节点服务器 填网关的外网地址 网关本地监听为0.0.0.0
-	KcpPort                 int32  `toml:"kcp_port"`                   // kcp端口号
-	TcpModeEnable           bool   `toml:"tcp_mode_enable"`            // 是否开启tcp模式 需要hook客户端网络库才能支持 共用kcp端口号
-	GameDataConfigPath      string `toml:"game_data_config_path"`      // 配置表路径
-	ClientProtoProxyEnable  bool   `toml:"client_proto_proxy_enable"`  // 是否开启客户端协议代理功能
-	Version                 string `toml:"version"`                    // 支持的客户端协议版本号 三位数字 多个以逗号分隔 如300,310,315,320
-	GateTcpMqAddr           string `toml:"gate_tcp_mq_addr"`           // 访问网关tcp直连消息队列的地址 填网关的内网地址
-	GateTcpMqPort           int32  `toml:"gate_tcp_mq_port"`           // tcp消息队列端口号
-	LoginSdkUrl             string `toml:"login_sdk_url"`              // 网关登录验证token的sdk服务器地址 目前填dispatch的内网地址
-	LoginSdkAccountKey      string `toml:"login_sdk_account_key"`      // sdk服务器账号验证的签名密钥
-	LoadSceneLuaConfig      bool   `toml:"load_scene_lua_config"`      // 是否加载场景详情LUA配置数据
-	DispatchUrl             string `toml:"dispatch_url"`               // 二级dispatch地址 将域名改为dispatch的外网地址
-	GmAuthKey               string `toml:"gm_auth_key"`                // gm认证密钥
-	RegisterAllProtoMessage bool   `toml:"register_all_proto_message"` // 注册全部pb消息
-	ByteCheckMode           int32  `toml:"byte_check_mode"`            // 网络包数据校验模式
-	StandaloneModeEnable    bool   `toml:"standalone_mode_enable"`     // 是否开启单进程模式
-	TrackPacket             bool   `toml:"track_packet"`               // 追踪收发包
+	DispatchHttpPort         int32  `toml:"dispatch_http_port"`          // dispatch的http端口
+	GmHttpPort               int32  `toml:"gm_http_port"`                // gm的http端口
+	KcpAddr                  string `toml:"kcp_addr"`                    // kcp地址 该地址只用来注册到节点服务器 填网关的外网地址 网关本地监听为0.0.0.0
+	KcpPort                  int32  `toml:"kcp_port"`                    // kcp端口号
+	TcpModeEnable            bool   `toml:"tcp_mode_enable"`             // 是否开启tcp模式 需要hook客户端网络库才能支持 共用kcp端口号
+	GameDataConfigPath       string `toml:"game_data_config_path"`       // 配置表路径
+	ClientProtoProxyEnable   bool   `toml:"client_proto_proxy_enable"`   // 是否开启客户端协议代理功能
+	Version                  string `toml:"version"`                     // 支持的客户端协议版本号 三位数字 多个以逗号分隔 如300,310,315,320
+	GateTcpMqAddr            string `toml:"gate_tcp_mq_addr"`            // 访问网关tcp直连消息队列的地址 填网关的内网地址
+	GateTcpMqPort            int32  `toml:"gate_tcp_mq_port"`            // tcp消息队列端口号
+	LoginSdkUrl              string `toml:"login_sdk_url"`               // 网关登录验证token的sdk服务器地址 目前填dispatch的内网地址
+	LoginSdkAccountKey       string `toml:"login_sdk_account_key"`       // sdk服务器账号验证的签名密钥
+	LoadSceneLuaConfig       bool   `toml:"load_scene_lua_config"`       // 是否加载场景详情LUA配置数据
+	DispatchUrl              string `toml:"dispatch_url"`                // 二级dispatch地址 将域名改为dispatch的外网地址
+	GmAuthKey                string `toml:"gm_auth_key"`                 // gm认证密钥
+	RegisterAllProtoMessage  bool   `toml:"register_all_proto_message"`  // 注册全部pb消息
+	ByteCheckMode            int32  `toml:"byte_check_mode"`             // 网络包数据校验模式
+	StandaloneModeEnable     bool   `toml:"standalone_mode_enable"`      // 是否开启单进程模式
+	TrackPacket              bool   `toml:"track_packet"`                // 追踪收发包
+	SensitiveWordFile        string `toml:"sensitive_word_file"`         // 额外的敏感词文件路径 每行一个词
+	SensitiveWordAllowFile   string `toml:"sensitive_word_allow_file"`   // 敏感词白名单文件路径 每行一个词
+	NavMeshPath              string `toml:"nav_mesh_path"`               // 服务端怪物ai寻路的navmesh数据目录 为空则直线移动
+	WeatherRefreshInterval   int32  `toml:"weather_refresh_interval"`    // 天气气象随机间隔 单位秒 为0则使用默认值
+	WorldBossRespawnInterval int32  `toml:"world_boss_respawn_interval"` // 世界boss复活间隔 单位秒 为0则使用默认值
+	WorldLevelAdjustCd       int32  `toml:"world_level_adjust_cd"`       // 调整世界等级冷却时间 单位秒 为0则使用默认值
+	PayProvider              string `toml:"pay_provider"`                // 支付渠道 为空则关闭充值 mock为本地模拟支付
+	PayCallbackUrl           string `toml:"pay_callback_url"`            // 模拟支付渠道的支付结果回调地址 填dispatch的内网地址
+	PayCallbackKey           string `toml:"pay_callback_key"`            // 支付结果回调的签名密钥 为空则拒绝全部支付回调
+	PayMockConfirmDelay      int32  `toml:"pay_mock_confirm_delay"`      // 模拟支付从下单到确认支付的延迟 单位秒
+	ReunionOfflineDay        int32  `toml:"reunion_offline_day"`         // 触发回归的最短离线天数 为0则使用默认值
+	UidLeaseSize             int32  `toml:"uid_lease_size"`              // 节点服务器每次从数据库预留的uid数量 为0则使用默认值
+	NodeLeaderLeaseTime      int32  `toml:"node_leader_lease_time"`      // 节点服务器主节点租约时间 单位秒 为0则使用默认值
+	LuaPluginPath            string `toml:"lua_plugin_path"`             // lua插件脚本目录 为空则不加载lua插件
+	LuaPluginTimeout         int32  `toml:"lua_plugin_timeout"`          // lua插件单次回调的最大执行时间 单位毫秒 为0则使用默认值
+	GateMaxConnNum           int32  `toml:"gate_max_conn_num"`           // 网关最大客户端连接数 为0则使用默认值
+	GateMaxConnNumPerIp      int32  `toml:"gate_max_conn_num_per_ip"`    // 网关单个ip最大连接数 为0则使用默认值
+	GateConnEstRate          int32  `toml:"gate_conn_est_rate"`          // 网关每秒建立连接数上限 为0则使用默认值
+	GateIpConnEstRate        int32  `toml:"gate_ip_conn_est_rate"`       // 网关单个ip每秒建立连接数上限 为0则使用默认值
+	GateRecvPacketRate       int32  `toml:"gate_recv_packet_rate"`       // 网关单个连接每秒上行包数上限 为0则使用默认值
+	GateIpRecvPacketRate     int32  `toml:"gate_ip_recv_packet_rate"`    // 网关单个ip每秒上行包数上限 为0则使用默认值
+	GateIpViolationLimit     int32  `toml:"gate_ip_violation_limit"`     // 单个ip每分钟超出限制多少次后封禁 为0则使用默认值
+	GateIpBanTime            int32  `toml:"gate_ip_ban_time"`            // 首次封禁ip时长 之后每次封禁时长翻倍 单位秒 为0则使用默认值
+	GateIpBanMaxTime         int32  `toml:"gate_ip_ban_max_time"`        // 封禁ip最大时长 单位秒 为0则使用默认值
+	GateIpExemptList         string `toml:"gate_ip_exempt_list"`         // 不受网关连接限制的ip列表 多个以逗号分隔
+	GateCapturePath          string `toml:"gate_capture_path"`           // 网关抓包文件目录 为空则使用默认值
+	GateCaptureMaxSize       int32  `toml:"gate_capture_max_size"`       // 网关单个抓包文件最大大小 超出后停止抓包 单位MB 为0则使用默认值
+	MetricsAddr              string `toml:"metrics_addr"`                // 监控指标/metrics的http监听地址 如0.0.0.0:9100 为空则不开启
+	ReplayModeEnable         bool   `toml:"replay_mode_enable"`          // 是否开启回放模式 游戏时间由抓包回放工具推进 只用于测试环境
 }
 
 // Hk4eRobot 原神机器人
@@ -61,6 +91,8 @@ type Hk4eRobot struct {
 	ClientMoveEnable   bool   `toml:"client_move_enable"`    // 是否开启客户端模拟移动
 	ClientMoveSpeed    int32  `toml:"client_move_speed"`     // 客户端模拟移动速度
 	ClientMoveRangeExt int32  `toml:"client_move_range_ext"` // 客户端模拟移动区域半径
+	ScenarioFile       string `toml:"scenario_file"`         // 场景测试脚本文件 为空则运行默认机器人逻辑
+	ScenarioRobotNum   int32  `toml:"scenario_robot_num"`    // 场景测试机器人数量 为0则使用脚本中的数量
 }
 
 // Logger 日志
@@ -89,6 +121,14 @@ type MQ struct {
 	NatsUrl string `toml:"nats_url"`
 }
 
+// Trace 链路追踪
+type Trace struct {
+	Exporter   string  `toml:"exporter"`    // 导出方式 为空则不开启 stdout为标准输出 file为写入文件
+	FilePath   string  `toml:"file_path"`   // 导出文件路径 为空则使用默认值
+	SampleUid  string  `toml:"sample_uid"`  // 必定采样的玩家uid 多个以逗号分隔
+	SampleRate float64 `toml:"sample_rate"` // 其余玩家的采样率 0到1之间 同一玩家的采样结果固定
+}
+
 func InitConfig(filePath string) {
 	CONF = new(Config)
 	CONF.loadConfigFile(filePath)
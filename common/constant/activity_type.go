@@ -0,0 +1,33 @@
+package constant
+
+const (
+	NEW_ACTIVITY_TYPE_NONE     = 0 // 无
+	NEW_ACTIVITY_TYPE_GENERAL  = 1 // 通用活动 只包含进度监听与奖励领取
+	NEW_ACTIVITY_TYPE_SALESMAN = 3 // 商人活动 每日交付物品领取奖励
+)
+
+const (
+	NEW_ACTIVITY_COND_NONE                    = 0 // 无
+	NEW_ACTIVITY_COND_FINISH_QUEST            = 4 // 完成任务 参数1:任务id
+	NEW_ACTIVITY_COND_SALESMAN_CAN_DELIVER    = 8 // 商人活动今日可交付
+	NEW_ACTIVITY_COND_SALESMAN_CAN_GET_REWARD = 9 // 商人活动可领取特殊奖励
+)
+
+const (
+	NEW_ACTIVITY_COND_COMPOSE_NONE = 0 // 无 同与
+	NEW_ACTIVITY_COND_COMPOSE_AND  = 1 // 与
+	NEW_ACTIVITY_COND_COMPOSE_OR   = 2 // 或
+)
+
+const (
+	NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_NONE         = 0   // 无
+	NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_GATHER       = 117 // 采集物件 参数1:物件id
+	NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE  = 118 // 击杀怪物 参数1:怪物id
+	NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_FINISH_QUEST = 700 // 完成任务 参数1:任务id
+)
+
+const (
+	REUNION_WATCHER_TRIGGER_TYPE_NONE          = 0   // 无
+	REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE   = 109 // 击杀怪物 参数1:怪物id列表
+	REUNION_WATCHER_TRIGGER_TYPE_COST_MATERIAL = 502 // 消耗物品 参数1:物品id
+)
@@ -39,8 +39,8 @@ const (
 	PLAYER_PROP_CUR_CLIMATE_TYPE                = 10036 // 天气相关
 	PLAYER_PROP_CUR_CLIMATE_AREA_ID             = 10037 // 天气相关
 	PLAYER_PROP_CUR_CLIMATE_AREA_CLIMATE_TYPE   = 10038 // 天气相关
-	PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT        = 10039 // 降低世界等级到此等级 暂不确定
-	PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD    = 10040 // 降低世界等级的CD
+	PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT        = 10039 // 世界等级上限 由冒险等阶决定
+	PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD    = 10040 // 调整世界等级的CD结束时间 单位秒
 	PLAYER_PROP_PLAYER_LEGENDARY_DAILY_TASK_NUM = 10041 // 传说每日任务数量 暂不确定
 	PLAYER_PROP_PLAYER_HOME_COIN                = 10042 // 洞天宝钱
 	PLAYER_PROP_PLAYER_WAIT_SUB_HOME_COIN       = 10043 // 扣到负数的洞天宝钱
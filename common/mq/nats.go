@@ -11,6 +11,8 @@ import (
 	"hk4e/common/config"
 	"hk4e/common/rpc"
 	"hk4e/node/api"
+	"hk4e/pkg/metrics"
+	"hk4e/pkg/trace"
 	"hk4e/protocol/cmd"
 
 	"github.com/flswld/halo/logger"
@@ -25,6 +27,16 @@ import (
 // 请不要用这个来搞RPC写一大堆异步回调!!!
 // 要用RPC有专门的NATSRPC
 
+const (
+	TransportNats = "nats"
+	TransportTcp  = "tcp"
+)
+
+var (
+	metricsSendMsg = metrics.NewCounter("mq_send_msg_total", "mq send msg count", "server_type", "transport")
+	metricsRecvMsg = metrics.NewCounter("mq_recv_msg_total", "mq recv msg count", "server_type", "transport")
+)
+
 type MessageQueue struct {
 	natsConn               *nats.Conn
 	natsMsgChan            chan *nats.Msg
@@ -36,6 +48,7 @@ type MessageQueue struct {
 	gateTcpMqEventChan     chan *GateTcpMqEvent
 	gateTcpMqDeadEventChan chan string
 	discoveryClient        *rpc.DiscoveryClient
+	traceContextFunc       func() trace.SpanContext
 }
 
 func NewMessageQueue(serverType string, appId string, discoveryClient *rpc.DiscoveryClient) (r *MessageQueue) {
@@ -95,6 +108,7 @@ func (m *MessageQueue) natsMsgRecvHandler() {
 	for {
 		natsMsg := <-m.natsMsgChan
 		rawData := natsMsg.Data
+		metricsRecvMsg.Inc(m.serverType, TransportNats)
 		netMsg := m.parseNetMsg(rawData)
 		if netMsg == nil {
 			continue
@@ -182,6 +196,7 @@ func (m *MessageQueue) sendHandler() {
 		api.GS:    make(map[string]*GateTcpMqInst),
 		api.MULTI: make(map[string]*GateTcpMqInst),
 		api.ROBOT: make(map[string]*GateTcpMqInst),
+		api.GM:    make(map[string]*GateTcpMqInst),
 	}
 	for {
 		select {
@@ -199,6 +214,7 @@ func (m *MessageQueue) sendHandler() {
 					logger.Error("nats publish msg error: %v", err)
 					return
 				}
+				metricsSendMsg.Inc(m.serverType, TransportNats)
 			}
 			// 广播消息只能走nats
 			if netMsg.ServerType == "ALL_SERVER_HK4E" {
@@ -238,6 +254,7 @@ func (m *MessageQueue) sendHandler() {
 				fallbackNatsMqSend()
 				continue
 			}
+			metricsSendMsg.Inc(m.serverType, TransportTcp)
 		case gateTcpMqEvent := <-m.gateTcpMqEventChan:
 			inst := gateTcpMqEvent.inst
 			switch gateTcpMqEvent.event {
@@ -437,6 +454,7 @@ func (m *MessageQueue) gateTcpMqRecvHandle(inst *GateTcpMqInst) {
 			}
 			recvLen += n
 		}
+		metricsRecvMsg.Inc(m.serverType, TransportTcp)
 		netMsg := m.parseNetMsg(payload[:msgLen])
 		if netMsg != nil {
 			m.netMsgOutput <- netMsg
@@ -1,6 +1,8 @@
 package mq
 
 import (
+	"hk4e/pkg/trace"
+
 	pb "google.golang.org/protobuf/proto"
 )
 
@@ -21,6 +23,25 @@ type NetMsg struct {
 	ServerMsg         *ServerMsg
 	OriginServerType  string
 	OriginServerAppId string
+	TraceId           string `msgpack:",omitempty"` // 链路追踪id 未采样时为空
+	SpanId            string `msgpack:",omitempty"` // 发送方的span id
+}
+
+// SetTrace 设置链路上下文 游戏消息同时写入GameMsg
+func (n *NetMsg) SetTrace(ctx trace.SpanContext) {
+	n.TraceId = ctx.TraceId
+	n.SpanId = ctx.SpanId
+	if n.GameMsg != nil {
+		n.GameMsg.SetTrace(ctx)
+	}
+}
+
+// GetTrace 获取链路上下文 NetMsg上没有时使用GameMsg上的
+func (n *NetMsg) GetTrace() trace.SpanContext {
+	if n.TraceId == "" && n.GameMsg != nil {
+		return n.GameMsg.GetTrace()
+	}
+	return trace.SpanContext{TraceId: n.TraceId, SpanId: n.SpanId}
 }
 
 const (
@@ -34,6 +55,17 @@ type GameMsg struct {
 	PayloadMessage     pb.Message `msgpack:"-"`
 	PayloadMessageData []byte
 	NotParse           bool
+	TraceId            string `msgpack:",omitempty"` // 链路追踪id 未采样时为空
+	SpanId             string `msgpack:",omitempty"` // 发送方的span id
+}
+
+func (g *GameMsg) SetTrace(ctx trace.SpanContext) {
+	g.TraceId = ctx.TraceId
+	g.SpanId = ctx.SpanId
+}
+
+func (g *GameMsg) GetTrace() trace.SpanContext {
+	return trace.SpanContext{TraceId: g.TraceId, SpanId: g.SpanId}
 }
 
 const (
@@ -60,6 +92,12 @@ const (
 	ServerStopNotify                         // 停服通知
 	ServerDispatchCancelNotify               // 服务器取消调度通知
 	ServerGmCmdNotify                        // 服务器GM指令执行通知
+	ServerRechargeOrderNotify                // 充值订单支付成功通知
+	ServerGateIpLimitReq                     // 网关ip限制查询与修改请求
+	ServerGateIpLimitRsp                     // 网关ip限制查询与修改响应
+	ServerGateCaptureReq                     // 网关抓包开始与停止请求
+	ServerGateCaptureRsp                     // 网关抓包开始与停止响应
+	ServerReplayClockNotify                  // 抓包回放时间同步通知
 )
 
 type ServerMsg struct {
@@ -74,6 +112,12 @@ type ServerMsg struct {
 	AppVersion       string
 	GmCmdFuncName    string
 	GmCmdParamList   []string
+	RechargeOrder    *RechargeOrderInfo
+	GateIpLimitReq   *GateIpLimitReq
+	GateIpLimitInfo  *GateIpLimitInfo
+	GateCaptureReq   *GateCaptureReq
+	GateCaptureInfo  *GateCaptureInfo
+	ReplayClock      *ReplayClockInfo
 }
 
 type OriginInfo struct {
@@ -120,3 +164,83 @@ type AddFriendInfo struct {
 	TargetUserId          uint32
 	ApplyPlayerOnlineInfo *PlayerBaseInfo
 }
+
+type RechargeOrderInfo struct {
+	OrderId   uint32
+	ProductId string
+	TradeNo   string
+}
+
+const (
+	GateIpLimitOpQuery     = iota // 查询
+	GateIpLimitOpBan              // 封禁ip
+	GateIpLimitOpUnban            // 解封ip
+	GateIpLimitOpExemptAdd        // 添加豁免ip
+	GateIpLimitOpExemptDel        // 删除豁免ip
+)
+
+type GateIpLimitReq struct {
+	ReqId   string
+	Op      uint8
+	IpAddr  string
+	BanTime uint32 // 封禁时长 单位秒 为0则按封禁次数递增
+}
+
+type GateIpBanInfo struct {
+	IpAddr  string `json:"ip_addr"`
+	EndTime int64  `json:"end_time"`
+	Level   int32  `json:"level"`
+}
+
+type GateIpLimitInfo struct {
+	ReqId            string           `json:"-"`
+	GateAppId        string           `json:"gate_appid"`
+	MaxConnNum       int32            `json:"max_conn_num"`
+	MaxConnNumPerIp  int32            `json:"max_conn_num_per_ip"`
+	ConnEstRate      int32            `json:"conn_est_rate"`
+	IpConnEstRate    int32            `json:"ip_conn_est_rate"`
+	RecvPacketRate   int32            `json:"recv_packet_rate"`
+	IpRecvPacketRate int32            `json:"ip_recv_packet_rate"`
+	ViolationLimit   int32            `json:"violation_limit"`
+	BanTime          int32            `json:"ban_time"`
+	BanMaxTime       int32            `json:"ban_max_time"`
+	ExemptList       []string         `json:"exempt_list"`
+	BanList          []*GateIpBanInfo `json:"ban_list"`
+	IpConnNumMap     map[string]int32 `json:"ip_conn_num_map"`
+}
+
+const (
+	GateCaptureOpQuery = iota // 查询
+	GateCaptureOpStart        // 开始抓包
+	GateCaptureOpStop         // 停止抓包
+)
+
+type GateCaptureReq struct {
+	ReqId     string
+	Op        uint8
+	UserId    uint32 // 按玩家uid抓包 玩家未在线时等到登录后开始
+	SessionId uint32 // 按会话id抓包
+}
+
+type GateCaptureItem struct {
+	UserId    uint32 `json:"uid"`
+	SessionId uint32 `json:"session_id"`
+	FilePath  string `json:"file_path"`
+	FileSize  int64  `json:"file_size"`
+	StartTime int64  `json:"start_time"`
+}
+
+type GateCaptureInfo struct {
+	ReqId          string             `json:"-"`
+	GateAppId      string             `json:"gate_appid"`
+	Retcode        int32              `json:"retcode"`
+	Message        string             `json:"message"`
+	CaptureList    []*GateCaptureItem `json:"capture_list"`
+	PendingUidList []uint32           `json:"pending_uid_list"`
+}
+
+type ReplayClockInfo struct {
+	Time    int64 // 抓包时间线上的时间 毫秒时间戳
+	IsStart bool  // 回放开始 重置时间与随机种子
+	Seed    int64 // 回放开始时使用的随机种子
+}
@@ -0,0 +1,57 @@
+package mq
+
+import (
+	"testing"
+
+	"hk4e/node/api"
+	"hk4e/pkg/trace"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNetMsgTrace(t *testing.T) {
+	m := &MessageQueue{serverType: api.GATE, appId: "gate", netMsgInput: make(chan *NetMsg, 10)}
+	ctx := trace.SpanContext{TraceId: trace.NewTraceId(), SpanId: trace.NewSpanId()}
+	gameMsg := &GameMsg{UserId: 10001, CmdId: 1, PayloadMessageData: []byte{1}, NotParse: true}
+	gameMsg.SetTrace(ctx)
+	m.SendToGs("gs", &NetMsg{MsgType: MsgTypeGame, EventId: NormalMsg, GameMsg: gameMsg})
+	netMsg := <-m.netMsgInput
+	if netMsg.GetTrace() != ctx || netMsg.TraceId != ctx.TraceId {
+		t.Fatalf("game msg trace not propagate to net msg: %+v", netMsg)
+	}
+	recvNetMsg := m.parseNetMsg(m.buildNetMsg(netMsg))
+	if recvNetMsg == nil || recvNetMsg.GetTrace() != ctx || recvNetMsg.GameMsg.GetTrace() != ctx {
+		t.Fatalf("trace lost after encode: %+v", recvNetMsg)
+	}
+
+	// 没有链路上下文的消息使用当前正在处理的链路
+	m.SetTraceContextFunc(func() trace.SpanContext {
+		return ctx
+	})
+	m.SendToMulti("multi", &NetMsg{MsgType: MsgTypeServer, ServerMsg: &ServerMsg{UserId: 10001}})
+	netMsg = <-m.netMsgInput
+	if netMsg.GetTrace() != ctx {
+		t.Fatalf("current trace not inject: %+v", netMsg)
+	}
+	m.SetTraceContextFunc(func() trace.SpanContext {
+		return trace.SpanContext{}
+	})
+	m.SendToGate("gate", &NetMsg{MsgType: MsgTypeServer, ServerMsg: &ServerMsg{UserId: 10001}})
+	netMsg = <-m.netMsgInput
+	if netMsg.GetTrace().IsValid() {
+		t.Fatalf("untraced msg should not carry trace: %+v", netMsg)
+	}
+	// 未采样的消息不额外编码链路字段 旧版本服务器可以正常解析
+	rawData, err := msgpack.Marshal(&NetMsg{MsgType: MsgTypeServer, ServerMsg: &ServerMsg{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fieldMap := make(map[string]any)
+	err = msgpack.Unmarshal(rawData, &fieldMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exist := fieldMap["TraceId"]; exist {
+		t.Fatalf("empty trace id should omit: %v", fieldMap)
+	}
+}
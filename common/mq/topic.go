@@ -2,6 +2,7 @@ package mq
 
 import (
 	"hk4e/node/api"
+	"hk4e/pkg/trace"
 )
 
 func (m *MessageQueue) getOriginServer() (originServerType string, originServerAppId string) {
@@ -10,6 +11,22 @@ func (m *MessageQueue) getOriginServer() (originServerType string, originServerA
 	return originServerType, originServerAppId
 }
 
+// SetTraceContextFunc 设置获取当前链路上下文的函数 发送未携带链路上下文的消息时使用
+func (m *MessageQueue) SetTraceContextFunc(fn func() trace.SpanContext) {
+	m.traceContextFunc = fn
+}
+
+// injectTrace 向下游传递链路上下文
+func (m *MessageQueue) injectTrace(netMsg *NetMsg) {
+	ctx := netMsg.GetTrace()
+	if !ctx.IsValid() && m.traceContextFunc != nil {
+		ctx = m.traceContextFunc()
+	}
+	if ctx.IsValid() {
+		netMsg.SetTrace(ctx)
+	}
+}
+
 func (m *MessageQueue) getTopic(serverType string, appId string) string {
 	topic := serverType + "_" + appId + "_" + "HK4E"
 	return topic
@@ -22,6 +39,7 @@ func (m *MessageQueue) SendToGate(appId string, netMsg *NetMsg) {
 	originServerType, originServerAppId := m.getOriginServer()
 	netMsg.OriginServerType = originServerType
 	netMsg.OriginServerAppId = originServerAppId
+	m.injectTrace(netMsg)
 	m.netMsgInput <- netMsg
 }
 
@@ -32,6 +50,7 @@ func (m *MessageQueue) SendToGs(appId string, netMsg *NetMsg) {
 	originServerType, originServerAppId := m.getOriginServer()
 	netMsg.OriginServerType = originServerType
 	netMsg.OriginServerAppId = originServerAppId
+	m.injectTrace(netMsg)
 	m.netMsgInput <- netMsg
 }
 
@@ -42,6 +61,7 @@ func (m *MessageQueue) SendToMulti(appId string, netMsg *NetMsg) {
 	originServerType, originServerAppId := m.getOriginServer()
 	netMsg.OriginServerType = originServerType
 	netMsg.OriginServerAppId = originServerAppId
+	m.injectTrace(netMsg)
 	m.netMsgInput <- netMsg
 }
 
@@ -65,6 +85,16 @@ func (m *MessageQueue) SendToDispatch(appId string, netMsg *NetMsg) {
 	m.netMsgInput <- netMsg
 }
 
+func (m *MessageQueue) SendToGm(appId string, netMsg *NetMsg) {
+	netMsg.Topic = m.getTopic(api.GM, appId)
+	netMsg.ServerType = api.GM
+	netMsg.AppId = appId
+	originServerType, originServerAppId := m.getOriginServer()
+	netMsg.OriginServerType = originServerType
+	netMsg.OriginServerAppId = originServerAppId
+	m.netMsgInput <- netMsg
+}
+
 func (m *MessageQueue) SendToAll(netMsg *NetMsg) {
 	netMsg.Topic = "ALL_SERVER_HK4E"
 	netMsg.ServerType = "ALL_SERVER_HK4E"
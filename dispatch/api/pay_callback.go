@@ -0,0 +1,30 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+type PayCallbackReq struct {
+	Uid       uint32 `json:"uid"`
+	OrderId   uint32 `json:"order_id"`
+	ProductId string `json:"product_id"`
+	TradeNo   string `json:"trade_no"`
+	Sign      string `json:"sign"`
+}
+
+type PayCallbackRsp struct {
+	RetCode int32  `json:"retcode"`
+	Message string `json:"message"`
+}
+
+// PayCallbackSign 支付结果回调签名
+func PayCallbackSign(req *PayCallbackReq, key string) string {
+	signStr := fmt.Sprintf("order_id=%d&product_id=%s&trade_no=%s&uid=%d", req.OrderId, req.ProductId, req.TradeNo, req.Uid)
+	signHash := hmac.New(sha256.New, []byte(key))
+	signHash.Write([]byte(signStr))
+	signData := signHash.Sum(nil)
+	return hex.EncodeToString(signData)
+}
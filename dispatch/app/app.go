@@ -44,6 +44,7 @@ func Run(ctx context.Context) error {
 			_, err := discoveryClient.KeepaliveServer(context.TODO(), &api.KeepaliveServerReq{
 				ServerType: api.DISPATCH,
 				AppId:      APPID,
+				AppVersion: APPVERSION,
 			})
 			if err != nil {
 				logger.Error("keepalive error: %v", err)
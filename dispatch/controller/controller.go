@@ -194,6 +194,7 @@ func (c *Controller) registerRouter() {
 		engine.StaticFS("/pictures", http.Dir("./static/geetest/pictures"))
 	}
 	engine.POST("/gate/token/verify", c.gateTokenVerify)
+	engine.POST("/pay/callback", c.payCallback)
 	port := config.GetConfig().Hk4e.DispatchHttpPort
 	addr := ":" + strconv.Itoa(int(port))
 	err := engine.Run(addr)
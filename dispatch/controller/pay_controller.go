@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"crypto/hmac"
+	"net/http"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/dispatch/api"
+
+	"github.com/flswld/halo/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// 支付渠道的支付结果回调 验证签名后通知GS发货
+// 玩家不在线时由GS在玩家登录时主动对账
+// 未配置签名密钥时拒绝全部回调
+func (c *Controller) payCallback(ctx *gin.Context) {
+	payCallbackKey := config.GetConfig().Hk4e.PayCallbackKey
+	if payCallbackKey == "" {
+		logger.Error("pay callback key not config, reject pay callback")
+		ctx.JSON(http.StatusOK, &api.PayCallbackRsp{RetCode: -3, Message: "支付回调未开启"})
+		return
+	}
+	payCallbackReq := new(api.PayCallbackReq)
+	err := ctx.ShouldBindJSON(payCallbackReq)
+	if err != nil {
+		ctx.JSON(http.StatusOK, &api.PayCallbackRsp{RetCode: -1, Message: "参数解析错误"})
+		return
+	}
+	logger.Info("pay callback, req: %v", payCallbackReq)
+	if !hmac.Equal([]byte(payCallbackReq.Sign), []byte(api.PayCallbackSign(payCallbackReq, payCallbackKey))) {
+		logger.Error("pay callback sign error, req: %v", payCallbackReq)
+		ctx.JSON(http.StatusOK, &api.PayCallbackRsp{RetCode: -2, Message: "签名错误"})
+		return
+	}
+	c.messageQueue.SendToAll(&mq.NetMsg{
+		MsgType: mq.MsgTypeServer,
+		EventId: mq.ServerRechargeOrderNotify,
+		ServerMsg: &mq.ServerMsg{
+			UserId: payCallbackReq.Uid,
+			RechargeOrder: &mq.RechargeOrderInfo{
+				OrderId:   payCallbackReq.OrderId,
+				ProductId: payCallbackReq.ProductId,
+				TradeNo:   payCallbackReq.TradeNo,
+			},
+		},
+	})
+	ctx.JSON(http.StatusOK, &api.PayCallbackRsp{RetCode: 0, Message: "OK"})
+}
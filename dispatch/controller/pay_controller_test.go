@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hk4e/common/config"
+	"hk4e/dispatch/api"
+
+	"github.com/flswld/halo/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPayCallbackNoKey(t *testing.T) {
+	logger.InitLogger(nil)
+	config.CONF = &config.Config{}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/pay/callback", new(Controller).payCallback)
+	req := &api.PayCallbackReq{Uid: 10001, OrderId: 1, ProductId: "product", TradeNo: "trade"}
+	// 空密钥的签名任何人都能计算 必须拒绝
+	req.Sign = api.PayCallbackSign(req, "")
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/pay/callback", strings.NewReader(string(body))))
+	rsp := new(api.PayCallbackRsp)
+	err = json.Unmarshal(recorder.Body.Bytes(), rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.RetCode == 0 {
+		t.Fatalf("pay callback should reject when key is empty: %+v", rsp)
+	}
+}
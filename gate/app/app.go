@@ -16,6 +16,7 @@ import (
 	"hk4e/gate/dao"
 	"hk4e/gate/net"
 	"hk4e/node/api"
+	"hk4e/pkg/trace"
 
 	"github.com/flswld/halo/logger"
 )
@@ -31,16 +32,18 @@ func Run(ctx context.Context) error {
 	}
 
 	// 注册到节点服务器
+	gateServerAddr := &api.GateServerAddr{
+		KcpAddr: config.GetConfig().Hk4e.KcpAddr,
+		KcpPort: uint32(config.GetConfig().Hk4e.KcpPort),
+		MqAddr:  config.GetConfig().Hk4e.GateTcpMqAddr,
+		MqPort:  uint32(config.GetConfig().Hk4e.GateTcpMqPort),
+	}
+	gameVersionList := strings.Split(config.GetConfig().Hk4e.Version, ",")
 	rsp, err := discoveryClient.RegisterServer(context.TODO(), &api.RegisterServerReq{
-		ServerType: api.GATE,
-		AppVersion: APPVERSION,
-		GateServerAddr: &api.GateServerAddr{
-			KcpAddr: config.GetConfig().Hk4e.KcpAddr,
-			KcpPort: uint32(config.GetConfig().Hk4e.KcpPort),
-			MqAddr:  config.GetConfig().Hk4e.GateTcpMqAddr,
-			MqPort:  uint32(config.GetConfig().Hk4e.GateTcpMqPort),
-		},
-		GameVersionList: strings.Split(config.GetConfig().Hk4e.Version, ","),
+		ServerType:      api.GATE,
+		AppVersion:      APPVERSION,
+		GateServerAddr:  gateServerAddr,
+		GameVersionList: gameVersionList,
 	})
 	if err != nil {
 		return err
@@ -51,9 +54,12 @@ func Run(ctx context.Context) error {
 		for {
 			<-ticker.C
 			_, err := discoveryClient.KeepaliveServer(context.TODO(), &api.KeepaliveServerReq{
-				ServerType: api.GATE,
-				AppId:      APPID,
-				LoadCount:  uint32(atomic.LoadInt32(&net.CLIENT_CONN_NUM)),
+				ServerType:      api.GATE,
+				AppId:           APPID,
+				LoadCount:       uint32(atomic.LoadInt32(&net.CLIENT_CONN_NUM)),
+				AppVersion:      APPVERSION,
+				GateServerAddr:  gateServerAddr,
+				GameVersionList: gameVersionList,
 			})
 			if err != nil {
 				logger.Error("keepalive error: %v", err)
@@ -80,6 +86,17 @@ func Run(ctx context.Context) error {
 		defer func() {
 			logger.CloseLogger()
 		}()
+		err = trace.InitTracer(&trace.Config{
+			ServiceName: "gate_" + APPID,
+			Exporter:    config.GetConfig().Trace.Exporter,
+			FilePath:    config.GetConfig().Trace.FilePath,
+			SampleUid:   config.GetConfig().Trace.SampleUid,
+			SampleRate:  config.GetConfig().Trace.SampleRate,
+		})
+		if err != nil {
+			return err
+		}
+		defer trace.CloseTracer()
 	}
 	logger.Warn("gate start, appid: %v", APPID)
 	defer func() {
@@ -0,0 +1,269 @@
+package net
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/pkg/capture"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 网关抓包
+// 按玩家uid或会话id将解密后 协议解码前的收发包写入抓包文件 配合cmd/replay回放
+
+const (
+	CapturePathDefault    = "./capture" // 抓包文件目录
+	CaptureMaxSizeDefault = 64          // 单个抓包文件最大大小 MB
+)
+
+var (
+	ErrCaptureSessionNotFound = errors.New("session not found")
+	ErrCaptureAlreadyStart    = errors.New("capture already start")
+	ErrCaptureNotStart        = errors.New("capture not start")
+)
+
+type captureItem struct {
+	session   *Session
+	writer    *capture.Writer
+	filePath  string
+	startTime time.Time
+}
+
+type CaptureManager struct {
+	lock           sync.Mutex
+	path           string
+	maxSize        int64
+	clientProto    bool
+	pendingUidMap  map[uint32]bool         // 等待登录后开始抓包的玩家uid
+	captureItemMap map[uint32]*captureItem // 正在抓包的会话 key:sessionId
+}
+
+func NewCaptureManager() *CaptureManager {
+	m := new(CaptureManager)
+	m.path = config.GetConfig().Hk4e.GateCapturePath
+	if m.path == "" {
+		m.path = CapturePathDefault
+	}
+	maxSize := config.GetConfig().Hk4e.GateCaptureMaxSize
+	if maxSize == 0 {
+		maxSize = CaptureMaxSizeDefault
+	}
+	m.maxSize = int64(maxSize) * 1024 * 1024
+	m.clientProto = config.GetConfig().Hk4e.ClientProtoProxyEnable
+	m.pendingUidMap = make(map[uint32]bool)
+	m.captureItemMap = make(map[uint32]*captureItem)
+	return m
+}
+
+// Start 开始抓包 返回抓包文件路径
+func (m *CaptureManager) Start(session *Session) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.start(session)
+}
+
+func (m *CaptureManager) start(session *Session) (string, error) {
+	if session.capture.Load() != nil {
+		return "", ErrCaptureAlreadyStart
+	}
+	err := os.MkdirAll(m.path, 0755)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	fileName := strconv.Itoa(int(session.userId)) + "_" + strconv.Itoa(int(session.sessionId)) + "_" + now.Format("20060102150405") + ".cap"
+	filePath := filepath.Join(m.path, fileName)
+	head := &capture.Head{
+		SessionId: session.sessionId,
+		Uid:       session.userId,
+	}
+	if m.clientProto {
+		head.Flag |= capture.FlagClientProto
+	}
+	writer, err := capture.Create(filePath, head, now, m.maxSize)
+	if err != nil {
+		return "", err
+	}
+	session.capture.Store(writer)
+	m.captureItemMap[session.sessionId] = &captureItem{
+		session:   session,
+		writer:    writer,
+		filePath:  filePath,
+		startTime: now,
+	}
+	logger.Warn("capture start, sessionId: %v, uid: %v, file: %v", session.sessionId, session.userId, filePath)
+	return filePath, nil
+}
+
+// Stop 停止抓包
+func (m *CaptureManager) Stop(session *Session) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stop(session)
+}
+
+func (m *CaptureManager) stop(session *Session) error {
+	writer := session.capture.Swap(nil)
+	if writer == nil {
+		return ErrCaptureNotStart
+	}
+	filePath := ""
+	item, exist := m.captureItemMap[session.sessionId]
+	if exist {
+		filePath = item.filePath
+		delete(m.captureItemMap, session.sessionId)
+	}
+	size := writer.GetSize()
+	err := writer.Close()
+	if err != nil {
+		logger.Error("close capture file error: %v, file: %v", err, filePath)
+	}
+	logger.Warn("capture stop, sessionId: %v, uid: %v, file: %v, size: %v", session.sessionId, session.userId, filePath, size)
+	return nil
+}
+
+// AddPendingUid 玩家登录后开始抓包
+func (m *CaptureManager) AddPendingUid(uid uint32) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pendingUidMap[uid] = true
+}
+
+func (m *CaptureManager) DelPendingUid(uid uint32) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, exist := m.pendingUidMap[uid]
+	delete(m.pendingUidMap, uid)
+	return exist
+}
+
+// OnLogin 会话关联玩家uid后调用
+func (m *CaptureManager) OnLogin(session *Session) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, exist := m.pendingUidMap[session.userId]
+	if !exist {
+		return
+	}
+	delete(m.pendingUidMap, session.userId)
+	_, err := m.start(session)
+	if err != nil {
+		logger.Error("capture start error: %v, uid: %v", err, session.userId)
+	}
+}
+
+// WriteFrame 写入一帧收发包 未抓包时直接返回 写入失败或超出大小限制时停止抓包
+func (m *CaptureManager) WriteFrame(session *Session, dir uint8, kcpMsg *KcpMsg) {
+	writer := session.capture.Load()
+	if writer == nil {
+		return
+	}
+	err := writer.WriteFrame(dir, time.Now(), kcpMsg.CmdId, kcpMsg.HeadData, kcpMsg.ProtoData)
+	if err == nil {
+		return
+	}
+	if err != capture.ErrClosed {
+		logger.Error("capture write frame error: %v, sessionId: %v", err, session.sessionId)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if session.capture.Load() == writer {
+		_ = m.stop(session)
+	}
+}
+
+func (m *CaptureManager) GetInfo() *mq.GateCaptureInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	info := &mq.GateCaptureInfo{
+		CaptureList:    make([]*mq.GateCaptureItem, 0, len(m.captureItemMap)),
+		PendingUidList: make([]uint32, 0, len(m.pendingUidMap)),
+	}
+	for sessionId, item := range m.captureItemMap {
+		info.CaptureList = append(info.CaptureList, &mq.GateCaptureItem{
+			UserId:    item.session.userId,
+			SessionId: sessionId,
+			FilePath:  item.filePath,
+			FileSize:  item.writer.GetSize(),
+			StartTime: item.startTime.Unix(),
+		})
+	}
+	sort.Slice(info.CaptureList, func(i, j int) bool {
+		return info.CaptureList[i].SessionId < info.CaptureList[j].SessionId
+	})
+	for uid := range m.pendingUidMap {
+		info.PendingUidList = append(info.PendingUidList, uid)
+	}
+	sort.Slice(info.PendingUidList, func(i, j int) bool {
+		return info.PendingUidList[i] < info.PendingUidList[j]
+	})
+	return info
+}
+
+// gateCaptureReqHandle 处理gm服务器的抓包请求 并返回当前的抓包状态
+func (c *ConnManager) gateCaptureReqHandle(netMsg *mq.NetMsg) {
+	req := netMsg.ServerMsg.GateCaptureReq
+	if req == nil {
+		return
+	}
+	var err error = nil
+	switch req.Op {
+	case mq.GateCaptureOpStart:
+		if req.UserId != 0 {
+			session := c.GetSessionByUserId(req.UserId)
+			if session != nil {
+				_, err = c.captureManager.Start(session)
+			} else {
+				logger.Warn("capture uid not online, wait login, uid: %v", req.UserId)
+				c.captureManager.AddPendingUid(req.UserId)
+			}
+		} else {
+			session := c.GetSession(req.SessionId)
+			if session != nil {
+				_, err = c.captureManager.Start(session)
+			} else {
+				err = ErrCaptureSessionNotFound
+			}
+		}
+	case mq.GateCaptureOpStop:
+		var session *Session = nil
+		pending := false
+		if req.UserId != 0 {
+			pending = c.captureManager.DelPendingUid(req.UserId)
+			session = c.GetSessionByUserId(req.UserId)
+			if session == nil && !pending {
+				err = ErrCaptureSessionNotFound
+			}
+		} else {
+			session = c.GetSession(req.SessionId)
+			if session == nil {
+				err = ErrCaptureSessionNotFound
+			}
+		}
+		if session != nil {
+			err = c.captureManager.Stop(session)
+			if err == ErrCaptureNotStart && pending {
+				err = nil
+			}
+		}
+	}
+	info := c.captureManager.GetInfo()
+	info.ReqId = req.ReqId
+	if err != nil {
+		info.Retcode = -1
+		info.Message = err.Error()
+	}
+	c.messageQueue.SendToGm(netMsg.OriginServerAppId, &mq.NetMsg{
+		MsgType:   mq.MsgTypeServer,
+		EventId:   mq.ServerGateCaptureRsp,
+		ServerMsg: &mq.ServerMsg{GateCaptureInfo: info},
+	})
+}
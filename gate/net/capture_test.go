@@ -0,0 +1,62 @@
+package net
+
+import (
+	"testing"
+
+	"hk4e/common/config"
+	"hk4e/pkg/capture"
+
+	"github.com/flswld/halo/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger(nil)
+	m.Run()
+}
+
+func TestCaptureManager(t *testing.T) {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{GateCapturePath: t.TempDir()}}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	m := NewCaptureManager()
+	session := &Session{sessionId: 1, userId: 0}
+	m.WriteFrame(session, capture.DirRecv, &KcpMsg{CmdId: 1})
+	// 按uid抓包 登录后开始
+	m.AddPendingUid(10001)
+	session.userId = 10001
+	m.OnLogin(session)
+	if session.capture.Load() == nil {
+		t.Fatal("capture should start after login")
+	}
+	if _, err := m.Start(session); err != ErrCaptureAlreadyStart {
+		t.Fatal("capture should not start twice")
+	}
+	m.WriteFrame(session, capture.DirRecv, &KcpMsg{CmdId: 1, HeadData: []byte{0x01}, ProtoData: []byte{0x02}})
+	m.WriteFrame(session, capture.DirSend, &KcpMsg{CmdId: 2})
+	info := m.GetInfo()
+	if len(info.CaptureList) != 1 || info.CaptureList[0].UserId != 10001 || len(info.PendingUidList) != 0 {
+		t.Fatalf("capture info error: %+v", info)
+	}
+	filePath := info.CaptureList[0].FilePath
+	if m.Stop(session) != nil || m.Stop(session) != ErrCaptureNotStart {
+		t.Fatal("capture stop error")
+	}
+	head, frameList, err := capture.ReadAll(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Uid != 10001 || head.SessionId != 1 || len(frameList) != 2 || frameList[0].CmdId != 1 || frameList[1].Dir != capture.DirSend {
+		t.Fatalf("capture file error: %+v, %v", head, len(frameList))
+	}
+	// 超出大小限制后自动停止
+	m.maxSize = 50
+	_, err = m.Start(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.WriteFrame(session, capture.DirRecv, &KcpMsg{CmdId: 1, ProtoData: make([]byte, 100)})
+	if session.capture.Load() != nil || len(m.GetInfo().CaptureList) != 0 {
+		t.Fatal("capture should stop after exceed size limit")
+	}
+}
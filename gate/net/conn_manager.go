@@ -16,6 +16,7 @@ import (
 	"hk4e/gate/client_proto"
 	"hk4e/gate/dao"
 	"hk4e/node/api"
+	"hk4e/pkg/capture"
 	"hk4e/pkg/random"
 	"hk4e/protocol/cmd"
 
@@ -26,14 +27,11 @@ import (
 // 网络连接管理
 
 const (
-	ConnEstFreqLimit      = 100        // 每秒连接建立频率限制
-	RecvPacketFreqLimit   = 1000       // 客户端上行每秒发包频率限制
-	SendPacketFreqLimit   = 1000       // 服务器下行每秒发包频率限制
-	PacketMaxLen          = 343 * 1024 // 最大应用层包长度
-	ConnRecvTimeout       = 30         // 收包超时时间 秒
-	ConnSendTimeout       = 10         // 发包超时时间 秒
-	MaxClientConnNumLimit = 1000       // 最大客户端连接数限制
-	TcpNoDelay            = true       // 是否禁用tcp的nagle
+	SendPacketFreqLimit = 1000       // 服务器下行每秒发包频率限制
+	PacketMaxLen        = 343 * 1024 // 最大应用层包长度
+	ConnRecvTimeout     = 30         // 收包超时时间 秒
+	ConnSendTimeout     = 10         // 发包超时时间 秒
+	TcpNoDelay          = true       // 是否禁用tcp的nagle
 )
 
 var CLIENT_CONN_NUM int32 = 0 // 当前客户端连接数
@@ -59,6 +57,8 @@ type ConnManager struct {
 	minLoadMultiServerAppId string
 	stopServerInfo          *api.StopServerInfo
 	whiteList               *api.GetWhiteListRsp
+	ipLimiter               *IpLimiter      // ip限制器
+	captureManager          *CaptureManager // 抓包管理器
 	// 会话
 	sessionIdCounter uint32
 	sessionMap       map[uint32]*Session
@@ -88,6 +88,8 @@ func NewConnManager(db *dao.Dao, messageQueue *mq.MessageQueue, discovery *rpc.D
 	r.minLoadMultiServerAppId = ""
 	r.stopServerInfo = nil
 	r.whiteList = nil
+	r.ipLimiter = NewIpLimiter(NewIpLimitConfig(), time.Now())
+	r.captureManager = NewCaptureManager()
 	r.sessionIdCounter = 0
 	r.sessionMap = make(map[uint32]*Session)
 	r.sessionUserIdMap = make(map[uint32]*Session)
@@ -159,6 +161,7 @@ func (c *ConnManager) run() error {
 	go c.autoSyncWhiteList()
 	c.syncStopServerInfo()
 	go c.autoSyncStopServerInfo()
+	go c.autoCleanIpLimiter()
 	go func() {
 		for {
 			connEvent := <-c.connEventChan
@@ -173,26 +176,59 @@ func (c *ConnManager) Close() {
 }
 
 func (c *ConnManager) kcpNetInfo() {
-	ticker := time.NewTicker(time.Second * 60)
+	// 监控指标每5秒采集一次 日志每60秒输出一次
+	ticker := time.NewTicker(time.Second * 5)
+	tickCount := 0
 	kcpErrorCount := uint64(0)
+	total := new(kcp.Snmp)
 	for {
 		<-ticker.C
 		snmp := kcp.DefaultSnmp.Copy()
+		kcp.DefaultSnmp.Reset()
+		addKcpSnmpMetrics(snmp)
+		total.BytesSent += snmp.BytesSent
+		total.BytesReceived += snmp.BytesReceived
+		total.OutBytes += snmp.OutBytes
+		total.InBytes += snmp.InBytes
+		total.OutPkts += snmp.OutPkts
+		total.InPkts += snmp.InPkts
+		total.CurrEstab += snmp.CurrEstab
 		kcpErrorCount += snmp.KCPInErrors
-		logger.Info("kcp send: %v B/s, kcp recv: %v B/s", snmp.BytesSent/60, snmp.BytesReceived/60)
-		logger.Info("udp send: %v B/s, udp recv: %v B/s", snmp.OutBytes/60, snmp.InBytes/60)
-		logger.Info("udp send: %v pps, udp recv: %v pps", snmp.OutPkts/60, snmp.InPkts/60)
+		tickCount++
+		if tickCount < 12 {
+			continue
+		}
+		logger.Info("kcp send: %v B/s, kcp recv: %v B/s", total.BytesSent/60, total.BytesReceived/60)
+		logger.Info("udp send: %v B/s, udp recv: %v B/s", total.OutBytes/60, total.InBytes/60)
+		logger.Info("udp send: %v pps, udp recv: %v pps", total.OutPkts/60, total.InPkts/60)
 		clientConnNum := atomic.LoadInt32(&CLIENT_CONN_NUM)
-		logger.Info("conn num: %v, new conn num: %v, kcp error num: %v", clientConnNum, snmp.CurrEstab, kcpErrorCount)
-		kcp.DefaultSnmp.Reset()
+		logger.Info("conn num: %v, new conn num: %v, kcp error num: %v", clientConnNum, total.CurrEstab, kcpErrorCount)
+		tickCount = 0
+		total = new(kcp.Snmp)
+	}
+}
+
+func (c *ConnManager) autoCleanIpLimiter() {
+	ticker := time.NewTicker(ipStateCleanTime)
+	for {
+		<-ticker.C
+		c.ipLimiter.Clean(time.Now())
 	}
 }
 
+// getConnIp 获取连接的客户端ip
+func getConnIp(conn Conn) string {
+	addr := conn.RemoteAddr().String()
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return ip
+}
+
 // 接收新连接协程
 func (c *ConnManager) acceptHandle(tcpMode bool, kcpListener *kcp.Listener, tcpListener *net.TCPListener) {
 	logger.Info("accept handle start, tcpMode: %v", tcpMode)
-	connEstFreqLimitCounter := 0
-	connEstFreqLimitTimer := time.Now().UnixNano()
 	for {
 		var conn Conn = nil
 		if !tcpMode {
@@ -217,18 +253,19 @@ func (c *ConnManager) acceptHandle(tcpMode bool, kcpListener *kcp.Listener, tcpL
 			}
 			conn = NewTcpConn(tcpConn)
 		}
-		// 连接建立频率限制
-		connEstFreqLimitCounter++
-		if connEstFreqLimitCounter > ConnEstFreqLimit {
-			now := time.Now().UnixNano()
-			if now-connEstFreqLimitTimer > int64(time.Second) {
-				connEstFreqLimitCounter = 0
-				connEstFreqLimitTimer = now
+		// ip连接数与连接建立频率限制
+		clientIp := getConnIp(conn)
+		limitResult := c.ipLimiter.OnConnEst(clientIp, time.Now())
+		if limitResult != IpLimitOk {
+			logger.Error("conn est limit, ip: %v, result: %v", clientIp, limitResult)
+			metricsIpLimit.Inc(limitResult.String())
+			if limitResult == IpLimitBan {
+				_ = conn.CloseReason(kcp.EnetSecurityKick)
+				c.kickIp(clientIp)
 			} else {
-				logger.Error("conn est freq limit, now: %v conn/s", connEstFreqLimitCounter)
 				_ = conn.CloseReason(kcp.EnetServerKick)
-				continue
 			}
+			continue
 		}
 		sessionId := uint32(0)
 		if !tcpMode {
@@ -241,6 +278,7 @@ func (c *ConnManager) acceptHandle(tcpMode bool, kcpListener *kcp.Listener, tcpL
 		session := &Session{
 			sessionId:        sessionId,
 			conn:             conn,
+			clientIp:         clientIp,
 			connState:        ConnEst,
 			isLogin:          atomic.Bool{},
 			userId:           0,
@@ -269,6 +307,7 @@ func (c *ConnManager) acceptHandle(tcpMode bool, kcpListener *kcp.Listener, tcpL
 type Session struct {
 	sessionId        uint32
 	conn             Conn
+	clientIp         string
 	connState        uint32
 	isLogin          atomic.Bool
 	userId           uint32
@@ -279,6 +318,7 @@ type Session struct {
 	multiServerAppId string
 	robotServerAppId string
 	useMagicSeed     bool
+	capture          atomic.Pointer[capture.Writer]
 }
 
 // 接收协程
@@ -287,8 +327,7 @@ func (c *ConnManager) recvHandle(session *Session) {
 	conn := session.conn
 	header := make([]byte, 4)
 	payload := make([]byte, PacketMaxLen)
-	pktFreqLimitCounter := 0
-	pktFreqLimitTimer := time.Now().UnixNano()
+	pktFreqLimitBucket := NewTokenBucket(c.ipLimiter.GetConfig().RecvPacketRate, time.Now())
 	_, isKcpConn := conn.(*kcp.UDPSession)
 	tcpConn, _ := conn.(*TCPConn)
 	for {
@@ -352,22 +391,21 @@ func (c *ConnManager) recvHandle(session *Session) {
 			bin = payload[:msgLen]
 		}
 		// 收包频率限制
-		pktFreqLimitCounter++
-		if pktFreqLimitCounter > RecvPacketFreqLimit {
-			now := time.Now().UnixNano()
-			if now-pktFreqLimitTimer > int64(time.Second) {
-				pktFreqLimitCounter = 0
-				pktFreqLimitTimer = now
-			} else {
-				logger.Error("exit recv loop, client packet send freq too high, sessionId: %v, pps: %v",
-					session.sessionId, pktFreqLimitCounter)
-				c.closeConn(session, kcp.EnetPacketFreqTooHigh)
-				return
+		limitResult := c.ipLimiter.OnRecvPacket(session.clientIp, pktFreqLimitBucket, time.Now())
+		if limitResult != IpLimitOk {
+			logger.Error("exit recv loop, client packet send freq too high, sessionId: %v, ip: %v, result: %v",
+				session.sessionId, session.clientIp, limitResult)
+			metricsIpLimit.Inc(limitResult.String())
+			c.closeConn(session, kcp.EnetPacketFreqTooHigh)
+			if limitResult == IpLimitBan {
+				c.kickIp(session.clientIp)
 			}
+			return
 		}
 		kcpMsgList := make([]*KcpMsg, 0)
 		DecodeBinToPayload(bin, session.sessionId, &kcpMsgList, session.xorKey)
 		for _, v := range kcpMsgList {
+			c.captureManager.WriteFrame(session, capture.DirRecv, v)
 			protoMsgList := ProtoDecode(v, c.serverCmdProtoMap, c.clientCmdProtoMap)
 			for _, vv := range protoMsgList {
 				c.forwardClientMsgToServerHandle(vv, session)
@@ -395,6 +433,7 @@ func (c *ConnManager) sendHandle(session *Session) {
 			logger.Error("encode kcp msg is nil, sessionId: %v", session.sessionId)
 			continue
 		}
+		c.captureManager.WriteFrame(session, capture.DirSend, kcpMsg)
 		bin := EncodePayloadToBin(kcpMsg, session.xorKey)
 		if isTcpConn {
 			// tcp流分割的4个字节payload长度头部
@@ -483,6 +522,24 @@ func (c *ConnManager) closeConnBySessionId(sessionId uint32, reason uint32) {
 	logger.Info("conn has been close, sessionId: %v", sessionId)
 }
 
+// 关闭指定ip的全部连接
+func (c *ConnManager) kickIp(ip string) {
+	sessionList := make([]*Session, 0)
+	c.sessionMapLock.RLock()
+	for _, session := range c.sessionMap {
+		if session.clientIp == ip {
+			sessionList = append(sessionList, session)
+		}
+	}
+	c.sessionMapLock.RUnlock()
+	for _, session := range sessionList {
+		c.closeConn(session, kcp.EnetSecurityKick)
+	}
+	if len(sessionList) != 0 {
+		logger.Warn("kick all conn of ip, ip: %v, num: %v", ip, len(sessionList))
+	}
+}
+
 // 关闭连接
 func (c *ConnManager) closeConn(session *Session, enetType uint32) {
 	ok := atomic.CompareAndSwapUint32(&(session.connState), ConnEst, ConnClose)
@@ -491,6 +548,9 @@ func (c *ConnManager) closeConn(session *Session, enetType uint32) {
 	}
 	logger.Info("[CLOSE] client disconnect, sessionId: %v, conv: %v, addr: %v",
 		session.sessionId, session.conn.GetConv(), session.conn.RemoteAddr())
+	metricsKickConn.Inc(getEnetTypeName(enetType))
+	c.ipLimiter.OnConnClose(session.clientIp, time.Now())
+	_ = c.captureManager.Stop(session)
 	// 清理数据
 	c.DeleteSession(session.sessionId, session.userId)
 	// 关闭连接
@@ -0,0 +1,447 @@
+package net
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 网关ip限制
+// 单个ip并发连接数上限 全局和单个ip的连接建立令牌桶限速 单个连接和单个ip的上行包令牌桶限速
+// 一分钟内多次超出限制的ip会被临时封禁 再次封禁时长翻倍 豁免列表内的ip不受限制
+
+const (
+	MaxClientConnNumLimitDefault = 1000      // 最大客户端连接数限制
+	MaxConnNumPerIpDefault       = 10        // 单个ip最大连接数
+	ConnEstFreqLimitDefault      = 100       // 每秒连接建立频率限制
+	IpConnEstFreqLimitDefault    = 5         // 单个ip每秒连接建立频率限制
+	RecvPacketFreqLimitDefault   = 1000      // 客户端上行每秒发包频率限制
+	IpRecvPacketFreqLimitDefault = 3000      // 单个ip上行每秒发包频率限制
+	IpViolationLimitDefault      = 10        // 单个ip每分钟超出限制多少次后封禁
+	IpBanTimeDefault             = 60        // 首次封禁时长 秒
+	IpBanMaxTimeDefault          = 3600 * 24 // 最大封禁时长 秒
+)
+
+const (
+	ipViolationWindow   = time.Minute      // 超限次数统计窗口
+	ipBanLevelResetTime = time.Hour * 24   // 封禁结束后超过该时间未再次封禁则重置封禁等级
+	ipStateIdleTime     = time.Minute      // 无连接的ip状态保留时间
+	ipStateCleanTime    = time.Second * 60 // ip状态清理间隔
+)
+
+// IpLimitResult ip限制检查结果
+type IpLimitResult uint8
+
+const (
+	IpLimitOk               = IpLimitResult(iota)
+	IpLimitBan              // ip已被封禁
+	IpLimitConnNum          // 单个ip连接数超限
+	IpLimitConnEstRate      // 全局连接建立频率超限
+	IpLimitIpConnEstRate    // 单个ip连接建立频率超限
+	IpLimitRecvPacketRate   // 单个连接上行包频率超限
+	IpLimitIpRecvPacketRate // 单个ip上行包频率超限
+)
+
+var ipLimitResultNameMap = map[IpLimitResult]string{
+	IpLimitOk:               "Ok",
+	IpLimitBan:              "Ban",
+	IpLimitConnNum:          "ConnNum",
+	IpLimitConnEstRate:      "ConnEstRate",
+	IpLimitIpConnEstRate:    "IpConnEstRate",
+	IpLimitRecvPacketRate:   "RecvPacketRate",
+	IpLimitIpRecvPacketRate: "IpRecvPacketRate",
+}
+
+func (r IpLimitResult) String() string {
+	return ipLimitResultNameMap[r]
+}
+
+// IpLimitConfig ip限制配置
+type IpLimitConfig struct {
+	MaxConnNum       int32
+	MaxConnNumPerIp  int32
+	ConnEstRate      int32
+	IpConnEstRate    int32
+	RecvPacketRate   int32
+	IpRecvPacketRate int32
+	ViolationLimit   int32
+	BanTime          int32
+	BanMaxTime       int32
+	ExemptList       []string
+}
+
+func getConfigValue(value int32, defaultValue int32) int32 {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// NewIpLimitConfig 读取网关配置 未配置的使用默认值
+func NewIpLimitConfig() *IpLimitConfig {
+	hk4e := config.GetConfig().Hk4e
+	cfg := &IpLimitConfig{
+		MaxConnNum:       getConfigValue(hk4e.GateMaxConnNum, MaxClientConnNumLimitDefault),
+		MaxConnNumPerIp:  getConfigValue(hk4e.GateMaxConnNumPerIp, MaxConnNumPerIpDefault),
+		ConnEstRate:      getConfigValue(hk4e.GateConnEstRate, ConnEstFreqLimitDefault),
+		IpConnEstRate:    getConfigValue(hk4e.GateIpConnEstRate, IpConnEstFreqLimitDefault),
+		RecvPacketRate:   getConfigValue(hk4e.GateRecvPacketRate, RecvPacketFreqLimitDefault),
+		IpRecvPacketRate: getConfigValue(hk4e.GateIpRecvPacketRate, IpRecvPacketFreqLimitDefault),
+		ViolationLimit:   getConfigValue(hk4e.GateIpViolationLimit, IpViolationLimitDefault),
+		BanTime:          getConfigValue(hk4e.GateIpBanTime, IpBanTimeDefault),
+		BanMaxTime:       getConfigValue(hk4e.GateIpBanMaxTime, IpBanMaxTimeDefault),
+		ExemptList:       make([]string, 0),
+	}
+	for _, ip := range strings.Split(hk4e.GateIpExemptList, ",") {
+		ip = strings.TrimSpace(ip)
+		if ip != "" {
+			cfg.ExemptList = append(cfg.ExemptList, ip)
+		}
+	}
+	return cfg
+}
+
+// TokenBucket 令牌桶 容量为每秒速率 非并发安全
+type TokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate int32, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   now,
+	}
+}
+
+// Allow 尝试取出一个令牌
+func (b *TokenBucket) Allow(now time.Time) bool {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// ipState 单个ip的限制状态
+type ipState struct {
+	connNum        int32
+	connEstBucket  *TokenBucket
+	recvBucket     *TokenBucket
+	violationCount int32
+	violationTime  time.Time
+	banLevel       int32
+	banEndTime     time.Time
+	activeTime     time.Time
+}
+
+// IpBanInfo ip封禁信息
+type IpBanInfo struct {
+	IpAddr  string
+	EndTime int64
+	Level   int32
+}
+
+// IpLimiter 网关ip限制器 并发安全
+type IpLimiter struct {
+	lock          sync.Mutex
+	cfg           *IpLimitConfig
+	connEstBucket *TokenBucket
+	ipStateMap    map[string]*ipState
+	exemptMap     map[string]bool
+}
+
+func NewIpLimiter(cfg *IpLimitConfig, now time.Time) *IpLimiter {
+	r := new(IpLimiter)
+	r.cfg = cfg
+	r.connEstBucket = NewTokenBucket(cfg.ConnEstRate, now)
+	r.ipStateMap = make(map[string]*ipState)
+	r.exemptMap = make(map[string]bool)
+	for _, ip := range cfg.ExemptList {
+		r.exemptMap[ip] = true
+	}
+	return r
+}
+
+func (l *IpLimiter) GetConfig() *IpLimitConfig {
+	return l.cfg
+}
+
+func (l *IpLimiter) getIpState(ip string, now time.Time) *ipState {
+	state, exist := l.ipStateMap[ip]
+	if !exist {
+		state = &ipState{
+			connEstBucket: NewTokenBucket(l.cfg.IpConnEstRate, now),
+			recvBucket:    NewTokenBucket(l.cfg.IpRecvPacketRate, now),
+		}
+		l.ipStateMap[ip] = state
+	}
+	state.activeTime = now
+	return state
+}
+
+// ban 封禁ip 时长为0时按封禁等级计算
+func (l *IpLimiter) ban(state *ipState, now time.Time, banTime time.Duration) {
+	if state.banLevel > 0 && now.Sub(state.banEndTime) > ipBanLevelResetTime {
+		state.banLevel = 0
+	}
+	state.banLevel++
+	if banTime == 0 {
+		banTime = time.Duration(l.cfg.BanTime) * time.Second
+		maxBanTime := time.Duration(l.cfg.BanMaxTime) * time.Second
+		for i := int32(1); i < state.banLevel && banTime < maxBanTime; i++ {
+			banTime *= 2
+		}
+		if banTime > maxBanTime {
+			banTime = maxBanTime
+		}
+	}
+	state.banEndTime = now.Add(banTime)
+	state.violationCount = 0
+}
+
+// violation 记录一次超限 返回是否触发封禁
+func (l *IpLimiter) violation(state *ipState, now time.Time) bool {
+	if now.Sub(state.violationTime) > ipViolationWindow {
+		state.violationCount = 0
+		state.violationTime = now
+	}
+	state.violationCount++
+	if state.violationCount < l.cfg.ViolationLimit {
+		return false
+	}
+	l.ban(state, now, 0)
+	return true
+}
+
+// OnConnEst 新连接建立 返回IpLimitOk时计入该ip的连接数 连接关闭时需要调用OnConnClose
+func (l *IpLimiter) OnConnEst(ip string, now time.Time) IpLimitResult {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state := l.getIpState(ip, now)
+	if !l.exemptMap[ip] {
+		if now.Before(state.banEndTime) {
+			return IpLimitBan
+		}
+		if state.connNum >= l.cfg.MaxConnNumPerIp {
+			if l.violation(state, now) {
+				return IpLimitBan
+			}
+			return IpLimitConnNum
+		}
+		if !state.connEstBucket.Allow(now) {
+			if l.violation(state, now) {
+				return IpLimitBan
+			}
+			return IpLimitIpConnEstRate
+		}
+		if !l.connEstBucket.Allow(now) {
+			return IpLimitConnEstRate
+		}
+	}
+	state.connNum++
+	return IpLimitOk
+}
+
+// OnConnClose 连接关闭
+func (l *IpLimiter) OnConnClose(ip string, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state, exist := l.ipStateMap[ip]
+	if !exist || state.connNum <= 0 {
+		return
+	}
+	state.connNum--
+	state.activeTime = now
+}
+
+// OnRecvPacket 收到客户端上行包 sessionBucket为该连接的令牌桶
+func (l *IpLimiter) OnRecvPacket(ip string, sessionBucket *TokenBucket, now time.Time) IpLimitResult {
+	sessionAllow := sessionBucket.Allow(now)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.exemptMap[ip] {
+		return IpLimitOk
+	}
+	state := l.getIpState(ip, now)
+	if now.Before(state.banEndTime) {
+		return IpLimitBan
+	}
+	result := IpLimitOk
+	if !sessionAllow {
+		result = IpLimitRecvPacketRate
+	} else if !state.recvBucket.Allow(now) {
+		result = IpLimitIpRecvPacketRate
+	}
+	if result != IpLimitOk && l.violation(state, now) {
+		return IpLimitBan
+	}
+	return result
+}
+
+// GetInfo 获取限制配置与当前状态
+func (l *IpLimiter) GetInfo(now time.Time) *mq.GateIpLimitInfo {
+	info := &mq.GateIpLimitInfo{
+		MaxConnNum:       l.cfg.MaxConnNum,
+		MaxConnNumPerIp:  l.cfg.MaxConnNumPerIp,
+		ConnEstRate:      l.cfg.ConnEstRate,
+		IpConnEstRate:    l.cfg.IpConnEstRate,
+		RecvPacketRate:   l.cfg.RecvPacketRate,
+		IpRecvPacketRate: l.cfg.IpRecvPacketRate,
+		ViolationLimit:   l.cfg.ViolationLimit,
+		BanTime:          l.cfg.BanTime,
+		BanMaxTime:       l.cfg.BanMaxTime,
+		ExemptList:       l.GetExemptList(),
+		BanList:          make([]*mq.GateIpBanInfo, 0),
+		IpConnNumMap:     l.GetIpConnNumMap(),
+	}
+	for _, banInfo := range l.GetBanList(now) {
+		info.BanList = append(info.BanList, &mq.GateIpBanInfo{IpAddr: banInfo.IpAddr, EndTime: banInfo.EndTime, Level: banInfo.Level})
+	}
+	return info
+}
+
+// IsBan ip是否被封禁
+func (l *IpLimiter) IsBan(ip string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state, exist := l.ipStateMap[ip]
+	if !exist || l.exemptMap[ip] {
+		return false
+	}
+	return now.Before(state.banEndTime)
+}
+
+// Ban 手动封禁ip 时长为0时按封禁等级计算
+func (l *IpLimiter) Ban(ip string, banTime time.Duration, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ban(l.getIpState(ip, now), now, banTime)
+}
+
+// Unban 解封ip并重置封禁等级
+func (l *IpLimiter) Unban(ip string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state, exist := l.ipStateMap[ip]
+	if !exist {
+		return
+	}
+	state.banEndTime = time.Time{}
+	state.banLevel = 0
+	state.violationCount = 0
+}
+
+// SetExempt 添加或删除豁免ip
+func (l *IpLimiter) SetExempt(ip string, exempt bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if exempt {
+		l.exemptMap[ip] = true
+	} else {
+		delete(l.exemptMap, ip)
+	}
+}
+
+// GetExemptList 获取豁免ip列表
+func (l *IpLimiter) GetExemptList() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	exemptList := make([]string, 0, len(l.exemptMap))
+	for ip := range l.exemptMap {
+		exemptList = append(exemptList, ip)
+	}
+	sort.Strings(exemptList)
+	return exemptList
+}
+
+// GetBanList 获取封禁中的ip列表
+func (l *IpLimiter) GetBanList(now time.Time) []*IpBanInfo {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	banList := make([]*IpBanInfo, 0)
+	for ip, state := range l.ipStateMap {
+		if now.Before(state.banEndTime) {
+			banList = append(banList, &IpBanInfo{IpAddr: ip, EndTime: state.banEndTime.Unix(), Level: state.banLevel})
+		}
+	}
+	sort.Slice(banList, func(i, j int) bool {
+		return banList[i].IpAddr < banList[j].IpAddr
+	})
+	return banList
+}
+
+// GetIpConnNumMap 获取每个ip的当前连接数
+func (l *IpLimiter) GetIpConnNumMap() map[string]int32 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	ipConnNumMap := make(map[string]int32)
+	for ip, state := range l.ipStateMap {
+		if state.connNum > 0 {
+			ipConnNumMap[ip] = state.connNum
+		}
+	}
+	return ipConnNumMap
+}
+
+// Clean 清理长时间无连接且没有封禁记录的ip状态
+func (l *IpLimiter) Clean(now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for ip, state := range l.ipStateMap {
+		if state.connNum > 0 || now.Sub(state.activeTime) < ipStateIdleTime {
+			continue
+		}
+		if state.banLevel > 0 && now.Sub(state.banEndTime) < ipBanLevelResetTime {
+			continue
+		}
+		delete(l.ipStateMap, ip)
+	}
+}
+
+// gateIpLimitReqHandle 处理gm服务器的ip限制查询与修改请求 并返回修改后的状态
+func (c *ConnManager) gateIpLimitReqHandle(netMsg *mq.NetMsg) {
+	req := netMsg.ServerMsg.GateIpLimitReq
+	if req == nil {
+		return
+	}
+	now := time.Now()
+	switch req.Op {
+	case mq.GateIpLimitOpBan:
+		logger.Warn("gm ban ip, ip: %v, time: %v", req.IpAddr, req.BanTime)
+		c.ipLimiter.Ban(req.IpAddr, time.Duration(req.BanTime)*time.Second, now)
+		// 在转发协程内关闭连接会阻塞会话删除 异步执行
+		go c.kickIp(req.IpAddr)
+	case mq.GateIpLimitOpUnban:
+		logger.Warn("gm unban ip, ip: %v", req.IpAddr)
+		c.ipLimiter.Unban(req.IpAddr)
+	case mq.GateIpLimitOpExemptAdd:
+		logger.Warn("gm add exempt ip, ip: %v", req.IpAddr)
+		c.ipLimiter.SetExempt(req.IpAddr, true)
+	case mq.GateIpLimitOpExemptDel:
+		logger.Warn("gm del exempt ip, ip: %v", req.IpAddr)
+		c.ipLimiter.SetExempt(req.IpAddr, false)
+	}
+	info := c.ipLimiter.GetInfo(now)
+	info.ReqId = req.ReqId
+	c.messageQueue.SendToGm(netMsg.OriginServerAppId, &mq.NetMsg{
+		MsgType:   mq.MsgTypeServer,
+		EventId:   mq.ServerGateIpLimitRsp,
+		ServerMsg: &mq.ServerMsg{GateIpLimitInfo: info},
+	})
+}
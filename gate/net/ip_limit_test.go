@@ -0,0 +1,162 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+)
+
+func newTestIpLimiter() *IpLimiter {
+	return NewIpLimiter(&IpLimitConfig{
+		MaxConnNum:       100,
+		MaxConnNumPerIp:  2,
+		ConnEstRate:      100,
+		IpConnEstRate:    3,
+		RecvPacketRate:   10,
+		IpRecvPacketRate: 15,
+		ViolationLimit:   3,
+		BanTime:          60,
+		BanMaxTime:       150,
+		ExemptList:       []string{"10.0.0.1"},
+	}, time.Unix(0, 0))
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := NewTokenBucket(2, now)
+	if !bucket.Allow(now) || !bucket.Allow(now) || bucket.Allow(now) {
+		t.Fatal("bucket burst error")
+	}
+	now = now.Add(time.Millisecond * 500)
+	if !bucket.Allow(now) || bucket.Allow(now) {
+		t.Fatal("bucket refill error")
+	}
+	// 令牌数不超过容量
+	now = now.Add(time.Hour)
+	if !bucket.Allow(now) || !bucket.Allow(now) || bucket.Allow(now) {
+		t.Fatal("bucket capacity error")
+	}
+}
+
+func TestIpLimiterConn(t *testing.T) {
+	l := newTestIpLimiter()
+	now := time.Unix(100, 0)
+	ip := "1.1.1.1"
+	if l.OnConnEst(ip, now) != IpLimitOk || l.OnConnEst(ip, now) != IpLimitOk {
+		t.Fatal("conn est should ok")
+	}
+	if l.OnConnEst(ip, now) != IpLimitConnNum {
+		t.Fatal("conn num per ip should limit")
+	}
+	// 其他ip不受影响
+	if l.OnConnEst("2.2.2.2", now) != IpLimitOk {
+		t.Fatal("other ip should not limit")
+	}
+	l.OnConnClose(ip, now)
+	if l.OnConnEst(ip, now) != IpLimitOk {
+		t.Fatal("conn est should ok after close")
+	}
+	l.OnConnClose(ip, now)
+	l.OnConnClose(ip, now)
+	// 连接建立频率限制
+	if l.OnConnEst(ip, now) != IpLimitIpConnEstRate {
+		t.Fatal("conn est rate should limit")
+	}
+	if l.GetIpConnNumMap()[ip] != 0 || l.GetIpConnNumMap()["2.2.2.2"] != 1 {
+		t.Fatalf("ip conn num error: %v", l.GetIpConnNumMap())
+	}
+	// 豁免ip不受限制
+	for i := 0; i < 10; i++ {
+		if l.OnConnEst("10.0.0.1", now) != IpLimitOk {
+			t.Fatal("exempt ip should not limit")
+		}
+	}
+}
+
+func TestIpLimiterBan(t *testing.T) {
+	l := newTestIpLimiter()
+	now := time.Unix(100, 0)
+	ip := "1.1.1.1"
+	bucket := NewTokenBucket(l.GetConfig().RecvPacketRate, now)
+	for i := 0; i < 10; i++ {
+		if l.OnRecvPacket(ip, bucket, now) != IpLimitOk {
+			t.Fatal("recv packet should ok")
+		}
+	}
+	if l.OnRecvPacket(ip, bucket, now) != IpLimitRecvPacketRate {
+		t.Fatal("session recv packet rate should limit")
+	}
+	// 同一个ip的多个连接共享ip令牌桶
+	otherBucket := NewTokenBucket(l.GetConfig().RecvPacketRate, now)
+	for i := 0; i < 5; i++ {
+		if l.OnRecvPacket(ip, otherBucket, now) != IpLimitOk {
+			t.Fatal("recv packet should ok")
+		}
+	}
+	if l.OnRecvPacket(ip, otherBucket, now) != IpLimitIpRecvPacketRate {
+		t.Fatal("ip recv packet rate should limit")
+	}
+	if l.OnRecvPacket(ip, otherBucket, now) != IpLimitBan {
+		t.Fatal("ip should be ban after violation limit")
+	}
+	if !l.IsBan(ip, now) || l.OnConnEst(ip, now) != IpLimitBan {
+		t.Fatal("ban ip should not conn")
+	}
+	banList := l.GetBanList(now)
+	if len(banList) != 1 || banList[0].EndTime != now.Unix()+60 || banList[0].Level != 1 {
+		t.Fatalf("ban info error: %+v", banList[0])
+	}
+	// 再次封禁时长翻倍 不超过最大封禁时长
+	now = now.Add(time.Second * 61)
+	l.Ban(ip, 0, now)
+	if l.GetBanList(now)[0].EndTime != now.Unix()+120 {
+		t.Fatal("second ban time should double")
+	}
+	now = now.Add(time.Second * 121)
+	l.Ban(ip, 0, now)
+	if l.GetBanList(now)[0].EndTime != now.Unix()+150 {
+		t.Fatal("ban time should not exceed max")
+	}
+	// 封禁结束后长时间未再犯则重置封禁等级
+	now = now.Add(time.Second*150 + ipBanLevelResetTime + time.Second)
+	l.Ban(ip, 0, now)
+	if l.GetBanList(now)[0].Level != 1 {
+		t.Fatal("ban level should reset")
+	}
+	l.Unban(ip)
+	if l.IsBan(ip, now) {
+		t.Fatal("ip should be unban")
+	}
+	// 豁免后不受封禁影响
+	l.Ban(ip, time.Hour, now)
+	l.SetExempt(ip, true)
+	if l.OnConnEst(ip, now) != IpLimitOk {
+		t.Fatal("exempt ip should not be ban")
+	}
+	l.OnConnClose(ip, now)
+	l.SetExempt(ip, false)
+	if l.OnConnEst(ip, now) != IpLimitBan {
+		t.Fatal("ip should be ban after exempt del")
+	}
+	// 清理无连接且已过期的ip状态
+	l.Unban(ip)
+	l.Clean(now.Add(ipStateIdleTime + time.Second))
+	if len(l.ipStateMap) != 0 {
+		t.Fatalf("ip state not clean: %v", len(l.ipStateMap))
+	}
+}
+
+func TestNewIpLimitConfig(t *testing.T) {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{GateMaxConnNumPerIp: 3, GateIpExemptList: "127.0.0.1, 10.0.0.1,"}}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	cfg := NewIpLimitConfig()
+	if cfg.MaxConnNumPerIp != 3 || cfg.MaxConnNum != MaxClientConnNumLimitDefault || cfg.RecvPacketRate != RecvPacketFreqLimitDefault {
+		t.Fatalf("config default value error: %+v", cfg)
+	}
+	if len(cfg.ExemptList) != 2 || cfg.ExemptList[1] != "10.0.0.1" {
+		t.Fatalf("exempt list parse error: %v", cfg.ExemptList)
+	}
+}
@@ -0,0 +1,76 @@
+package net
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"hk4e/pkg/metrics"
+
+	"github.com/flswld/halo/protocol/kcp"
+)
+
+// 网关监控指标
+
+var (
+	metricsKickConn = metrics.NewCounter("gate_kick_conn_total", "gate closed connections by enet reason", "reason")
+	metricsKcpSnmp  = metrics.NewCounter("gate_kcp_snmp_total", "gate kcp and udp snmp counters", "name")
+	metricsIpLimit  = metrics.NewCounter("gate_ip_limit_total", "gate rejected connections and packets by ip limit result", "result")
+)
+
+func init() {
+	metrics.NewGaugeFunc("gate_session_num", "gate current client session num", func() float64 {
+		return float64(atomic.LoadInt32(&CLIENT_CONN_NUM))
+	})
+}
+
+var enetTypeNameMap = map[uint32]string{
+	kcp.EnetTimeout:               "Timeout",
+	kcp.EnetClientClose:           "ClientClose",
+	kcp.EnetClientRebindFail:      "ClientRebindFail",
+	kcp.EnetClientShutdown:        "ClientShutdown",
+	kcp.EnetServerRelogin:         "ServerRelogin",
+	kcp.EnetServerKick:            "ServerKick",
+	kcp.EnetServerShutdown:        "ServerShutdown",
+	kcp.EnetNotFoundSession:       "NotFoundSession",
+	kcp.EnetLoginUnfinished:       "LoginUnfinished",
+	kcp.EnetPacketFreqTooHigh:     "PacketFreqTooHigh",
+	kcp.EnetPingTimeout:           "PingTimeout",
+	kcp.EnetTransferFailed:        "TransferFailed",
+	kcp.EnetServerKillClient:      "ServerKillClient",
+	kcp.EnetCheckMoveSpeed:        "CheckMoveSpeed",
+	kcp.EnetAccountPasswordChange: "AccountPasswordChange",
+	kcp.EnetSecurityKick:          "SecurityKick",
+	kcp.EnetLuaShellTimeout:       "LuaShellTimeout",
+	kcp.EnetSDKFailKick:           "SDKFailKick",
+	kcp.EnetPacketCostTime:        "PacketCostTime",
+	kcp.EnetPacketUnionFreq:       "PacketUnionFreq",
+	kcp.EnetWaitSndMax:            "WaitSndMax",
+}
+
+func getEnetTypeName(enetType uint32) string {
+	name, exist := enetTypeNameMap[enetType]
+	if !exist {
+		return strconv.Itoa(int(enetType))
+	}
+	return name
+}
+
+// addKcpSnmpMetrics 将一个周期内的snmp增量累加到监控指标
+// 每个周期都会重置snmp 所以CurrEstab实际为周期内新建立的连接数
+func addKcpSnmpMetrics(snmp *kcp.Snmp) {
+	metricsKcpSnmp.Add(float64(snmp.BytesSent), "BytesSent")
+	metricsKcpSnmp.Add(float64(snmp.BytesReceived), "BytesReceived")
+	metricsKcpSnmp.Add(float64(snmp.CurrEstab), "NewEstab")
+	metricsKcpSnmp.Add(float64(snmp.KCPInErrors), "KCPInErrors")
+	metricsKcpSnmp.Add(float64(snmp.InPkts), "InPkts")
+	metricsKcpSnmp.Add(float64(snmp.OutPkts), "OutPkts")
+	metricsKcpSnmp.Add(float64(snmp.InSegs), "InSegs")
+	metricsKcpSnmp.Add(float64(snmp.OutSegs), "OutSegs")
+	metricsKcpSnmp.Add(float64(snmp.InBytes), "InBytes")
+	metricsKcpSnmp.Add(float64(snmp.OutBytes), "OutBytes")
+	metricsKcpSnmp.Add(float64(snmp.RetransSegs), "RetransSegs")
+	metricsKcpSnmp.Add(float64(snmp.FastRetransSegs), "FastRetransSegs")
+	metricsKcpSnmp.Add(float64(snmp.EarlyRetransSegs), "EarlyRetransSegs")
+	metricsKcpSnmp.Add(float64(snmp.LostSegs), "LostSegs")
+	metricsKcpSnmp.Add(float64(snmp.RepeatSegs), "RepeatSegs")
+}
@@ -23,6 +23,7 @@ import (
 	"hk4e/pkg/endec"
 	"hk4e/pkg/httpclient"
 	"hk4e/pkg/random"
+	"hk4e/pkg/trace"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -79,12 +80,15 @@ func (c *ConnManager) forwardClientMsgToServerHandle(protoMsg *ProtoMsg, session
 		req := protoMsg.PayloadMessage.(*proto.PlayerLoginReq)
 		req.TargetUid = 0
 		req.TargetHomeOwnerUid = 0
+		span := c.startForwardSpan(protoMsg, session)
+		defer span.End()
 		gameMsg := &mq.GameMsg{
 			UserId:         session.userId,
 			CmdId:          protoMsg.CmdId,
 			ClientSeq:      protoMsg.HeadMessage.ClientSequenceId,
 			PayloadMessage: req,
 		}
+		gameMsg.SetTrace(span.Context())
 		// 转发到GS
 		c.messageQueue.SendToGs(session.gsServerAppId, &mq.NetMsg{
 			MsgType: mq.MsgTypeGame,
@@ -97,12 +101,15 @@ func (c *ConnManager) forwardClientMsgToServerHandle(protoMsg *ProtoMsg, session
 				protoMsg.CmdId, session.userId, protoMsg.SessionId)
 			return
 		}
+		span := c.startForwardSpan(protoMsg, session)
+		defer span.End()
 		gameMsg := &mq.GameMsg{
 			UserId:             session.userId,
 			CmdId:              protoMsg.CmdId,
 			ClientSeq:          protoMsg.HeadMessage.ClientSequenceId,
 			PayloadMessageData: nil,
 		}
+		gameMsg.SetTrace(span.Context())
 		// 在这里直接序列化成二进制数据 终结PayloadMessage的生命周期并回收进缓存池
 		payloadMessageData, err := pb.Marshal(protoMsg.PayloadMessage)
 		if err != nil {
@@ -146,6 +153,19 @@ func (c *ConnManager) forwardClientMsgToServerHandle(protoMsg *ProtoMsg, session
 	}
 }
 
+// startForwardSpan 按玩家uid采样 开始客户端消息转发的链路 未采样时返回nil
+func (c *ConnManager) startForwardSpan(protoMsg *ProtoMsg, session *Session) *trace.Span {
+	if !trace.ShouldSample(session.userId) {
+		return nil
+	}
+	span := trace.StartSpan("gate.forward."+c.serverCmdProtoMap.GetCmdNameByCmdId(protoMsg.CmdId), trace.SpanKindProducer, trace.SpanContext{})
+	span.SetAttr("uid", session.userId)
+	span.SetAttr("cmd_id", protoMsg.CmdId)
+	span.SetAttr("session_id", session.sessionId)
+	span.SetAttr("gs_appid", session.gsServerAppId)
+	return span
+}
+
 // 转发其他服务器的消息到客户端 所有连接共享一个协程
 func (c *ConnManager) forwardServerMsgToClientHandle() {
 	logger.Debug("server msg forward handle start")
@@ -186,6 +206,12 @@ func (c *ConnManager) gameMsgHandle(
 	gameMsg := netMsg.GameMsg
 	switch netMsg.EventId {
 	case mq.NormalMsg:
+		if parent := netMsg.GetTrace(); parent.IsValid() {
+			span := trace.StartSpan("gate.send."+c.serverCmdProtoMap.GetCmdNameByCmdId(gameMsg.CmdId), trace.SpanKindConsumer, parent)
+			span.SetAttr("uid", gameMsg.UserId)
+			span.SetAttr("origin_appid", netMsg.OriginServerAppId)
+			defer span.End()
+		}
 		// 分发到每个连接具体的发送协程
 		sessionId, exist := userIdSessionIdMap[gameMsg.UserId]
 		if !exist {
@@ -257,6 +283,10 @@ func (c *ConnManager) serverMsgHandle(
 ) {
 	serverMsg := netMsg.ServerMsg
 	switch netMsg.EventId {
+	case mq.ServerGateIpLimitReq:
+		c.gateIpLimitReqHandle(netMsg)
+	case mq.ServerGateCaptureReq:
+		c.gateCaptureReqHandle(netMsg)
 	case mq.ServerUserGsChangeNotify:
 		sessionId, exist := userIdSessionIdMap[serverMsg.UserId]
 		if !exist {
@@ -412,7 +442,7 @@ func (c *ConnManager) doGateLogin(req *proto.GetPlayerTokenReq, session *Session
 		}
 	}
 	clientConnNum := atomic.LoadInt32(&CLIENT_CONN_NUM)
-	if clientConnNum > MaxClientConnNumLimit {
+	if clientConnNum > c.ipLimiter.GetConfig().MaxConnNum {
 		logger.Error("gate conn num limit, uid: %v", uid)
 		return c.loginFailRsp(uid, proto.Retcode_RET_MAX_PLAYER, false, 0)
 	}
@@ -470,6 +500,7 @@ func (c *ConnManager) doGateLogin(req *proto.GetPlayerTokenReq, session *Session
 	session.userId = uid
 	c.SetSession(session, session.sessionId, session.userId)
 	c.createSessionChan <- session
+	c.captureManager.OnLogin(session)
 	// 绑定各个服务器appid
 	if c.minLoadGsServerAppId == "" {
 		return c.loginFailRsp(0, proto.Retcode_RET_SVR_ERROR, false, 0)
@@ -105,7 +105,7 @@ type ActionData struct {
 	ModifierName                 string       `json:"modifierName"`
 	EnableLockHP                 bool         `json:"enableLockHP"`
 	DisableWhenLoading           bool         `json:"disableWhenLoading"`
-	Lethal                       bool         `json:"lethal"`
+	Lethal                       *bool        `json:"lethal"`
 	MuteHealEffect               bool         `json:"muteHealEffect"`
 	ByServer                     bool         `json:"byServer"`
 	LifeByOwnerIsAlive           bool         `json:"lifeByOwnerIsAlive"`
@@ -140,16 +140,46 @@ type ActionData struct {
 	CallParamList                []int32      `json:"callParamList"`
 	Content                      string       `json:"content"`
 	CostStaminaRatio             DynamicFloat `json:"costStaminaRatio"`
+	Value                        DynamicFloat `json:"value"`
+	UseLimitRange                bool         `json:"useLimitRange"`
+	MaxValue                     DynamicFloat `json:"maxValue"`
+	MinValue                     DynamicFloat `json:"minValue"`
+	AbilityName                  string       `json:"abilityName"`
+	State                        DynamicFloat `json:"state"`
 
 	Actions        []*ActionData `json:"actions"`
 	SuccessActions []*ActionData `json:"successActions"`
 	FailActions    []*ActionData `json:"failActions"`
 }
 
+// IsLethal 扣血是否可以致死 配置缺省时为真
+func (a *ActionData) IsLethal() bool {
+	if a.Lethal == nil {
+		return true
+	}
+	return *a.Lethal
+}
+
 type MixinData struct {
-	Type             string       `json:"$type"`
-	ModifierName     string       `json:"modifierName"`
-	CostStaminaDelta DynamicFloat `json:"costStaminaDelta"`
+	Type                  string          `json:"$type"`
+	ModifierName          string          `json:"modifierName"`
+	CostStaminaDelta      DynamicFloat    `json:"costStaminaDelta"`
+	GlobalValueKey        string          `json:"globalValueKey"`
+	RemoveAppliedModifier bool            `json:"removeAppliedModifier"`
+	ValueSteps            []DynamicFloat  `json:"valueSteps"`
+	ModifierNameSteps     StringArray     `json:"modifierNameSteps"`
+	OnShieldBroken        []*ActionData   `json:"onShieldBroken"`
+	AttackID              string          `json:"attackID"`
+	AttackInfo            *AttackInfoData `json:"attackInfo"`
+}
+
+type AttackInfoData struct {
+	AttackProperty *AttackPropertyData `json:"attackProperty"`
+}
+
+type AttackPropertyData struct {
+	DamagePercentage DynamicFloat `json:"damagePercentage"`
+	ElementType      string       `json:"elementType"`
 }
 
 type PropertyData struct {
@@ -189,9 +219,37 @@ func (m *ModifierOrderMap) GetByLocalId(modifierLocalId uint32) *ModifierData {
 	return m.Map[key]
 }
 
+// GetLocalIdByName 获取modifier的localId 即modifier名称排序后的下标
+func (m *ModifierOrderMap) GetLocalIdByName(modifierName string) (uint32, bool) {
+	for index, key := range m.KeyOrder {
+		if key == modifierName {
+			return uint32(index), true
+		}
+	}
+	return 0, false
+}
+
 type DynamicFloat interface {
 }
 
+// StringArray 字符串数组 配置中只有一个元素时可能直接写成字符串
+type StringArray []string
+
+func (a *StringArray) UnmarshalJSON(data []byte) error {
+	var str string
+	if json.Unmarshal(data, &str) == nil {
+		*a = []string{str}
+		return nil
+	}
+	var strList []string
+	err := json.Unmarshal(data, &strList)
+	if err != nil {
+		return err
+	}
+	*a = strList
+	return nil
+}
+
 func (g *GameDataConfig) loadAbilityJsonConfig() {
 	g.AbilityDataMap = make(map[string]*AbilityData)
 	g.AbilityDataHashMap = make(map[uint32]*AbilityData)
@@ -226,6 +284,7 @@ func (g *GameDataConfig) loadAbilityJsonConfigLoop(path string) {
 		err = hjson.Unmarshal(fileData, &abilityJsonConfigList)
 		if err != nil {
 			logger.Info("parse file error: %v, path: %v", err, filePath)
+			g.abilityParseErrorCount++
 			continue
 		}
 		for _, abilityJsonConfig := range abilityJsonConfigList {
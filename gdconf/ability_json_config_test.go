@@ -0,0 +1,26 @@
+package gdconf
+
+import (
+	"testing"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 当前配置中无法解析的能力配置文件数量 新增字段需要兼容配置中的动态值 不能使该数量增加
+const abilityParseErrorCountMax = 8
+
+func TestLoadAbilityJsonConfig(t *testing.T) {
+	logger.InitLogger(nil)
+	defer logger.CloseLogger()
+	g := &GameDataConfig{jsonPrefix: "./game_data_config/json/"}
+	g.loadAbilityJsonConfig()
+	if g.abilityParseErrorCount > abilityParseErrorCountMax {
+		t.Fatalf("ability parse error count increase, count: %v, max: %v", g.abilityParseErrorCount, abilityParseErrorCountMax)
+	}
+	// valueSteps中含有动态值的配置文件
+	for _, abilityName := range []string{"Weapon_Pole_NormalAttackUp", "Slime_Rock_TestTool_RockShieldDestroy"} {
+		if g.AbilityDataMap[abilityName] == nil {
+			t.Fatalf("ability not load, abilityName: %v", abilityName)
+		}
+	}
+}
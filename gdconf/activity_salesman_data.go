@@ -0,0 +1,88 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// ActivitySalesmanData 商人活动配置表
+type ActivitySalesmanData struct {
+	ScheduleId             int32      `csv:"活动排期id"`
+	DailyConfigId1         int32      `csv:"活动配置ID1,omitempty"`
+	DailyConfigId2         int32      `csv:"活动配置ID2,omitempty"`
+	DailyConfigId3         int32      `csv:"活动配置ID3,omitempty"`
+	DailyConfigId4         int32      `csv:"活动配置ID4,omitempty"`
+	DailyConfigId5         int32      `csv:"活动配置ID5,omitempty"`
+	DailyConfigId6         int32      `csv:"活动配置ID6,omitempty"`
+	DailyConfigId7         int32      `csv:"活动配置ID7,omitempty"`
+	RewardIdList           IntArray   `csv:"奖池,omitempty"`
+	SpecialRewardId        int32      `csv:"特殊奖池,omitempty"`
+	SpecialProbList        FloatArray `csv:"特殊奖池每日概率,omitempty"`
+	SpecialRewardPreviewId int32      `csv:"[特殊奖池]展示ID,omitempty"`
+	DailyConfigIdList      []int32    // 每日交付配置id列表 按活动开启天数轮换
+}
+
+// ActivitySalesmanDailyData 商人活动每日交付配置表
+type ActivitySalesmanDailyData struct {
+	DailyConfigId  int32 `csv:"活动配置ID"`
+	CostItem1Id    int32 `csv:"[所需物品]1ID,omitempty"`
+	CostItem1Count int32 `csv:"[所需物品]1数量,omitempty"`
+	CostItem2Id    int32 `csv:"[所需物品]2ID,omitempty"`
+	CostItem2Count int32 `csv:"[所需物品]2数量,omitempty"`
+	CostItem3Id    int32 `csv:"[所需物品]3ID,omitempty"`
+	CostItem3Count int32 `csv:"[所需物品]3数量,omitempty"`
+	TalkId         int32 `csv:"当日一次性对话ID,omitempty"`
+
+	CostItemMap map[uint32]uint32
+}
+
+func (g *GameDataConfig) loadActivitySalesmanData() {
+	g.SalesmanDataMap = make(map[int32]*ActivitySalesmanData)
+	salesmanDataList := make([]*ActivitySalesmanData, 0)
+	readTable[ActivitySalesmanData](g.txtPrefix+"ActivitySalesmanData.txt", &salesmanDataList)
+	for _, salesmanData := range salesmanDataList {
+		salesmanData.DailyConfigIdList = make([]int32, 0)
+		for _, dailyConfigId := range []int32{
+			salesmanData.DailyConfigId1,
+			salesmanData.DailyConfigId2,
+			salesmanData.DailyConfigId3,
+			salesmanData.DailyConfigId4,
+			salesmanData.DailyConfigId5,
+			salesmanData.DailyConfigId6,
+			salesmanData.DailyConfigId7,
+		} {
+			if dailyConfigId == 0 {
+				continue
+			}
+			salesmanData.DailyConfigIdList = append(salesmanData.DailyConfigIdList, dailyConfigId)
+		}
+		g.SalesmanDataMap[salesmanData.ScheduleId] = salesmanData
+	}
+	logger.Info("ActivitySalesmanData Count: %v", len(g.SalesmanDataMap))
+
+	g.SalesmanDailyDataMap = make(map[int32]*ActivitySalesmanDailyData)
+	salesmanDailyDataList := make([]*ActivitySalesmanDailyData, 0)
+	readTable[ActivitySalesmanDailyData](g.txtPrefix+"ActivitySalesmanDailyData.txt", &salesmanDailyDataList)
+	for _, salesmanDailyData := range salesmanDailyDataList {
+		salesmanDailyData.CostItemMap = make(map[uint32]uint32)
+		for _, costItem := range [][2]int32{
+			{salesmanDailyData.CostItem1Id, salesmanDailyData.CostItem1Count},
+			{salesmanDailyData.CostItem2Id, salesmanDailyData.CostItem2Count},
+			{salesmanDailyData.CostItem3Id, salesmanDailyData.CostItem3Count},
+		} {
+			if costItem[0] == 0 || costItem[1] == 0 {
+				continue
+			}
+			salesmanDailyData.CostItemMap[uint32(costItem[0])] += uint32(costItem[1])
+		}
+		g.SalesmanDailyDataMap[salesmanDailyData.DailyConfigId] = salesmanDailyData
+	}
+	logger.Info("ActivitySalesmanDailyData Count: %v", len(g.SalesmanDailyDataMap))
+}
+
+func GetActivitySalesmanDataByScheduleId(scheduleId int32) *ActivitySalesmanData {
+	return CONF.SalesmanDataMap[scheduleId]
+}
+
+func GetActivitySalesmanDailyDataById(dailyConfigId int32) *ActivitySalesmanDailyData {
+	return CONF.SalesmanDailyDataMap[dailyConfigId]
+}
@@ -0,0 +1,42 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// AnimalCodexData 生物志图鉴配置表
+type AnimalCodexData struct {
+	CodexId      int32 `csv:"图鉴ID"`
+	Type         int32 `csv:"类型,omitempty"`
+	DescribeId   int32 `csv:"怪物ID,omitempty"`
+	IsSeenActive int32 `csv:"见到即解锁,omitempty"`
+	IsDisuse     int32 `csv:"已废弃,omitempty"`
+	CountType    int32 `csv:"统计类型,omitempty"`
+}
+
+func (g *GameDataConfig) loadAnimalCodexData() {
+	g.AnimalCodexDataMap = make(map[int32]*AnimalCodexData)
+	g.AnimalCodexDescribeIdMap = make(map[int32]*AnimalCodexData)
+	animalCodexDataList := make([]*AnimalCodexData, 0)
+	readTable[AnimalCodexData](g.txtPrefix+"AnimalCodexData.txt", &animalCodexDataList)
+	for _, animalCodexData := range animalCodexDataList {
+		if animalCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.AnimalCodexDataMap[animalCodexData.CodexId] = animalCodexData
+		g.AnimalCodexDescribeIdMap[animalCodexData.DescribeId] = animalCodexData
+	}
+	logger.Info("AnimalCodexData Count: %v", len(g.AnimalCodexDataMap))
+}
+
+func GetAnimalCodexDataById(codexId int32) *AnimalCodexData {
+	return CONF.AnimalCodexDataMap[codexId]
+}
+
+func GetAnimalCodexDataByDescribeId(describeId int32) *AnimalCodexData {
+	return CONF.AnimalCodexDescribeIdMap[describeId]
+}
+
+func GetAnimalCodexDataMap() map[int32]*AnimalCodexData {
+	return CONF.AnimalCodexDataMap
+}
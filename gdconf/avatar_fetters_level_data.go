@@ -0,0 +1,40 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// AvatarFettersLevelData 角色好感度等级配置表
+type AvatarFettersLevelData struct {
+	Level int32 `csv:"羁绊等级"`
+	Exp   int32 `csv:"升到下一级所需经验,omitempty"`
+}
+
+func (g *GameDataConfig) loadAvatarFettersLevelData() {
+	g.AvatarFettersLevelDataMap = make(map[int32]*AvatarFettersLevelData)
+	avatarFettersLevelDataList := make([]*AvatarFettersLevelData, 0)
+	readTable[AvatarFettersLevelData](g.txtPrefix+"AvatarFettersLevelData.txt", &avatarFettersLevelDataList)
+	for _, avatarFettersLevelData := range avatarFettersLevelDataList {
+		g.AvatarFettersLevelDataMap[avatarFettersLevelData.Level] = avatarFettersLevelData
+	}
+	logger.Info("AvatarFettersLevelData Count: %v", len(g.AvatarFettersLevelDataMap))
+}
+
+func GetAvatarFettersLevelDataByLevel(level int32) *AvatarFettersLevelData {
+	return CONF.AvatarFettersLevelDataMap[level]
+}
+
+// GetAvatarFettersMaxLevel 获取好感度最大等级
+func GetAvatarFettersMaxLevel() int32 {
+	maxLevel := int32(0)
+	for level := range CONF.AvatarFettersLevelDataMap {
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+	return maxLevel
+}
+
+func GetAvatarFettersLevelDataMap() map[int32]*AvatarFettersLevelData {
+	return CONF.AvatarFettersLevelDataMap
+}
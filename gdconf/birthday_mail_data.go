@@ -0,0 +1,41 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// BirthdayMailData 生日邮件配置表
+type BirthdayMailData struct {
+	Id            int32 `csv:"ID"`
+	MailId        int32 `csv:"MailID,omitempty"`
+	RewardId      int32 `csv:"RewardId,omitempty"`
+	EffectiveTime int64 `csv:"生效时间戳,omitempty"`
+}
+
+func (g *GameDataConfig) loadBirthdayMailData() {
+	g.BirthdayMailDataMap = make(map[int32]*BirthdayMailData)
+	birthdayMailDataList := make([]*BirthdayMailData, 0)
+	readTable[BirthdayMailData](g.txtPrefix+"BirthdayMailData.txt", &birthdayMailDataList)
+	for _, birthdayMailData := range birthdayMailDataList {
+		g.BirthdayMailDataMap[birthdayMailData.Id] = birthdayMailData
+	}
+	logger.Info("BirthdayMailData Count: %v", len(g.BirthdayMailDataMap))
+}
+
+// GetBirthdayMailDataByTime 获取某个时间点生效的生日邮件配置 取生效时间最晚的一个
+func GetBirthdayMailDataByTime(now int64) *BirthdayMailData {
+	var ret *BirthdayMailData = nil
+	for _, birthdayMailData := range CONF.BirthdayMailDataMap {
+		if birthdayMailData.EffectiveTime > now {
+			continue
+		}
+		if ret == nil || birthdayMailData.EffectiveTime > ret.EffectiveTime {
+			ret = birthdayMailData
+		}
+	}
+	return ret
+}
+
+func GetBirthdayMailDataMap() map[int32]*BirthdayMailData {
+	return CONF.BirthdayMailDataMap
+}
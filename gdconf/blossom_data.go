@@ -0,0 +1,216 @@
+package gdconf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flswld/halo/logger"
+)
+
+// BlossomGroupsData 循环营地点配置表
+type BlossomGroupsData struct {
+	CampId             int32    `csv:"ID"`
+	CityId             int32    `csv:"城市ID,omitempty"`
+	SectionId          int32    `csv:"区域ID,omitempty"`
+	RefreshTypeList    IntArray `csv:"刷新类型,omitempty"`
+	RefreshGroupId     int32    `csv:"刷新group,omitempty"`
+	DecorateGroupId    int32    `csv:"装饰group,omitempty"`
+	NextCampIdList     IntArray `csv:"下游点,omitempty"`
+	IsGuarantee        int32    `csv:"是否保底,omitempty"`
+	IsInitialRefresh   int32    `csv:"是否初始刷新,omitempty"`
+	FinishProgress     int32    `csv:"完成进度,omitempty"`
+	UnlockPlayerLevel  int32    `csv:"解锁冒险等级,omitempty"`
+	DelayUnloadSeconds int32    `csv:"延迟卸载秒数,omitempty"`
+}
+
+// BlossomRefreshData 循环营地刷新配置表
+type BlossomRefreshData struct {
+	RefreshId        int32                 `csv:"ID"`
+	CityId           int32                 `csv:"城市ID,omitempty"`
+	RefreshType      int32                 `csv:"循环营地刷新类型,omitempty"`
+	RefreshCount     int32                 `csv:"循环营地刷新数量,omitempty"`
+	RefreshTimeStr   string                `csv:"循环营地刷新时间,omitempty"`
+	Cond1Type        int32                 `csv:"[刷新条件]1类型,omitempty"`
+	Cond1Param       string                `csv:"[刷新条件]1参数,omitempty"`
+	Cond2Type        int32                 `csv:"[刷新条件]2类型,omitempty"`
+	Cond2Param       string                `csv:"[刷新条件]2参数,omitempty"`
+	Cond3Type        int32                 `csv:"[刷新条件]3类型,omitempty"`
+	Cond3Param       string                `csv:"[刷新条件]3参数,omitempty"`
+	ReviseLevel      int32                 `csv:"修正等级,omitempty"`
+	ChestId          int32                 `csv:"宝箱索引ID,omitempty"`
+	Reward1DropId    int32                 `csv:"[奖励]1DropID,omitempty"`
+	Reward2DropId    int32                 `csv:"[奖励]2DropID,omitempty"`
+	Reward3DropId    int32                 `csv:"[奖励]3DropID,omitempty"`
+	Reward4DropId    int32                 `csv:"[奖励]4DropID,omitempty"`
+	Reward5DropId    int32                 `csv:"[奖励]5DropID,omitempty"`
+	Reward6DropId    int32                 `csv:"[奖励]6DropID,omitempty"`
+	Reward7DropId    int32                 `csv:"[奖励]7DropID,omitempty"`
+	Reward8DropId    int32                 `csv:"[奖励]8DropID,omitempty"`
+	Reward9DropId    int32                 `csv:"[奖励]9DropID,omitempty"`
+	RefreshSecond    int32                 // 每日刷新时间 当天的秒数
+	CondList         []*BlossomRefreshCond // 刷新条件
+	RewardDropIdList []int32               // 奖励掉落 下标为世界等级
+}
+
+// BlossomRefreshCond 循环营地刷新条件
+type BlossomRefreshCond struct {
+	Type     int32
+	ParamStr string
+	Param    IntArray
+}
+
+const (
+	BlossomRefreshCondPlayerLevel = 1 // 刷新条件 冒险等级
+)
+
+// BlossomChestData 循环营地宝箱配置表
+type BlossomChestData struct {
+	ChestId     int32 `csv:"宝箱索引ID"`
+	GadgetId    int32 `csv:"宝箱gadget_id,omitempty"`
+	WorldResin  int32 `csv:"大世界体力消耗,omitempty"`
+	ResinCost   int32 `csv:"体力消耗,omitempty"`
+	RefreshType int32 `csv:"对应营地枚举,omitempty"`
+}
+
+// BlossomOpenData 循环营地开启配置表
+type BlossomOpenData struct {
+	CityId          int32 `csv:"城市ID"`
+	OpenPlayerLevel int32 `csv:"循环营地开启等级,omitempty"`
+}
+
+// BlossomSectionOrderData 循环营地区域顺序配置表
+type BlossomSectionOrderData struct {
+	Id        int32 `csv:"ID"`
+	CityId    int32 `csv:"城市ID,omitempty"`
+	SectionId int32 `csv:"区域ID,omitempty"`
+	Order     int32 `csv:"序列,omitempty"`
+}
+
+func (g *GameDataConfig) loadBlossomData() {
+	g.BlossomGroupsDataMap = make(map[int32]*BlossomGroupsData)
+	g.BlossomGroupsDataGroupIdMap = make(map[int32]*BlossomGroupsData)
+	blossomGroupsDataList := make([]*BlossomGroupsData, 0)
+	readTable[BlossomGroupsData](g.txtPrefix+"BlossomGroupsData.txt", &blossomGroupsDataList)
+	for _, blossomGroupsData := range blossomGroupsDataList {
+		g.BlossomGroupsDataMap[blossomGroupsData.CampId] = blossomGroupsData
+		g.BlossomGroupsDataGroupIdMap[blossomGroupsData.RefreshGroupId] = blossomGroupsData
+	}
+	logger.Info("BlossomGroupsData Count: %v", len(g.BlossomGroupsDataMap))
+
+	g.BlossomRefreshDataMap = make(map[int32]*BlossomRefreshData)
+	blossomRefreshDataList := make([]*BlossomRefreshData, 0)
+	readTable[BlossomRefreshData](g.txtPrefix+"BlossomRefreshData.txt", &blossomRefreshDataList)
+	for _, blossomRefreshData := range blossomRefreshDataList {
+		refreshTime, err := time.Parse(time.TimeOnly, blossomRefreshData.RefreshTimeStr)
+		if err != nil {
+			info := fmt.Sprintf("blossom refresh time format error: %v", blossomRefreshData)
+			panic(info)
+		}
+		blossomRefreshData.RefreshSecond = int32(refreshTime.Hour()*3600 + refreshTime.Minute()*60 + refreshTime.Second())
+		blossomRefreshData.CondList = make([]*BlossomRefreshCond, 0)
+		for _, cond := range []*BlossomRefreshCond{
+			{Type: blossomRefreshData.Cond1Type, ParamStr: blossomRefreshData.Cond1Param},
+			{Type: blossomRefreshData.Cond2Type, ParamStr: blossomRefreshData.Cond2Param},
+			{Type: blossomRefreshData.Cond3Type, ParamStr: blossomRefreshData.Cond3Param},
+		} {
+			if cond.Type == 0 {
+				continue
+			}
+			cond.Param = make(IntArray, 0)
+			_ = cond.Param.UnmarshalCSV([]byte(cond.ParamStr))
+			blossomRefreshData.CondList = append(blossomRefreshData.CondList, cond)
+		}
+		blossomRefreshData.RewardDropIdList = []int32{
+			blossomRefreshData.Reward1DropId, blossomRefreshData.Reward2DropId, blossomRefreshData.Reward3DropId,
+			blossomRefreshData.Reward4DropId, blossomRefreshData.Reward5DropId, blossomRefreshData.Reward6DropId,
+			blossomRefreshData.Reward7DropId, blossomRefreshData.Reward8DropId, blossomRefreshData.Reward9DropId,
+		}
+		g.BlossomRefreshDataMap[blossomRefreshData.RefreshId] = blossomRefreshData
+	}
+	logger.Info("BlossomRefreshData Count: %v", len(g.BlossomRefreshDataMap))
+
+	g.BlossomChestDataMap = make(map[int32]*BlossomChestData)
+	blossomChestDataList := make([]*BlossomChestData, 0)
+	readTable[BlossomChestData](g.txtPrefix+"BlossomChestData.txt", &blossomChestDataList)
+	for _, blossomChestData := range blossomChestDataList {
+		g.BlossomChestDataMap[blossomChestData.ChestId] = blossomChestData
+	}
+	logger.Info("BlossomChestData Count: %v", len(g.BlossomChestDataMap))
+
+	g.BlossomOpenDataMap = make(map[int32]*BlossomOpenData)
+	blossomOpenDataList := make([]*BlossomOpenData, 0)
+	readTable[BlossomOpenData](g.txtPrefix+"BlossomOpenData.txt", &blossomOpenDataList)
+	for _, blossomOpenData := range blossomOpenDataList {
+		g.BlossomOpenDataMap[blossomOpenData.CityId] = blossomOpenData
+	}
+	logger.Info("BlossomOpenData Count: %v", len(g.BlossomOpenDataMap))
+
+	g.BlossomSectionOrderDataMap = make(map[int32]map[int32]*BlossomSectionOrderData)
+	blossomSectionOrderDataList := make([]*BlossomSectionOrderData, 0)
+	readTable[BlossomSectionOrderData](g.txtPrefix+"BlossomSectionOrderData.txt", &blossomSectionOrderDataList)
+	for _, blossomSectionOrderData := range blossomSectionOrderDataList {
+		_, exist := g.BlossomSectionOrderDataMap[blossomSectionOrderData.CityId]
+		if !exist {
+			g.BlossomSectionOrderDataMap[blossomSectionOrderData.CityId] = make(map[int32]*BlossomSectionOrderData)
+		}
+		g.BlossomSectionOrderDataMap[blossomSectionOrderData.CityId][blossomSectionOrderData.SectionId] = blossomSectionOrderData
+	}
+	logger.Info("BlossomSectionOrderData Count: %v", len(g.BlossomSectionOrderDataMap))
+}
+
+func GetBlossomGroupsDataById(campId int32) *BlossomGroupsData {
+	return CONF.BlossomGroupsDataMap[campId]
+}
+
+func GetBlossomGroupsDataByGroupId(groupId int32) *BlossomGroupsData {
+	return CONF.BlossomGroupsDataGroupIdMap[groupId]
+}
+
+// GetBlossomCampList 获取城市内支持某刷新类型的营地点 按区域顺序与id排序
+func GetBlossomCampList(cityId int32, refreshType int32) []*BlossomGroupsData {
+	sectionOrderMap := CONF.BlossomSectionOrderDataMap[cityId]
+	campList := make([]*BlossomGroupsData, 0)
+	for _, blossomGroupsData := range CONF.BlossomGroupsDataMap {
+		if blossomGroupsData.CityId != cityId {
+			continue
+		}
+		for _, campRefreshType := range blossomGroupsData.RefreshTypeList {
+			if campRefreshType == refreshType {
+				campList = append(campList, blossomGroupsData)
+				break
+			}
+		}
+	}
+	getOrder := func(sectionId int32) int32 {
+		sectionOrder, exist := sectionOrderMap[sectionId]
+		if !exist {
+			return 0
+		}
+		return sectionOrder.Order
+	}
+	sort.Slice(campList, func(i, j int) bool {
+		orderI, orderJ := getOrder(campList[i].SectionId), getOrder(campList[j].SectionId)
+		if orderI != orderJ {
+			return orderI < orderJ
+		}
+		return campList[i].CampId < campList[j].CampId
+	})
+	return campList
+}
+
+func GetBlossomRefreshDataById(refreshId int32) *BlossomRefreshData {
+	return CONF.BlossomRefreshDataMap[refreshId]
+}
+
+func GetBlossomRefreshDataMap() map[int32]*BlossomRefreshData {
+	return CONF.BlossomRefreshDataMap
+}
+
+func GetBlossomChestDataById(chestId int32) *BlossomChestData {
+	return CONF.BlossomChestDataMap[chestId]
+}
+
+func GetBlossomOpenDataByCityId(cityId int32) *BlossomOpenData {
+	return CONF.BlossomOpenDataMap[cityId]
+}
@@ -0,0 +1,39 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// BooksCodexData 书籍图鉴配置表
+type BooksCodexData struct {
+	CodexId  int32 `csv:"图鉴ID"`
+	ItemId   int32 `csv:"物品ID,omitempty"`
+	IsDisuse int32 `csv:"已废弃,omitempty"`
+}
+
+func (g *GameDataConfig) loadBooksCodexData() {
+	g.BooksCodexDataMap = make(map[int32]*BooksCodexData)
+	g.BooksCodexDataItemIdMap = make(map[int32]*BooksCodexData)
+	booksCodexDataList := make([]*BooksCodexData, 0)
+	readTable[BooksCodexData](g.txtPrefix+"BooksCodexData.txt", &booksCodexDataList)
+	for _, booksCodexData := range booksCodexDataList {
+		if booksCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.BooksCodexDataMap[booksCodexData.CodexId] = booksCodexData
+		g.BooksCodexDataItemIdMap[booksCodexData.ItemId] = booksCodexData
+	}
+	logger.Info("BooksCodexData Count: %v", len(g.BooksCodexDataMap))
+}
+
+func GetBooksCodexDataById(codexId int32) *BooksCodexData {
+	return CONF.BooksCodexDataMap[codexId]
+}
+
+func GetBooksCodexDataByItemId(itemId int32) *BooksCodexData {
+	return CONF.BooksCodexDataItemIdMap[itemId]
+}
+
+func GetBooksCodexDataMap() map[int32]*BooksCodexData {
+	return CONF.BooksCodexDataMap
+}
@@ -6,8 +6,10 @@ import (
 
 // DungeonData 地牢配置表
 type DungeonData struct {
-	DungeonId int32 `csv:"ID"`
-	SceneId   int32 `csv:"场景ID,omitempty"`
+	DungeonId           int32 `csv:"ID"`
+	SceneId             int32 `csv:"场景ID,omitempty"`
+	PassCond            int32 `csv:"通关条件,omitempty"`
+	SettleCountdownTime int32 `csv:"结算倒计时,omitempty"`
 }
 
 func (g *GameDataConfig) loadDungeonData() {
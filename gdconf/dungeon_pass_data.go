@@ -0,0 +1,66 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// DungeonPassData 地牢通关条件配置表
+type DungeonPassData struct {
+	Id              int32              `csv:"ID"`
+	CondCompose     int32              `csv:"[条件]组合,omitempty"`
+	CondType1       int32              `csv:"[条件]1类型,omitempty"`
+	CondType1Param1 int32              `csv:"[条件]1参数1,omitempty"`
+	CondType1Param2 int32              `csv:"[条件]1参数2,omitempty"`
+	CondType1Param3 int32              `csv:"[条件]1参数3,omitempty"`
+	CondType2       int32              `csv:"[条件]2类型,omitempty"`
+	CondType2Param1 int32              `csv:"[条件]2参数1,omitempty"`
+	CondType2Param2 int32              `csv:"[条件]2参数2,omitempty"`
+	CondType2Param3 int32              `csv:"[条件]2参数3,omitempty"`
+	CondType3       int32              `csv:"[条件]3类型,omitempty"`
+	CondType3Param1 int32              `csv:"[条件]3参数1,omitempty"`
+	CondType3Param2 int32              `csv:"[条件]3参数2,omitempty"`
+	CondType3Param3 int32              `csv:"[条件]3参数3,omitempty"`
+	CondType4       int32              `csv:"[条件]4类型,omitempty"`
+	CondType4Param1 int32              `csv:"[条件]4参数1,omitempty"`
+	CondType4Param2 int32              `csv:"[条件]4参数2,omitempty"`
+	CondType4Param3 int32              `csv:"[条件]4参数3,omitempty"`
+	CondList        []*DungeonPassCond // 通关条件
+}
+
+// DungeonPassCond 地牢通关条件
+type DungeonPassCond struct {
+	Type  int32
+	Param []int32
+}
+
+func (g *GameDataConfig) loadDungeonPassData() {
+	g.DungeonPassDataMap = make(map[int32]*DungeonPassData)
+	dungeonPassDataList := make([]*DungeonPassData, 0)
+	readTable[DungeonPassData](g.txtPrefix+"DungeonPassData.txt", &dungeonPassDataList)
+	for _, dungeonPassData := range dungeonPassDataList {
+		dungeonPassData.CondList = make([]*DungeonPassCond, 0)
+		for _, cond := range [][]int32{
+			{dungeonPassData.CondType1, dungeonPassData.CondType1Param1, dungeonPassData.CondType1Param2, dungeonPassData.CondType1Param3},
+			{dungeonPassData.CondType2, dungeonPassData.CondType2Param1, dungeonPassData.CondType2Param2, dungeonPassData.CondType2Param3},
+			{dungeonPassData.CondType3, dungeonPassData.CondType3Param1, dungeonPassData.CondType3Param2, dungeonPassData.CondType3Param3},
+			{dungeonPassData.CondType4, dungeonPassData.CondType4Param1, dungeonPassData.CondType4Param2, dungeonPassData.CondType4Param3},
+		} {
+			if cond[0] == 0 {
+				continue
+			}
+			paramList := make([]int32, 0)
+			for _, param := range cond[1:] {
+				if param != 0 {
+					paramList = append(paramList, param)
+				}
+			}
+			dungeonPassData.CondList = append(dungeonPassData.CondList, &DungeonPassCond{Type: cond[0], Param: paramList})
+		}
+		g.DungeonPassDataMap[dungeonPassData.Id] = dungeonPassData
+	}
+	logger.Info("DungeonPassData Count: %v", len(g.DungeonPassDataMap))
+}
+
+func GetDungeonPassDataById(id int32) *DungeonPassData {
+	return CONF.DungeonPassDataMap[id]
+}
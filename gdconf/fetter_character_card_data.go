@@ -0,0 +1,30 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// FetterCharacterCardData 角色好感度名片奖励配置表
+type FetterCharacterCardData struct {
+	AvatarId    int32 `csv:"角色ID"`
+	FetterLevel int32 `csv:"羁绊等级,omitempty"`
+	RewardId    int32 `csv:"奖励RewardID,omitempty"`
+}
+
+func (g *GameDataConfig) loadFetterCharacterCardData() {
+	g.FetterCharacterCardDataMap = make(map[int32]*FetterCharacterCardData)
+	fetterCharacterCardDataList := make([]*FetterCharacterCardData, 0)
+	readTable[FetterCharacterCardData](g.txtPrefix+"FetterCharacterCardData.txt", &fetterCharacterCardDataList)
+	for _, fetterCharacterCardData := range fetterCharacterCardDataList {
+		g.FetterCharacterCardDataMap[fetterCharacterCardData.AvatarId] = fetterCharacterCardData
+	}
+	logger.Info("FetterCharacterCardData Count: %v", len(g.FetterCharacterCardDataMap))
+}
+
+func GetFetterCharacterCardDataByAvatarId(avatarId int32) *FetterCharacterCardData {
+	return CONF.FetterCharacterCardDataMap[avatarId]
+}
+
+func GetFetterCharacterCardDataMap() map[int32]*FetterCharacterCardData {
+	return CONF.FetterCharacterCardDataMap
+}
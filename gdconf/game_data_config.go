@@ -15,6 +15,7 @@ import (
 
 	"hk4e/common/config"
 	"hk4e/common/constant"
+	"hk4e/pkg/wordfilter"
 
 	"github.com/flswld/halo/logger"
 	"github.com/jszwec/csvutil"
@@ -36,65 +37,116 @@ type GameDataConfig struct {
 	luaPrefix  string
 	extPrefix  string
 	loadExt    bool
+	// 解析失败的能力配置文件数量
+	abilityParseErrorCount int
 	// 配置表数据
-	SceneDataMap               map[int32]*SceneData                       // 场景
-	SceneLuaConfigMap          map[int32]*SceneLuaConfig                  // 场景LUA配置
-	SceneLuaGroupMap           map[int32]*Group                           // 场景LUA区块group索引
-	SceneLuaStateLruMap        map[int32]*LuaStateLru                     // 场景LUA虚拟机LRU内存淘汰
-	TriggerDataMap             map[int32]*TriggerData                     // 场景区域触发器
-	ScenePointJsonConfigMap    map[int32]*ScenePointJsonConfig            // 场景传送点JSON配置
-	AbilityDataMap             map[string]*AbilityData                    // 能力
-	AbilityDataHashMap         map[uint32]*AbilityData                    // 能力哈希
-	DefaultAbilityNameList     []string                                   // 默认能力
-	GadgetJsonConfigMap        map[string]*ConfigGadget                   // 物件JSON配置
-	GadgetLuaConfigMap         map[string]*GadgetLuaConfig                // 物件LUA配置
-	SceneTagDataMap            map[int32]*SceneTagData                    // 场景标签
-	GatherDataMap              map[int32]*GatherData                      // 采集物
-	GatherDataPointTypeMap     map[int32]*GatherData                      // 采集物场景节点索引
-	WorldAreaDataMap           map[int32]*WorldAreaData                   // 世界区域
-	AvatarDataMap              map[int32]*AvatarData                      // 角色
-	AvatarSkillDataMap         map[int32]*AvatarSkillData                 // 角色技能
-	AvatarSkillDepotDataMap    map[int32]*AvatarSkillDepotData            // 角色技能库
-	FetterDataMap              map[int32]*FetterData                      // 角色资料解锁
-	FetterDataAvatarIdMap      map[int32][]int32                          // 角色资料解锁角色id索引
-	ItemDataMap                map[int32]*ItemData                        // 统一道具
-	AvatarLevelDataMap         map[int32]*AvatarLevelData                 // 角色等级
-	AvatarPromoteDataMap       map[int32]map[int32]*AvatarPromoteData     // 角色突破
-	PlayerLevelDataMap         map[int32]*PlayerLevelData                 // 玩家等级
-	WeaponLevelDataMap         map[int32]*WeaponLevelData                 // 武器等级
-	WeaponPromoteDataMap       map[int32]map[int32]*WeaponPromoteData     // 角色突破
-	RewardDataMap              map[int32]*RewardData                      // 奖励
-	AvatarCostumeDataMap       map[int32]*AvatarCostumeData               // 角色时装
-	AvatarFlycloakDataMap      map[int32]*AvatarFlycloakData              // 角色风之翼
-	ReliquaryMainDataMap       map[int32]map[int32]*ReliquaryMainData     // 圣遗物主属性
-	ReliquaryAffixDataMap      map[int32]map[int32]*ReliquaryAffixData    // 圣遗物追加属性
-	QuestDataMap               map[int32]*QuestData                       // 任务
-	ParentQuestMap             map[int32]map[int32]*QuestData             // 父任务索引
-	DropDataMap                map[int32]*DropData                        // 掉落
-	MonsterDropDataMap         map[string]map[int32]*MonsterDropData      // 怪物掉落
-	ChestDropDataMap           map[string]map[int32]*ChestDropData        // 宝箱掉落
-	DungeonDataMap             map[int32]*DungeonData                     // 地牢
-	GadgetDataMap              map[int32]*GadgetData                      // 物件
-	RefreshPolicyDataMap       map[int32]*RefreshPolicyData               // 刷新策略
-	GCGCharDataMap             map[int32]*GCGCharData                     // 七圣召唤角色卡牌
-	GCGSkillDataMap            map[int32]*GCGSkillData                    // 七圣召唤卡牌技能
-	GachaDropGroupDataMap      map[int32]*GachaDropGroupData              // 卡池掉落组 临时的
-	OpenStateDataMap           map[int32]*OpenStateData                   // 开放状态
-	WeatherDataMap             map[int32]*WeatherData                     // 天气
-	WeatherDataJsonMap         map[int32]map[int32]*WeatherData           // 天气 json的天气区域id格式
-	WeatherTemplateDataMap     map[string]map[int32]*WeatherTemplateData  // 天气模版
-	WeatherAreaJsonConfigMap   map[int32]map[int32]*WeatherAreaJsonConfig // 天气区域JSON配置
-	PubgWorldGadgetDataMap     map[int32]*PubgWorldGadgetData             // pubg世界物件
-	MonsterRelationshipDataMap map[int32]*MonsterRelationshipData         // 怪物关联
-	MonsterDataMap             map[int32]*MonsterData                     // 怪物
-	ProudSkillDataMap          map[int32]map[int32]*ProudSkillData        // 天赋
-	AvatarCurveDataMap         map[int32]*AvatarCurveData                 // 角色曲线
-	WeaponCurveDataMap         map[int32]*WeaponCurveData                 // 武器曲线
-	ReliquaryLevelDataMap      map[int32]map[int32]*ReliquaryLevelData    // 圣遗物等级
-	MonsterCurveDataMap        map[int32]*MonsterCurveData                // 怪物曲线
-	WidgetJsonConfigMap        map[string]*ConfigWidget                   // 小道具JSON配置
-	ChapterDataMap             map[int32]*ChapterData                     // 章节
-	MainQuestDataMap           map[int32]*MainQuestData                   // 主线任务
+	SceneDataMap                map[int32]*SceneData                         // 场景
+	SceneLuaConfigMap           map[int32]*SceneLuaConfig                    // 场景LUA配置
+	SceneLuaGroupMap            map[int32]*Group                             // 场景LUA区块group索引
+	SceneLuaStateLruMap         map[int32]*LuaStateLru                       // 场景LUA虚拟机LRU内存淘汰
+	TriggerDataMap              map[int32]*TriggerData                       // 场景区域触发器
+	ScenePointJsonConfigMap     map[int32]*ScenePointJsonConfig              // 场景传送点JSON配置
+	AbilityDataMap              map[string]*AbilityData                      // 能力
+	AbilityDataHashMap          map[uint32]*AbilityData                      // 能力哈希
+	DefaultAbilityNameList      []string                                     // 默认能力
+	GadgetJsonConfigMap         map[string]*ConfigGadget                     // 物件JSON配置
+	GadgetLuaConfigMap          map[string]*GadgetLuaConfig                  // 物件LUA配置
+	SceneTagDataMap             map[int32]*SceneTagData                      // 场景标签
+	GatherDataMap               map[int32]*GatherData                        // 采集物
+	GatherDataPointTypeMap      map[int32]*GatherData                        // 采集物场景节点索引
+	WorldAreaDataMap            map[int32]*WorldAreaData                     // 世界区域
+	AvatarDataMap               map[int32]*AvatarData                        // 角色
+	AvatarSkillDataMap          map[int32]*AvatarSkillData                   // 角色技能
+	AvatarSkillDepotDataMap     map[int32]*AvatarSkillDepotData              // 角色技能库
+	FetterDataMap               map[int32]*FetterData                        // 角色资料解锁
+	FetterDataAvatarIdMap       map[int32][]int32                            // 角色资料解锁角色id索引
+	ItemDataMap                 map[int32]*ItemData                          // 统一道具
+	AvatarLevelDataMap          map[int32]*AvatarLevelData                   // 角色等级
+	AvatarPromoteDataMap        map[int32]map[int32]*AvatarPromoteData       // 角色突破
+	PlayerLevelDataMap          map[int32]*PlayerLevelData                   // 玩家等级
+	WorldLevelDataMap           map[int32]*WorldLevelData                    // 世界等级
+	WeaponLevelDataMap          map[int32]*WeaponLevelData                   // 武器等级
+	WeaponPromoteDataMap        map[int32]map[int32]*WeaponPromoteData       // 角色突破
+	RewardDataMap               map[int32]*RewardData                        // 奖励
+	AvatarCostumeDataMap        map[int32]*AvatarCostumeData                 // 角色时装
+	AvatarFlycloakDataMap       map[int32]*AvatarFlycloakData                // 角色风之翼
+	ReliquaryMainDataMap        map[int32]map[int32]*ReliquaryMainData       // 圣遗物主属性
+	ReliquaryAffixDataMap       map[int32]map[int32]*ReliquaryAffixData      // 圣遗物追加属性
+	QuestDataMap                map[int32]*QuestData                         // 任务
+	ParentQuestMap              map[int32]map[int32]*QuestData               // 父任务索引
+	DropDataMap                 map[int32]*DropData                          // 掉落
+	MonsterDropDataMap          map[string]map[int32]*MonsterDropData        // 怪物掉落
+	ChestDropDataMap            map[string]map[int32]*ChestDropData          // 宝箱掉落
+	DungeonDataMap              map[int32]*DungeonData                       // 地牢
+	DungeonPassDataMap          map[int32]*DungeonPassData                   // 地牢通关条件
+	GadgetDataMap               map[int32]*GadgetData                        // 物件
+	RefreshPolicyDataMap        map[int32]*RefreshPolicyData                 // 刷新策略
+	GCGCharDataMap              map[int32]*GCGCharData                       // 七圣召唤角色卡牌
+	GCGSkillDataMap             map[int32]*GCGSkillData                      // 七圣召唤卡牌技能
+	GachaDropGroupDataMap       map[int32]*GachaDropGroupData                // 卡池掉落组 临时的
+	OpenStateDataMap            map[int32]*OpenStateData                     // 开放状态
+	WeatherDataMap              map[int32]*WeatherData                       // 天气
+	WeatherDataJsonMap          map[int32]map[int32]*WeatherData             // 天气 json的天气区域id格式
+	WeatherTemplateDataMap      map[string]map[int32]*WeatherTemplateData    // 天气模版
+	WeatherAreaJsonConfigMap    map[int32]map[int32]*WeatherAreaJsonConfig   // 天气区域JSON配置
+	PubgWorldGadgetDataMap      map[int32]*PubgWorldGadgetData               // pubg世界物件
+	MonsterRelationshipDataMap  map[int32]*MonsterRelationshipData           // 怪物关联
+	MonsterDataMap              map[int32]*MonsterData                       // 怪物
+	ProudSkillDataMap           map[int32]map[int32]*ProudSkillData          // 天赋
+	AvatarCurveDataMap          map[int32]*AvatarCurveData                   // 角色曲线
+	WeaponCurveDataMap          map[int32]*WeaponCurveData                   // 武器曲线
+	ReliquaryLevelDataMap       map[int32]map[int32]*ReliquaryLevelData      // 圣遗物等级
+	MonsterCurveDataMap         map[int32]*MonsterCurveData                  // 怪物曲线
+	WidgetJsonConfigMap         map[string]*ConfigWidget                     // 小道具JSON配置
+	ChapterDataMap              map[int32]*ChapterData                       // 章节
+	MainQuestDataMap            map[int32]*MainQuestData                     // 主线任务
+	SignInCondConfigDataMap     map[int32]*SignInCondConfigData              // 签到开启条件
+	SignInDataMap               map[int32]map[int32]*SignInData              // 签到每日奖励
+	MailDataMap                 map[int32]*MailData                          // 邮件
+	BirthdayMailDataMap         map[int32]*BirthdayMailData                  // 生日邮件
+	SensitiveWordDataMap        map[int32]*SensitiveWordData                 // 敏感词
+	SensitiveWordFilter         *wordfilter.Filter                           // 敏感词过滤器
+	QuestCodexDataMap           map[int32]*QuestCodexData                    // 任务图鉴
+	QuestCodexDataChapterIdMap  map[int32][]*QuestCodexData                  // 任务图鉴章节id索引
+	WeaponCodexDataMap          map[int32]*WeaponCodexData                   // 武器图鉴
+	WeaponCodexDataWeaponIdMap  map[int32]*WeaponCodexData                   // 武器图鉴武器id索引
+	AnimalCodexDataMap          map[int32]*AnimalCodexData                   // 生物志图鉴
+	AnimalCodexDescribeIdMap    map[int32]*AnimalCodexData                   // 生物志图鉴生物大类id索引
+	MaterialCodexDataMap        map[int32]*MaterialCodexData                 // 材料图鉴
+	MaterialCodexDataItemIdMap  map[int32]*MaterialCodexData                 // 材料图鉴物品id索引
+	BooksCodexDataMap           map[int32]*BooksCodexData                    // 书籍图鉴
+	BooksCodexDataItemIdMap     map[int32]*BooksCodexData                    // 书籍图鉴物品id索引
+	ViewCodexDataMap            map[int32]*ViewCodexData                     // 风景图鉴
+	ViewCodexDataGroupIdMap     map[int32][]*ViewCodexData                   // 风景图鉴场景group id索引
+	AvatarFettersLevelDataMap   map[int32]*AvatarFettersLevelData            // 角色好感度等级
+	FetterCharacterCardDataMap  map[int32]*FetterCharacterCardData           // 角色好感度名片奖励
+	TeamResonanceDataMap        map[int32]*TeamResonanceData                 // 队伍元素共鸣
+	BlossomGroupsDataMap        map[int32]*BlossomGroupsData                 // 循环营地点
+	BlossomGroupsDataGroupIdMap map[int32]*BlossomGroupsData                 // 循环营地点刷新group索引
+	BlossomRefreshDataMap       map[int32]*BlossomRefreshData                // 循环营地刷新
+	BlossomChestDataMap         map[int32]*BlossomChestData                  // 循环营地宝箱
+	BlossomOpenDataMap          map[int32]*BlossomOpenData                   // 循环营地开启
+	BlossomSectionOrderDataMap  map[int32]map[int32]*BlossomSectionOrderData // 循环营地区域顺序
+	InvestigationMonsterDataMap map[int32]*InvestigationMonsterData          // 讨伐怪物
+	WorldBossGroupMap           map[int32]*InvestigationMonsterData          // 世界boss场景组索引
+	TrialAvatarDataMap          map[int32]*TrialAvatarData                   // 试用角色
+	TrialReliquaryDataMap       map[int32]*TrialReliquaryData                // 试用圣遗物
+	TrialTemplateDataList       []*TrialTemplateData                         // 试用角色模板 按等级排序
+	TrialAvatarDungeonDataMap   map[int32]*TrialAvatarActivityData           // 试用角色地牢 地牢id索引
+	NewActivityDataMap          map[int32]*NewActivityData                   // 活动
+	NewActivityScheduleDataMap  map[int32]*NewActivityScheduleData           // 活动排期
+	NewActivityWatcherDataMap   map[int32]*NewActivityWatcherData            // 活动进度监听
+	NewActivityCondDataMap      map[int32]*NewActivityCondData               // 活动条件
+	NewActivityCondGroupDataMap map[int32]*NewActivityCondGroupData          // 活动条件组
+	SalesmanDataMap             map[int32]*ActivitySalesmanData              // 商人活动
+	SalesmanDailyDataMap        map[int32]*ActivitySalesmanDailyData         // 商人活动每日交付
+	ProductIdDataMap            map[string]*ProductIdData                    // 充值商品id
+	RechargePrimogemDataMap     map[int32]*RechargePrimogemData              // 创世结晶充值档位
+	ReunionScheduleDataMap      map[int32]*ReunionScheduleData               // 回归排期
+	ReunionSignInDataMap        map[int32]map[int32]*ReunionSignInData       // 回归签到
+	ReunionMissionDataMap       map[int32]*ReunionMissionData                // 回归任务
+	ReunionWatcherDataMap       map[int32]*ReunionWatcherData                // 回归任务进度监听
+	ReunionPrivilegeDataMap     map[int32]*ReunionPrivilegeData              // 回归特权
 }
 
 func InitGameDataConfig() {
@@ -186,6 +238,7 @@ func (g *GameDataConfig) load(loadSceneLua bool) {
 	g.loadAvatarLevelData()            // 角色等级
 	g.loadAvatarPromoteData()          // 角色突破
 	g.loadPlayerLevelData()            // 玩家等级
+	g.loadWorldLevelData()             // 世界等级
 	g.loadWeaponLevelData()            // 武器等级
 	g.loadWeaponPromoteData()          // 武器突破
 	g.loadRewardData()                 // 奖励
@@ -198,6 +251,7 @@ func (g *GameDataConfig) load(loadSceneLua bool) {
 	g.loadMonsterDropData()            // 怪物掉落
 	g.loadChestDropData()              // 宝箱掉落
 	g.loadDungeonData()                // 地牢
+	g.loadDungeonPassData()            // 地牢通关条件
 	g.loadGadgetData()                 // 物件
 	g.loadRefreshPolicyData()          // 刷新策略
 	g.loadGCGCharData()                // 七圣召唤角色卡牌
@@ -216,6 +270,27 @@ func (g *GameDataConfig) load(loadSceneLua bool) {
 	g.loadWidgetJsonConfig()           // 小道具JSON配置
 	g.loadChapterData()                // 章节
 	g.loadMainQuestData()              // 主线任务
+	g.loadSignInCondConfigData()       // 签到开启条件
+	g.loadSignInData()                 // 签到每日奖励
+	g.loadMailData()                   // 邮件
+	g.loadBirthdayMailData()           // 生日邮件
+	g.loadSensitiveWordData()          // 敏感词
+	g.loadQuestCodexData()             // 任务图鉴
+	g.loadWeaponCodexData()            // 武器图鉴
+	g.loadAnimalCodexData()            // 生物志图鉴
+	g.loadMaterialCodexData()          // 材料图鉴
+	g.loadBooksCodexData()             // 书籍图鉴
+	g.loadViewCodexData()              // 风景图鉴
+	g.loadAvatarFettersLevelData()     // 角色好感度等级
+	g.loadFetterCharacterCardData()    // 角色好感度名片奖励
+	g.loadTeamResonanceData()          // 队伍元素共鸣
+	g.loadBlossomData()                // 循环营地
+	g.loadInvestigationMonsterData()   // 讨伐怪物
+	g.loadTrialAvatarData()            // 试用角色
+	g.loadNewActivityData()            // 活动
+	g.loadActivitySalesmanData()       // 商人活动
+	g.loadRechargeData()               // 充值
+	g.loadReunionData()                // 回归
 	if g.loadExt {
 		g.loadGachaDropGroupData()  // 卡池掉落组 临时的
 		g.loadPubgWorldGadgetData() // pubg世界物件
@@ -347,6 +422,9 @@ func initLuaState(luaState *lua.LState) {
 	luaState.SetField(eventType, "EVENT_GROUP_LOAD", lua.LNumber(constant.LUA_EVENT_GROUP_LOAD))
 	luaState.SetField(eventType, "EVENT_TIMER_EVENT", lua.LNumber(constant.LUA_EVENT_TIMER_EVENT))
 	luaState.SetField(eventType, "EVENT_SELECT_OPTION", lua.LNumber(constant.LUA_EVENT_SELECT_OPTION))
+	luaState.SetField(eventType, "EVENT_GROUP_REFRESH", lua.LNumber(constant.LUA_EVENT_GROUP_REFRESH))
+	luaState.SetField(eventType, "EVENT_BLOSSOM_PROGRESS_FINISH", lua.LNumber(constant.LUA_EVENT_BLOSSOM_PROGRESS_FINISH))
+	luaState.SetField(eventType, "EVENT_BLOSSOM_CHEST_DIE", lua.LNumber(constant.LUA_EVENT_BLOSSOM_CHEST_DIE))
 
 	entityType := luaState.NewTable()
 	luaState.SetGlobal("EntityType", entityType)
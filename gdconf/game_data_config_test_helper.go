@@ -0,0 +1,16 @@
+package gdconf
+
+import (
+	"testing"
+)
+
+// SetTestConf 单元测试替换全局配置 测试结束时恢复原配置
+func SetTestConf(tb testing.TB, conf *GameDataConfig) *GameDataConfig {
+	tb.Helper()
+	oldConf := CONF
+	CONF = conf
+	tb.Cleanup(func() {
+		CONF = oldConf
+	})
+	return conf
+}
@@ -0,0 +1,52 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// InvestigationMonsterData 讨伐怪物配置表
+type InvestigationMonsterData struct {
+	Id              int32    `csv:"ID"`
+	CityId          int32    `csv:"所属城市ID,omitempty"`
+	MonsterIdList   IntArray `csv:"怪物ID列表,omitempty"`
+	GroupIdList     IntArray `csv:"所属GroupID列表,omitempty"`
+	AliveVariable   string   `csv:"指示存活Group变量,omitempty"`
+	RewardPreviewId int32    `csv:"奖励展示ID,omitempty"`
+	Category        int32    `csv:"所属分类,omitempty"`
+}
+
+const (
+	InvestigationMonsterCategoryWorldBoss = 3 // 分类 世界boss
+)
+
+func (g *GameDataConfig) loadInvestigationMonsterData() {
+	g.InvestigationMonsterDataMap = make(map[int32]*InvestigationMonsterData)
+	g.WorldBossGroupMap = make(map[int32]*InvestigationMonsterData)
+	investigationMonsterDataList := make([]*InvestigationMonsterData, 0)
+	readTable[InvestigationMonsterData](g.txtPrefix+"InvestigationMonsterData.txt", &investigationMonsterDataList)
+	for _, investigationMonsterData := range investigationMonsterDataList {
+		g.InvestigationMonsterDataMap[investigationMonsterData.Id] = investigationMonsterData
+		if investigationMonsterData.Category != InvestigationMonsterCategoryWorldBoss {
+			continue
+		}
+		for _, groupId := range investigationMonsterData.GroupIdList {
+			g.WorldBossGroupMap[groupId] = investigationMonsterData
+		}
+	}
+	logger.Info("InvestigationMonsterData Count: %v", len(g.InvestigationMonsterDataMap))
+}
+
+// GetWorldBossByGroupId 获取场景组对应的世界boss
+func GetWorldBossByGroupId(groupId int32) *InvestigationMonsterData {
+	return CONF.WorldBossGroupMap[groupId]
+}
+
+// IsWorldBossMonster 是否为世界boss怪物
+func (i *InvestigationMonsterData) IsWorldBossMonster(monsterId int32) bool {
+	for _, id := range i.MonsterIdList {
+		if id == monsterId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// MailData 邮件配置表
+type MailData struct {
+	MailId    int32 `csv:"ID"`
+	ExpireDay int32 `csv:"过期天数,omitempty"`
+	IsStar    int32 `csv:"是否星标,omitempty"`
+	RewardId  int32 `csv:"RewardID,omitempty"`
+	IsCollect int32 `csv:"是否可收藏,omitempty"`
+}
+
+func (g *GameDataConfig) loadMailData() {
+	g.MailDataMap = make(map[int32]*MailData)
+	mailDataList := make([]*MailData, 0)
+	readTable[MailData](g.txtPrefix+"MailData.txt", &mailDataList)
+	for _, mailData := range mailDataList {
+		g.MailDataMap[mailData.MailId] = mailData
+	}
+	logger.Info("MailData Count: %v", len(g.MailDataMap))
+}
+
+func GetMailDataById(mailId int32) *MailData {
+	return CONF.MailDataMap[mailId]
+}
+
+func GetMailDataMap() map[int32]*MailData {
+	return CONF.MailDataMap
+}
@@ -0,0 +1,39 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// MaterialCodexData 材料图鉴配置表
+type MaterialCodexData struct {
+	CodexId  int32 `csv:"图鉴ID"`
+	ItemId   int32 `csv:"物品ID,omitempty"`
+	IsDisuse int32 `csv:"已废弃,omitempty"`
+}
+
+func (g *GameDataConfig) loadMaterialCodexData() {
+	g.MaterialCodexDataMap = make(map[int32]*MaterialCodexData)
+	g.MaterialCodexDataItemIdMap = make(map[int32]*MaterialCodexData)
+	materialCodexDataList := make([]*MaterialCodexData, 0)
+	readTable[MaterialCodexData](g.txtPrefix+"MaterialCodexData.txt", &materialCodexDataList)
+	for _, materialCodexData := range materialCodexDataList {
+		if materialCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.MaterialCodexDataMap[materialCodexData.CodexId] = materialCodexData
+		g.MaterialCodexDataItemIdMap[materialCodexData.ItemId] = materialCodexData
+	}
+	logger.Info("MaterialCodexData Count: %v", len(g.MaterialCodexDataMap))
+}
+
+func GetMaterialCodexDataById(codexId int32) *MaterialCodexData {
+	return CONF.MaterialCodexDataMap[codexId]
+}
+
+func GetMaterialCodexDataByItemId(itemId int32) *MaterialCodexData {
+	return CONF.MaterialCodexDataItemIdMap[itemId]
+}
+
+func GetMaterialCodexDataMap() map[int32]*MaterialCodexData {
+	return CONF.MaterialCodexDataMap
+}
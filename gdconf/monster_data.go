@@ -62,6 +62,7 @@ type MonsterData struct {
 	Drop3Id        int32 `csv:"[掉落]3ID,omitempty"`
 	Drop3HpPercent int32 `csv:"[掉落]3血量百分比,omitempty"`
 	KillDropId     int32 `csv:"击杀掉落ID,omitempty"`
+	DescribeId     int32 `csv:"生物大类ID,omitempty"`
 
 	FightPropList []*FightProp       // 战斗属性列表
 	PropGrowList  []*PropGrow        // 属性成长列表
@@ -0,0 +1,179 @@
+package gdconf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flswld/halo/logger"
+)
+
+// NewActivityData 活动配置表
+type NewActivityData struct {
+	ActivityId      int32    `csv:"活动id"`
+	ActivityType    int32    `csv:"活动类型,omitempty"`
+	CondGroupIdList IntArray `csv:"条件组列表,omitempty"`
+	WatcherIdList   IntArray `csv:"watcher列表,omitempty"`
+}
+
+// NewActivityScheduleData 活动排期配置表
+type NewActivityScheduleData struct {
+	ScheduleId   int32     `csv:"排期id"`
+	BeginTimeStr string    `csv:"开始时间"`
+	EndTimeStr   string    `csv:"结束时间"`
+	ActivityId   int32     `csv:"活动id"`
+	BeginTime    time.Time // 开始时间
+	EndTime      time.Time // 结束时间
+}
+
+// NewActivityWatcherData 活动进度监听配置表
+type NewActivityWatcherData struct {
+	WatcherId        int32   `csv:"ID"`
+	TriggerType      int32   `csv:"[触发条件]类型,omitempty"`
+	TriggerParamStr  string  `csv:"[触发条件]参数1,omitempty"`
+	Progress         int32   `csv:"进度,omitempty"`
+	IsDisuse         int32   `csv:"已废弃,omitempty"`
+	RewardId         int32   `csv:"RewardID,omitempty"`
+	IsAutoReward     int32   `csv:"是否直接发奖,omitempty"`
+	TriggerParamList []int32 // 触发条件参数1 非数字参数的监听不由服务器计数
+}
+
+// NewActivityCondData 活动条件配置表
+type NewActivityCondData struct {
+	CondId      int32              `csv:"CondId"`
+	CondCompose int32              `csv:"[条件]组合,omitempty"`
+	Cond1Type   int32              `csv:"[条件]1类型,omitempty"`
+	Cond1Param  string             `csv:"[条件]1参数,omitempty"`
+	Cond2Type   int32              `csv:"[条件]2类型,omitempty"`
+	Cond2Param  string             `csv:"[条件]2参数,omitempty"`
+	Cond3Type   int32              `csv:"[条件]3类型,omitempty"`
+	Cond3Param  string             `csv:"[条件]3参数,omitempty"`
+	Cond4Type   int32              `csv:"[条件]4类型,omitempty"`
+	Cond4Param  string             `csv:"[条件]4参数,omitempty"`
+	Cond5Type   int32              `csv:"[条件]5类型,omitempty"`
+	Cond5Param  string             `csv:"[条件]5参数,omitempty"`
+	CondList    []*NewActivityCond // 条件列表
+}
+
+type NewActivityCond struct {
+	Type  int32
+	Param []int32 // 非数字参数的条件为空
+}
+
+// NewActivityCondGroupData 活动条件组配置表
+type NewActivityCondGroupData struct {
+	CondGroupId int32    `csv:"条件组id"`
+	CondIdList  IntArray `csv:"条件列表,omitempty"`
+}
+
+func (g *GameDataConfig) loadNewActivityData() {
+	g.NewActivityDataMap = make(map[int32]*NewActivityData)
+	newActivityDataList := make([]*NewActivityData, 0)
+	readTable[NewActivityData](g.txtPrefix+"NewActivityData.txt", &newActivityDataList)
+	for _, newActivityData := range newActivityDataList {
+		g.NewActivityDataMap[newActivityData.ActivityId] = newActivityData
+	}
+	logger.Info("NewActivityData Count: %v", len(g.NewActivityDataMap))
+
+	g.NewActivityScheduleDataMap = make(map[int32]*NewActivityScheduleData)
+	newActivityScheduleDataList := make([]*NewActivityScheduleData, 0)
+	readTable[NewActivityScheduleData](g.txtPrefix+"NewActivityScheduleData.txt", &newActivityScheduleDataList)
+	for _, newActivityScheduleData := range newActivityScheduleDataList {
+		beginTime, err := time.ParseInLocation(time.DateTime, newActivityScheduleData.BeginTimeStr, time.Local)
+		if err != nil {
+			info := fmt.Sprintf("activity schedule begin time format error: %v", newActivityScheduleData)
+			panic(info)
+		}
+		endTime, err := time.ParseInLocation(time.DateTime, newActivityScheduleData.EndTimeStr, time.Local)
+		if err != nil {
+			info := fmt.Sprintf("activity schedule end time format error: %v", newActivityScheduleData)
+			panic(info)
+		}
+		newActivityScheduleData.BeginTime = beginTime
+		newActivityScheduleData.EndTime = endTime
+		g.NewActivityScheduleDataMap[newActivityScheduleData.ScheduleId] = newActivityScheduleData
+	}
+	logger.Info("NewActivityScheduleData Count: %v", len(g.NewActivityScheduleDataMap))
+
+	g.NewActivityWatcherDataMap = make(map[int32]*NewActivityWatcherData)
+	newActivityWatcherDataList := make([]*NewActivityWatcherData, 0)
+	readTable[NewActivityWatcherData](g.txtPrefix+"NewActivityWatcherData.txt", &newActivityWatcherDataList)
+	for _, newActivityWatcherData := range newActivityWatcherDataList {
+		newActivityWatcherData.TriggerParamList = parseWatcherTriggerParam(newActivityWatcherData.TriggerParamStr)
+		g.NewActivityWatcherDataMap[newActivityWatcherData.WatcherId] = newActivityWatcherData
+	}
+	logger.Info("NewActivityWatcherData Count: %v", len(g.NewActivityWatcherDataMap))
+
+	g.NewActivityCondDataMap = make(map[int32]*NewActivityCondData)
+	newActivityCondDataList := make([]*NewActivityCondData, 0)
+	readTable[NewActivityCondData](g.txtPrefix+"NewActivityCondData.txt", &newActivityCondDataList)
+	for _, newActivityCondData := range newActivityCondDataList {
+		newActivityCondData.CondList = make([]*NewActivityCond, 0)
+		for _, cond := range []struct {
+			condType  int32
+			condParam string
+		}{
+			{newActivityCondData.Cond1Type, newActivityCondData.Cond1Param},
+			{newActivityCondData.Cond2Type, newActivityCondData.Cond2Param},
+			{newActivityCondData.Cond3Type, newActivityCondData.Cond3Param},
+			{newActivityCondData.Cond4Type, newActivityCondData.Cond4Param},
+			{newActivityCondData.Cond5Type, newActivityCondData.Cond5Param},
+		} {
+			if cond.condType == 0 {
+				continue
+			}
+			newActivityCondData.CondList = append(newActivityCondData.CondList, &NewActivityCond{
+				Type:  cond.condType,
+				Param: parseWatcherTriggerParam(cond.condParam),
+			})
+		}
+		g.NewActivityCondDataMap[newActivityCondData.CondId] = newActivityCondData
+	}
+	logger.Info("NewActivityCondData Count: %v", len(g.NewActivityCondDataMap))
+
+	g.NewActivityCondGroupDataMap = make(map[int32]*NewActivityCondGroupData)
+	newActivityCondGroupDataList := make([]*NewActivityCondGroupData, 0)
+	readTable[NewActivityCondGroupData](g.txtPrefix+"NewActivityCondGroupData.txt", &newActivityCondGroupDataList)
+	for _, newActivityCondGroupData := range newActivityCondGroupDataList {
+		g.NewActivityCondGroupDataMap[newActivityCondGroupData.CondGroupId] = newActivityCondGroupData
+	}
+	logger.Info("NewActivityCondGroupData Count: %v", len(g.NewActivityCondGroupDataMap))
+}
+
+// 解析监听触发条件和活动条件的参数 部分参数为客户端能力名等字符串 忽略即可
+func parseWatcherTriggerParam(str string) []int32 {
+	paramList := make([]int32, 0)
+	for _, paramStr := range splitStringArray(str) {
+		param, err := strconv.ParseInt(strings.TrimSpace(paramStr), 10, 32)
+		if err != nil {
+			return make([]int32, 0)
+		}
+		paramList = append(paramList, int32(param))
+	}
+	return paramList
+}
+
+func GetNewActivityDataById(activityId int32) *NewActivityData {
+	return CONF.NewActivityDataMap[activityId]
+}
+
+func GetNewActivityScheduleDataMap() map[int32]*NewActivityScheduleData {
+	return CONF.NewActivityScheduleDataMap
+}
+
+func GetNewActivityScheduleDataById(scheduleId int32) *NewActivityScheduleData {
+	return CONF.NewActivityScheduleDataMap[scheduleId]
+}
+
+func GetNewActivityWatcherDataById(watcherId int32) *NewActivityWatcherData {
+	return CONF.NewActivityWatcherDataMap[watcherId]
+}
+
+func GetNewActivityCondDataById(condId int32) *NewActivityCondData {
+	return CONF.NewActivityCondDataMap[condId]
+}
+
+func GetNewActivityCondGroupDataById(condGroupId int32) *NewActivityCondGroupData {
+	return CONF.NewActivityCondGroupDataMap[condGroupId]
+}
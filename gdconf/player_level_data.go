@@ -6,8 +6,9 @@ import (
 
 // PlayerLevelData 玩家等级配置表
 type PlayerLevelData struct {
-	Level int32 `csv:"等级"`
-	Exp   int32 `csv:"升到下一级所需经验,omitempty"`
+	Level      int32 `csv:"等级"`
+	Exp        int32 `csv:"升到下一级所需经验,omitempty"`
+	WorldLevel int32 `csv:"世界等级,omitempty"`
 }
 
 func (g *GameDataConfig) loadPlayerLevelData() {
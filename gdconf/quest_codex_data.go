@@ -0,0 +1,40 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// QuestCodexData 任务图鉴配置表
+type QuestCodexData struct {
+	CodexId       int32 `csv:"图鉴ID"`
+	ParentQuestId int32 `csv:"父任务ID,omitempty"`
+	ChapterId     int32 `csv:"章节ID,omitempty"`
+	IsDisuse      int32 `csv:"已废弃,omitempty"`
+}
+
+func (g *GameDataConfig) loadQuestCodexData() {
+	g.QuestCodexDataMap = make(map[int32]*QuestCodexData)
+	g.QuestCodexDataChapterIdMap = make(map[int32][]*QuestCodexData)
+	questCodexDataList := make([]*QuestCodexData, 0)
+	readTable[QuestCodexData](g.txtPrefix+"QuestCodexData.txt", &questCodexDataList)
+	for _, questCodexData := range questCodexDataList {
+		if questCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.QuestCodexDataMap[questCodexData.CodexId] = questCodexData
+		g.QuestCodexDataChapterIdMap[questCodexData.ChapterId] = append(g.QuestCodexDataChapterIdMap[questCodexData.ChapterId], questCodexData)
+	}
+	logger.Info("QuestCodexData Count: %v", len(g.QuestCodexDataMap))
+}
+
+func GetQuestCodexDataById(codexId int32) *QuestCodexData {
+	return CONF.QuestCodexDataMap[codexId]
+}
+
+func GetQuestCodexDataListByChapterId(chapterId int32) []*QuestCodexData {
+	return CONF.QuestCodexDataChapterIdMap[chapterId]
+}
+
+func GetQuestCodexDataMap() map[int32]*QuestCodexData {
+	return CONF.QuestCodexDataMap
+}
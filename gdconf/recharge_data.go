@@ -0,0 +1,51 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// ProductIdData 充值商品id配置表
+type ProductIdData struct {
+	ProductId string `csv:"ProductID"`
+	ConfigId  int32  `csv:"configID,omitempty"`
+}
+
+// RechargePrimogemData 创世结晶充值档位配置表
+type RechargePrimogemData struct {
+	ConfigId      int32  `csv:"ConfigID"`
+	PriceTier     string `csv:"支付档位,omitempty"`
+	McoinBase     int32  `csv:"创世结晶数量,omitempty"`
+	McoinNonFirst int32  `csv:"额外送创世结晶数量,omitempty"`
+	McoinFirst    int32  `csv:"首充额外送创世结晶数量,omitempty"`
+}
+
+func (g *GameDataConfig) loadRechargeData() {
+	g.ProductIdDataMap = make(map[string]*ProductIdData)
+	productIdDataList := make([]*ProductIdData, 0)
+	readTable[ProductIdData](g.txtPrefix+"ProductIDData.txt", &productIdDataList)
+	for _, productIdData := range productIdDataList {
+		g.ProductIdDataMap[productIdData.ProductId] = productIdData
+	}
+	logger.Info("ProductIdData Count: %v", len(g.ProductIdDataMap))
+
+	g.RechargePrimogemDataMap = make(map[int32]*RechargePrimogemData)
+	rechargePrimogemDataList := make([]*RechargePrimogemData, 0)
+	readTable[RechargePrimogemData](g.txtPrefix+"RechargePrimogemData.txt", &rechargePrimogemDataList)
+	for _, rechargePrimogemData := range rechargePrimogemDataList {
+		g.RechargePrimogemDataMap[rechargePrimogemData.ConfigId] = rechargePrimogemData
+	}
+	logger.Info("RechargePrimogemData Count: %v", len(g.RechargePrimogemDataMap))
+}
+
+func GetProductIdDataMap() map[string]*ProductIdData {
+	return CONF.ProductIdDataMap
+}
+
+// GetRechargePrimogemDataByProductId 通过商品id获取创世结晶充值档位
+func GetRechargePrimogemDataByProductId(productId string) *RechargePrimogemData {
+	productIdData, exist := CONF.ProductIdDataMap[productId]
+	if !exist {
+		return nil
+	}
+	return CONF.RechargePrimogemDataMap[productIdData.ConfigId]
+}
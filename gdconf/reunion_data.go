@@ -0,0 +1,150 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// ReunionScheduleData 回归排期配置表
+type ReunionScheduleData struct {
+	ScheduleId        int32 `csv:"ID"`
+	Level             int32 `csv:"激活等级,omitempty"`
+	FirstGiftRewardId int32 `csv:"见面礼奖励ID,omitempty"`
+	SignInId          int32 `csv:"每日签到ID,omitempty"`
+	MissionId         int32 `csv:"回归任务ID,omitempty"`
+	PrivilegeId       int32 `csv:"回流特权ID,omitempty"`
+}
+
+// ReunionSignInData 回归签到配置表
+type ReunionSignInData struct {
+	SignInId int32 `csv:"组ID"`
+	Day      int32 `csv:"第几天,omitempty"`
+	RewardId int32 `csv:"奖励ID1,omitempty"`
+}
+
+// ReunionMissionData 回归任务配置表
+type ReunionMissionData struct {
+	MissionId         int32    `csv:"ID"`
+	WatcherGroupId    int32    `csv:"Watcher组ID,omitempty"`
+	StageScoreList    IntArray `csv:"分档积分,omitempty"`
+	StageRewardIdList IntArray `csv:"分档奖励ID,omitempty"`
+}
+
+// ReunionWatcherData 回归任务进度监听配置表
+type ReunionWatcherData struct {
+	WatcherId        int32    `csv:"ID"`
+	TriggerType      int32    `csv:"[触发条件]类型,omitempty"`
+	TriggerParamStr  string   `csv:"[触发条件]参数1,omitempty"`
+	Progress         int32    `csv:"进度,omitempty"`
+	IsDisuse         int32    `csv:"已废弃,omitempty"`
+	GroupId          int32    `csv:"组ID,omitempty"`
+	LevelRange       IntArray `csv:"激活等级区间,omitempty"`
+	RewardId         int32    `csv:"奖励ID,omitempty"`
+	Score            int32    `csv:"积分,omitempty"`
+	RewardDay        int32    `csv:"可领奖天数,omitempty"`
+	TriggerParamList []int32  // 触发条件参数1 非数字参数的监听不由服务器计数
+}
+
+// ReunionPrivilegeData 回归特权配置表
+type ReunionPrivilegeData struct {
+	PrivilegeId int32 `csv:"ID"`
+	DailyCount  int32 `csv:"每日次数,omitempty"`
+	TotalCount  int32 `csv:"总次数,omitempty"`
+}
+
+// IsLevelMatch 玩家等级是否位于监听的激活等级区间 左闭右开
+func (r *ReunionWatcherData) IsLevelMatch(level int32) bool {
+	if len(r.LevelRange) != 2 {
+		return true
+	}
+	return level >= r.LevelRange[0] && level < r.LevelRange[1]
+}
+
+func (g *GameDataConfig) loadReunionData() {
+	g.ReunionScheduleDataMap = make(map[int32]*ReunionScheduleData)
+	reunionScheduleDataList := make([]*ReunionScheduleData, 0)
+	readTable[ReunionScheduleData](g.txtPrefix+"ReunionScheduleData.txt", &reunionScheduleDataList)
+	for _, reunionScheduleData := range reunionScheduleDataList {
+		g.ReunionScheduleDataMap[reunionScheduleData.ScheduleId] = reunionScheduleData
+	}
+	logger.Info("ReunionScheduleData Count: %v", len(g.ReunionScheduleDataMap))
+
+	g.ReunionSignInDataMap = make(map[int32]map[int32]*ReunionSignInData)
+	reunionSignInDataList := make([]*ReunionSignInData, 0)
+	readTable[ReunionSignInData](g.txtPrefix+"ReunionSignInData.txt", &reunionSignInDataList)
+	for _, reunionSignInData := range reunionSignInDataList {
+		_, exist := g.ReunionSignInDataMap[reunionSignInData.SignInId]
+		if !exist {
+			g.ReunionSignInDataMap[reunionSignInData.SignInId] = make(map[int32]*ReunionSignInData)
+		}
+		g.ReunionSignInDataMap[reunionSignInData.SignInId][reunionSignInData.Day] = reunionSignInData
+	}
+	logger.Info("ReunionSignInData Count: %v", len(g.ReunionSignInDataMap))
+
+	g.ReunionMissionDataMap = make(map[int32]*ReunionMissionData)
+	reunionMissionDataList := make([]*ReunionMissionData, 0)
+	readTable[ReunionMissionData](g.txtPrefix+"ReunionMissionData.txt", &reunionMissionDataList)
+	for _, reunionMissionData := range reunionMissionDataList {
+		g.ReunionMissionDataMap[reunionMissionData.MissionId] = reunionMissionData
+	}
+	logger.Info("ReunionMissionData Count: %v", len(g.ReunionMissionDataMap))
+
+	g.ReunionWatcherDataMap = make(map[int32]*ReunionWatcherData)
+	reunionWatcherDataList := make([]*ReunionWatcherData, 0)
+	readTable[ReunionWatcherData](g.txtPrefix+"ReunionWatcherData.txt", &reunionWatcherDataList)
+	for _, reunionWatcherData := range reunionWatcherDataList {
+		reunionWatcherData.TriggerParamList = parseWatcherTriggerParam(reunionWatcherData.TriggerParamStr)
+		g.ReunionWatcherDataMap[reunionWatcherData.WatcherId] = reunionWatcherData
+	}
+	logger.Info("ReunionWatcherData Count: %v", len(g.ReunionWatcherDataMap))
+
+	g.ReunionPrivilegeDataMap = make(map[int32]*ReunionPrivilegeData)
+	reunionPrivilegeDataList := make([]*ReunionPrivilegeData, 0)
+	readTable[ReunionPrivilegeData](g.txtPrefix+"ReunionPrivilegeData.txt", &reunionPrivilegeDataList)
+	for _, reunionPrivilegeData := range reunionPrivilegeDataList {
+		g.ReunionPrivilegeDataMap[reunionPrivilegeData.PrivilegeId] = reunionPrivilegeData
+	}
+	logger.Info("ReunionPrivilegeData Count: %v", len(g.ReunionPrivilegeDataMap))
+}
+
+// GetLatestReunionScheduleData 获取当前生效的回归排期 取id最大的一期
+func GetLatestReunionScheduleData() *ReunionScheduleData {
+	var latest *ReunionScheduleData = nil
+	for _, reunionScheduleData := range CONF.ReunionScheduleDataMap {
+		if latest == nil || reunionScheduleData.ScheduleId > latest.ScheduleId {
+			latest = reunionScheduleData
+		}
+	}
+	return latest
+}
+
+func GetReunionScheduleDataById(scheduleId int32) *ReunionScheduleData {
+	return CONF.ReunionScheduleDataMap[scheduleId]
+}
+
+func GetReunionSignInDataByDay(signInId int32, day int32) *ReunionSignInData {
+	value, exist := CONF.ReunionSignInDataMap[signInId]
+	if !exist {
+		return nil
+	}
+	return value[day]
+}
+
+func GetReunionSignInDayCount(signInId int32) int32 {
+	return int32(len(CONF.ReunionSignInDataMap[signInId]))
+}
+
+func GetReunionMissionDataById(missionId int32) *ReunionMissionData {
+	return CONF.ReunionMissionDataMap[missionId]
+}
+
+func GetReunionWatcherDataById(watcherId int32) *ReunionWatcherData {
+	return CONF.ReunionWatcherDataMap[watcherId]
+}
+
+func GetReunionWatcherDataMap() map[int32]*ReunionWatcherData {
+	return CONF.ReunionWatcherDataMap
+}
+
+func GetReunionPrivilegeDataById(privilegeId int32) *ReunionPrivilegeData {
+	return CONF.ReunionPrivilegeDataMap[privilegeId]
+}
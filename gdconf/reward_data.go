@@ -7,6 +7,7 @@ import (
 // RewardData 奖励配置表
 type RewardData struct {
 	RewardId         int32 `csv:"RewardID"`
+	FetterExp        int32 `csv:"好感经验,omitempty"`
 	RewardItem1Id    int32 `csv:"Reward道具1ID,omitempty"`
 	RewardItem1Count int32 `csv:"Reward道具1数量,omitempty"`
 	RewardItem2Id    int32 `csv:"Reward道具2ID,omitempty"`
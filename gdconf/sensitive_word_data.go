@@ -0,0 +1,67 @@
+package gdconf
+
+import (
+	"os"
+	"strings"
+
+	"hk4e/common/config"
+	"hk4e/pkg/wordfilter"
+
+	"github.com/flswld/halo/logger"
+)
+
+// SensitiveWordData 敏感词配置表
+type SensitiveWordData struct {
+	Id   int32  `csv:"ID"`
+	Word string `csv:"敏感词,omitempty"`
+}
+
+func (g *GameDataConfig) loadSensitiveWordData() {
+	g.SensitiveWordDataMap = make(map[int32]*SensitiveWordData)
+	sensitiveWordDataList := make([]*SensitiveWordData, 0)
+	readTable[SensitiveWordData](g.txtPrefix+"SensitiveWordData.txt", &sensitiveWordDataList)
+	wordList := make([]string, 0)
+	for _, sensitiveWordData := range sensitiveWordDataList {
+		g.SensitiveWordDataMap[sensitiveWordData.Id] = sensitiveWordData
+		wordList = append(wordList, sensitiveWordData.Word)
+	}
+	// 额外的敏感词和白名单
+	extWordList := readWordListFile(config.GetConfig().Hk4e.SensitiveWordFile)
+	allowWordList := readWordListFile(config.GetConfig().Hk4e.SensitiveWordAllowFile)
+	wordList = append(wordList, extWordList...)
+	if len(wordList) == 0 {
+		panic("sensitive word list is empty, check SensitiveWordData.txt and sensitive_word_file")
+	}
+	g.SensitiveWordFilter = wordfilter.NewFilter(wordList, allowWordList)
+	logger.Info("SensitiveWordData Count: %v, ExtWord Count: %v, AllowWord Count: %v",
+		len(g.SensitiveWordDataMap), len(extWordList), len(allowWordList))
+}
+
+// 读取词表文件 每行一个词 忽略空行和#开头的注释行
+func readWordListFile(path string) []string {
+	wordList := make([]string, 0)
+	if path == "" {
+		return wordList
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("read word list file error: %v, path: %v", err, path)
+		return wordList
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		wordList = append(wordList, line)
+	}
+	return wordList
+}
+
+func GetSensitiveWordDataMap() map[int32]*SensitiveWordData {
+	return CONF.SensitiveWordDataMap
+}
+
+func GetSensitiveWordFilter() *wordfilter.Filter {
+	return CONF.SensitiveWordFilter
+}
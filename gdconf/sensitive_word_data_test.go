@@ -0,0 +1,37 @@
+package gdconf
+
+import (
+	"os"
+	"testing"
+
+	"hk4e/common/config"
+
+	"github.com/flswld/halo/logger"
+)
+
+func TestLoadSensitiveWordData(t *testing.T) {
+	logger.InitLogger(nil)
+	defer logger.CloseLogger()
+	config.CONF = &config.Config{Hk4e: config.Hk4e{}}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	g := &GameDataConfig{txtPrefix: "./game_data_config/txt/"}
+	g.loadSensitiveWordData()
+	if len(g.SensitiveWordDataMap) == 0 || !g.SensitiveWordFilter.Contains("你是傻逼") {
+		t.Fatalf("sensitive word data not load, count: %v", len(g.SensitiveWordDataMap))
+	}
+	// 词表为空时加载失败 不能静默关闭过滤
+	emptyPrefix := t.TempDir() + "/"
+	err := os.WriteFile(emptyPrefix+"SensitiveWordData.txt", []byte("ID\t敏感词\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("empty sensitive word list should panic")
+		}
+	}()
+	g = &GameDataConfig{txtPrefix: emptyPrefix}
+	g.loadSensitiveWordData()
+}
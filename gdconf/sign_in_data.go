@@ -0,0 +1,88 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// SignInCondConfigData 签到开启条件配置表
+type SignInCondConfigData struct {
+	ScheduleId  int32 `csv:"ID"`
+	CondType1   int32 `csv:"[开启条件]1类型,omitempty"`
+	CondParam11 int32 `csv:"[开启条件]1参数1,omitempty"`
+	TotalDay    int32 `csv:"总天数,omitempty"`
+}
+
+// SignInData 签到每日奖励配置表
+type SignInData struct {
+	ScheduleId    int32 `csv:"ID"`
+	Day           int32 `csv:"第几天"`
+	Item1Id       int32 `csv:"[道具]1ID,omitempty"`
+	Item1Count    int32 `csv:"[道具]1数量,omitempty"`
+	Item2Id       int32 `csv:"[道具]2ID,omitempty"`
+	Item2Count    int32 `csv:"[道具]2数量,omitempty"`
+	Item3Id       int32 `csv:"[道具]3ID,omitempty"`
+	Item3Count    int32 `csv:"[道具]3数量,omitempty"`
+	Item4Id       int32 `csv:"[道具]4ID,omitempty"`
+	Item4Count    int32 `csv:"[道具]4数量,omitempty"`
+	Item5Id       int32 `csv:"[道具]5ID,omitempty"`
+	Item5Count    int32 `csv:"[道具]5数量,omitempty"`
+	RewardItemMap map[uint32]uint32
+}
+
+func (g *GameDataConfig) loadSignInCondConfigData() {
+	g.SignInCondConfigDataMap = make(map[int32]*SignInCondConfigData)
+	signInCondConfigDataList := make([]*SignInCondConfigData, 0)
+	readTable[SignInCondConfigData](g.txtPrefix+"SignInCondData.txt", &signInCondConfigDataList)
+	for _, signInCondConfigData := range signInCondConfigDataList {
+		g.SignInCondConfigDataMap[signInCondConfigData.ScheduleId] = signInCondConfigData
+	}
+	logger.Info("SignInCondConfigData Count: %v", len(g.SignInCondConfigDataMap))
+}
+
+func (g *GameDataConfig) loadSignInData() {
+	g.SignInDataMap = make(map[int32]map[int32]*SignInData)
+	signInDataList := make([]*SignInData, 0)
+	readTable[SignInData](g.txtPrefix+"SignInDayData.txt", &signInDataList)
+	for _, signInData := range signInDataList {
+		// 奖励物品整合
+		signInData.RewardItemMap = map[uint32]uint32{
+			uint32(signInData.Item1Id): uint32(signInData.Item1Count),
+			uint32(signInData.Item2Id): uint32(signInData.Item2Count),
+			uint32(signInData.Item3Id): uint32(signInData.Item3Count),
+			uint32(signInData.Item4Id): uint32(signInData.Item4Count),
+			uint32(signInData.Item5Id): uint32(signInData.Item5Count),
+		}
+		for itemId, count := range signInData.RewardItemMap {
+			// 两个值都不能为0
+			if itemId == 0 || count == 0 {
+				delete(signInData.RewardItemMap, itemId)
+			}
+		}
+		_, exist := g.SignInDataMap[signInData.ScheduleId]
+		if !exist {
+			g.SignInDataMap[signInData.ScheduleId] = make(map[int32]*SignInData)
+		}
+		g.SignInDataMap[signInData.ScheduleId][signInData.Day] = signInData
+	}
+	logger.Info("SignInData Count: %v", len(g.SignInDataMap))
+}
+
+func GetSignInCondConfigDataById(scheduleId int32) *SignInCondConfigData {
+	return CONF.SignInCondConfigDataMap[scheduleId]
+}
+
+func GetSignInCondConfigDataMap() map[int32]*SignInCondConfigData {
+	return CONF.SignInCondConfigDataMap
+}
+
+func GetSignInDataByScheduleIdAndDay(scheduleId int32, day int32) *SignInData {
+	value, exist := CONF.SignInDataMap[scheduleId]
+	if !exist {
+		return nil
+	}
+	return value[day]
+}
+
+func GetSignInDataMap() map[int32]map[int32]*SignInData {
+	return CONF.SignInDataMap
+}
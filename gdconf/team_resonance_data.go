@@ -0,0 +1,105 @@
+package gdconf
+
+import (
+	"hk4e/common/constant"
+
+	"github.com/flswld/halo/logger"
+)
+
+// TeamResonanceData 队伍元素共鸣配置表
+type TeamResonanceData struct {
+	TeamResonanceId int32   `csv:"共鸣恩赐ID"`
+	OpenConfig      string  `csv:"开启天赋配置,omitempty"`
+	Param1          float32 `csv:"参数1,omitempty"`
+	Param2          float32 `csv:"参数2,omitempty"`
+	Param3          float32 `csv:"参数3,omitempty"`
+	Param4          float32 `csv:"参数4,omitempty"`
+	Param5          float32 `csv:"参数5,omitempty"`
+	Param6          float32 `csv:"参数6,omitempty"`
+	Param7          float32 `csv:"参数7,omitempty"`
+	Param8          float32 `csv:"参数8,omitempty"`
+	Param9          float32 `csv:"参数9,omitempty"`
+	Param10         float32 `csv:"参数10,omitempty"`
+	GroupId         int32   `csv:"共鸣恩赐组ID,omitempty"`
+	Level           int32   `csv:"共鸣恩赐等级,omitempty"`
+	FireCount       int32   `csv:"火属性要求个数,omitempty"`
+	WaterCount      int32   `csv:"水属性要求个数,omitempty"`
+	GrassCount      int32   `csv:"草属性要求个数,omitempty"`
+	ElecCount       int32   `csv:"雷属性要求个数,omitempty"`
+	IceCount        int32   `csv:"冰属性要求个数,omitempty"`
+	WindCount       int32   `csv:"风属性要求个数,omitempty"`
+	RockCount       int32   `csv:"岩属性要求个数,omitempty"`
+	Cond            int32   `csv:"特殊条件,omitempty"`
+	NeedPromoteSum  int32   `csv:"需求突破等级总和,omitempty"`
+
+	ParamList       []float32          // 参数列表
+	ElementCountMap map[int]int32      // 元素类型要求个数 key:元素类型 value:要求个数
+	AllDifferent    bool               // 是否要求队伍中所有角色元素类型各不相同
+	FightPropMap    map[uint32]float32 // 常驻的战斗属性加成
+}
+
+const (
+	TeamResonanceCondAllDifferent = 1 // 特殊条件 元素类型各不相同
+)
+
+// 元素共鸣常驻战斗属性加成 key:开启天赋配置 value:战斗属性与参数下标
+// 仅需特定条件触发的效果(例如冰共鸣对冰冻敌人的暴击加成)交由客户端ability处理
+var teamResonanceFightPropParamMap = map[string]map[uint32]int{
+	"TeamResonance_Fire_Lv2":  {constant.FIGHT_PROP_ATTACK_PERCENT: 0},
+	"TeamResonance_Water_Lv2": {constant.FIGHT_PROP_HP_PERCENT: 0},
+	"TeamResonance_Wind_Lv2":  {constant.FIGHT_PROP_SKILL_CD_MINUS_RATIO: 2},
+	"TeamResonance_Grass_Lv2": {constant.FIGHT_PROP_ELEMENT_MASTERY: 0},
+	"TeamResonance_Rock_Lv2":  {constant.FIGHT_PROP_SHIELD_COST_MINUS_RATIO: 0},
+	"TeamResonance_AllDifferent": {
+		constant.FIGHT_PROP_PHYSICAL_SUB_HURT: 0,
+		constant.FIGHT_PROP_FIRE_SUB_HURT:     0,
+		constant.FIGHT_PROP_ELEC_SUB_HURT:     0,
+		constant.FIGHT_PROP_WATER_SUB_HURT:    0,
+		constant.FIGHT_PROP_GRASS_SUB_HURT:    0,
+		constant.FIGHT_PROP_WIND_SUB_HURT:     0,
+		constant.FIGHT_PROP_ROCK_SUB_HURT:     0,
+		constant.FIGHT_PROP_ICE_SUB_HURT:      0,
+	},
+}
+
+func (g *GameDataConfig) loadTeamResonanceData() {
+	g.TeamResonanceDataMap = make(map[int32]*TeamResonanceData)
+	teamResonanceDataList := make([]*TeamResonanceData, 0)
+	readTable[TeamResonanceData](g.txtPrefix+"TeamResonanceData.txt", &teamResonanceDataList)
+	for _, teamResonanceData := range teamResonanceDataList {
+		teamResonanceData.ParamList = []float32{
+			teamResonanceData.Param1, teamResonanceData.Param2, teamResonanceData.Param3, teamResonanceData.Param4, teamResonanceData.Param5,
+			teamResonanceData.Param6, teamResonanceData.Param7, teamResonanceData.Param8, teamResonanceData.Param9, teamResonanceData.Param10,
+		}
+		teamResonanceData.ElementCountMap = make(map[int]int32)
+		for elementType, count := range map[int]int32{
+			constant.ELEMENT_TYPE_FIRE:  teamResonanceData.FireCount,
+			constant.ELEMENT_TYPE_WATER: teamResonanceData.WaterCount,
+			constant.ELEMENT_TYPE_GRASS: teamResonanceData.GrassCount,
+			constant.ELEMENT_TYPE_ELEC:  teamResonanceData.ElecCount,
+			constant.ELEMENT_TYPE_ICE:   teamResonanceData.IceCount,
+			constant.ELEMENT_TYPE_WIND:  teamResonanceData.WindCount,
+			constant.ELEMENT_TYPE_ROCK:  teamResonanceData.RockCount,
+		} {
+			if count == 0 {
+				continue
+			}
+			teamResonanceData.ElementCountMap[elementType] = count
+		}
+		teamResonanceData.AllDifferent = teamResonanceData.Cond == TeamResonanceCondAllDifferent
+		teamResonanceData.FightPropMap = make(map[uint32]float32)
+		for fightProp, paramIndex := range teamResonanceFightPropParamMap[teamResonanceData.OpenConfig] {
+			teamResonanceData.FightPropMap[fightProp] = teamResonanceData.ParamList[paramIndex]
+		}
+		g.TeamResonanceDataMap[teamResonanceData.TeamResonanceId] = teamResonanceData
+	}
+	logger.Info("TeamResonanceData Count: %v", len(g.TeamResonanceDataMap))
+}
+
+func GetTeamResonanceDataById(teamResonanceId int32) *TeamResonanceData {
+	return CONF.TeamResonanceDataMap[teamResonanceId]
+}
+
+func GetTeamResonanceDataMap() map[int32]*TeamResonanceData {
+	return CONF.TeamResonanceDataMap
+}
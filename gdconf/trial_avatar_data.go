@@ -0,0 +1,176 @@
+package gdconf
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flswld/halo/logger"
+)
+
+const TrialUseTemplate = "使用模板"
+
+// TrialAvatarData 试用角色配置表
+type TrialAvatarData struct {
+	TrialAvatarId int32    `csv:"试用角色ID"`
+	AvatarParam   IntArray `csv:"角色参数,omitempty"`
+	WeaponParam   IntArray `csv:"武器,omitempty"`
+	ReliquaryStr  string   `csv:"圣遗物,omitempty"`
+	SkillDepotId  int32    `csv:"技能库编号,omitempty"`
+	TalentStr     string   `csv:"天赋,omitempty"`
+	SkillLevelStr string   `csv:"主动技能等级,omitempty"`
+	CostumeId     int32    `csv:"定制时装,omitempty"`
+	AvatarId      int32    // 角色id
+	Level         int32    // 角色等级
+	WeaponId      int32    // 武器道具id
+	WeaponLevel   int32    // 武器等级
+	TalentIdxList []int32  // 命座 技能库命座下标 从1开始
+	SkillLevel    int32    // 主动技能等级
+	ReliquaryList []int32  // 试用圣遗物定制id
+	TemplateLevel int32    // 使用的模板等级 未使用模板为0
+}
+
+// TrialTemplateData 试用角色模板配置表
+type TrialTemplateData struct {
+	Level         int32    `csv:"等级"`
+	ReliquaryList IntArray `csv:"圣遗物,omitempty"`
+	TalentIdxList IntArray `csv:"天赋,omitempty"`
+	SkillLevel    int32    `csv:"主动技能等级,omitempty"`
+}
+
+// TrialReliquaryData 试用圣遗物配置表
+type TrialReliquaryData struct {
+	Id               int32    `csv:"定制ID"`
+	ReliquaryId      int32    `csv:"对应圣遗物ID,omitempty"`
+	Level            int32    `csv:"定制等级,omitempty"`
+	Promote          int32    `csv:"定制突破等级,omitempty"`
+	MainPropId       int32    `csv:"定制主属性类别,omitempty"`
+	AppendPropIdList IntArray `csv:"定制追加属性ID,omitempty"`
+}
+
+// TrialAvatarActivityData 试用角色活动关卡配置表
+type TrialAvatarActivityData struct {
+	Id                int32    `csv:"ID"`
+	DungeonId         int32    `csv:"进入关卡ID,omitempty"`
+	TrialAvatarIdList IntArray `csv:"角色,omitempty"`
+}
+
+func (g *GameDataConfig) loadTrialAvatarData() {
+	g.TrialReliquaryDataMap = make(map[int32]*TrialReliquaryData)
+	trialReliquaryDataList := make([]*TrialReliquaryData, 0)
+	readTable[TrialReliquaryData](g.txtPrefix+"TrialReliquaryData.txt", &trialReliquaryDataList)
+	for _, trialReliquaryData := range trialReliquaryDataList {
+		g.TrialReliquaryDataMap[trialReliquaryData.Id] = trialReliquaryData
+	}
+	logger.Info("TrialReliquaryData Count: %v", len(g.TrialReliquaryDataMap))
+
+	trialTemplateDataList := make([]*TrialTemplateData, 0)
+	readTable[TrialTemplateData](g.txtPrefix+"TrialTemplateData.txt", &trialTemplateDataList)
+	sort.Slice(trialTemplateDataList, func(i, j int) bool {
+		return trialTemplateDataList[i].Level < trialTemplateDataList[j].Level
+	})
+	g.TrialTemplateDataList = trialTemplateDataList
+	logger.Info("TrialTemplateData Count: %v", len(g.TrialTemplateDataList))
+
+	g.TrialAvatarDataMap = make(map[int32]*TrialAvatarData)
+	trialAvatarDataList := make([]*TrialAvatarData, 0)
+	readTable[TrialAvatarData](g.txtPrefix+"TrialAvatarData.txt", &trialAvatarDataList)
+	for _, trialAvatarData := range trialAvatarDataList {
+		if len(trialAvatarData.AvatarParam) != 2 || len(trialAvatarData.WeaponParam) != 2 {
+			info := fmt.Sprintf("trial avatar param format error: %v", trialAvatarData)
+			panic(info)
+		}
+		trialAvatarData.AvatarId = trialAvatarData.AvatarParam[0]
+		trialAvatarData.Level = trialAvatarData.AvatarParam[1]
+		trialAvatarData.WeaponId = trialAvatarData.WeaponParam[0]
+		trialAvatarData.WeaponLevel = trialAvatarData.WeaponParam[1]
+		template := getTrialTemplateData(trialTemplateDataList, trialAvatarData.Level)
+		if template != nil {
+			trialAvatarData.TemplateLevel = template.Level
+		}
+		trialAvatarData.TalentIdxList = parseTrialIntArray(trialAvatarData.TalentStr, template, func(t *TrialTemplateData) []int32 {
+			return t.TalentIdxList
+		})
+		trialAvatarData.ReliquaryList = parseTrialIntArray(trialAvatarData.ReliquaryStr, template, func(t *TrialTemplateData) []int32 {
+			return t.ReliquaryList
+		})
+		skillLevelList := parseTrialIntArray(trialAvatarData.SkillLevelStr, template, func(t *TrialTemplateData) []int32 {
+			return []int32{t.SkillLevel}
+		})
+		trialAvatarData.SkillLevel = 1
+		if len(skillLevelList) > 0 && skillLevelList[0] > 0 {
+			trialAvatarData.SkillLevel = skillLevelList[0]
+		}
+		g.TrialAvatarDataMap[trialAvatarData.TrialAvatarId] = trialAvatarData
+	}
+	logger.Info("TrialAvatarData Count: %v", len(g.TrialAvatarDataMap))
+
+	g.TrialAvatarDungeonDataMap = make(map[int32]*TrialAvatarActivityData)
+	trialAvatarActivityDataList := make([]*TrialAvatarActivityData, 0)
+	readTable[TrialAvatarActivityData](g.txtPrefix+"TrialAvatarActivityData.txt", &trialAvatarActivityDataList)
+	for _, trialAvatarActivityData := range trialAvatarActivityDataList {
+		if trialAvatarActivityData.DungeonId == 0 || len(trialAvatarActivityData.TrialAvatarIdList) == 0 {
+			continue
+		}
+		g.TrialAvatarDungeonDataMap[trialAvatarActivityData.DungeonId] = trialAvatarActivityData
+	}
+	logger.Info("TrialAvatarDungeonData Count: %v", len(g.TrialAvatarDungeonDataMap))
+}
+
+// 解析试用角色配置字段 填写使用模板时从模板中读取
+func parseTrialIntArray(str string, template *TrialTemplateData, getFromTemplate func(t *TrialTemplateData) []int32) []int32 {
+	if str == TrialUseTemplate {
+		if template == nil {
+			return make([]int32, 0)
+		}
+		return getFromTemplate(template)
+	}
+	intArray := make(IntArray, 0)
+	_ = intArray.UnmarshalCSV([]byte(str))
+	return intArray
+}
+
+// 获取不超过角色等级的最高等级模板
+func getTrialTemplateData(trialTemplateDataList []*TrialTemplateData, level int32) *TrialTemplateData {
+	var ret *TrialTemplateData = nil
+	for _, trialTemplateData := range trialTemplateDataList {
+		if trialTemplateData.Level > level {
+			break
+		}
+		ret = trialTemplateData
+	}
+	return ret
+}
+
+func GetTrialAvatarDataById(trialAvatarId int32) *TrialAvatarData {
+	return CONF.TrialAvatarDataMap[trialAvatarId]
+}
+
+// GetTrialAvatarDungeonDataByDungeonId 获取地牢发放的试用角色配置 非试用角色地牢返回nil
+func GetTrialAvatarDungeonDataByDungeonId(dungeonId int32) *TrialAvatarActivityData {
+	return CONF.TrialAvatarDungeonDataMap[dungeonId]
+}
+
+func GetTrialReliquaryDataById(id int32) *TrialReliquaryData {
+	return CONF.TrialReliquaryDataMap[id]
+}
+
+func GetTrialTemplateDataByLevel(level int32) *TrialTemplateData {
+	return getTrialTemplateData(CONF.TrialTemplateDataList, level)
+}
+
+// GetAvatarPromoteLevelByLevel 获取角色达到某等级所需的最低突破等级
+func GetAvatarPromoteLevelByLevel(promoteId int32, level int32) int32 {
+	promoteMap := CONF.AvatarPromoteDataMap[promoteId]
+	promoteLevel := int32(0)
+	for {
+		avatarPromoteData, exist := promoteMap[promoteLevel]
+		if !exist || avatarPromoteData.LevelLimit == 0 || avatarPromoteData.LevelLimit >= level {
+			break
+		}
+		if _, exist = promoteMap[promoteLevel+1]; !exist {
+			break
+		}
+		promoteLevel++
+	}
+	return promoteLevel
+}
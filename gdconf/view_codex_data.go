@@ -0,0 +1,42 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// ViewCodexData 风景图鉴配置表
+type ViewCodexData struct {
+	CodexId  int32 `csv:"图鉴ID"`
+	GadgetId int32 `csv:"物件ID,omitempty"`
+	SceneId  int32 `csv:"SceneID,omitempty"`
+	GroupId  int32 `csv:"GroupID,omitempty"`
+	ConfigId int32 `csv:"ConfigID,omitempty"`
+	IsDisuse int32 `csv:"已废弃,omitempty"`
+}
+
+func (g *GameDataConfig) loadViewCodexData() {
+	g.ViewCodexDataMap = make(map[int32]*ViewCodexData)
+	g.ViewCodexDataGroupIdMap = make(map[int32][]*ViewCodexData)
+	viewCodexDataList := make([]*ViewCodexData, 0)
+	readTable[ViewCodexData](g.txtPrefix+"ViewCodexData.txt", &viewCodexDataList)
+	for _, viewCodexData := range viewCodexDataList {
+		if viewCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.ViewCodexDataMap[viewCodexData.CodexId] = viewCodexData
+		g.ViewCodexDataGroupIdMap[viewCodexData.GroupId] = append(g.ViewCodexDataGroupIdMap[viewCodexData.GroupId], viewCodexData)
+	}
+	logger.Info("ViewCodexData Count: %v", len(g.ViewCodexDataMap))
+}
+
+func GetViewCodexDataById(codexId int32) *ViewCodexData {
+	return CONF.ViewCodexDataMap[codexId]
+}
+
+func GetViewCodexDataListByGroupId(groupId int32) []*ViewCodexData {
+	return CONF.ViewCodexDataGroupIdMap[groupId]
+}
+
+func GetViewCodexDataMap() map[int32]*ViewCodexData {
+	return CONF.ViewCodexDataMap
+}
@@ -0,0 +1,39 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// WeaponCodexData 武器图鉴配置表
+type WeaponCodexData struct {
+	CodexId  int32 `csv:"图鉴ID"`
+	WeaponId int32 `csv:"武器ID,omitempty"`
+	IsDisuse int32 `csv:"已废弃,omitempty"`
+}
+
+func (g *GameDataConfig) loadWeaponCodexData() {
+	g.WeaponCodexDataMap = make(map[int32]*WeaponCodexData)
+	g.WeaponCodexDataWeaponIdMap = make(map[int32]*WeaponCodexData)
+	weaponCodexDataList := make([]*WeaponCodexData, 0)
+	readTable[WeaponCodexData](g.txtPrefix+"WeaponCodexData.txt", &weaponCodexDataList)
+	for _, weaponCodexData := range weaponCodexDataList {
+		if weaponCodexData.IsDisuse != 0 {
+			continue
+		}
+		g.WeaponCodexDataMap[weaponCodexData.CodexId] = weaponCodexData
+		g.WeaponCodexDataWeaponIdMap[weaponCodexData.WeaponId] = weaponCodexData
+	}
+	logger.Info("WeaponCodexData Count: %v", len(g.WeaponCodexDataMap))
+}
+
+func GetWeaponCodexDataById(codexId int32) *WeaponCodexData {
+	return CONF.WeaponCodexDataMap[codexId]
+}
+
+func GetWeaponCodexDataByWeaponId(weaponId int32) *WeaponCodexData {
+	return CONF.WeaponCodexDataWeaponIdMap[weaponId]
+}
+
+func GetWeaponCodexDataMap() map[int32]*WeaponCodexData {
+	return CONF.WeaponCodexDataMap
+}
@@ -0,0 +1,39 @@
+package gdconf
+
+import (
+	"github.com/flswld/halo/logger"
+)
+
+// WorldLevelData 世界等级配置表
+type WorldLevelData struct {
+	WorldLevel   int32 `csv:"世界等级"`
+	MonsterLevel int32 `csv:"标准怪物等级,omitempty"`
+}
+
+func (g *GameDataConfig) loadWorldLevelData() {
+	g.WorldLevelDataMap = make(map[int32]*WorldLevelData)
+	worldLevelDataList := make([]*WorldLevelData, 0)
+	readTable[WorldLevelData](g.txtPrefix+"WorldLevelData.txt", &worldLevelDataList)
+	for _, worldLevelData := range worldLevelDataList {
+		g.WorldLevelDataMap[worldLevelData.WorldLevel] = worldLevelData
+	}
+	logger.Info("WorldLevelData Count: %v", len(g.WorldLevelDataMap))
+}
+
+func GetWorldLevelDataByLevel(worldLevel int32) *WorldLevelData {
+	return CONF.WorldLevelDataMap[worldLevel]
+}
+
+// GetWorldLevelByPlayerLevel 获取冒险等阶对应的世界等级 取不超过该等阶的最高世界等级
+func GetWorldLevelByPlayerLevel(playerLevel int32) int32 {
+	worldLevel := int32(0)
+	for level, playerLevelData := range CONF.PlayerLevelDataMap {
+		if level > playerLevel {
+			continue
+		}
+		if playerLevelData.WorldLevel > worldLevel {
+			worldLevel = playerLevelData.WorldLevel
+		}
+	}
+	return worldLevel
+}
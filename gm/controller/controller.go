@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"hk4e/common/config"
@@ -22,6 +23,9 @@ type Controller struct {
 	messageQueue          *mq.MessageQueue
 	globalGsOnlineMap     map[uint32]string // 全服玩家在线表
 	globalGsOnlineMapLock sync.RWMutex
+	gateRspMap            map[string]chan *mq.NetMsg // 等待网关响应 k:reqId
+	gateRspMapLock        sync.Mutex
+	gateReqCounter        atomic.Uint64
 }
 
 func NewController(discoveryClient *rpc.DiscoveryClient, messageQueue *mq.MessageQueue) (*Controller, error) {
@@ -29,12 +33,16 @@ func NewController(discoveryClient *rpc.DiscoveryClient, messageQueue *mq.Messag
 	r.gmClientMap = make(map[uint32]*rpc.GMClient)
 	r.discoveryClient = discoveryClient
 	r.messageQueue = messageQueue
+	r.gateRspMap = make(map[string]chan *mq.NetMsg)
 	go func() {
 		for {
-			_, ok := <-r.messageQueue.GetNetMsg()
+			netMsg, ok := <-r.messageQueue.GetNetMsg()
 			if !ok {
 				return
 			}
+			if netMsg.MsgType == mq.MsgTypeServer {
+				r.gateRspHandle(netMsg)
+			}
 		}
 	}()
 	r.globalGsOnlineMap = make(map[uint32]string)
@@ -110,6 +118,14 @@ func (c *Controller) registerRouter() {
 	engine.POST("/server/white/add", c.serverWhiteAdd)
 	engine.POST("/server/white/del", c.serverWhiteDel)
 	engine.POST("/server/dispatch/cancel", c.serverDispatchCancel)
+	engine.GET("/server/gate/ip/limit", c.serverGateIpLimit)
+	engine.POST("/server/gate/ip/ban", c.serverGateIpBan)
+	engine.POST("/server/gate/ip/unban", c.serverGateIpUnban)
+	engine.POST("/server/gate/ip/exempt/add", c.serverGateIpExemptAdd)
+	engine.POST("/server/gate/ip/exempt/del", c.serverGateIpExemptDel)
+	engine.GET("/server/gate/capture", c.serverGateCapture)
+	engine.POST("/server/gate/capture/start", c.serverGateCaptureStart)
+	engine.POST("/server/gate/capture/stop", c.serverGateCaptureStop)
 	port := config.GetConfig().Hk4e.GmHttpPort
 	addr := ":" + strconv.Itoa(int(port))
 	err := engine.Run(addr)
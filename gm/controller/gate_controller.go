@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"hk4e/common/mq"
+	"hk4e/node/api"
+
+	"github.com/flswld/halo/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const GateRspTimeout = time.Second * 3 // 等待网关响应的超时时间
+
+func (c *Controller) gateRspHandle(netMsg *mq.NetMsg) {
+	reqId := ""
+	switch netMsg.EventId {
+	case mq.ServerGateIpLimitRsp:
+		info := netMsg.ServerMsg.GateIpLimitInfo
+		if info == nil {
+			return
+		}
+		info.GateAppId = netMsg.OriginServerAppId
+		reqId = info.ReqId
+	case mq.ServerGateCaptureRsp:
+		info := netMsg.ServerMsg.GateCaptureInfo
+		if info == nil {
+			return
+		}
+		info.GateAppId = netMsg.OriginServerAppId
+		reqId = info.ReqId
+	default:
+		return
+	}
+	c.gateRspMapLock.Lock()
+	rspChan, exist := c.gateRspMap[reqId]
+	c.gateRspMapLock.Unlock()
+	if !exist {
+		return
+	}
+	select {
+	case rspChan <- netMsg:
+	default:
+	}
+}
+
+// gateCall 向全部网关发送请求 并等待各个网关的响应 响应按网关appid排序
+func (c *Controller) gateCall(ctx context.Context, eventId uint16, newServerMsg func(reqId string) *mq.ServerMsg) ([]*mq.NetMsg, error) {
+	rsp, err := c.discoveryClient.GetAllGateServerInfoList(ctx, &api.NullMsg{})
+	if err != nil {
+		return nil, err
+	}
+	reqId := strconv.FormatInt(time.Now().UnixNano(), 10) + "_" + strconv.FormatUint(c.gateReqCounter.Add(1), 10)
+	gateNum := len(rsp.GateServerInfoList)
+	rspChan := make(chan *mq.NetMsg, gateNum)
+	c.gateRspMapLock.Lock()
+	c.gateRspMap[reqId] = rspChan
+	c.gateRspMapLock.Unlock()
+	defer func() {
+		c.gateRspMapLock.Lock()
+		delete(c.gateRspMap, reqId)
+		c.gateRspMapLock.Unlock()
+	}()
+	for _, gateServerInfo := range rsp.GateServerInfoList {
+		c.messageQueue.SendToGate(gateServerInfo.AppId, &mq.NetMsg{
+			MsgType:   mq.MsgTypeServer,
+			EventId:   eventId,
+			ServerMsg: newServerMsg(reqId),
+		})
+	}
+	netMsgList := make([]*mq.NetMsg, 0, gateNum)
+	timer := time.NewTimer(GateRspTimeout)
+	defer timer.Stop()
+	for len(netMsgList) < gateNum {
+		select {
+		case netMsg := <-rspChan:
+			netMsgList = append(netMsgList, netMsg)
+		case <-timer.C:
+			logger.Error("wait gate rsp timeout, eventId: %v, rsp num: %v, gate num: %v", eventId, len(netMsgList), gateNum)
+			gateNum = len(netMsgList)
+		}
+	}
+	sort.Slice(netMsgList, func(i, j int) bool {
+		return netMsgList[i].OriginServerAppId < netMsgList[j].OriginServerAppId
+	})
+	return netMsgList, nil
+}
+
+func (c *Controller) gateIpLimitRsp(ctx *gin.Context, req *mq.GateIpLimitReq) {
+	netMsgList, err := c.gateCall(ctx.Request.Context(), mq.ServerGateIpLimitReq, func(reqId string) *mq.ServerMsg {
+		req.ReqId = reqId
+		return &mq.ServerMsg{GateIpLimitReq: req}
+	})
+	if err != nil {
+		logger.Error("gate ip limit call error: %v", err)
+		ctx.JSON(http.StatusOK, &CommonRsp{Code: -1, Msg: "服务器内部错误", Data: err})
+		return
+	}
+	infoList := make([]*mq.GateIpLimitInfo, 0, len(netMsgList))
+	for _, netMsg := range netMsgList {
+		infoList = append(infoList, netMsg.ServerMsg.GateIpLimitInfo)
+	}
+	ctx.JSON(http.StatusOK, &CommonRsp{Code: 0, Msg: "", Data: infoList})
+}
+
+func (c *Controller) serverGateIpLimit(ctx *gin.Context) {
+	c.gateIpLimitRsp(ctx, &mq.GateIpLimitReq{Op: mq.GateIpLimitOpQuery})
+}
+
+type ServerGateIpBan struct {
+	IpAddr  string `json:"ip_addr"`
+	BanTime uint32 `json:"ban_time"`
+}
+
+func (c *Controller) serverGateIpBan(ctx *gin.Context) {
+	req := new(ServerGateIpBan)
+	err := ctx.ShouldBindJSON(req)
+	if err != nil || req.IpAddr == "" {
+		ctx.JSON(http.StatusOK, &CommonRsp{Code: -1, Msg: "参数解析错误", Data: err})
+		return
+	}
+	c.gateIpLimitRsp(ctx, &mq.GateIpLimitReq{Op: mq.GateIpLimitOpBan, IpAddr: req.IpAddr, BanTime: req.BanTime})
+}
+
+type ServerGateIp struct {
+	IpAddr string `json:"ip_addr"`
+}
+
+func (c *Controller) serverGateIpOp(ctx *gin.Context, op uint8) {
+	req := new(ServerGateIp)
+	err := ctx.ShouldBindJSON(req)
+	if err != nil || req.IpAddr == "" {
+		ctx.JSON(http.StatusOK, &CommonRsp{Code: -1, Msg: "参数解析错误", Data: err})
+		return
+	}
+	c.gateIpLimitRsp(ctx, &mq.GateIpLimitReq{Op: op, IpAddr: req.IpAddr})
+}
+
+func (c *Controller) serverGateIpUnban(ctx *gin.Context) {
+	c.serverGateIpOp(ctx, mq.GateIpLimitOpUnban)
+}
+
+func (c *Controller) serverGateIpExemptAdd(ctx *gin.Context) {
+	c.serverGateIpOp(ctx, mq.GateIpLimitOpExemptAdd)
+}
+
+func (c *Controller) serverGateIpExemptDel(ctx *gin.Context) {
+	c.serverGateIpOp(ctx, mq.GateIpLimitOpExemptDel)
+}
+
+func (c *Controller) gateCaptureRsp(ctx *gin.Context, req *mq.GateCaptureReq) {
+	netMsgList, err := c.gateCall(ctx.Request.Context(), mq.ServerGateCaptureReq, func(reqId string) *mq.ServerMsg {
+		req.ReqId = reqId
+		return &mq.ServerMsg{GateCaptureReq: req}
+	})
+	if err != nil {
+		logger.Error("gate capture call error: %v", err)
+		ctx.JSON(http.StatusOK, &CommonRsp{Code: -1, Msg: "服务器内部错误", Data: err})
+		return
+	}
+	infoList := make([]*mq.GateCaptureInfo, 0, len(netMsgList))
+	for _, netMsg := range netMsgList {
+		infoList = append(infoList, netMsg.ServerMsg.GateCaptureInfo)
+	}
+	ctx.JSON(http.StatusOK, &CommonRsp{Code: 0, Msg: "", Data: infoList})
+}
+
+func (c *Controller) serverGateCapture(ctx *gin.Context) {
+	c.gateCaptureRsp(ctx, &mq.GateCaptureReq{Op: mq.GateCaptureOpQuery})
+}
+
+type ServerGateCapture struct {
+	Uid       uint32 `json:"uid"`
+	SessionId uint32 `json:"session_id"`
+}
+
+// serverGateCaptureOp 按uid或会话id抓包 会话id只在所在网关上有效 同时指定时以uid为准
+func (c *Controller) serverGateCaptureOp(ctx *gin.Context, op uint8) {
+	req := new(ServerGateCapture)
+	err := ctx.ShouldBindJSON(req)
+	if err != nil || (req.Uid == 0 && req.SessionId == 0) {
+		ctx.JSON(http.StatusOK, &CommonRsp{Code: -1, Msg: "参数解析错误", Data: err})
+		return
+	}
+	c.gateCaptureRsp(ctx, &mq.GateCaptureReq{Op: op, UserId: req.Uid, SessionId: req.SessionId})
+}
+
+func (c *Controller) serverGateCaptureStart(ctx *gin.Context) {
+	c.serverGateCaptureOp(ctx, mq.GateCaptureOpStart)
+}
+
+func (c *Controller) serverGateCaptureStop(ctx *gin.Context) {
+	c.serverGateCaptureOp(ctx, mq.GateCaptureOpStop)
+}
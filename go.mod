@@ -23,6 +23,7 @@ require (
 	github.com/yuin/gopher-lua v1.0.0
 	gitlab.com/gomidi/midi/v2 v2.0.25
 	go.mongodb.org/mongo-driver v1.8.3
+	golang.org/x/text v0.23.0
 	google.golang.org/protobuf v1.34.0
 	gorm.io/driver/mysql v1.5.7
 	gorm.io/gorm v1.25.12
@@ -79,7 +80,6 @@ require (
 	golang.org/x/net v0.36.0 // indirect
 	golang.org/x/sync v0.12.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
 	golang.org/x/time v0.11.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	modernc.org/libc v1.22.5 // indirect
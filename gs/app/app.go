@@ -18,6 +18,7 @@ import (
 	"hk4e/gs/game"
 	"hk4e/gs/service"
 	"hk4e/node/api"
+	"hk4e/pkg/trace"
 
 	"github.com/flswld/halo/logger"
 	"github.com/nats-io/nats.go"
@@ -43,21 +44,24 @@ func Run(ctx context.Context) error {
 		return err
 	}
 	APPID = rsp.GetAppId()
+	GSID = rsp.GetGsId()
 	go func() {
 		ticker := time.NewTicker(time.Second * 15)
 		for {
 			<-ticker.C
 			_, err := discoveryClient.KeepaliveServer(context.TODO(), &api.KeepaliveServerReq{
-				ServerType: api.GS,
-				AppId:      APPID,
-				LoadCount:  uint32(atomic.LoadInt32(&game.ONLINE_PLAYER_NUM)),
+				ServerType:    api.GS,
+				AppId:         APPID,
+				LoadCount:     uint32(atomic.LoadInt32(&game.ONLINE_PLAYER_NUM)),
+				AppVersion:    APPVERSION,
+				GsId:          GSID,
+				OnlineUidList: game.GetOnlinePlayerUidList(),
 			})
 			if err != nil {
 				logger.Error("keepalive error: %v", err)
 			}
 		}
 	}()
-	GSID = rsp.GetGsId()
 	defer func() {
 		_, _ = discoveryClient.CancelServer(context.TODO(), &api.CancelServerReq{
 			ServerType: api.GS,
@@ -78,6 +82,17 @@ func Run(ctx context.Context) error {
 		defer func() {
 			logger.CloseLogger()
 		}()
+		err = trace.InitTracer(&trace.Config{
+			ServiceName: "gs_" + strconv.Itoa(int(GSID)) + "_" + APPID,
+			Exporter:    config.GetConfig().Trace.Exporter,
+			FilePath:    config.GetConfig().Trace.FilePath,
+			SampleUid:   config.GetConfig().Trace.SampleUid,
+			SampleRate:  config.GetConfig().Trace.SampleRate,
+		})
+		if err != nil {
+			return err
+		}
+		defer trace.CloseTracer()
 	}
 	logger.Warn("gs start, appid: %v, gsid: %v", APPID, GSID)
 	defer func() {
@@ -4,14 +4,17 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
+	"hk4e/common/config"
 	"hk4e/common/constant"
 	"hk4e/common/mq"
 	"hk4e/common/rpc"
 	"hk4e/gs/dao"
 	"hk4e/gs/model"
 	"hk4e/pkg/alg"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/reflection"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -39,7 +42,8 @@ var COMMAND_MANAGER *CommandManager = nil
 var GCG_MANAGER *GCGManager = nil
 var PLUGIN_MANAGER *PluginManager = nil
 
-var ONLINE_PLAYER_NUM int32 = 0 // 当前在线玩家数
+var ONLINE_PLAYER_NUM int32 = 0    // 当前在线玩家数
+var ONLINE_PLAYER_UID_MAP sync.Map // 当前在线玩家uid集合 供其他协程读取 key:uid value:是否在线
 
 var SELF *model.Player
 
@@ -56,6 +60,8 @@ type Game struct {
 	endlessLoopCounter map[int]uint64       // 死循环保护计数器
 	transactionSeq     uint32               // 事务序列号
 	ai                 *model.Player        // 本服的Ai玩家对象
+	payProvider        IPayProvider         // 支付渠道
+	replayClock        *clock.ManualClock   // 回放模式下由回放工具推进的时钟
 }
 
 func NewGameCore(discoveryClient *rpc.DiscoveryClient, db *dao.Dao, messageQueue *mq.MessageQueue, gsId uint32, gsAppid string, gsAppVersion string) (r *Game) {
@@ -63,6 +69,7 @@ func NewGameCore(discoveryClient *rpc.DiscoveryClient, db *dao.Dao, messageQueue
 	r.discoveryClient = discoveryClient
 	r.db = db
 	r.messageQueue = messageQueue
+	r.messageQueue.SetTraceContextFunc(getCurTraceContext)
 	r.gsId = gsId
 	r.gsAppid = gsAppid
 	r.gsAppVersion = gsAppVersion
@@ -71,6 +78,8 @@ func NewGameCore(discoveryClient *rpc.DiscoveryClient, db *dao.Dao, messageQueue
 	r.dispatchCancel = false
 	r.endlessLoopCounter = make(map[int]uint64)
 	r.transactionSeq = 0
+	r.payProvider = NewPayProvider(config.GetConfig().Hk4e.PayProvider)
+	r.initReplayMode()
 	GAME = r
 	LOCAL_EVENT_MANAGER = NewLocalEventManager()
 	ROUTE_MANAGER = NewRouteManager()
@@ -81,6 +90,8 @@ func NewGameCore(discoveryClient *rpc.DiscoveryClient, db *dao.Dao, messageQueue
 	GCG_MANAGER = NewGCGManager()
 	PLUGIN_MANAGER = NewPluginManager()
 	RegLuaScriptLibFunc()
+	RegAbilityHandler()
+	RegQuestHandler()
 	// 创建本服的Ai世界
 	uid := AiBaseUid + gsId
 	name := AiName
@@ -197,6 +208,7 @@ func (g *Game) gameMainLoop() {
 			TICK_MANAGER.OnGameServerTick()
 			end := time.Now().UnixNano()
 			tickCost += end - start
+			metricsTickCost.Observe(float64(end-start) / 1e9)
 		case localEvent := <-LOCAL_EVENT_MANAGER.GetLocalEventChan():
 			// 处理本地事件
 			start := time.Now().UnixNano()
@@ -310,7 +322,7 @@ func (g *Game) EndlessLoopCheck(checkType int) {
 
 func (g *Game) NewTransaction(uid uint32) string {
 	g.transactionSeq++
-	return strconv.Itoa(int(uid)) + "-" + strconv.Itoa(int(time.Now().Unix())) + "-" + strconv.Itoa(int(g.transactionSeq))
+	return strconv.Itoa(int(uid)) + "-" + strconv.Itoa(int(clock.Now().Unix())) + "-" + strconv.Itoa(int(g.transactionSeq))
 }
 
 var EXIT_SAVE_FIN_CHAN chan bool
@@ -507,6 +519,25 @@ func (g *Game) SendToSceneA(scene *Scene, cmdId uint16, seq uint32, msg pb.Messa
 	}
 }
 
+// SendToScenePosA 给场景内指定坐标视野范围内的所有玩家发消息
+func (g *Game) SendToScenePosA(scene *Scene, pos *model.Vector, cmdId uint16, seq uint32, msg pb.Message) {
+	world := scene.GetWorld()
+	if WORLD_MANAGER.IsAiWorld(world) {
+		aiWorldAoi := world.GetAiWorldAoi()
+		otherWorldAvatarMap := aiWorldAoi.GetObjectListByPos(float32(pos.X), float32(pos.Y), float32(pos.Z), 1)
+		for uid := range otherWorldAvatarMap {
+			g.SendMsg(cmdId, uint32(uid), seq, msg)
+		}
+	} else {
+		for _, v := range scene.GetAllPlayer() {
+			if !g.IsInVision(pos, g.GetPlayerPos(v), constant.VISION_LEVEL_NORMAL) {
+				continue
+			}
+			g.SendMsg(cmdId, v.PlayerId, seq, msg)
+		}
+	}
+}
+
 // SendToSceneACV 给场景内所有指定客户端版本的玩家发消息
 func (g *Game) SendToSceneACV(scene *Scene, cmdId uint16, seq uint32, msg pb.Message, aecUid uint32, clientVersion int) {
 	world := scene.GetWorld()
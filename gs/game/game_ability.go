@@ -0,0 +1,650 @@
+package game
+
+import (
+	"math"
+	"strconv"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/endec"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 能力执行器
+// action和mixin按配置类型注册处理函数 客户端上报的能力调用与服务端内部触发的能力动作都经由此处分发
+
+const (
+	AbilityServerModifierIdBase = 1 << 31 // 服务端附加的modifier实例id起始值 避免与客户端分配的id冲突
+	AbilityActionMaxDepth       = 8       // 能力动作嵌套触发的最大深度
+	TileAttackRange             = 2.0     // 地块攻击的判定范围
+)
+
+// 物件状态配置名称
+var abilityGadgetStateMap = map[string]uint32{
+	"Default":          constant.GADGET_STATE_DEFAULT,
+	"GatherDrop":       constant.GADGET_STATE_GATHER_DROP,
+	"ChestLocked":      constant.GADGET_STATE_CHEST_LOCKED,
+	"ChestOpened":      constant.GADGET_STATE_CHEST_OPENED,
+	"ChestTrap":        constant.GADGET_STATE_CHEST_TRAP,
+	"ChestBramble":     constant.GADGET_STATE_CHEST_BRAMBLE,
+	"ChestFrozen":      constant.GADGET_STATE_CHEST_FROZEN,
+	"ChestRock":        constant.GADGET_STATE_CHEST_ROCK,
+	"GearStart":        constant.GADGET_STATE_GEAR_START,
+	"GearStop":         constant.GADGET_STATE_GEAR_STOP,
+	"GearAction1":      constant.GADGET_STATE_GEAR_ACTION1,
+	"GearAction2":      constant.GADGET_STATE_GEAR_ACTION2,
+	"CrystalResonate1": constant.GADGET_STATE_CRYSTAL_RESONATE1,
+	"CrystalResonate2": constant.GADGET_STATE_CRYSTAL_RESONATE2,
+	"CrystalExplode":   constant.GADGET_STATE_CRYSTAL_EXPLODE,
+	"CrystalDrain":     constant.GADGET_STATE_CRYSTAL_DRAIN,
+	"StatueActive":     constant.GADGET_STATE_STATUE_ACTIVE,
+	"Action01":         constant.GADGET_STATE_ACTION01,
+	"Action02":         constant.GADGET_STATE_ACTION02,
+	"Action03":         constant.GADGET_STATE_ACTION03,
+}
+
+// AbilityEnv 能力执行依赖的游戏功能 由Game实现
+type AbilityEnv interface {
+	EntityFightPropUpdateNotifyBroadcast(scene *Scene, entity IEntity)
+	KillEntity(player *model.Player, scene *Scene, entityId uint32, dieType proto.PlayerDieType)
+	KillPlayerAvatar(player *model.Player, avatarId uint32, dieType proto.PlayerDieType)
+	ChangeGadgetState(player *model.Player, entityId uint32, state uint32)
+	UpdatePlayerStamina(player *model.Player, staminaCost int32)
+	TriggerQuest(player *model.Player, cond int32, complexParam string, param ...int32)
+	CreateGadget(player *model.Player, pos *model.Vector, gadgetId uint32) uint32
+	CreateDropGadget(player *model.Player, pos *model.Vector, gadgetId, itemId, count uint32) uint32
+}
+
+// AbilityContext 能力执行上下文
+type AbilityContext struct {
+	env         AbilityEnv
+	ability     *Ability
+	abilityData *gdconf.AbilityData
+	entity      IEntity       // 能力所属实体
+	localId     int32         // 客户端上报的配置localId 服务端触发时为0
+	invokeData  []byte        // 客户端上报的原始数据 服务端触发时为空
+	invoker     *model.Player // 上报能力调用的玩家 服务端触发时为空
+	depth       int
+}
+
+func NewAbilityContext(env AbilityEnv, ability *Ability, abilityData *gdconf.AbilityData, entity IEntity, localId int32, invokeData []byte, invoker *model.Player) *AbilityContext {
+	return &AbilityContext{
+		env:         env,
+		ability:     ability,
+		abilityData: abilityData,
+		entity:      entity,
+		localId:     localId,
+		invokeData:  invokeData,
+		invoker:     invoker,
+		depth:       0,
+	}
+}
+
+// 派生出同一次执行链路中其他能力的上下文
+func (c *AbilityContext) fork(ability *Ability, abilityData *gdconf.AbilityData, entity IEntity) *AbilityContext {
+	return &AbilityContext{
+		env:         c.env,
+		ability:     ability,
+		abilityData: abilityData,
+		entity:      entity,
+		localId:     0,
+		invokeData:  nil,
+		invoker:     c.invoker,
+		depth:       c.depth + 1,
+	}
+}
+
+func (c *AbilityContext) GetOwner() *model.Player {
+	return c.entity.GetScene().GetWorld().GetOwner()
+}
+
+// 获取能力所属的玩家 角色实体属于对应玩家 其余实体属于房主
+func (c *AbilityContext) getEntityPlayer(entity IEntity) *model.Player {
+	avatarEntity, ok := entity.(*AvatarEntity)
+	if ok {
+		return entity.GetScene().GetAllPlayer()[avatarEntity.GetUid()]
+	}
+	return entity.GetScene().GetWorld().GetOwner()
+}
+
+// 上报能力调用的玩家是否拥有实体的权威 服务端触发的能力不做限制
+func (c *AbilityContext) hasAuthority(entity IEntity) bool {
+	if c.invoker == nil {
+		return true
+	}
+	player := c.getEntityPlayer(entity)
+	return player != nil && player.PlayerId == c.invoker.PlayerId
+}
+
+func (c *AbilityContext) GetDynamicFloat(dynamicFloat gdconf.DynamicFloat) float32 {
+	return c.ability.GetDynamicFloat(c.abilityData, dynamicFloat)
+}
+
+type AbilityActionHandler func(ctx *AbilityContext, action *gdconf.ActionData)
+
+type AbilityMixinHandler func(ctx *AbilityContext, mixin *gdconf.MixinData)
+
+var abilityActionHandlerMap = make(map[string]AbilityActionHandler)
+var abilityMixinHandlerMap = make(map[string]AbilityMixinHandler)
+
+func RegAbilityActionHandler(actionType string, handler AbilityActionHandler) {
+	abilityActionHandlerMap[actionType] = handler
+}
+
+func RegAbilityMixinHandler(mixinType string, handler AbilityMixinHandler) {
+	abilityMixinHandlerMap[mixinType] = handler
+}
+
+// RegAbilityHandler 注册能力action和mixin的处理方法
+func RegAbilityHandler() {
+	RegAbilityActionHandler("ExecuteGadgetLua", AbilityActionExecuteGadgetLua)
+	RegAbilityActionHandler("KillSelf", AbilityActionKillSelf)
+	RegAbilityActionHandler("AvatarSkillStart", AbilityActionAvatarSkillStart)
+	RegAbilityActionHandler("CreateGadget", AbilityActionCreateGadget)
+	RegAbilityActionHandler("GenerateElemBall", AbilityActionGenerateElemBall)
+	RegAbilityActionHandler("HealHP", AbilityActionHealHP)
+	RegAbilityActionHandler("LoseHP", AbilityActionLoseHP)
+	RegAbilityActionHandler("ApplyModifier", AbilityActionApplyModifier)
+	RegAbilityActionHandler("RemoveModifier", AbilityActionRemoveModifier)
+	RegAbilityActionHandler("AddGlobalValue", AbilityActionAddGlobalValue)
+	RegAbilityActionHandler("SetGlobalValue", AbilityActionSetGlobalValue)
+	RegAbilityActionHandler("TriggerAbility", AbilityActionTriggerAbility)
+	RegAbilityActionHandler("ChangeGadgetState", AbilityActionChangeGadgetState)
+
+	RegAbilityMixinHandler("CostStaminaMixin", AbilityMixinCostStamina)
+	RegAbilityMixinHandler("AttachModifierToSelfGlobalValueMixin", AbilityMixinAttachModifierToSelfGlobalValue)
+	RegAbilityMixinHandler("ShieldBarMixin", AbilityMixinShieldBar)
+	RegAbilityMixinHandler("TileAttackMixin", AbilityMixinTileAttack)
+	RegAbilityMixinHandler("TileAttackManagerMixin", AbilityMixinTileAttack)
+}
+
+// ExecAbilityAction 执行能力动作
+func ExecAbilityAction(ctx *AbilityContext, action *gdconf.ActionData) {
+	logger.Debug("[AbilityAction] type: %v, entityId: %v", action.Type, ctx.entity.GetId())
+	handler, exist := abilityActionHandlerMap[action.Type]
+	if !exist {
+		logger.Error("not support ability action type: %v, abilityName: %v, entityId: %v", action.Type, ctx.ability.abilityName, ctx.entity.GetId())
+		return
+	}
+	handler(ctx, action)
+}
+
+// ExecAbilityMixin 执行能力mixin
+func ExecAbilityMixin(ctx *AbilityContext, mixin *gdconf.MixinData) {
+	logger.Debug("[AbilityMixin] type: %v, entityId: %v", mixin.Type, ctx.entity.GetId())
+	handler, exist := abilityMixinHandlerMap[mixin.Type]
+	if !exist {
+		return
+	}
+	handler(ctx, mixin)
+}
+
+// 执行服务端触发的动作列表 未注册的动作类型多为客户端表现 直接忽略
+func execAbilityActionList(ctx *AbilityContext, actionList []*gdconf.ActionData) {
+	if ctx.depth > AbilityActionMaxDepth {
+		logger.Error("ability action depth limit, abilityName: %v, entityId: %v", ctx.ability.abilityName, ctx.entity.GetId())
+		return
+	}
+	for _, action := range actionList {
+		_, exist := abilityActionHandlerMap[action.Type]
+		if !exist {
+			continue
+		}
+		ExecAbilityAction(ctx, action)
+	}
+}
+
+// 获取能力动作的目标实体列表 队伍类目标为能力所属玩家队伍的角色实体 全体玩家类目标为场景内全部的角色实体 其余均作用于自身
+func getAbilityTargetList(ctx *AbilityContext, target string) []IEntity {
+	switch target {
+	case "Team", "CurTeamAvatars", "AllPlayerAvatars":
+		teamPlayer := ctx.getEntityPlayer(ctx.entity)
+		ret := make([]IEntity, 0)
+		for _, entity := range ctx.entity.GetScene().GetAllEntity() {
+			avatarEntity, ok := entity.(*AvatarEntity)
+			if !ok {
+				continue
+			}
+			if target != "AllPlayerAvatars" && (teamPlayer == nil || avatarEntity.GetUid() != teamPlayer.PlayerId) {
+				continue
+			}
+			ret = append(ret, entity)
+		}
+		return ret
+	default:
+		return []IEntity{ctx.entity}
+	}
+}
+
+/************************************************** 动作 **************************************************/
+
+func AbilityActionExecuteGadgetLua(ctx *AbilityContext, action *gdconf.ActionData) {
+	entity := ctx.entity
+	iGadgetEntity, ok := entity.(IGadgetEntity)
+	if !ok {
+		logger.Error("entity is not gadget, entityId: %v", entity.GetId())
+		return
+	}
+	gadgetDataConfig := gdconf.GetGadgetDataById(int32(iGadgetEntity.GetGadgetId()))
+	if gadgetDataConfig == nil {
+		logger.Error("get gadget data config is nil, gadgetId: %v", iGadgetEntity.GetGadgetId())
+		return
+	}
+	if gadgetDataConfig.ServerLuaScript != "" {
+		gadgetLuaConfig := gdconf.GetGadgetLuaConfigByName(gadgetDataConfig.ServerLuaScript)
+		if gadgetLuaConfig == nil {
+			logger.Error("get gadget lua config is nil, name: %v", gadgetDataConfig.ServerLuaScript)
+			return
+		}
+		CallGadgetLuaFunc(gadgetLuaConfig.LuaState, "OnClientExecuteReq",
+			&LuaCtx{uid: ctx.GetOwner().PlayerId, targetEntityId: entity.GetId(), groupId: entity.GetGroupId()},
+			action.Param1, action.Param2, action.Param3)
+	}
+}
+
+func AbilityActionKillSelf(ctx *AbilityContext, action *gdconf.ActionData) {
+	ctx.env.KillEntity(ctx.GetOwner(), ctx.entity.GetScene(), ctx.entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
+}
+
+func AbilityActionAvatarSkillStart(ctx *AbilityContext, action *gdconf.ActionData) {
+	owner := ctx.GetOwner()
+	staminaCost := ctx.GetDynamicFloat(action.CostStaminaRatio)
+	ctx.env.UpdatePlayerStamina(owner, int32(staminaCost)*-100)
+	ctx.env.TriggerQuest(owner, constant.QUEST_FINISH_COND_TYPE_SKILL, "", action.SkillID)
+}
+
+func AbilityActionCreateGadget(ctx *AbilityContext, action *gdconf.ActionData) {
+	if !action.ByServer {
+		return
+	}
+	ctx.env.CreateGadget(ctx.GetOwner(), ctx.entity.GetPos(), uint32(action.GadgetID))
+}
+
+func AbilityActionGenerateElemBall(ctx *AbilityContext, action *gdconf.ActionData) {
+	itemDataConfig := gdconf.GetItemDataById(action.ConfigID)
+	if itemDataConfig == nil {
+		logger.Error("get item data config is nil, itemId: %v", action.ConfigID)
+		return
+	}
+	if itemDataConfig.GadgetId == 0 {
+		return
+	}
+	baseEnergy := ctx.GetDynamicFloat(action.BaseEnergy)
+	ratio := ctx.GetDynamicFloat(action.Ratio)
+	totalEnergy := baseEnergy * ratio
+	for _, itemUse := range itemDataConfig.ItemUseList {
+		if itemUse.UseOption != constant.ITEM_USE_ADD_ELEM_ENERGY {
+			continue
+		}
+		if len(itemUse.UseParam) != 3 {
+			continue
+		}
+		sameEnergy, err := strconv.Atoi(itemUse.UseParam[1])
+		if err != nil {
+			continue
+		}
+		count := math.Ceil(float64(totalEnergy) / float64(sameEnergy))
+		for i := 0; i < int(count); i++ {
+			ctx.env.CreateDropGadget(ctx.GetOwner(), ctx.entity.GetPos(), uint32(itemDataConfig.GadgetId), uint32(action.ConfigID), 1)
+		}
+	}
+}
+
+// 计算加血扣血的数值 施法者为能力所属实体
+func calcAbilityHpAmount(ctx *AbilityContext, action *gdconf.ActionData, target IEntity) float32 {
+	casterFightProp := ctx.entity.GetFightProp()
+	targetFightProp := target.GetFightProp()
+	amount := ctx.GetDynamicFloat(action.Amount)
+	amount += ctx.GetDynamicFloat(action.AmountByCasterAttackRatio) * casterFightProp[constant.FIGHT_PROP_CUR_ATTACK]
+	amount += ctx.GetDynamicFloat(action.AmountByCasterMaxHPRatio) * casterFightProp[constant.FIGHT_PROP_MAX_HP]
+	amount += ctx.GetDynamicFloat(action.AmountByCasterCurrentHPRatio) * casterFightProp[constant.FIGHT_PROP_CUR_HP]
+	amount += ctx.GetDynamicFloat(action.AmountByTargetMaxHPRatio) * targetFightProp[constant.FIGHT_PROP_MAX_HP]
+	amount += ctx.GetDynamicFloat(action.AmountByTargetCurrentHPRatio) * targetFightProp[constant.FIGHT_PROP_CUR_HP]
+	return amount
+}
+
+// 修改实体血量 血量归零时杀死实体 返回实际的变化量
+func changeAbilityEntityHp(ctx *AbilityContext, entity IEntity, delta float32, lethal bool) float32 {
+	if entity.GetLifeState() == constant.LIFE_STATE_DEAD {
+		return 0.0
+	}
+	scene := entity.GetScene()
+	fightProp := entity.GetFightProp()
+	currHp := fightProp[constant.FIGHT_PROP_CUR_HP]
+	maxHp := fightProp[constant.FIGHT_PROP_MAX_HP]
+	newHp := currHp + delta
+	if newHp > maxHp {
+		newHp = maxHp
+	}
+	if newHp < 0.0 {
+		newHp = 0.0
+	}
+	if !lethal && newHp < 1.0 {
+		newHp = float32(math.Min(1.0, float64(currHp)))
+	}
+	if newHp == currHp {
+		return 0.0
+	}
+	fightProp[constant.FIGHT_PROP_CUR_HP] = newHp
+	ctx.env.EntityFightPropUpdateNotifyBroadcast(scene, entity)
+	if newHp > 0.0 {
+		return newHp - currHp
+	}
+	avatarEntity, ok := entity.(*AvatarEntity)
+	if ok {
+		player := scene.GetAllPlayer()[avatarEntity.GetUid()]
+		if player != nil {
+			ctx.env.KillPlayerAvatar(player, avatarEntity.GetAvatarId(), proto.PlayerDieType_PLAYER_DIE_NONE)
+		}
+	} else {
+		ctx.env.KillEntity(ctx.GetOwner(), scene, entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
+	}
+	return newHp - currHp
+}
+
+// 客户端上报的能力调用只允许修改上报玩家拥有权威的实体血量
+func checkAbilityHpAuthority(ctx *AbilityContext, target IEntity) bool {
+	if ctx.hasAuthority(ctx.entity) && ctx.hasAuthority(target) {
+		return true
+	}
+	logger.Error("ability hp change without authority, invoker: %v, entityId: %v, targetEntityId: %v",
+		ctx.invoker.PlayerId, ctx.entity.GetId(), target.GetId())
+	return false
+}
+
+func AbilityActionHealHP(ctx *AbilityContext, action *gdconf.ActionData) {
+	healRatio := float32(1.0)
+	if action.HealRatio != nil {
+		healRatio = ctx.GetDynamicFloat(action.HealRatio)
+	}
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		if !checkAbilityHpAuthority(ctx, target) {
+			continue
+		}
+		amount := calcAbilityHpAmount(ctx, action, target) * healRatio
+		if amount <= 0.0 {
+			continue
+		}
+		changeAbilityEntityHp(ctx, target, amount, true)
+	}
+}
+
+func AbilityActionLoseHP(ctx *AbilityContext, action *gdconf.ActionData) {
+	lethal := action.IsLethal() && !action.EnableLockHP
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		if isAbilityEntityWuDi(target) {
+			continue
+		}
+		if !checkAbilityHpAuthority(ctx, target) {
+			continue
+		}
+		amount := calcAbilityHpAmount(ctx, action, target)
+		if amount <= 0.0 {
+			continue
+		}
+		changeAbilityEntityHp(ctx, target, -amount, lethal)
+	}
+}
+
+// 实体是否无敌
+func isAbilityEntityWuDi(entity IEntity) bool {
+	switch entity.(type) {
+	case *AvatarEntity:
+		player := entity.GetScene().GetAllPlayer()[entity.(*AvatarEntity).GetUid()]
+		return player != nil && player.WuDi
+	case *MonsterEntity:
+		return entity.GetScene().GetMonsterWudi()
+	default:
+		return false
+	}
+}
+
+// 查找实体上由指定能力附加的modifier
+func findAbilityModifier(entity IEntity, ability *Ability, modifierLocalId uint32) *Modifier {
+	for _, modifier := range entity.GetAllModifier() {
+		if modifier.parentAbilityNameHash == ability.abilityNameHash && modifier.modifierLocalId == modifierLocalId {
+			return modifier
+		}
+	}
+	return nil
+}
+
+// 服务端附加modifier 已存在时不重复附加
+func applyAbilityModifier(ctx *AbilityContext, entity IEntity, modifierName string) {
+	modifierLocalId, exist := ctx.abilityData.Modifiers.GetLocalIdByName(modifierName)
+	if !exist {
+		logger.Error("modifier not exist, modifierName: %v, abilityName: %v", modifierName, ctx.ability.abilityName)
+		return
+	}
+	if findAbilityModifier(entity, ctx.ability, modifierLocalId) != nil {
+		return
+	}
+	instancedModifierId := uint32(AbilityServerModifierIdBase)
+	for _, modifier := range entity.GetAllModifier() {
+		if modifier.instancedModifierId > instancedModifierId {
+			instancedModifierId = modifier.instancedModifierId
+		}
+	}
+	entity.AddModifier(ctx.ability, instancedModifierId+1, modifierLocalId)
+}
+
+func removeAbilityModifier(ctx *AbilityContext, entity IEntity, modifierName string) {
+	modifierLocalId, exist := ctx.abilityData.Modifiers.GetLocalIdByName(modifierName)
+	if !exist {
+		return
+	}
+	modifier := findAbilityModifier(entity, ctx.ability, modifierLocalId)
+	if modifier == nil {
+		return
+	}
+	entity.RemoveModifier(modifier.instancedModifierId)
+}
+
+func AbilityActionApplyModifier(ctx *AbilityContext, action *gdconf.ActionData) {
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		applyAbilityModifier(ctx, target, action.ModifierName)
+	}
+}
+
+func AbilityActionRemoveModifier(ctx *AbilityContext, action *gdconf.ActionData) {
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		removeAbilityModifier(ctx, target, action.ModifierName)
+	}
+}
+
+// 设置实体全局值 并刷新依赖该全局值的mixin
+func setAbilityGlobalValue(ctx *AbilityContext, action *gdconf.ActionData, entity IEntity, value float32) {
+	if action.UseLimitRange {
+		minValue := ctx.GetDynamicFloat(action.MinValue)
+		maxValue := ctx.GetDynamicFloat(action.MaxValue)
+		if value < minValue {
+			value = minValue
+		}
+		if value > maxValue {
+			value = maxValue
+		}
+	}
+	entity.GetDynamicValueMap()[uint32(endec.Hk4eAbilityHashCode(action.Key))] = value
+	refreshGlobalValueMixin(ctx, entity, action.Key)
+}
+
+func AbilityActionAddGlobalValue(ctx *AbilityContext, action *gdconf.ActionData) {
+	key := uint32(endec.Hk4eAbilityHashCode(action.Key))
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		value := target.GetDynamicValueMap()[key] + ctx.GetDynamicFloat(action.Value)
+		setAbilityGlobalValue(ctx, action, target, value)
+	}
+}
+
+func AbilityActionSetGlobalValue(ctx *AbilityContext, action *gdconf.ActionData) {
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		setAbilityGlobalValue(ctx, action, target, ctx.GetDynamicFloat(action.Value))
+	}
+}
+
+// 全局值变化后 重新计算实体身上全部能力中关联该全局值的modifier附加
+func refreshGlobalValueMixin(ctx *AbilityContext, entity IEntity, globalValueKey string) {
+	for _, ability := range entity.GetAllAbility() {
+		abilityDataConfig := gdconf.GetAbilityDataByName(ability.abilityName)
+		if abilityDataConfig == nil {
+			continue
+		}
+		for _, mixin := range abilityDataConfig.AbilityMixins {
+			if mixin.Type != "AttachModifierToSelfGlobalValueMixin" || mixin.GlobalValueKey != globalValueKey {
+				continue
+			}
+			AbilityMixinAttachModifierToSelfGlobalValue(ctx.fork(ability, abilityDataConfig, entity), mixin)
+		}
+	}
+}
+
+func AbilityActionTriggerAbility(ctx *AbilityContext, action *gdconf.ActionData) {
+	abilityDataConfig := gdconf.GetAbilityDataByName(action.AbilityName)
+	if abilityDataConfig == nil {
+		logger.Error("get ability data config is nil, abilityName: %v", action.AbilityName)
+		return
+	}
+	for _, target := range getAbilityTargetList(ctx, action.Target) {
+		var ability *Ability = nil
+		for _, targetAbility := range target.GetAllAbility() {
+			if targetAbility.abilityName == action.AbilityName {
+				ability = targetAbility
+				break
+			}
+		}
+		if ability == nil {
+			// 实体上没有该能力 使用临时的能力实例执行
+			ability = &Ability{
+				abilityName:               action.AbilityName,
+				abilityNameHash:           uint32(endec.Hk4eAbilityHashCode(action.AbilityName)),
+				instancedAbilityId:        0,
+				abilitySpecialOverrideMap: make(map[uint32]float32),
+				shieldBarMap:              make(map[int32]float32),
+			}
+		}
+		execAbilityActionList(ctx.fork(ability, abilityDataConfig, target), abilityDataConfig.OnAbilityStart)
+	}
+}
+
+func AbilityActionChangeGadgetState(ctx *AbilityContext, action *gdconf.ActionData) {
+	_, ok := ctx.entity.(IGadgetEntity)
+	if !ok {
+		logger.Error("entity is not gadget, entityId: %v", ctx.entity.GetId())
+		return
+	}
+	var state uint32 = 0
+	switch action.State.(type) {
+	case float64:
+		state = uint32(action.State.(float64))
+	case string:
+		value, exist := abilityGadgetStateMap[action.State.(string)]
+		if !exist {
+			logger.Error("gadget state not support, state: %v, entityId: %v", action.State, ctx.entity.GetId())
+			return
+		}
+		state = value
+	default:
+		logger.Error("gadget state not support, state: %v, entityId: %v", action.State, ctx.entity.GetId())
+		return
+	}
+	ctx.env.ChangeGadgetState(ctx.GetOwner(), ctx.entity.GetId(), state)
+}
+
+/************************************************** mixin **************************************************/
+
+func AbilityMixinCostStamina(ctx *AbilityContext, mixin *gdconf.MixinData) {
+	staminaCost := ctx.GetDynamicFloat(mixin.CostStaminaDelta)
+	ctx.env.UpdatePlayerStamina(ctx.GetOwner(), int32(staminaCost)*-100)
+}
+
+// AbilityMixinAttachModifierToSelfGlobalValue 按全局值所在的区间附加对应的modifier
+func AbilityMixinAttachModifierToSelfGlobalValue(ctx *AbilityContext, mixin *gdconf.MixinData) {
+	value := ctx.entity.GetDynamicValueMap()[uint32(endec.Hk4eAbilityHashCode(mixin.GlobalValueKey))]
+	stepIndex := -1
+	for index, valueStep := range mixin.ValueSteps {
+		if value >= ctx.GetDynamicFloat(valueStep) {
+			stepIndex = index
+		}
+	}
+	for index, modifierName := range mixin.ModifierNameSteps {
+		if modifierName == "" {
+			continue
+		}
+		if index == stepIndex {
+			applyAbilityModifier(ctx, ctx.entity, modifierName)
+		} else if mixin.RemoveAppliedModifier {
+			removeAbilityModifier(ctx, ctx.entity, modifierName)
+		}
+	}
+}
+
+// AbilityMixinShieldBar 记录护盾值 护盾被击破时执行击破动作
+func AbilityMixinShieldBar(ctx *AbilityContext, mixin *gdconf.MixinData) {
+	if len(ctx.invokeData) == 0 {
+		return
+	}
+	shieldBar := new(proto.AbilityMixinShieldBar)
+	err := pb.Unmarshal(ctx.invokeData, shieldBar)
+	if err != nil {
+		logger.Error("parse AbilityMixinShieldBar error: %v", err)
+		return
+	}
+	lastShield, exist := ctx.ability.shieldBarMap[ctx.localId]
+	if !exist {
+		lastShield = shieldBar.MaxShield
+	}
+	ctx.ability.shieldBarMap[ctx.localId] = shieldBar.Shield
+	if lastShield > 0.0 && shieldBar.Shield <= 0.0 {
+		execAbilityActionList(ctx, mixin.OnShieldBroken)
+	}
+}
+
+// AbilityMixinTileAttack 地块攻击 对范围内敌对的实体造成伤害
+func AbilityMixinTileAttack(ctx *AbilityContext, mixin *gdconf.MixinData) {
+	if mixin.AttackInfo == nil || mixin.AttackInfo.AttackProperty == nil {
+		return
+	}
+	damage := ctx.entity.GetFightProp()[constant.FIGHT_PROP_CUR_ATTACK] * ctx.GetDynamicFloat(mixin.AttackInfo.AttackProperty.DamagePercentage)
+	if damage <= 0.0 {
+		return
+	}
+	// 伤害与范围均由服务端计算 只需校验上报玩家拥有攻击来源的权威
+	if !ctx.hasAuthority(ctx.entity) {
+		logger.Error("tile attack without authority, invoker: %v, entityId: %v", ctx.invoker.PlayerId, ctx.entity.GetId())
+		return
+	}
+	_, isAvatar := ctx.entity.(*AvatarEntity)
+	pos := ctx.entity.GetPos()
+	for _, target := range ctx.entity.GetScene().GetAllEntity() {
+		if target.GetId() == ctx.entity.GetId() {
+			continue
+		}
+		// 角色的地块攻击怪物 其余实体的地块攻击角色
+		switch target.(type) {
+		case *AvatarEntity:
+			if isAvatar {
+				continue
+			}
+		case *MonsterEntity:
+			if !isAvatar {
+				continue
+			}
+		default:
+			continue
+		}
+		targetPos := target.GetPos()
+		dx := targetPos.X - pos.X
+		dz := targetPos.Z - pos.Z
+		if math.Sqrt(dx*dx+dz*dz) > TileAttackRange {
+			continue
+		}
+		if isAbilityEntityWuDi(target) {
+			continue
+		}
+		changeAbilityEntityHp(ctx, target, -damage, true)
+	}
+}
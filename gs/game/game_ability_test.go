@@ -0,0 +1,533 @@
+package game
+
+import (
+	"os"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/endec"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger(nil)
+	RegAbilityHandler()
+	RegQuestHandler()
+	code := m.Run()
+	logger.CloseLogger()
+	os.Exit(code)
+}
+
+type fakeAbilityEnv struct {
+	fightPropNotifyCount int
+	killEntityIdList     []uint32
+	killAvatarIdList     []uint32
+	gadgetStateMap       map[uint32]uint32
+	staminaCost          int32
+}
+
+func newFakeAbilityEnv() *fakeAbilityEnv {
+	return &fakeAbilityEnv{
+		fightPropNotifyCount: 0,
+		killEntityIdList:     make([]uint32, 0),
+		killAvatarIdList:     make([]uint32, 0),
+		gadgetStateMap:       make(map[uint32]uint32),
+		staminaCost:          0,
+	}
+}
+
+func (f *fakeAbilityEnv) EntityFightPropUpdateNotifyBroadcast(scene *Scene, entity IEntity) {
+	f.fightPropNotifyCount++
+}
+
+func (f *fakeAbilityEnv) KillEntity(player *model.Player, scene *Scene, entityId uint32, dieType proto.PlayerDieType) {
+	f.killEntityIdList = append(f.killEntityIdList, entityId)
+}
+
+func (f *fakeAbilityEnv) KillPlayerAvatar(player *model.Player, avatarId uint32, dieType proto.PlayerDieType) {
+	f.killAvatarIdList = append(f.killAvatarIdList, avatarId)
+}
+
+func (f *fakeAbilityEnv) ChangeGadgetState(player *model.Player, entityId uint32, state uint32) {
+	f.gadgetStateMap[entityId] = state
+}
+
+func (f *fakeAbilityEnv) UpdatePlayerStamina(player *model.Player, staminaCost int32) {
+	f.staminaCost += staminaCost
+}
+
+func (f *fakeAbilityEnv) TriggerQuest(player *model.Player, cond int32, complexParam string, param ...int32) {
+}
+
+func (f *fakeAbilityEnv) CreateGadget(player *model.Player, pos *model.Vector, gadgetId uint32) uint32 {
+	return 0
+}
+
+func (f *fakeAbilityEnv) CreateDropGadget(player *model.Player, pos *model.Vector, gadgetId, itemId, count uint32) uint32 {
+	return 0
+}
+
+const (
+	testAbilityName        = "Test_Ability"
+	testTriggerAbilityName = "Test_Trigger_Ability"
+	testGlobalValueKey     = "_Test_Global_Value"
+)
+
+func newTestAbilityData() *gdconf.AbilityData {
+	return &gdconf.AbilityData{
+		AbilityName: testAbilityName,
+		Modifiers: gdconf.ModifierOrderMap{
+			KeyOrder: []string{"Test_Modifier_A", "Test_Modifier_B"},
+			Map: map[string]*gdconf.ModifierData{
+				"Test_Modifier_A": {},
+				"Test_Modifier_B": {},
+			},
+		},
+		AbilitySpecials: map[string]float32{"HealAmount": 100.0},
+		AbilityMixins: []*gdconf.MixinData{{
+			Type:                  "AttachModifierToSelfGlobalValueMixin",
+			GlobalValueKey:        testGlobalValueKey,
+			RemoveAppliedModifier: true,
+			ValueSteps:            []gdconf.DynamicFloat{-0.5, 0.5},
+			ModifierNameSteps:     gdconf.StringArray{"Test_Modifier_A", "Test_Modifier_B"},
+		}},
+	}
+}
+
+type testAbilityScene struct {
+	env         *fakeAbilityEnv
+	scene       *Scene
+	player      *model.Player
+	abilityData *gdconf.AbilityData
+}
+
+func newTestAbilityScene(t *testing.T) *testAbilityScene {
+	abilityData := newTestAbilityData()
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		AbilityDataMap: map[string]*gdconf.AbilityData{
+			testAbilityName: abilityData,
+			testTriggerAbilityName: {
+				AbilityName: testTriggerAbilityName,
+				OnAbilityStart: []*gdconf.ActionData{
+					{Type: "SetGlobalValue", Key: testGlobalValueKey, Value: 3.0},
+					{Type: "ActCameraShake"},
+				},
+			},
+		},
+	})
+	player := &model.Player{PlayerId: 10001}
+	world := &World{owner: player}
+	scene := &Scene{
+		id:        3,
+		world:     world,
+		playerMap: map[uint32]*model.Player{player.PlayerId: player},
+		entityMap: make(map[uint32]IEntity),
+	}
+	return &testAbilityScene{
+		env:         newFakeAbilityEnv(),
+		scene:       scene,
+		player:      player,
+		abilityData: abilityData,
+	}
+}
+
+func (s *testAbilityScene) newEntity(entityId uint32, pos *model.Vector, curHp float32, maxHp float32, attack float32) *Entity {
+	entity := &Entity{
+		id:        entityId,
+		scene:     s.scene,
+		lifeState: constant.LIFE_STATE_ALIVE,
+		pos:       pos,
+		rot:       new(model.Vector),
+		fightProp: map[uint32]float32{
+			constant.FIGHT_PROP_CUR_HP:     curHp,
+			constant.FIGHT_PROP_MAX_HP:     maxHp,
+			constant.FIGHT_PROP_CUR_ATTACK: attack,
+		},
+		abilityMap:      make(map[uint32]*Ability),
+		modifierMap:     make(map[uint32]*Modifier),
+		dynamicValueMap: make(map[uint32]float32),
+	}
+	entity.AddAbility(testAbilityName, 1)
+	return entity
+}
+
+func (s *testAbilityScene) addMonster(entityId uint32, pos *model.Vector, curHp float32, maxHp float32, attack float32) *MonsterEntity {
+	monsterEntity := &MonsterEntity{Entity: s.newEntity(entityId, pos, curHp, maxHp, attack)}
+	s.scene.entityMap[entityId] = monsterEntity
+	return monsterEntity
+}
+
+func (s *testAbilityScene) addAvatar(entityId uint32, pos *model.Vector, curHp float32, maxHp float32) *AvatarEntity {
+	return s.addPlayerAvatar(s.player, entityId, pos, curHp, maxHp)
+}
+
+func (s *testAbilityScene) addPlayerAvatar(player *model.Player, entityId uint32, pos *model.Vector, curHp float32, maxHp float32) *AvatarEntity {
+	avatarEntity := &AvatarEntity{Entity: s.newEntity(entityId, pos, curHp, maxHp, 0.0), uid: player.PlayerId, avatarId: 10000000 + entityId}
+	s.scene.entityMap[entityId] = avatarEntity
+	return avatarEntity
+}
+
+// 加入一个联机的客人玩家
+func (s *testAbilityScene) addGuest() *model.Player {
+	guest := &model.Player{PlayerId: 10002}
+	s.scene.playerMap[guest.PlayerId] = guest
+	return guest
+}
+
+func (s *testAbilityScene) addGadget(entityId uint32, pos *model.Vector, attack float32) *GadgetEntity {
+	gadgetEntity := &GadgetEntity{Entity: s.newEntity(entityId, pos, 1.0, 1.0, attack)}
+	s.scene.entityMap[entityId] = gadgetEntity
+	return gadgetEntity
+}
+
+func (s *testAbilityScene) newContext(entity IEntity, invokeData []byte) *AbilityContext {
+	return s.newInvokerContext(s.player, entity, invokeData)
+}
+
+func (s *testAbilityScene) newInvokerContext(invoker *model.Player, entity IEntity, invokeData []byte) *AbilityContext {
+	return NewAbilityContext(s.env, entity.GetAbility(1), s.abilityData, entity, 0, invokeData, invoker)
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestRegAbilityHandler(t *testing.T) {
+	for _, actionType := range []string{"HealHP", "LoseHP", "ApplyModifier", "RemoveModifier", "AddGlobalValue", "SetGlobalValue", "TriggerAbility", "ChangeGadgetState"} {
+		if _, exist := abilityActionHandlerMap[actionType]; !exist {
+			t.Fatalf("action handler not register: %v", actionType)
+		}
+	}
+	for _, mixinType := range []string{"AttachModifierToSelfGlobalValueMixin", "ShieldBarMixin", "TileAttackMixin"} {
+		if _, exist := abilityMixinHandlerMap[mixinType]; !exist {
+			t.Fatalf("mixin handler not register: %v", mixinType)
+		}
+	}
+}
+
+func TestAbilityActionHealHP(t *testing.T) {
+	testCaseList := []struct {
+		name   string
+		action *gdconf.ActionData
+		curHp  float32
+		wantHp float32
+	}{
+		{"amount", &gdconf.ActionData{Type: "HealHP", Amount: 100.0}, 500.0, 600.0},
+		{"ability special amount", &gdconf.ActionData{Type: "HealHP", Amount: "%HealAmount"}, 500.0, 600.0},
+		{"caster max hp ratio", &gdconf.ActionData{Type: "HealHP", AmountByCasterMaxHPRatio: 0.2}, 500.0, 700.0},
+		{"heal ratio", &gdconf.ActionData{Type: "HealHP", Amount: 100.0, HealRatio: 0.5}, 500.0, 550.0},
+		{"max hp limit", &gdconf.ActionData{Type: "HealHP", Amount: 800.0}, 500.0, 1000.0},
+		{"zero amount", &gdconf.ActionData{Type: "HealHP"}, 500.0, 500.0},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		monsterEntity := s.addMonster(100, new(model.Vector), testCase.curHp, 1000.0, 0.0)
+		ExecAbilityAction(s.newContext(monsterEntity, nil), testCase.action)
+		if got := monsterEntity.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != testCase.wantHp {
+			t.Fatalf("%v: got hp %v, want %v", testCase.name, got, testCase.wantHp)
+		}
+	}
+}
+
+func TestAbilityActionLoseHP(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		action      *gdconf.ActionData
+		monsterWudi bool
+		wantHp      float32
+		wantKill    bool
+	}{
+		{"amount", &gdconf.ActionData{Type: "LoseHP", Amount: 100.0}, false, 400.0, false},
+		{"target max hp ratio", &gdconf.ActionData{Type: "LoseHP", AmountByTargetMaxHPRatio: 0.1}, false, 400.0, false},
+		{"lethal by default", &gdconf.ActionData{Type: "LoseHP", Amount: 600.0}, false, 0.0, true},
+		{"not lethal", &gdconf.ActionData{Type: "LoseHP", Amount: 600.0, Lethal: boolPtr(false)}, false, 1.0, false},
+		{"lock hp", &gdconf.ActionData{Type: "LoseHP", Amount: 600.0, EnableLockHP: true}, false, 1.0, false},
+		{"monster wudi", &gdconf.ActionData{Type: "LoseHP", Amount: 100.0}, true, 500.0, false},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		s.scene.SetMonsterWudi(testCase.monsterWudi)
+		monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 0.0)
+		ExecAbilityAction(s.newContext(monsterEntity, nil), testCase.action)
+		if got := monsterEntity.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != testCase.wantHp {
+			t.Fatalf("%v: got hp %v, want %v", testCase.name, got, testCase.wantHp)
+		}
+		if kill := len(s.env.killEntityIdList) != 0; kill != testCase.wantKill {
+			t.Fatalf("%v: got kill %v, want %v", testCase.name, kill, testCase.wantKill)
+		}
+	}
+}
+
+func TestAbilityActionLoseHPAvatar(t *testing.T) {
+	s := newTestAbilityScene(t)
+	avatarEntity := s.addAvatar(200, new(model.Vector), 100.0, 1000.0)
+	ExecAbilityAction(s.newContext(avatarEntity, nil), &gdconf.ActionData{Type: "LoseHP", Amount: 200.0})
+	if len(s.env.killAvatarIdList) != 1 || s.env.killAvatarIdList[0] != avatarEntity.GetAvatarId() {
+		t.Fatalf("avatar not kill, kill list: %v", s.env.killAvatarIdList)
+	}
+	s = newTestAbilityScene(t)
+	s.player.WuDi = true
+	avatarEntity = s.addAvatar(200, new(model.Vector), 100.0, 1000.0)
+	ExecAbilityAction(s.newContext(avatarEntity, nil), &gdconf.ActionData{Type: "LoseHP", Amount: 200.0})
+	if got := avatarEntity.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 100.0 || len(s.env.killAvatarIdList) != 0 {
+		t.Fatalf("wudi avatar lose hp, hp: %v", got)
+	}
+}
+
+func TestAbilityTeamTarget(t *testing.T) {
+	s := newTestAbilityScene(t)
+	guest := s.addGuest()
+	hostAvatar := s.addAvatar(200, new(model.Vector), 500.0, 1000.0)
+	guestAvatar := s.addPlayerAvatar(guest, 201, new(model.Vector), 500.0, 1000.0)
+	ExecAbilityAction(s.newInvokerContext(guest, guestAvatar, nil), &gdconf.ActionData{Type: "HealHP", Amount: 100.0, Target: "Team"})
+	if got := guestAvatar.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 600.0 {
+		t.Fatalf("guest team avatar not heal, hp: %v", got)
+	}
+	if got := hostAvatar.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 500.0 {
+		t.Fatalf("host avatar heal by guest team ability, hp: %v", got)
+	}
+	targetList := getAbilityTargetList(s.newContext(hostAvatar, nil), "AllPlayerAvatars")
+	if len(targetList) != 2 {
+		t.Fatalf("all player avatars target count: %v", len(targetList))
+	}
+}
+
+func TestAbilityHpAuthority(t *testing.T) {
+	s := newTestAbilityScene(t)
+	guest := s.addGuest()
+	hostAvatar := s.addAvatar(200, new(model.Vector), 500.0, 1000.0)
+	monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 100.0)
+	// 客人上报房主角色与房主权威怪物的血量变化
+	ExecAbilityAction(s.newInvokerContext(guest, hostAvatar, nil), &gdconf.ActionData{Type: "LoseHP", Amount: 200.0})
+	ExecAbilityAction(s.newInvokerContext(guest, monsterEntity, nil), &gdconf.ActionData{Type: "HealHP", Amount: 200.0})
+	ExecAbilityMixin(s.newInvokerContext(guest, monsterEntity, nil), &gdconf.MixinData{
+		Type:       "TileAttackMixin",
+		AttackInfo: &gdconf.AttackInfoData{AttackProperty: &gdconf.AttackPropertyData{DamagePercentage: 1.0}},
+	})
+	if got := hostAvatar.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 500.0 {
+		t.Fatalf("host avatar hp change by guest, hp: %v", got)
+	}
+	if got := monsterEntity.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 500.0 {
+		t.Fatalf("monster hp change by guest, hp: %v", got)
+	}
+	// 服务端触发的能力不做限制
+	ExecAbilityAction(s.newInvokerContext(nil, hostAvatar, nil), &gdconf.ActionData{Type: "LoseHP", Amount: 200.0})
+	if got := hostAvatar.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != 300.0 {
+		t.Fatalf("server ability hp change fail, hp: %v", got)
+	}
+}
+
+func TestAbilityActionModifier(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		actionList []*gdconf.ActionData
+		wantList   []uint32
+	}{
+		{"apply", []*gdconf.ActionData{
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_B"},
+		}, []uint32{1}},
+		{"apply twice", []*gdconf.ActionData{
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_A"},
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_A"},
+		}, []uint32{0}},
+		{"apply and remove", []*gdconf.ActionData{
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_A"},
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_B"},
+			{Type: "RemoveModifier", ModifierName: "Test_Modifier_A"},
+		}, []uint32{1}},
+		{"remove not exist", []*gdconf.ActionData{
+			{Type: "RemoveModifier", ModifierName: "Test_Modifier_A"},
+		}, []uint32{}},
+		{"apply unknown", []*gdconf.ActionData{
+			{Type: "ApplyModifier", ModifierName: "Test_Modifier_Unknown"},
+		}, []uint32{}},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 0.0)
+		for _, action := range testCase.actionList {
+			ExecAbilityAction(s.newContext(monsterEntity, nil), action)
+		}
+		modifierList := monsterEntity.GetAllModifier()
+		if len(modifierList) != len(testCase.wantList) {
+			t.Fatalf("%v: got modifier count %v, want %v", testCase.name, len(modifierList), len(testCase.wantList))
+		}
+		for index, modifier := range modifierList {
+			if modifier.modifierLocalId != testCase.wantList[index] {
+				t.Fatalf("%v: got modifier local id %v, want %v", testCase.name, modifier.modifierLocalId, testCase.wantList[index])
+			}
+			if modifier.instancedModifierId <= AbilityServerModifierIdBase {
+				t.Fatalf("%v: instanced modifier id not in server range: %v", testCase.name, modifier.instancedModifierId)
+			}
+		}
+	}
+}
+
+func TestAbilityActionGlobalValue(t *testing.T) {
+	testCaseList := []struct {
+		name         string
+		actionList   []*gdconf.ActionData
+		wantValue    float32
+		wantModifier int // 期望附加的modifier localId -1表示没有
+	}{
+		{"set", []*gdconf.ActionData{
+			{Type: "SetGlobalValue", Key: testGlobalValueKey, Value: 0.0},
+		}, 0.0, 0},
+		{"set limit range", []*gdconf.ActionData{
+			{Type: "SetGlobalValue", Key: testGlobalValueKey, Value: 5.0, UseLimitRange: true, MinValue: 0.0, MaxValue: 2.0},
+		}, 2.0, 1},
+		{"add", []*gdconf.ActionData{
+			{Type: "AddGlobalValue", Key: testGlobalValueKey, Value: -1.0},
+			{Type: "AddGlobalValue", Key: testGlobalValueKey, Value: -1.0},
+		}, -2.0, -1},
+		{"add step change", []*gdconf.ActionData{
+			{Type: "SetGlobalValue", Key: testGlobalValueKey, Value: 0.0},
+			{Type: "AddGlobalValue", Key: testGlobalValueKey, Value: 1.0},
+		}, 1.0, 1},
+		{"add limit range", []*gdconf.ActionData{
+			{Type: "AddGlobalValue", Key: testGlobalValueKey, Value: 3.0, UseLimitRange: true, MinValue: 0.0, MaxValue: 1.0},
+			{Type: "AddGlobalValue", Key: testGlobalValueKey, Value: 3.0, UseLimitRange: true, MinValue: 0.0, MaxValue: 1.0},
+		}, 1.0, 1},
+	}
+	key := uint32(endec.Hk4eAbilityHashCode(testGlobalValueKey))
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 0.0)
+		for _, action := range testCase.actionList {
+			ExecAbilityAction(s.newContext(monsterEntity, nil), action)
+		}
+		if got := monsterEntity.GetDynamicValueMap()[key]; got != testCase.wantValue {
+			t.Fatalf("%v: got value %v, want %v", testCase.name, got, testCase.wantValue)
+		}
+		modifierList := monsterEntity.GetAllModifier()
+		if testCase.wantModifier == -1 {
+			if len(modifierList) != 0 {
+				t.Fatalf("%v: got modifier count %v, want 0", testCase.name, len(modifierList))
+			}
+			continue
+		}
+		if len(modifierList) != 1 || modifierList[0].modifierLocalId != uint32(testCase.wantModifier) {
+			t.Fatalf("%v: modifier error, count: %v, want local id: %v", testCase.name, len(modifierList), testCase.wantModifier)
+		}
+	}
+}
+
+func TestAbilityActionTriggerAbility(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		abilityName string
+		wantValue   float32
+	}{
+		{"trigger", testTriggerAbilityName, 3.0},
+		{"trigger unknown", "Test_Unknown_Ability", 0.0},
+	}
+	key := uint32(endec.Hk4eAbilityHashCode(testGlobalValueKey))
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 0.0)
+		ExecAbilityAction(s.newContext(monsterEntity, nil), &gdconf.ActionData{Type: "TriggerAbility", AbilityName: testCase.abilityName})
+		if got := monsterEntity.GetDynamicValueMap()[key]; got != testCase.wantValue {
+			t.Fatalf("%v: got value %v, want %v", testCase.name, got, testCase.wantValue)
+		}
+	}
+}
+
+func TestAbilityActionChangeGadgetState(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		state     gdconf.DynamicFloat
+		gadget    bool
+		wantState uint32
+		wantOk    bool
+	}{
+		{"state name", "GearStart", true, constant.GADGET_STATE_GEAR_START, true},
+		{"state number", 202.0, true, constant.GADGET_STATE_GEAR_STOP, true},
+		{"unknown state name", "Unknown", true, 0, false},
+		{"not gadget", "GearStart", false, 0, false},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		var entity IEntity = nil
+		if testCase.gadget {
+			entity = s.addGadget(300, new(model.Vector), 0.0)
+		} else {
+			entity = s.addMonster(300, new(model.Vector), 500.0, 1000.0, 0.0)
+		}
+		ExecAbilityAction(s.newContext(entity, nil), &gdconf.ActionData{Type: "ChangeGadgetState", State: testCase.state})
+		state, ok := s.env.gadgetStateMap[entity.GetId()]
+		if ok != testCase.wantOk || state != testCase.wantState {
+			t.Fatalf("%v: got state %v %v, want %v %v", testCase.name, state, ok, testCase.wantState, testCase.wantOk)
+		}
+	}
+}
+
+func TestAbilityMixinShieldBar(t *testing.T) {
+	mixin := &gdconf.MixinData{
+		Type:           "ShieldBarMixin",
+		OnShieldBroken: []*gdconf.ActionData{{Type: "ActCameraShake"}, {Type: "KillSelf"}},
+	}
+	testCaseList := []struct {
+		name       string
+		shieldList []float32
+		wantBroken int
+	}{
+		{"not broken", []float32{100.0, 50.0}, 0},
+		{"broken", []float32{100.0, 50.0, 0.0}, 1},
+		{"broken at first hit", []float32{0.0}, 1},
+		{"broken once", []float32{50.0, 0.0, 0.0}, 1},
+		{"broken again after recover", []float32{0.0, 100.0, 0.0}, 2},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		monsterEntity := s.addMonster(100, new(model.Vector), 500.0, 1000.0, 0.0)
+		for _, shield := range testCase.shieldList {
+			invokeData, err := pb.Marshal(&proto.AbilityMixinShieldBar{MaxShield: 100.0, Shield: shield})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ExecAbilityMixin(s.newContext(monsterEntity, invokeData), mixin)
+		}
+		if got := len(s.env.killEntityIdList); got != testCase.wantBroken {
+			t.Fatalf("%v: got broken %v, want %v", testCase.name, got, testCase.wantBroken)
+		}
+	}
+}
+
+func TestAbilityMixinTileAttack(t *testing.T) {
+	mixin := &gdconf.MixinData{
+		Type:     "TileAttackManagerMixin",
+		AttackID: "TileAtk_Test",
+		AttackInfo: &gdconf.AttackInfoData{
+			AttackProperty: &gdconf.AttackPropertyData{DamagePercentage: 0.5, ElementType: "Fire"},
+		},
+	}
+	testCaseList := []struct {
+		name   string
+		pos    *model.Vector
+		avatar bool
+		wantHp float32
+	}{
+		{"avatar in range", &model.Vector{X: 1.0, Y: 0.0, Z: 1.0}, true, 950.0},
+		{"avatar out of range", &model.Vector{X: 5.0, Y: 0.0, Z: 0.0}, true, 1000.0},
+		{"monster in range", &model.Vector{X: 1.0, Y: 0.0, Z: 0.0}, false, 1000.0},
+	}
+	for _, testCase := range testCaseList {
+		s := newTestAbilityScene(t)
+		gadgetEntity := s.addGadget(300, new(model.Vector), 100.0)
+		var target IEntity = nil
+		if testCase.avatar {
+			target = s.addAvatar(200, testCase.pos, 1000.0, 1000.0)
+		} else {
+			target = s.addMonster(100, testCase.pos, 1000.0, 1000.0, 0.0)
+		}
+		ExecAbilityMixin(s.newContext(gadgetEntity, nil), mixin)
+		if got := target.GetFightProp()[constant.FIGHT_PROP_CUR_HP]; got != testCase.wantHp {
+			t.Fatalf("%v: got hp %v, want %v", testCase.name, got, testCase.wantHp)
+		}
+	}
+}
@@ -29,7 +29,7 @@ func (g *GMCmd) GMTeleportPlayer(userId, sceneId uint32, posX, posY, posZ float6
 		logger.Error("player is nil, uid: %v", userId)
 		return
 	}
-	GAME.TeleportPlayer(
+	ok := GAME.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_GM,
 		sceneId,
@@ -38,6 +38,9 @@ func (g *GMCmd) GMTeleportPlayer(userId, sceneId uint32, posX, posY, posZ float6
 		0,
 		0,
 	)
+	if !ok {
+		logger.Error("teleport player fail, sceneId: %v, uid: %v", sceneId, userId)
+	}
 }
 
 // GMAddItem 添加玩家道具
@@ -370,7 +373,13 @@ func (g *GMCmd) GMSetWeather(userId uint32, climateType uint32) {
 		logger.Error("player is nil, uid: %v", userId)
 		return
 	}
-	GAME.SetPlayerWeather(player, player.WeatherInfo.WeatherAreaId, climateType, true)
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, userId)
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	GAME.SetSceneWeather(scene, player.WeatherInfo.WeatherAreaId, climateType)
 }
 
 // GMCreateMonster 在玩家附近创建怪物
@@ -790,6 +799,21 @@ func (g *GMCmd) StopPubg() {
 	pluginPubg.StopPubg()
 }
 
+// ReloadLuaPlugin 重新加载lua插件 插件名为空则重新加载插件目录下的全部插件
+func (g *GMCmd) ReloadLuaPlugin(name string) {
+	err := PLUGIN_MANAGER.ReloadLuaPlugin(name)
+	if err != nil {
+		logger.Error("reload lua plugin error: %v", err)
+		return
+	}
+	logger.Info("reload lua plugin finish, loaded: %v", PLUGIN_MANAGER.GetLuaPluginNameList())
+}
+
+// UnloadLuaPlugin 卸载lua插件
+func (g *GMCmd) UnloadLuaPlugin(name string) {
+	PLUGIN_MANAGER.DelLuaPlugin(name)
+}
+
 func (g *GMCmd) SetPhysicsEngineParam(pathTracing bool) {
 	world := WORLD_MANAGER.GetAiWorld()
 	engine := world.GetBulletPhysicsEngine()
@@ -829,6 +853,21 @@ func (g *GMCmd) AiWorldAoiDebug() {
 	}
 }
 
+// SetWorldMonsterAi 开关玩家所在世界的服务端怪物ai
+func (g *GMCmd) SetWorldMonsterAi(userId uint32, enable bool) {
+	player := USER_MANAGER.GetOnlineUser(userId)
+	if player == nil {
+		logger.Error("player is nil, uid: %v", userId)
+		return
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, userId)
+		return
+	}
+	world.GetMonsterAiManager().SetEnable(enable)
+}
+
 func (g *GMCmd) GetPlayerData(userId uint32) *model.Player {
 	player := USER_MANAGER.GetOnlineUser(userId)
 	if player == nil {
@@ -855,3 +894,20 @@ func (g *GMCmd) SendMail(userId uint32, title string, content string) {
 	}
 	GAME.AddPlayerMail(userId, title, content)
 }
+
+// ReconcileOrder 充值订单对账 补发漏掉的已支付订单 uid为0时对本服全部在线玩家对账
+func (g *GMCmd) ReconcileOrder(userId uint32) int {
+	if userId == 0 {
+		count := 0
+		for _, player := range USER_MANAGER.GetAllOnlineUserList() {
+			count += GAME.ReconcilePlayerOrder(player)
+		}
+		return count
+	}
+	player := USER_MANAGER.GetOnlineUser(userId)
+	if player == nil {
+		logger.Error("player is nil, uid: %v", userId)
+		return 0
+	}
+	return GAME.ReconcilePlayerOrder(player)
+}
@@ -350,7 +350,11 @@ func (c *CommandManager) DelAllController(controllerList ...*CommandController)
 func (c *CommandManager) DelController(controller *CommandController) {
 	// 支持一个命令拥有多个别名
 	for _, name := range controller.AliasList {
-		delete(c.commandControllerMap, name)
+		name = strings.ToLower(name)
+		// 别名已被其他命令覆盖则不删除
+		if c.commandControllerMap[name] == controller {
+			delete(c.commandControllerMap, name)
+		}
 	}
 	// 卸载列表上的控制器
 	for i, commandController := range c.commandControllerList {
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"hk4e/pkg/metrics"
+	"hk4e/protocol/cmd"
+)
+
+// 游戏服务器监控指标
+
+var (
+	metricsTickCost = metrics.NewHistogram("gs_main_loop_tick_seconds", "gs main loop global tick cost",
+		metrics.DefaultLatencyBuckets)
+	metricsRouteCost = metrics.NewHistogram("gs_cmd_handler_seconds", "gs client cmd handler cost and count by cmd name",
+		metrics.DefaultLatencyBuckets, "cmd")
+	metricsWorldNum        = metrics.NewGauge("gs_world_num", "gs current world num")
+	metricsSceneNum        = metrics.NewGauge("gs_scene_num", "gs current scene num")
+	metricsSaveUserQueue   = metrics.NewGauge("gs_save_user_queue_len", "gs save user chan queue len")
+	metricsAsyncWriteQueue = metrics.NewGauge("gs_async_write_db_queue_len", "gs async write db chan queue len")
+)
+
+func init() {
+	metrics.NewGaugeFunc("gs_online_player_num", "gs current online player num", func() float64 {
+		return float64(atomic.LoadInt32(&ONLINE_PLAYER_NUM))
+	})
+}
+
+// cmdNameCacheMap 只在主协程访问 k:cmdId v:cmdName
+var cmdNameCacheMap = make(map[uint16]string)
+
+func getCmdNameCache(cmdId uint16) string {
+	cmdName, exist := cmdNameCacheMap[cmdId]
+	if exist {
+		return cmdName
+	}
+	if cmdProtoMap == nil {
+		cmdProtoMap = cmd.NewCmdProtoMap()
+	}
+	cmdName = cmdProtoMap.GetCmdNameByCmdId(cmdId)
+	if cmdName == "" {
+		cmdName = strconv.Itoa(int(cmdId))
+	}
+	cmdNameCacheMap[cmdId] = cmdName
+	return cmdName
+}
+
+// updateGameMetrics 主协程内采集只能在主协程访问的数据
+func updateGameMetrics() {
+	worldMap := WORLD_MANAGER.GetAllWorld()
+	sceneNum := 0
+	for _, world := range worldMap {
+		sceneNum += len(world.GetAllScene())
+	}
+	metricsWorldNum.Set(float64(len(worldMap)))
+	metricsSceneNum.Set(float64(sceneNum))
+	metricsSaveUserQueue.Set(float64(len(USER_MANAGER.saveUserChan)))
+	metricsAsyncWriteQueue.Set(float64(len(USER_MANAGER.asyncWriteDbChan)))
+}
@@ -0,0 +1,418 @@
+package game
+
+import (
+	"math"
+	"os"
+
+	"hk4e/common/constant"
+	"hk4e/gs/model"
+	"hk4e/pkg/navmesh"
+	"hk4e/pkg/navmesh/format"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 服务端怪物ai
+// 没有客户端做怪物权威的无头场景(ai世界等)由服务端驱动怪物 受击仇恨 脱战拉回 寻路追击
+
+const (
+	MonsterAiTickTime          = 200  // 怪物ai tick间隔毫秒
+	MonsterAiMoveSpeed         = 4.0  // 怪物移动速度 米每秒
+	MonsterAiAttackRange       = 2.0  // 怪物攻击距离 进入该距离后停止追击
+	MonsterAiLeashRadius       = 30.0 // 怪物脱战半径 离开出生点超过该距离后返回出生点
+	MonsterAiRepathDistance    = 1.0  // 追击目标移动超过该距离后重新寻路
+	MonsterAiArriveDistance    = 0.1  // 到达路径点的判定距离
+	MonsterAiNavMeshMaxIter    = 100  // navmesh寻路最大迭代次数
+	MonsterAiNavMeshSampleDist = 1.0  // navmesh采样点的最大偏移距离
+)
+
+const (
+	MonsterAiStateIdle   = iota // 待机
+	MonsterAiStateChase         // 追击
+	MonsterAiStateReturn        // 脱战返回出生点
+)
+
+// MonsterAiPathFinder 怪物寻路
+type MonsterAiPathFinder interface {
+	// FindPath 返回不包含起点的路径拐点列表
+	FindPath(sceneId uint32, source, target *model.Vector) ([]*model.Vector, bool)
+}
+
+// NavMeshPathFinder 基于navmesh的寻路 没有navmesh数据的场景直线移动
+type NavMeshPathFinder struct {
+	navMeshManagerMap map[uint32]*navmesh.NavMeshManager
+}
+
+func NewNavMeshPathFinder(navMeshPath string) (r *NavMeshPathFinder) {
+	r = new(NavMeshPathFinder)
+	r.navMeshManagerMap = make(map[uint32]*navmesh.NavMeshManager)
+	if navMeshPath == "" {
+		return r
+	}
+	fileList, err := os.ReadDir(navMeshPath)
+	if err != nil {
+		logger.Error("open navmesh dir error: %v", err)
+		return r
+	}
+	navMeshDataMap := make(map[uint32]*format.NavMeshData)
+	for _, file := range fileList {
+		if file.IsDir() {
+			continue
+		}
+		navMeshDataFormat, err := format.LoadFromMhyFile(navMeshPath + "/" + file.Name())
+		if err != nil {
+			logger.Error("parse navmesh file error: %v, fileName: %v", err, file.Name())
+			continue
+		}
+		sceneId := navMeshDataFormat.M_NavMeshDataID
+		if navMeshDataMap[sceneId] == nil {
+			navMeshDataMap[sceneId] = navMeshDataFormat
+		} else {
+			navMeshDataMap[sceneId].M_NavMeshTiles = append(navMeshDataMap[sceneId].M_NavMeshTiles, navMeshDataFormat.M_NavMeshTiles...)
+		}
+	}
+	for sceneId, navMeshData := range navMeshDataMap {
+		navMeshManager := navmesh.NewNavMeshManager()
+		err = navMeshManager.LoadData(navmesh.NewDataFromFormat(navMeshData))
+		if err != nil {
+			logger.Error("load navmesh data error: %v, sceneId: %v", err, sceneId)
+			continue
+		}
+		r.navMeshManagerMap[sceneId] = navMeshManager
+	}
+	logger.Info("load monster ai navmesh finish, scene count: %v", len(r.navMeshManagerMap))
+	return r
+}
+
+func (n *NavMeshPathFinder) FindPath(sceneId uint32, source, target *model.Vector) ([]*model.Vector, bool) {
+	navMeshManager, exist := n.navMeshManagerMap[sceneId]
+	if !exist {
+		return []*model.Vector{{X: target.X, Y: target.Y, Z: target.Z}}, true
+	}
+	var hit navmesh.NavMeshHit
+	if !navMeshManager.SamplePosition(&hit, navmesh.NewVector3f(float32(source.X), float32(source.Y), float32(source.Z)), MonsterAiNavMeshSampleDist) {
+		return nil, false
+	}
+	sourcePos := hit.GetPosition()
+	if !navMeshManager.SamplePosition(&hit, navmesh.NewVector3f(float32(target.X), float32(target.Y), float32(target.Z)), MonsterAiNavMeshSampleDist) {
+		return nil, false
+	}
+	targetPos := hit.GetPosition()
+	corners, partial := navMeshManager.CalculatePath(sourcePos, targetPos, MonsterAiNavMeshMaxIter)
+	if partial || len(corners) == 0 {
+		return nil, false
+	}
+	path := make([]*model.Vector, 0, len(corners))
+	// 第一个拐点为起点
+	for _, corner := range corners[1:] {
+		path = append(path, &model.Vector{X: float64(corner.GetData(0)), Y: float64(corner.GetData(1)), Z: float64(corner.GetData(2))})
+	}
+	return path, true
+}
+
+// MonsterAi 单个怪物的ai状态
+type MonsterAi struct {
+	entityId      uint32
+	sceneId       uint32
+	bornPos       *model.Vector
+	state         int
+	threatMap     map[uint32]float32 // 仇恨表 key:角色实体id value:仇恨值
+	path          []*model.Vector    // 当前路径
+	pathIndex     int
+	pathTargetPos *model.Vector // 当前路径的终点
+}
+
+func NewMonsterAi(entityId, sceneId uint32, bornPos *model.Vector) *MonsterAi {
+	return &MonsterAi{
+		entityId:      entityId,
+		sceneId:       sceneId,
+		bornPos:       &model.Vector{X: bornPos.X, Y: bornPos.Y, Z: bornPos.Z},
+		state:         MonsterAiStateIdle,
+		threatMap:     make(map[uint32]float32),
+		path:          nil,
+		pathIndex:     0,
+		pathTargetPos: nil,
+	}
+}
+
+func (m *MonsterAi) GetState() int {
+	return m.state
+}
+
+// AddThreat 增加仇恨 返回出生点途中的怪物不接受仇恨
+func (m *MonsterAi) AddThreat(avatarEntityId uint32, threat float32) {
+	if m.state == MonsterAiStateReturn {
+		return
+	}
+	if threat < 1.0 {
+		threat = 1.0
+	}
+	m.threatMap[avatarEntityId] += threat
+	m.state = MonsterAiStateChase
+}
+
+// 获取仇恨最高的存活角色实体 清理已失效的仇恨
+func (m *MonsterAi) getThreatTarget(scene *Scene) IEntity {
+	var target IEntity = nil
+	maxThreat := float32(0.0)
+	for avatarEntityId, threat := range m.threatMap {
+		entity := scene.GetEntity(avatarEntityId)
+		if entity == nil || entity.GetLifeState() != constant.LIFE_STATE_ALIVE {
+			delete(m.threatMap, avatarEntityId)
+			continue
+		}
+		if target == nil || threat > maxThreat || (threat == maxThreat && avatarEntityId < target.GetId()) {
+			target = entity
+			maxThreat = threat
+		}
+	}
+	return target
+}
+
+func (m *MonsterAi) setPath(scene *Scene, pathFinder MonsterAiPathFinder, source, target *model.Vector) bool {
+	path, ok := pathFinder.FindPath(scene.GetId(), source, target)
+	if !ok {
+		m.path = nil
+		m.pathIndex = 0
+		m.pathTargetPos = nil
+		return false
+	}
+	m.path = path
+	m.pathIndex = 0
+	m.pathTargetPos = &model.Vector{X: target.X, Y: target.Y, Z: target.Z}
+	return true
+}
+
+// 沿路径移动 返回是否发生了移动以及是否到达路径终点
+func (m *MonsterAi) moveAlongPath(entity IEntity, dt float64) (bool, bool) {
+	pos := entity.GetPos()
+	newPos := &model.Vector{X: pos.X, Y: pos.Y, Z: pos.Z}
+	remain := MonsterAiMoveSpeed * dt
+	moved := false
+	dirX, dirZ := 0.0, 0.0
+	for remain > 0.0 && m.pathIndex < len(m.path) {
+		next := m.path[m.pathIndex]
+		dx, dy, dz := next.X-newPos.X, next.Y-newPos.Y, next.Z-newPos.Z
+		dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
+		if dist > MonsterAiArriveDistance {
+			dirX, dirZ = dx, dz
+		}
+		if dist <= remain {
+			newPos.X, newPos.Y, newPos.Z = next.X, next.Y, next.Z
+			remain -= dist
+			m.pathIndex++
+		} else {
+			newPos.X += dx / dist * remain
+			newPos.Y += dy / dist * remain
+			newPos.Z += dz / dist * remain
+			remain = 0.0
+		}
+		moved = true
+	}
+	if moved {
+		entity.SetPos(newPos)
+		if dirX != 0.0 || dirZ != 0.0 {
+			rot := entity.GetRot()
+			entity.SetRot(&model.Vector{X: rot.X, Y: math.Atan2(dirX, dirZ) * 180.0 / math.Pi, Z: rot.Z})
+		}
+	}
+	return moved, m.pathIndex >= len(m.path)
+}
+
+// Tick 怪物ai帧更新 返回是否发生了移动以及是否刚刚完成脱战重置
+func (m *MonsterAi) Tick(scene *Scene, entity IEntity, pathFinder MonsterAiPathFinder, dt float64) (bool, bool) {
+	pos := entity.GetPos()
+	if m.state != MonsterAiStateReturn && monsterAiDistance(pos, m.bornPos) > MonsterAiLeashRadius {
+		// 超出脱战半径 清空仇恨返回出生点
+		m.state = MonsterAiStateReturn
+		m.threatMap = make(map[uint32]float32)
+		m.path = nil
+	}
+	switch m.state {
+	case MonsterAiStateIdle:
+		return false, false
+	case MonsterAiStateChase:
+		target := m.getThreatTarget(scene)
+		if target == nil {
+			m.state = MonsterAiStateReturn
+			m.path = nil
+			return false, false
+		}
+		targetPos := target.GetPos()
+		if monsterAiDistance(pos, targetPos) <= MonsterAiAttackRange {
+			m.path = nil
+			return false, false
+		}
+		if m.path == nil || m.pathIndex >= len(m.path) || monsterAiDistance(m.pathTargetPos, targetPos) > MonsterAiRepathDistance {
+			if !m.setPath(scene, pathFinder, pos, targetPos) {
+				return false, false
+			}
+		}
+		moved, _ := m.moveAlongPath(entity, dt)
+		return moved, false
+	case MonsterAiStateReturn:
+		if m.path == nil {
+			if !m.setPath(scene, pathFinder, pos, m.bornPos) {
+				// 无法寻路回出生点 直接拉回
+				m.path = []*model.Vector{m.bornPos}
+				m.pathIndex = 0
+			}
+		}
+		moved, arrive := m.moveAlongPath(entity, dt)
+		if arrive {
+			m.state = MonsterAiStateIdle
+			m.path = nil
+			return moved, true
+		}
+		return moved, false
+	}
+	return false, false
+}
+
+func monsterAiDistance(p1, p2 *model.Vector) float64 {
+	dx, dy, dz := p1.X-p2.X, p1.Y-p2.Y, p1.Z-p2.Z
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// MonsterAiManager 世界的怪物ai管理器
+type MonsterAiManager struct {
+	enable     bool
+	pathFinder MonsterAiPathFinder
+	aiMap      map[uint32]*MonsterAi // key:怪物实体id
+}
+
+func NewMonsterAiManager(pathFinder MonsterAiPathFinder, enable bool) *MonsterAiManager {
+	return &MonsterAiManager{
+		enable:     enable,
+		pathFinder: pathFinder,
+		aiMap:      make(map[uint32]*MonsterAi),
+	}
+}
+
+func (m *MonsterAiManager) IsEnable() bool {
+	return m.enable
+}
+
+func (m *MonsterAiManager) SetEnable(enable bool) {
+	m.enable = enable
+	if !enable {
+		m.aiMap = make(map[uint32]*MonsterAi)
+	}
+}
+
+func (m *MonsterAiManager) GetMonsterAi(entityId uint32) *MonsterAi {
+	return m.aiMap[entityId]
+}
+
+// 获取怪物ai 不存在时以怪物出生点创建
+func (m *MonsterAiManager) getOrCreateMonsterAi(scene *Scene, monsterEntity *MonsterEntity) *MonsterAi {
+	monsterAi, exist := m.aiMap[monsterEntity.GetId()]
+	if !exist {
+		monsterAi = NewMonsterAi(monsterEntity.GetId(), scene.GetId(), monsterEntity.GetBornPos())
+		m.aiMap[monsterEntity.GetId()] = monsterAi
+	}
+	return monsterAi
+}
+
+// AddThreat 怪物受击增加仇恨
+func (m *MonsterAiManager) AddThreat(scene *Scene, monsterEntity *MonsterEntity, attackerId uint32, damage float32) {
+	if !m.enable {
+		return
+	}
+	attacker := scene.GetEntity(attackerId)
+	gadgetClientEntity, ok := attacker.(*GadgetClientEntity)
+	if ok {
+		// 子弹等客户端物件 仇恨记到物件的所有者
+		attacker = scene.GetEntity(gadgetClientEntity.GetOwnerEntityId())
+	}
+	_, ok = attacker.(*AvatarEntity)
+	if !ok {
+		return
+	}
+	m.getOrCreateMonsterAi(scene, monsterEntity).AddThreat(attacker.GetId(), damage)
+}
+
+// Tick 更新场景内全部怪物ai 返回发生了移动的怪物实体与完成脱战重置的怪物实体
+func (m *MonsterAiManager) Tick(scene *Scene, dt float64) ([]IEntity, []IEntity) {
+	moveList := make([]IEntity, 0)
+	resetList := make([]IEntity, 0)
+	if !m.enable {
+		return moveList, resetList
+	}
+	for entityId, monsterAi := range m.aiMap {
+		if monsterAi.sceneId != scene.GetId() {
+			continue
+		}
+		entity := scene.GetEntity(entityId)
+		if entity == nil || entity.GetLifeState() != constant.LIFE_STATE_ALIVE {
+			delete(m.aiMap, entityId)
+			continue
+		}
+		moved, reset := monsterAi.Tick(scene, entity, m.pathFinder, dt)
+		if moved {
+			moveList = append(moveList, entity)
+		}
+		if reset {
+			resetList = append(resetList, entity)
+		}
+	}
+	return moveList, resetList
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// MonsterAiTick 世界的怪物ai帧更新 移动的怪物广播给视野内的玩家
+func (g *Game) MonsterAiTick(world *World) {
+	monsterAiManager := world.GetMonsterAiManager()
+	if !monsterAiManager.IsEnable() {
+		return
+	}
+	for _, scene := range world.GetAllScene() {
+		moveList, resetList := monsterAiManager.Tick(scene, MonsterAiTickTime/1000.0)
+		for _, entity := range moveList {
+			g.MonsterAiMoveNotify(scene, entity)
+		}
+		for _, entity := range resetList {
+			// 脱战回满血
+			fightProp := entity.GetFightProp()
+			fightProp[constant.FIGHT_PROP_CUR_HP] = fightProp[constant.FIGHT_PROP_MAX_HP]
+			g.EntityFightPropUpdateNotifyBroadcast(scene, entity)
+		}
+	}
+}
+
+// MonsterAiMoveNotify 广播服务端驱动的怪物移动
+func (g *Game) MonsterAiMoveNotify(scene *Scene, entity IEntity) {
+	pos := entity.GetPos()
+	rot := entity.GetRot()
+	entity.SetLastMoveReliableSeq(entity.GetLastMoveReliableSeq() + 1)
+	entity.SetLastMoveSceneTimeMs(uint32(scene.GetSceneTime()))
+	entityMoveInfo := &proto.EntityMoveInfo{
+		EntityId: entity.GetId(),
+		MotionInfo: &proto.MotionInfo{
+			Pos:   &proto.Vector{X: float32(pos.X), Y: float32(pos.Y), Z: float32(pos.Z)},
+			Rot:   &proto.Vector{X: float32(rot.X), Y: float32(rot.Y), Z: float32(rot.Z)},
+			Speed: new(proto.Vector),
+			State: proto.MotionState_MOTION_RUN,
+		},
+		SceneTime:   entity.GetLastMoveSceneTimeMs(),
+		ReliableSeq: entity.GetLastMoveReliableSeq(),
+		IsReliable:  true,
+	}
+	combatData, err := pb.Marshal(entityMoveInfo)
+	if err != nil {
+		logger.Error("build EntityMoveInfo error: %v", err)
+		return
+	}
+	ntf := &proto.CombatInvocationsNotify{
+		InvokeList: []*proto.CombatInvokeEntry{{
+			CombatData:   combatData,
+			ForwardType:  proto.ForwardType_FORWARD_TO_ALL,
+			ArgumentType: proto.CombatTypeArgument_ENTITY_MOVE,
+		}},
+	}
+	g.SendToScenePosA(scene, pos, cmd.CombatInvocationsNotify, 0, ntf)
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gs/model"
+)
+
+type fakeMonsterAiPathFinder struct {
+	cornerList  []*model.Vector
+	findCount   int
+	unreachable bool
+}
+
+func (f *fakeMonsterAiPathFinder) FindPath(sceneId uint32, source, target *model.Vector) ([]*model.Vector, bool) {
+	f.findCount++
+	if f.unreachable {
+		return nil, false
+	}
+	path := make([]*model.Vector, 0, len(f.cornerList)+1)
+	path = append(path, f.cornerList...)
+	path = append(path, &model.Vector{X: target.X, Y: target.Y, Z: target.Z})
+	return path, true
+}
+
+func newTestMonsterAi(t *testing.T, pathFinder MonsterAiPathFinder) (*testAbilityScene, *MonsterAiManager, *MonsterEntity) {
+	s := newTestAbilityScene(t)
+	monsterAiManager := NewMonsterAiManager(pathFinder, true)
+	monsterEntity := s.addMonster(100, &model.Vector{X: 0.0, Y: 0.0, Z: 0.0}, 1000.0, 1000.0, 10.0)
+	monsterEntity.bornPos = &model.Vector{X: 0.0, Y: 0.0, Z: 0.0}
+	return s, monsterAiManager, monsterEntity
+}
+
+func monsterAiTickN(monsterAiManager *MonsterAiManager, scene *Scene, n int) ([]IEntity, []IEntity) {
+	moveList, resetList := make([]IEntity, 0), make([]IEntity, 0)
+	for i := 0; i < n; i++ {
+		move, reset := monsterAiManager.Tick(scene, MonsterAiTickTime/1000.0)
+		moveList = append(moveList, move...)
+		resetList = append(resetList, reset...)
+	}
+	return moveList, resetList
+}
+
+func TestMonsterAiThreat(t *testing.T) {
+	s, monsterAiManager, monsterEntity := newTestMonsterAi(t, &fakeMonsterAiPathFinder{})
+	avatarA := s.addAvatar(1, &model.Vector{X: 10.0, Y: 0.0, Z: 0.0}, 100.0, 100.0)
+	avatarB := s.addAvatar(2, &model.Vector{X: -10.0, Y: 0.0, Z: 0.0}, 100.0, 100.0)
+	bullet := &GadgetClientEntity{GadgetEntity: &GadgetEntity{Entity: s.newEntity(3, new(model.Vector), 1.0, 1.0, 0.0)}, ownerEntityId: avatarB.GetId()}
+	s.scene.entityMap[bullet.GetId()] = bullet
+
+	monsterAiManager.AddThreat(s.scene, monsterEntity, avatarA.GetId(), 50.0)
+	// 子弹造成的仇恨记到所有者
+	monsterAiManager.AddThreat(s.scene, monsterEntity, bullet.GetId(), 80.0)
+	// 非角色攻击者不计仇恨
+	monsterAiManager.AddThreat(s.scene, monsterEntity, monsterEntity.GetId(), 1000.0)
+	monsterAi := monsterAiManager.GetMonsterAi(monsterEntity.GetId())
+	if monsterAi == nil || monsterAi.GetState() != MonsterAiStateChase {
+		t.Fatalf("monster ai not chase, ai: %+v", monsterAi)
+	}
+	if len(monsterAi.threatMap) != 2 || monsterAi.threatMap[avatarB.GetId()] != 80.0 {
+		t.Fatalf("threat map error: %v", monsterAi.threatMap)
+	}
+	if target := monsterAi.getThreatTarget(s.scene); target.GetId() != avatarB.GetId() {
+		t.Fatalf("threat target error, entityId: %v", target.GetId())
+	}
+	// 死亡的角色从仇恨表移除
+	avatarB.SetLifeState(constant.LIFE_STATE_DEAD)
+	if target := monsterAi.getThreatTarget(s.scene); target.GetId() != avatarA.GetId() {
+		t.Fatalf("threat target error, entityId: %v", target.GetId())
+	}
+	if _, exist := monsterAi.threatMap[avatarB.GetId()]; exist {
+		t.Fatalf("dead avatar threat not removed")
+	}
+
+	disable := NewMonsterAiManager(&fakeMonsterAiPathFinder{}, false)
+	disable.AddThreat(s.scene, monsterEntity, avatarA.GetId(), 50.0)
+	if disable.GetMonsterAi(monsterEntity.GetId()) != nil {
+		t.Fatalf("disabled manager created monster ai")
+	}
+}
+
+func TestMonsterAiChase(t *testing.T) {
+	pathFinder := &fakeMonsterAiPathFinder{cornerList: []*model.Vector{{X: 4.0, Y: 0.0, Z: 0.0}}}
+	s, monsterAiManager, monsterEntity := newTestMonsterAi(t, pathFinder)
+	avatar := s.addAvatar(1, &model.Vector{X: 4.0, Y: 0.0, Z: 8.0}, 100.0, 100.0)
+	monsterAiManager.AddThreat(s.scene, monsterEntity, avatar.GetId(), 10.0)
+
+	// 先沿拐点移动 再转向目标
+	moveList, _ := monsterAiTickN(monsterAiManager, s.scene, 5)
+	if len(moveList) != 5 {
+		t.Fatalf("move count error: %v", len(moveList))
+	}
+	pos := monsterEntity.GetPos()
+	if math.Abs(pos.X-4.0) > 1e-6 || math.Abs(pos.Z) > 1e-6 {
+		t.Fatalf("monster not at corner, pos: %+v", pos)
+	}
+	// 到达攻击距离后停止
+	monsterAiTickN(monsterAiManager, s.scene, 10)
+	pos = monsterEntity.GetPos()
+	if math.Abs(monsterAiDistance(pos, avatar.GetPos())-MonsterAiAttackRange) > 0.8 {
+		t.Fatalf("monster not in attack range, pos: %+v", pos)
+	}
+	if math.Abs(monsterEntity.GetRot().Y) > 1e-6 {
+		t.Fatalf("monster rot error, rot: %+v", monsterEntity.GetRot())
+	}
+	moveList, _ = monsterAiTickN(monsterAiManager, s.scene, 1)
+	if len(moveList) != 0 {
+		t.Fatalf("monster move in attack range")
+	}
+	// 目标移动后重新寻路
+	findCount := pathFinder.findCount
+	avatar.SetPos(&model.Vector{X: 4.0, Y: 0.0, Z: 12.0})
+	moveList, _ = monsterAiTickN(monsterAiManager, s.scene, 1)
+	if len(moveList) != 1 || pathFinder.findCount != findCount+1 {
+		t.Fatalf("monster not repath, move: %v, find: %v", len(moveList), pathFinder.findCount)
+	}
+}
+
+func TestMonsterAiLeash(t *testing.T) {
+	s, monsterAiManager, monsterEntity := newTestMonsterAi(t, &fakeMonsterAiPathFinder{})
+	avatar := s.addAvatar(1, &model.Vector{X: 100.0, Y: 0.0, Z: 0.0}, 100.0, 100.0)
+	monsterAiManager.AddThreat(s.scene, monsterEntity, avatar.GetId(), 10.0)
+	monsterAi := monsterAiManager.GetMonsterAi(monsterEntity.GetId())
+
+	// 追出脱战半径后返回出生点
+	monsterAiTickN(monsterAiManager, s.scene, 40)
+	if monsterAi.GetState() != MonsterAiStateReturn || len(monsterAi.threatMap) != 0 {
+		t.Fatalf("monster not return, state: %v, threat: %v", monsterAi.GetState(), monsterAi.threatMap)
+	}
+	// 返回途中不接受仇恨
+	monsterAiManager.AddThreat(s.scene, monsterEntity, avatar.GetId(), 10.0)
+	if monsterAi.GetState() != MonsterAiStateReturn {
+		t.Fatalf("monster accept threat when return")
+	}
+	_, resetList := monsterAiTickN(monsterAiManager, s.scene, 100)
+	if len(resetList) != 1 || monsterAi.GetState() != MonsterAiStateIdle {
+		t.Fatalf("monster not reset, reset: %v, state: %v", len(resetList), monsterAi.GetState())
+	}
+	if monsterAiDistance(monsterEntity.GetPos(), monsterEntity.GetBornPos()) > 1e-6 {
+		t.Fatalf("monster not at born pos, pos: %+v", monsterEntity.GetPos())
+	}
+}
+
+func TestMonsterAiUnreachable(t *testing.T) {
+	pathFinder := &fakeMonsterAiPathFinder{unreachable: true}
+	s, monsterAiManager, monsterEntity := newTestMonsterAi(t, pathFinder)
+	avatar := s.addAvatar(1, &model.Vector{X: 10.0, Y: 0.0, Z: 0.0}, 100.0, 100.0)
+	monsterAiManager.AddThreat(s.scene, monsterEntity, avatar.GetId(), 10.0)
+	moveList, _ := monsterAiTickN(monsterAiManager, s.scene, 3)
+	if len(moveList) != 0 {
+		t.Fatalf("monster move without path")
+	}
+	// 怪物死亡后移除ai
+	monsterEntity.SetLifeState(constant.LIFE_STATE_DEAD)
+	monsterAiTickN(monsterAiManager, s.scene, 1)
+	if monsterAiManager.GetMonsterAi(monsterEntity.GetId()) != nil {
+		t.Fatalf("dead monster ai not removed")
+	}
+}
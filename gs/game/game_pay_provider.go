@@ -0,0 +1,107 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"hk4e/common/config"
+	httpapi "hk4e/dispatch/api"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/pkg/httpclient"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 支付渠道
+
+// IPayProvider 支付渠道接口
+type IPayProvider interface {
+	// Name 支付渠道名
+	Name() string
+	// CreateOrder 在支付渠道下单 支付成功后由渠道回调dispatch的支付结果回调接口
+	CreateOrder(uid uint32, order *model.Order) error
+	// QueryOrder 向支付渠道查询订单支付状态 用于对账
+	QueryOrder(uid uint32, order *model.Order) (paid bool, tradeNo string, err error)
+}
+
+var payProviderMap = map[string]func() IPayProvider{
+	"mock": func() IPayProvider { return NewMockPayProvider() },
+}
+
+// RegPayProvider 注册支付渠道
+func RegPayProvider(name string, newFunc func() IPayProvider) {
+	payProviderMap[name] = newFunc
+}
+
+// NewPayProvider 按名称创建支付渠道 未配置或不存在时返回nil 即关闭充值
+func NewPayProvider(name string) IPayProvider {
+	if name == "" {
+		return nil
+	}
+	newFunc, exist := payProviderMap[name]
+	if !exist {
+		logger.Error("pay provider not exist, name: %v", name)
+		return nil
+	}
+	return newFunc()
+}
+
+// MockPayProvider 本地模拟支付渠道 下单后延迟一段时间自动确认支付并回调dispatch
+type MockPayProvider struct {
+	CallbackUrl  string        // 支付结果回调地址
+	CallbackKey  string        // 支付结果回调签名密钥
+	ConfirmDelay time.Duration // 下单到确认支付的延迟
+}
+
+func NewMockPayProvider() *MockPayProvider {
+	r := new(MockPayProvider)
+	r.CallbackUrl = config.GetConfig().Hk4e.PayCallbackUrl
+	r.CallbackKey = config.GetConfig().Hk4e.PayCallbackKey
+	r.ConfirmDelay = time.Second * time.Duration(config.GetConfig().Hk4e.PayMockConfirmDelay)
+	return r
+}
+
+func (m *MockPayProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockPayProvider) CreateOrder(uid uint32, order *model.Order) error {
+	if m.CallbackUrl == "" {
+		// 没有回调地址 只能等玩家登录时对账发货
+		return nil
+	}
+	req := &httpapi.PayCallbackReq{
+		Uid:       uid,
+		OrderId:   order.OrderId,
+		ProductId: order.ProductId,
+		TradeNo:   m.tradeNo(uid, order),
+	}
+	req.Sign = httpapi.PayCallbackSign(req, m.CallbackKey)
+	go func() {
+		time.Sleep(m.ConfirmDelay)
+		rsp, err := httpclient.PostJson[httpapi.PayCallbackRsp](m.CallbackUrl, req)
+		if err != nil {
+			logger.Error("mock pay callback http error: %v, uid: %v, orderId: %v", err, uid, req.OrderId)
+			return
+		}
+		if rsp.RetCode != 0 {
+			logger.Error("mock pay callback fail, retCode: %v, msg: %v, uid: %v, orderId: %v", rsp.RetCode, rsp.Message, uid, req.OrderId)
+			return
+		}
+	}()
+	return nil
+}
+
+func (m *MockPayProvider) QueryOrder(uid uint32, order *model.Order) (bool, string, error) {
+	// 模拟支付不保存任何状态 下单超过确认延迟即视为已支付
+	payTime := time.Unix(int64(order.CreateTime), 0).Add(m.ConfirmDelay)
+	if clock.Now().Before(payTime) {
+		return false, "", nil
+	}
+	return true, m.tradeNo(uid, order), nil
+}
+
+func (m *MockPayProvider) tradeNo(uid uint32, order *model.Order) string {
+	return fmt.Sprintf("mock_%d_%d", uid, order.OrderId)
+}
@@ -0,0 +1,615 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+
+	"github.com/flswld/halo/logger"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// lua插件
+// 插件目录下每个.lua文件为一个插件 文件名为插件名
+// 脚本通过全局的Plugin表监听事件 添加全局tick 创建用户timer 注册命令
+// 脚本可以定义全局函数OnEnable和OnDisable作为生命周期回调
+// 每次从服务器调用脚本都有独立的执行时间预算 超出预算的插件会被禁用 重新加载后恢复
+// 字符串库和表库中在宿主侧一次性完成大量计算的函数限制了输入和结果的长度
+
+const (
+	LuaPluginTimeoutDefault  = 50      // 单次回调默认的最大执行时间 单位毫秒
+	luaPluginStringLenLimit  = 1 << 20 // 字符串函数生成的字符串最大长度
+	luaPluginPatternLenLimit = 1 << 16 // 模式匹配的目标字符串最大长度
+)
+
+func GetLuaPluginTimeout() time.Duration {
+	timeout := config.GetConfig().Hk4e.LuaPluginTimeout
+	if timeout <= 0 {
+		timeout = LuaPluginTimeoutDefault
+	}
+	return time.Duration(timeout) * time.Millisecond
+}
+
+var ErrLuaPluginBudgetExceeded = errors.New("lua plugin time budget exceeded")
+
+// PluginLua lua插件
+type PluginLua struct {
+	*Plugin
+	name           string
+	filePath       string
+	luaState       *lua.LState
+	timeout        time.Duration
+	budgetExceeded bool
+}
+
+// NewPluginLua 加载并执行插件脚本
+func NewPluginLua(name string, filePath string, timeout time.Duration) (*PluginLua, error) {
+	p := &PluginLua{
+		Plugin:   NewPlugin(),
+		name:     name,
+		filePath: filePath,
+		timeout:  timeout,
+	}
+	p.luaState = newPluginLuaState()
+	p.initPluginApi()
+	fn, err := p.luaState.LoadFile(filePath)
+	if err != nil {
+		p.luaState.Close()
+		return nil, err
+	}
+	_, err = p.call(fn, 0)
+	if err != nil {
+		p.luaState.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// newPluginLuaState 创建沙箱环境 只开放基础库 表 字符串 数学库 并移除加载外部代码的函数
+func newPluginLuaState() *lua.LState {
+	luaState := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       256,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		luaState.Push(luaState.NewFunction(lib.fn))
+		luaState.Push(lua.LString(lib.name))
+		luaState.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require", "collectgarbage", "newproxy", "_printregs", "getfenv", "setfenv"} {
+		luaState.SetGlobal(name, lua.LNil)
+	}
+	limitPluginLuaLib(luaState)
+	return luaState
+}
+
+// limitPluginLuaLib 宿主侧实现的库函数执行期间不会检查超时 调用前限制输入和结果的长度
+func limitPluginLuaLib(luaState *lua.LState) {
+	wrap := func(table *lua.LTable, name string, check func(L *lua.LState)) *lua.LFunction {
+		fn := table.RawGetString(name)
+		wrapFn := luaState.NewFunction(func(L *lua.LState) int {
+			ctx := L.Context()
+			if ctx != nil && ctx.Err() != nil {
+				L.RaiseError(ctx.Err().Error())
+			}
+			check(L)
+			L.Insert(fn, 1)
+			L.Call(L.GetTop()-1, lua.MultRet)
+			return L.GetTop()
+		})
+		table.RawSetString(name, wrapFn)
+		return wrapFn
+	}
+	checkPattern := func(L *lua.LState) {
+		if len(L.CheckString(1)) > luaPluginPatternLenLimit {
+			L.RaiseError("string too long for pattern matching, limit: %v", luaPluginPatternLenLimit)
+		}
+	}
+	strTable := luaState.GetGlobal(lua.StringLibName).(*lua.LTable)
+	wrap(strTable, "rep", func(L *lua.LState) {
+		str := L.CheckString(1)
+		n := L.CheckInt(2)
+		if n > 0 && int64(len(str))*int64(n) > luaPluginStringLenLimit {
+			L.RaiseError("string.rep result too long, limit: %v", luaPluginStringLenLimit)
+		}
+	})
+	wrap(strTable, "find", checkPattern)
+	wrap(strTable, "match", checkPattern)
+	strTable.RawSetString("gfind", wrap(strTable, "gmatch", checkPattern))
+	wrap(strTable, "gsub", func(L *lua.LState) {
+		checkPattern(L)
+		str := L.CheckString(1)
+		repl, ok := L.Get(3).(lua.LString)
+		if !ok {
+			return
+		}
+		// 替换次数最多为目标字符串长度加一
+		n := int64(len(str) + 1)
+		if maxN := L.OptInt64(4, n); maxN >= 0 && maxN < n {
+			n = maxN
+		}
+		if int64(len(str))+n*int64(len(repl)) > luaPluginStringLenLimit {
+			L.RaiseError("string.gsub result too long, limit: %v", luaPluginStringLenLimit)
+		}
+	})
+	tabTable := luaState.GetGlobal(lua.TabLibName).(*lua.LTable)
+	wrap(tabTable, "concat", func(L *lua.LState) {
+		table := L.CheckTable(1)
+		sep := L.OptString(2, "")
+		i := L.OptInt(3, 1)
+		j := L.OptInt(4, table.Len())
+		if i < 1 {
+			i = 1
+		}
+		if j > table.Len() {
+			j = table.Len()
+		}
+		length := 0
+		for ; i <= j; i++ {
+			length += len(lua.LVAsString(table.RawGetInt(i))) + len(sep)
+			if length > luaPluginStringLenLimit {
+				L.RaiseError("table.concat result too long, limit: %v", luaPluginStringLenLimit)
+			}
+		}
+	})
+}
+
+func (p *PluginLua) GetName() string {
+	return p.name
+}
+
+// call 在执行时间预算内调用脚本函数 脚本回调服务器接口再次触发的调用共用外层的预算
+func (p *PluginLua) call(fn lua.LValue, nRet int, args ...lua.LValue) ([]lua.LValue, error) {
+	if p.budgetExceeded {
+		return nil, ErrLuaPluginBudgetExceeded
+	}
+	if fn == nil || fn.Type() != lua.LTFunction {
+		return nil, nil
+	}
+	ctx := p.luaState.Context()
+	if ctx == nil {
+		var cancel context.CancelFunc = nil
+		ctx, cancel = context.WithTimeout(context.Background(), p.timeout)
+		p.luaState.SetContext(ctx)
+		defer func() {
+			p.luaState.RemoveContext()
+			cancel()
+		}()
+	}
+	top := p.luaState.GetTop()
+	err := p.luaState.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    nRet,
+		Protect: true,
+	}, args...)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// 超出预算后禁用插件 防止拖慢主循环
+		p.budgetExceeded = true
+		p.isEnable = false
+		p.luaState.SetTop(top)
+		logger.Error("lua plugin time budget exceeded, disable plugin, name: %v, timeout: %v", p.name, p.timeout)
+		return nil, ErrLuaPluginBudgetExceeded
+	}
+	if err != nil {
+		p.luaState.SetTop(top)
+		logger.Error("call lua plugin error, name: %v, error: %v", p.name, err)
+		return nil, err
+	}
+	retList := make([]lua.LValue, 0, nRet)
+	for i := nRet; i > 0; i-- {
+		retList = append(retList, p.luaState.Get(-i))
+	}
+	p.luaState.Pop(nRet)
+	return retList, nil
+}
+
+// OnEnable 插件启用生命周期
+func (p *PluginLua) OnEnable() {
+	_, _ = p.call(p.luaState.GetGlobal("OnEnable"), 0)
+}
+
+// OnDisable 插件禁用生命周期
+func (p *PluginLua) OnDisable() {
+	if !p.budgetExceeded {
+		_, _ = p.call(p.luaState.GetGlobal("OnDisable"), 0)
+	}
+	p.luaState.Close()
+}
+
+// initPluginApi 向脚本暴露的接口
+func (p *PluginLua) initPluginApi() {
+	luaState := p.luaState
+	eventIdTable := luaState.NewTable()
+	for eventId, name := range PluginEventIdNameMap {
+		luaState.SetField(eventIdTable, name, lua.LNumber(eventId))
+	}
+	luaState.SetGlobal("PluginEventId", eventIdTable)
+	priorityTable := luaState.NewTable()
+	luaState.SetField(priorityTable, "LOWEST", lua.LNumber(PluginEventPriorityLowest))
+	luaState.SetField(priorityTable, "LOW", lua.LNumber(PluginEventPriorityLow))
+	luaState.SetField(priorityTable, "NORMAL", lua.LNumber(PluginEventPriorityNormal))
+	luaState.SetField(priorityTable, "HIGH", lua.LNumber(PluginEventPriorityHigh))
+	luaState.SetField(priorityTable, "HIGHEST", lua.LNumber(PluginEventPriorityHighest))
+	luaState.SetGlobal("PluginEventPriority", priorityTable)
+	tickTable := luaState.NewTable()
+	luaState.SetField(tickTable, "SECOND", lua.LNumber(PluginGlobalTickSecond))
+	luaState.SetField(tickTable, "MINUTE_CHANGE", lua.LNumber(PluginGlobalTickMinuteChange))
+	luaState.SetGlobal("PluginGlobalTick", tickTable)
+	luaState.SetGlobal("print", luaState.NewFunction(p.luaLog))
+	luaState.SetGlobal("Plugin", luaState.SetFuncs(luaState.NewTable(), map[string]lua.LGFunction{
+		"GetName":         func(L *lua.LState) int { L.Push(lua.LString(p.name)); return 1 },
+		"Log":             p.luaLog,
+		"ListenEvent":     p.luaListenEvent,
+		"AddGlobalTick":   p.luaAddGlobalTick,
+		"CreateUserTimer": p.luaCreateUserTimer,
+		"RegCommand":      p.luaRegCommand,
+		"SendChat":        p.luaSendChat,
+		"GetOnlineUidList": func(L *lua.LState) int {
+			uidTable := L.NewTable()
+			for _, player := range USER_MANAGER.GetAllOnlineUserList() {
+				uidTable.Append(lua.LNumber(player.PlayerId))
+			}
+			L.Push(uidTable)
+			return 1
+		},
+		"Now": func(L *lua.LState) int { L.Push(lua.LNumber(clock.Now().UnixMilli())); return 1 },
+	}))
+}
+
+// Plugin.Log(msg, ...)
+func (p *PluginLua) luaLog(L *lua.LState) int {
+	strList := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		strList = append(strList, L.ToStringMeta(L.Get(i)).String())
+	}
+	logger.Info("[LUA PLUGIN %v] %v", p.name, strings.Join(strList, " "))
+	return 0
+}
+
+// Plugin.ListenEvent(eventId, priority, func(event) return cancel end)
+func (p *PluginLua) luaListenEvent(L *lua.LState) int {
+	eventId := PluginEventId(L.CheckInt(1))
+	priority := PluginEventPriority(L.CheckInt(2))
+	fn := L.CheckFunction(3)
+	p.ListenEvent(eventId, priority, func(event IPluginEvent) {
+		retList, err := p.call(fn, 1, pluginEventToLuaTable(p.luaState, eventId, event))
+		if err != nil {
+			return
+		}
+		if lua.LVAsBool(retList[0]) {
+			event.Cancel()
+		}
+	})
+	return 0
+}
+
+// Plugin.AddGlobalTick(tick, func() end)
+func (p *PluginLua) luaAddGlobalTick(L *lua.LState) int {
+	tick := PluginGlobalTick(L.CheckInt(1))
+	fn := L.CheckFunction(2)
+	p.AddGlobalTick(tick, func() {
+		_, _ = p.call(fn, 0)
+	})
+	return 0
+}
+
+// Plugin.CreateUserTimer(uid, delaySecond, func(uid, ...) end, ...)
+func (p *PluginLua) luaCreateUserTimer(L *lua.LState) int {
+	userId := uint32(L.CheckInt(1))
+	delay := uint32(L.CheckInt(2))
+	fn := L.CheckFunction(3)
+	data := make([]any, 0)
+	for i := 4; i <= L.GetTop(); i++ {
+		data = append(data, L.Get(i))
+	}
+	p.CreateUserTimer(userId, delay, func(player *model.Player, data []any) {
+		argList := []lua.LValue{lua.LNumber(player.PlayerId)}
+		for _, value := range data {
+			argList = append(argList, value.(lua.LValue))
+		}
+		_, _ = p.call(fn, 0, argList...)
+	}, data...)
+	return 0
+}
+
+// Plugin.RegCommand({name=, alias_list={}, description=, usage_list={}, perm=}, func(uid, paramList) return ok, msg end)
+func (p *PluginLua) luaRegCommand(L *lua.LState) int {
+	info := L.CheckTable(1)
+	fn := L.CheckFunction(2)
+	controller := &CommandController{
+		Name:        L.GetField(info, "name").String(),
+		AliasList:   luaTableToStringList(L.GetField(info, "alias_list")),
+		Description: L.GetField(info, "description").String(),
+		UsageList:   luaTableToStringList(L.GetField(info, "usage_list")),
+		Perm:        CommandPerm(lua.LVAsNumber(L.GetField(info, "perm"))),
+	}
+	controller.Func = func(c *CommandContent) bool {
+		paramTable := p.luaState.NewTable()
+		for _, param := range c.ParamList {
+			paramTable.Append(lua.LString(param))
+		}
+		retList, err := p.call(fn, 2, lua.LNumber(c.Executor.PlayerId), paramTable)
+		if err != nil {
+			c.SetElse(func() {
+				c.SendFailMessage(c.Executor, "插件命令执行失败。")
+			})
+			return false
+		}
+		if msg, ok := retList[1].(lua.LString); ok && msg != "" {
+			if lua.LVAsBool(retList[0]) {
+				c.SendSuccMessage(c.Executor, "%v", string(msg))
+			} else {
+				c.SetElse(func() {
+					c.SendFailMessage(c.Executor, "%v", string(msg))
+				})
+			}
+		}
+		return lua.LVAsBool(retList[0])
+	}
+	p.RegCommandController(controller)
+	return 0
+}
+
+// Plugin.SendChat(uid, text) 以GM指令机器人的身份发送私聊
+func (p *PluginLua) luaSendChat(L *lua.LState) int {
+	userId := uint32(L.CheckInt(1))
+	text := L.CheckString(2)
+	GAME.SendPrivateChat(COMMAND_MANAGER.system, userId, text)
+	return 0
+}
+
+func luaTableToStringList(value lua.LValue) []string {
+	strList := make([]string, 0)
+	table, ok := value.(*lua.LTable)
+	if !ok {
+		return strList
+	}
+	table.ForEach(func(_ lua.LValue, v lua.LValue) {
+		strList = append(strList, v.String())
+	})
+	return strList
+}
+
+// pluginEventToLuaTable 将插件事件转换为脚本中的表 字段名与事件结构的字段名一致
+// 玩家字段转换为玩家uid 协议字段按协议字段名转换为表
+func pluginEventToLuaTable(luaState *lua.LState, eventId PluginEventId, event IPluginEvent) *lua.LTable {
+	table := luaState.NewTable()
+	luaState.SetField(table, "EventId", lua.LNumber(eventId))
+	refValue := reflect.ValueOf(event)
+	if refValue.Kind() == reflect.Pointer {
+		refValue = refValue.Elem()
+	}
+	if refValue.Kind() != reflect.Struct {
+		return table
+	}
+	refType := refValue.Type()
+	for i := 0; i < refType.NumField(); i++ {
+		field := refType.Field(i)
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		luaState.SetField(table, field.Name, goValueToLuaValue(luaState, refValue.Field(i).Interface()))
+	}
+	return table
+}
+
+func goValueToLuaValue(luaState *lua.LState, value any) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case *model.Player:
+		if v == nil {
+			return lua.LNil
+		}
+		return lua.LNumber(v.PlayerId)
+	case pb.Message:
+		if reflect.ValueOf(v).IsNil() {
+			return lua.LNil
+		}
+		data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(v)
+		if err != nil {
+			return lua.LNil
+		}
+		var obj any = nil
+		err = json.Unmarshal(data, &obj)
+		if err != nil {
+			return lua.LNil
+		}
+		return jsonValueToLuaValue(luaState, obj)
+	case string:
+		return lua.LString(v)
+	case bool:
+		return lua.LBool(v)
+	}
+	refValue := reflect.ValueOf(value)
+	switch refValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(refValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(refValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		return lua.LNumber(refValue.Float())
+	case reflect.Slice, reflect.Array:
+		table := luaState.NewTable()
+		for i := 0; i < refValue.Len(); i++ {
+			table.Append(goValueToLuaValue(luaState, refValue.Index(i).Interface()))
+		}
+		return table
+	case reflect.Pointer:
+		if refValue.IsNil() {
+			return lua.LNil
+		}
+		return goValueToLuaValue(luaState, refValue.Elem().Interface())
+	case reflect.Struct:
+		table := luaState.NewTable()
+		refType := refValue.Type()
+		for i := 0; i < refType.NumField(); i++ {
+			if !refType.Field(i).IsExported() {
+				continue
+			}
+			luaState.SetField(table, refType.Field(i).Name, goValueToLuaValue(luaState, refValue.Field(i).Interface()))
+		}
+		return table
+	default:
+		return lua.LString(fmt.Sprintf("%v", value))
+	}
+}
+
+func jsonValueToLuaValue(luaState *lua.LState, value any) lua.LValue {
+	switch v := value.(type) {
+	case map[string]any:
+		table := luaState.NewTable()
+		for key, item := range v {
+			luaState.SetField(table, key, jsonValueToLuaValue(luaState, item))
+		}
+		return table
+	case []any:
+		table := luaState.NewTable()
+		for _, item := range v {
+			table.Append(jsonValueToLuaValue(luaState, item))
+		}
+		return table
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case bool:
+		return lua.LBool(v)
+	default:
+		return lua.LNil
+	}
+}
+
+/************************************************** 插件管理 **************************************************/
+
+// LoadAllLuaPlugin 加载插件目录下的全部lua插件
+func (p *PluginManager) LoadAllLuaPlugin() {
+	pluginPath := config.GetConfig().Hk4e.LuaPluginPath
+	if pluginPath == "" {
+		return
+	}
+	for _, name := range p.ListLuaPluginFile(pluginPath) {
+		err := p.LoadLuaPlugin(name)
+		if err != nil {
+			logger.Error("load lua plugin error, name: %v, err: %v", name, err)
+		}
+	}
+}
+
+// ListLuaPluginFile 获取插件目录下的全部插件名
+func (p *PluginManager) ListLuaPluginFile(pluginPath string) []string {
+	entryList, err := os.ReadDir(pluginPath)
+	if err != nil {
+		logger.Error("read lua plugin dir error: %v", err)
+		return nil
+	}
+	nameList := make([]string, 0)
+	for _, entry := range entryList {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".lua" {
+			continue
+		}
+		nameList = append(nameList, strings.TrimSuffix(entry.Name(), ".lua"))
+	}
+	sort.Strings(nameList)
+	return nameList
+}
+
+// LoadLuaPlugin 加载lua插件 已加载则先卸载再重新加载
+// 新脚本加载失败时保留旧插件继续运行
+func (p *PluginManager) LoadLuaPlugin(name string) error {
+	pluginPath := config.GetConfig().Hk4e.LuaPluginPath
+	if pluginPath == "" {
+		return errors.New("lua plugin path not config")
+	}
+	if name == "" || strings.ContainsAny(name, "/\\") || strings.Contains(name, "..") {
+		return errors.New("invalid lua plugin name")
+	}
+	pluginLua, err := NewPluginLua(name, filepath.Join(pluginPath, name+".lua"), GetLuaPluginTimeout())
+	if err != nil {
+		return err
+	}
+	old, exist := p.luaPluginMap[name]
+	if exist {
+		p.DelLuaPlugin(old.name)
+	}
+	logger.Info("lua plugin enable, name: %v", name)
+	p.luaPluginMap[name] = pluginLua
+	pluginLua.OnEnable()
+	return nil
+}
+
+// DelLuaPlugin 卸载lua插件
+func (p *PluginManager) DelLuaPlugin(name string) bool {
+	pluginLua, exist := p.luaPluginMap[name]
+	if !exist {
+		logger.Error("lua plugin not exist, name: %v", name)
+		return false
+	}
+	logger.Info("lua plugin disable, name: %v", name)
+	delete(p.luaPluginMap, name)
+	pluginLua.OnDisable()
+	COMMAND_MANAGER.DelAllController(pluginLua.commandControllerList...)
+	return true
+}
+
+// ReloadLuaPlugin 重新加载lua插件 插件名为空时重新加载目录下的全部插件并卸载已删除文件的插件
+func (p *PluginManager) ReloadLuaPlugin(name string) error {
+	if name != "" {
+		return p.LoadLuaPlugin(name)
+	}
+	pluginPath := config.GetConfig().Hk4e.LuaPluginPath
+	if pluginPath == "" {
+		return errors.New("lua plugin path not config")
+	}
+	nameList := p.ListLuaPluginFile(pluginPath)
+	fileMap := make(map[string]struct{})
+	errList := make([]string, 0)
+	for _, fileName := range nameList {
+		fileMap[fileName] = struct{}{}
+		err := p.LoadLuaPlugin(fileName)
+		if err != nil {
+			errList = append(errList, fmt.Sprintf("%v: %v", fileName, err))
+		}
+	}
+	for pluginName := range p.luaPluginMap {
+		if _, exist := fileMap[pluginName]; !exist {
+			p.DelLuaPlugin(pluginName)
+		}
+	}
+	if len(errList) != 0 {
+		return errors.New(strings.Join(errList, "; "))
+	}
+	return nil
+}
+
+// GetLuaPluginNameList 获取已加载的lua插件名列表
+func (p *PluginManager) GetLuaPluginNameList() []string {
+	nameList := make([]string, 0, len(p.luaPluginMap))
+	for name := range p.luaPluginMap {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+	return nameList
+}
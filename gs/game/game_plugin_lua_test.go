@@ -0,0 +1,175 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hk4e/common/config"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+func newTestLuaPluginManager(t *testing.T, timeout int32) string {
+	pluginPath := t.TempDir()
+	config.CONF = &config.Config{Hk4e: config.Hk4e{LuaPluginPath: pluginPath, LuaPluginTimeout: timeout}}
+	oldCommandManager, oldPluginManager := COMMAND_MANAGER, PLUGIN_MANAGER
+	COMMAND_MANAGER = NewCommandManager()
+	PLUGIN_MANAGER = NewPluginManager()
+	t.Cleanup(func() {
+		config.CONF = nil
+		COMMAND_MANAGER, PLUGIN_MANAGER = oldCommandManager, oldPluginManager
+	})
+	return pluginPath
+}
+
+func writeTestLuaPlugin(t *testing.T, pluginPath string, name string, script string) {
+	err := os.WriteFile(filepath.Join(pluginPath, name+".lua"), []byte(script), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLuaPluginEvent(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 0)
+	writeTestLuaPlugin(t, pluginPath, "mark", `
+count = 0
+Plugin.ListenEvent(PluginEventId.MARK_MAP, PluginEventPriority.NORMAL, function(event)
+	count = count + 1
+	-- 禁止玩家在地图上标点
+	return event.Player == 10001 and event.Req.op == "ADD"
+end)
+Plugin.RegCommand({name = "mark", alias_list = {"Mark"}, perm = 0}, function(uid, paramList)
+	return true, "count " .. count
+end)
+`)
+	PLUGIN_MANAGER.LoadAllLuaPlugin()
+	if len(PLUGIN_MANAGER.GetLuaPluginNameList()) != 1 {
+		t.Fatalf("lua plugin not load: %v", PLUGIN_MANAGER.GetLuaPluginNameList())
+	}
+	newEvent := func(uid uint32) *PluginEventMarkMap {
+		return &PluginEventMarkMap{
+			PluginEvent: NewPluginEvent(),
+			Player:      &model.Player{PlayerId: uid},
+			Req:         &proto.MarkMapReq{Op: proto.MarkMapReq_ADD},
+		}
+	}
+	if !PLUGIN_MANAGER.TriggerEvent(PluginEventIdMarkMap, newEvent(10001)) {
+		t.Fatal("event should be cancel by lua plugin")
+	}
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdMarkMap, newEvent(10002)) {
+		t.Fatal("event should not be cancel")
+	}
+	if COMMAND_MANAGER.commandControllerMap["mark"] == nil {
+		t.Fatal("lua plugin command not register")
+	}
+	// 卸载后事件与命令一并移除
+	if !PLUGIN_MANAGER.DelLuaPlugin("mark") {
+		t.Fatal("unload lua plugin fail")
+	}
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdMarkMap, newEvent(10001)) {
+		t.Fatal("event should not be handle after unload")
+	}
+	if COMMAND_MANAGER.commandControllerMap["mark"] != nil {
+		t.Fatal("lua plugin command not unregister")
+	}
+}
+
+func TestLuaPluginBudget(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 10)
+	writeTestLuaPlugin(t, pluginPath, "loop", `
+tickCount = 0
+Plugin.AddGlobalTick(PluginGlobalTick.SECOND, function()
+	tickCount = tickCount + 1
+	if tickCount == 2 then
+		while true do
+			pcall(function() while true do end end)
+		end
+	end
+end)
+`)
+	PLUGIN_MANAGER.LoadAllLuaPlugin()
+	pluginLua := PLUGIN_MANAGER.luaPluginMap["loop"]
+	PLUGIN_MANAGER.HandleGlobalTick(PluginGlobalTickSecond)
+	if !pluginLua.isEnable {
+		t.Fatal("plugin should enable before exceed budget")
+	}
+	PLUGIN_MANAGER.HandleGlobalTick(PluginGlobalTickSecond)
+	if pluginLua.isEnable || !pluginLua.budgetExceeded {
+		t.Fatal("plugin should be disable after exceed budget")
+	}
+	PLUGIN_MANAGER.HandleGlobalTick(PluginGlobalTickSecond)
+	// 重新加载后恢复
+	err := PLUGIN_MANAGER.ReloadLuaPlugin("loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadPluginLua := PLUGIN_MANAGER.luaPluginMap["loop"]
+	if reloadPluginLua == pluginLua || !reloadPluginLua.isEnable {
+		t.Fatal("plugin not reload")
+	}
+	// 脚本加载期间超出预算则加载失败 保留旧插件
+	writeTestLuaPlugin(t, pluginPath, "loop", `while true do end`)
+	if PLUGIN_MANAGER.ReloadLuaPlugin("loop") == nil {
+		t.Fatal("reload endless loop script should fail")
+	}
+	if PLUGIN_MANAGER.luaPluginMap["loop"] != reloadPluginLua {
+		t.Fatal("old plugin should keep running")
+	}
+}
+
+func TestLuaPluginSandbox(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 0)
+	writeTestLuaPlugin(t, pluginPath, "sandbox", `
+assert(os == nil and io == nil and require == nil and loadstring == nil and dofile == nil and coroutine == nil)
+`)
+	writeTestLuaPlugin(t, pluginPath, "error", `error("load fail")`)
+	err := PLUGIN_MANAGER.ReloadLuaPlugin("")
+	if err == nil {
+		t.Fatal("error plugin should load fail")
+	}
+	nameList := PLUGIN_MANAGER.GetLuaPluginNameList()
+	if len(nameList) != 1 || nameList[0] != "sandbox" {
+		t.Fatalf("lua plugin load error: %v", nameList)
+	}
+	// 删除文件后重新加载全部插件会卸载该插件
+	_ = os.Remove(filepath.Join(pluginPath, "sandbox.lua"))
+	_ = PLUGIN_MANAGER.ReloadLuaPlugin("")
+	if len(PLUGIN_MANAGER.GetLuaPluginNameList()) != 0 {
+		t.Fatalf("removed lua plugin not unload: %v", PLUGIN_MANAGER.GetLuaPluginNameList())
+	}
+	if PLUGIN_MANAGER.LoadLuaPlugin("../sandbox") == nil {
+		t.Fatal("invalid plugin name should fail")
+	}
+}
+
+func TestLuaPluginStringLimit(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 0)
+	writeTestLuaPlugin(t, pluginPath, "str", `
+assert(string.rep("ab", 3) == "ababab")
+assert(not pcall(string.rep, "a", 1e9))
+assert(not pcall(function() return ("a"):rep(1e9) end))
+assert(string.find("abc", "b") == 2 and ("abc"):match("b") == "b")
+local count = 0
+for _ in string.gmatch("a,b,c", "%a") do
+	count = count + 1
+end
+assert(count == 3)
+local long = string.rep("a", 131072)
+assert(not pcall(string.find, long, "a-b"))
+assert(not pcall(string.match, long, "a-b"))
+assert(not pcall(string.gmatch, long, "a"))
+assert(not pcall(string.gsub, string.rep("a", 1000), "a", string.rep("b", 2000)))
+assert(string.gsub("hello world", "o", "0") == "hell0 w0rld")
+assert(table.concat({"a", "b", 1}, ",") == "a,b,1")
+local list = {}
+for i = 1, 100 do
+	list[i] = string.rep("a", 65536)
+end
+assert(not pcall(table.concat, list))
+`)
+	err := PLUGIN_MANAGER.LoadLuaPlugin("str")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
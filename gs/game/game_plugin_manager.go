@@ -20,11 +20,16 @@ func (p *PluginManager) InitPlugin() {
 		NewPluginPubg(),
 	}
 	p.RegAllPlugin(iPluginList...)
+	// lua插件
+	p.LoadAllLuaPlugin()
 }
 
 // 事件定义
 // 添加事件编号 事件结构
 // 即可触发以及监听事件
+// Pre前缀的事件在逻辑执行前触发 取消后触发处不会继续执行
+// 其余事件为逻辑执行后的通知 取消无效
+// 同一事件按优先级从高到低执行 优先级相同时编译插件先于lua插件执行 同一插件内按监听顺序执行
 
 // PluginEventId 事件编号
 type PluginEventId uint16
@@ -39,8 +44,46 @@ const (
 	PluginEventIdEvtBeingHit
 	PluginEventIdEvtCreateGadget
 	PluginEventIdEvtBulletHit
+	PluginEventIdPlayerLogin
+	PluginEventIdPlayerOffline
+	PluginEventIdPreAddItem
+	PluginEventIdPostAddItem
+	PluginEventIdPostCostItem
+	PluginEventIdPreChat
+	PluginEventIdMonsterDie
+	PluginEventIdQuestStateChange
+	PluginEventIdPreTeleport
+	PluginEventIdPreSetUpAvatarTeam
+	PluginEventIdChangeAvatar
+	PluginEventIdPreJoinPlayerScene
+	PluginEventIdPlayerLeaveWorld
 )
 
+// PluginEventIdNameMap 事件名 暴露给lua插件使用
+var PluginEventIdNameMap = map[PluginEventId]string{
+	PluginEventIdMarkMap:               "MARK_MAP",
+	PluginEventIdAvatarDieAnimationEnd: "AVATAR_DIE_ANIMATION_END",
+	PluginEventIdGadgetInteract:        "GADGET_INTERACT",
+	PluginEventIdPostEnterScene:        "POST_ENTER_SCENE",
+	PluginEventIdEvtDoSkillSucc:        "EVT_DO_SKILL_SUCC",
+	PluginEventIdEvtBeingHit:           "EVT_BEING_HIT",
+	PluginEventIdEvtCreateGadget:       "EVT_CREATE_GADGET",
+	PluginEventIdEvtBulletHit:          "EVT_BULLET_HIT",
+	PluginEventIdPlayerLogin:           "PLAYER_LOGIN",
+	PluginEventIdPlayerOffline:         "PLAYER_OFFLINE",
+	PluginEventIdPreAddItem:            "PRE_ADD_ITEM",
+	PluginEventIdPostAddItem:           "POST_ADD_ITEM",
+	PluginEventIdPostCostItem:          "POST_COST_ITEM",
+	PluginEventIdPreChat:               "PRE_CHAT",
+	PluginEventIdMonsterDie:            "MONSTER_DIE",
+	PluginEventIdQuestStateChange:      "QUEST_STATE_CHANGE",
+	PluginEventIdPreTeleport:           "PRE_TELEPORT",
+	PluginEventIdPreSetUpAvatarTeam:    "PRE_SET_UP_AVATAR_TEAM",
+	PluginEventIdChangeAvatar:          "CHANGE_AVATAR",
+	PluginEventIdPreJoinPlayerScene:    "PRE_JOIN_PLAYER_SCENE",
+	PluginEventIdPlayerLeaveWorld:      "PLAYER_LEAVE_WORLD",
+}
+
 // PluginEventMarkMap 地图标点
 type PluginEventMarkMap struct {
 	*PluginEvent
@@ -97,6 +140,107 @@ type PluginEventEvtBulletHit struct {
 	Ntf    *proto.EvtBulletHitNotify // 请求
 }
 
+// PluginEventPlayerLogin 玩家登录完成
+type PluginEventPlayerLogin struct {
+	*PluginEvent
+	Player      *model.Player // 玩家
+	IsNewPlayer bool          // 是否为新注册玩家
+}
+
+// PluginEventPlayerOffline 玩家下线 在保存玩家数据前触发
+type PluginEventPlayerOffline struct {
+	*PluginEvent
+	Player     *model.Player // 玩家
+	IsChangeGs bool          // 是否为跨服迁移
+}
+
+// PluginEventPreAddItem 添加物品前 取消后不添加物品
+// 只在掉落 采集和GM指令添加物品时触发 其余原因不可取消
+type PluginEventPreAddItem struct {
+	*PluginEvent
+	Player     *model.Player          // 玩家
+	ItemList   []*ChangeItem          // 物品列表
+	HintReason proto.ActionReasonType // 原因
+}
+
+// PluginEventPostAddItem 添加物品后
+type PluginEventPostAddItem struct {
+	*PluginEvent
+	Player     *model.Player          // 玩家
+	ItemList   []*ChangeItem          // 物品列表
+	HintReason proto.ActionReasonType // 原因
+}
+
+// PluginEventPostCostItem 消耗物品后
+type PluginEventPostCostItem struct {
+	*PluginEvent
+	Player   *model.Player // 玩家
+	ItemList []*ChangeItem // 物品列表
+}
+
+// PluginEventPreChat 发送聊天前 取消后不发送
+type PluginEventPreChat struct {
+	*PluginEvent
+	Player *model.Player        // 玩家
+	Req    *proto.PlayerChatReq // 请求
+}
+
+// PluginEventMonsterDie 怪物死亡
+type PluginEventMonsterDie struct {
+	*PluginEvent
+	Player    *model.Player       // 击杀的玩家
+	SceneId   uint32              // 场景id
+	EntityId  uint32              // 实体id
+	MonsterId uint32              // 怪物id
+	DieType   proto.PlayerDieType // 死亡类型
+}
+
+// PluginEventQuestStateChange 任务状态变化
+type PluginEventQuestStateChange struct {
+	*PluginEvent
+	Player  *model.Player // 玩家
+	QuestId uint32        // 任务id
+	State   uint8         // 新的任务状态
+}
+
+// PluginEventPreTeleport 传送前 取消后不传送 可修改目标坐标
+type PluginEventPreTeleport struct {
+	*PluginEvent
+	Player      *model.Player     // 玩家
+	EnterReason proto.EnterReason // 进入场景原因
+	SceneId     uint32            // 目标场景id
+	Pos         *model.Vector     // 目标坐标
+}
+
+// PluginEventPreSetUpAvatarTeam 设置队伍前 取消后不修改队伍
+type PluginEventPreSetUpAvatarTeam struct {
+	*PluginEvent
+	Player *model.Player             // 玩家
+	Req    *proto.SetUpAvatarTeamReq // 请求
+}
+
+// PluginEventChangeAvatar 切换当前角色后
+type PluginEventChangeAvatar struct {
+	*PluginEvent
+	Player      *model.Player // 玩家
+	OldAvatarId uint32        // 切换前的角色id
+	NewAvatarId uint32        // 切换后的角色id
+}
+
+// PluginEventPreJoinPlayerScene 加入他人世界前 取消后不加入
+type PluginEventPreJoinPlayerScene struct {
+	*PluginEvent
+	Player    *model.Player // 玩家
+	TargetUid uint32        // 目标世界主人uid
+}
+
+// PluginEventPlayerLeaveWorld 离开多人世界后
+type PluginEventPlayerLeaveWorld struct {
+	*PluginEvent
+	Player *model.Player                           // 玩家
+	Reason proto.PlayerQuitFromMpNotify_QuitReason // 原因
+}
+
 type PluginEventFunc func(event IPluginEvent)
 
 // IPluginEvent 插件事件接口
@@ -235,16 +379,19 @@ func (p *Plugin) CreateUserTimer(userId uint32, delay uint32, timerFunc PluginUs
 // RegCommandController 注册命令控制器
 func (p *Plugin) RegCommandController(controller *CommandController) {
 	COMMAND_MANAGER.RegController(controller)
+	p.commandControllerList = append(p.commandControllerList, controller)
 }
 
 type PluginManager struct {
 	pluginMap        map[reflect.Type]IPlugin // 插件集合
+	luaPluginMap     map[string]*PluginLua    // lua插件集合 k:插件名
 	userTimerCounter uint64                   // 用户timer计数器
 }
 
 func NewPluginManager() *PluginManager {
 	r := new(PluginManager)
 	r.pluginMap = make(map[reflect.Type]IPlugin)
+	r.luaPluginMap = make(map[string]*PluginLua)
 	return r
 }
 
@@ -311,16 +458,37 @@ func (p *PluginManager) GetPlugin(value IPlugin) (plugin IPlugin, err error) {
 	return plugin, nil
 }
 
+// getEnablePluginList 获取全部已启用的插件 包括lua插件
+// 编译插件按类型名排序 lua插件按插件名排序 保证事件执行顺序稳定
+func (p *PluginManager) getEnablePluginList() []*Plugin {
+	pluginList := make([]*Plugin, 0, len(p.pluginMap)+len(p.luaPluginMap))
+	refTypeList := make([]reflect.Type, 0, len(p.pluginMap))
+	for refType := range p.pluginMap {
+		refTypeList = append(refTypeList, refType)
+	}
+	sort.Slice(refTypeList, func(i, j int) bool {
+		return refTypeList[i].String() < refTypeList[j].String()
+	})
+	for _, refType := range refTypeList {
+		plugin := p.pluginMap[refType].GetPlugin()
+		if plugin.isEnable {
+			pluginList = append(pluginList, plugin)
+		}
+	}
+	for _, name := range p.GetLuaPluginNameList() {
+		plugin := p.luaPluginMap[name].GetPlugin()
+		if plugin.isEnable {
+			pluginList = append(pluginList, plugin)
+		}
+	}
+	return pluginList
+}
+
 // TriggerEvent 触发事件
 func (p *PluginManager) TriggerEvent(eventId PluginEventId, event IPluginEvent) bool {
 	// 获取每个插件监听的事件并根据优先级排序
 	eventInfoList := make([]*PluginEventInfo, 0)
-	for _, iPlugin := range p.pluginMap {
-		plugin := iPlugin.GetPlugin()
-		// 插件未启用则跳过
-		if !plugin.isEnable {
-			continue
-		}
+	for _, plugin := range p.getEnablePluginList() {
 		// 获取插件事件列表
 		infoList, exist := plugin.eventMap[eventId]
 		if !exist {
@@ -330,8 +498,8 @@ func (p *PluginManager) TriggerEvent(eventId PluginEventId, event IPluginEvent)
 			eventInfoList = append(eventInfoList, info)
 		}
 	}
-	// 根据优先级排序
-	sort.Slice(eventInfoList, func(i, j int) bool {
+	// 根据优先级排序 优先级相同的保持插件顺序
+	sort.SliceStable(eventInfoList, func(i, j int) bool {
 		return eventInfoList[i].Priority > eventInfoList[j].Priority
 	})
 	// 执行每个处理函数
@@ -344,12 +512,7 @@ func (p *PluginManager) TriggerEvent(eventId PluginEventId, event IPluginEvent)
 
 // HandleGlobalTick 处理全局tick
 func (p *PluginManager) HandleGlobalTick(tick PluginGlobalTick) {
-	for _, iPlugin := range p.pluginMap {
-		plugin := iPlugin.GetPlugin()
-		// 插件未启用则跳过
-		if !plugin.isEnable {
-			continue
-		}
+	for _, plugin := range p.getEnablePluginList() {
 		// 获取插件tick处理函数列表
 		tickFuncList, exist := plugin.globalTickMap[tick]
 		if !exist {
@@ -371,21 +534,16 @@ func (p *PluginManager) HandleUserTimer(player *model.Player, data []any) {
 	userTimerId := data[0].(uint64)
 	data = data[1:]
 	// 通知插件
-	for _, iPlugin := range p.pluginMap {
-		plugin := iPlugin.GetPlugin()
-		// 插件未启用则跳过
-		if !plugin.isEnable {
-			continue
-		}
+	for _, plugin := range p.getEnablePluginList() {
 		// 获取插件用户timer处理函数列表
 		timerFunc, exist := plugin.userTimerMap[userTimerId]
 		if !exist {
-			logger.Error("plugin timer not exist, id: %v", userTimerId)
 			continue
 		}
-		timerFunc(player, data)
 		delete(plugin.userTimerMap, userTimerId)
+		timerFunc(player, data)
 		// 只需要执行一次
-		break
+		return
 	}
+	logger.Error("plugin timer not exist, id: %v", userTimerId)
 }
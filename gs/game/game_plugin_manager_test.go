@@ -0,0 +1,122 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+type testPluginA struct {
+	*Plugin
+}
+
+type testPluginB struct {
+	*Plugin
+}
+
+func TestPluginEventOrder(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 0)
+	orderList := make([]string, 0)
+	record := func(name string, cancel bool) PluginEventFunc {
+		return func(event IPluginEvent) {
+			order := name
+			if event.IsCancel() {
+				order += "(cancel)"
+			}
+			orderList = append(orderList, order)
+			if cancel {
+				event.Cancel()
+			}
+		}
+	}
+	// 注册顺序与执行顺序无关
+	pluginB := &testPluginB{Plugin: NewPlugin()}
+	pluginB.ListenEvent(PluginEventIdPreChat, PluginEventPriorityNormal, record("b_normal", false))
+	pluginB.ListenEvent(PluginEventIdPreChat, PluginEventPriorityHighest, record("b_highest", true))
+	pluginA := &testPluginA{Plugin: NewPlugin()}
+	pluginA.ListenEvent(PluginEventIdPreChat, PluginEventPriorityNormal, record("a_normal_1", false), record("a_normal_2", false))
+	pluginA.ListenEvent(PluginEventIdPreChat, PluginEventPriorityLowest, record("a_lowest", false))
+	PLUGIN_MANAGER.RegAllPlugin(pluginB, pluginA)
+	writeTestLuaPlugin(t, pluginPath, "chat", `
+Plugin.ListenEvent(PluginEventId.PRE_CHAT, PluginEventPriority.NORMAL, function(event)
+	Plugin.Log(event.Player, event.Req.chat_info.text)
+end)
+`)
+	PLUGIN_MANAGER.LoadAllLuaPlugin()
+	luaOrder := "lua_chat"
+	PLUGIN_MANAGER.luaPluginMap["chat"].ListenEvent(PluginEventIdPreChat, PluginEventPriorityNormal, func(event IPluginEvent) {
+		orderList = append(orderList, luaOrder)
+	})
+	event := &PluginEventPreChat{
+		PluginEvent: NewPluginEvent(),
+		Player:      &model.Player{PlayerId: 10001},
+		Req:         &proto.PlayerChatReq{ChatInfo: &proto.ChatInfo{Content: &proto.ChatInfo_Text{Text: "hello"}}},
+	}
+	// 高优先级先执行 取消后仍会传递给后续处理函数
+	// 优先级相同时编译插件按类型名排序 然后是lua插件 同一插件内按监听顺序
+	if !PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreChat, event) {
+		t.Fatal("event should be cancel")
+	}
+	expectList := []string{"b_highest", "a_normal_1(cancel)", "a_normal_2(cancel)", "b_normal(cancel)", "lua_chat", "a_lowest(cancel)"}
+	if !reflect.DeepEqual(orderList, expectList) {
+		t.Fatalf("event order error: %v", orderList)
+	}
+	// 禁用的插件不再收到事件
+	pluginB.isEnable = false
+	orderList = orderList[:0]
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreChat, &PluginEventPreChat{PluginEvent: NewPluginEvent(), Req: event.Req}) {
+		t.Fatal("event should not be cancel")
+	}
+	if !reflect.DeepEqual(orderList, []string{"a_normal_1", "a_normal_2", "lua_chat", "a_lowest"}) {
+		t.Fatalf("disable plugin event order error: %v", orderList)
+	}
+}
+
+func TestPluginPreAddItem(t *testing.T) {
+	pluginPath := newTestLuaPluginManager(t, 0)
+	player := &model.Player{PlayerId: 10001, Online: true}
+	oldUserManager := USER_MANAGER
+	USER_MANAGER = &UserManager{playerMap: map[uint32]*model.Player{player.PlayerId: player}}
+	t.Cleanup(func() {
+		USER_MANAGER = oldUserManager
+	})
+	writeTestLuaPlugin(t, pluginPath, "item", `
+postCount = 0
+Plugin.ListenEvent(PluginEventId.PRE_ADD_ITEM, PluginEventPriority.NORMAL, function(event)
+	return true
+end)
+Plugin.ListenEvent(PluginEventId.POST_ADD_ITEM, PluginEventPriority.NORMAL, function(event)
+	postCount = postCount + 1
+end)
+`)
+	PLUGIN_MANAGER.LoadAllLuaPlugin()
+	postCount := func() int {
+		return int(PLUGIN_MANAGER.luaPluginMap["item"].luaState.GetGlobal("postCount").(lua.LNumber))
+	}
+	g := new(Game)
+	// 前置事件取消后不添加物品 也不会触发后置事件
+	if g.AddPlayerItem(player.PlayerId, []*ChangeItem{}, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP) {
+		t.Fatal("add item should be cancel")
+	}
+	if postCount() != 0 {
+		t.Fatal("post event should not trigger after cancel")
+	}
+	// 已经提交状态的原因不触发前置事件 不可取消
+	for _, reason := range []proto.ActionReasonType{
+		proto.ActionReasonType_ACTION_REASON_RECHARGE,
+		proto.ActionReasonType_ACTION_REASON_MAIL_ATTACHMENT,
+		proto.ActionReasonType_ACTION_REASON_SIGN_IN_REWARD,
+		proto.ActionReasonType_ACTION_REASON_OPEN_BLOSSOM_CHEST,
+	} {
+		if !g.AddPlayerItem(player.PlayerId, []*ChangeItem{}, reason) {
+			t.Fatalf("add item should succ, reason: %v", reason)
+		}
+	}
+	if postCount() != 4 {
+		t.Fatal("post event not trigger")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/random"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -405,7 +406,7 @@ func (p *PluginPubg) GlobalTickPubg() {
 
 // GlobalTickMinuteChange 定时开启pubg游戏
 func (p *PluginPubg) GlobalTickMinuteChange() {
-	minute := time.Now().Minute()
+	minute := clock.Now().Minute()
 	roomNumber := GAME.GetGsId() - 1
 	startMinute := roomNumber % 6 * 10
 	if uint32(minute) == startMinute {
@@ -441,7 +442,7 @@ func (p *PluginPubg) UserTimerPubgEnd(player *model.Player, data []any) {
 	WORLD_MANAGER.InitAiWorld(player)
 	roomNumber := GAME.GetGsId() - 1
 	startMinute := roomNumber % 6 * 10
-	info := fmt.Sprintf("下一次游戏开启时间：%02d:%02d。", time.Now().Add(time.Hour).Hour(), startMinute)
+	info := fmt.Sprintf("下一次游戏开启时间：%02d:%02d。", clock.Now().Add(time.Hour).Hour(), startMinute)
 	GAME.PlayerChatReq(player, &proto.PlayerChatReq{ChatInfo: &proto.ChatInfo{Content: &proto.ChatInfo_Text{Text: info}}})
 }
 
@@ -725,7 +726,7 @@ func (p *PluginPubg) PubgHit(scene *Scene, defAvatarEntityId uint32, atkAvatarEn
 	if atkPlayer == nil {
 		return
 	}
-	now := time.Now().UnixMilli()
+	now := clock.Now().UnixMilli()
 	lastHitTime := p.playerHitTimeMap[atkPlayer.PlayerId]
 	if now-lastHitTime < PUBG_NORMAL_ATTACK_INTERVAL_TIME {
 		return
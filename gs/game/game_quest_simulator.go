@@ -0,0 +1,375 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+)
+
+// 任务引擎覆盖率统计与离线任务链模拟
+// 在虚拟玩家身上按配置表推进任务 已实现的完成条件视为客户端会正常触发 找出每条任务链第一个卡住的子任务
+
+const (
+	QuestSimulatorMaxRound = 10000 // 模拟推进的最大轮数
+)
+
+const (
+	QuestCoverageKindAcceptCond = "accept_cond"
+	QuestCoverageKindFinishCond = "finish_cond"
+	QuestCoverageKindFailCond   = "fail_cond"
+	QuestCoverageKindExec       = "exec"
+	QuestCoverageKindFailExec   = "fail_exec"
+	QuestCoverageKindStartExec  = "start_exec"
+)
+
+const (
+	QuestBlockReasonAcceptCondNotSupport = "accept_cond_not_support" // 领取条件未实现
+	QuestBlockReasonAcceptCondNotMatch   = "accept_cond_not_match"   // 领取条件已实现但无法满足
+	QuestBlockReasonFinishCondNotSupport = "finish_cond_not_support" // 完成条件未实现
+	QuestBlockReasonFinishCondNotMatch   = "finish_cond_not_match"   // 完成条件已实现但无法满足
+)
+
+// QuestTypeCoverage 某一种条件或执行类型的覆盖情况
+type QuestTypeCoverage struct {
+	Kind       string
+	Type       int32
+	Support    bool
+	QuestCount int // 使用该类型的子任务数量
+}
+
+// QuestChainResult 一条任务链(父任务)的模拟结果
+type QuestChainResult struct {
+	ParentQuestId  int32
+	MainQuest      bool // 是否主线任务
+	QuestCount     int
+	FinishCount    int
+	BlockQuestId   int32   // 第一个卡住的子任务 任务链完成时为0
+	BlockReason    string  // 卡住原因
+	BlockTypeList  []int32 // 卡住相关的未实现类型
+	NotSupportExec []int32 // 任务链中未实现的执行类型 不阻塞任务推进
+}
+
+func (q *QuestChainResult) IsFinish() bool {
+	return q.BlockQuestId == 0
+}
+
+// QuestCoverageReport 任务引擎覆盖率报告
+type QuestCoverageReport struct {
+	QuestCount    int
+	TypeList      []*QuestTypeCoverage
+	ChainList     []*QuestChainResult
+	SimulateRound int // 模拟推进轮数
+}
+
+// BuildQuestCoverageReport 统计当前配置表的任务覆盖率并模拟全部任务链
+func BuildQuestCoverageReport() *QuestCoverageReport {
+	report := &QuestCoverageReport{
+		QuestCount: len(gdconf.GetQuestDataMap()),
+		TypeList:   make([]*QuestTypeCoverage, 0),
+		ChainList:  make([]*QuestChainResult, 0),
+	}
+	report.buildTypeCoverage()
+	player := &model.Player{PlayerId: PlayerBaseUid}
+	report.SimulateRound = SimulateQuest(player)
+	report.buildChainResult(player.GetDbQuest())
+	return report
+}
+
+func (r *QuestCoverageReport) buildTypeCoverage() {
+	coverageMap := make(map[string]map[int32]*QuestTypeCoverage)
+	addCoverage := func(kind string, typ int32) {
+		kindMap, exist := coverageMap[kind]
+		if !exist {
+			kindMap = make(map[int32]*QuestTypeCoverage)
+			coverageMap[kind] = kindMap
+		}
+		coverage, exist := kindMap[typ]
+		if !exist {
+			coverage = &QuestTypeCoverage{Kind: kind, Type: typ, Support: QuestTypeSupport(kind, typ), QuestCount: 0}
+			kindMap[typ] = coverage
+			r.TypeList = append(r.TypeList, coverage)
+		}
+		coverage.QuestCount++
+	}
+	for _, questData := range gdconf.GetQuestDataMap() {
+		// 同一子任务内重复的类型只统计一次
+		for kind, typeList := range questDataTypeListMap(questData) {
+			typeMap := make(map[int32]bool)
+			for _, typ := range typeList {
+				if typeMap[typ] {
+					continue
+				}
+				typeMap[typ] = true
+				addCoverage(kind, typ)
+			}
+		}
+	}
+	sort.Slice(r.TypeList, func(i, j int) bool {
+		if r.TypeList[i].Kind != r.TypeList[j].Kind {
+			return questKindOrder(r.TypeList[i].Kind) < questKindOrder(r.TypeList[j].Kind)
+		}
+		return r.TypeList[i].Type < r.TypeList[j].Type
+	})
+}
+
+func questDataTypeListMap(questData *gdconf.QuestData) map[string][]int32 {
+	condTypeList := func(condList []*gdconf.QuestCond) []int32 {
+		typeList := make([]int32, 0, len(condList))
+		for _, cond := range condList {
+			typeList = append(typeList, cond.Type)
+		}
+		return typeList
+	}
+	execTypeList := func(execList []*gdconf.QuestExec) []int32 {
+		typeList := make([]int32, 0, len(execList))
+		for _, exec := range execList {
+			typeList = append(typeList, exec.Type)
+		}
+		return typeList
+	}
+	return map[string][]int32{
+		QuestCoverageKindAcceptCond: condTypeList(questData.AcceptCondList),
+		QuestCoverageKindFinishCond: condTypeList(questData.FinishCondList),
+		QuestCoverageKindFailCond:   condTypeList(questData.FailCondList),
+		QuestCoverageKindExec:       execTypeList(questData.ExecList),
+		QuestCoverageKindFailExec:   execTypeList(questData.FailExecList),
+		QuestCoverageKindStartExec:  execTypeList(questData.StartExecList),
+	}
+}
+
+// QuestTypeSupport 任务引擎是否注册了该类型的处理方法
+func QuestTypeSupport(kind string, typ int32) bool {
+	exist := false
+	switch kind {
+	case QuestCoverageKindAcceptCond:
+		_, exist = questAcceptCondHandlerMap[typ]
+	case QuestCoverageKindFinishCond:
+		_, exist = questFinishCondHandlerMap[typ]
+	case QuestCoverageKindFailCond:
+		_, exist = questFailCondHandlerMap[typ]
+	default:
+		_, exist = questExecHandlerMap[typ]
+	}
+	return exist
+}
+
+func questKindOrder(kind string) int {
+	switch kind {
+	case QuestCoverageKindAcceptCond:
+		return 0
+	case QuestCoverageKindFinishCond:
+		return 1
+	case QuestCoverageKindFailCond:
+		return 2
+	case QuestCoverageKindExec:
+		return 3
+	case QuestCoverageKindFailExec:
+		return 4
+	default:
+		return 5
+	}
+}
+
+func getSortQuestDataList() []*gdconf.QuestData {
+	questDataList := make([]*gdconf.QuestData, 0, len(gdconf.GetQuestDataMap()))
+	for _, questData := range gdconf.GetQuestDataMap() {
+		questDataList = append(questDataList, questData)
+	}
+	sort.Slice(questDataList, func(i, j int) bool {
+		return questDataList[i].QuestId < questDataList[j].QuestId
+	})
+	return questDataList
+}
+
+// SimulateQuest 在虚拟玩家身上推进任务直到无法继续 返回推进轮数
+// 领取条件与任务引擎使用同一套判定 已实现的完成条件视为满足 未实现的完成条件永远不满足 不模拟失败分支
+func SimulateQuest(player *model.Player) int {
+	dbQuest := player.GetDbQuest()
+	questDataList := getSortQuestDataList()
+	round := 0
+	for ; round < QuestSimulatorMaxRound; round++ {
+		progress := false
+		for _, questData := range questDataList {
+			if dbQuest.GetQuestById(uint32(questData.QuestId)) != nil {
+				continue
+			}
+			if !CheckQuestAcceptCond(dbQuest, questData) {
+				continue
+			}
+			dbQuest.AddQuest(uint32(questData.QuestId))
+			dbQuest.StartQuest(uint32(questData.QuestId))
+			progress = true
+		}
+		for _, questData := range questDataList {
+			quest := dbQuest.GetQuestById(uint32(questData.QuestId))
+			if quest == nil || quest.State != constant.QUEST_STATE_UNFINISHED {
+				continue
+			}
+			for index, finishCond := range questData.FinishCondList {
+				if !QuestTypeSupport(QuestCoverageKindFinishCond, finishCond.Type) {
+					continue
+				}
+				finishCount := finishCond.Count
+				if finishCount == 0 {
+					finishCount = 1
+				}
+				quest.FinishCountList[index] = uint32(finishCount)
+			}
+			dbQuest.CheckQuestFinish(quest.QuestId)
+			if quest.State == constant.QUEST_STATE_FINISHED {
+				progress = true
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+	return round
+}
+
+func (r *QuestCoverageReport) buildChainResult(dbQuest *model.DbQuest) {
+	parentQuestMap := make(map[int32][]*gdconf.QuestData)
+	for _, questData := range getSortQuestDataList() {
+		parentQuestMap[questData.ParentQuestId] = append(parentQuestMap[questData.ParentQuestId], questData)
+	}
+	for parentQuestId, questDataList := range parentQuestMap {
+		sort.SliceStable(questDataList, func(i, j int) bool {
+			return questDataList[i].Sequence < questDataList[j].Sequence
+		})
+		chain := &QuestChainResult{
+			ParentQuestId:  parentQuestId,
+			MainQuest:      gdconf.GetMainQuestDataById(parentQuestId) != nil,
+			QuestCount:     len(questDataList),
+			FinishCount:    0,
+			BlockQuestId:   0,
+			BlockReason:    "",
+			BlockTypeList:  make([]int32, 0),
+			NotSupportExec: make([]int32, 0),
+		}
+		execTypeMap := make(map[int32]bool)
+		for _, questData := range questDataList {
+			for _, execList := range [][]*gdconf.QuestExec{questData.StartExecList, questData.ExecList, questData.FailExecList} {
+				for _, exec := range execList {
+					if QuestTypeSupport(QuestCoverageKindExec, exec.Type) || execTypeMap[exec.Type] {
+						continue
+					}
+					execTypeMap[exec.Type] = true
+					chain.NotSupportExec = append(chain.NotSupportExec, exec.Type)
+				}
+			}
+			quest := dbQuest.GetQuestById(uint32(questData.QuestId))
+			if quest != nil && quest.State == constant.QUEST_STATE_FINISHED {
+				chain.FinishCount++
+				continue
+			}
+			if chain.BlockQuestId != 0 {
+				continue
+			}
+			chain.BlockQuestId = questData.QuestId
+			if quest == nil {
+				chain.BlockTypeList = questNotSupportCondTypeList(questData.AcceptCondList, QuestCoverageKindAcceptCond)
+				if len(chain.BlockTypeList) != 0 {
+					chain.BlockReason = QuestBlockReasonAcceptCondNotSupport
+				} else {
+					chain.BlockReason = QuestBlockReasonAcceptCondNotMatch
+				}
+			} else {
+				chain.BlockTypeList = questNotSupportCondTypeList(questData.FinishCondList, QuestCoverageKindFinishCond)
+				if len(chain.BlockTypeList) != 0 {
+					chain.BlockReason = QuestBlockReasonFinishCondNotSupport
+				} else {
+					chain.BlockReason = QuestBlockReasonFinishCondNotMatch
+				}
+			}
+		}
+		sort.Slice(chain.NotSupportExec, func(i, j int) bool {
+			return chain.NotSupportExec[i] < chain.NotSupportExec[j]
+		})
+		r.ChainList = append(r.ChainList, chain)
+	}
+	sort.Slice(r.ChainList, func(i, j int) bool {
+		return r.ChainList[i].ParentQuestId < r.ChainList[j].ParentQuestId
+	})
+}
+
+func questNotSupportCondTypeList(condList []*gdconf.QuestCond, kind string) []int32 {
+	typeList := make([]int32, 0)
+	typeMap := make(map[int32]bool)
+	for _, cond := range condList {
+		if QuestTypeSupport(kind, cond.Type) || typeMap[cond.Type] {
+			continue
+		}
+		typeMap[cond.Type] = true
+		typeList = append(typeList, cond.Type)
+	}
+	sort.Slice(typeList, func(i, j int) bool {
+		return typeList[i] < typeList[j]
+	})
+	return typeList
+}
+
+// WriteText 输出文本格式报告 内容只与配置表和已实现的类型有关 可直接在不同提交间diff
+func (r *QuestCoverageReport) WriteText(w io.Writer, mainOnly bool) error {
+	chainTotal, chainFinish, mainTotal, mainFinish := 0, 0, 0, 0
+	for _, chain := range r.ChainList {
+		chainTotal++
+		if chain.IsFinish() {
+			chainFinish++
+		}
+		if chain.MainQuest {
+			mainTotal++
+			if chain.IsFinish() {
+				mainFinish++
+			}
+		}
+	}
+	lineList := make([]string, 0)
+	lineList = append(lineList, "# quest coverage report")
+	lineList = append(lineList, "## summary")
+	lineList = append(lineList, fmt.Sprintf("quest: %v", r.QuestCount))
+	lineList = append(lineList, fmt.Sprintf("chain: %v finish: %v", chainTotal, chainFinish))
+	lineList = append(lineList, fmt.Sprintf("main chain: %v finish: %v", mainTotal, mainFinish))
+	lineList = append(lineList, fmt.Sprintf("simulate round: %v", r.SimulateRound))
+	lineList = append(lineList, "## type")
+	for _, coverage := range r.TypeList {
+		support := "not_support"
+		if coverage.Support {
+			support = "support"
+		}
+		lineList = append(lineList, fmt.Sprintf("%v %v %v quest: %v", coverage.Kind, coverage.Type, support, coverage.QuestCount))
+	}
+	lineList = append(lineList, "## chain")
+	for _, chain := range r.ChainList {
+		if mainOnly && !chain.MainQuest {
+			continue
+		}
+		main := ""
+		if chain.MainQuest {
+			main = " main"
+		}
+		line := ""
+		if chain.IsFinish() {
+			line = fmt.Sprintf("%v%v finish %v/%v", chain.ParentQuestId, main, chain.FinishCount, chain.QuestCount)
+		} else {
+			line = fmt.Sprintf("%v%v block %v/%v quest: %v reason: %v", chain.ParentQuestId, main, chain.FinishCount, chain.QuestCount, chain.BlockQuestId, chain.BlockReason)
+			if len(chain.BlockTypeList) != 0 {
+				line += fmt.Sprintf(" type: %v", chain.BlockTypeList)
+			}
+		}
+		if len(chain.NotSupportExec) != 0 {
+			line += fmt.Sprintf(" not_support_exec: %v", chain.NotSupportExec)
+		}
+		lineList = append(lineList, line)
+	}
+	for _, line := range lineList {
+		_, err := io.WriteString(w, line+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
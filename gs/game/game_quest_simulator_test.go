@@ -0,0 +1,216 @@
+package game
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+)
+
+// 解析player_quest.go 获取函数内switch语句的case常量名 只有continue的case视为未实现
+func parseQuestSwitchCaseList(t *testing.T, funcName string) [][]string {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "player_quest.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse player_quest.go error: %v", err)
+	}
+	switchCaseList := make([][]string, 0)
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Name.Name != funcName {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			switchStmt, ok := node.(*ast.SwitchStmt)
+			if !ok {
+				return true
+			}
+			caseList := make([]string, 0)
+			for _, stmt := range switchStmt.Body.List {
+				caseClause := stmt.(*ast.CaseClause)
+				if len(caseClause.Body) == 1 {
+					branchStmt, ok := caseClause.Body[0].(*ast.BranchStmt)
+					if ok && branchStmt.Tok == token.CONTINUE {
+						continue
+					}
+				}
+				for _, expr := range caseClause.List {
+					selectorExpr, ok := expr.(*ast.SelectorExpr)
+					if !ok || !strings.HasPrefix(selectorExpr.Sel.Name, "QUEST_") || strings.HasPrefix(selectorExpr.Sel.Name, "QUEST_LOGIC_TYPE_") {
+						continue
+					}
+					caseList = append(caseList, selectorExpr.Sel.Name)
+				}
+			}
+			if len(caseList) != 0 {
+				switchCaseList = append(switchCaseList, caseList)
+			}
+			return true
+		})
+	}
+	return switchCaseList
+}
+
+func TestQuestHandlerRegistry(t *testing.T) {
+	// 任务引擎只能通过注册的处理方法分发类型 不能再单独写switch分支
+	for _, funcName := range []string{"CheckQuestAcceptCond", "ExecQuest", "TriggerQuest"} {
+		switchCaseList := parseQuestSwitchCaseList(t, funcName)
+		if len(switchCaseList) != 0 {
+			t.Errorf("%v should dispatch quest type by handler map, switch case: %v", funcName, switchCaseList)
+		}
+	}
+	// 覆盖率报告与任务引擎对同一类型的判断必须一致
+	finishCondTypeList := []int32{
+		constant.QUEST_FINISH_COND_TYPE_FINISH_PLOT,
+		constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY,
+		constant.QUEST_FINISH_COND_TYPE_ADD_QUEST_PROGRESS,
+	}
+	questDataList := make([]*gdconf.QuestData, 0)
+	for index, condType := range finishCondTypeList {
+		questDataList = append(questDataList, newTestQuestData(int32(50001+index), int32(500+index), 1, nil,
+			[]*gdconf.QuestCond{{Type: condType, Param: []int32{1}, ComplexParam: "1", Count: 2}}, nil))
+	}
+	setTestQuestConfig(t, questDataList...)
+	report := BuildQuestCoverageReport()
+	supportMap := make(map[int32]bool)
+	for _, coverage := range report.TypeList {
+		if coverage.Kind == QuestCoverageKindFinishCond {
+			supportMap[coverage.Type] = coverage.Support
+		}
+	}
+	g := &Game{endlessLoopCounter: make(map[int]uint64)}
+	player := &model.Player{PlayerId: 10001, PropMap: make(map[uint32]uint32)}
+	oldUserManager := USER_MANAGER
+	USER_MANAGER = &UserManager{playerMap: map[uint32]*model.Player{player.PlayerId: player}}
+	t.Cleanup(func() {
+		USER_MANAGER = oldUserManager
+	})
+	dbQuest := player.GetDbQuest()
+	for index := range finishCondTypeList {
+		dbQuest.AddQuest(uint32(50001 + index))
+		dbQuest.StartQuest(uint32(50001 + index))
+	}
+	for index, condType := range finishCondTypeList {
+		questId := uint32(50001 + index)
+		g.TriggerQuest(player, condType, "1", 1)
+		engineSupport := dbQuest.GetQuestById(questId).FinishCountList[0] != 0
+		if engineSupport != supportMap[condType] {
+			t.Errorf("quest finish cond type %v diverge, engine: %v, report: %v", condType, engineSupport, supportMap[condType])
+		}
+	}
+	if !supportMap[constant.QUEST_FINISH_COND_TYPE_FINISH_PLOT] || supportMap[constant.QUEST_FINISH_COND_TYPE_ADD_QUEST_PROGRESS] {
+		t.Errorf("quest finish cond support error: %v", supportMap)
+	}
+}
+
+func newTestQuestData(questId, parentQuestId, sequence int32, acceptCondList, finishCondList []*gdconf.QuestCond, execList []*gdconf.QuestExec) *gdconf.QuestData {
+	return &gdconf.QuestData{
+		QuestId:        questId,
+		ParentQuestId:  parentQuestId,
+		Sequence:       sequence,
+		AcceptCondList: acceptCondList,
+		FinishCondList: finishCondList,
+		FailCondList:   make([]*gdconf.QuestCond, 0),
+		ExecList:       execList,
+		FailExecList:   make([]*gdconf.QuestExec, 0),
+		StartExecList:  make([]*gdconf.QuestExec, 0),
+	}
+}
+
+func setTestQuestConfig(t *testing.T, questDataList ...*gdconf.QuestData) {
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		QuestDataMap:     make(map[int32]*gdconf.QuestData),
+		ParentQuestMap:   make(map[int32]map[int32]*gdconf.QuestData),
+		MainQuestDataMap: map[int32]*gdconf.MainQuestData{100: {ParentQuestId: 100}},
+	})
+	for _, questData := range questDataList {
+		gdconf.CONF.QuestDataMap[questData.QuestId] = questData
+		questMap, exist := gdconf.CONF.ParentQuestMap[questData.ParentQuestId]
+		if !exist {
+			questMap = make(map[int32]*gdconf.QuestData)
+			gdconf.CONF.ParentQuestMap[questData.ParentQuestId] = questMap
+		}
+		questMap[questData.QuestId] = questData
+	}
+}
+
+func TestQuestCoverageReport(t *testing.T) {
+	stateEqual := func(questId int32) []*gdconf.QuestCond {
+		return []*gdconf.QuestCond{{Type: constant.QUEST_ACCEPT_COND_TYPE_STATE_EQUAL, Param: []int32{questId, constant.QUEST_STATE_FINISHED}}}
+	}
+	finishCond := func(condType int32) []*gdconf.QuestCond {
+		return []*gdconf.QuestCond{{Type: condType, Param: []int32{1}, Count: 2}}
+	}
+	setTestQuestConfig(t,
+		// 主线任务链 第三步完成条件未实现
+		newTestQuestData(10001, 100, 1, nil, finishCond(constant.QUEST_FINISH_COND_TYPE_FINISH_PLOT),
+			[]*gdconf.QuestExec{{Type: constant.QUEST_EXEC_TYPE_DEL_PACK_ITEM}}),
+		newTestQuestData(10002, 100, 2, stateEqual(10001), finishCond(constant.QUEST_FINISH_COND_TYPE_COMPLETE_TALK), nil),
+		newTestQuestData(10003, 100, 3, stateEqual(10002), finishCond(constant.QUEST_FINISH_COND_TYPE_ADD_QUEST_PROGRESS), nil),
+		newTestQuestData(10004, 100, 4, stateEqual(10003), nil, nil),
+		// 完整可完成的任务链 依赖其他任务链
+		newTestQuestData(20001, 200, 1, stateEqual(10002), finishCond(constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY), nil),
+		// 领取条件未实现
+		newTestQuestData(30001, 300, 1, []*gdconf.QuestCond{{Type: constant.QUEST_ACCEPT_COND_TYPE_PACK_HAVE_ITEM, Param: []int32{101}}}, nil, nil),
+		// 领取条件已实现但无法满足
+		newTestQuestData(40001, 400, 1, stateEqual(99999), nil, nil),
+	)
+
+	report := BuildQuestCoverageReport()
+	if report.QuestCount != 7 || len(report.ChainList) != 4 {
+		t.Fatalf("report count error, quest: %v, chain: %v", report.QuestCount, len(report.ChainList))
+	}
+	expectList := []struct {
+		parentQuestId int32
+		finishCount   int
+		blockQuestId  int32
+		blockReason   string
+	}{
+		{100, 2, 10003, QuestBlockReasonFinishCondNotSupport},
+		{200, 1, 0, ""},
+		{300, 0, 30001, QuestBlockReasonAcceptCondNotSupport},
+		{400, 0, 40001, QuestBlockReasonAcceptCondNotMatch},
+	}
+	for index, expect := range expectList {
+		chain := report.ChainList[index]
+		if chain.ParentQuestId != expect.parentQuestId || chain.FinishCount != expect.finishCount ||
+			chain.BlockQuestId != expect.blockQuestId || chain.BlockReason != expect.blockReason {
+			t.Errorf("chain result error, expect: %+v, chain: %+v", expect, chain)
+		}
+	}
+	if !report.ChainList[0].MainQuest || report.ChainList[1].MainQuest {
+		t.Errorf("main quest flag error")
+	}
+
+	buf := new(bytes.Buffer)
+	err := report.WriteText(buf, false)
+	if err != nil {
+		t.Fatalf("write report error: %v", err)
+	}
+	text := buf.String()
+	for _, line := range []string{
+		"chain: 4 finish: 1\n",
+		"main chain: 1 finish: 0\n",
+		"accept_cond 3 not_support quest: 1\n",
+		"finish_cond 4 support quest: 1\n",
+		"exec 1 not_support quest: 1\n",
+		"100 main block 2/4 quest: 10003 reason: finish_cond_not_support type: [24] not_support_exec: [1]\n",
+		"200 finish 1/1\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("report text missing line: %q\n%v", line, text)
+		}
+	}
+	// 报告内容稳定 可以在提交间diff
+	buf2 := new(bytes.Buffer)
+	_ = BuildQuestCoverageReport().WriteText(buf2, false)
+	if buf2.String() != text {
+		t.Errorf("report text not stable")
+	}
+}
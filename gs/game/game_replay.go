@@ -0,0 +1,45 @@
+package game
+
+import (
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/pkg/clock"
+	"hk4e/pkg/random"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 确定性回放
+// 开启回放模式后游戏逻辑使用的时间由抓包回放工具按抓包时间线推进 回放开始时重置随机种子
+// 同一份抓包以相同的种子多次回放 GS内依赖时间和随机数的逻辑结果一致
+// 服务器tick与存档等基础设施仍然按真实时间运行 只用于测试环境
+
+// 开启回放模式 回放工具同步时间之前时钟停在启动时间
+func (g *Game) initReplayMode() {
+	if !config.GetConfig().Hk4e.ReplayModeEnable {
+		return
+	}
+	g.replayClock = clock.NewManualClock(time.Now())
+	clock.SetClock(g.replayClock)
+	logger.Warn("replay mode enable, game time is driven by replay tool")
+}
+
+// ServerReplayClockNotify 回放工具同步时间 回放开始时重置时间与随机种子
+func (g *Game) ServerReplayClockNotify(replayClock *mq.ReplayClockInfo) {
+	if g.replayClock == nil {
+		logger.Error("replay mode not enable, ignore replay clock notify")
+		return
+	}
+	now := time.UnixMilli(replayClock.Time)
+	if replayClock.IsStart {
+		g.replayClock.Reset(now)
+		random.SetSeed(replayClock.Seed)
+		logger.Warn("replay start, time: %v, seed: %v", now.Format(time.DateTime), replayClock.Seed)
+		return
+	}
+	if !g.replayClock.Set(now) {
+		logger.Error("replay clock go back, ignore, time: %v, current: %v", now, g.replayClock.Now())
+	}
+}
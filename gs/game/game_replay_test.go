@@ -0,0 +1,53 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/pkg/clock"
+	"hk4e/pkg/random"
+)
+
+func TestServerReplayClockNotify(t *testing.T) {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{ReplayModeEnable: true}}
+	t.Cleanup(func() {
+		config.CONF = nil
+		clock.SetClock(nil)
+	})
+	g := new(Game)
+	g.initReplayMode()
+	startTime := int64(1700000000000)
+	replay := func() []int32 {
+		g.ServerReplayClockNotify(&mq.ReplayClockInfo{Time: startTime, IsStart: true, Seed: 7})
+		return []int32{random.GetRandomInt32(0, 10000), random.GetRandomInt32(0, 10000)}
+	}
+	first := replay()
+	g.ServerReplayClockNotify(&mq.ReplayClockInfo{Time: startTime + 5000})
+	if clock.Now().UnixMilli() != startTime+5000 {
+		t.Fatalf("replay clock not advance, now: %v", clock.Now().UnixMilli())
+	}
+	g.ServerReplayClockNotify(&mq.ReplayClockInfo{Time: startTime + 1000})
+	if clock.Now().UnixMilli() != startTime+5000 {
+		t.Fatalf("replay clock go back, now: %v", clock.Now().UnixMilli())
+	}
+	// 再次回放时时间与随机序列重置
+	second := replay()
+	if clock.Now().UnixMilli() != startTime || first[0] != second[0] || first[1] != second[1] {
+		t.Fatalf("replay not repeat, now: %v, first: %v, second: %v", clock.Now().UnixMilli(), first, second)
+	}
+}
+
+func TestServerReplayClockNotifyDisable(t *testing.T) {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{ReplayModeEnable: false}}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	g := new(Game)
+	g.initReplayMode()
+	g.ServerReplayClockNotify(&mq.ReplayClockInfo{Time: 1000, IsStart: true, Seed: 7})
+	if time.Since(clock.Now()) > time.Minute {
+		t.Fatalf("replay clock notify should be ignore when replay mode disable, now: %v", clock.Now())
+	}
+}
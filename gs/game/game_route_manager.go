@@ -1,6 +1,8 @@
 package game
 
 import (
+	"time"
+
 	"hk4e/common/mq"
 	"hk4e/gs/model"
 	"hk4e/node/api"
@@ -47,6 +49,7 @@ func (r *RouteManager) initRoute() {
 		cmd.EntityAiSyncNotify:                GAME.EntityAiSyncNotify,
 		cmd.WearEquipReq:                      GAME.WearEquipReq,
 		cmd.ChangeGameTimeReq:                 GAME.ChangeGameTimeReq,
+		cmd.AdjustWorldLevelReq:               GAME.AdjustWorldLevelReq,
 		cmd.GetPlayerSocialDetailReq:          GAME.GetPlayerSocialDetailReq,
 		cmd.SetPlayerBirthdayReq:              GAME.SetPlayerBirthdayReq,
 		cmd.SetNameCardReq:                    GAME.SetNameCardReq,
@@ -150,6 +153,26 @@ func (r *RouteManager) initRoute() {
 		cmd.SceneAudioNotify:                  GAME.SceneAudioNotify,
 		cmd.WidgetDoBagReq:                    GAME.WidgetDoBagReq,
 		cmd.PersonalSceneJumpReq:              GAME.PersonalSceneJumpReq,
+		cmd.SignInInfoReq:                     GAME.SignInInfoReq,
+		cmd.GetSignInRewardReq:                GAME.GetSignInRewardReq,
+		cmd.QueryCodexMonsterBeKilledNumReq:   GAME.QueryCodexMonsterBeKilledNumReq,
+		cmd.ViewCodexReq:                      GAME.ViewCodexReq,
+		cmd.AvatarFetterLevelRewardReq:        GAME.AvatarFetterLevelRewardReq,
+		cmd.GetActivityScheduleReq:            GAME.GetActivityScheduleReq,
+		cmd.GetActivityInfoReq:                GAME.GetActivityInfoReq,
+		cmd.ActivityTakeWatcherRewardReq:      GAME.ActivityTakeWatcherRewardReq,
+		cmd.ActivityTakeWatcherRewardBatchReq: GAME.ActivityTakeWatcherRewardBatchReq,
+		cmd.SalesmanDeliverItemReq:            GAME.SalesmanDeliverItemReq,
+		cmd.SalesmanTakeSpecialRewardReq:      GAME.SalesmanTakeSpecialRewardReq,
+		cmd.RechargeReq:                       GAME.RechargeReq,
+		cmd.ReunionBriefInfoReq:               GAME.ReunionBriefInfoReq,
+		cmd.TakeReunionFirstGiftRewardReq:     GAME.TakeReunionFirstGiftRewardReq,
+		cmd.GetReunionSignInInfoReq:           GAME.GetReunionSignInInfoReq,
+		cmd.TakeReunionSignInRewardReq:        GAME.TakeReunionSignInRewardReq,
+		cmd.GetReunionMissionInfoReq:          GAME.GetReunionMissionInfoReq,
+		cmd.TakeReunionWatcherRewardReq:       GAME.TakeReunionWatcherRewardReq,
+		cmd.TakeReunionMissionRewardReq:       GAME.TakeReunionMissionRewardReq,
+		cmd.GetReunionPrivilegeInfoReq:        GAME.GetReunionPrivilegeInfoReq,
 	}
 }
 
@@ -187,11 +210,21 @@ func (r *RouteManager) doRoute(cmdId uint16, userId uint32, clientSeq uint32, pa
 	}
 	player.ClientSeq = clientSeq
 	SELF = player
+	start := time.Now()
 	handlerFunc(player, payloadMsg)
+	metricsRouteCost.Observe(time.Since(start).Seconds(), getCmdNameCache(cmdId))
 	SELF = nil
 }
 
 func (r *RouteManager) RouteHandle(netMsg *mq.NetMsg) {
+	span := startRouteSpan(netMsg)
+	if span != nil {
+		CUR_SPAN.Store(span)
+		defer func() {
+			CUR_SPAN.Store(nil)
+			span.End()
+		}()
+	}
 	switch netMsg.MsgType {
 	case mq.MsgTypeGame:
 		if netMsg.OriginServerType != api.GATE {
@@ -248,6 +281,10 @@ func (r *RouteManager) RouteHandle(netMsg *mq.NetMsg) {
 				ParamList:  serverMsg.GmCmdParamList,
 				ResultChan: nil,
 			}
+		case mq.ServerRechargeOrderNotify:
+			GAME.ServerRechargeOrderNotify(serverMsg.UserId, serverMsg.RechargeOrder)
+		case mq.ServerReplayClockNotify:
+			GAME.ServerReplayClockNotify(serverMsg.ReplayClock)
 		default:
 		}
 	}
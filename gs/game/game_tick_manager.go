@@ -6,6 +6,7 @@ import (
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -44,8 +45,8 @@ func NewTickManager() (r *TickManager) {
 	r.globalTick = time.NewTicker(time.Millisecond * ServerTickTime)
 	r.globalTickCount = 0
 	r.userTickMap = make(map[uint32]*UserTick)
-	r.tm = time.Now()
-	logger.Info("game server tick start at: %v", time.Now().UnixMilli())
+	r.tm = clock.Now()
+	logger.Info("game server tick start at: %v", clock.Now().UnixMilli())
 	return r
 }
 
@@ -84,14 +85,14 @@ func (t *TickManager) CreateUserTimer(userId uint32, action int, delay uint32, d
 		return
 	}
 	userTick.timerIdCounter++
-	timeout := time.Now().UnixMilli() + int64(delay)*1000
+	timeout := clock.Now().UnixMilli() + int64(delay)*1000
 	userTick.timerMap[userTick.timerIdCounter] = &UserTimer{
 		timeout: timeout,
 		action:  action,
 		data:    data,
 	}
 	logger.Debug("create user timer, uid: %v, action: %v, time: %v",
-		userId, action, time.Now().Add(time.Second*time.Duration(delay)).Format("2006-01-02 15:04:05"))
+		userId, action, clock.Now().Add(time.Second*time.Duration(delay)).Format("2006-01-02 15:04:05"))
 }
 
 func (t *TickManager) onUserTickSecond(userId uint32, now int64) {
@@ -106,6 +107,12 @@ func (t *TickManager) onUserTickMinute(userId uint32, now int64) {
 	if userId < PlayerBaseUid {
 		return
 	}
+	if player.SceneLoadState == model.SceneEnterDone {
+		// 在场景中的玩家当前队伍中的角色增加好感度经验
+		GAME.AddPlayerTeamFetterExp(player, FetterExpTeamPerMinute)
+	}
+	// 活动开启结束检查
+	GAME.ActivityTick(player, time.UnixMilli(now))
 	if uint32(now/1000)-player.LastKeepaliveTime > 60 {
 		logger.Error("remove keepalive timeout user, uid: %v", userId)
 		GAME.OnOffline(userId, &ChangeGsInfo{
@@ -162,7 +169,7 @@ func (t *TickManager) userTimerHandle(userId uint32, action int, data []any) {
 
 func (t *TickManager) OnGameServerTick() {
 	t.globalTickCount++
-	tm := time.Now()
+	tm := clock.Now()
 	now := tm.UnixMilli()
 	if t.globalTickCount%(50/ServerTickTime) == 0 {
 		t.onTick50MilliSecond(now)
@@ -232,10 +239,19 @@ func (t *TickManager) OnGameServerTick() {
 
 func (t *TickManager) onMonthChange(now int64) {
 	logger.Info("on month change, time: %v", now)
+	// 重置在线玩家的签到周期
+	for _, player := range USER_MANAGER.GetAllOnlineUserList() {
+		GAME.CheckPlayerSignInReset(player, time.UnixMilli(now))
+	}
 }
 
 func (t *TickManager) onDayChange(now int64) {
 	logger.Info("on day change, time: %v", now)
+	for _, player := range USER_MANAGER.GetAllOnlineUserList() {
+		// 跨天在线也算作当天登录
+		player.GetDbLogin().LoginDay(time.UnixMilli(now))
+		GAME.ReunionDayChange(player, time.UnixMilli(now))
+	}
 }
 
 func (t *TickManager) onHourChange(now int64) {
@@ -251,16 +267,6 @@ func (t *TickManager) onTickHour(now int64) {
 
 func (t *TickManager) onTickMinute(now int64) {
 	gdconf.LuaStateLruRemove()
-	for _, world := range WORLD_MANAGER.GetAllWorld() {
-		if world.GetOwner().SceneLoadState == model.SceneEnterDone {
-			// 天气气象随机
-			for _, scene := range world.GetAllScene() {
-				for _, scenePlayer := range scene.GetAllPlayer() {
-					GAME.WeatherClimateRandom(scenePlayer, scenePlayer.WeatherInfo.WeatherAreaId)
-				}
-			}
-		}
-	}
 }
 
 func (t *TickManager) onTick10Second(now int64) {
@@ -268,6 +274,12 @@ func (t *TickManager) onTick10Second(now int64) {
 		if world.GetOwner().SceneLoadState == model.SceneEnterDone {
 			GAME.SceneTimeNotify(world)
 			GAME.PlayerTimeNotify(world)
+			// 同步世界的游戏时间
+			GAME.PlayerGameTimeNotify(world)
+			// 天气气象随机
+			GAME.SceneWeatherRefresh(world, now, false)
+			// 地脉之花与世界boss刷新
+			GAME.WorldEventTick(world, now)
 		}
 	}
 }
@@ -308,8 +320,8 @@ func (t *TickManager) onTickSecond(now int64) {
 			if world.IsMultiplayerWorld() {
 				GAME.WorldPlayerRTTNotify(world)
 			}
-			// 场景时间增加
-			if !world.GetOwner().Pause && world.GetOwner().PropMap[constant.PLAYER_PROP_IS_GAME_TIME_LOCKED] != 1 {
+			// 场景时间增加 多人世界不受房主暂停影响
+			if !world.IsGameTimePause() {
 				world.ChangeGameTime(world.GetGameTime() + 1)
 				GAME.TriggerQuest(world.GetOwner(), constant.QUEST_FINISH_COND_TYPE_GAME_TIME_TICK, "")
 			}
@@ -319,6 +331,7 @@ func (t *TickManager) onTickSecond(now int64) {
 	for _, game := range GCG_MANAGER.gameMap {
 		game.onTick()
 	}
+	updateGameMetrics()
 }
 
 func (t *TickManager) onTick200MilliSecond(now int64) {
@@ -330,6 +343,8 @@ func (t *TickManager) onTick200MilliSecond(now int64) {
 				GAME.VehicleRestoreStaminaHandler(player)
 			}
 		}
+		// 服务端怪物ai
+		GAME.MonsterAiTick(world)
 	}
 }
 
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"hk4e/common/mq"
+	"hk4e/pkg/trace"
+)
+
+// 链路追踪
+
+// CUR_SPAN 主协程当前正在处理的消息的链路 处理期间发出的消息会携带该链路上下文
+var CUR_SPAN atomic.Pointer[trace.Span]
+
+func getCurTraceContext() trace.SpanContext {
+	return CUR_SPAN.Load().Context()
+}
+
+// startRouteSpan 上游携带链路上下文或按玩家uid采样时开始处理消息的链路 否则返回nil
+func startRouteSpan(netMsg *mq.NetMsg) *trace.Span {
+	parent := netMsg.GetTrace()
+	switch netMsg.MsgType {
+	case mq.MsgTypeGame:
+		gameMsg := netMsg.GameMsg
+		if gameMsg == nil {
+			return nil
+		}
+		if !parent.IsValid() && !trace.ShouldSample(gameMsg.UserId) {
+			return nil
+		}
+		span := trace.StartSpan("gs.route."+getCmdNameCache(gameMsg.CmdId), trace.SpanKindServer, parent)
+		span.SetAttr("uid", gameMsg.UserId)
+		span.SetAttr("cmd_id", gameMsg.CmdId)
+		span.SetAttr("client_seq", gameMsg.ClientSeq)
+		span.SetAttr("origin_appid", netMsg.OriginServerAppId)
+		return span
+	case mq.MsgTypeServer:
+		if !parent.IsValid() || netMsg.ServerMsg == nil {
+			return nil
+		}
+		span := trace.StartSpan("gs.server_msg."+strconv.Itoa(int(netMsg.EventId)), trace.SpanKindConsumer, parent)
+		span.SetAttr("uid", netMsg.ServerMsg.UserId)
+		span.SetAttr("origin_server_type", netMsg.OriginServerType)
+		span.SetAttr("origin_appid", netMsg.OriginServerAppId)
+		return span
+	default:
+		return nil
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"hk4e/common/mq"
 	"hk4e/gs/dao"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/proto"
 
 	"github.com/flswld/halo/logger"
@@ -177,10 +178,20 @@ func (u *UserManager) UserLoginLoad(userId uint32, clientSeq uint32, gateAppId s
 	}()
 }
 
+// GetOnlinePlayerUidList 获取当前在线玩家uid列表 可以在其他协程调用
+func GetOnlinePlayerUidList() []uint32 {
+	uidList := make([]uint32, 0)
+	ONLINE_PLAYER_UID_MAP.Range(func(uid, value any) bool {
+		uidList = append(uidList, uid.(uint32))
+		return true
+	})
+	return uidList
+}
+
 // OnlineUser 玩家上线
 func (u *UserManager) OnlineUser(player *model.Player) {
 	player.Online = true
-	player.OnlineTime = uint32(time.Now().Unix())
+	player.OnlineTime = uint32(clock.Now().Unix())
 	u.AddUser(player)
 	GAME.messageQueue.SendToAll(&mq.NetMsg{
 		MsgType: mq.MsgTypeServer,
@@ -191,6 +202,7 @@ func (u *UserManager) OnlineUser(player *model.Player) {
 		},
 	})
 	atomic.AddInt32(&ONLINE_PLAYER_NUM, 1)
+	ONLINE_PLAYER_UID_MAP.Store(player.PlayerId, true)
 }
 
 type ChangeGsInfo struct {
@@ -206,8 +218,8 @@ type PlayerOfflineInfo struct {
 // UserOfflineSave 玩家离线数据库保存
 func (u *UserManager) UserOfflineSave(player *model.Player, changeGsInfo *ChangeGsInfo) {
 	player.Online = false
-	player.OfflineTime = uint32(time.Now().Unix())
-	player.TotalOnlineTime += uint32(time.Now().Unix()) - player.OnlineTime
+	player.OfflineTime = uint32(clock.Now().Unix())
+	player.TotalOnlineTime += uint32(clock.Now().Unix()) - player.OnlineTime
 	if player.NotSave {
 		LOCAL_EVENT_MANAGER.GetLocalEventChan() <- &LocalEvent{
 			EventId: UserOfflineSaveToDbFinish,
@@ -291,6 +303,7 @@ func (u *UserManager) OfflineUser(player *model.Player, changeGsInfo *ChangeGsIn
 		},
 	})
 	atomic.AddInt32(&ONLINE_PLAYER_NUM, -1)
+	ONLINE_PLAYER_UID_MAP.Delete(player.PlayerId)
 	if changeGsInfo.IsChangeGs {
 		gsAppId := u.GetRemoteUserGsAppId(changeGsInfo.JoinHostUserId)
 		GAME.messageQueue.SendToGate(player.GateAppId, &mq.NetMsg{
@@ -0,0 +1,455 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 世界事件模块 地脉之花与世界boss的刷新调度
+
+const (
+	BlossomScheduleStateNone   = 0 // 未开始
+	BlossomScheduleStateReady  = 1 // 可挑战
+	BlossomScheduleStateBattle = 2 // 挑战中
+	BlossomScheduleStateChest  = 3 // 奖励宝箱已创建
+)
+
+const (
+	WorldBossRespawnIntervalDefault = 300 // 默认世界boss复活间隔 单位秒
+)
+
+// GetWorldBossRespawnInterval 获取世界boss复活间隔 毫秒
+func GetWorldBossRespawnInterval() int64 {
+	interval := config.GetConfig().Hk4e.WorldBossRespawnInterval
+	if interval <= 0 {
+		interval = WorldBossRespawnIntervalDefault
+	}
+	return int64(interval) * 1000
+}
+
+// GetBlossomNextRefreshTime 获取地脉之花下次每日刷新的时间 毫秒
+func GetBlossomNextRefreshTime(refreshSecond int32, now int64) int64 {
+	nowTime := time.UnixMilli(now)
+	refreshTime := time.Date(nowTime.Year(), nowTime.Month(), nowTime.Day(), 0, 0, int(refreshSecond), 0, nowTime.Location())
+	if !refreshTime.After(nowTime) {
+		refreshTime = refreshTime.AddDate(0, 0, 1)
+	}
+	return refreshTime.UnixMilli()
+}
+
+// CheckBlossomRefreshCond 检查循环营地刷新条件 只处理有奖励宝箱的刷新类型
+func CheckBlossomRefreshCond(player *model.Player, refreshData *gdconf.BlossomRefreshData) bool {
+	if refreshData.ChestId == 0 || gdconf.GetBlossomChestDataById(refreshData.ChestId) == nil {
+		return false
+	}
+	playerLevel := int32(player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL])
+	openData := gdconf.GetBlossomOpenDataByCityId(refreshData.CityId)
+	if openData == nil || playerLevel < openData.OpenPlayerLevel {
+		return false
+	}
+	for _, cond := range refreshData.CondList {
+		switch cond.Type {
+		case gdconf.BlossomRefreshCondPlayerLevel:
+			if len(cond.Param) < 1 || playerLevel < cond.Param[0] {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SelectBlossomCamp 选择地脉之花出现的营地点 优先下游点 否则按区域顺序选择下一个 跳过其他地脉之花已占用的营地点
+func SelectBlossomCamp(player *model.Player, refreshData *gdconf.BlossomRefreshData, currCampId uint32) *gdconf.BlossomGroupsData {
+	playerLevel := int32(player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL])
+	dbWorld := player.GetDbWorld()
+	campList := make([]*gdconf.BlossomGroupsData, 0)
+	for _, campData := range gdconf.GetBlossomCampList(refreshData.CityId, refreshData.RefreshType) {
+		if campData.UnlockPlayerLevel > playerLevel {
+			continue
+		}
+		occupy := false
+		for _, blossom := range dbWorld.BlossomMap {
+			if blossom.RefreshId != uint32(refreshData.RefreshId) && blossom.CampId == uint32(campData.CampId) {
+				occupy = true
+				break
+			}
+		}
+		if occupy {
+			continue
+		}
+		campList = append(campList, campData)
+	}
+	if len(campList) == 0 {
+		return nil
+	}
+	if currCampId == 0 {
+		for _, campData := range campList {
+			if campData.IsInitialRefresh == 1 {
+				return campData
+			}
+		}
+		return campList[0]
+	}
+	currCampData := gdconf.GetBlossomGroupsDataById(int32(currCampId))
+	if currCampData != nil {
+		for _, nextCampId := range currCampData.NextCampIdList {
+			for _, campData := range campList {
+				if campData.CampId == nextCampId {
+					return campData
+				}
+			}
+		}
+	}
+	for index, campData := range campList {
+		if campData.CampId == int32(currCampId) {
+			return campList[(index+1)%len(campList)]
+		}
+	}
+	return campList[0]
+}
+
+// WorldEventTick 世界事件定时调度
+func (g *Game) WorldEventTick(world *World, now int64) {
+	if WORLD_MANAGER.IsAiWorld(world) {
+		return
+	}
+	g.WorldBlossomRefresh(world, now)
+	g.WorldBossRespawn(world, now)
+}
+
+// WorldBlossomRefresh 地脉之花刷新 领奖后到达每日刷新时间则转移到新的营地点
+func (g *Game) WorldBlossomRefresh(world *World, now int64) {
+	owner := world.GetOwner()
+	dbWorld := owner.GetDbWorld()
+	refreshIdList := make([]int, 0)
+	for refreshId := range gdconf.GetBlossomRefreshDataMap() {
+		refreshIdList = append(refreshIdList, int(refreshId))
+	}
+	sort.Ints(refreshIdList)
+	for _, refreshId := range refreshIdList {
+		refreshData := gdconf.GetBlossomRefreshDataById(int32(refreshId))
+		if !CheckBlossomRefreshCond(owner, refreshData) {
+			continue
+		}
+		blossom, exist := dbWorld.BlossomMap[uint32(refreshId)]
+		if exist && (blossom.NextRefreshTime == 0 || now < blossom.NextRefreshTime) {
+			continue
+		}
+		currCampId := uint32(0)
+		if exist {
+			currCampId = blossom.CampId
+		}
+		campData := SelectBlossomCamp(owner, refreshData, currCampId)
+		if campData == nil {
+			continue
+		}
+		if !exist {
+			blossom = &model.Blossom{RefreshId: uint32(refreshId)}
+			dbWorld.BlossomMap[uint32(refreshId)] = blossom
+		}
+		oldCampData := gdconf.GetBlossomGroupsDataById(int32(blossom.CampId))
+		blossom.CampId = uint32(campData.CampId)
+		blossom.State = BlossomScheduleStateNone
+		blossom.Progress = 0
+		blossom.ChestConfigId = 0
+		blossom.NextRefreshTime = 0
+		logger.Debug("blossom refresh, refreshId: %v, campId: %v, uid: %v", refreshId, campData.CampId, owner.PlayerId)
+		// 旧营地点的场景组已加载则还原为初始小组
+		if oldCampData != nil && oldCampData.CampId != campData.CampId {
+			scene, player := g.GetWorldGroupScenePlayer(world, uint32(oldCampData.RefreshGroupId))
+			groupConfig := gdconf.GetSceneGroup(oldCampData.RefreshGroupId)
+			if scene != nil && groupConfig != nil {
+				g.RefreshBlossomGroup(player, uint32(oldCampData.RefreshGroupId), uint8(groupConfig.GroupInitConfig.Suite), true)
+			}
+		}
+		// 新营地点的场景组已加载则按场景组加载流程重新刷新
+		scene, player := g.GetWorldGroupScenePlayer(world, uint32(campData.RefreshGroupId))
+		if scene != nil {
+			g.GroupLoadTriggerCheck(player, scene.GetGroupById(uint32(campData.RefreshGroupId)))
+		}
+	}
+}
+
+// GetWorldGroupScenePlayer 获取加载了某场景组的场景 以及用于执行触发器的场景内玩家 优先房主
+func (g *Game) GetWorldGroupScenePlayer(world *World, groupId uint32) (*Scene, *model.Player) {
+	owner := world.GetOwner()
+	for _, scene := range world.GetAllScene() {
+		if scene.GetGroupById(groupId) == nil {
+			continue
+		}
+		playerMap := scene.GetAllPlayer()
+		if len(playerMap) == 0 {
+			continue
+		}
+		if player, exist := playerMap[owner.PlayerId]; exist {
+			return scene, player
+		}
+		for _, player := range playerMap {
+			return scene, player
+		}
+	}
+	return nil, nil
+}
+
+// GetWorldBlossomByGroupId 获取场景组对应营地点上当前可挑战的地脉之花
+func (g *Game) GetWorldBlossomByGroupId(world *World, groupId uint32) (*model.Blossom, *gdconf.BlossomRefreshData, *gdconf.BlossomGroupsData) {
+	campData := gdconf.GetBlossomGroupsDataByGroupId(int32(groupId))
+	if campData == nil {
+		return nil, nil, nil
+	}
+	for _, blossom := range world.GetOwner().GetDbWorld().BlossomMap {
+		if blossom.CampId != uint32(campData.CampId) || blossom.NextRefreshTime != 0 {
+			continue
+		}
+		refreshData := gdconf.GetBlossomRefreshDataById(int32(blossom.RefreshId))
+		if refreshData == nil {
+			continue
+		}
+		return blossom, refreshData, campData
+	}
+	return nil, nil, nil
+}
+
+// RefreshBlossomGroup 刷新循环营地场景组 营地点上有地脉之花时通知场景组刷新
+func (g *Game) RefreshBlossomGroup(player *model.Player, groupId uint32, suiteId uint8, excludePrev bool) {
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	group := scene.GetGroupById(groupId)
+	if group != nil && excludePrev {
+		// 移除之前的全部小组
+		for id := range group.GetAllSuite() {
+			if id == suiteId {
+				continue
+			}
+			g.RemoveSceneGroupSuite(player, groupId, id)
+		}
+	}
+	g.RefreshSceneGroupSuite(player, groupId, suiteId)
+	blossom, _, _ := g.GetWorldBlossomByGroupId(world, groupId)
+	if blossom == nil {
+		return
+	}
+	group = scene.GetGroupById(groupId)
+	if group == nil {
+		return
+	}
+	if blossom.State == BlossomScheduleStateChest {
+		// 奖励宝箱还未领取 重新创建
+		g.CreateBlossomChest(player, groupId, blossom.ChestConfigId)
+		return
+	}
+	blossom.State = BlossomScheduleStateNone
+	blossom.Progress = 0
+	g.GroupRefreshTriggerCheck(player, group)
+}
+
+// AddBlossomProgress 增加地脉之花挑战进度 达到完成进度时通知场景组
+func (g *Game) AddBlossomProgress(player *model.Player, groupId uint32) {
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		return
+	}
+	blossom, _, campData := g.GetWorldBlossomByGroupId(world, groupId)
+	if blossom == nil || blossom.State != BlossomScheduleStateBattle {
+		return
+	}
+	blossom.Progress++
+	if blossom.Progress < uint32(campData.FinishProgress) {
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	group := scene.GetGroupById(groupId)
+	if group == nil {
+		return
+	}
+	g.BlossomProgressFinishTriggerCheck(player, group)
+}
+
+// CreateBlossomChest 创建地脉之花奖励宝箱 宝箱物件由刷新类型决定
+func (g *Game) CreateBlossomChest(player *model.Player, groupId uint32, configId uint32) {
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		return
+	}
+	blossom, refreshData, _ := g.GetWorldBlossomByGroupId(world, groupId)
+	if blossom == nil {
+		return
+	}
+	groupConfig := gdconf.GetSceneGroup(int32(groupId))
+	if groupConfig == nil {
+		logger.Error("get group config is nil, groupId: %v, uid: %v", groupId, player.PlayerId)
+		return
+	}
+	gadgetConfig, exist := groupConfig.GadgetMap[int32(configId)]
+	if !exist {
+		logger.Error("gadget config not exist, configId: %v, uid: %v", configId, player.PlayerId)
+		return
+	}
+	chestData := gdconf.GetBlossomChestDataById(refreshData.ChestId)
+	if chestData == nil {
+		logger.Error("get blossom chest data is nil, chestId: %v, uid: %v", refreshData.ChestId, player.PlayerId)
+		return
+	}
+	blossom.State = BlossomScheduleStateChest
+	blossom.ChestConfigId = configId
+	chestConfig := *gadgetConfig
+	if chestData.GadgetId != 0 {
+		chestConfig.GadgetId = chestData.GadgetId
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	entityId := g.CreateConfigEntity(scene, groupId, &chestConfig)
+	if entityId == 0 {
+		return
+	}
+	entity := scene.GetEntity(entityId)
+	scene.AddGroupSuite(groupId, uint8(groupConfig.GroupInitConfig.Suite), map[uint32]IEntity{entityId: entity})
+	g.AddSceneEntityNotify(player, proto.VisionType_VISION_BORN, []uint32{entityId}, true, false)
+	group := scene.GetGroupById(groupId)
+	if group == nil {
+		return
+	}
+	g.GadgetCreateTriggerCheck(player, group, entity)
+}
+
+// GetBlossomByChest 获取宝箱实体对应的地脉之花
+func (g *Game) GetBlossomByChest(world *World, entity IEntity) *model.Blossom {
+	blossom, _, _ := g.GetWorldBlossomByGroupId(world, entity.GetGroupId())
+	if blossom == nil || blossom.State != BlossomScheduleStateChest || blossom.ChestConfigId != entity.GetConfigId() {
+		return nil
+	}
+	return blossom
+}
+
+// GetBlossomRewardDropId 获取世界等级对应的奖励掉落id 超出配置的世界等级取最后一个 没有配置则返回0
+func GetBlossomRewardDropId(refreshData *gdconf.BlossomRefreshData, worldLevel uint32) int32 {
+	dropIdList := refreshData.RewardDropIdList
+	if len(dropIdList) == 0 {
+		return 0
+	}
+	if int(worldLevel) >= len(dropIdList) {
+		return dropIdList[len(dropIdList)-1]
+	}
+	return dropIdList[worldLevel]
+}
+
+// BlossomChestReward 领取地脉之花奖励 消耗树脂 按世界等级掉落
+// 地脉之花的位置与状态属于房主的世界 领奖冷却按领奖玩家记录 客机领奖不影响房主和其他玩家
+func (g *Game) BlossomChestReward(player *model.Player, scene *Scene, entity IEntity) proto.Retcode {
+	world := scene.GetWorld()
+	owner := world.GetOwner()
+	blossom, refreshData, _ := g.GetWorldBlossomByGroupId(world, entity.GetGroupId())
+	if blossom == nil || blossom.State != BlossomScheduleStateChest {
+		return proto.Retcode_RET_BLOSSOM_CHEST_HAS_TAKEN
+	}
+	now := clock.Now().UnixMilli()
+	dbWorld := player.GetDbWorld()
+	if now < dbWorld.BlossomRewardMap[blossom.RefreshId] {
+		return proto.Retcode_RET_BLOSSOM_CHEST_HAS_TAKEN
+	}
+	chestData := gdconf.GetBlossomChestDataById(refreshData.ChestId)
+	if chestData == nil {
+		return proto.Retcode_RET_BLOSSOM_CHEST_NO_QUALIFICATION
+	}
+	dropId := GetBlossomRewardDropId(refreshData, owner.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL])
+	dropDataConfig := gdconf.GetDropDataById(dropId)
+	if dropDataConfig == nil {
+		logger.Error("get drop data config is nil, dropId: %v, refreshId: %v, uid: %v", dropId, blossom.RefreshId, player.PlayerId)
+		return proto.Retcode_RET_BLOSSOM_CHEST_NO_QUALIFICATION
+	}
+	dropTimes := 1
+	if chestData.WorldResin != 0 && chestData.ResinCost != 0 {
+		ok := g.CostPlayerItem(player.PlayerId, []*ChangeItem{{ItemId: constant.ITEM_ID_RESIN, ChangeCount: uint32(chestData.ResinCost)}})
+		if !ok {
+			return proto.Retcode_RET_RESIN_NOT_ENOUGH
+		}
+		// 消耗树脂获得好感度经验
+		g.AddPlayerTeamFetterExp(player, uint32(chestData.ResinCost)/FetterExpResinCost*FetterExpResinReward)
+		// 回归特权 消耗树脂的奖励翻倍
+		if g.UseReunionPrivilege(player, clock.Now()) {
+			dropTimes = 2
+		}
+	}
+	itemList := make([]*ChangeItem, 0)
+	for itemId, count := range g.doRandDropFullTimes(dropDataConfig, dropTimes) {
+		itemList = append(itemList, &ChangeItem{ItemId: itemId, ChangeCount: count})
+	}
+	g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_OPEN_BLOSSOM_CHEST)
+	nextRefreshTime := GetBlossomNextRefreshTime(refreshData.RefreshSecond, now)
+	dbWorld.BlossomRewardMap[blossom.RefreshId] = nextRefreshTime
+	if player.PlayerId != owner.PlayerId {
+		// 客机领奖 宝箱保留给其他玩家
+		return proto.Retcode_RET_SUCC
+	}
+	// 房主领奖后进入冷却 到达每日刷新时间后转移营地点
+	blossom.State = BlossomScheduleStateNone
+	blossom.Progress = 0
+	blossom.ChestConfigId = 0
+	blossom.NextRefreshTime = nextRefreshTime
+	group := scene.GetGroupById(entity.GetGroupId())
+	configId := entity.GetConfigId()
+	g.KillEntity(player, scene, entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
+	if group != nil {
+		g.BlossomChestDieTriggerCheck(player, group, configId)
+	}
+	return proto.Retcode_RET_SUCC
+}
+
+// WorldBossKill 世界boss被击杀 记录复活时间
+func (g *Game) WorldBossKill(world *World, monsterEntity *MonsterEntity) {
+	worldBoss := gdconf.GetWorldBossByGroupId(int32(monsterEntity.GetGroupId()))
+	if worldBoss == nil || !worldBoss.IsWorldBossMonster(int32(monsterEntity.GetMonsterId())) {
+		return
+	}
+	respawnTime := clock.Now().UnixMilli() + GetWorldBossRespawnInterval()
+	world.GetOwner().GetDbWorld().WorldBossRespawnMap[monsterEntity.GetGroupId()] = respawnTime
+	logger.Debug("world boss kill, groupId: %v, respawnTime: %v, uid: %v", monsterEntity.GetGroupId(), respawnTime, world.GetOwner().PlayerId)
+}
+
+// WorldBossRespawn 世界boss到达复活时间 重置场景组存档并刷新已加载的场景组
+func (g *Game) WorldBossRespawn(world *World, now int64) {
+	owner := world.GetOwner()
+	dbWorld := owner.GetDbWorld()
+	for groupId, respawnTime := range dbWorld.WorldBossRespawnMap {
+		if now < respawnTime {
+			continue
+		}
+		delete(dbWorld.WorldBossRespawnMap, groupId)
+		groupConfig := gdconf.GetSceneGroup(int32(groupId))
+		if groupConfig == nil {
+			continue
+		}
+		sceneGroup := owner.GetSceneGroupById(groupId)
+		if sceneGroup == nil {
+			continue
+		}
+		sceneGroup.RemoveAllKill()
+		for _, variable := range groupConfig.VariableMap {
+			sceneGroup.SetVariable(variable.Name, variable.Value)
+		}
+		logger.Debug("world boss respawn, groupId: %v, uid: %v", groupId, owner.PlayerId)
+		scene, player := g.GetWorldGroupScenePlayer(world, groupId)
+		if scene == nil {
+			continue
+		}
+		g.RefreshSceneGroupSuite(player, groupId, uint8(groupConfig.GroupInitConfig.Suite))
+		group := scene.GetGroupById(groupId)
+		if group == nil {
+			continue
+		}
+		g.GroupRefreshTriggerCheck(player, group)
+	}
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+const (
+	testBlossomRefreshId = 1
+	testBlossomCityId    = 1
+	testWorldBossGroupId = 133102769
+	testWorldBossBlockId = 1021
+)
+
+func newTestWorldEventScene(t *testing.T) (*testAbilityScene, *Game) {
+	s := newTestAbilityScene(t)
+	config.CONF = &config.Config{Hk4e: config.Hk4e{WorldBossRespawnInterval: 60}}
+	gdconf.CONF.BlossomOpenDataMap = map[int32]*gdconf.BlossomOpenData{
+		testBlossomCityId: {CityId: testBlossomCityId, OpenPlayerLevel: 10},
+	}
+	gdconf.CONF.BlossomChestDataMap = map[int32]*gdconf.BlossomChestData{
+		1: {ChestId: 1, GadgetId: 70210109, WorldResin: 1, ResinCost: 20, RefreshType: 1},
+	}
+	gdconf.CONF.BlossomRefreshDataMap = map[int32]*gdconf.BlossomRefreshData{
+		testBlossomRefreshId: {
+			RefreshId:     testBlossomRefreshId,
+			CityId:        testBlossomCityId,
+			RefreshType:   1,
+			ChestId:       1,
+			RefreshSecond: 4 * 3600,
+			CondList:      []*gdconf.BlossomRefreshCond{{Type: gdconf.BlossomRefreshCondPlayerLevel, Param: []int32{15}}},
+		},
+	}
+	gdconf.CONF.BlossomGroupsDataMap = map[int32]*gdconf.BlossomGroupsData{
+		101: {CampId: 101, CityId: testBlossomCityId, RefreshTypeList: []int32{1}, RefreshGroupId: 10101, NextCampIdList: []int32{103}, IsInitialRefresh: 1},
+		102: {CampId: 102, CityId: testBlossomCityId, RefreshTypeList: []int32{1, 3}, RefreshGroupId: 10102},
+		103: {CampId: 103, CityId: testBlossomCityId, RefreshTypeList: []int32{1}, RefreshGroupId: 10103, UnlockPlayerLevel: 30},
+		104: {CampId: 104, CityId: testBlossomCityId, RefreshTypeList: []int32{3}, RefreshGroupId: 10104},
+	}
+	gdconf.CONF.BlossomGroupsDataGroupIdMap = make(map[int32]*gdconf.BlossomGroupsData)
+	for _, campData := range gdconf.CONF.BlossomGroupsDataMap {
+		gdconf.CONF.BlossomGroupsDataGroupIdMap[campData.RefreshGroupId] = campData
+	}
+	gdconf.CONF.WorldBossGroupMap = map[int32]*gdconf.InvestigationMonsterData{
+		testWorldBossGroupId: {Id: 1, MonsterIdList: []int32{26050801}, GroupIdList: []int32{testWorldBossGroupId}, Category: gdconf.InvestigationMonsterCategoryWorldBoss},
+	}
+	gdconf.CONF.SceneLuaGroupMap = map[int32]*gdconf.Group{
+		testWorldBossGroupId: {
+			Id:              testWorldBossGroupId,
+			BlockId:         testWorldBossBlockId,
+			VariableMap:     map[string]*gdconf.Variable{"boss_exist": {Name: "boss_exist", Value: 1}},
+			GroupInitConfig: &gdconf.GroupInitConfig{Suite: 1},
+		},
+	}
+	s.scene.world.sceneMap = map[uint32]*Scene{s.scene.id: s.scene}
+	s.player.PropMap = map[uint32]uint32{constant.PLAYER_PROP_PLAYER_LEVEL: 20}
+	s.player.SceneBlockMap = map[uint32]*model.SceneBlock{
+		testWorldBossBlockId: {BlockId: testWorldBossBlockId, SceneGroupMap: make(map[uint32]*model.SceneGroup)},
+	}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	return s, new(Game)
+}
+
+func TestBlossomNextRefreshTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.Local).UnixMilli()
+	next := GetBlossomNextRefreshTime(4*3600, now)
+	if next != time.Date(2024, 1, 1, 4, 0, 0, 0, time.Local).UnixMilli() {
+		t.Fatalf("next refresh time error, next: %v", time.UnixMilli(next))
+	}
+	// 已过当天刷新时间则到第二天
+	now = time.Date(2024, 1, 1, 4, 0, 0, 0, time.Local).UnixMilli()
+	next = GetBlossomNextRefreshTime(4*3600, now)
+	if next != time.Date(2024, 1, 2, 4, 0, 0, 0, time.Local).UnixMilli() {
+		t.Fatalf("next refresh time error, next: %v", time.UnixMilli(next))
+	}
+}
+
+func TestBlossomCampSchedule(t *testing.T) {
+	s, g := newTestWorldEventScene(t)
+	dbWorld := s.player.GetDbWorld()
+	now := time.Now().UnixMilli()
+
+	// 未满足刷新条件不出现
+	s.player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] = 12
+	g.WorldBlossomRefresh(s.scene.world, now)
+	if len(dbWorld.BlossomMap) != 0 {
+		t.Fatalf("blossom should not refresh before cond")
+	}
+
+	// 初始刷新营地点
+	s.player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] = 20
+	g.WorldBlossomRefresh(s.scene.world, now)
+	blossom := dbWorld.BlossomMap[testBlossomRefreshId]
+	if blossom == nil || blossom.CampId != 101 {
+		t.Fatalf("blossom initial camp error, blossom: %+v", blossom)
+	}
+	blossom.State = BlossomScheduleStateChest
+	g.WorldBlossomRefresh(s.scene.world, now)
+	if blossom.CampId != 101 || blossom.State != BlossomScheduleStateChest {
+		t.Fatalf("blossom should not move before reward taken, blossom: %+v", blossom)
+	}
+
+	// 冷却中不转移
+	blossom.State = BlossomScheduleStateNone
+	blossom.NextRefreshTime = now + 1000
+	g.WorldBlossomRefresh(s.scene.world, now)
+	if blossom.CampId != 101 {
+		t.Fatalf("blossom should not move during cooldown, blossom: %+v", blossom)
+	}
+
+	// 下游点未解锁 按区域顺序转移到下一个营地点
+	g.WorldBlossomRefresh(s.scene.world, now+1000)
+	if blossom.CampId != 102 || blossom.NextRefreshTime != 0 {
+		t.Fatalf("blossom next camp error, blossom: %+v", blossom)
+	}
+
+	// 下游点解锁后优先下游点
+	s.player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] = 30
+	campData := SelectBlossomCamp(s.player, gdconf.GetBlossomRefreshDataById(testBlossomRefreshId), 101)
+	if campData == nil || campData.CampId != 103 {
+		t.Fatalf("blossom downstream camp error, campData: %+v", campData)
+	}
+}
+
+func TestWorldBossRespawn(t *testing.T) {
+	s, g := newTestWorldEventScene(t)
+	world := s.scene.world
+	dbWorld := s.player.GetDbWorld()
+
+	// 非boss怪物不记录
+	g.WorldBossKill(world, &MonsterEntity{Entity: &Entity{groupId: testWorldBossGroupId}, monsterId: 21010101})
+	if len(dbWorld.WorldBossRespawnMap) != 0 {
+		t.Fatalf("normal monster should not record respawn time")
+	}
+	before := time.Now().UnixMilli()
+	g.WorldBossKill(world, &MonsterEntity{Entity: &Entity{groupId: testWorldBossGroupId}, monsterId: 26050801})
+	respawnTime, exist := dbWorld.WorldBossRespawnMap[testWorldBossGroupId]
+	if !exist || respawnTime < before+60*1000 {
+		t.Fatalf("world boss respawn time error, respawnTime: %v, before: %v", respawnTime, before)
+	}
+	sceneGroup := s.player.GetSceneGroupById(testWorldBossGroupId)
+	sceneGroup.AddKill(1)
+	sceneGroup.SetVariable("boss_exist", 0)
+
+	// 未到复活时间
+	g.WorldBossRespawn(world, respawnTime-1)
+	if !sceneGroup.CheckIsKill(1) {
+		t.Fatalf("world boss respawn before respawn time")
+	}
+
+	// 到达复活时间 重置击杀记录与场景组变量
+	g.WorldBossRespawn(world, respawnTime)
+	if sceneGroup.CheckIsKill(1) || sceneGroup.GetVariableByName("boss_exist") != 1 {
+		t.Fatalf("world boss not respawn, sceneGroup: %+v", sceneGroup)
+	}
+	if len(dbWorld.WorldBossRespawnMap) != 0 {
+		t.Fatalf("world boss respawn record not clear")
+	}
+}
+
+func TestBlossomChestReward(t *testing.T) {
+	s, g := newTestWorldEventScene(t)
+	gdconf.CONF.DropDataMap = map[int32]*gdconf.DropData{
+		100: {DropId: 100},
+	}
+	host := s.player
+	host.Online = true
+	guest := s.addGuest()
+	guest.Online = true
+	guest.PropMap = map[uint32]uint32{constant.PLAYER_PROP_PLAYER_RESIN: 60}
+	oldUserManager, oldPluginManager := USER_MANAGER, PLUGIN_MANAGER
+	USER_MANAGER = &UserManager{playerMap: map[uint32]*model.Player{host.PlayerId: host, guest.PlayerId: guest}}
+	PLUGIN_MANAGER = NewPluginManager()
+	t.Cleanup(func() {
+		USER_MANAGER, PLUGIN_MANAGER = oldUserManager, oldPluginManager
+	})
+	g.WorldBlossomRefresh(s.scene.world, time.Now().UnixMilli())
+	blossom := host.GetDbWorld().BlossomMap[testBlossomRefreshId]
+	blossom.State = BlossomScheduleStateChest
+	blossom.ChestConfigId = 1
+	chest := s.addGadget(100, new(model.Vector), 0.0)
+	chest.groupId = 10101
+	chest.configId = 1
+
+	// 没有配置奖励掉落时不消耗树脂
+	refreshData := gdconf.GetBlossomRefreshDataById(testBlossomRefreshId)
+	if ret := g.BlossomChestReward(guest, s.scene, chest); ret != proto.Retcode_RET_BLOSSOM_CHEST_NO_QUALIFICATION {
+		t.Fatalf("blossom chest without reward drop should be refused, ret: %v", ret)
+	}
+	if guest.PropMap[constant.PLAYER_PROP_PLAYER_RESIN] != 60 {
+		t.Fatalf("resin should not cost, resin: %v", guest.PropMap[constant.PLAYER_PROP_PLAYER_RESIN])
+	}
+	// 世界等级超出配置取最后一个
+	refreshData.RewardDropIdList = []int32{0, 100}
+	host.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] = 8
+	if dropId := GetBlossomRewardDropId(refreshData, 8); dropId != 100 {
+		t.Fatalf("blossom reward drop id error, dropId: %v", dropId)
+	}
+
+	// 客机领奖只记录客机的冷却 房主的地脉之花不进入冷却
+	if ret := g.BlossomChestReward(guest, s.scene, chest); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("guest take blossom chest error, ret: %v", ret)
+	}
+	if guest.PropMap[constant.PLAYER_PROP_PLAYER_RESIN] != 40 || guest.GetDbWorld().BlossomRewardMap[testBlossomRefreshId] == 0 {
+		t.Fatalf("guest blossom reward error, resin: %v, rewardMap: %v", guest.PropMap[constant.PLAYER_PROP_PLAYER_RESIN], guest.GetDbWorld().BlossomRewardMap)
+	}
+	if blossom.State != BlossomScheduleStateChest || blossom.NextRefreshTime != 0 || len(host.GetDbWorld().BlossomRewardMap) != 0 {
+		t.Fatalf("guest reward should not change host blossom, blossom: %+v", blossom)
+	}
+	if ret := g.BlossomChestReward(guest, s.scene, chest); ret != proto.Retcode_RET_BLOSSOM_CHEST_HAS_TAKEN {
+		t.Fatalf("guest should take blossom chest only once, ret: %v", ret)
+	}
+	if len(guest.GetDbWorld().BlossomMap) != 0 {
+		t.Fatalf("guest world blossom should not change, blossomMap: %v", guest.GetDbWorld().BlossomMap)
+	}
+}
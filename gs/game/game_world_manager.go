@@ -2,12 +2,13 @@ package game
 
 import (
 	"math"
-	"time"
 
+	"hk4e/common/config"
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
 	"hk4e/pkg/alg"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/random"
 	"hk4e/protocol/proto"
 
@@ -29,6 +30,7 @@ type WorldManager struct {
 	sceneBlockAoiMap    map[uint32]*alg.AoiManager         // 场景区块aoi
 	sceneEntityAoiMap   map[uint32]map[int]*alg.AoiManager // 场景实体aoi
 	multiplayerWorldNum uint32                             // 本服当前的多人世界数量
+	monsterAiPathFinder MonsterAiPathFinder                // 服务端怪物ai寻路
 }
 
 func NewWorldManager(snowflake *alg.SnowflakeWorker) (r *WorldManager) {
@@ -36,6 +38,7 @@ func NewWorldManager(snowflake *alg.SnowflakeWorker) (r *WorldManager) {
 	r.worldMap = make(map[uint64]*World)
 	r.snowflake = snowflake
 	r.LoadSceneAoi()
+	r.monsterAiPathFinder = NewNavMeshPathFinder(config.GetConfig().Hk4e.NavMeshPath)
 	r.multiplayerWorldNum = 0
 	return r
 }
@@ -59,7 +62,6 @@ func (w *WorldManager) CreateWorld(owner *model.Player) *World {
 		enterSceneToken:      uint32(random.GetRandomInt32(5000, 50000)),
 		enterSceneContextMap: make(map[uint32]*EnterSceneContext),
 		entityIdCounter:      0,
-		worldLevel:           0,
 		multiplayer:          false,
 		mpLevelEntityId:      0,
 		chatMsgList:          make([]*proto.ChatInfo, 0),
@@ -69,6 +71,7 @@ func (w *WorldManager) CreateWorld(owner *model.Player) *World {
 		peerList:             make([]*model.Player, 0),
 		aiWorldAoi:           nil,
 		bulletPhysicsEngine:  nil,
+		monsterAiManager:     NewMonsterAiManager(w.monsterAiPathFinder, false),
 	}
 	world.mpLevelEntityId = world.GetNextWorldEntityId(constant.ENTITY_TYPE_MP_LEVEL)
 	w.worldMap[worldId] = world
@@ -80,6 +83,7 @@ func (w *WorldManager) CreateWorld(owner *model.Player) *World {
 		world.aiWorldAoi = aoiManager
 		logger.Info("ai world aoi init finish")
 		world.NewPhysicsEngine()
+		world.monsterAiManager.SetEnable(true)
 	}
 
 	return world
@@ -232,7 +236,6 @@ type World struct {
 	enterSceneToken      uint32
 	enterSceneContextMap map[uint32]*EnterSceneContext // 场景切换上下文 key:EnterSceneToken value:EnterSceneContext
 	entityIdCounter      uint32                        // 世界的实体id生成计数器
-	worldLevel           uint8                         // 世界等级
 	multiplayer          bool                          // 是否多人世界
 	mpLevelEntityId      uint32                        // 多人世界等级实体id
 	chatMsgList          []*proto.ChatInfo             // 世界聊天消息列表
@@ -242,12 +245,17 @@ type World struct {
 	peerList             []*model.Player               // 玩家编号列表
 	aiWorldAoi           *alg.AoiManager               // ai世界的aoi管理器
 	bulletPhysicsEngine  *PhysicsEngine                // 蓄力箭子弹物理引擎
+	monsterAiManager     *MonsterAiManager             // 服务端怪物ai管理器
 }
 
 func (w *World) GetBulletPhysicsEngine() *PhysicsEngine {
 	return w.bulletPhysicsEngine
 }
 
+func (w *World) GetMonsterAiManager() *MonsterAiManager {
+	return w.monsterAiManager
+}
+
 func (w *World) GetId() uint64 {
 	return w.id
 }
@@ -309,8 +317,9 @@ func (w *World) RemoveAllEnterSceneContextByUid(uid uint32) {
 	}
 }
 
+// GetWorldLevel 世界等级为房主的世界等级
 func (w *World) GetWorldLevel() uint8 {
-	return w.worldLevel
+	return uint8(w.owner.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL])
 }
 
 func (w *World) IsMultiplayerWorld() bool {
@@ -846,11 +855,11 @@ func (w *World) IsPlayerFirstEnter(player *model.Player) bool {
 }
 
 func (w *World) PlayerEnter(uid uint32) {
-	w.playerFirstEnterMap[uid] = time.Now().UnixMilli()
+	w.playerFirstEnterMap[uid] = clock.Now().UnixMilli()
 }
 
 func (w *World) AddWaitPlayer(uid uint32) {
-	w.waitEnterPlayerMap[uid] = time.Now().UnixMilli()
+	w.waitEnterPlayerMap[uid] = clock.Now().UnixMilli()
 }
 
 func (w *World) GetAllWaitPlayer() []uint32 {
@@ -873,6 +882,15 @@ func (w *World) ChangeGameTime(time uint32) {
 	w.GetOwner().GetDbWorld().GameTime = time % 1440
 }
 
+// IsGameTimePause 游戏时间是否暂停 以世界房主为准
+func (w *World) IsGameTimePause() bool {
+	owner := w.GetOwner()
+	if owner.PropMap[constant.PLAYER_PROP_IS_GAME_TIME_LOCKED] == 1 {
+		return true
+	}
+	return owner.Pause && !w.IsMultiplayerWorld()
+}
+
 func (w *World) CreateScene(sceneId uint32) *Scene {
 	scene := &Scene{
 		id:         sceneId,
@@ -880,8 +898,9 @@ func (w *World) CreateScene(sceneId uint32) *Scene {
 		playerMap:  make(map[uint32]*model.Player),
 		entityMap:  make(map[uint32]IEntity),
 		groupMap:   make(map[uint32]*Group),
-		createTime: time.Now().UnixMilli(),
+		createTime: clock.Now().UnixMilli(),
 		meeoIndex:  0,
+		weatherMap: make(map[uint32]*SceneWeather),
 	}
 	w.sceneMap[sceneId] = scene
 	return scene
@@ -3,12 +3,11 @@ package game
 import (
 	"fmt"
 	"math"
-	"strconv"
-	"time"
 
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/endec"
 	"hk4e/protocol/proto"
 
@@ -21,11 +20,48 @@ type Scene struct {
 	id          uint32
 	world       *World
 	playerMap   map[uint32]*model.Player
-	entityMap   map[uint32]IEntity // 场景中全部的实体
-	groupMap    map[uint32]*Group  // 场景中按group->suite分类的实体
-	createTime  int64              // 场景创建时间
-	meeoIndex   uint32             // 客户端风元素染色同步协议的计数器
-	monsterWudi bool               // 是否开启场景内怪物无敌
+	entityMap   map[uint32]IEntity       // 场景中全部的实体
+	groupMap    map[uint32]*Group        // 场景中按group->suite分类的实体
+	createTime  int64                    // 场景创建时间
+	meeoIndex   uint32                   // 客户端风元素染色同步协议的计数器
+	monsterWudi bool                     // 是否开启场景内怪物无敌
+	weatherMap  map[uint32]*SceneWeather // 场景天气区域的气象 key:天气区域id
+	dungeon     *SceneDungeon            // 地牢副本进度 非地牢场景为空
+}
+
+// SceneDungeon 地牢副本进度
+type SceneDungeon struct {
+	dungeonId uint32
+	settled   bool              // 是否已经结算
+	killMap   map[uint32]uint32 // 击杀的怪物数量 key:怪物id
+}
+
+func (d *SceneDungeon) GetDungeonId() uint32 {
+	return d.dungeonId
+}
+
+func (d *SceneDungeon) IsSettled() bool {
+	return d.settled
+}
+
+func (d *SceneDungeon) Settle() {
+	d.settled = true
+}
+
+func (d *SceneDungeon) AddKill(monsterId uint32) {
+	d.killMap[monsterId]++
+}
+
+func (d *SceneDungeon) GetKillCount(monsterId uint32) uint32 {
+	return d.killMap[monsterId]
+}
+
+func (d *SceneDungeon) GetTotalKillCount() uint32 {
+	totalCount := uint32(0)
+	for _, count := range d.killMap {
+		totalCount += count
+	}
+	return totalCount
 }
 
 func (s *Scene) GetId() uint32 {
@@ -68,12 +104,63 @@ func (s *Scene) SetMonsterWudi(monsterWudi bool) {
 	s.monsterWudi = monsterWudi
 }
 
+// SceneWeather 场景天气区域的气象
+type SceneWeather struct {
+	weatherAreaId   uint32
+	climateType     uint32
+	nextRefreshTime int64 // 下次随机气象的时间 毫秒时间戳
+}
+
+func (w *SceneWeather) GetWeatherAreaId() uint32 {
+	return w.weatherAreaId
+}
+
+func (w *SceneWeather) GetClimateType() uint32 {
+	return w.climateType
+}
+
+func (w *SceneWeather) GetNextRefreshTime() int64 {
+	return w.nextRefreshTime
+}
+
+func (s *Scene) GetWeather(weatherAreaId uint32) *SceneWeather {
+	return s.weatherMap[weatherAreaId]
+}
+
+func (s *Scene) GetAllWeather() map[uint32]*SceneWeather {
+	return s.weatherMap
+}
+
+func (s *Scene) SetWeather(weatherAreaId uint32, climateType uint32, nextRefreshTime int64) *SceneWeather {
+	weather, exist := s.weatherMap[weatherAreaId]
+	if !exist {
+		weather = &SceneWeather{weatherAreaId: weatherAreaId}
+		s.weatherMap[weatherAreaId] = weather
+	}
+	weather.climateType = climateType
+	weather.nextRefreshTime = nextRefreshTime
+	return weather
+}
+
+// InitDungeon 第一个玩家进入地牢场景时重置副本进度
+func (s *Scene) InitDungeon(dungeonId uint32) {
+	s.dungeon = &SceneDungeon{
+		dungeonId: dungeonId,
+		settled:   false,
+		killMap:   make(map[uint32]uint32),
+	}
+}
+
+func (s *Scene) GetDungeon() *SceneDungeon {
+	return s.dungeon
+}
+
 func (s *Scene) GetSceneCreateTime() int64 {
 	return s.createTime
 }
 
 func (s *Scene) GetSceneTime() int64 {
-	now := time.Now().UnixMilli()
+	now := clock.Now().UnixMilli()
 	return now - s.createTime
 }
 
@@ -158,6 +245,7 @@ func (s *Scene) CreateEntityMonster(pos, rot *model.Vector, level uint8, configI
 			groupId:     groupId,
 			visionLevel: visionLevel,
 		},
+		bornPos: &model.Vector{X: pos.X, Y: pos.Y, Z: pos.Z},
 	}
 	return entity
 }
@@ -476,6 +564,7 @@ type Ability struct {
 	abilityNameHash           uint32
 	instancedAbilityId        uint32
 	abilitySpecialOverrideMap map[uint32]float32
+	shieldBarMap              map[int32]float32 // 护盾条mixin的护盾值 key:mixin localId value:护盾值
 }
 
 type Modifier struct {
@@ -519,8 +608,6 @@ type IEntity interface {
 	AddModifier(ability *Ability, instancedModifierId uint32, modifierLocalId uint32)
 	GetAllModifier() []*Modifier
 	RemoveModifier(instancedModifierId uint32)
-	AbilityAction(ability *Ability, action *gdconf.ActionData, entity IEntity)
-	AbilityMixin(ability *Ability, mixin *gdconf.MixinData, entity IEntity)
 	GetDynamicValueMap() map[uint32]float32
 }
 
@@ -657,6 +744,7 @@ func (e *Entity) AddAbility(abilityName string, instancedAbilityId uint32) {
 		abilityNameHash:           uint32(endec.Hk4eAbilityHashCode(abilityName)),
 		instancedAbilityId:        instancedAbilityId,
 		abilitySpecialOverrideMap: make(map[uint32]float32),
+		shieldBarMap:              make(map[int32]float32),
 	}
 }
 
@@ -762,100 +850,6 @@ func (a *Ability) GetDynamicFloat(abilityData *gdconf.AbilityData, dynamicFloat
 	}
 }
 
-func (e *Entity) AbilityAction(ability *Ability, action *gdconf.ActionData, entity IEntity) {
-	logger.Debug("[AbilityAction] type: %v, entityId: %v", action.Type, entity.GetId())
-	owner := entity.GetScene().GetWorld().GetOwner()
-	switch action.Type {
-	case "ExecuteGadgetLua":
-		iGadgetEntity, ok := entity.(IGadgetEntity)
-		if !ok {
-			logger.Error("entity is not gadget, entityId: %v", entity.GetId())
-			return
-		}
-		gadgetDataConfig := gdconf.GetGadgetDataById(int32(iGadgetEntity.GetGadgetId()))
-		if gadgetDataConfig == nil {
-			logger.Error("get gadget data config is nil, gadgetId: %v", iGadgetEntity.GetGadgetId())
-			return
-		}
-		if gadgetDataConfig.ServerLuaScript != "" {
-			gadgetLuaConfig := gdconf.GetGadgetLuaConfigByName(gadgetDataConfig.ServerLuaScript)
-			if gadgetLuaConfig == nil {
-				logger.Error("get gadget lua config is nil, name: %v", gadgetDataConfig.ServerLuaScript)
-				return
-			}
-			CallGadgetLuaFunc(gadgetLuaConfig.LuaState, "OnClientExecuteReq",
-				&LuaCtx{uid: owner.PlayerId, targetEntityId: entity.GetId(), groupId: entity.GetGroupId()},
-				action.Param1, action.Param2, action.Param3)
-		}
-	case "KillSelf":
-		GAME.KillEntity(owner, entity.GetScene(), entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
-	case "AvatarSkillStart":
-		abilityDataConfig := gdconf.GetAbilityDataByName(ability.abilityName)
-		if abilityDataConfig == nil {
-			logger.Error("get ability data config is nil, abilityName: %v", ability.abilityName)
-			return
-		}
-		staminaCost := ability.GetDynamicFloat(abilityDataConfig, action.CostStaminaRatio)
-		GAME.UpdatePlayerStamina(owner, int32(staminaCost)*-100)
-		GAME.TriggerQuest(owner, constant.QUEST_FINISH_COND_TYPE_SKILL, "", action.SkillID)
-	case "CreateGadget":
-		if !action.ByServer {
-			return
-		}
-		GAME.CreateGadget(owner, entity.GetPos(), uint32(action.GadgetID))
-	case "GenerateElemBall":
-		itemDataConfig := gdconf.GetItemDataById(action.ConfigID)
-		if itemDataConfig == nil {
-			logger.Error("get item data config is nil, itemId: %v", action.ConfigID)
-			return
-		}
-		if itemDataConfig.GadgetId == 0 {
-			return
-		}
-		abilityDataConfig := gdconf.GetAbilityDataByName(ability.abilityName)
-		if abilityDataConfig == nil {
-			logger.Error("get ability data config is nil, abilityName: %v", ability.abilityName)
-			return
-		}
-		baseEnergy := ability.GetDynamicFloat(abilityDataConfig, action.BaseEnergy)
-		ratio := ability.GetDynamicFloat(abilityDataConfig, action.Ratio)
-		totalEnergy := baseEnergy * ratio
-		for _, itemUse := range itemDataConfig.ItemUseList {
-			if itemUse.UseOption != constant.ITEM_USE_ADD_ELEM_ENERGY {
-				continue
-			}
-			if len(itemUse.UseParam) != 3 {
-				continue
-			}
-			sameEnergy, err := strconv.Atoi(itemUse.UseParam[1])
-			if err != nil {
-				continue
-			}
-			count := math.Ceil(float64(totalEnergy) / float64(sameEnergy))
-			for i := 0; i < int(count); i++ {
-				GAME.CreateDropGadget(owner, entity.GetPos(), uint32(itemDataConfig.GadgetId), uint32(action.ConfigID), 1)
-			}
-		}
-	default:
-		logger.Error("not support ability action type: %v, abilityName: %v, entityId: %v", action.Type, ability.abilityName, entity.GetId())
-	}
-}
-
-func (e *Entity) AbilityMixin(ability *Ability, mixin *gdconf.MixinData, entity IEntity) {
-	logger.Debug("[AbilityMixin] type: %v, entityId: %v", mixin.Type, entity.GetId())
-	owner := entity.GetScene().GetWorld().GetOwner()
-	switch mixin.Type {
-	case "CostStaminaMixin":
-		abilityDataConfig := gdconf.GetAbilityDataByName(ability.abilityName)
-		if abilityDataConfig == nil {
-			logger.Error("get ability data config is nil, abilityName: %v", ability.abilityName)
-			return
-		}
-		staminaCost := ability.GetDynamicFloat(abilityDataConfig, mixin.CostStaminaDelta)
-		GAME.UpdatePlayerStamina(owner, int32(staminaCost)*-100)
-	}
-}
-
 func (e *Entity) GetDynamicValueMap() map[uint32]float32 {
 	return e.dynamicValueMap
 }
@@ -893,6 +887,11 @@ func (w *WeaponEntity) InitAbility() {
 type MonsterEntity struct {
 	*Entity
 	monsterId uint32
+	bornPos   *model.Vector // 出生点
+}
+
+func (m *MonsterEntity) GetBornPos() *model.Vector {
+	return m.bornPos
 }
 
 func (m *MonsterEntity) GetMonsterId() uint32 {
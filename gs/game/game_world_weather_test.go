@@ -0,0 +1,105 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+)
+
+const testWeatherAreaId = 3001
+
+func newTestWeatherScene(t *testing.T) (*testAbilityScene, *Game) {
+	s := newTestAbilityScene(t)
+	config.CONF = &config.Config{Hk4e: config.Hk4e{WeatherRefreshInterval: 60}}
+	gdconf.CONF.WeatherDataMap = map[int32]*gdconf.WeatherData{
+		testWeatherAreaId: {WeatherAreaId: testWeatherAreaId, JsonWeatherAreaId: 1, TemplateName: "Test_Template"},
+	}
+	// 只有下雨权重 随机结果固定
+	gdconf.CONF.WeatherTemplateDataMap = map[string]map[int32]*gdconf.WeatherTemplateData{
+		"Test_Template": {1: {TemplateName: "Test_Template", Weather: 1, Rain: 100}},
+	}
+	s.scene.weatherMap = make(map[uint32]*SceneWeather)
+	s.scene.world.sceneMap = map[uint32]*Scene{s.scene.id: s.scene}
+	s.player.PropMap = make(map[uint32]uint32)
+	s.player.WeatherInfo = model.NewWeatherInfo()
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	return s, new(Game)
+}
+
+func TestSceneWeatherShareByArea(t *testing.T) {
+	s, g := newTestWeatherScene(t)
+	other := &model.Player{PlayerId: 10002, WeatherInfo: model.NewWeatherInfo()}
+	s.scene.playerMap[other.PlayerId] = other
+
+	climateType := g.GetSceneWeatherClimate(s.scene, testWeatherAreaId)
+	if climateType != constant.CLIMATE_TYPE_RAIN {
+		t.Fatalf("climate type error, climateType: %v", climateType)
+	}
+	// 同一场景同一天气区域的玩家看到同一气象
+	g.SetSceneWeather(s.scene, testWeatherAreaId, constant.CLIMATE_TYPE_SNOW)
+	if g.GetSceneWeatherClimate(s.scene, testWeatherAreaId) != constant.CLIMATE_TYPE_SNOW {
+		t.Fatalf("scene weather not shared")
+	}
+	if g.GetSceneWeatherClimate(s.scene, 0) != 0 {
+		t.Fatalf("weather area 0 should not have climate")
+	}
+	if len(s.scene.GetAllWeather()) != 1 {
+		t.Fatalf("scene weather count error, count: %v", len(s.scene.GetAllWeather()))
+	}
+}
+
+func TestSceneWeatherRefresh(t *testing.T) {
+	s, g := newTestWeatherScene(t)
+	now := time.Now().UnixMilli()
+	g.SetSceneWeather(s.scene, testWeatherAreaId, constant.CLIMATE_TYPE_SNOW)
+	weather := s.scene.GetWeather(testWeatherAreaId)
+	if weather.GetNextRefreshTime() < now+60*1000 {
+		t.Fatalf("next refresh time error, next: %v, now: %v", weather.GetNextRefreshTime(), now)
+	}
+
+	// 未到随机时间不变
+	g.SceneWeatherRefresh(s.scene.world, now, false)
+	if s.scene.GetWeather(testWeatherAreaId).GetClimateType() != constant.CLIMATE_TYPE_SNOW {
+		t.Fatalf("weather refresh before next refresh time")
+	}
+
+	// 房主锁定天气 到期也只推迟下次随机时间
+	s.player.PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] = 1
+	later := now + 120*1000
+	g.SceneWeatherRefresh(s.scene.world, later, false)
+	weather = s.scene.GetWeather(testWeatherAreaId)
+	if weather.GetClimateType() != constant.CLIMATE_TYPE_SNOW || weather.GetNextRefreshTime() != later+60*1000 {
+		t.Fatalf("locked weather refresh error, climateType: %v, next: %v", weather.GetClimateType(), weather.GetNextRefreshTime())
+	}
+
+	// 解锁后到期重新随机
+	s.player.PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] = 0
+	g.SceneWeatherRefresh(s.scene.world, later+60*1000, false)
+	if s.scene.GetWeather(testWeatherAreaId).GetClimateType() != constant.CLIMATE_TYPE_RAIN {
+		t.Fatalf("weather not refresh after next refresh time")
+	}
+}
+
+func TestWorldGameTimePause(t *testing.T) {
+	s, _ := newTestWeatherScene(t)
+	world := s.scene.world
+	s.player.Pause = true
+	if !world.IsGameTimePause() {
+		t.Fatalf("single player world should pause")
+	}
+	// 多人世界不受房主暂停影响
+	world.multiplayer = true
+	if world.IsGameTimePause() {
+		t.Fatalf("multiplayer world should not pause")
+	}
+	s.player.PropMap[constant.PLAYER_PROP_IS_GAME_TIME_LOCKED] = 1
+	if !world.IsGameTimePause() {
+		t.Fatalf("game time locked world should pause")
+	}
+}
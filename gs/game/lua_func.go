@@ -192,11 +192,20 @@ func RegLuaScriptLibFunc() {
 	gdconf.RegScriptLibFunc("RefreshGroup", RefreshGroup)
 	gdconf.RegScriptLibFunc("RemoveExtraGroupSuite", RemoveExtraGroupSuite)
 	gdconf.RegScriptLibFunc("ShowReminder", ShowReminder)
+	gdconf.RegScriptLibFunc("CauseDungeonSuccess", CauseDungeonSuccess)
+	gdconf.RegScriptLibFunc("CauseDungeonFail", CauseDungeonFail)
 	gdconf.RegScriptLibFunc("KillGroupEntity", KillGroupEntity)
 	gdconf.RegScriptLibFunc("SetWorktopOptions", SetWorktopOptions)
 	gdconf.RegScriptLibFunc("SetWorktopOptionsByGroupId", SetWorktopOptionsByGroupId)
 	gdconf.RegScriptLibFunc("DelWorktopOption", DelWorktopOption)
 	gdconf.RegScriptLibFunc("DelWorktopOptionByGroupId", DelWorktopOptionByGroupId)
+	gdconf.RegScriptLibFunc("GetBlossomScheduleStateByGroupId", GetBlossomScheduleStateByGroupId)
+	gdconf.RegScriptLibFunc("SetBlossomScheduleStateByGroupId", SetBlossomScheduleStateByGroupId)
+	gdconf.RegScriptLibFunc("GetBlossomRefreshTypeByGroupId", GetBlossomRefreshTypeByGroupId)
+	gdconf.RegScriptLibFunc("RefreshBlossomDropRewardByGroupId", RefreshBlossomDropRewardByGroupId)
+	gdconf.RegScriptLibFunc("AddBlossomScheduleProgressByGroupId", AddBlossomScheduleProgressByGroupId)
+	gdconf.RegScriptLibFunc("CreateBlossomChestByGroupId", CreateBlossomChestByGroupId)
+	gdconf.RegScriptLibFunc("RefreshBlossomGroup", RefreshBlossomGroup)
 	// 调用物件LUA方法
 	gdconf.RegScriptLibFunc("SetGadgetState", SetGadgetState)
 	gdconf.RegScriptLibFunc("GetGadgetState", GetGadgetState)
@@ -214,6 +223,7 @@ type CommonLuaTableParam struct {
 	Suite        int32  `json:"suite"`
 	KillPolicy   int32  `json:"kill_policy"`
 	SubfieldName string `json:"subfield_name"`
+	ExcludePrev  bool   `json:"exclude_prev"`
 }
 
 func GetEntityType(luaState *lua.LState) int {
@@ -909,9 +919,8 @@ func EnterWeatherArea(luaState *lua.LState) int {
 		return 1
 	}
 	weatherAreaId := luaState.ToInt(2)
-	// 设置玩家天气
-	climateType := GAME.GetWeatherAreaClimate(uint32(weatherAreaId))
-	GAME.SetPlayerWeather(player, uint32(weatherAreaId), climateType, true)
+	// 设置玩家天气区域
+	GAME.SetPlayerWeatherArea(player, uint32(weatherAreaId), true)
 	luaState.Push(lua.LNumber(0))
 	return 1
 }
@@ -929,7 +938,13 @@ func SetWeatherAreaState(luaState *lua.LState) int {
 	}
 	weatherAreaId := luaState.ToInt(2)
 	climateType := luaState.ToInt(3)
-	GAME.SetPlayerWeather(player, uint32(weatherAreaId), uint32(climateType), true)
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	GAME.SetSceneWeather(scene, uint32(weatherAreaId), uint32(climateType))
 	luaState.Push(lua.LNumber(0))
 	return 1
 }
@@ -980,6 +995,48 @@ func ShowReminder(luaState *lua.LState) int {
 	return 1
 }
 
+func CauseDungeonSuccess(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	GAME.DungeonSettle(world.GetSceneById(player.GetSceneId()), true)
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
+func CauseDungeonFail(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	GAME.DungeonSettle(world.GetSceneById(player.GetSceneId()), false)
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
 func KillGroupEntity(luaState *lua.LState) int {
 	ctx, ok := luaState.Get(1).(*lua.LTable)
 	if !ok {
@@ -1378,3 +1435,175 @@ func DropSubfield(luaState *lua.LState) int {
 	luaState.Push(lua.LNumber(0))
 	return 1
 }
+
+// GetBlossomContextGroupId 获取地脉之花场景组id 为0则为当前上下文场景组
+func GetBlossomContextGroupId(ctx *lua.LTable, luaState *lua.LState, groupId uint32) uint32 {
+	if groupId != 0 {
+		return groupId
+	}
+	ctxGroupId, ok := luaState.GetField(ctx, "groupId").(lua.LNumber)
+	if !ok {
+		return 0
+	}
+	return uint32(ctxGroupId)
+}
+
+func GetBlossomScheduleStateByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaState.ToInt(2)))
+	blossom, _, _ := GAME.GetWorldBlossomByGroupId(world, groupId)
+	if blossom == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	luaState.Push(lua.LNumber(blossom.State))
+	return 1
+}
+
+func SetBlossomScheduleStateByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaState.ToInt(2)))
+	state := luaState.ToInt(3)
+	blossom, _, _ := GAME.GetWorldBlossomByGroupId(world, groupId)
+	if blossom == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	// 奖励宝箱只能由创建宝箱进入
+	if state == BlossomScheduleStateChest || blossom.State == BlossomScheduleStateChest {
+		luaState.Push(lua.LNumber(0))
+		return 1
+	}
+	blossom.State = uint8(state)
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
+func GetBlossomRefreshTypeByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNil)
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNil)
+		return 1
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		luaState.Push(lua.LNil)
+		return 1
+	}
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaState.ToInt(2)))
+	_, refreshData, _ := GAME.GetWorldBlossomByGroupId(world, groupId)
+	if refreshData == nil {
+		luaState.Push(lua.LNil)
+		return 1
+	}
+	luaState.Push(lua.LNumber(refreshData.RefreshType))
+	return 1
+}
+
+func RefreshBlossomDropRewardByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	// 奖励在领取宝箱时按世界等级计算
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
+func AddBlossomScheduleProgressByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaState.ToInt(2)))
+	GAME.AddBlossomProgress(player, groupId)
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
+func CreateBlossomChestByGroupId(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaState.ToInt(2)))
+	configId := luaState.ToInt(3)
+	GAME.CreateBlossomChest(player, groupId, uint32(configId))
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
+
+func RefreshBlossomGroup(luaState *lua.LState) int {
+	ctx, ok := luaState.Get(1).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	player := GetContextPlayer(ctx, luaState)
+	if player == nil {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	luaTable, ok := luaState.Get(2).(*lua.LTable)
+	if !ok {
+		luaState.Push(lua.LNumber(-1))
+		return 1
+	}
+	luaTableParam := new(CommonLuaTableParam)
+	gdconf.ParseLuaTableToObject[*CommonLuaTableParam](luaTable, luaTableParam)
+	groupId := GetBlossomContextGroupId(ctx, luaState, uint32(luaTableParam.GroupId))
+	GAME.RefreshBlossomGroup(player, groupId, uint8(luaTableParam.Suite), luaTableParam.ExcludePrev)
+	luaState.Push(lua.LNumber(0))
+	return 1
+}
@@ -394,3 +394,81 @@ func (g *Game) SelectOptionTriggerCheck(player *model.Player, group *Group, enti
 		}
 	})
 }
+
+// GroupRefreshTriggerCheck 场景组刷新触发器检测
+func (g *Game) GroupRefreshTriggerCheck(player *model.Player, group *Group) {
+	forEachGroupTrigger(player, group, func(triggerConfig *gdconf.Trigger, groupConfig *gdconf.Group) {
+		if triggerConfig.Event != constant.LUA_EVENT_GROUP_REFRESH {
+			return
+		}
+		if triggerConfig.Condition != "" {
+			cond := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Condition,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{})
+			if !cond {
+				return
+			}
+		}
+		if triggerConfig.Action != "" {
+			logger.Debug("scene group trigger do action, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			ok := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Action,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{})
+			if !ok {
+				logger.Error("trigger action fail, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			}
+		}
+	})
+}
+
+// BlossomProgressFinishTriggerCheck 地脉之花挑战完成触发器检测
+func (g *Game) BlossomProgressFinishTriggerCheck(player *model.Player, group *Group) {
+	forEachGroupTrigger(player, group, func(triggerConfig *gdconf.Trigger, groupConfig *gdconf.Group) {
+		if triggerConfig.Event != constant.LUA_EVENT_BLOSSOM_PROGRESS_FINISH {
+			return
+		}
+		if triggerConfig.Condition != "" {
+			cond := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Condition,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{})
+			if !cond {
+				return
+			}
+		}
+		if triggerConfig.Action != "" {
+			logger.Debug("scene group trigger do action, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			ok := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Action,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{})
+			if !ok {
+				logger.Error("trigger action fail, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			}
+		}
+	})
+}
+
+// BlossomChestDieTriggerCheck 地脉之花奖励宝箱领取触发器检测
+func (g *Game) BlossomChestDieTriggerCheck(player *model.Player, group *Group, configId uint32) {
+	forEachGroupTrigger(player, group, func(triggerConfig *gdconf.Trigger, groupConfig *gdconf.Group) {
+		if triggerConfig.Event != constant.LUA_EVENT_BLOSSOM_CHEST_DIE {
+			return
+		}
+		if triggerConfig.Condition != "" {
+			cond := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Condition,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{param1: int32(configId)})
+			if !cond {
+				return
+			}
+		}
+		if triggerConfig.Action != "" {
+			logger.Debug("scene group trigger do action, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			ok := CallSceneLuaFunc(groupConfig.GetLuaState(), triggerConfig.Action,
+				&LuaCtx{uid: player.PlayerId, groupId: uint32(groupConfig.Id)},
+				&LuaEvt{param1: int32(configId)})
+			if !ok {
+				logger.Error("trigger action fail, trigger: %+v, uid: %v", triggerConfig, player.PlayerId)
+			}
+		}
+	})
+}
@@ -0,0 +1,472 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 活动类型注册
+
+// IActivity 活动类型接口 不同类型的活动在此实现各自的玩法逻辑
+// 进度监听与奖励领取为所有活动共用的逻辑 不需要单独实现
+type IActivity interface {
+	// OnStart 玩家的活动开启
+	OnStart(g *Game, player *model.Player, activity *model.Activity)
+	// OnEnd 玩家的活动结束 活动数据会在此之后删除
+	OnEnd(g *Game, player *model.Player, activity *model.Activity)
+	// PacketActivityDetail 填充活动详细信息
+	PacketActivityDetail(g *Game, player *model.Player, activity *model.Activity, activityInfo *proto.ActivityInfo)
+}
+
+// 活动类型注册表 未注册的活动类型不会开启
+var activityTypeMap = map[uint32]IActivity{
+	constant.NEW_ACTIVITY_TYPE_GENERAL:  new(ActivityGeneral),
+	constant.NEW_ACTIVITY_TYPE_SALESMAN: new(ActivitySalesman),
+}
+
+// RegActivityType 注册活动类型
+func RegActivityType(activityType uint32, iActivity IActivity) {
+	activityTypeMap[activityType] = iActivity
+}
+
+func GetActivityByType(activityType uint32) IActivity {
+	return activityTypeMap[activityType]
+}
+
+// ActivityGeneral 通用活动 只包含进度监听与奖励领取
+type ActivityGeneral struct {
+}
+
+func (a *ActivityGeneral) OnStart(g *Game, player *model.Player, activity *model.Activity) {
+}
+
+func (a *ActivityGeneral) OnEnd(g *Game, player *model.Player, activity *model.Activity) {
+}
+
+func (a *ActivityGeneral) PacketActivityDetail(g *Game, player *model.Player, activity *model.Activity, activityInfo *proto.ActivityInfo) {
+}
+
+// 活动条件注册
+
+// ActivityCondHandler 判断活动条件是否满足
+type ActivityCondHandler func(g *Game, player *model.Player, activity *model.Activity, param []int32) bool
+
+// 活动条件注册表 未注册的条件类型视为不满足
+var activityCondHandlerMap = map[int32]ActivityCondHandler{
+	constant.NEW_ACTIVITY_COND_FINISH_QUEST:            ActivityCondFinishQuest,
+	constant.NEW_ACTIVITY_COND_SALESMAN_CAN_DELIVER:    ActivityCondSalesmanCanDeliver,
+	constant.NEW_ACTIVITY_COND_SALESMAN_CAN_GET_REWARD: ActivityCondSalesmanCanGetReward,
+}
+
+// RegActivityCondHandler 注册活动条件
+func RegActivityCondHandler(condType int32, handler ActivityCondHandler) {
+	activityCondHandlerMap[condType] = handler
+}
+
+// ActivityCondFinishQuest 完成任务
+func ActivityCondFinishQuest(g *Game, player *model.Player, activity *model.Activity, param []int32) bool {
+	if len(param) < 1 {
+		return false
+	}
+	quest := player.GetDbQuest().GetQuestById(uint32(param[0]))
+	return quest != nil && quest.State == constant.QUEST_STATE_FINISHED
+}
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) GetActivityScheduleReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	g.CheckPlayerActivity(player, now)
+
+	rsp := &proto.GetActivityScheduleRsp{
+		ActivityScheduleList: g.PacketActivityScheduleList(now),
+	}
+	g.SendMsg(cmd.GetActivityScheduleRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) GetActivityInfoReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.GetActivityInfoReq)
+
+	g.CheckPlayerActivity(player, clock.Now())
+	activityIdList := req.ActivityIdList
+	if len(activityIdList) == 0 {
+		activityIdList = g.GetPlayerActivityIdList(player)
+	}
+	activityInfoList := make([]*proto.ActivityInfo, 0)
+	for _, activityId := range activityIdList {
+		activityInfo := g.PacketActivityInfo(player, activityId)
+		if activityInfo == nil {
+			continue
+		}
+		activityInfoList = append(activityInfoList, activityInfo)
+	}
+
+	rsp := &proto.GetActivityInfoRsp{
+		ActivityInfoList: activityInfoList,
+	}
+	g.SendMsg(cmd.GetActivityInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) ActivityTakeWatcherRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.ActivityTakeWatcherRewardReq)
+
+	g.CheckPlayerActivity(player, clock.Now())
+	ret := g.TakeActivityWatcherReward(player, req.ActivityId, req.WatcherId, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER)
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.ActivityTakeWatcherRewardRsp, player, &proto.ActivityTakeWatcherRewardRsp{}, ret)
+		return
+	}
+
+	rsp := &proto.ActivityTakeWatcherRewardRsp{
+		ActivityId: req.ActivityId,
+		WatcherId:  req.WatcherId,
+	}
+	g.SendMsg(cmd.ActivityTakeWatcherRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) ActivityTakeWatcherRewardBatchReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.ActivityTakeWatcherRewardBatchReq)
+
+	g.CheckPlayerActivity(player, clock.Now())
+	watcherIdList := make([]uint32, 0)
+	itemMap := make(map[uint32]uint32)
+	for _, watcherId := range req.WatcherIdList {
+		ret := g.TakeActivityWatcherReward(player, req.ActivityId, watcherId, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER_BATCH)
+		if ret != proto.Retcode_RET_SUCC {
+			continue
+		}
+		watcherIdList = append(watcherIdList, watcherId)
+		watcherDataConfig := gdconf.GetNewActivityWatcherDataById(int32(watcherId))
+		rewardConfig := gdconf.GetRewardDataById(watcherDataConfig.RewardId)
+		if rewardConfig == nil {
+			continue
+		}
+		for itemId, count := range rewardConfig.RewardItemMap {
+			itemMap[itemId] += count
+		}
+	}
+	if len(watcherIdList) == 0 {
+		g.SendError(cmd.ActivityTakeWatcherRewardBatchRsp, player, &proto.ActivityTakeWatcherRewardBatchRsp{}, proto.Retcode_RET_ACTIVITY_WATCHER_REWARD_NOT_FINISHED)
+		return
+	}
+	itemList := make([]*proto.ItemParam, 0, len(itemMap))
+	for itemId, count := range itemMap {
+		itemList = append(itemList, &proto.ItemParam{ItemId: itemId, Count: count})
+	}
+
+	rsp := &proto.ActivityTakeWatcherRewardBatchRsp{
+		ActivityId:    req.ActivityId,
+		WatcherIdList: watcherIdList,
+		ItemList:      itemList,
+	}
+	g.SendMsg(cmd.ActivityTakeWatcherRewardBatchRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// GetOpenActivityScheduleMap 获取某个时间点开启中的活动排期 key:活动id
+func GetOpenActivityScheduleMap(now time.Time) map[uint32]*gdconf.NewActivityScheduleData {
+	scheduleMap := make(map[uint32]*gdconf.NewActivityScheduleData)
+	for _, scheduleDataConfig := range gdconf.GetNewActivityScheduleDataMap() {
+		if now.Before(scheduleDataConfig.BeginTime) || !now.Before(scheduleDataConfig.EndTime) {
+			continue
+		}
+		activityDataConfig := gdconf.GetNewActivityDataById(scheduleDataConfig.ActivityId)
+		if activityDataConfig == nil {
+			continue
+		}
+		if GetActivityByType(uint32(activityDataConfig.ActivityType)) == nil {
+			continue
+		}
+		// 同一个活动的排期时间重叠时取排期id较大的
+		exist, ok := scheduleMap[uint32(scheduleDataConfig.ActivityId)]
+		if ok && exist.ScheduleId > scheduleDataConfig.ScheduleId {
+			continue
+		}
+		scheduleMap[uint32(scheduleDataConfig.ActivityId)] = scheduleDataConfig
+	}
+	return scheduleMap
+}
+
+// CheckPlayerActivity 按排期开启和结束玩家的活动 返回新开启和已结束的活动id
+func (g *Game) CheckPlayerActivity(player *model.Player, now time.Time) (startList []uint32, endList []uint32) {
+	startList, endList = make([]uint32, 0), make([]uint32, 0)
+	dbActivity := player.GetDbActivity()
+	scheduleMap := GetOpenActivityScheduleMap(now)
+	for activityId, activity := range dbActivity.ActivityMap {
+		scheduleDataConfig, exist := scheduleMap[activityId]
+		if exist && uint32(scheduleDataConfig.ScheduleId) == activity.ScheduleId {
+			continue
+		}
+		activityDataConfig := gdconf.GetNewActivityDataById(int32(activityId))
+		if activityDataConfig != nil {
+			iActivity := GetActivityByType(uint32(activityDataConfig.ActivityType))
+			if iActivity != nil {
+				iActivity.OnEnd(g, player, activity)
+			}
+		}
+		dbActivity.EndActivity(activityId)
+		endList = append(endList, activityId)
+	}
+	for activityId, scheduleDataConfig := range scheduleMap {
+		activityDataConfig := gdconf.GetNewActivityDataById(int32(activityId))
+		watcherProgressMap := make(map[uint32]uint32)
+		for _, watcherId := range activityDataConfig.WatcherIdList {
+			watcherDataConfig := gdconf.GetNewActivityWatcherDataById(watcherId)
+			if watcherDataConfig == nil || watcherDataConfig.IsDisuse != 0 {
+				continue
+			}
+			watcherProgressMap[uint32(watcherId)] = uint32(watcherDataConfig.Progress)
+		}
+		activity, ok := dbActivity.StartActivity(activityId, uint32(scheduleDataConfig.ScheduleId), watcherProgressMap)
+		if !ok {
+			continue
+		}
+		GetActivityByType(uint32(activityDataConfig.ActivityType)).OnStart(g, player, activity)
+		startList = append(startList, activityId)
+	}
+	sort.Slice(startList, func(i, j int) bool { return startList[i] < startList[j] })
+	sort.Slice(endList, func(i, j int) bool { return endList[i] < endList[j] })
+	return startList, endList
+}
+
+// ActivityTick 玩家活动定时检查 活动开启或结束时通知客户端
+func (g *Game) ActivityTick(player *model.Player, now time.Time) {
+	startList, endList := g.CheckPlayerActivity(player, now)
+	if len(startList) == 0 && len(endList) == 0 {
+		return
+	}
+	g.SendMsg(cmd.ActivityScheduleInfoNotify, player.PlayerId, player.ClientSeq, g.PacketActivityScheduleInfoNotify(now))
+	for _, activityId := range startList {
+		g.SendActivityInfoNotify(player, activityId)
+	}
+}
+
+// GetPlayerActivityIdList 获取玩家开启中的活动id列表
+func (g *Game) GetPlayerActivityIdList(player *model.Player) []uint32 {
+	dbActivity := player.GetDbActivity()
+	activityIdList := make([]uint32, 0, len(dbActivity.ActivityMap))
+	for activityId := range dbActivity.ActivityMap {
+		activityIdList = append(activityIdList, activityId)
+	}
+	sort.Slice(activityIdList, func(i, j int) bool { return activityIdList[i] < activityIdList[j] })
+	return activityIdList
+}
+
+// TriggerActivityWatcher 触发活动进度监听 由击杀采集完成任务等游戏事件调用
+func (g *Game) TriggerActivityWatcher(player *model.Player, triggerType int32, param int32, count uint32) {
+	dbActivity := player.GetDbActivity()
+	for _, activityId := range g.GetPlayerActivityIdList(player) {
+		activity := dbActivity.GetActivity(activityId)
+		for _, watcherId := range AddActivityWatcherProgress(activity, triggerType, param, count) {
+			watcher := activity.GetWatcher(watcherId)
+			watcherDataConfig := gdconf.GetNewActivityWatcherDataById(int32(watcherId))
+			if watcher.IsFinish() && watcherDataConfig.IsAutoReward != 0 {
+				// 直接发奖的监听完成时自动领取
+				g.TakeActivityWatcherReward(player, activityId, watcherId, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER)
+				continue
+			}
+			g.SendMsg(cmd.ActivityUpdateWatcherNotify, player.PlayerId, player.ClientSeq, &proto.ActivityUpdateWatcherNotify{
+				ActivityId:  activityId,
+				WatcherInfo: g.PacketActivityWatcherInfo(watcher),
+			})
+		}
+	}
+}
+
+// AddActivityWatcherProgress 增加活动中匹配触发条件的监听进度 返回进度发生变化的监听id
+func AddActivityWatcherProgress(activity *model.Activity, triggerType int32, param int32, count uint32) []uint32 {
+	changeList := make([]uint32, 0)
+	for watcherId := range activity.WatcherMap {
+		watcherDataConfig := gdconf.GetNewActivityWatcherDataById(int32(watcherId))
+		if watcherDataConfig == nil || watcherDataConfig.TriggerType != triggerType {
+			continue
+		}
+		match := false
+		for _, triggerParam := range watcherDataConfig.TriggerParamList {
+			if triggerParam == param {
+				match = true
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		if activity.AddWatcherProgress(watcherId, count) {
+			changeList = append(changeList, watcherId)
+		}
+	}
+	sort.Slice(changeList, func(i, j int) bool { return changeList[i] < changeList[j] })
+	return changeList
+}
+
+// TakeActivityWatcherReward 领取活动进度监听奖励
+func (g *Game) TakeActivityWatcherReward(player *model.Player, activityId uint32, watcherId uint32, reason proto.ActionReasonType) proto.Retcode {
+	activity := player.GetDbActivity().GetActivity(activityId)
+	if activity == nil {
+		return proto.Retcode_RET_ACTIVITY_CLOSE
+	}
+	watcher := activity.GetWatcher(watcherId)
+	if watcher == nil || !watcher.IsFinish() {
+		return proto.Retcode_RET_ACTIVITY_WATCHER_REWARD_NOT_FINISHED
+	}
+	if watcher.IsTakenReward {
+		return proto.Retcode_RET_ACTIVITY_WATCHER_REWARD_TAKEN
+	}
+	watcherDataConfig := gdconf.GetNewActivityWatcherDataById(int32(watcherId))
+	if watcherDataConfig == nil {
+		logger.Error("get activity watcher data config is nil, watcherId: %v", watcherId)
+		return proto.Retcode_RET_SVR_ERROR
+	}
+	watcher.IsTakenReward = true
+	if watcherDataConfig.RewardId != 0 {
+		g.RewardItem(player.PlayerId, uint32(watcherDataConfig.RewardId), reason)
+	}
+	g.SendMsg(cmd.ActivityUpdateWatcherNotify, player.PlayerId, player.ClientSeq, &proto.ActivityUpdateWatcherNotify{
+		ActivityId:  activityId,
+		WatcherInfo: g.PacketActivityWatcherInfo(watcher),
+	})
+	return proto.Retcode_RET_SUCC
+}
+
+// CheckActivityCond 检查活动条件是否满足 条件类型未实现的视为不满足
+func (g *Game) CheckActivityCond(player *model.Player, activity *model.Activity, condId int32) bool {
+	condDataConfig := gdconf.GetNewActivityCondDataById(condId)
+	if condDataConfig == nil {
+		return false
+	}
+	// 没有配置条件的默认满足
+	if len(condDataConfig.CondList) == 0 {
+		return true
+	}
+	switch condDataConfig.CondCompose {
+	case constant.NEW_ACTIVITY_COND_COMPOSE_NONE, constant.NEW_ACTIVITY_COND_COMPOSE_AND:
+		for _, cond := range condDataConfig.CondList {
+			if !g.checkActivityCondItem(player, activity, cond) {
+				return false
+			}
+		}
+		return true
+	case constant.NEW_ACTIVITY_COND_COMPOSE_OR:
+		for _, cond := range condDataConfig.CondList {
+			if g.checkActivityCondItem(player, activity, cond) {
+				return true
+			}
+		}
+		return false
+	default:
+		logger.Error("not support activity cond compose: %v, condId: %v", condDataConfig.CondCompose, condId)
+		return false
+	}
+}
+
+func (g *Game) checkActivityCondItem(player *model.Player, activity *model.Activity, cond *gdconf.NewActivityCond) bool {
+	handler, exist := activityCondHandlerMap[cond.Type]
+	if !exist {
+		return false
+	}
+	return handler(g, player, activity, cond.Param)
+}
+
+// GetActivityMeetCondList 获取活动条件组中已满足的条件id列表
+func (g *Game) GetActivityMeetCondList(player *model.Player, activity *model.Activity) []uint32 {
+	meetCondList := make([]uint32, 0)
+	activityDataConfig := gdconf.GetNewActivityDataById(int32(activity.ActivityId))
+	if activityDataConfig == nil {
+		return meetCondList
+	}
+	for _, condGroupId := range activityDataConfig.CondGroupIdList {
+		condGroupDataConfig := gdconf.GetNewActivityCondGroupDataById(condGroupId)
+		if condGroupDataConfig == nil {
+			continue
+		}
+		for _, condId := range condGroupDataConfig.CondIdList {
+			if !g.CheckActivityCond(player, activity, condId) {
+				continue
+			}
+			meetCondList = append(meetCondList, uint32(condId))
+		}
+	}
+	return meetCondList
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketActivityScheduleList(now time.Time) []*proto.ActivityScheduleInfo {
+	activityScheduleList := make([]*proto.ActivityScheduleInfo, 0)
+	for activityId, scheduleDataConfig := range GetOpenActivityScheduleMap(now) {
+		activityScheduleList = append(activityScheduleList, &proto.ActivityScheduleInfo{
+			ScheduleId: uint32(scheduleDataConfig.ScheduleId),
+			IsOpen:     true,
+			ActivityId: activityId,
+			BeginTime:  uint32(scheduleDataConfig.BeginTime.Unix()),
+			EndTime:    uint32(scheduleDataConfig.EndTime.Unix()),
+		})
+	}
+	sort.Slice(activityScheduleList, func(i, j int) bool {
+		return activityScheduleList[i].ActivityId < activityScheduleList[j].ActivityId
+	})
+	return activityScheduleList
+}
+
+func (g *Game) PacketActivityScheduleInfoNotify(now time.Time) *proto.ActivityScheduleInfoNotify {
+	return &proto.ActivityScheduleInfoNotify{
+		ActivityScheduleList: g.PacketActivityScheduleList(now),
+	}
+}
+
+func (g *Game) PacketActivityInfo(player *model.Player, activityId uint32) *proto.ActivityInfo {
+	activity := player.GetDbActivity().GetActivity(activityId)
+	if activity == nil {
+		return nil
+	}
+	activityDataConfig := gdconf.GetNewActivityDataById(int32(activityId))
+	if activityDataConfig == nil {
+		return nil
+	}
+	scheduleDataConfig := gdconf.GetNewActivityScheduleDataById(int32(activity.ScheduleId))
+	if scheduleDataConfig == nil {
+		return nil
+	}
+	activityInfo := &proto.ActivityInfo{
+		ActivityId:      activityId,
+		ScheduleId:      activity.ScheduleId,
+		ActivityType:    uint32(activityDataConfig.ActivityType),
+		BeginTime:       uint32(scheduleDataConfig.BeginTime.Unix()),
+		EndTime:         uint32(scheduleDataConfig.EndTime.Unix()),
+		IsStarting:      true,
+		WatcherInfoList: make([]*proto.ActivityWatcherInfo, 0, len(activity.WatcherMap)),
+		MeetCondList:    g.GetActivityMeetCondList(player, activity),
+	}
+	for _, watcherId := range activityDataConfig.WatcherIdList {
+		watcher := activity.GetWatcher(uint32(watcherId))
+		if watcher == nil {
+			continue
+		}
+		activityInfo.WatcherInfoList = append(activityInfo.WatcherInfoList, g.PacketActivityWatcherInfo(watcher))
+	}
+	iActivity := GetActivityByType(uint32(activityDataConfig.ActivityType))
+	if iActivity != nil {
+		iActivity.PacketActivityDetail(g, player, activity, activityInfo)
+	}
+	return activityInfo
+}
+
+func (g *Game) PacketActivityWatcherInfo(watcher *model.ActivityWatcher) *proto.ActivityWatcherInfo {
+	return &proto.ActivityWatcherInfo{
+		WatcherId:     watcher.WatcherId,
+		CurProgress:   watcher.CurProgress,
+		TotalProgress: watcher.TotalProgress,
+		IsTakenReward: watcher.IsTakenReward,
+	}
+}
@@ -0,0 +1,243 @@
+package game
+
+import (
+	"time"
+
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/pkg/random"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// ActivitySalesman 商人活动 每天交付一次物品换取奖池中的随机奖励 交付时按概率解锁特殊奖励
+type ActivitySalesman struct {
+}
+
+func (a *ActivitySalesman) OnStart(g *Game, player *model.Player, activity *model.Activity) {
+	activity.Salesman = new(model.ActivitySalesman)
+}
+
+func (a *ActivitySalesman) OnEnd(g *Game, player *model.Player, activity *model.Activity) {
+	salesman := activity.Salesman
+	if salesman == nil || !salesman.IsSpecialRewardUnlock || salesman.IsTakenSpecialReward {
+		return
+	}
+	// 已解锁但未领取的特殊奖励在活动结束时直接发放
+	salesmanDataConfig := gdconf.GetActivitySalesmanDataByScheduleId(int32(activity.ScheduleId))
+	if salesmanDataConfig == nil || salesmanDataConfig.SpecialRewardId == 0 {
+		return
+	}
+	salesman.IsTakenSpecialReward = true
+	g.RewardItem(player.PlayerId, uint32(salesmanDataConfig.SpecialRewardId), proto.ActionReasonType_ACTION_REASON_SALESMAN_REWARD)
+}
+
+func (a *ActivitySalesman) PacketActivityDetail(g *Game, player *model.Player, activity *model.Activity, activityInfo *proto.ActivityInfo) {
+	salesman := activity.Salesman
+	if salesman == nil {
+		return
+	}
+	salesmanDataConfig := gdconf.GetActivitySalesmanDataByScheduleId(int32(activity.ScheduleId))
+	scheduleDataConfig := gdconf.GetNewActivityScheduleDataById(int32(activity.ScheduleId))
+	if salesmanDataConfig == nil || scheduleDataConfig == nil {
+		return
+	}
+	now := clock.Now()
+	isTodayHasDelivered := IsSalesmanTodayDelivered(scheduleDataConfig, salesman, now)
+	status := proto.SalesmanStatusType_SALESMAN_STATUS_STARTED
+	if isTodayHasDelivered {
+		status = proto.SalesmanStatusType_SALESMAN_STATUS_DELIVERED
+	}
+	activityInfo.Detail = &proto.ActivityInfo_SalesmanInfo{
+		SalesmanInfo: &proto.SalesmanActivityDetailInfo{
+			SpecialRewardPreviewId:  uint32(salesmanDataConfig.SpecialRewardPreviewId),
+			Status:                  status,
+			LastDeliverTime:         salesman.LastDeliverTime,
+			SelectedRewardIdMap:     make(map[uint32]uint32),
+			DeliverCount:            salesman.DeliverCount,
+			IsHasTakenSpecialReward: salesman.IsTakenSpecialReward,
+			DayIndex:                GetSalesmanDayIndex(scheduleDataConfig, now),
+			CondDayCount:            salesman.DeliverCount,
+			DayRewardId:             salesman.DayRewardId,
+			IsTodayHasDelivered:     isTodayHasDelivered,
+		},
+	}
+}
+
+// ActivityCondSalesmanCanDeliver 商人活动今日可交付
+func ActivityCondSalesmanCanDeliver(g *Game, player *model.Player, activity *model.Activity, param []int32) bool {
+	if activity.Salesman == nil {
+		return false
+	}
+	scheduleDataConfig := gdconf.GetNewActivityScheduleDataById(int32(activity.ScheduleId))
+	if scheduleDataConfig == nil {
+		return false
+	}
+	return !IsSalesmanTodayDelivered(scheduleDataConfig, activity.Salesman, clock.Now())
+}
+
+// ActivityCondSalesmanCanGetReward 商人活动可领取特殊奖励
+func ActivityCondSalesmanCanGetReward(g *Game, player *model.Player, activity *model.Activity, param []int32) bool {
+	if activity.Salesman == nil {
+		return false
+	}
+	return activity.Salesman.IsSpecialRewardUnlock && !activity.Salesman.IsTakenSpecialReward
+}
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) SalesmanDeliverItemReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.SalesmanDeliverItemReq)
+
+	now := clock.Now()
+	g.CheckPlayerActivity(player, now)
+	activity, ret := g.SalesmanDeliverItem(player, req.ScheduleId, now)
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.SalesmanDeliverItemRsp, player, &proto.SalesmanDeliverItemRsp{ScheduleId: req.ScheduleId}, ret)
+		return
+	}
+	g.SendActivityInfoNotify(player, activity.ActivityId)
+
+	rsp := &proto.SalesmanDeliverItemRsp{
+		ScheduleId: req.ScheduleId,
+	}
+	g.SendMsg(cmd.SalesmanDeliverItemRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) SalesmanTakeSpecialRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.SalesmanTakeSpecialRewardReq)
+
+	g.CheckPlayerActivity(player, clock.Now())
+	activity, ret := g.SalesmanTakeSpecialReward(player, req.ScheduleId)
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.SalesmanTakeSpecialRewardRsp, player, &proto.SalesmanTakeSpecialRewardRsp{ScheduleId: req.ScheduleId}, ret)
+		return
+	}
+	g.SendActivityInfoNotify(player, activity.ActivityId)
+
+	rsp := &proto.SalesmanTakeSpecialRewardRsp{
+		ScheduleId: req.ScheduleId,
+	}
+	g.SendMsg(cmd.SalesmanTakeSpecialRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// GetSalesmanDayIndex 获取商人活动开启的天数 从0开始
+func GetSalesmanDayIndex(scheduleDataConfig *gdconf.NewActivityScheduleData, now time.Time) uint32 {
+	if now.Before(scheduleDataConfig.BeginTime) {
+		return 0
+	}
+	return uint32(now.Sub(scheduleDataConfig.BeginTime) / (time.Hour * 24))
+}
+
+// IsSalesmanTodayDelivered 商人活动今日是否已交付 以活动开始时间为每日的分界
+func IsSalesmanTodayDelivered(scheduleDataConfig *gdconf.NewActivityScheduleData, salesman *model.ActivitySalesman, now time.Time) bool {
+	if salesman.DeliverCount == 0 {
+		return false
+	}
+	lastDeliverTime := time.Unix(int64(salesman.LastDeliverTime), 0)
+	return GetSalesmanDayIndex(scheduleDataConfig, lastDeliverTime) == GetSalesmanDayIndex(scheduleDataConfig, now)
+}
+
+// GetPlayerSalesmanActivity 按排期id获取玩家开启中的商人活动
+func (g *Game) GetPlayerSalesmanActivity(player *model.Player, scheduleId uint32) *model.Activity {
+	for _, activity := range player.GetDbActivity().ActivityMap {
+		if activity.ScheduleId == scheduleId && activity.Salesman != nil {
+			return activity
+		}
+	}
+	return nil
+}
+
+// SalesmanDeliverItem 商人活动交付当日所需物品 获得奖池中的随机奖励并按概率解锁特殊奖励
+func (g *Game) SalesmanDeliverItem(player *model.Player, scheduleId uint32, now time.Time) (*model.Activity, proto.Retcode) {
+	activity := g.GetPlayerSalesmanActivity(player, scheduleId)
+	if activity == nil {
+		return nil, proto.Retcode_RET_ACTIVITY_CLOSE
+	}
+	salesman := activity.Salesman
+	salesmanDataConfig := gdconf.GetActivitySalesmanDataByScheduleId(int32(scheduleId))
+	scheduleDataConfig := gdconf.GetNewActivityScheduleDataById(int32(scheduleId))
+	if salesmanDataConfig == nil || scheduleDataConfig == nil || len(salesmanDataConfig.DailyConfigIdList) == 0 {
+		logger.Error("get salesman data config is nil, scheduleId: %v", scheduleId)
+		return nil, proto.Retcode_RET_SVR_ERROR
+	}
+	if IsSalesmanTodayDelivered(scheduleDataConfig, salesman, now) {
+		return nil, proto.Retcode_RET_SALESMAN_ALREADY_DELIVERED
+	}
+	dayIndex := GetSalesmanDayIndex(scheduleDataConfig, now)
+	dailyConfigId := salesmanDataConfig.DailyConfigIdList[int(dayIndex)%len(salesmanDataConfig.DailyConfigIdList)]
+	dailyDataConfig := gdconf.GetActivitySalesmanDailyDataById(dailyConfigId)
+	if dailyDataConfig == nil {
+		logger.Error("get salesman daily data config is nil, dailyConfigId: %v", dailyConfigId)
+		return nil, proto.Retcode_RET_SVR_ERROR
+	}
+	// 先检查全部物品数量 避免只扣除了一部分
+	costItemList := make([]*ChangeItem, 0, len(dailyDataConfig.CostItemMap))
+	for itemId, count := range dailyDataConfig.CostItemMap {
+		if g.GetPlayerItemCount(player.PlayerId, itemId) < count {
+			return nil, proto.Retcode_RET_ITEM_COUNT_NOT_ENOUGH
+		}
+		costItemList = append(costItemList, &ChangeItem{ItemId: itemId, ChangeCount: count})
+	}
+	if !g.CostPlayerItem(player.PlayerId, costItemList) {
+		return nil, proto.Retcode_RET_ITEM_COUNT_NOT_ENOUGH
+	}
+	salesman.DeliverCount++
+	salesman.LastDeliverTime = uint32(now.Unix())
+	if len(salesmanDataConfig.RewardIdList) > 0 {
+		index := random.GetRandomInt32(0, int32(len(salesmanDataConfig.RewardIdList)-1))
+		salesman.DayRewardId = uint32(salesmanDataConfig.RewardIdList[index])
+		g.RewardItem(player.PlayerId, salesman.DayRewardId, proto.ActionReasonType_ACTION_REASON_SALESMAN_DELIVER_ITEM)
+	}
+	// 特殊奖励按交付次数取对应的概率 超出配置的次数取最后一个
+	if !salesman.IsSpecialRewardUnlock && len(salesmanDataConfig.SpecialProbList) > 0 {
+		index := int(salesman.DeliverCount) - 1
+		if index >= len(salesmanDataConfig.SpecialProbList) {
+			index = len(salesmanDataConfig.SpecialProbList) - 1
+		}
+		if random.GetRandomFloat32(0.0, 1.0) < salesmanDataConfig.SpecialProbList[index] {
+			salesman.IsSpecialRewardUnlock = true
+		}
+	}
+	return activity, proto.Retcode_RET_SUCC
+}
+
+// SalesmanTakeSpecialReward 商人活动领取已解锁的特殊奖励
+func (g *Game) SalesmanTakeSpecialReward(player *model.Player, scheduleId uint32) (*model.Activity, proto.Retcode) {
+	activity := g.GetPlayerSalesmanActivity(player, scheduleId)
+	if activity == nil {
+		return nil, proto.Retcode_RET_ACTIVITY_CLOSE
+	}
+	salesman := activity.Salesman
+	if !salesman.IsSpecialRewardUnlock {
+		return nil, proto.Retcode_RET_SALESMAN_REWARD_COUNT_NOT_ENOUGH
+	}
+	if salesman.IsTakenSpecialReward {
+		return nil, proto.Retcode_RET_REWARD_HAS_TAKEN
+	}
+	salesmanDataConfig := gdconf.GetActivitySalesmanDataByScheduleId(int32(scheduleId))
+	if salesmanDataConfig == nil {
+		logger.Error("get salesman data config is nil, scheduleId: %v", scheduleId)
+		return nil, proto.Retcode_RET_SVR_ERROR
+	}
+	salesman.IsTakenSpecialReward = true
+	if salesmanDataConfig.SpecialRewardId != 0 {
+		g.RewardItem(player.PlayerId, uint32(salesmanDataConfig.SpecialRewardId), proto.ActionReasonType_ACTION_REASON_SALESMAN_REWARD)
+	}
+	return activity, proto.Retcode_RET_SUCC
+}
+
+// SendActivityInfoNotify 通知客户端活动数据变化
+func (g *Game) SendActivityInfoNotify(player *model.Player, activityId uint32) {
+	activityInfo := g.PacketActivityInfo(player, activityId)
+	if activityInfo == nil {
+		return
+	}
+	g.SendMsg(cmd.ActivityInfoNotify, player.PlayerId, player.ClientSeq, &proto.ActivityInfoNotify{ActivityInfo: activityInfo})
+}
@@ -0,0 +1,252 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/proto"
+)
+
+func newTestActivityConfig(t *testing.T) {
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		NewActivityDataMap: map[int32]*gdconf.NewActivityData{
+			2001: {ActivityId: 2001, ActivityType: constant.NEW_ACTIVITY_TYPE_GENERAL, WatcherIdList: gdconf.IntArray{1, 2, 3, 4}},
+			// 未注册的活动类型不开启
+			2002: {ActivityId: 2002, ActivityType: 9999},
+		},
+		NewActivityScheduleDataMap: map[int32]*gdconf.NewActivityScheduleData{
+			1: {
+				ScheduleId: 1, ActivityId: 2001,
+				BeginTime: time.Date(2023, 6, 1, 4, 0, 0, 0, time.Local), EndTime: time.Date(2023, 6, 15, 4, 0, 0, 0, time.Local),
+			},
+			2: {
+				ScheduleId: 2, ActivityId: 2001,
+				BeginTime: time.Date(2023, 7, 1, 4, 0, 0, 0, time.Local), EndTime: time.Date(2023, 7, 15, 4, 0, 0, 0, time.Local),
+			},
+			3: {
+				ScheduleId: 3, ActivityId: 2002,
+				BeginTime: time.Date(2023, 6, 1, 4, 0, 0, 0, time.Local), EndTime: time.Date(2023, 7, 15, 4, 0, 0, 0, time.Local),
+			},
+		},
+		NewActivityWatcherDataMap: map[int32]*gdconf.NewActivityWatcherData{
+			1: {WatcherId: 1, TriggerType: constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101}, Progress: 3},
+			2: {WatcherId: 2, TriggerType: constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_GATHER, TriggerParamList: []int32{70950021, 70330041}, Progress: 2},
+			3: {WatcherId: 3, TriggerType: constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_FINISH_QUEST, TriggerParamList: []int32{4111309}, Progress: 1, IsAutoReward: 1},
+			// 已废弃的监听不计数
+			4: {WatcherId: 4, TriggerType: constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101}, Progress: 1, IsDisuse: 1},
+		},
+	})
+}
+
+func TestActivitySchedule(t *testing.T) {
+	newTestActivityConfig(t)
+	g := new(Game)
+	player := &model.Player{PlayerId: 10001}
+
+	startList, endList := g.CheckPlayerActivity(player, time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local))
+	if len(startList) != 0 || len(endList) != 0 {
+		t.Fatalf("activity should not open before schedule, start: %v, end: %v", startList, endList)
+	}
+
+	now := time.Date(2023, 6, 2, 0, 0, 0, 0, time.Local)
+	startList, _ = g.CheckPlayerActivity(player, now)
+	if !reflect.DeepEqual(startList, []uint32{2001}) {
+		t.Fatalf("activity start error, start: %v", startList)
+	}
+	activity := player.GetDbActivity().GetActivity(2001)
+	if activity.ScheduleId != 1 || len(activity.WatcherMap) != 3 {
+		t.Fatalf("activity data error, activity: %+v", activity)
+	}
+	if !reflect.DeepEqual(g.GetPlayerActivityIdList(player), []uint32{2001}) {
+		t.Fatalf("player activity list error: %v", g.GetPlayerActivityIdList(player))
+	}
+	// 重复检查不会重置活动
+	activity.AddWatcherProgress(1, 1)
+	if startList, endList = g.CheckPlayerActivity(player, now.Add(time.Hour)); len(startList) != 0 || len(endList) != 0 {
+		t.Fatalf("activity should not restart, start: %v, end: %v", startList, endList)
+	}
+
+	// 排期结束
+	_, endList = g.CheckPlayerActivity(player, time.Date(2023, 6, 15, 4, 0, 0, 0, time.Local))
+	if !reflect.DeepEqual(endList, []uint32{2001}) || player.GetDbActivity().GetActivity(2001) != nil {
+		t.Fatalf("activity end error, end: %v", endList)
+	}
+
+	// 新一期排期重新开启 进度重置
+	startList, _ = g.CheckPlayerActivity(player, time.Date(2023, 7, 2, 0, 0, 0, 0, time.Local))
+	activity = player.GetDbActivity().GetActivity(2001)
+	if !reflect.DeepEqual(startList, []uint32{2001}) || activity.ScheduleId != 2 || activity.GetWatcher(1).CurProgress != 0 {
+		t.Fatalf("activity restart error, activity: %+v", activity)
+	}
+}
+
+func TestActivityWatcher(t *testing.T) {
+	newTestActivityConfig(t)
+	g := new(Game)
+	player := &model.Player{PlayerId: 10001}
+	g.CheckPlayerActivity(player, time.Date(2023, 6, 2, 0, 0, 0, 0, time.Local))
+	activity := player.GetDbActivity().GetActivity(2001)
+
+	// 参数不匹配不计数
+	g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE, 21010201, 1)
+	if activity.GetWatcher(1).CurProgress != 0 {
+		t.Fatalf("watcher progress should not change")
+	}
+	for i := 0; i < 5; i++ {
+		g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE, 21010101, 1)
+	}
+	if activity.GetWatcher(1).CurProgress != 3 || !activity.GetWatcher(1).IsFinish() {
+		t.Fatalf("watcher progress error, progress: %v", activity.GetWatcher(1).CurProgress)
+	}
+	// 多个参数任意一个匹配即可
+	g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_GATHER, 70330041, 1)
+	if activity.GetWatcher(2).CurProgress != 1 {
+		t.Fatalf("gather watcher progress error, progress: %v", activity.GetWatcher(2).CurProgress)
+	}
+	if ret := g.TakeActivityWatcherReward(player, 2001, 2, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER); ret != proto.Retcode_RET_ACTIVITY_WATCHER_REWARD_NOT_FINISHED {
+		t.Fatalf("unfinished watcher reward should be refused, ret: %v", ret)
+	}
+	if ret := g.TakeActivityWatcherReward(player, 2001, 1, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("take watcher reward error, ret: %v", ret)
+	}
+	if ret := g.TakeActivityWatcherReward(player, 2001, 1, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER); ret != proto.Retcode_RET_ACTIVITY_WATCHER_REWARD_TAKEN {
+		t.Fatalf("watcher reward should be taken only once, ret: %v", ret)
+	}
+	if ret := g.TakeActivityWatcherReward(player, 2002, 1, proto.ActionReasonType_ACTION_REASON_ACTIVITY_WATCHER); ret != proto.Retcode_RET_ACTIVITY_CLOSE {
+		t.Fatalf("closed activity reward should be refused, ret: %v", ret)
+	}
+	// 直接发奖的监听完成时自动领取
+	g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_FINISH_QUEST, 4111309, 1)
+	if !activity.GetWatcher(3).IsTakenReward {
+		t.Fatalf("auto reward watcher not taken")
+	}
+
+	activityInfo := g.PacketActivityInfo(player, 2001)
+	if activityInfo == nil || len(activityInfo.WatcherInfoList) != 3 || !activityInfo.WatcherInfoList[0].IsTakenReward {
+		t.Fatalf("packet activity info error, activityInfo: %v", activityInfo)
+	}
+}
+
+func newTestSalesmanConfig(t *testing.T) {
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		NewActivityDataMap: map[int32]*gdconf.NewActivityData{
+			5003: {ActivityId: 5003, ActivityType: constant.NEW_ACTIVITY_TYPE_SALESMAN, CondGroupIdList: gdconf.IntArray{500301}},
+		},
+		NewActivityScheduleDataMap: map[int32]*gdconf.NewActivityScheduleData{
+			5003001: {
+				ScheduleId: 5003001, ActivityId: 5003,
+				BeginTime: time.Date(2023, 6, 1, 4, 0, 0, 0, time.Local), EndTime: time.Date(2023, 6, 15, 4, 0, 0, 0, time.Local),
+			},
+		},
+		NewActivityCondDataMap: map[int32]*gdconf.NewActivityCondData{
+			5003001: {CondId: 5003001, CondList: []*gdconf.NewActivityCond{{Type: constant.NEW_ACTIVITY_COND_SALESMAN_CAN_DELIVER}}},
+			5003002: {CondId: 5003002, CondList: []*gdconf.NewActivityCond{{Type: constant.NEW_ACTIVITY_COND_SALESMAN_CAN_GET_REWARD}}},
+			5003003: {CondId: 5003003, CondCompose: constant.NEW_ACTIVITY_COND_COMPOSE_OR, CondList: []*gdconf.NewActivityCond{
+				{Type: constant.NEW_ACTIVITY_COND_FINISH_QUEST, Param: []int32{4100101}},
+				{Type: constant.NEW_ACTIVITY_COND_SALESMAN_CAN_GET_REWARD},
+			}},
+			// 未实现的条件类型不满足
+			5003004: {CondId: 5003004, CondList: []*gdconf.NewActivityCond{{Type: 9999}}},
+			5003005: {CondId: 5003005},
+		},
+		NewActivityCondGroupDataMap: map[int32]*gdconf.NewActivityCondGroupData{
+			500301: {CondGroupId: 500301, CondIdList: gdconf.IntArray{5003001, 5003002, 5003003, 5003004, 5003005}},
+		},
+		SalesmanDataMap: map[int32]*gdconf.ActivitySalesmanData{
+			5003001: {
+				ScheduleId: 5003001, DailyConfigIdList: []int32{500301, 500302},
+				RewardIdList: gdconf.IntArray{470001}, SpecialRewardId: 470007, SpecialProbList: gdconf.FloatArray{0, 1},
+			},
+		},
+		SalesmanDailyDataMap: map[int32]*gdconf.ActivitySalesmanDailyData{
+			500301: {DailyConfigId: 500301, CostItemMap: map[uint32]uint32{constant.ITEM_ID_SCOIN: 100}},
+			500302: {DailyConfigId: 500302, CostItemMap: map[uint32]uint32{constant.ITEM_ID_SCOIN: 200}},
+		},
+		RewardDataMap: map[int32]*gdconf.RewardData{
+			470001: {RewardId: 470001, RewardItemMap: map[uint32]uint32{constant.ITEM_ID_HCOIN: 10}},
+			470007: {RewardId: 470007, RewardItemMap: map[uint32]uint32{constant.ITEM_ID_HCOIN: 100}},
+		},
+		ItemDataMap: map[int32]*gdconf.ItemData{
+			int32(constant.ITEM_ID_HCOIN): {ItemId: int32(constant.ITEM_ID_HCOIN), Type: constant.ITEM_TYPE_VIRTUAL},
+		},
+	})
+}
+
+func TestActivitySalesman(t *testing.T) {
+	newTestSalesmanConfig(t)
+	player := &model.Player{PlayerId: 10001, Online: true, PropMap: make(map[uint32]uint32)}
+	oldUserManager, oldPluginManager := USER_MANAGER, PLUGIN_MANAGER
+	USER_MANAGER = &UserManager{playerMap: map[uint32]*model.Player{player.PlayerId: player}}
+	PLUGIN_MANAGER = NewPluginManager()
+	now := time.Date(2023, 6, 2, 0, 0, 0, 0, time.Local)
+	clock.SetClock(clock.NewManualClock(now))
+	t.Cleanup(func() {
+		USER_MANAGER, PLUGIN_MANAGER = oldUserManager, oldPluginManager
+		clock.SetClock(nil)
+	})
+	g := &Game{endlessLoopCounter: make(map[int]uint64)}
+	g.CheckPlayerActivity(player, now)
+	activity := player.GetDbActivity().GetActivity(5003)
+	if activity == nil || activity.Salesman == nil {
+		t.Fatalf("salesman activity not start")
+	}
+	// 今日可交付 其他条件不满足
+	if meetCondList := g.GetActivityMeetCondList(player, activity); !reflect.DeepEqual(meetCondList, []uint32{5003001, 5003005}) {
+		t.Fatalf("meet cond list error: %v", meetCondList)
+	}
+
+	// 物品不足不能交付
+	if _, ret := g.SalesmanDeliverItem(player, 5003001, now); ret != proto.Retcode_RET_ITEM_COUNT_NOT_ENOUGH {
+		t.Fatalf("deliver without item should be refused, ret: %v", ret)
+	}
+	player.PropMap[constant.PLAYER_PROP_PLAYER_SCOIN] = 1000
+	if _, ret := g.SalesmanDeliverItem(player, 5003001, now); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("deliver error, ret: %v", ret)
+	}
+	salesman := activity.Salesman
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_SCOIN] != 900 || player.PropMap[constant.PLAYER_PROP_PLAYER_HCOIN] != 10 ||
+		salesman.DeliverCount != 1 || salesman.DayRewardId != 470001 || salesman.IsSpecialRewardUnlock {
+		t.Fatalf("deliver result error, prop: %v, salesman: %+v", player.PropMap, salesman)
+	}
+	// 每天只能交付一次
+	if _, ret := g.SalesmanDeliverItem(player, 5003001, now.Add(time.Hour*3)); ret != proto.Retcode_RET_SALESMAN_ALREADY_DELIVERED {
+		t.Fatalf("deliver twice a day should be refused, ret: %v", ret)
+	}
+	if meetCondList := g.GetActivityMeetCondList(player, activity); !reflect.DeepEqual(meetCondList, []uint32{5003005}) {
+		t.Fatalf("meet cond list error after deliver: %v", meetCondList)
+	}
+	if _, ret := g.SalesmanTakeSpecialReward(player, 5003001); ret != proto.Retcode_RET_SALESMAN_REWARD_COUNT_NOT_ENOUGH {
+		t.Fatalf("locked special reward should be refused, ret: %v", ret)
+	}
+
+	// 第二天换用第二天的交付配置 交付后解锁特殊奖励
+	now = now.Add(time.Hour * 24)
+	clock.SetClock(clock.NewManualClock(now))
+	if _, ret := g.SalesmanDeliverItem(player, 5003001, now); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("deliver next day error, ret: %v", ret)
+	}
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_SCOIN] != 700 || !salesman.IsSpecialRewardUnlock {
+		t.Fatalf("deliver next day result error, prop: %v, salesman: %+v", player.PropMap, salesman)
+	}
+	if meetCondList := g.GetActivityMeetCondList(player, activity); !reflect.DeepEqual(meetCondList, []uint32{5003002, 5003003, 5003005}) {
+		t.Fatalf("meet cond list error after unlock: %v", meetCondList)
+	}
+	detail := g.PacketActivityInfo(player, 5003).GetSalesmanInfo()
+	if detail == nil || detail.DayIndex != 1 || !detail.IsTodayHasDelivered || detail.Status != proto.SalesmanStatusType_SALESMAN_STATUS_DELIVERED {
+		t.Fatalf("packet salesman info error: %v", detail)
+	}
+	if _, ret := g.SalesmanTakeSpecialReward(player, 5003001); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("take special reward error, ret: %v", ret)
+	}
+	if _, ret := g.SalesmanTakeSpecialReward(player, 5003001); ret != proto.Retcode_RET_REWARD_HAS_TAKEN {
+		t.Fatalf("special reward should be taken only once, ret: %v", ret)
+	}
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_HCOIN] != 120 {
+		t.Fatalf("special reward not add, prop: %v", player.PropMap)
+	}
+}
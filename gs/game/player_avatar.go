@@ -26,6 +26,11 @@ func (g *Game) AvatarUpgradeReq(player *model.Player, payloadMsg pb.Message) {
 		g.SendError(cmd.AvatarUpgradeRsp, player, &proto.AvatarUpgradeRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarUpgradeRsp, player, &proto.AvatarUpgradeRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	// 获取经验书物品配置表
 	itemDataConfig := gdconf.GetItemDataById(int32(req.ItemId))
 	if itemDataConfig == nil {
@@ -108,6 +113,11 @@ func (g *Game) AvatarPromoteReq(player *model.Player, payloadMsg pb.Message) {
 		g.SendError(cmd.AvatarPromoteRsp, player, &proto.AvatarPromoteRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.Guid)
+		g.SendError(cmd.AvatarPromoteRsp, player, &proto.AvatarPromoteRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	// 获取角色配置表
 	avatarDataConfig := gdconf.GetAvatarDataById(int32(avatar.AvatarId))
 	if avatarDataConfig == nil {
@@ -198,6 +208,11 @@ func (g *Game) AvatarPromoteGetRewardReq(player *model.Player, payloadMsg pb.Mes
 		g.SendError(cmd.AvatarPromoteGetRewardRsp, player, &proto.AvatarPromoteGetRewardRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarPromoteGetRewardRsp, player, &proto.AvatarPromoteGetRewardRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	// 获取角色配置表
 	avatarDataConfig := gdconf.GetAvatarDataById(int32(avatar.AvatarId))
 	if avatarDataConfig == nil {
@@ -247,6 +262,11 @@ func (g *Game) AvatarWearFlycloakReq(player *model.Player, payloadMsg pb.Message
 		g.SendError(cmd.AvatarWearFlycloakRsp, player, &proto.AvatarWearFlycloakRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarWearFlycloakRsp, player, &proto.AvatarWearFlycloakRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 
 	// 确保要更换的风之翼已获得
 	exist := false
@@ -297,6 +317,11 @@ func (g *Game) AvatarChangeCostumeReq(player *model.Player, payloadMsg pb.Messag
 		g.SendError(cmd.AvatarChangeCostumeRsp, player, &proto.AvatarChangeCostumeRsp{}, proto.Retcode_RET_COSTUME_AVATAR_ERROR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarChangeCostumeRsp, player, &proto.AvatarChangeCostumeRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 
 	// 确保要更换的时装已获得
 	exist := false
@@ -353,6 +378,11 @@ func (g *Game) AvatarSkillUpgradeReq(player *model.Player, payloadMsg pb.Message
 		g.SendError(cmd.AvatarSkillUpgradeRsp, player, &proto.AvatarSkillUpgradeRsp{})
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarSkillUpgradeRsp, player, &proto.AvatarSkillUpgradeRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	skillLevel, exist := avatar.SkillLevelMap[req.AvatarSkillId]
 	if !exist {
 		g.SendError(cmd.AvatarSkillUpgradeRsp, player, &proto.AvatarSkillUpgradeRsp{})
@@ -424,6 +454,11 @@ func (g *Game) UnlockAvatarTalentReq(player *model.Player, payloadMsg pb.Message
 		g.SendError(cmd.UnlockAvatarTalentRsp, player, &proto.UnlockAvatarTalentRsp{})
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.UnlockAvatarTalentRsp, player, &proto.UnlockAvatarTalentRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 
 	ok = g.CostPlayerItem(player.PlayerId, []*ChangeItem{{ItemId: avatar.AvatarId - 10000000 + 1100, ChangeCount: 1}})
 	if !ok {
@@ -701,6 +736,8 @@ func (g *Game) ChangePlayerAvatarSkillDepot(userId uint32, avatarId uint32, chan
 	avatar.FightPropMap[uint32(fightPropEnergy.MaxEnergy)] = float32(avatarSkillDataConfig.CostElemVal)
 	avatar.FightPropMap[uint32(fightPropEnergy.CurEnergy)] = float32(avatar.CurrEnergy)
 	g.UpdatePlayerAvatarFightProp(player.PlayerId, avatarId)
+	// 元素类型改变 重新计算元素共鸣
+	g.UpdateWorldTeamResonance(world, true)
 }
 
 // AddPlayerAvatarHp 角色加血
@@ -911,44 +948,41 @@ func (g *Game) PacketAvatarInfo(avatar *model.Avatar) *proto.AvatarInfo {
 				Value: &proto.PropValue_Ival{Ival: int64(avatar.SatiationPenalty)},
 			},
 		},
-		LifeState:     uint32(avatar.LifeState),
-		EquipGuidList: object.ConvMapValueToList[uint64, uint64](avatar.EquipGuidMap),
-		FightPropMap:  avatar.FightPropMap,
-		SkillDepotId:  avatar.SkillDepotId,
-		FetterInfo: &proto.AvatarFetterInfo{
-			ExpLevel:                uint32(avatar.FetterLevel),
-			ExpNumber:               avatar.FetterExp,
-			FetterList:              nil,
-			RewardedFetterLevelList: []uint32{10},
-		},
+		LifeState:                uint32(avatar.LifeState),
+		EquipGuidList:            object.ConvMapValueToList[uint64, uint64](avatar.EquipGuidMap),
+		FightPropMap:             avatar.FightPropMap,
+		SkillDepotId:             avatar.SkillDepotId,
+		FetterInfo:               g.PacketAvatarFetterInfo(avatar),
 		SkillLevelMap:            avatar.SkillLevelMap,
 		TalentIdList:             avatar.TalentIdList,
 		InherentProudSkillList:   gdconf.GetAvatarInherentProudSkillList(avatar.SkillDepotId, avatar.Promote),
-		AvatarType:               1,
+		AvatarType:               uint32(proto.AvatarType_AVATAR_TYPE_FORMAL),
+		TeamResonanceList:        avatar.TeamResonanceList,
 		WearingFlycloakId:        avatar.FlyCloak,
 		CostumeId:                avatar.Costume,
 		BornTime:                 uint32(avatar.BornTime),
 		PendingPromoteRewardList: make([]uint32, 0, len(avatar.PromoteRewardMap)),
 	}
-	for _, v := range avatar.FetterList {
-		pbAvatar.FetterInfo.FetterList = append(pbAvatar.FetterInfo.FetterList, &proto.FetterData{
-			FetterId:    v,
-			FetterState: constant.FETTER_STATE_FINISH,
-		})
-	}
-	// 解锁全部资料
-	for _, v := range gdconf.GetFetterIdListByAvatarId(int32(avatar.AvatarId)) {
-		pbAvatar.FetterInfo.FetterList = append(pbAvatar.FetterInfo.FetterList, &proto.FetterData{
-			FetterId:    uint32(v),
-			FetterState: constant.FETTER_STATE_FINISH,
-		})
-	}
 	// 突破等级奖励
 	for promoteLevel, isTaken := range avatar.PromoteRewardMap {
 		if !isTaken {
 			pbAvatar.PendingPromoteRewardList = append(pbAvatar.PendingPromoteRewardList, promoteLevel)
 		}
 	}
+	// 试用角色装备不在背包中 随角色信息下发
+	if avatar.IsTrial() {
+		pbAvatar.AvatarType = uint32(proto.AvatarType_AVATAR_TYPE_TRIAL)
+		pbAvatar.TrialAvatarInfo = &proto.TrialAvatarInfo{
+			TrialAvatarId:  avatar.TrialAvatarId,
+			TrialEquipList: make([]*proto.Item, 0),
+		}
+		if avatar.EquipWeapon != nil {
+			pbAvatar.TrialAvatarInfo.TrialEquipList = append(pbAvatar.TrialAvatarInfo.TrialEquipList, g.PacketStoreItemChangeNotifyByWeapon(avatar.EquipWeapon).ItemList...)
+		}
+		for _, reliquary := range avatar.EquipReliquaryMap {
+			pbAvatar.TrialAvatarInfo.TrialEquipList = append(pbAvatar.TrialAvatarInfo.TrialEquipList, g.PacketStoreItemChangeNotifyByReliquary(reliquary).ItemList...)
+		}
+	}
 	return pbAvatar
 }
 
@@ -978,7 +1012,7 @@ func (g *Game) PacketAvatarDataNotify(player *model.Player) *proto.AvatarDataNot
 	dbTeam := player.GetDbTeam()
 	avatarDataNotify := &proto.AvatarDataNotify{
 		CurAvatarTeamId:   uint32(dbTeam.GetActiveTeamId()),
-		ChooseAvatarGuid:  dbAvatar.GetAvatarById(dbAvatar.MainCharAvatarId).Guid,
+		ChooseAvatarGuid:  dbAvatar.GetAvatarMap()[dbAvatar.MainCharAvatarId].Guid,
 		OwnedFlycloakList: dbAvatar.FlyCloakList,
 		// 角色衣装
 		OwnedCostumeList: dbAvatar.CostumeList,
@@ -992,7 +1026,7 @@ func (g *Game) PacketAvatarDataNotify(player *model.Player) *proto.AvatarDataNot
 	for teamIndex, team := range dbTeam.TeamList {
 		var teamAvatarGuidList []uint64 = nil
 		for _, avatarId := range team.GetAvatarIdList() {
-			teamAvatarGuidList = append(teamAvatarGuidList, dbAvatar.GetAvatarById(avatarId).Guid)
+			teamAvatarGuidList = append(teamAvatarGuidList, dbAvatar.GetAvatarMap()[avatarId].Guid)
 		}
 		avatarDataNotify.AvatarTeamMap[uint32(teamIndex)+1] = &proto.AvatarTeam{
 			AvatarGuidList: teamAvatarGuidList,
@@ -1,11 +1,11 @@
 package game
 
 import (
-	"time"
-
+	"hk4e/common/config"
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -14,6 +14,11 @@ import (
 	pb "google.golang.org/protobuf/proto"
 )
 
+const (
+	WorldLevelAdjustMinLimit  = 5     // 世界等级上限达到此等级后才能降低世界等级
+	WorldLevelAdjustCdDefault = 86400 // 默认调整世界等级冷却时间 单位秒
+)
+
 /************************************************** 接口请求 **************************************************/
 
 func (g *Game) PlayerSetPauseReq(player *model.Player, payloadMsg pb.Message) {
@@ -31,6 +36,12 @@ func (g *Game) SetPlayerPropReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.SetPlayerPropReq)
 	for _, propValue := range req.PropList {
 		logger.Debug("player set prop, key: %v, value: %v, uid: %v", propValue.Type, propValue.Val, player.PlayerId)
+		switch propValue.Type {
+		case constant.PLAYER_PROP_PLAYER_WORLD_LEVEL, constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT, constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD:
+			// 世界等级只能通过调整世界等级请求修改
+			logger.Error("player set world level prop not allow, key: %v, uid: %v", propValue.Type, player.PlayerId)
+			continue
+		}
 		player.PropMap[propValue.Type] = uint32(propValue.Val)
 	}
 	g.SendSucc(cmd.SetPlayerPropRsp, player, &proto.SetPlayerPropRsp{})
@@ -56,6 +67,39 @@ func (g *Game) SetOpenStateReq(player *model.Player, payloadMsg pb.Message) {
 	})
 }
 
+// AdjustWorldLevelReq 调整世界等级请求
+func (g *Game) AdjustWorldLevelReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.AdjustWorldLevelReq)
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
+		return
+	}
+	now := uint32(clock.Now().Unix())
+	retCode := g.AdjustPlayerWorldLevel(player, world, req.CurWorldLevel, req.ExpectWorldLevel, now)
+	if retCode != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.AdjustWorldLevelRsp, player, &proto.AdjustWorldLevelRsp{
+			CdOverTime:      player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD],
+			AfterWorldLevel: player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL],
+		}, retCode)
+		return
+	}
+	logger.Info("player adjust world level, worldLevel: %v, uid: %v", player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL], player.PlayerId)
+
+	g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(
+		player,
+		constant.PLAYER_PROP_PLAYER_WORLD_LEVEL,
+		constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT,
+		constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD,
+	))
+	g.WorldLevelNotify(world)
+
+	g.SendMsg(cmd.AdjustWorldLevelRsp, player.PlayerId, player.ClientSeq, &proto.AdjustWorldLevelRsp{
+		CdOverTime:      player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD],
+		AfterWorldLevel: player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL],
+	})
+}
+
 /************************************************** 游戏功能 **************************************************/
 
 // HandlePlayerExpAdd 玩家冒险阅历增加处理
@@ -87,39 +131,102 @@ func (g *Game) HandlePlayerExpAdd(userId uint32) {
 		player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL]++
 		player.PropMap[constant.PLAYER_PROP_PLAYER_EXP] -= uint32(playerLevelConfig.Exp)
 	}
-	// TODO 完善的世界等级机制
-	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] = g.GetWorldLevelByPlayerLevel(player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL])
+	oldWorldLevel := player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL]
+	g.UpdatePlayerWorldLevel(player)
 	// 更新玩家属性
 	g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(
 		player,
 		constant.PLAYER_PROP_PLAYER_LEVEL,
 		constant.PLAYER_PROP_PLAYER_EXP,
 		constant.PLAYER_PROP_PLAYER_WORLD_LEVEL,
+		constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT,
 	))
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] != oldWorldLevel {
+		world := WORLD_MANAGER.GetWorldById(player.WorldId)
+		if world != nil && world.GetOwner().PlayerId == player.PlayerId {
+			g.WorldLevelNotify(world)
+		}
+	}
 	g.TriggerOpenState(userId)
 }
 
-func (g *Game) GetWorldLevelByPlayerLevel(playerLevel uint32) uint32 {
-	if playerLevel < 20 {
-		return 0
-	} else if playerLevel < 25 {
-		return 1
-	} else if playerLevel < 30 {
-		return 2
-	} else if playerLevel < 35 {
-		return 3
-	} else if playerLevel < 40 {
-		return 4
-	} else if playerLevel < 45 {
-		return 5
-	} else if playerLevel < 50 {
-		return 6
-	} else if playerLevel < 55 {
-		return 7
-	} else if playerLevel < 60 {
-		return 8
-	} else {
-		return 8
+// UpdatePlayerWorldLevel 根据冒险等阶更新世界等级上限 已降低世界等级的保持低于上限一级
+func (g *Game) UpdatePlayerWorldLevel(player *model.Player) {
+	oldLimit := player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT]
+	isLowered := player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] < oldLimit
+	limit := uint32(gdconf.GetWorldLevelByPlayerLevel(int32(player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL])))
+	worldLevel := limit
+	if isLowered && limit > 0 {
+		worldLevel = limit - 1
+	}
+	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT] = limit
+	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] = worldLevel
+}
+
+// AdjustPlayerWorldLevel 调整世界等级 只能在自己的单人世界中降低一级或恢复 now单位秒
+func (g *Game) AdjustPlayerWorldLevel(player *model.Player, world *World, curWorldLevel uint32, expectWorldLevel uint32, now uint32) proto.Retcode {
+	if world.GetOwner().PlayerId != player.PlayerId {
+		return proto.Retcode_RET_MP_NOT_IN_MY_WORLD
+	}
+	if world.IsMultiplayerWorld() {
+		return proto.Retcode_RET_MP_IN_MP_MODE
+	}
+	worldLevel := player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL]
+	limit := player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT]
+	if curWorldLevel != worldLevel {
+		return proto.Retcode_RET_FAIL
+	}
+	if limit < WorldLevelAdjustMinLimit {
+		return proto.Retcode_RET_WORLD_LEVEL_ADJUST_MIN_LEVEL
+	}
+	// 只能降低到上限减一或恢复到上限
+	if expectWorldLevel == worldLevel || (expectWorldLevel != limit && expectWorldLevel != limit-1) {
+		return proto.Retcode_RET_FAIL
+	}
+	if now < player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD] {
+		return proto.Retcode_RET_WORLD_LEVEL_ADJUST_CD
+	}
+	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] = expectWorldLevel
+	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD] = now + GetWorldLevelAdjustCd()
+	return proto.Retcode_RET_SUCC
+}
+
+// GetWorldLevelAdjustCd 获取调整世界等级的冷却时间 秒
+func GetWorldLevelAdjustCd() uint32 {
+	cd := config.GetConfig().Hk4e.WorldLevelAdjustCd
+	if cd <= 0 {
+		cd = WorldLevelAdjustCdDefault
+	}
+	return uint32(cd)
+}
+
+// GetWorldLevelEntityLevel 获取世界等级修正后的实体等级 不低于世界等级的标准怪物等级
+func GetWorldLevelEntityLevel(configLevel int32, worldLevel uint32) uint8 {
+	level := configLevel
+	if worldLevel > 0 {
+		worldLevelDataConfig := gdconf.GetWorldLevelDataByLevel(int32(worldLevel))
+		if worldLevelDataConfig != nil && worldLevelDataConfig.MonsterLevel > level {
+			level = worldLevelDataConfig.MonsterLevel
+		}
+	}
+	if level < 1 {
+		level = 1
+	}
+	if level > 100 {
+		level = 100
+	}
+	return uint8(level)
+}
+
+// WorldLevelNotify 通知世界内的全部玩家房主的世界等级
+func (g *Game) WorldLevelNotify(world *World) {
+	worldDataNotify := &proto.WorldDataNotify{
+		WorldPropMap: make(map[uint32]*proto.PropValue),
+	}
+	// 世界等级
+	worldDataNotify.WorldPropMap[1] = g.PacketPropValue(1, world.GetWorldLevel())
+	for _, worldPlayer := range world.GetAllPlayer() {
+		g.SendMsg(cmd.WorldDataNotify, worldPlayer.PlayerId, worldPlayer.ClientSeq, worldDataNotify)
 	}
 }
 
@@ -215,7 +322,7 @@ func (g *Game) AddPlayerNameCard(userId uint32, nameCardId uint32) {
 func (g *Game) PacketPlayerDataNotify(player *model.Player) *proto.PlayerDataNotify {
 	ntf := &proto.PlayerDataNotify{
 		NickName:          player.NickName,
-		ServerTime:        uint64(time.Now().UnixMilli()),
+		ServerTime:        uint64(clock.Now().UnixMilli()),
 		IsFirstLoginToday: true,
 		RegionId:          1,
 		PropMap:           make(map[uint32]*proto.PropValue),
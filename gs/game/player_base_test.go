@@ -0,0 +1,101 @@
+package game
+
+import (
+	"testing"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+// 只有房主的世界 房主冒险等阶42 世界等级上限5
+func newTestWorldLevelWorld(t *testing.T) *World {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{WorldLevelAdjustCd: 3600}}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		PlayerLevelDataMap: map[int32]*gdconf.PlayerLevelData{
+			1:  {Level: 1},
+			20: {Level: 20, WorldLevel: 1},
+			40: {Level: 40, WorldLevel: 5},
+			45: {Level: 45, WorldLevel: 6},
+		},
+		WorldLevelDataMap: map[int32]*gdconf.WorldLevelData{
+			5: {WorldLevel: 5, MonsterLevel: 69},
+			6: {WorldLevel: 6, MonsterLevel: 80},
+		},
+	})
+	player := &model.Player{PlayerId: 10001, PropMap: map[uint32]uint32{constant.PLAYER_PROP_PLAYER_LEVEL: 42}}
+	return &World{owner: player}
+}
+
+func TestUpdatePlayerWorldLevel(t *testing.T) {
+	player := newTestWorldLevelWorld(t).GetOwner()
+	g := new(Game)
+	g.UpdatePlayerWorldLevel(player)
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] != 5 || player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT] != 5 {
+		t.Fatalf("world level error, propMap: %v", player.PropMap)
+	}
+	// 已降低世界等级 升级后仍低于上限一级
+	player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] = 4
+	player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] = 45
+	g.UpdatePlayerWorldLevel(player)
+	if player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL] != 5 || player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_LIMIT] != 6 {
+		t.Fatalf("lowered world level error, propMap: %v", player.PropMap)
+	}
+}
+
+func TestAdjustPlayerWorldLevel(t *testing.T) {
+	world := newTestWorldLevelWorld(t)
+	player := world.GetOwner()
+	g := new(Game)
+	g.UpdatePlayerWorldLevel(player)
+	now := uint32(1000000)
+
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 5, 3, now); retCode != proto.Retcode_RET_FAIL {
+		t.Fatalf("lower more than one level should fail, retCode: %v", retCode)
+	}
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 5, 4, now); retCode != proto.Retcode_RET_SUCC {
+		t.Fatalf("lower world level fail, retCode: %v", retCode)
+	}
+	if world.GetWorldLevel() != 4 || player.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL_ADJUST_CD] != now+3600 {
+		t.Fatalf("lower world level error, propMap: %v", player.PropMap)
+	}
+	// 冷却中不能恢复
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 4, 5, now+1); retCode != proto.Retcode_RET_WORLD_LEVEL_ADJUST_CD {
+		t.Fatalf("adjust world level during cd, retCode: %v", retCode)
+	}
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 4, 5, now+3600); retCode != proto.Retcode_RET_SUCC {
+		t.Fatalf("restore world level fail, retCode: %v", retCode)
+	}
+	if world.GetWorldLevel() != 5 {
+		t.Fatalf("restore world level error, worldLevel: %v", world.GetWorldLevel())
+	}
+	// 多人世界与低世界等级不能调整
+	world.multiplayer = true
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 5, 4, now+7200); retCode != proto.Retcode_RET_MP_IN_MP_MODE {
+		t.Fatalf("adjust world level in mp, retCode: %v", retCode)
+	}
+	world.multiplayer = false
+	player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] = 20
+	g.UpdatePlayerWorldLevel(player)
+	if retCode := g.AdjustPlayerWorldLevel(player, world, 1, 0, now+7200); retCode != proto.Retcode_RET_WORLD_LEVEL_ADJUST_MIN_LEVEL {
+		t.Fatalf("adjust world level below min limit, retCode: %v", retCode)
+	}
+}
+
+func TestWorldLevelEntityLevel(t *testing.T) {
+	newTestWorldLevelWorld(t)
+	if level := GetWorldLevelEntityLevel(30, 0); level != 30 {
+		t.Fatalf("world level 0 entity level error, level: %v", level)
+	}
+	if level := GetWorldLevelEntityLevel(30, 6); level != 80 {
+		t.Fatalf("world level 6 entity level error, level: %v", level)
+	}
+	if level := GetWorldLevelEntityLevel(90, 5); level != 90 {
+		t.Fatalf("high config level should not lower, level: %v", level)
+	}
+}
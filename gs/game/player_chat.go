@@ -1,10 +1,10 @@
 package game
 
 import (
-	"time"
-
 	"hk4e/common/mq"
+	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -99,8 +99,8 @@ func (g *Game) PrivateChatReq(player *model.Player, payloadMsg pb.Message) {
 			g.SendError(cmd.PrivateChatRsp, player, &proto.PrivateChatRsp{}, proto.Retcode_RET_PRIVATE_CHAT_CONTENT_TOO_LONG)
 			return
 		}
-		// 发送私聊文本消息
-		g.SendPrivateChat(player, targetUid, text)
+		// 发送私聊文本消息 敏感词打码
+		g.SendPrivateChat(player, targetUid, g.FilterSensitiveWord(text))
 		// 输入命令 会检测是否为命令的
 		COMMAND_MANAGER.PlayerInputCommand(player, targetUid, text)
 	case *proto.PrivateChatReq_Icon:
@@ -136,11 +136,22 @@ func (g *Game) ReadPrivateChatReq(player *model.Player, payloadMsg pb.Message) {
 
 func (g *Game) PlayerChatReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.PlayerChatReq)
+
+	// 触发事件
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreChat, &PluginEventPreChat{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		Req:         req,
+	}) {
+		g.SendError(cmd.PlayerChatRsp, player, &proto.PlayerChatRsp{})
+		return
+	}
+
 	channelId := req.ChannelId
 	chatInfo := req.ChatInfo
 
 	sendChatInfo := &proto.ChatInfo{
-		Time:    uint32(time.Now().Unix()),
+		Time:    uint32(clock.Now().Unix()),
 		Uid:     player.PlayerId,
 		Content: nil,
 	}
@@ -151,7 +162,7 @@ func (g *Game) PlayerChatReq(player *model.Player, payloadMsg pb.Message) {
 			return
 		}
 		sendChatInfo.Content = &proto.ChatInfo_Text{
-			Text: text,
+			Text: g.FilterSensitiveWord(text),
 		}
 	case *proto.ChatInfo_Icon:
 		icon := chatInfo.Content.(*proto.ChatInfo_Icon).Icon
@@ -168,7 +179,7 @@ func (g *Game) PlayerChatReq(player *model.Player, payloadMsg pb.Message) {
 		return
 	}
 	world.AddChat(sendChatInfo)
-	chatMsg := g.ConvChatInfoToChatMsg(chatInfo)
+	chatMsg := g.ConvChatInfoToChatMsg(sendChatInfo)
 	chatMsg.IsDelete = true
 
 	ntf := &proto.PlayerChatNotify{
@@ -195,11 +206,33 @@ func (g *Game) GetChatEmojiCollectionReq(player *model.Player, payloadMsg pb.Mes
 
 /************************************************** 游戏功能 **************************************************/
 
+const (
+	SensitiveWordMask = '*' // 敏感词打码字符
+)
+
+// FilterSensitiveWord 聊天文本敏感词打码
+func (g *Game) FilterSensitiveWord(text string) string {
+	filter := gdconf.GetSensitiveWordFilter()
+	if filter == nil {
+		return text
+	}
+	return filter.Replace(text, SensitiveWordMask)
+}
+
+// HasSensitiveWord 文本是否含有敏感词
+func (g *Game) HasSensitiveWord(text string) bool {
+	filter := gdconf.GetSensitiveWordFilter()
+	if filter == nil {
+		return false
+	}
+	return filter.Contains(text)
+}
+
 // SendPrivateChat 发送私聊文本消息给玩家
 func (g *Game) SendPrivateChat(player *model.Player, targetUid uint32, content any) {
 	chatMsg := &model.ChatMsg{
 		Sequence: 0,
-		Time:     uint32(time.Now().Unix()),
+		Time:     uint32(clock.Now().Unix()),
 		ToUid:    targetUid,
 		Uid:      player.PlayerId,
 		IsRead:   false,
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"sort"
+
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 需要同步给客户端的图鉴类型
+var CodexTypeList = []proto.CodexType{
+	proto.CodexType_CODEX_QUEST,
+	proto.CodexType_CODEX_WEAPON,
+	proto.CodexType_CODEX_ANIMAL,
+	proto.CodexType_CODEX_MATERIAL,
+	proto.CodexType_CODEX_BOOKS,
+	proto.CodexType_CODEX_VIEW,
+}
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) QueryCodexMonsterBeKilledNumReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.QueryCodexMonsterBeKilledNumReq)
+
+	dbCodex := player.GetDbCodex()
+	rsp := &proto.QueryCodexMonsterBeKilledNumRsp{
+		CodexIdList:       make([]uint32, 0, len(req.CodexIdList)),
+		BeCapturedNumList: make([]uint32, 0, len(req.CodexIdList)),
+		BeKilledNumList:   make([]uint32, 0, len(req.CodexIdList)),
+	}
+	for _, codexId := range req.CodexIdList {
+		rsp.CodexIdList = append(rsp.CodexIdList, codexId)
+		rsp.BeCapturedNumList = append(rsp.BeCapturedNumList, 0)
+		rsp.BeKilledNumList = append(rsp.BeKilledNumList, dbCodex.GetMonsterKill(codexId))
+	}
+	g.SendMsg(cmd.QueryCodexMonsterBeKilledNumRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) ViewCodexReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.ViewCodexReq)
+
+	dbCodex := player.GetDbCodex()
+	typeDataList := make([]*proto.CodexTypeData, 0)
+	for _, typeData := range req.TypeDataList {
+		codexIdList := make([]uint32, 0)
+		haveViewedList := make([]bool, 0)
+		for _, codexId := range typeData.CodexIdList {
+			if !dbCodex.ViewCodex(uint32(typeData.Type), codexId) {
+				continue
+			}
+			codexIdList = append(codexIdList, codexId)
+			haveViewedList = append(haveViewedList, true)
+		}
+		typeDataList = append(typeDataList, &proto.CodexTypeData{
+			CodexIdList:    codexIdList,
+			Type:           typeData.Type,
+			HaveViewedList: haveViewedList,
+		})
+	}
+
+	rsp := &proto.ViewCodexRsp{
+		TypeDataList: typeDataList,
+	}
+	g.SendMsg(cmd.ViewCodexRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// UnlockPlayerCodex 解锁图鉴 新解锁时通知客户端
+func (g *Game) UnlockPlayerCodex(player *model.Player, codexType proto.CodexType, codexId uint32) {
+	dbCodex := player.GetDbCodex()
+	if !dbCodex.UnlockCodex(uint32(codexType), codexId) {
+		return
+	}
+	logger.Debug("unlock codex, type: %v, codexId: %v, uid: %v", codexType, codexId, player.PlayerId)
+	g.SendMsg(cmd.CodexDataUpdateNotify, player.PlayerId, player.ClientSeq, &proto.CodexDataUpdateNotify{
+		Id:   codexId,
+		Type: codexType,
+	})
+}
+
+// CodexObtainItem 首次获得物品解锁图鉴
+func (g *Game) CodexObtainItem(player *model.Player, itemId uint32) {
+	weaponCodexDataConfig := gdconf.GetWeaponCodexDataByWeaponId(int32(itemId))
+	if weaponCodexDataConfig != nil {
+		g.UnlockPlayerCodex(player, proto.CodexType_CODEX_WEAPON, uint32(weaponCodexDataConfig.CodexId))
+	}
+	materialCodexDataConfig := gdconf.GetMaterialCodexDataByItemId(int32(itemId))
+	if materialCodexDataConfig != nil {
+		g.UnlockPlayerCodex(player, proto.CodexType_CODEX_MATERIAL, uint32(materialCodexDataConfig.CodexId))
+	}
+	booksCodexDataConfig := gdconf.GetBooksCodexDataByItemId(int32(itemId))
+	if booksCodexDataConfig != nil {
+		g.UnlockPlayerCodex(player, proto.CodexType_CODEX_BOOKS, uint32(booksCodexDataConfig.CodexId))
+	}
+}
+
+// CodexKillMonster 击杀怪物解锁生物志并记录击杀数量
+func (g *Game) CodexKillMonster(player *model.Player, monsterId uint32) {
+	monsterDataConfig := gdconf.GetMonsterDataById(int32(monsterId))
+	if monsterDataConfig == nil {
+		return
+	}
+	animalCodexDataConfig := gdconf.GetAnimalCodexDataByDescribeId(monsterDataConfig.DescribeId)
+	if animalCodexDataConfig == nil {
+		return
+	}
+	codexId := uint32(animalCodexDataConfig.CodexId)
+	player.GetDbCodex().AddMonsterKill(codexId)
+	g.UnlockPlayerCodex(player, proto.CodexType_CODEX_ANIMAL, codexId)
+}
+
+// CodexFinishChapter 完成任务章节解锁任务图鉴
+func (g *Game) CodexFinishChapter(player *model.Player, chapterId uint32) {
+	for _, questCodexDataConfig := range gdconf.GetQuestCodexDataListByChapterId(int32(chapterId)) {
+		g.UnlockPlayerCodex(player, proto.CodexType_CODEX_QUEST, uint32(questCodexDataConfig.CodexId))
+	}
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketCodexDataFullNotify(player *model.Player) *proto.CodexDataFullNotify {
+	dbCodex := player.GetDbCodex()
+	ntf := &proto.CodexDataFullNotify{
+		TypeDataList: make([]*proto.CodexTypeData, 0, len(CodexTypeList)),
+	}
+	for _, codexType := range CodexTypeList {
+		codexIdMap := dbCodex.CodexMap[uint32(codexType)]
+		codexIdList := make([]uint32, 0, len(codexIdMap))
+		for codexId := range codexIdMap {
+			codexIdList = append(codexIdList, codexId)
+		}
+		sort.Slice(codexIdList, func(i, j int) bool {
+			return codexIdList[i] < codexIdList[j]
+		})
+		haveViewedList := make([]bool, 0, len(codexIdList))
+		for _, codexId := range codexIdList {
+			haveViewedList = append(haveViewedList, codexIdMap[codexId])
+		}
+		ntf.TypeDataList = append(ntf.TypeDataList, &proto.CodexTypeData{
+			CodexIdList:    codexIdList,
+			Type:           codexType,
+			HaveViewedList: haveViewedList,
+		})
+	}
+	return ntf
+}
@@ -4,10 +4,10 @@ import (
 	"math"
 	"reflect"
 	"strings"
-	"time"
 
 	"hk4e/common/constant"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -19,7 +19,7 @@ func (g *Game) PingReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.PingReq)
 
 	player.ClientTime = req.ClientTime
-	now := uint32(time.Now().Unix())
+	now := uint32(clock.Now().Unix())
 	// 客户端与服务器时间相差太过严重
 	if math.Abs(math.Max(float64(now), float64(player.ClientTime))-math.Min(float64(now), float64(player.ClientTime))) > 600.0 {
 		logger.Error("abs of client time and server time above 600s, clientTime: %v, uid: %v", player.ClientTime, player.PlayerId)
@@ -61,7 +61,7 @@ func (g *Game) ClientRttNotify(userId uint32, clientRtt uint32) {
 
 func (g *Game) ServerAnnounceNotify(announceId uint32, announceMsg string) {
 	for _, onlinePlayer := range USER_MANAGER.GetAllOnlineUserList() {
-		now := uint32(time.Now().Unix())
+		now := uint32(clock.Now().Unix())
 		serverAnnounceNotify := &proto.ServerAnnounceNotify{
 			AnnounceDataList: []*proto.AnnounceData{{
 				ConfigId:              announceId,
@@ -228,7 +228,7 @@ func (g *Game) PlayerTimeNotify(world *World) {
 		playerTimeNotify := &proto.PlayerTimeNotify{
 			IsPaused:   player.Pause,
 			PlayerTime: uint64(player.TotalOnlineTime),
-			ServerTime: uint64(time.Now().UnixMilli()),
+			ServerTime: uint64(clock.Now().UnixMilli()),
 		}
 		g.SendMsg(cmd.PlayerTimeNotify, player.PlayerId, 0, playerTimeNotify)
 	}
@@ -59,6 +59,11 @@ func (g *Game) TakeoffEquipReq(player *model.Player, payloadMsg pb.Message) {
 		g.SendError(cmd.TakeoffEquipRsp, player, &proto.TakeoffEquipRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.TakeoffEquipRsp, player, &proto.TakeoffEquipRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	// 确保角色已装备指定位置的圣遗物
 	reliquary, ok := avatar.EquipReliquaryMap[uint8(req.Slot)]
 	if !ok {
@@ -93,6 +98,11 @@ func (g *Game) WearEquipReq(player *model.Player, payloadMsg pb.Message) {
 		g.SendError(cmd.WearEquipRsp, player, &proto.WearEquipRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
 		return
 	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.WearEquipRsp, player, &proto.WearEquipRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	// 获取角色配置表
 	avatarConfig := gdconf.GetAvatarDataById(int32(avatar.AvatarId))
 	if avatarConfig == nil {
@@ -0,0 +1,153 @@
+package game
+
+import (
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	FetterExpTeamPerMinute  = 1  // 在队伍中每分钟获得的好感度经验
+	FetterExpDungeonSuccess = 15 // 地牢通关结算获得的好感度经验 只在结算时发放一次
+	FetterExpResinCost      = 20 // 领取地脉之花奖励每消耗多少树脂
+	FetterExpResinReward    = 15 // 获得多少好感度经验
+)
+
+/************************************************** 接口请求 **************************************************/
+
+// AvatarFetterLevelRewardReq 角色好感度等级奖励领取请求
+func (g *Game) AvatarFetterLevelRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.AvatarFetterLevelRewardReq)
+	avatar, ok := player.GameObjectGuidMap[req.AvatarGuid].(*model.Avatar)
+	if !ok {
+		logger.Error("avatar error, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarFetterLevelRewardRsp, player, &proto.AvatarFetterLevelRewardRsp{}, proto.Retcode_RET_CAN_NOT_FIND_AVATAR)
+		return
+	}
+	if avatar.IsTrial() {
+		logger.Error("trial avatar can not be changed, avatarGuid: %v", req.AvatarGuid)
+		g.SendError(cmd.AvatarFetterLevelRewardRsp, player, &proto.AvatarFetterLevelRewardRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
+	fetterCharacterCardConfig := gdconf.GetFetterCharacterCardDataByAvatarId(int32(avatar.AvatarId))
+	if fetterCharacterCardConfig == nil || uint32(fetterCharacterCardConfig.FetterLevel) != req.FetterLevel {
+		g.SendError(cmd.AvatarFetterLevelRewardRsp, player, &proto.AvatarFetterLevelRewardRsp{})
+		return
+	}
+	if uint32(avatar.FetterLevel) < req.FetterLevel {
+		g.SendError(cmd.AvatarFetterLevelRewardRsp, player, &proto.AvatarFetterLevelRewardRsp{}, proto.Retcode_RET_FETTER_REWARD_LEVEL_NOT_ENOUGH)
+		return
+	}
+	if avatar.IsFetterRewarded(req.FetterLevel) {
+		g.SendError(cmd.AvatarFetterLevelRewardRsp, player, &proto.AvatarFetterLevelRewardRsp{}, proto.Retcode_RET_FETTER_REWARD_ALREADY_GOT)
+		return
+	}
+	g.RewardAvatarFetterLevel(player, avatar)
+
+	rsp := &proto.AvatarFetterLevelRewardRsp{
+		AvatarGuid:  req.AvatarGuid,
+		RewardId:    uint32(fetterCharacterCardConfig.RewardId),
+		FetterLevel: req.FetterLevel,
+	}
+	g.SendMsg(cmd.AvatarFetterLevelRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// AddPlayerTeamFetterExp 当前队伍中的角色增加好感度经验
+func (g *Game) AddPlayerTeamFetterExp(player *model.Player, exp uint32) {
+	activeTeam := player.GetDbTeam().GetActiveTeam()
+	if activeTeam == nil {
+		return
+	}
+	g.AddPlayerAvatarFetterExp(player, activeTeam.GetAvatarIdList(), exp)
+}
+
+// AddPlayerAvatarFetterExp 角色增加好感度经验
+func (g *Game) AddPlayerAvatarFetterExp(player *model.Player, avatarIdList []uint32, exp uint32) {
+	if exp == 0 {
+		return
+	}
+	maxLevel := uint8(gdconf.GetAvatarFettersMaxLevel())
+	dbAvatar := player.GetDbAvatar()
+	ntf := &proto.AvatarFetterDataNotify{
+		FetterInfoMap: make(map[uint64]*proto.AvatarFetterInfo),
+	}
+	for _, avatarId := range avatarIdList {
+		avatar := dbAvatar.GetAvatarById(avatarId)
+		if avatar == nil {
+			continue
+		}
+		if avatar.FetterLevel >= maxLevel {
+			continue
+		}
+		avatar.FetterExp += exp
+		for avatar.FetterLevel < maxLevel {
+			avatarFettersLevelConfig := gdconf.GetAvatarFettersLevelDataByLevel(int32(avatar.FetterLevel))
+			if avatarFettersLevelConfig == nil {
+				break
+			}
+			// 角色好感度经验小于升级所需的经验则跳出循环
+			if avatar.FetterExp < uint32(avatarFettersLevelConfig.Exp) {
+				break
+			}
+			avatar.FetterExp -= uint32(avatarFettersLevelConfig.Exp)
+			avatar.FetterLevel++
+		}
+		if avatar.FetterLevel >= maxLevel {
+			// 满级溢出的经验处理
+			avatar.FetterExp = 0
+			g.RewardAvatarFetterLevel(player, avatar)
+		}
+		ntf.FetterInfoMap[avatar.Guid] = g.PacketAvatarFetterInfo(avatar)
+	}
+	if len(ntf.FetterInfoMap) == 0 {
+		return
+	}
+	g.SendMsg(cmd.AvatarFetterDataNotify, player.PlayerId, player.ClientSeq, ntf)
+}
+
+// RewardAvatarFetterLevel 发放角色好感度等级名片奖励
+func (g *Game) RewardAvatarFetterLevel(player *model.Player, avatar *model.Avatar) {
+	fetterCharacterCardConfig := gdconf.GetFetterCharacterCardDataByAvatarId(int32(avatar.AvatarId))
+	if fetterCharacterCardConfig == nil {
+		return
+	}
+	fetterLevel := uint32(fetterCharacterCardConfig.FetterLevel)
+	if uint32(avatar.FetterLevel) < fetterLevel || avatar.IsFetterRewarded(fetterLevel) {
+		return
+	}
+	avatar.FetterRewardList = append(avatar.FetterRewardList, fetterLevel)
+	// 名片道具获得时自动使用 解锁到名片列表
+	g.RewardItem(player.PlayerId, uint32(fetterCharacterCardConfig.RewardId), proto.ActionReasonType_ACTION_REASON_FETTER_LEVEL_REWARD)
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketAvatarFetterInfo(avatar *model.Avatar) *proto.AvatarFetterInfo {
+	avatarFetterInfo := &proto.AvatarFetterInfo{
+		ExpLevel:                uint32(avatar.FetterLevel),
+		ExpNumber:               avatar.FetterExp,
+		FetterList:              make([]*proto.FetterData, 0),
+		RewardedFetterLevelList: avatar.FetterRewardList,
+	}
+	for _, v := range avatar.FetterList {
+		avatarFetterInfo.FetterList = append(avatarFetterInfo.FetterList, &proto.FetterData{
+			FetterId:    v,
+			FetterState: constant.FETTER_STATE_FINISH,
+		})
+	}
+	// 解锁全部资料
+	for _, v := range gdconf.GetFetterIdListByAvatarId(int32(avatar.AvatarId)) {
+		avatarFetterInfo.FetterList = append(avatarFetterInfo.FetterList, &proto.FetterData{
+			FetterId:    uint32(v),
+			FetterState: constant.FETTER_STATE_FINISH,
+		})
+	}
+	return avatarFetterInfo
+}
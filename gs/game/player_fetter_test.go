@@ -0,0 +1,128 @@
+package game
+
+import (
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+)
+
+const (
+	testDungeonId        = 1001
+	testDungeonGroupId   = 220017001
+	testDungeonMonsterId = 21010101
+)
+
+func newTestFetterPlayer(playerId uint32, sceneId uint32) *model.Player {
+	player := &model.Player{PlayerId: playerId, SceneId: sceneId}
+	dbAvatar := player.GetDbAvatar()
+	dbAvatar.AvatarMap[10000021] = &model.Avatar{AvatarId: 10000021, FightPropMap: make(map[uint32]float32)}
+	player.GetDbTeam().GetActiveTeam().SetAvatarIdList([]uint32{10000021})
+	return player
+}
+
+// 地牢场景内有房主与一名客人 另一名客人在大世界 通关条件1为击杀group内全部怪物 通关条件2为击杀3只怪物或击杀指定怪物
+func newTestDungeonScene(t *testing.T, passCond int32) (*testAbilityScene, *Game) {
+	s := newTestAbilityScene(t)
+	gdconf.CONF.AvatarFettersLevelDataMap = map[int32]*gdconf.AvatarFettersLevelData{
+		0:  {Level: 0, Exp: 1000},
+		10: {Level: 10},
+	}
+	gdconf.CONF.DungeonDataMap = map[int32]*gdconf.DungeonData{
+		testDungeonId: {DungeonId: testDungeonId, SceneId: int32(s.scene.id), PassCond: passCond},
+	}
+	gdconf.CONF.DungeonPassDataMap = map[int32]*gdconf.DungeonPassData{
+		1: {Id: 1, CondList: []*gdconf.DungeonPassCond{{Type: constant.DUNGEON_COND_KILL_GROUP_MONSTER, Param: []int32{testDungeonGroupId}}}},
+		2: {Id: 2, CondCompose: constant.QUEST_LOGIC_TYPE_OR, CondList: []*gdconf.DungeonPassCond{
+			{Type: constant.DUNGEON_COND_KILL_MONSTER_COUNT, Param: []int32{3}},
+			{Type: constant.DUNGEON_COND_KILL_MONSTER, Param: []int32{testDungeonMonsterId}},
+		}},
+	}
+	host := newTestFetterPlayer(s.player.PlayerId, s.scene.id)
+	s.player = host
+	guest := newTestFetterPlayer(10002, s.scene.id)
+	s.scene.world.owner = host
+	s.scene.playerMap = map[uint32]*model.Player{host.PlayerId: host, guest.PlayerId: guest}
+	s.scene.InitDungeon(testDungeonId)
+	return s, new(Game)
+}
+
+func getTestFetterExp(player *model.Player) uint32 {
+	return player.GetDbAvatar().GetAvatarById(10000021).FetterExp
+}
+
+func TestDungeonSettleFetterExp(t *testing.T) {
+	s, g := newTestDungeonScene(t, 1)
+	outside := newTestFetterPlayer(10003, 1)
+	s.scene.world.playerMap = map[uint32]*model.Player{outside.PlayerId: outside}
+
+	// 只有地牢场景内的玩家获得好感度经验 重复结算不会重复发放
+	g.DungeonSettle(s.scene, true)
+	g.DungeonSettle(s.scene, true)
+	for _, player := range s.scene.GetAllPlayer() {
+		if getTestFetterExp(player) != FetterExpDungeonSuccess {
+			t.Fatalf("dungeon player fetter exp error, uid: %v, exp: %v", player.PlayerId, getTestFetterExp(player))
+		}
+	}
+	if getTestFetterExp(outside) != 0 {
+		t.Fatalf("player outside dungeon should not get fetter exp, exp: %v", getTestFetterExp(outside))
+	}
+
+	// 失败结算不发放
+	s.scene.InitDungeon(testDungeonId)
+	g.DungeonSettle(s.scene, false)
+	if getTestFetterExp(s.player) != FetterExpDungeonSuccess {
+		t.Fatalf("dungeon fail should not give fetter exp, exp: %v", getTestFetterExp(s.player))
+	}
+
+	// 非地牢场景不结算
+	s.scene.dungeon = nil
+	g.DungeonSettle(s.scene, true)
+	if getTestFetterExp(s.player) != FetterExpDungeonSuccess {
+		t.Fatalf("normal scene should not settle, exp: %v", getTestFetterExp(s.player))
+	}
+}
+
+func TestDungeonPassCond(t *testing.T) {
+	s, g := newTestDungeonScene(t, 1)
+	monsterA := s.addMonster(100, new(model.Vector), 100.0, 100.0, 0.0)
+	monsterB := s.addMonster(101, new(model.Vector), 100.0, 100.0, 0.0)
+	group := &Group{id: testDungeonGroupId, suiteMap: map[uint8]*Suite{
+		1: {id: 1, entityMap: map[uint32]IEntity{monsterA.GetId(): monsterA, monsterB.GetId(): monsterB}},
+	}}
+	s.scene.groupMap = map[uint32]*Group{testDungeonGroupId: group}
+	kill := func(monsterEntity *MonsterEntity) {
+		group.DestroyEntity(monsterEntity.GetId())
+		g.DungeonMonsterKill(s.scene, monsterEntity)
+	}
+
+	// group内还有怪物时不结算
+	kill(monsterA)
+	if s.scene.GetDungeon().IsSettled() {
+		t.Fatal("dungeon should not settle with monster alive")
+	}
+	kill(monsterB)
+	if !s.scene.GetDungeon().IsSettled() || getTestFetterExp(s.player) != FetterExpDungeonSuccess {
+		t.Fatalf("dungeon should settle after group monster killed, exp: %v", getTestFetterExp(s.player))
+	}
+
+	// 或条件 击杀数量达到或者击杀指定怪物
+	gdconf.CONF.DungeonDataMap[testDungeonId].PassCond = 2
+	s.scene.InitDungeon(testDungeonId)
+	for i := 0; i < 3; i++ {
+		if s.scene.GetDungeon().IsSettled() {
+			t.Fatalf("dungeon should not settle before kill count, count: %v", i)
+		}
+		kill(monsterA)
+	}
+	if !s.scene.GetDungeon().IsSettled() {
+		t.Fatal("dungeon should settle after kill count")
+	}
+	s.scene.InitDungeon(testDungeonId)
+	monsterB.monsterId = testDungeonMonsterId
+	kill(monsterB)
+	if !s.scene.GetDungeon().IsSettled() {
+		t.Fatal("dungeon should settle after kill monster")
+	}
+}
@@ -202,6 +202,9 @@ func (g *Game) handleEvtBeingHit(player *model.Player, scene *Scene, hitInfo *pr
 		g.EntityFightPropUpdateNotifyBroadcast(scene, defEntity)
 		if currHp == 0.0 {
 			g.KillEntity(player, scene, defEntity.GetId(), proto.PlayerDieType_PLAYER_DIE_GM)
+		} else {
+			// 服务端怪物ai仇恨
+			scene.GetWorld().GetMonsterAiManager().AddThreat(scene, monsterEntity, attackResult.AttackerId, attackResult.Damage)
 		}
 		if defEntity.GetGroupId() == 0 {
 			return
@@ -701,7 +704,7 @@ func (g *Game) handleAbilityInvoke(player *model.Player, entry *proto.AbilityInv
 			logger.Error("get action data config is nil, localId: %v", entry.Head.LocalId)
 			return
 		}
-		entity.AbilityAction(ability, actionDataConfig, entity)
+		ExecAbilityAction(NewAbilityContext(g, ability, abilityDataConfig, entity, entry.Head.LocalId, entry.AbilityData, player), actionDataConfig)
 	} else if strings.Contains(entry.ArgumentType.String(), "MIXIN") {
 		ability := entity.GetAbility(entry.Head.InstancedAbilityId)
 		if ability == nil {
@@ -718,7 +721,7 @@ func (g *Game) handleAbilityInvoke(player *model.Player, entry *proto.AbilityInv
 			logger.Error("get mixin data config is nil, localId: %v", entry.Head.LocalId)
 			return
 		}
-		entity.AbilityMixin(ability, mixinDataConfig, entity)
+		ExecAbilityMixin(NewAbilityContext(g, ability, abilityDataConfig, entity, entry.Head.LocalId, entry.AbilityData, player), mixinDataConfig)
 	} else if strings.Contains(entry.ArgumentType.String(), "META") {
 		switch entry.ArgumentType {
 		case proto.AbilityInvokeArgument_ABILITY_META_ADD_NEW_ABILITY:
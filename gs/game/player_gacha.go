@@ -5,6 +5,7 @@ import (
 
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/random"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -27,9 +28,9 @@ func (g *Game) GetGachaInfoReq(player *model.Player, payloadMsg pb.Message) {
 	userInfo := &UserInfo{
 		UserId: player.PlayerId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour * time.Duration(1))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(clock.Now().Add(24 * time.Hour * time.Duration(1))),
+			IssuedAt:  jwt.NewNumericDate(clock.Now()),
+			NotBefore: jwt.NewNumericDate(clock.Now()),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, userInfo)
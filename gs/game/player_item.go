@@ -212,6 +212,14 @@ type ChangeItem struct {
 	ChangeCount uint32
 }
 
+// 可以被插件取消的添加物品原因
+// 充值 邮件 签到 购买和消耗资源兑换等路径在添加物品前已经提交了状态 取消会导致玩家损失 不触发前置事件
+var pluginCancelAddItemReasonMap = map[proto.ActionReasonType]bool{
+	proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP: true,
+	proto.ActionReasonType_ACTION_REASON_GATHER:        true,
+	proto.ActionReasonType_ACTION_REASON_GM:            true,
+}
+
 // AddPlayerItem 添加玩家物品
 func (g *Game) AddPlayerItem(userId uint32, itemList []*ChangeItem, hintReason proto.ActionReasonType) bool {
 	player := USER_MANAGER.GetOnlineUser(userId)
@@ -219,6 +227,15 @@ func (g *Game) AddPlayerItem(userId uint32, itemList []*ChangeItem, hintReason p
 		logger.Error("player is nil, uid: %v", userId)
 		return false
 	}
+	// 触发事件
+	if pluginCancelAddItemReasonMap[hintReason] && PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreAddItem, &PluginEventPreAddItem{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		ItemList:    itemList,
+		HintReason:  hintReason,
+	}) {
+		return false
+	}
 	itemMap := make(map[uint32]uint32)
 	for _, changeItem := range itemList {
 		itemMap[changeItem.ItemId] += changeItem.ChangeCount
@@ -291,6 +308,8 @@ func (g *Game) AddPlayerItem(userId uint32, itemList []*ChangeItem, hintReason p
 	}
 	for itemId, addCount := range itemMap {
 		g.TriggerQuest(player, constant.QUEST_FINISH_COND_TYPE_OBTAIN_ITEM, "", int32(itemId))
+		// 图鉴解锁
+		g.CodexObtainItem(player, itemId)
 		itemDataConfig := gdconf.GetItemDataById(int32(itemId))
 		if itemDataConfig == nil {
 			continue
@@ -315,6 +334,13 @@ func (g *Game) AddPlayerItem(userId uint32, itemList []*ChangeItem, hintReason p
 			}
 		}
 	}
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdPostAddItem, &PluginEventPostAddItem{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		ItemList:    itemList,
+		HintReason:  hintReason,
+	})
 	return true
 }
 
@@ -377,6 +403,16 @@ func (g *Game) CostPlayerItem(userId uint32, itemList []*ChangeItem) bool {
 	if len(delNtf.GuidList) > 0 {
 		g.SendMsg(cmd.StoreItemDelNotify, userId, player.ClientSeq, delNtf)
 	}
+	for itemId, costCount := range itemMap {
+		// 回归任务进度
+		g.TriggerReunionWatcher(player, constant.REUNION_WATCHER_TRIGGER_TYPE_COST_MATERIAL, int32(itemId), costCount)
+	}
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdPostCostItem, &PluginEventPostCostItem{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		ItemList:    itemList,
+	})
 	return true
 }
 
@@ -395,6 +431,13 @@ func (g *Game) RewardItem(userId uint32, rewardId uint32, hintReason proto.Actio
 		})
 	}
 	g.AddPlayerItem(userId, rewardItemList, hintReason)
+	if rewardConfig.FetterExp > 0 {
+		// 奖励好感度经验
+		player := USER_MANAGER.GetOnlineUser(userId)
+		if player != nil {
+			g.AddPlayerTeamFetterExp(player, uint32(rewardConfig.FetterExp))
+		}
+	}
 	return true
 }
 
@@ -5,6 +5,7 @@ import (
 	"hk4e/common/region"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -84,6 +85,7 @@ func (g *Game) OnLogin(userId uint32, clientSeq uint32, gateAppId string, player
 		return
 	}
 
+	isNewPlayer := player == nil
 	if player == nil {
 		logger.Info("reg new player, uid: %v", userId)
 		player = g.CreatePlayer(userId)
@@ -115,9 +117,13 @@ func (g *Game) OnLogin(userId uint32, clientSeq uint32, gateAppId string, player
 		g.AcceptQuest(player, false)
 	}
 
+	g.UpdatePlayerWorldLevel(player)
 	g.TriggerOpenState(userId)
 
 	if player.IsBorn {
+		now := clock.Now()
+		player.GetDbLogin().LoginDay(now)
+		g.CheckPlayerReunion(player, now)
 		g.LoginNotify(userId, clientSeq, player)
 		if req.TargetUid != 0 {
 			hostPlayer := USER_MANAGER.GetOnlineUser(req.TargetUid)
@@ -157,6 +163,18 @@ func (g *Game) OnLogin(userId uint32, clientSeq uint32, gateAppId string, player
 	}
 	g.SendMsg(cmd.PlayerLoginRsp, userId, clientSeq, rsp)
 
+	if player.IsBorn {
+		g.CheckPlayerSignInReset(player, clock.Now())
+		g.CheckBirthdayMail(player, clock.Now())
+	}
+
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdPlayerLogin, &PluginEventPlayerLogin{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		IsNewPlayer: isNewPlayer,
+	})
+
 	SELF = nil
 }
 
@@ -260,6 +278,13 @@ func (g *Game) OnOffline(userId uint32, changeGsInfo *ChangeGsInfo) {
 		return
 	}
 
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdPlayerOffline, &PluginEventPlayerOffline{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		IsChangeGs:  changeGsInfo != nil && changeGsInfo.IsChangeGs,
+	})
+
 	dbAvatar := player.GetDbAvatar()
 	for _, avatar := range dbAvatar.GetAvatarMap() {
 		dbAvatar.SaveOfflineFightProp(avatar)
@@ -290,5 +315,17 @@ func (g *Game) LoginNotify(userId uint32, clientSeq uint32, player *model.Player
 	g.SendMsg(cmd.FinishedParentQuestNotify, userId, clientSeq, g.PacketFinishedParentQuestNotify(player))
 	g.SendMsg(cmd.AllMarkPointNotify, userId, clientSeq, &proto.AllMarkPointNotify{MarkList: g.PacketMapMarkPointList(player)})
 	g.SendMsg(cmd.AllWidgetDataNotify, userId, clientSeq, &proto.AllWidgetDataNotify{SlotList: g.PacketWidgetSlotDataList(player)})
+	g.SendMsg(cmd.CodexDataFullNotify, userId, clientSeq, g.PacketCodexDataFullNotify(player))
+	now := clock.Now()
+	g.CheckPlayerActivity(player, now)
+	g.SendMsg(cmd.ActivityScheduleInfoNotify, userId, clientSeq, g.PacketActivityScheduleInfoNotify(now))
+	g.SendMsg(cmd.PlayerRechargeDataNotify, userId, clientSeq, g.PacketPlayerRechargeDataNotify())
+	g.ReconcilePlayerOrder(player)
+	if player.GetDbReunion().IsActive(now) {
+		g.SendMsg(cmd.ReunionActivateNotify, userId, clientSeq, &proto.ReunionActivateNotify{
+			IsActivate:       true,
+			ReunionBriefInfo: g.PacketReunionBriefInfo(player, now),
+		})
+	}
 	g.GCGLogin(player) // 发送GCG登录相关的通知包
 }
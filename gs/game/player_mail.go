@@ -3,7 +3,9 @@ package game
 import (
 	"time"
 
+	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -56,9 +58,46 @@ func (g *Game) DelMailReq(player *model.Player, payloadMsg pb.Message) {
 func (g *Game) GetMailItemReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.GetMailItemReq)
 
+	mailIdList := make([]uint32, 0)
+	changeItemList := make([]*ChangeItem, 0)
+	pbMailList := make([]*proto.MailData, 0)
+	for _, mailId := range req.MailIdList {
+		mail, exist := player.MailMap[mailId]
+		if !exist {
+			continue
+		}
+		if mail.IsAttachmentGot || len(mail.ItemList) == 0 {
+			continue
+		}
+		for _, mailItem := range mail.ItemList {
+			changeItemList = append(changeItemList, &ChangeItem{ItemId: mailItem.ItemId, ChangeCount: mailItem.Count})
+		}
+		mail.IsAttachmentGot = true
+		mail.IsRead = true
+		if g.IsBirthdayMail(mail) {
+			// 附件进入背包后才记录生日邮件的发放年份
+			player.GetDbSocial().MarkBirthdayMail(time.Unix(int64(mail.SendTime), 0))
+		}
+		mailIdList = append(mailIdList, mailId)
+		pbMailList = append(pbMailList, g.PacketMail(mail))
+	}
+	if len(changeItemList) != 0 {
+		g.AddPlayerItem(player.PlayerId, changeItemList, proto.ActionReasonType_ACTION_REASON_MAIL_ATTACHMENT)
+	}
+	if len(pbMailList) != 0 {
+		g.SendMsg(cmd.MailChangeNotify, player.PlayerId, player.ClientSeq, &proto.MailChangeNotify{
+			MailList:      pbMailList,
+			DelMailIdList: nil,
+		})
+	}
+
+	pbItemList := make([]*proto.EquipParam, 0, len(changeItemList))
+	for _, changeItem := range changeItemList {
+		pbItemList = append(pbItemList, &proto.EquipParam{ItemId: changeItem.ItemId, ItemNum: changeItem.ChangeCount})
+	}
 	rsp := &proto.GetMailItemRsp{
-		MailIdList: req.MailIdList,
-		ItemList:   nil,
+		MailIdList: mailIdList,
+		ItemList:   pbItemList,
 	}
 	g.SendMsg(cmd.GetMailItemRsp, player.PlayerId, player.ClientSeq, rsp)
 }
@@ -78,21 +117,40 @@ func (g *Game) ChangeMailStarNotify(player *model.Player, payloadMsg pb.Message)
 var MailIdSeq uint32 = 0
 
 func (g *Game) AddPlayerMail(userId uint32, title string, content string) {
+	g.AddPlayerMailWithItem(userId, title, content, 0, nil)
+}
+
+// AddPlayerMailWithItem 发送带附件的邮件 configId不为0时使用邮件配置表的过期时间和星标
+func (g *Game) AddPlayerMailWithItem(userId uint32, title string, content string, configId uint32, itemList []*model.MailItem) {
 	player := USER_MANAGER.GetOnlineUser(userId)
 	if player == nil {
 		logger.Error("player is nil, uid: %v", userId)
 		return
 	}
+	expireDay := uint32(1)
+	isStar := false
+	if configId != 0 {
+		mailDataConfig := gdconf.GetMailDataById(int32(configId))
+		if mailDataConfig != nil {
+			if mailDataConfig.ExpireDay > 0 {
+				expireDay = uint32(mailDataConfig.ExpireDay)
+			}
+			isStar = mailDataConfig.IsStar != 0
+		}
+	}
 	MailIdSeq++
 	mail := &model.Mail{
-		MailId:     MailIdSeq,
-		Title:      title,
-		Content:    content,
-		Sender:     "flswld",
-		SendTime:   uint32(time.Now().Unix()),
-		ExpireTime: uint32(time.Now().Add(time.Hour * 24).Unix()),
-		IsRead:     false,
-		IsStar:     false,
+		MailId:          MailIdSeq,
+		Title:           title,
+		Content:         content,
+		Sender:          "flswld",
+		SendTime:        uint32(clock.Now().Unix()),
+		ExpireTime:      uint32(clock.Now().Add(time.Hour * 24 * time.Duration(expireDay)).Unix()),
+		IsRead:          false,
+		IsStar:          isStar,
+		ConfigId:        configId,
+		ItemList:        itemList,
+		IsAttachmentGot: false,
 	}
 	player.MailMap[mail.MailId] = mail
 	ntf := &proto.MailChangeNotify{
@@ -162,6 +220,15 @@ func (g *Game) StarPlayerMail(userId uint32, mailIdList []uint32, isStar bool) {
 /************************************************** 打包封装 **************************************************/
 
 func (g *Game) PacketMail(mail *model.Mail) *proto.MailData {
+	pbItemList := make([]*proto.MailItem, 0, len(mail.ItemList))
+	for _, mailItem := range mail.ItemList {
+		pbItemList = append(pbItemList, &proto.MailItem{
+			EquipParam: &proto.EquipParam{
+				ItemId:  mailItem.ItemId,
+				ItemNum: mailItem.Count,
+			},
+		})
+	}
 	return &proto.MailData{
 		MailId: mail.MailId,
 		MailTextContent: &proto.MailTextContent{
@@ -169,13 +236,13 @@ func (g *Game) PacketMail(mail *model.Mail) *proto.MailData {
 			Content: mail.Content,
 			Sender:  mail.Sender,
 		},
-		ItemList:        nil,
+		ItemList:        pbItemList,
 		SendTime:        mail.SendTime,
 		ExpireTime:      mail.ExpireTime,
 		Importance:      uint32(object.ConvBoolToInt64(mail.IsStar)),
 		IsRead:          mail.IsRead,
-		IsAttachmentGot: false,
-		ConfigId:        0,
+		IsAttachmentGot: mail.IsAttachmentGot,
+		ConfigId:        mail.ConfigId,
 		ArgumentList:    nil,
 		CollectState:    proto.MailCollectState_MAIL_NOT_COLLECTIBLE,
 	}
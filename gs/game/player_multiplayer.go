@@ -6,6 +6,7 @@ import (
 	"hk4e/common/constant"
 	"hk4e/common/mq"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -116,6 +117,15 @@ func (g *Game) SceneKickPlayerReq(player *model.Player, payloadMsg pb.Message) {
 // JoinPlayerSceneReq 进入他人世界请求
 func (g *Game) JoinPlayerSceneReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.JoinPlayerSceneReq)
+	// 触发事件
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreJoinPlayerScene, &PluginEventPreJoinPlayerScene{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		TargetUid:   req.TargetUid,
+	}) {
+		g.SendError(cmd.JoinPlayerSceneRsp, player, &proto.JoinPlayerSceneRsp{})
+		return
+	}
 	rsp := &proto.JoinPlayerSceneRsp{
 		Retcode: int32(proto.Retcode_RET_JOIN_OTHER_WAIT),
 	}
@@ -246,12 +256,12 @@ func (g *Game) PlayerApplyEnterWorld(player *model.Player, targetUid uint32) {
 		return
 	}
 	applyTime, exist := targetPlayer.CoopApplyMap[player.PlayerId]
-	if exist && time.Now().UnixNano() < applyTime+int64(10*time.Second) {
+	if exist && clock.Now().UnixNano() < applyTime+int64(10*time.Second) {
 		// 申请过期
 		applyFailNotify(proto.PlayerApplyEnterMpResultNotify_PLAYER_CANNOT_ENTER_MP)
 		return
 	}
-	targetPlayer.CoopApplyMap[player.PlayerId] = time.Now().UnixNano()
+	targetPlayer.CoopApplyMap[player.PlayerId] = clock.Now().UnixNano()
 
 	playerApplyEnterMpNotify := new(proto.PlayerApplyEnterMpNotify)
 	playerApplyEnterMpNotify.SrcPlayerInfo = g.PacketOnlinePlayerInfo(player)
@@ -260,7 +270,7 @@ func (g *Game) PlayerApplyEnterWorld(player *model.Player, targetUid uint32) {
 
 func (g *Game) PlayerDealEnterWorld(hostPlayer *model.Player, otherUid uint32, agree bool) {
 	applyTime, exist := hostPlayer.CoopApplyMap[otherUid]
-	if !exist || time.Now().UnixNano() > applyTime+int64(10*time.Second) {
+	if !exist || clock.Now().UnixNano() > applyTime+int64(10*time.Second) {
 		return
 	}
 	delete(hostPlayer.CoopApplyMap, otherUid)
@@ -321,6 +331,7 @@ func (g *Game) PlayerLeaveWorld(player *model.Player, force bool, reason proto.P
 	if force {
 		g.SendMsg(cmd.PlayerQuitFromMpNotify, player.PlayerId, player.ClientSeq, &proto.PlayerQuitFromMpNotify{Reason: reason})
 		g.ReLoginPlayer(player.PlayerId, true)
+		g.triggerPlayerLeaveWorldEvent(player, reason)
 		return true
 	}
 	oldWorld := WORLD_MANAGER.GetWorldById(player.WorldId)
@@ -338,9 +349,19 @@ func (g *Game) PlayerLeaveWorld(player *model.Player, force bool, reason proto.P
 	}
 	g.SendMsg(cmd.PlayerQuitFromMpNotify, player.PlayerId, player.ClientSeq, &proto.PlayerQuitFromMpNotify{Reason: reason})
 	g.ReLoginPlayer(player.PlayerId, true)
+	g.triggerPlayerLeaveWorldEvent(player, reason)
 	return true
 }
 
+func (g *Game) triggerPlayerLeaveWorldEvent(player *model.Player, reason proto.PlayerQuitFromMpNotify_QuitReason) {
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdPlayerLeaveWorld, &PluginEventPlayerLeaveWorld{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		Reason:      reason,
+	})
+}
+
 func (g *Game) JoinOtherWorld(player *model.Player, hostPlayer *model.Player) {
 	hostWorld := WORLD_MANAGER.GetWorldById(hostPlayer.WorldId)
 	if hostWorld == nil {
@@ -447,6 +468,7 @@ func (g *Game) WorldAddPlayer(world *World, player *model.Player) {
 	}
 	world.AddPlayer(player)
 	player.WorldId = world.GetId()
+	g.UpdateWorldTeamResonance(world, false)
 	if world.IsMultiplayerWorld() && world.GetWorldPlayerNum() > 1 {
 		g.UpdateWorldPlayerInfo(world, player)
 	}
@@ -502,6 +524,7 @@ func (g *Game) WorldRemovePlayer(world *World, player *model.Player) {
 		WORLD_MANAGER.DestroyWorld(world.GetId())
 		return
 	}
+	g.UpdateWorldTeamResonance(world, false)
 	if world.IsMultiplayerWorld() && world.GetWorldPlayerNum() > 0 {
 		g.UpdateWorldPlayerInfo(world, player)
 		world.GetOwner().RemoteWorldPlayerNum = uint32(world.GetWorldPlayerNum())
@@ -537,7 +560,7 @@ func (g *Game) UpdateWorldPlayerInfo(hostWorld *World, excludePlayer *model.Play
 		g.SendMsg(cmd.WorldPlayerInfoNotify, worldPlayer.PlayerId, worldPlayer.ClientSeq, worldPlayerInfoNotify)
 
 		serverTimeNotify := &proto.ServerTimeNotify{
-			ServerTime: uint64(time.Now().UnixMilli()),
+			ServerTime: uint64(clock.Now().UnixMilli()),
 		}
 		g.SendMsg(cmd.ServerTimeNotify, worldPlayer.PlayerId, worldPlayer.ClientSeq, serverTimeNotify)
 
@@ -653,11 +676,11 @@ func (g *Game) ServerPlayerMpReq(playerMpInfo *mq.PlayerMpInfo, gsAppId string)
 			return
 		}
 		applyTime, exist := hostPlayer.CoopApplyMap[playerMpInfo.ApplyUserId]
-		if exist && time.Now().UnixNano() < applyTime+int64(10*time.Second) {
+		if exist && clock.Now().UnixNano() < applyTime+int64(10*time.Second) {
 			applyFailNotify(proto.PlayerApplyEnterMpResultNotify_PLAYER_CANNOT_ENTER_MP)
 			return
 		}
-		hostPlayer.CoopApplyMap[playerMpInfo.ApplyUserId] = time.Now().UnixNano()
+		hostPlayer.CoopApplyMap[playerMpInfo.ApplyUserId] = clock.Now().UnixNano()
 
 		playerApplyEnterMpNotify := new(proto.PlayerApplyEnterMpNotify)
 		playerApplyEnterMpNotify.SrcPlayerInfo = &proto.OnlinePlayerInfo{
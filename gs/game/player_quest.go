@@ -107,135 +107,115 @@ func matchParamEqual(param1 []int32, param2 []int32, num int) bool {
 	return true
 }
 
-// AcceptQuest 接取任务
-func (g *Game) AcceptQuest(player *model.Player, notify bool) {
-	g.EndlessLoopCheck(EndlessLoopCheckTypeAcceptQuest)
-	dbQuest := player.GetDbQuest()
-	addQuestIdList := make([]uint32, 0)
-	for _, questData := range gdconf.GetQuestDataMap() {
-		if dbQuest.GetQuestById(uint32(questData.QuestId)) != nil {
+// CheckQuestAcceptCond 检查任务领取条件
+func CheckQuestAcceptCond(dbQuest *model.DbQuest, questData *gdconf.QuestData) bool {
+	resultList := make([]bool, 0)
+	for _, acceptCond := range questData.AcceptCondList {
+		handler, exist := questAcceptCondHandlerMap[acceptCond.Type]
+		if !exist {
+			// logger.Error("not support quest accept cond type: %v, questId: %v", acceptCond.Type, questData.QuestId)
 			continue
 		}
-		resultList := make([]bool, 0)
-		for _, acceptCond := range questData.AcceptCondList {
-			result := false
-			switch acceptCond.Type {
-			case constant.QUEST_ACCEPT_COND_TYPE_STATE_EQUAL:
-				// 某个任务状态等于 参数1:任务id 参数2:任务状态
-				if len(acceptCond.Param) != 2 {
-					break
-				}
-				quest := dbQuest.GetQuestById(uint32(acceptCond.Param[0]))
-				if quest == nil {
-					break
-				}
-				if quest.State != uint8(acceptCond.Param[1]) {
-					break
-				}
-				result = true
-			case constant.QUEST_ACCEPT_COND_TYPE_STATE_NOT_EQUAL:
-				// 某个任务状态不等于 参数1:任务id 参数2:任务状态
-				if len(acceptCond.Param) != 2 {
-					break
-				}
-				quest := dbQuest.GetQuestById(uint32(acceptCond.Param[0]))
-				if quest == nil {
-					break
-				}
-				if quest.State == uint8(acceptCond.Param[1]) {
-					break
-				}
-				result = true
-			default:
-				// logger.Error("not support quest accept cond type: %v, questId: %v, uid: %v", acceptCond.Type, questData.QuestId, player.PlayerId)
-				continue
+		result := handler(dbQuest, acceptCond)
+		resultList = append(resultList, result)
+	}
+	if len(resultList) != len(questData.AcceptCondList) {
+		return false
+	}
+	accept := false
+	switch questData.AcceptCondCompose {
+	case constant.QUEST_LOGIC_TYPE_NONE:
+		fallthrough
+	case constant.QUEST_LOGIC_TYPE_AND:
+		accept = true
+		for _, result := range resultList {
+			if !result {
+				accept = false
+				break
 			}
-			resultList = append(resultList, result)
 		}
-		if len(resultList) != len(questData.AcceptCondList) {
-			continue
-		}
-		accept := false
-		switch questData.AcceptCondCompose {
-		case constant.QUEST_LOGIC_TYPE_NONE:
-			fallthrough
-		case constant.QUEST_LOGIC_TYPE_AND:
-			accept = true
-			for _, result := range resultList {
-				if !result {
-					accept = false
-					break
-				}
+	case constant.QUEST_LOGIC_TYPE_OR:
+		accept = false
+		for _, result := range resultList {
+			if result {
+				accept = true
+				break
 			}
-		case constant.QUEST_LOGIC_TYPE_OR:
+		}
+	case constant.QUEST_LOGIC_TYPE_A_AND_ETCOR:
+		if len(resultList) < 2 {
 			accept = false
-			for _, result := range resultList {
-				if result {
-					accept = true
-					break
-				}
-			}
-		case constant.QUEST_LOGIC_TYPE_A_AND_ETCOR:
-			if len(resultList) < 2 {
-				accept = false
+			break
+		}
+		acceptA := resultList[0]
+		acceptEtc := false
+		for _, result := range resultList[1:] {
+			if result {
+				acceptEtc = true
 				break
 			}
-			acceptA := resultList[0]
-			acceptEtc := false
-			for _, result := range resultList[1:] {
-				if result {
-					acceptEtc = true
-					break
-				}
-			}
-			accept = acceptA && acceptEtc
-		case constant.QUEST_LOGIC_TYPE_A_AND_B_AND_ETCOR:
-			if len(resultList) < 3 {
-				accept = false
+		}
+		accept = acceptA && acceptEtc
+	case constant.QUEST_LOGIC_TYPE_A_AND_B_AND_ETCOR:
+		if len(resultList) < 3 {
+			accept = false
+			break
+		}
+		acceptA := resultList[0]
+		acceptB := resultList[1]
+		acceptEtc := false
+		for _, result := range resultList[2:] {
+			if result {
+				acceptEtc = true
 				break
 			}
-			acceptA := resultList[0]
-			acceptB := resultList[1]
-			acceptEtc := false
-			for _, result := range resultList[2:] {
-				if result {
-					acceptEtc = true
-					break
-				}
-			}
-			accept = acceptA && acceptB && acceptEtc
-		case constant.QUEST_LOGIC_TYPE_A_OR_ETCAND:
-			if len(resultList) < 2 {
-				accept = false
+		}
+		accept = acceptA && acceptB && acceptEtc
+	case constant.QUEST_LOGIC_TYPE_A_OR_ETCAND:
+		if len(resultList) < 2 {
+			accept = false
+			break
+		}
+		acceptA := resultList[0]
+		acceptEtc := true
+		for _, result := range resultList[1:] {
+			if !result {
+				acceptEtc = false
 				break
 			}
-			acceptA := resultList[0]
-			acceptEtc := true
-			for _, result := range resultList[1:] {
-				if !result {
-					acceptEtc = false
-					break
-				}
-			}
-			accept = acceptA || acceptEtc
-		case constant.QUEST_LOGIC_TYPE_A_OR_B_OR_ETCAND:
-			if len(resultList) < 3 {
-				accept = false
+		}
+		accept = acceptA || acceptEtc
+	case constant.QUEST_LOGIC_TYPE_A_OR_B_OR_ETCAND:
+		if len(resultList) < 3 {
+			accept = false
+			break
+		}
+		acceptA := resultList[0]
+		acceptB := resultList[1]
+		acceptEtc := true
+		for _, result := range resultList[2:] {
+			if !result {
+				acceptEtc = false
 				break
 			}
-			acceptA := resultList[0]
-			acceptB := resultList[1]
-			acceptEtc := true
-			for _, result := range resultList[2:] {
-				if !result {
-					acceptEtc = false
-					break
-				}
-			}
-			accept = acceptA || acceptB || acceptEtc
-		default:
-			logger.Error("not support quest accept cond logic type: %v, questId: %v, uid: %v", questData.AcceptCondCompose, questData.QuestId, player.PlayerId)
 		}
+		accept = acceptA || acceptB || acceptEtc
+	default:
+		logger.Error("not support quest accept cond logic type: %v, questId: %v", questData.AcceptCondCompose, questData.QuestId)
+	}
+	return accept
+}
+
+// AcceptQuest 接取任务
+func (g *Game) AcceptQuest(player *model.Player, notify bool) {
+	g.EndlessLoopCheck(EndlessLoopCheckTypeAcceptQuest)
+	dbQuest := player.GetDbQuest()
+	addQuestIdList := make([]uint32, 0)
+	for _, questData := range gdconf.GetQuestDataMap() {
+		if dbQuest.GetQuestById(uint32(questData.QuestId)) != nil {
+			continue
+		}
+		accept := CheckQuestAcceptCond(dbQuest, questData)
 		if accept {
 			if questData.QuestId == 35304 {
 				// TODO 任务异常的权柄释放元素爆发时没有能量
@@ -278,6 +258,7 @@ func (g *Game) StartQuest(player *model.Player, questId uint32, notify bool) {
 
 	g.ExecQuest(player, questId, QuestExecTypeStart)
 	g.QuestStartTriggerCheck(player, questId)
+	g.triggerQuestStateChangeEvent(player, questId, constant.QUEST_STATE_UNFINISHED)
 
 	if notify {
 		g.SendMsg(cmd.QuestListUpdateNotify, player.PlayerId, player.ClientSeq, &proto.QuestListUpdateNotify{
@@ -304,6 +285,19 @@ func (g *Game) StartQuest(player *model.Player, questId uint32, notify bool) {
 	}
 }
 
+// 解析任务执行参数中的试用角色id列表
+func parseQuestTrialAvatarIdList(param string) []uint32 {
+	trialAvatarIdList := make([]uint32, 0)
+	for _, str := range strings.FieldsFunc(param, func(r rune) bool { return r == ',' || r == ';' }) {
+		trialAvatarId, err := strconv.Atoi(str)
+		if err != nil || trialAvatarId == 0 {
+			continue
+		}
+		trialAvatarIdList = append(trialAvatarIdList, uint32(trialAvatarId))
+	}
+	return trialAvatarIdList
+}
+
 // ExecQuest 执行任务
 func (g *Game) ExecQuest(player *model.Player, questId uint32, questExecType int) {
 	g.EndlessLoopCheck(EndlessLoopCheckTypeExecQuest)
@@ -323,163 +317,12 @@ func (g *Game) ExecQuest(player *model.Player, questId uint32, questExecType int
 		return
 	}
 	for _, questExec := range questExecList {
-		switch questExec.Type {
-		case constant.QUEST_EXEC_TYPE_NOTIFY_GROUP_LUA:
-			// 通知LUA侧
-		case constant.QUEST_EXEC_TYPE_REFRESH_GROUP_SUITE:
-			// 刷新场景小组
-			if len(questExec.Param) != 2 {
-				continue
-			}
-			split := strings.Split(questExec.Param[1], ",")
-			if len(split) != 2 {
-				continue
-			}
-			groupId, err := strconv.Atoi(split[0])
-			if err != nil {
-				continue
-			}
-			suiteId, err := strconv.Atoi(split[1])
-			if err != nil {
-				continue
-			}
-			g.RefreshSceneGroupSuite(player, uint32(groupId), uint8(suiteId))
-		case constant.QUEST_EXEC_TYPE_SET_OPEN_STATE:
-			// 设置游戏功能开放状态
-			if len(questExec.Param) != 2 {
-				continue
-			}
-			key, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			value, err := strconv.Atoi(questExec.Param[1])
-			if err != nil {
-				continue
-			}
-			g.ChangePlayerOpenState(player.PlayerId, uint32(key), uint32(value))
-		case constant.QUEST_EXEC_TYPE_UNLOCK_POINT:
-			// 解锁传送点
-			if len(questExec.Param) != 2 {
-				continue
-			}
-			sceneId, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			pointId, err := strconv.Atoi(questExec.Param[1])
-			if err != nil {
-				continue
-			}
-			g.UnlockPlayerScenePoint(player, uint32(sceneId), uint32(pointId))
-		case constant.QUEST_EXEC_TYPE_UNLOCK_AREA:
-			// 解锁场景区域
-			if len(questExec.Param) != 2 {
-				continue
-			}
-			sceneId, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			areaId, err := strconv.Atoi(questExec.Param[1])
-			if err != nil {
-				continue
-			}
-			g.UnlockPlayerSceneArea(player, uint32(sceneId), uint32(areaId))
-		case constant.QUEST_EXEC_TYPE_CHANGE_AVATAR_ELEMET:
-			// 改变主角元素类型
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			elementType, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			dbAvatar := player.GetDbAvatar()
-			g.ChangePlayerAvatarSkillDepot(player.PlayerId, dbAvatar.MainCharAvatarId, 0, elementType)
-		case constant.QUEST_EXEC_TYPE_SET_IS_FLYABLE:
-			// 设置允许飞行状态
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			value, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			player.PropMap[constant.PLAYER_PROP_IS_FLYABLE] = uint32(value)
-			g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(player, constant.PLAYER_PROP_IS_FLYABLE))
-		case constant.QUEST_EXEC_TYPE_SET_IS_WEATHER_LOCKED:
-			// 设置天气锁定状态
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			value, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			player.PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] = uint32(value)
-			g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(player, constant.PLAYER_PROP_IS_WEATHER_LOCKED))
-		case constant.QUEST_EXEC_TYPE_SET_IS_GAME_TIME_LOCKED:
-			// 设置游戏时间锁定状态
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			value, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			player.PropMap[constant.PLAYER_PROP_IS_GAME_TIME_LOCKED] = uint32(value)
-			g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(player, constant.PLAYER_PROP_IS_GAME_TIME_LOCKED))
-		case constant.QUEST_EXEC_TYPE_SET_IS_TRANSFERABLE:
-			// 设置允许传送状态
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			value, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			player.PropMap[constant.PLAYER_PROP_IS_TRANSFERABLE] = uint32(value)
-			g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(player, constant.PLAYER_PROP_IS_TRANSFERABLE))
-		case constant.QUEST_EXEC_TYPE_SET_GAME_TIME:
-			// 设置游戏时间
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			hour, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			world := WORLD_MANAGER.GetWorldById(player.WorldId)
-			if world == nil {
-				logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
-				continue
-			}
-			scene := world.GetSceneById(player.GetSceneId())
-			if scene == nil {
-				logger.Error("scene is nil, sceneId: %v, uid: %v", player.GetSceneId(), player.PlayerId)
-				continue
-			}
-			g.ChangeGameTime(world, uint32(hour*60))
-		case constant.QUEST_EXEC_TYPE_ROLLBACK_QUEST:
-			// 回滚任务
-			if len(questExec.Param) != 1 {
-				continue
-			}
-			rollbackQuestId, err := strconv.Atoi(questExec.Param[0])
-			if err != nil {
-				continue
-			}
-			dbQuest := player.GetDbQuest()
-			rollbackQuest := dbQuest.GetQuestById(uint32(rollbackQuestId))
-			if rollbackQuest.State != constant.QUEST_STATE_FAILED {
-				continue
-			}
-			rollbackQuest.State = constant.QUEST_STATE_UNSTARTED
-			g.StartQuest(player, rollbackQuest.QuestId, true)
-		default:
+		handler, exist := questExecHandlerMap[questExec.Type]
+		if !exist {
 			logger.Error("not support quest exec type: %v, questId: %v, uid: %v", questExec.Type, questId, player.PlayerId)
+			continue
 		}
+		handler(g, player, questId, questExec)
 	}
 }
 
@@ -500,148 +343,30 @@ func (g *Game) TriggerQuest(player *model.Player, cond int32, complexParam strin
 			if failCond.Type != cond {
 				continue
 			}
-			switch cond {
-			case constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY:
-				// LUA侧通知 复杂参数
-				if failCond.ComplexParam != complexParam {
-					continue
-				}
-				dbQuest.FailQuest(quest.QuestId)
-			case constant.QUEST_FINISH_COND_TYPE_COMPLETE_TALK:
-				// 与NPC对话 参数1:对话id
-				ok := matchParamEqual(failCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.FailQuest(quest.QuestId)
-			default:
+			handler, exist := questFailCondHandlerMap[cond]
+			if !exist {
 				logger.Error("not support quest fail cond type: %v, questId: %v, uid: %v", cond, quest.QuestId, player.PlayerId)
+				continue
+			}
+			if !handler(player, failCond, complexParam, param) {
+				continue
 			}
+			dbQuest.FailQuest(quest.QuestId)
 			updateQuestIdMap[quest.QuestId] = struct{}{}
 		}
 		for index, finishCond := range questDataConfig.FinishCondList {
 			if finishCond.Type != cond {
 				continue
 			}
-			switch cond {
-			case constant.QUEST_FINISH_COND_TYPE_FINISH_PLOT:
-				// 完成剧情 参数1:剧情id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_TRIGGER_FIRE:
-				// 场景触发器跳了 参数1:触发器id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_UNLOCK_TRANS_POINT:
-				// 解锁传送锚点 参数1:场景id 参数2:传送锚点id
-				dbWorld := player.GetDbWorld()
-				dbScene := dbWorld.GetSceneById(uint32(finishCond.Param[0]))
-				if dbScene == nil {
-					continue
-				}
-				unlock := dbScene.CheckPointUnlock(uint32(finishCond.Param[1]))
-				if !unlock {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_COMPLETE_TALK:
-				// 与NPC对话 参数1:对话id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY:
-				// LUA侧通知 复杂参数
-				if finishCond.ComplexParam != complexParam {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_SKILL:
-				// 使用技能 参数1:技能id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_OBTAIN_ITEM:
-				// 获得道具 参数1:道具id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_UNLOCK_AREA:
-				// 解锁场景区域 参数1:场景id 参数2:场景区域id
-				dbWorld := player.GetDbWorld()
-				dbScene := dbWorld.GetSceneById(uint32(finishCond.Param[0]))
-				if dbScene == nil {
-					continue
-				}
-				unlock := dbScene.CheckAreaUnlock(uint32(finishCond.Param[1]))
-				if !unlock {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_ADD_QUEST_PROGRESS:
-				// TODO 这你妈到底是加父任务的进度还是子任务的进度
+			handler, exist := questFinishCondHandlerMap[cond]
+			if !exist {
+				logger.Debug("not support quest finish cond type: %v, questId: %v, uid: %v", cond, quest.QuestId, player.PlayerId)
 				continue
-			case constant.QUEST_FINISH_COND_TYPE_ENTER_DUNGEON:
-				// 进入地牢 参数1:地牢id 参数2:传送锚点id
-				ok := matchParamEqual(finishCond.Param, param, 2)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_ENTER_MY_WORLD:
-				// 进入世界 参数1:场景id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_ENTER_ROOM:
-				// 进入房间 参数1:场景id
-				ok := matchParamEqual(finishCond.Param, param, 1)
-				if !ok {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			case constant.QUEST_FINISH_COND_TYPE_GAME_TIME_TICK:
-				// 游戏时间
-				split := strings.Split(finishCond.ComplexParam, ",")
-				if len(split) != 2 {
-					continue
-				}
-				split0, err := strconv.Atoi(split[0])
-				if err != nil {
-					continue
-				}
-				split1, err := strconv.Atoi(split[1])
-				if err != nil {
-					continue
-				}
-				startGameTimeHour := uint32(split0)
-				endGameTimeHour := uint32(split1)
-				world := WORLD_MANAGER.GetWorldById(player.WorldId)
-				if world == nil {
-					continue
-				}
-				gameTime := world.GetGameTime()
-				gameTimeHour := gameTime / 60
-				if gameTimeHour < startGameTimeHour || gameTimeHour > endGameTimeHour {
-					continue
-				}
-				dbQuest.AddQuestFinishCount(quest.QuestId, index)
-			default:
-				logger.Error("not support quest finish cond type: %v, questId: %v, uid: %v", cond, quest.QuestId, player.PlayerId)
 			}
+			if !handler(player, finishCond, complexParam, param) {
+				continue
+			}
+			dbQuest.AddQuestFinishCount(quest.QuestId, index)
 			updateQuestIdMap[quest.QuestId] = struct{}{}
 		}
 		dbQuest.CheckQuestFinish(quest.QuestId)
@@ -677,6 +402,9 @@ func (g *Game) TriggerQuest(player *model.Player, cond int32, complexParam strin
 func (g *Game) FinishQuest(player *model.Player, questId uint32) {
 	// 任务完成执行
 	g.ExecQuest(player, questId, QuestExecTypeFinish)
+	g.triggerQuestStateChangeEvent(player, questId, constant.QUEST_STATE_FINISHED)
+	// 活动进度
+	g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_FINISH_QUEST, int32(questId), 1)
 	// 任务完成发奖
 	questDataConfig := gdconf.GetQuestDataById(int32(questId))
 	if questDataConfig == nil {
@@ -713,6 +441,8 @@ func (g *Game) FinishQuest(player *model.Player, questId uint32) {
 				ChapterState: proto.ChapterState_CHAPTER_STATE_END,
 				ChapterId:    uint32(chapterDataConfig.ChapterId),
 			})
+			// 图鉴解锁
+			g.CodexFinishChapter(player, uint32(chapterDataConfig.ChapterId))
 		}
 	}
 }
@@ -721,6 +451,17 @@ func (g *Game) FinishQuest(player *model.Player, questId uint32) {
 func (g *Game) FailQuest(player *model.Player, questId uint32) {
 	// 任务失败执行
 	g.ExecQuest(player, questId, QuestExecTypeFail)
+	g.triggerQuestStateChangeEvent(player, questId, constant.QUEST_STATE_FAILED)
+}
+
+func (g *Game) triggerQuestStateChangeEvent(player *model.Player, questId uint32, state uint8) {
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdQuestStateChange, &PluginEventQuestStateChange{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		QuestId:     questId,
+		State:       state,
+	})
 }
 
 /************************************************** 打包封装 **************************************************/
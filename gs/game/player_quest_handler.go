@@ -0,0 +1,346 @@
+package game
+
+import (
+	"strconv"
+	"strings"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 任务条件与执行的处理方法
+// 按类型注册处理函数 任务引擎与离线任务模拟器都以此为准判断类型是否已实现
+
+// QuestAcceptCondHandler 判断任务领取条件是否满足
+type QuestAcceptCondHandler func(dbQuest *model.DbQuest, acceptCond *gdconf.QuestCond) bool
+
+// QuestCondHandler 判断触发的参数是否满足任务完成或失败条件
+type QuestCondHandler func(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool
+
+// QuestExecHandler 执行任务动作
+type QuestExecHandler func(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec)
+
+var questAcceptCondHandlerMap = make(map[int32]QuestAcceptCondHandler)
+var questFinishCondHandlerMap = make(map[int32]QuestCondHandler)
+var questFailCondHandlerMap = make(map[int32]QuestCondHandler)
+var questExecHandlerMap = make(map[int32]QuestExecHandler)
+
+func RegQuestAcceptCondHandler(condType int32, handler QuestAcceptCondHandler) {
+	questAcceptCondHandlerMap[condType] = handler
+}
+
+func RegQuestFinishCondHandler(condType int32, handler QuestCondHandler) {
+	questFinishCondHandlerMap[condType] = handler
+}
+
+func RegQuestFailCondHandler(condType int32, handler QuestCondHandler) {
+	questFailCondHandlerMap[condType] = handler
+}
+
+func RegQuestExecHandler(execType int32, handler QuestExecHandler) {
+	questExecHandlerMap[execType] = handler
+}
+
+// RegQuestHandler 注册任务条件与执行的处理方法
+func RegQuestHandler() {
+	RegQuestAcceptCondHandler(constant.QUEST_ACCEPT_COND_TYPE_STATE_EQUAL, QuestAcceptCondStateEqual)
+	RegQuestAcceptCondHandler(constant.QUEST_ACCEPT_COND_TYPE_STATE_NOT_EQUAL, QuestAcceptCondStateNotEqual)
+
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_FINISH_PLOT, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_TRIGGER_FIRE, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_UNLOCK_TRANS_POINT, QuestCondUnlockTransPoint)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_COMPLETE_TALK, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY, QuestCondLuaNotify)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_SKILL, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_OBTAIN_ITEM, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_UNLOCK_AREA, QuestCondUnlockArea)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_ENTER_DUNGEON, QuestCondParamEqual2)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_ENTER_MY_WORLD, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_ENTER_ROOM, QuestCondParamEqual1)
+	RegQuestFinishCondHandler(constant.QUEST_FINISH_COND_TYPE_GAME_TIME_TICK, QuestCondGameTimeTick)
+
+	RegQuestFailCondHandler(constant.QUEST_FINISH_COND_TYPE_LUA_NOTIFY, QuestCondLuaNotify)
+	RegQuestFailCondHandler(constant.QUEST_FINISH_COND_TYPE_COMPLETE_TALK, QuestCondParamEqual1)
+
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_NOTIFY_GROUP_LUA, QuestExecNotifyGroupLua)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_REFRESH_GROUP_SUITE, QuestExecRefreshGroupSuite)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_OPEN_STATE, QuestExecSetOpenState)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_UNLOCK_POINT, QuestExecUnlockPoint)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_UNLOCK_AREA, QuestExecUnlockArea)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_CHANGE_AVATAR_ELEMET, QuestExecChangeAvatarElement)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_IS_FLYABLE, QuestExecSetPlayerProp(constant.PLAYER_PROP_IS_FLYABLE))
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_IS_WEATHER_LOCKED, QuestExecSetPlayerProp(constant.PLAYER_PROP_IS_WEATHER_LOCKED))
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_IS_GAME_TIME_LOCKED, QuestExecSetPlayerProp(constant.PLAYER_PROP_IS_GAME_TIME_LOCKED))
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_IS_TRANSFERABLE, QuestExecSetPlayerProp(constant.PLAYER_PROP_IS_TRANSFERABLE))
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_SET_GAME_TIME, QuestExecSetGameTime)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_ROLLBACK_QUEST, QuestExecRollbackQuest)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_GRANT_TRIAL_AVATAR, QuestExecGrantTrialAvatar)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_REMOVE_TRIAL_AVATAR, QuestExecRemoveTrialAvatar)
+	RegQuestExecHandler(constant.QUEST_EXEC_TYPE_GRANT_TRIAL_AVATAR_AND_LOCK_TEAM, QuestExecGrantTrialAvatar)
+}
+
+/************************************************** 领取条件 **************************************************/
+
+// QuestAcceptCondStateEqual 某个任务状态等于 参数1:任务id 参数2:任务状态
+func QuestAcceptCondStateEqual(dbQuest *model.DbQuest, acceptCond *gdconf.QuestCond) bool {
+	if len(acceptCond.Param) != 2 {
+		return false
+	}
+	quest := dbQuest.GetQuestById(uint32(acceptCond.Param[0]))
+	if quest == nil {
+		return false
+	}
+	return quest.State == uint8(acceptCond.Param[1])
+}
+
+// QuestAcceptCondStateNotEqual 某个任务状态不等于 参数1:任务id 参数2:任务状态
+func QuestAcceptCondStateNotEqual(dbQuest *model.DbQuest, acceptCond *gdconf.QuestCond) bool {
+	if len(acceptCond.Param) != 2 {
+		return false
+	}
+	quest := dbQuest.GetQuestById(uint32(acceptCond.Param[0]))
+	if quest == nil {
+		return false
+	}
+	return quest.State != uint8(acceptCond.Param[1])
+}
+
+/************************************************** 完成与失败条件 **************************************************/
+
+// QuestCondParamEqual1 参数1与触发参数相同 如剧情id 触发器id 对话id 技能id 道具id 场景id
+func QuestCondParamEqual1(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	return matchParamEqual(cond.Param, param, 1)
+}
+
+// QuestCondParamEqual2 参数1与参数2与触发参数相同 如进入地牢 参数1:地牢id 参数2:传送锚点id
+func QuestCondParamEqual2(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	return matchParamEqual(cond.Param, param, 2)
+}
+
+// QuestCondLuaNotify LUA侧通知 复杂参数
+func QuestCondLuaNotify(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	return cond.ComplexParam == complexParam
+}
+
+// QuestCondUnlockTransPoint 解锁传送锚点 参数1:场景id 参数2:传送锚点id
+func QuestCondUnlockTransPoint(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	if len(cond.Param) != 2 {
+		return false
+	}
+	dbScene := player.GetDbWorld().GetSceneById(uint32(cond.Param[0]))
+	if dbScene == nil {
+		return false
+	}
+	return dbScene.CheckPointUnlock(uint32(cond.Param[1]))
+}
+
+// QuestCondUnlockArea 解锁场景区域 参数1:场景id 参数2:场景区域id
+func QuestCondUnlockArea(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	if len(cond.Param) != 2 {
+		return false
+	}
+	dbScene := player.GetDbWorld().GetSceneById(uint32(cond.Param[0]))
+	if dbScene == nil {
+		return false
+	}
+	return dbScene.CheckAreaUnlock(uint32(cond.Param[1]))
+}
+
+// QuestCondGameTimeTick 游戏时间 复杂参数:开始小时,结束小时
+func QuestCondGameTimeTick(player *model.Player, cond *gdconf.QuestCond, complexParam string, param []int32) bool {
+	split := strings.Split(cond.ComplexParam, ",")
+	if len(split) != 2 {
+		return false
+	}
+	split0, err := strconv.Atoi(split[0])
+	if err != nil {
+		return false
+	}
+	split1, err := strconv.Atoi(split[1])
+	if err != nil {
+		return false
+	}
+	startGameTimeHour := uint32(split0)
+	endGameTimeHour := uint32(split1)
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		return false
+	}
+	gameTimeHour := world.GetGameTime() / 60
+	return gameTimeHour >= startGameTimeHour && gameTimeHour <= endGameTimeHour
+}
+
+/************************************************** 执行 **************************************************/
+
+// QuestExecNotifyGroupLua 通知LUA侧
+func QuestExecNotifyGroupLua(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+}
+
+// QuestExecRefreshGroupSuite 刷新场景小组 参数2:小组id,小组suite
+func QuestExecRefreshGroupSuite(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 2 {
+		return
+	}
+	split := strings.Split(questExec.Param[1], ",")
+	if len(split) != 2 {
+		return
+	}
+	groupId, err := strconv.Atoi(split[0])
+	if err != nil {
+		return
+	}
+	suiteId, err := strconv.Atoi(split[1])
+	if err != nil {
+		return
+	}
+	g.RefreshSceneGroupSuite(player, uint32(groupId), uint8(suiteId))
+}
+
+// QuestExecSetOpenState 设置游戏功能开放状态 参数1:功能 参数2:状态
+func QuestExecSetOpenState(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 2 {
+		return
+	}
+	key, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	value, err := strconv.Atoi(questExec.Param[1])
+	if err != nil {
+		return
+	}
+	g.ChangePlayerOpenState(player.PlayerId, uint32(key), uint32(value))
+}
+
+// QuestExecUnlockPoint 解锁传送点 参数1:场景id 参数2:传送点id
+func QuestExecUnlockPoint(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 2 {
+		return
+	}
+	sceneId, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	pointId, err := strconv.Atoi(questExec.Param[1])
+	if err != nil {
+		return
+	}
+	g.UnlockPlayerScenePoint(player, uint32(sceneId), uint32(pointId))
+}
+
+// QuestExecUnlockArea 解锁场景区域 参数1:场景id 参数2:区域id
+func QuestExecUnlockArea(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 2 {
+		return
+	}
+	sceneId, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	areaId, err := strconv.Atoi(questExec.Param[1])
+	if err != nil {
+		return
+	}
+	g.UnlockPlayerSceneArea(player, uint32(sceneId), uint32(areaId))
+}
+
+// QuestExecChangeAvatarElement 改变主角元素类型 参数1:元素类型
+func QuestExecChangeAvatarElement(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 1 {
+		return
+	}
+	elementType, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	dbAvatar := player.GetDbAvatar()
+	g.ChangePlayerAvatarSkillDepot(player.PlayerId, dbAvatar.MainCharAvatarId, 0, elementType)
+}
+
+// QuestExecSetPlayerProp 设置玩家属性 如允许飞行 天气锁定 游戏时间锁定 允许传送 参数1:属性值
+func QuestExecSetPlayerProp(propType uint32) QuestExecHandler {
+	return func(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+		if len(questExec.Param) != 1 {
+			return
+		}
+		value, err := strconv.Atoi(questExec.Param[0])
+		if err != nil {
+			return
+		}
+		player.PropMap[propType] = uint32(value)
+		g.SendMsg(cmd.PlayerPropNotify, player.PlayerId, player.ClientSeq, g.PacketPlayerPropNotify(player, propType))
+	}
+}
+
+// QuestExecSetGameTime 设置游戏时间 参数1:小时
+func QuestExecSetGameTime(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 1 {
+		return
+	}
+	hour, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	if scene == nil {
+		logger.Error("scene is nil, sceneId: %v, uid: %v", player.GetSceneId(), player.PlayerId)
+		return
+	}
+	g.ChangeGameTime(world, uint32(hour*60))
+}
+
+// QuestExecRollbackQuest 回滚失败的任务 参数1:任务id
+func QuestExecRollbackQuest(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) != 1 {
+		return
+	}
+	rollbackQuestId, err := strconv.Atoi(questExec.Param[0])
+	if err != nil {
+		return
+	}
+	dbQuest := player.GetDbQuest()
+	rollbackQuest := dbQuest.GetQuestById(uint32(rollbackQuestId))
+	if rollbackQuest.State != constant.QUEST_STATE_FAILED {
+		return
+	}
+	rollbackQuest.State = constant.QUEST_STATE_UNSTARTED
+	g.StartQuest(player, rollbackQuest.QuestId, true)
+}
+
+// QuestExecGrantTrialAvatar 发放试用角色 参数1:试用角色id列表
+func QuestExecGrantTrialAvatar(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) < 1 {
+		return
+	}
+	trialAvatarIdList := parseQuestTrialAvatarIdList(questExec.Param[0])
+	if len(trialAvatarIdList) == 0 {
+		return
+	}
+	lockTeam := questExec.Type == constant.QUEST_EXEC_TYPE_GRANT_TRIAL_AVATAR_AND_LOCK_TEAM
+	ret := g.GrantPlayerTrialAvatar(player, trialAvatarIdList, lockTeam)
+	if ret != proto.Retcode_RET_SUCC {
+		logger.Error("grant trial avatar error, ret: %v, questId: %v, uid: %v", ret, questId, player.PlayerId)
+	}
+}
+
+// QuestExecRemoveTrialAvatar 移除试用角色 参数1:试用角色id列表
+func QuestExecRemoveTrialAvatar(g *Game, player *model.Player, questId uint32, questExec *gdconf.QuestExec) {
+	if len(questExec.Param) < 1 {
+		return
+	}
+	trialAvatarIdList := parseQuestTrialAvatarIdList(questExec.Param[0])
+	if len(trialAvatarIdList) == 0 {
+		return
+	}
+	g.RemovePlayerTrialAvatar(player, trialAvatarIdList)
+}
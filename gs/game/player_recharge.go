@@ -0,0 +1,247 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	"hk4e/common/constant"
+	"hk4e/common/mq"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+	"hk4e/protocol/proto_log"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	MaxUnpaidOrderNum = 5 // 同时存在的待支付订单数量上限
+)
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) RechargeReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.RechargeReq)
+	if req.McoinProduct == nil {
+		// 目前只支持创世结晶充值
+		g.SendError(cmd.RechargeRsp, player, &proto.RechargeRsp{}, proto.Retcode_RET_PRODUCT_NOT_EXIST)
+		return
+	}
+	if g.payProvider == nil {
+		g.SendError(cmd.RechargeRsp, player, &proto.RechargeRsp{})
+		return
+	}
+	order, ret := g.CreatePlayerOrder(player, req.McoinProduct.ProductId, uint32(clock.Now().Unix()))
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.RechargeRsp, player, &proto.RechargeRsp{}, ret)
+		return
+	}
+	err := g.payProvider.CreateOrder(player.PlayerId, order)
+	if err != nil {
+		logger.Error("pay provider create order error: %v, provider: %v, uid: %v, orderId: %v", err, g.payProvider.Name(), player.PlayerId, order.OrderId)
+		player.GetDbRecharge().DeleteOrder(order.OrderId)
+		g.SendError(cmd.RechargeRsp, player, &proto.RechargeRsp{})
+		return
+	}
+	g.LogOrderAdd(player, order)
+	g.SendMsg(cmd.RechargeRsp, player.PlayerId, player.ClientSeq, &proto.RechargeRsp{ProductId: order.ProductId})
+}
+
+// ServerRechargeOrderNotify 支付渠道回调的订单支付成功通知
+func (g *Game) ServerRechargeOrderNotify(userId uint32, rechargeOrder *mq.RechargeOrderInfo) {
+	player := USER_MANAGER.GetOnlineUser(userId)
+	if player == nil {
+		// 玩家不在本服在线 等登录时对账发货
+		return
+	}
+	order := player.GetDbRecharge().GetOrder(rechargeOrder.OrderId)
+	if order == nil || order.ProductId != rechargeOrder.ProductId {
+		logger.Error("recharge order not match, uid: %v, rechargeOrder: %v", userId, rechargeOrder)
+		return
+	}
+	ret := g.PayPlayerOrder(player, rechargeOrder.OrderId, rechargeOrder.TradeNo, uint32(clock.Now().Unix()))
+	if ret != proto.Retcode_RET_SUCC {
+		logger.Info("recharge order pay ignore, ret: %v, uid: %v, orderId: %v", ret, userId, rechargeOrder.OrderId)
+		return
+	}
+	g.DeliverPlayerOrder(player, rechargeOrder.OrderId, false)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// CreatePlayerOrder 创建创世结晶充值订单
+func (g *Game) CreatePlayerOrder(player *model.Player, productId string, now uint32) (*model.Order, proto.Retcode) {
+	rechargePrimogemDataConfig := gdconf.GetRechargePrimogemDataByProductId(productId)
+	if rechargePrimogemDataConfig == nil {
+		return nil, proto.Retcode_RET_PRODUCT_NOT_EXIST
+	}
+	dbRecharge := player.GetDbRecharge()
+	if len(dbRecharge.GetOrderListByState(model.OrderStateCreate)) >= MaxUnpaidOrderNum {
+		return nil, proto.Retcode_RET_UNFINISH_ORDER
+	}
+	order := dbRecharge.CreateOrder(productId, uint32(rechargePrimogemDataConfig.ConfigId), rechargePrimogemDataConfig.PriceTier, now)
+	return order, proto.Retcode_RET_SUCC
+}
+
+// PayPlayerOrder 订单确认支付
+func (g *Game) PayPlayerOrder(player *model.Player, orderId uint32, tradeNo string, now uint32) proto.Retcode {
+	dbRecharge := player.GetDbRecharge()
+	order := dbRecharge.GetOrder(orderId)
+	if order == nil {
+		return proto.Retcode_RET_ORDER_INFO_NOT_EXIST
+	}
+	if order.State == model.OrderStateFinish {
+		return proto.Retcode_RET_ORDER_FINISHED
+	}
+	if !dbRecharge.PayOrder(orderId, tradeNo, now) {
+		// 重复的支付通知
+		return proto.Retcode_RET_ORDER_TRADE_EARLY
+	}
+	return proto.Retcode_RET_SUCC
+}
+
+// SettlePlayerOrder 结算已支付订单 计算应发放的创世结晶数量 每个订单只会结算成功一次
+func (g *Game) SettlePlayerOrder(player *model.Player, orderId uint32, now uint32) (uint32, proto.Retcode) {
+	dbRecharge := player.GetDbRecharge()
+	order := dbRecharge.GetOrder(orderId)
+	if order == nil {
+		return 0, proto.Retcode_RET_ORDER_INFO_NOT_EXIST
+	}
+	switch order.State {
+	case model.OrderStateCreate:
+		return 0, proto.Retcode_RET_UNFINISH_ORDER
+	case model.OrderStateFinish:
+		return 0, proto.Retcode_RET_ORDER_FINISHED
+	}
+	rechargePrimogemDataConfig := gdconf.GetRechargePrimogemDataByProductId(order.ProductId)
+	if rechargePrimogemDataConfig == nil {
+		return 0, proto.Retcode_RET_PRODUCT_NOT_EXIST
+	}
+	isFirstBuy := dbRecharge.IsFirstBuy(order.ConfigId)
+	addMcoin := uint32(rechargePrimogemDataConfig.McoinBase)
+	if isFirstBuy {
+		// 首充双倍
+		addMcoin += uint32(rechargePrimogemDataConfig.McoinFirst)
+	} else {
+		addMcoin += uint32(rechargePrimogemDataConfig.McoinNonFirst)
+	}
+	if !dbRecharge.FinishOrder(orderId, addMcoin, isFirstBuy, now) {
+		return 0, proto.Retcode_RET_ORDER_FINISHED
+	}
+	return addMcoin, proto.Retcode_RET_SUCC
+}
+
+// DeliverPlayerOrder 已支付订单发货
+func (g *Game) DeliverPlayerOrder(player *model.Player, orderId uint32, isRetry bool) bool {
+	addMcoin, ret := g.SettlePlayerOrder(player, orderId, uint32(clock.Now().Unix()))
+	if ret != proto.Retcode_RET_SUCC {
+		logger.Error("settle order error, ret: %v, uid: %v, orderId: %v", ret, player.PlayerId, orderId)
+		return false
+	}
+	order := player.GetDbRecharge().GetOrder(orderId)
+	g.AddPlayerItem(player.PlayerId, []*ChangeItem{{ItemId: constant.ITEM_ID_MCOIN, ChangeCount: addMcoin}}, proto.ActionReasonType_ACTION_REASON_RECHARGE)
+	g.LogOrderFinish(player, order, isRetry)
+	g.SendMsg(cmd.OrderFinishNotify, player.PlayerId, player.ClientSeq, &proto.OrderFinishNotify{
+		OrderId:   order.OrderId,
+		AddMcoin:  addMcoin,
+		ProductId: order.ProductId,
+	})
+	g.SendMsg(cmd.OrderDisplayNotify, player.PlayerId, player.ClientSeq, &proto.OrderDisplayNotify{OrderId: order.OrderId})
+	return true
+}
+
+// QueryPlayerOrder 向支付渠道查询全部待支付订单 返回已支付待发货的订单id列表
+func (g *Game) QueryPlayerOrder(player *model.Player, now uint32) []uint32 {
+	dbRecharge := player.GetDbRecharge()
+	if g.payProvider != nil {
+		for _, order := range dbRecharge.GetOrderListByState(model.OrderStateCreate) {
+			paid, tradeNo, err := g.payProvider.QueryOrder(player.PlayerId, order)
+			if err != nil {
+				logger.Error("pay provider query order error: %v, provider: %v, uid: %v, orderId: %v", err, g.payProvider.Name(), player.PlayerId, order.OrderId)
+				continue
+			}
+			if !paid {
+				continue
+			}
+			g.PayPlayerOrder(player, order.OrderId, tradeNo, now)
+		}
+	}
+	orderIdList := make([]uint32, 0)
+	for _, order := range dbRecharge.GetOrderListByState(model.OrderStatePaid) {
+		orderIdList = append(orderIdList, order.OrderId)
+	}
+	return orderIdList
+}
+
+// ReconcilePlayerOrder 对账 补发漏掉的已支付订单 返回补发的订单数量
+func (g *Game) ReconcilePlayerOrder(player *model.Player) int {
+	count := 0
+	for _, orderId := range g.QueryPlayerOrder(player, uint32(clock.Now().Unix())) {
+		if g.DeliverPlayerOrder(player, orderId, true) {
+			count++
+		}
+	}
+	if count > 0 {
+		logger.Info("reconcile order finish, uid: %v, count: %v", player.PlayerId, count)
+	}
+	return count
+}
+
+// LogOrderAdd 下单日志
+func (g *Game) LogOrderAdd(player *model.Player, order *model.Order) {
+	logger.Info("[ORDER_LOG] head: %v, body: %v",
+		g.PacketOrderLogHead(proto_log.OrderActionType_ORDER_ACTION_ADD),
+		&proto_log.OrderLogBodyAdd{
+			OrderId:   order.OrderId,
+			Uid:       player.PlayerId,
+			ProductId: order.ProductId,
+			PriceTier: order.PriceTier,
+			PayPlat:   g.payProvider.Name(),
+		})
+}
+
+// LogOrderFinish 发货日志
+func (g *Game) LogOrderFinish(player *model.Player, order *model.Order, isRetry bool) {
+	logger.Info("[ORDER_LOG] head: %v, body: %v, tradeNo: %v, addMcoin: %v",
+		g.PacketOrderLogHead(proto_log.OrderActionType_ORDER_ACTION_FINISH),
+		&proto_log.OrderLogBodyFinish{
+			OrderId:    order.OrderId,
+			Uid:        player.PlayerId,
+			FinishTime: order.FinishTime,
+			IsRetry:    isRetry,
+		},
+		order.TradeNo, order.AddMcoin)
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketPlayerRechargeDataNotify() *proto.PlayerRechargeDataNotify {
+	ntf := &proto.PlayerRechargeDataNotify{
+		ProductPriceTierList: make([]*proto.ProductPriceTier, 0),
+	}
+	for productId := range gdconf.GetProductIdDataMap() {
+		rechargePrimogemDataConfig := gdconf.GetRechargePrimogemDataByProductId(productId)
+		if rechargePrimogemDataConfig == nil {
+			continue
+		}
+		ntf.ProductPriceTierList = append(ntf.ProductPriceTierList, &proto.ProductPriceTier{
+			ProductId: productId,
+			PriceTier: rechargePrimogemDataConfig.PriceTier,
+		})
+	}
+	sort.Slice(ntf.ProductPriceTierList, func(i, j int) bool {
+		return ntf.ProductPriceTierList[i].ProductId < ntf.ProductPriceTierList[j].ProductId
+	})
+	return ntf
+}
+
+func (g *Game) PacketOrderLogHead(action proto_log.OrderActionType) *proto_log.OrderLogHead {
+	return &proto_log.OrderLogHead{
+		Time:       clock.Now().Format(time.DateTime),
+		ActionId:   uint32(action),
+		ActionName: action.String(),
+	}
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpapi "hk4e/dispatch/api"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+func newTestRechargeConfig(t *testing.T) {
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		ProductIdDataMap: map[string]*gdconf.ProductIdData{
+			"ys_chn_primogem_tier_1": {ProductId: "ys_chn_primogem_tier_1", ConfigId: 1},
+			"ys_glb_primogem_tier_1": {ProductId: "ys_glb_primogem_tier_1", ConfigId: 1},
+			"ys_chn_primogem_tier_5": {ProductId: "ys_chn_primogem_tier_5", ConfigId: 5},
+		},
+		RechargePrimogemDataMap: map[int32]*gdconf.RechargePrimogemData{
+			1: {ConfigId: 1, PriceTier: "Tier_1", McoinBase: 60, McoinNonFirst: 0, McoinFirst: 60},
+			5: {ConfigId: 5, PriceTier: "Tier_5", McoinBase: 3280, McoinNonFirst: 600, McoinFirst: 3280},
+		},
+	})
+}
+
+type fakePayProvider struct {
+	paidMap map[uint32]string
+}
+
+func (f *fakePayProvider) Name() string {
+	return "fake"
+}
+
+func (f *fakePayProvider) CreateOrder(uid uint32, order *model.Order) error {
+	return nil
+}
+
+func (f *fakePayProvider) QueryOrder(uid uint32, order *model.Order) (bool, string, error) {
+	if order.OrderId == 3 {
+		return false, "", errors.New("query timeout")
+	}
+	tradeNo, exist := f.paidMap[order.OrderId]
+	return exist, tradeNo, nil
+}
+
+func TestRechargeOrderSettle(t *testing.T) {
+	newTestRechargeConfig(t)
+	g := new(Game)
+	player := &model.Player{PlayerId: 10001}
+
+	if _, ret := g.CreatePlayerOrder(player, "ys_chn_primogem_tier_9", 1000); ret != proto.Retcode_RET_PRODUCT_NOT_EXIST {
+		t.Fatalf("unknown product should be refused, ret: %v", ret)
+	}
+	order, ret := g.CreatePlayerOrder(player, "ys_chn_primogem_tier_5", 1000)
+	if ret != proto.Retcode_RET_SUCC || order.ConfigId != 5 || order.PriceTier != "Tier_5" {
+		t.Fatalf("create order error, ret: %v, order: %+v", ret, order)
+	}
+	if _, ret = g.SettlePlayerOrder(player, order.OrderId, 1001); ret != proto.Retcode_RET_UNFINISH_ORDER {
+		t.Fatalf("unpaid order should not settle, ret: %v", ret)
+	}
+	if ret = g.PayPlayerOrder(player, order.OrderId, "trade_1", 1002); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("pay order error, ret: %v", ret)
+	}
+	// 支付渠道重复回调
+	if ret = g.PayPlayerOrder(player, order.OrderId, "trade_1", 1003); ret != proto.Retcode_RET_ORDER_TRADE_EARLY {
+		t.Fatalf("repeat pay should be ignored, ret: %v", ret)
+	}
+	// 首充双倍
+	addMcoin, ret := g.SettlePlayerOrder(player, order.OrderId, 1004)
+	if ret != proto.Retcode_RET_SUCC || addMcoin != 3280+3280 || !order.IsFirstBuy {
+		t.Fatalf("first buy settle error, ret: %v, addMcoin: %v", ret, addMcoin)
+	}
+	if _, ret = g.SettlePlayerOrder(player, order.OrderId, 1005); ret != proto.Retcode_RET_ORDER_FINISHED {
+		t.Fatalf("order should settle only once, ret: %v", ret)
+	}
+	if ret = g.PayPlayerOrder(player, order.OrderId, "trade_1", 1006); ret != proto.Retcode_RET_ORDER_FINISHED {
+		t.Fatalf("finished order pay should be refused, ret: %v", ret)
+	}
+	// 非首充只有额外赠送
+	order, _ = g.CreatePlayerOrder(player, "ys_chn_primogem_tier_5", 1007)
+	g.PayPlayerOrder(player, order.OrderId, "trade_2", 1008)
+	if addMcoin, _ = g.SettlePlayerOrder(player, order.OrderId, 1009); addMcoin != 3280+600 || order.IsFirstBuy {
+		t.Fatalf("non first buy settle error, addMcoin: %v", addMcoin)
+	}
+	// 不同商品id对应同一档位 共享首充状态
+	order, _ = g.CreatePlayerOrder(player, "ys_chn_primogem_tier_1", 1010)
+	g.PayPlayerOrder(player, order.OrderId, "trade_3", 1011)
+	g.SettlePlayerOrder(player, order.OrderId, 1012)
+	order, _ = g.CreatePlayerOrder(player, "ys_glb_primogem_tier_1", 1013)
+	g.PayPlayerOrder(player, order.OrderId, "trade_4", 1014)
+	if addMcoin, _ = g.SettlePlayerOrder(player, order.OrderId, 1015); addMcoin != 60 {
+		t.Fatalf("shared config first buy error, addMcoin: %v", addMcoin)
+	}
+
+	// 待支付订单数量上限
+	for i := 0; i < MaxUnpaidOrderNum; i++ {
+		g.CreatePlayerOrder(player, "ys_chn_primogem_tier_1", 1016)
+	}
+	if _, ret = g.CreatePlayerOrder(player, "ys_chn_primogem_tier_1", 1016); ret != proto.Retcode_RET_UNFINISH_ORDER {
+		t.Fatalf("unpaid order num should be limited, ret: %v", ret)
+	}
+}
+
+func TestRechargeOrderQuery(t *testing.T) {
+	newTestRechargeConfig(t)
+	g := new(Game)
+	provider := &fakePayProvider{paidMap: map[uint32]string{1: "trade_1", 3: "trade_3"}}
+	g.payProvider = provider
+	player := &model.Player{PlayerId: 10001}
+	for i := 0; i < 4; i++ {
+		g.CreatePlayerOrder(player, "ys_chn_primogem_tier_1", 1000)
+	}
+	// 渠道回调已到达但还未发货的订单
+	g.PayPlayerOrder(player, 2, "trade_2", 1001)
+
+	// 订单1渠道已支付 订单2已支付未发货 订单3查询失败 订单4未支付
+	orderIdList := g.QueryPlayerOrder(player, 1002)
+	if len(orderIdList) != 2 || orderIdList[0] != 1 || orderIdList[1] != 2 {
+		t.Fatalf("query order error, orderIdList: %v", orderIdList)
+	}
+	dbRecharge := player.GetDbRecharge()
+	if dbRecharge.GetOrder(1).TradeNo != "trade_1" || dbRecharge.GetOrder(3).State != model.OrderStateCreate {
+		t.Fatalf("query order state error")
+	}
+	for _, orderId := range orderIdList {
+		g.SettlePlayerOrder(player, orderId, 1003)
+	}
+	// 已发货的订单不会再次查询出来
+	provider.paidMap[4] = "trade_4"
+	orderIdList = g.QueryPlayerOrder(player, 1004)
+	if len(orderIdList) != 1 || orderIdList[0] != 4 {
+		t.Fatalf("query order after settle error, orderIdList: %v", orderIdList)
+	}
+}
+
+func TestMockPayProvider(t *testing.T) {
+	const callbackKey = "test_key"
+	reqChan := make(chan *httpapi.PayCallbackReq, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(httpapi.PayCallbackReq)
+		_ = json.NewDecoder(r.Body).Decode(req)
+		rsp := &httpapi.PayCallbackRsp{RetCode: 0}
+		if req.Sign != httpapi.PayCallbackSign(req, callbackKey) {
+			rsp.RetCode = -2
+		}
+		_ = json.NewEncoder(w).Encode(rsp)
+		reqChan <- req
+	}))
+	defer server.Close()
+
+	provider := &MockPayProvider{CallbackUrl: server.URL, CallbackKey: callbackKey, ConfirmDelay: time.Millisecond * 10}
+	order := &model.Order{OrderId: 7, ProductId: "ys_chn_primogem_tier_1", CreateTime: uint32(time.Now().Unix())}
+	if paid, _, _ := provider.QueryOrder(10001, &model.Order{OrderId: 8, CreateTime: uint32(time.Now().Unix()) + 60}); paid {
+		t.Fatalf("order should not be paid before confirm delay")
+	}
+	if err := provider.CreateOrder(10001, order); err != nil {
+		t.Fatalf("mock create order error: %v", err)
+	}
+	select {
+	case req := <-reqChan:
+		if req.Uid != 10001 || req.OrderId != 7 || req.ProductId != order.ProductId || req.Sign != httpapi.PayCallbackSign(req, callbackKey) {
+			t.Fatalf("mock pay callback error, req: %+v", req)
+		}
+		paid, tradeNo, err := provider.QueryOrder(10001, &model.Order{OrderId: 7, CreateTime: order.CreateTime - 1})
+		if err != nil || !paid || tradeNo != req.TradeNo {
+			t.Fatalf("mock query order error, paid: %v, tradeNo: %v, err: %v", paid, tradeNo, err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("mock pay callback timeout")
+	}
+}
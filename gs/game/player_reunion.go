@@ -0,0 +1,487 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	ReunionOfflineDayDefault = 14 // 默认触发回归的最短离线天数
+	ReunionDurationDay       = 14 // 回归持续天数
+)
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) ReunionBriefInfoReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	rsp := &proto.ReunionBriefInfoRsp{
+		IsActivate: player.GetDbReunion().IsActive(now),
+	}
+	if rsp.IsActivate {
+		rsp.ReunionBriefInfo = g.PacketReunionBriefInfo(player, now)
+	}
+	g.SendMsg(cmd.ReunionBriefInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) TakeReunionFirstGiftRewardReq(player *model.Player, payloadMsg pb.Message) {
+	rewardId, ret := g.TakeReunionFirstGiftReward(player, clock.Now())
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.TakeReunionFirstGiftRewardRsp, player, &proto.TakeReunionFirstGiftRewardRsp{}, ret)
+		return
+	}
+	g.RewardItem(player.PlayerId, rewardId, proto.ActionReasonType_ACTION_REASON_REUNION_FIRST_GIFT_REWARD)
+	g.SendMsg(cmd.TakeReunionFirstGiftRewardRsp, player.PlayerId, player.ClientSeq, &proto.TakeReunionFirstGiftRewardRsp{RewardId: int32(rewardId)})
+}
+
+func (g *Game) GetReunionSignInInfoReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	if !player.GetDbReunion().IsActive(now) {
+		g.SendError(cmd.GetReunionSignInInfoRsp, player, &proto.GetReunionSignInInfoRsp{}, proto.Retcode_RET_REUNION_NOT_ACTIVATED)
+		return
+	}
+	rsp := &proto.GetReunionSignInInfoRsp{
+		SignInInfo: g.PacketReunionSignInInfo(player),
+	}
+	g.SendMsg(cmd.GetReunionSignInInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) TakeReunionSignInRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.TakeReunionSignInRewardReq)
+	rewardId, ret := g.TakeReunionSignInReward(player, req.RewardDay, clock.Now())
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.TakeReunionSignInRewardRsp, player, &proto.TakeReunionSignInRewardRsp{}, ret)
+		return
+	}
+	g.RewardItem(player.PlayerId, rewardId, proto.ActionReasonType_ACTION_REASON_REUNION_SIGN_IN_REWARD)
+	rsp := &proto.TakeReunionSignInRewardRsp{
+		SignInInfo: g.PacketReunionSignInInfo(player),
+	}
+	g.SendMsg(cmd.TakeReunionSignInRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) GetReunionMissionInfoReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	if !player.GetDbReunion().IsActive(now) {
+		g.SendError(cmd.GetReunionMissionInfoRsp, player, &proto.GetReunionMissionInfoRsp{}, proto.Retcode_RET_REUNION_NOT_ACTIVATED)
+		return
+	}
+	rsp := &proto.GetReunionMissionInfoRsp{
+		MissionInfo: g.PacketReunionMissionInfo(player, now),
+	}
+	g.SendMsg(cmd.GetReunionMissionInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) TakeReunionWatcherRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.TakeReunionWatcherRewardReq)
+	ret := g.TakeReunionWatcherReward(player, req.WatcherId, clock.Now())
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.TakeReunionWatcherRewardRsp, player, &proto.TakeReunionWatcherRewardRsp{}, ret)
+		return
+	}
+	rsp := &proto.TakeReunionWatcherRewardRsp{
+		MissionId: req.MissionId,
+		WatcherId: req.WatcherId,
+	}
+	g.SendMsg(cmd.TakeReunionWatcherRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) TakeReunionMissionRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.TakeReunionMissionRewardReq)
+	now := clock.Now()
+	rewardId, ret := g.TakeReunionMissionReward(player, req.RewardIndex, now)
+	if ret != proto.Retcode_RET_SUCC {
+		g.SendError(cmd.TakeReunionMissionRewardRsp, player, &proto.TakeReunionMissionRewardRsp{}, ret)
+		return
+	}
+	g.RewardItem(player.PlayerId, rewardId, proto.ActionReasonType_ACTION_REASON_REUNION_WATCHER_REWARD)
+	rsp := &proto.TakeReunionMissionRewardRsp{
+		RewardIndex: req.RewardIndex,
+		RewardId:    rewardId,
+		MissionInfo: g.PacketReunionMissionInfo(player, now),
+	}
+	g.SendMsg(cmd.TakeReunionMissionRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) GetReunionPrivilegeInfoReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	if !player.GetDbReunion().IsActive(now) {
+		g.SendError(cmd.GetReunionPrivilegeInfoRsp, player, &proto.GetReunionPrivilegeInfoRsp{}, proto.Retcode_RET_REUNION_NOT_ACTIVATED)
+		return
+	}
+	rsp := &proto.GetReunionPrivilegeInfoRsp{
+		PrivilegeInfo: g.PacketReunionPrivilegeInfo(player, now),
+	}
+	g.SendMsg(cmd.GetReunionPrivilegeInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// GetReunionOfflineDay 获取触发回归的最短离线天数
+func GetReunionOfflineDay() uint32 {
+	day := config.GetConfig().Hk4e.ReunionOfflineDay
+	if day <= 0 {
+		day = ReunionOfflineDayDefault
+	}
+	return uint32(day)
+}
+
+// CheckPlayerReunion 检查玩家回归状态 回归到期则结束 满足离线时长与等级要求则开启新的回归
+func (g *Game) CheckPlayerReunion(player *model.Player, now time.Time) (start bool, finish bool) {
+	dbReunion := player.GetDbReunion()
+	if dbReunion.ScheduleId != 0 && !dbReunion.IsActive(now) {
+		dbReunion.Finish()
+		finish = true
+		logger.Info("player reunion finish, uid: %v", player.PlayerId)
+	}
+	if dbReunion.IsActive(now) {
+		return false, finish
+	}
+	if !player.IsBorn || player.OfflineTime == 0 {
+		return false, finish
+	}
+	if dbReunion.StartTime >= player.OfflineTime {
+		// 本次离线已经触发过回归
+		return false, finish
+	}
+	if uint32(now.Unix()) < player.OfflineTime+GetReunionOfflineDay()*86400 {
+		return false, finish
+	}
+	scheduleDataConfig := gdconf.GetLatestReunionScheduleData()
+	if scheduleDataConfig == nil {
+		return false, finish
+	}
+	level := int32(player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL])
+	if level < scheduleDataConfig.Level {
+		return false, finish
+	}
+	watcherProgressMap := make(map[uint32]uint32)
+	missionDataConfig := gdconf.GetReunionMissionDataById(scheduleDataConfig.MissionId)
+	if missionDataConfig != nil {
+		for _, watcherDataConfig := range gdconf.GetReunionWatcherDataMap() {
+			if watcherDataConfig.GroupId != missionDataConfig.WatcherGroupId || watcherDataConfig.IsDisuse != 0 {
+				continue
+			}
+			if !watcherDataConfig.IsLevelMatch(level) {
+				continue
+			}
+			watcherProgressMap[uint32(watcherDataConfig.WatcherId)] = uint32(watcherDataConfig.Progress)
+		}
+	}
+	dbReunion.Start(uint32(scheduleDataConfig.ScheduleId), now, time.Hour*24*ReunionDurationDay, watcherProgressMap)
+	logger.Info("player reunion start, uid: %v, scheduleId: %v, offlineTime: %v", player.PlayerId, scheduleDataConfig.ScheduleId, player.OfflineTime)
+	return true, finish
+}
+
+// ReunionDayChange 跨天时刷新在线玩家的回归状态
+func (g *Game) ReunionDayChange(player *model.Player, now time.Time) {
+	dbReunion := player.GetDbReunion()
+	if dbReunion.ScheduleId == 0 {
+		return
+	}
+	_, finish := g.CheckPlayerReunion(player, now)
+	if finish {
+		g.SendMsg(cmd.ReunionSettleNotify, player.PlayerId, player.ClientSeq, &proto.ReunionSettleNotify{})
+		return
+	}
+	g.SendMsg(cmd.ReunionDailyRefreshNotify, player.PlayerId, player.ClientSeq, &proto.ReunionDailyRefreshNotify{
+		ReunionBriefInfo: g.PacketReunionBriefInfo(player, now),
+	})
+}
+
+// TakeReunionFirstGiftReward 领取回归见面礼 返回奖励id
+func (g *Game) TakeReunionFirstGiftReward(player *model.Player, now time.Time) (uint32, proto.Retcode) {
+	dbReunion := player.GetDbReunion()
+	if !dbReunion.IsActive(now) {
+		return 0, proto.Retcode_RET_REUNION_NOT_ACTIVATED
+	}
+	if dbReunion.IsTakenFirstGift {
+		return 0, proto.Retcode_RET_REUNION_ALREADY_TAKE_FIRST_REWARD
+	}
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return 0, proto.Retcode_RET_REUNION_FINISHED
+	}
+	dbReunion.IsTakenFirstGift = true
+	return uint32(scheduleDataConfig.FirstGiftRewardId), proto.Retcode_RET_SUCC
+}
+
+// TakeReunionSignInReward 领取回归签到奖励 每天一次 按天数顺序领取 返回奖励id
+func (g *Game) TakeReunionSignInReward(player *model.Player, rewardDay uint32, now time.Time) (uint32, proto.Retcode) {
+	dbReunion := player.GetDbReunion()
+	if !dbReunion.IsActive(now) {
+		return 0, proto.Retcode_RET_REUNION_NOT_ACTIVATED
+	}
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return 0, proto.Retcode_RET_REUNION_FINISHED
+	}
+	dayCount := uint32(gdconf.GetReunionSignInDayCount(scheduleDataConfig.SignInId))
+	if rewardDay != dbReunion.SignInCount+1 || !dbReunion.CanSignIn(now, dayCount) {
+		return 0, proto.Retcode_RET_REUNION_SIGN_IN_REWARDED
+	}
+	signInDataConfig := gdconf.GetReunionSignInDataByDay(scheduleDataConfig.SignInId, int32(rewardDay))
+	if signInDataConfig == nil {
+		return 0, proto.Retcode_RET_REUNION_SIGN_IN_REWARDED
+	}
+	dbReunion.SignIn(now)
+	return uint32(signInDataConfig.RewardId), proto.Retcode_RET_SUCC
+}
+
+// TriggerReunionWatcher 触发回归任务进度监听 由击杀消耗树脂等游戏事件调用
+func (g *Game) TriggerReunionWatcher(player *model.Player, triggerType int32, param int32, count uint32) {
+	dbReunion := player.GetDbReunion()
+	now := clock.Now()
+	if !dbReunion.IsActive(now) {
+		return
+	}
+	for _, watcherId := range AddReunionWatcherProgress(dbReunion, triggerType, param, count) {
+		g.SendMsg(cmd.UpdateReunionWatcherNotify, player.PlayerId, player.ClientSeq, &proto.UpdateReunionWatcherNotify{
+			MissionId:   g.GetPlayerReunionMissionId(player),
+			WatcherInfo: g.PacketReunionWatcherInfo(dbReunion, dbReunion.GetWatcher(watcherId)),
+		})
+	}
+}
+
+// AddReunionWatcherProgress 增加回归任务中匹配触发条件的监听进度 返回进度发生变化的监听id
+func AddReunionWatcherProgress(dbReunion *model.DbReunion, triggerType int32, param int32, count uint32) []uint32 {
+	changeList := make([]uint32, 0)
+	for watcherId := range dbReunion.WatcherMap {
+		watcherDataConfig := gdconf.GetReunionWatcherDataById(int32(watcherId))
+		if watcherDataConfig == nil || watcherDataConfig.TriggerType != triggerType {
+			continue
+		}
+		match := false
+		for _, triggerParam := range watcherDataConfig.TriggerParamList {
+			if triggerParam == param {
+				match = true
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		if dbReunion.AddWatcherProgress(watcherId, count) {
+			changeList = append(changeList, watcherId)
+		}
+	}
+	sort.Slice(changeList, func(i, j int) bool {
+		return changeList[i] < changeList[j]
+	})
+	return changeList
+}
+
+// TakeReunionWatcherReward 领取回归任务监听奖励 获得任务积分
+func (g *Game) TakeReunionWatcherReward(player *model.Player, watcherId uint32, now time.Time) proto.Retcode {
+	dbReunion := player.GetDbReunion()
+	if !dbReunion.IsActive(now) {
+		return proto.Retcode_RET_REUNION_NOT_ACTIVATED
+	}
+	watcher := dbReunion.GetWatcher(watcherId)
+	if watcher == nil || !watcher.IsFinish() {
+		return proto.Retcode_RET_REUNION_WATCHER_NOT_FINISH
+	}
+	if watcher.IsTakenReward {
+		return proto.Retcode_RET_REUNION_WATCHER_REWARDED
+	}
+	watcherDataConfig := gdconf.GetReunionWatcherDataById(int32(watcherId))
+	if watcherDataConfig == nil {
+		logger.Error("get reunion watcher data config is nil, watcherId: %v", watcherId)
+		return proto.Retcode_RET_SVR_ERROR
+	}
+	if dbReunion.GetDay(now) <= uint32(watcherDataConfig.RewardDay) {
+		return proto.Retcode_RET_REUNION_WATCHER_REWARD_NOT_UNLOCKED
+	}
+	dbReunion.TakeWatcherReward(watcherId, uint32(watcherDataConfig.Score))
+	if watcherDataConfig.RewardId != 0 {
+		g.RewardItem(player.PlayerId, uint32(watcherDataConfig.RewardId), proto.ActionReasonType_ACTION_REASON_REUNION_WATCHER_REWARD)
+	}
+	g.SendMsg(cmd.UpdateReunionWatcherNotify, player.PlayerId, player.ClientSeq, &proto.UpdateReunionWatcherNotify{
+		MissionId:   g.GetPlayerReunionMissionId(player),
+		WatcherInfo: g.PacketReunionWatcherInfo(dbReunion, watcher),
+	})
+	return proto.Retcode_RET_SUCC
+}
+
+// TakeReunionMissionReward 领取回归任务的积分分档奖励 返回奖励id
+func (g *Game) TakeReunionMissionReward(player *model.Player, rewardIndex uint32, now time.Time) (uint32, proto.Retcode) {
+	dbReunion := player.GetDbReunion()
+	if !dbReunion.IsActive(now) {
+		return 0, proto.Retcode_RET_REUNION_NOT_ACTIVATED
+	}
+	missionDataConfig := gdconf.GetReunionMissionDataById(int32(g.GetPlayerReunionMissionId(player)))
+	if missionDataConfig == nil || int(rewardIndex) >= len(missionDataConfig.StageScoreList) || int(rewardIndex) >= len(missionDataConfig.StageRewardIdList) {
+		return 0, proto.Retcode_RET_REUNION_MISSION_NOT_FINISH
+	}
+	if dbReunion.MissionRewardMap[rewardIndex] {
+		return 0, proto.Retcode_RET_REUNION_MISSION_REWARDED
+	}
+	if dbReunion.MissionScore < uint32(missionDataConfig.StageScoreList[rewardIndex]) {
+		return 0, proto.Retcode_RET_REUNION_MISSION_NOT_FINISH
+	}
+	dbReunion.MissionRewardMap[rewardIndex] = true
+	return uint32(missionDataConfig.StageRewardIdList[rewardIndex]), proto.Retcode_RET_SUCC
+}
+
+// UseReunionPrivilege 消耗树脂领取奖励时尝试使用回归特权 成功则奖励翻倍
+func (g *Game) UseReunionPrivilege(player *model.Player, now time.Time) bool {
+	dbReunion := player.GetDbReunion()
+	if !dbReunion.IsActive(now) {
+		return false
+	}
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return false
+	}
+	privilegeDataConfig := gdconf.GetReunionPrivilegeDataById(scheduleDataConfig.PrivilegeId)
+	if privilegeDataConfig == nil {
+		return false
+	}
+	if !dbReunion.UsePrivilege(now, uint32(privilegeDataConfig.DailyCount), uint32(privilegeDataConfig.TotalCount)) {
+		return false
+	}
+	g.SendMsg(cmd.ReunionPrivilegeChangeNotify, player.PlayerId, player.ClientSeq, &proto.ReunionPrivilegeChangeNotify{
+		PrivilegeInfo: g.PacketReunionPrivilegeInfo(player, now),
+	})
+	return true
+}
+
+func (g *Game) GetPlayerReunionMissionId(player *model.Player) uint32 {
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(player.GetDbReunion().ScheduleId))
+	if scheduleDataConfig == nil {
+		return 0
+	}
+	return uint32(scheduleDataConfig.MissionId)
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketReunionBriefInfo(player *model.Player, now time.Time) *proto.ReunionBriefInfo {
+	dbReunion := player.GetDbReunion()
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return nil
+	}
+	missionInfo := g.PacketReunionMissionInfo(player, now)
+	missionHasReward := false
+	for _, watcherInfo := range missionInfo.WatcherList {
+		if watcherInfo.CurProgress >= watcherInfo.TotalProgress && !watcherInfo.IsTakenReward && watcherInfo.RewardUnlockTime <= uint32(now.Unix()) {
+			missionHasReward = true
+			break
+		}
+	}
+	dayCount := uint32(gdconf.GetReunionSignInDayCount(scheduleDataConfig.SignInId))
+	return &proto.ReunionBriefInfo{
+		FirstGiftRewardId: uint32(scheduleDataConfig.FirstGiftRewardId),
+		PrivilegeId:       uint32(scheduleDataConfig.PrivilegeId),
+		MissionId:         uint32(scheduleDataConfig.MissionId),
+		FirstDayStartTime: dbReunion.StartTime,
+		SignInHasReward:   dbReunion.CanSignIn(now, dayCount),
+		StartTime:         dbReunion.StartTime,
+		IsTakenFirstGift:  dbReunion.IsTakenFirstGift,
+		FinishTime:        dbReunion.FinishTime,
+		MissionHasReward:  missionHasReward,
+		PrivilegeInfo:     g.PacketReunionPrivilegeInfo(player, now),
+		SignInConfigId:    uint32(scheduleDataConfig.SignInId),
+	}
+}
+
+func (g *Game) PacketReunionSignInInfo(player *model.Player) *proto.ReunionSignInInfo {
+	dbReunion := player.GetDbReunion()
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return nil
+	}
+	rewardDayList := make([]uint32, 0, dbReunion.SignInCount)
+	for day := uint32(1); day <= dbReunion.SignInCount; day++ {
+		rewardDayList = append(rewardDayList, day)
+	}
+	return &proto.ReunionSignInInfo{
+		SignInCount:    dbReunion.SignInCount,
+		RewardDayList:  rewardDayList,
+		ConfigId:       uint32(scheduleDataConfig.SignInId),
+		LastSignInTime: dbReunion.LastSignInTime,
+	}
+}
+
+func (g *Game) PacketReunionMissionInfo(player *model.Player, now time.Time) *proto.ReunionMissionInfo {
+	dbReunion := player.GetDbReunion()
+	missionInfo := &proto.ReunionMissionInfo{
+		MissionId:         g.GetPlayerReunionMissionId(player),
+		CurScore:          dbReunion.MissionScore,
+		WatcherList:       make([]*proto.ReunionWatcherInfo, 0),
+		CurDayWatcherList: make([]*proto.ReunionWatcherInfo, 0),
+		IsTakenRewardList: make([]bool, 0),
+		NextRefreshTime:   dbReunion.GetDayStartTime(dbReunion.GetDay(now) + 1),
+	}
+	day := dbReunion.GetDay(now)
+	for _, watcher := range dbReunion.WatcherMap {
+		watcherInfo := g.PacketReunionWatcherInfo(dbReunion, watcher)
+		missionInfo.WatcherList = append(missionInfo.WatcherList, watcherInfo)
+		watcherDataConfig := gdconf.GetReunionWatcherDataById(int32(watcher.WatcherId))
+		if watcherDataConfig != nil && uint32(watcherDataConfig.RewardDay)+1 == day {
+			missionInfo.CurDayWatcherList = append(missionInfo.CurDayWatcherList, watcherInfo)
+		}
+	}
+	sort.Slice(missionInfo.WatcherList, func(i, j int) bool {
+		return missionInfo.WatcherList[i].WatcherId < missionInfo.WatcherList[j].WatcherId
+	})
+	sort.Slice(missionInfo.CurDayWatcherList, func(i, j int) bool {
+		return missionInfo.CurDayWatcherList[i].WatcherId < missionInfo.CurDayWatcherList[j].WatcherId
+	})
+	missionDataConfig := gdconf.GetReunionMissionDataById(int32(missionInfo.MissionId))
+	if missionDataConfig != nil {
+		isFinished := true
+		for index, score := range missionDataConfig.StageScoreList {
+			isTaken := dbReunion.MissionRewardMap[uint32(index)]
+			missionInfo.IsTakenRewardList = append(missionInfo.IsTakenRewardList, isTaken)
+			if !isTaken {
+				isFinished = false
+			}
+			if dbReunion.MissionScore < uint32(score) {
+				isFinished = false
+			}
+		}
+		missionInfo.IsFinished = isFinished
+		missionInfo.IsTakenReward = isFinished
+	}
+	return missionInfo
+}
+
+func (g *Game) PacketReunionWatcherInfo(dbReunion *model.DbReunion, watcher *model.ActivityWatcher) *proto.ReunionWatcherInfo {
+	rewardUnlockTime := dbReunion.StartTime
+	watcherDataConfig := gdconf.GetReunionWatcherDataById(int32(watcher.WatcherId))
+	if watcherDataConfig != nil {
+		rewardUnlockTime = dbReunion.GetDayStartTime(uint32(watcherDataConfig.RewardDay) + 1)
+	}
+	return &proto.ReunionWatcherInfo{
+		WatcherId:        watcher.WatcherId,
+		CurProgress:      watcher.CurProgress,
+		TotalProgress:    watcher.TotalProgress,
+		IsTakenReward:    watcher.IsTakenReward,
+		RewardUnlockTime: rewardUnlockTime,
+	}
+}
+
+func (g *Game) PacketReunionPrivilegeInfo(player *model.Player, now time.Time) *proto.ReunionPrivilegeInfo {
+	dbReunion := player.GetDbReunion()
+	scheduleDataConfig := gdconf.GetReunionScheduleDataById(int32(dbReunion.ScheduleId))
+	if scheduleDataConfig == nil {
+		return nil
+	}
+	return &proto.ReunionPrivilegeInfo{
+		PrivilegeId: uint32(scheduleDataConfig.PrivilegeId),
+		CurDayCount: dbReunion.GetPrivilegeDayCount(now),
+		TotalCount:  dbReunion.PrivilegeTotalCount,
+	}
+}
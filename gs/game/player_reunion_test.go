@@ -0,0 +1,195 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+func newTestReunionPlayer(t *testing.T, offlineTime time.Time, level uint32) *model.Player {
+	config.CONF = &config.Config{Hk4e: config.Hk4e{ReunionOfflineDay: 14}}
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		ReunionScheduleDataMap: map[int32]*gdconf.ReunionScheduleData{
+			1004: {ScheduleId: 1004, Level: 10, FirstGiftRewardId: 3006200, SignInId: 3, MissionId: 5, PrivilegeId: 1},
+			1005: {ScheduleId: 1005, Level: 10, FirstGiftRewardId: 3006300, SignInId: 3, MissionId: 5, PrivilegeId: 1},
+		},
+		ReunionSignInDataMap: map[int32]map[int32]*gdconf.ReunionSignInData{
+			3: {
+				1: {SignInId: 3, Day: 1, RewardId: 3006201},
+				2: {SignInId: 3, Day: 2, RewardId: 3006202},
+			},
+		},
+		ReunionMissionDataMap: map[int32]*gdconf.ReunionMissionData{
+			5: {MissionId: 5, WatcherGroupId: 1004, StageScoreList: gdconf.IntArray{20, 40}, StageRewardIdList: gdconf.IntArray{3006360, 3006361}},
+		},
+		ReunionWatcherDataMap: map[int32]*gdconf.ReunionWatcherData{
+			1: {WatcherId: 1, TriggerType: constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101, 21010201}, Progress: 2, GroupId: 1004, LevelRange: gdconf.IntArray{10, 30}, Score: 20},
+			2: {WatcherId: 2, TriggerType: constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101}, Progress: 2, GroupId: 1004, LevelRange: gdconf.IntArray{30, 61}, Score: 20},
+			3: {WatcherId: 3, TriggerType: constant.REUNION_WATCHER_TRIGGER_TYPE_COST_MATERIAL, TriggerParamList: []int32{constant.ITEM_ID_RESIN}, Progress: 60, GroupId: 1004, Score: 20, RewardDay: 1},
+			// 其他任务组与已废弃的监听不会开启
+			4: {WatcherId: 4, TriggerType: constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101}, Progress: 1, GroupId: 1003},
+			5: {WatcherId: 5, TriggerType: constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, TriggerParamList: []int32{21010101}, Progress: 1, GroupId: 1004, IsDisuse: 1},
+		},
+		ReunionPrivilegeDataMap: map[int32]*gdconf.ReunionPrivilegeData{
+			1: {PrivilegeId: 1, DailyCount: 2, TotalCount: 3},
+		},
+	})
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	return &model.Player{
+		PlayerId:    10001,
+		IsBorn:      true,
+		OfflineTime: uint32(offlineTime.Unix()),
+		PropMap:     map[uint32]uint32{constant.PLAYER_PROP_PLAYER_LEVEL: level},
+	}
+}
+
+func TestReunionActivate(t *testing.T) {
+	now := time.Date(2023, 6, 20, 10, 0, 0, 0, time.Local)
+	g := new(Game)
+
+	// 离线时长不足
+	player := newTestReunionPlayer(t, now.AddDate(0, 0, -13), 20)
+	if start, _ := g.CheckPlayerReunion(player, now); start {
+		t.Fatal("reunion should not start before offline day")
+	}
+	// 等级不足
+	player = newTestReunionPlayer(t, now.AddDate(0, 0, -30), 9)
+	if start, _ := g.CheckPlayerReunion(player, now); start {
+		t.Fatal("reunion should not start below level")
+	}
+
+	player = newTestReunionPlayer(t, now.AddDate(0, 0, -30), 20)
+	start, _ := g.CheckPlayerReunion(player, now)
+	dbReunion := player.GetDbReunion()
+	if !start || dbReunion.ScheduleId != 1005 || !dbReunion.IsActive(now) {
+		t.Fatalf("reunion start error, dbReunion: %+v", dbReunion)
+	}
+	if len(dbReunion.WatcherMap) != 2 || dbReunion.GetWatcher(1) == nil || dbReunion.GetWatcher(3) == nil {
+		t.Fatalf("reunion watcher error, watcherMap: %v", dbReunion.WatcherMap)
+	}
+	// 回归期间不会重复开启
+	if start, _ = g.CheckPlayerReunion(player, now.Add(time.Hour)); start || dbReunion.ReunionCount != 1 {
+		t.Fatal("reunion should not restart")
+	}
+
+	// 到期结束 同一次离线不会再次触发
+	finishTime := now.AddDate(0, 0, ReunionDurationDay)
+	start, finish := g.CheckPlayerReunion(player, finishTime)
+	if start || !finish || dbReunion.IsActive(finishTime) {
+		t.Fatalf("reunion finish error, start: %v, finish: %v", start, finish)
+	}
+	// 再次长时间离线后重新触发
+	player.OfflineTime = uint32(finishTime.Unix())
+	if start, _ = g.CheckPlayerReunion(player, finishTime.AddDate(0, 0, 20)); !start || dbReunion.ReunionCount != 2 {
+		t.Fatal("reunion should start again after another long offline")
+	}
+}
+
+func TestReunionReward(t *testing.T) {
+	now := time.Date(2023, 6, 20, 10, 0, 0, 0, time.Local)
+	g := new(Game)
+	player := newTestReunionPlayer(t, now.AddDate(0, 0, -30), 20)
+
+	if _, ret := g.TakeReunionFirstGiftReward(player, now); ret != proto.Retcode_RET_REUNION_NOT_ACTIVATED {
+		t.Fatalf("first gift should need reunion, ret: %v", ret)
+	}
+	g.CheckPlayerReunion(player, now)
+	if rewardId, ret := g.TakeReunionFirstGiftReward(player, now); ret != proto.Retcode_RET_SUCC || rewardId != 3006300 {
+		t.Fatalf("take first gift error, ret: %v, rewardId: %v", ret, rewardId)
+	}
+	if _, ret := g.TakeReunionFirstGiftReward(player, now); ret != proto.Retcode_RET_REUNION_ALREADY_TAKE_FIRST_REWARD {
+		t.Fatalf("first gift should be taken only once, ret: %v", ret)
+	}
+
+	// 签到 每天一次 按顺序领取
+	if _, ret := g.TakeReunionSignInReward(player, 2, now); ret != proto.Retcode_RET_REUNION_SIGN_IN_REWARDED {
+		t.Fatalf("sign in out of order, ret: %v", ret)
+	}
+	if rewardId, ret := g.TakeReunionSignInReward(player, 1, now); ret != proto.Retcode_RET_SUCC || rewardId != 3006201 {
+		t.Fatalf("sign in error, ret: %v, rewardId: %v", ret, rewardId)
+	}
+	if _, ret := g.TakeReunionSignInReward(player, 2, now.Add(time.Hour)); ret != proto.Retcode_RET_REUNION_SIGN_IN_REWARDED {
+		t.Fatalf("sign in twice in one day, ret: %v", ret)
+	}
+	if rewardId, ret := g.TakeReunionSignInReward(player, 2, now.AddDate(0, 0, 3)); ret != proto.Retcode_RET_SUCC || rewardId != 3006202 {
+		t.Fatalf("sign in next day error, ret: %v, rewardId: %v", ret, rewardId)
+	}
+	if _, ret := g.TakeReunionSignInReward(player, 3, now.AddDate(0, 0, 4)); ret != proto.Retcode_RET_REUNION_SIGN_IN_REWARDED {
+		t.Fatalf("sign in beyond day count, ret: %v", ret)
+	}
+
+	// 任务进度
+	dbReunion := player.GetDbReunion()
+	if changeList := AddReunionWatcherProgress(dbReunion, constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, 21010201, 1); len(changeList) != 1 || changeList[0] != 1 {
+		t.Fatalf("watcher progress error, changeList: %v", changeList)
+	}
+	if ret := g.TakeReunionWatcherReward(player, 1, now); ret != proto.Retcode_RET_REUNION_WATCHER_NOT_FINISH {
+		t.Fatalf("unfinished watcher reward, ret: %v", ret)
+	}
+	AddReunionWatcherProgress(dbReunion, constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, 21010101, 5)
+	AddReunionWatcherProgress(dbReunion, constant.REUNION_WATCHER_TRIGGER_TYPE_COST_MATERIAL, constant.ITEM_ID_RESIN, 60)
+	if dbReunion.GetWatcher(1).CurProgress != 2 || !dbReunion.GetWatcher(3).IsFinish() {
+		t.Fatal("watcher progress should be capped")
+	}
+	if ret := g.TakeReunionWatcherReward(player, 1, now); ret != proto.Retcode_RET_SUCC || dbReunion.MissionScore != 20 {
+		t.Fatalf("take watcher reward error, ret: %v, score: %v", ret, dbReunion.MissionScore)
+	}
+	if ret := g.TakeReunionWatcherReward(player, 1, now); ret != proto.Retcode_RET_REUNION_WATCHER_REWARDED {
+		t.Fatalf("watcher reward should be taken only once, ret: %v", ret)
+	}
+	// 第二天才解锁的监听
+	if ret := g.TakeReunionWatcherReward(player, 3, now); ret != proto.Retcode_RET_REUNION_WATCHER_REWARD_NOT_UNLOCKED {
+		t.Fatalf("locked watcher reward, ret: %v", ret)
+	}
+
+	// 积分分档奖励
+	if rewardId, ret := g.TakeReunionMissionReward(player, 0, now); ret != proto.Retcode_RET_SUCC || rewardId != 3006360 {
+		t.Fatalf("take mission reward error, ret: %v, rewardId: %v", ret, rewardId)
+	}
+	if _, ret := g.TakeReunionMissionReward(player, 0, now); ret != proto.Retcode_RET_REUNION_MISSION_REWARDED {
+		t.Fatalf("mission reward should be taken only once, ret: %v", ret)
+	}
+	if _, ret := g.TakeReunionMissionReward(player, 1, now); ret != proto.Retcode_RET_REUNION_MISSION_NOT_FINISH {
+		t.Fatalf("mission reward score not enough, ret: %v", ret)
+	}
+	g.TakeReunionWatcherReward(player, 3, now.AddDate(0, 0, 1))
+	if _, ret := g.TakeReunionMissionReward(player, 1, now.AddDate(0, 0, 1)); ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("take second mission reward error, ret: %v", ret)
+	}
+	missionInfo := g.PacketReunionMissionInfo(player, now.AddDate(0, 0, 1))
+	if !missionInfo.IsFinished || len(missionInfo.WatcherList) != 2 || len(missionInfo.CurDayWatcherList) != 1 {
+		t.Fatalf("packet mission info error, missionInfo: %v", missionInfo)
+	}
+}
+
+func TestReunionPrivilege(t *testing.T) {
+	now := time.Date(2023, 6, 20, 10, 0, 0, 0, time.Local)
+	g := new(Game)
+	player := newTestReunionPlayer(t, now.AddDate(0, 0, -30), 20)
+	if g.UseReunionPrivilege(player, now) {
+		t.Fatal("privilege should need reunion")
+	}
+	g.CheckPlayerReunion(player, now)
+	// 每日次数
+	if !g.UseReunionPrivilege(player, now) || !g.UseReunionPrivilege(player, now) || g.UseReunionPrivilege(player, now) {
+		t.Fatal("privilege daily count error")
+	}
+	// 跨天刷新每日次数 但不超过总次数
+	tomorrow := now.AddDate(0, 0, 1)
+	if g.PacketReunionPrivilegeInfo(player, tomorrow).CurDayCount != 0 {
+		t.Fatal("privilege daily count should refresh")
+	}
+	if !g.UseReunionPrivilege(player, tomorrow) || g.UseReunionPrivilege(player, tomorrow) {
+		t.Fatal("privilege total count error")
+	}
+	if info := g.PacketReunionPrivilegeInfo(player, tomorrow); info.CurDayCount != 1 || info.TotalCount != 3 {
+		t.Fatalf("packet privilege info error, info: %v", info)
+	}
+}
@@ -3,12 +3,13 @@ package game
 import (
 	"math"
 	"strconv"
-	"time"
 
+	"hk4e/common/config"
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
 	"hk4e/pkg/alg"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/pkg/random"
 	"hk4e/protocol/cmd"
@@ -24,6 +25,10 @@ const (
 	ENTITY_MAX_BATCH_SEND_NUM = 1000 // 单次同步客户端的最大实体数量
 )
 
+const (
+	WeatherRefreshIntervalDefault = 300 // 默认天气气象随机间隔 单位秒
+)
+
 /************************************************** 接口请求 **************************************************/
 
 // EnterSceneReadyReq 准备进入场景
@@ -96,11 +101,7 @@ func (g *Game) EnterSceneReadyReq(player *model.Player, payloadMsg pb.Message) {
 		player.SceneLoadState = model.SceneNone
 
 		if player.SceneJump {
-			oldScene.RemovePlayer(player)
-			player.SetSceneId(newSceneId)
-			player.SetPos(newPos)
-			player.SetRot(newRot)
-			newScene.AddPlayer(player)
+			g.PlayerSceneJump(player, world, oldScene, newScene, ctx)
 		} else {
 			player.SetPos(newPos)
 			player.SetRot(newRot)
@@ -171,7 +172,7 @@ func (g *Game) SceneInitFinishReq(player *model.Player, payloadMsg pb.Message) {
 	}
 
 	serverTimeNotify := &proto.ServerTimeNotify{
-		ServerTime: uint64(time.Now().UnixMilli()),
+		ServerTime: uint64(clock.Now().UnixMilli()),
 	}
 	g.SendMsg(cmd.ServerTimeNotify, player.PlayerId, player.ClientSeq, serverTimeNotify)
 
@@ -288,14 +289,12 @@ func (g *Game) SceneInitFinishReq(player *model.Player, payloadMsg pb.Message) {
 		g.SendMsg(cmd.PlayerEnterSceneInfoNotify, player.PlayerId, player.ClientSeq, playerEnterSceneInfoNotify)
 	}
 
-	// 天气未初始化 或 未锁定天气并且是场景跳跃 更新天气
-	if player.WeatherInfo.WeatherAreaId == 0 || player.PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] == 0 {
+	// 天气未初始化 或 未锁定天气并且是场景跳跃 更新天气区域
+	if player.WeatherInfo.WeatherAreaId == 0 || !g.IsWorldWeatherLocked(world) {
 		// 初始化天气区域id
 		weatherAreaId := g.GetPlayerInWeatherAreaId(player, player.GetPos())
 		if weatherAreaId != 0 {
-			// 获取天气气象
-			climateType := g.GetWeatherAreaClimate(weatherAreaId)
-			g.SetPlayerWeather(player, weatherAreaId, climateType, false)
+			g.SetPlayerWeatherArea(player, weatherAreaId, false)
 		} else {
 			logger.Error("weather area id error, weatherAreaId: %v", weatherAreaId)
 		}
@@ -412,10 +411,10 @@ func (g *Game) EnterSceneDoneReq(player *model.Player, payloadMsg pb.Message) {
 		g.AddSceneEntityNotify(player, visionType, entityIdList, false, false)
 	}
 
-	// 设置玩家天气
+	// 设置玩家天气 同一场景内的玩家共享天气区域的气象
 	sceneAreaWeatherNotify := &proto.SceneAreaWeatherNotify{
 		WeatherAreaId: player.WeatherInfo.WeatherAreaId,
-		ClimateType:   player.WeatherInfo.ClimateType,
+		ClimateType:   g.GetSceneWeatherClimate(scene, player.WeatherInfo.WeatherAreaId),
 	}
 	g.SendMsg(cmd.SceneAreaWeatherNotify, player.PlayerId, player.ClientSeq, sceneAreaWeatherNotify)
 
@@ -542,6 +541,20 @@ func (g *Game) EntityForceSyncReq(player *model.Player, payloadMsg pb.Message) {
 
 /************************************************** 游戏功能 **************************************************/
 
+// PlayerSceneJump 玩家从旧场景跳跃到新场景 离开场景时移除试用角色 进入试用角色地牢时发放试用角色
+func (g *Game) PlayerSceneJump(player *model.Player, world *World, oldScene *Scene, newScene *Scene, ctx *EnterSceneContext) {
+	g.RemovePlayerAllTrialAvatarOnSceneJump(player, world)
+	oldScene.RemovePlayer(player)
+	player.SetSceneId(ctx.NewSceneId)
+	player.SetPos(ctx.NewPos)
+	player.SetRot(ctx.NewRot)
+	newScene.AddPlayer(player)
+	if ctx.DungeonId != 0 && len(newScene.GetAllPlayer()) == 1 {
+		newScene.InitDungeon(ctx.DungeonId)
+	}
+	g.GrantPlayerDungeonTrialAvatarOnSceneJump(player, world, ctx.DungeonId)
+}
+
 type SceneBlockLoadInfo struct {
 	Uid            uint32
 	SceneBlockList []*model.SceneBlock
@@ -946,6 +959,25 @@ func (g *Game) KillEntity(player *model.Player, scene *Scene, entityId uint32, d
 		return
 	}
 
+	monsterEntity, ok := entity.(*MonsterEntity)
+	if ok {
+		// 图鉴解锁
+		g.CodexKillMonster(player, monsterEntity.GetMonsterId())
+		// 活动进度
+		g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_MONSTER_DIE, int32(monsterEntity.GetMonsterId()), 1)
+		// 回归任务进度
+		g.TriggerReunionWatcher(player, constant.REUNION_WATCHER_TRIGGER_TYPE_MONSTER_DIE, int32(monsterEntity.GetMonsterId()), 1)
+		// 触发事件
+		PLUGIN_MANAGER.TriggerEvent(PluginEventIdMonsterDie, &PluginEventMonsterDie{
+			PluginEvent: NewPluginEvent(),
+			Player:      player,
+			SceneId:     scene.GetId(),
+			EntityId:    entity.GetId(),
+			MonsterId:   monsterEntity.GetMonsterId(),
+			DieType:     dieType,
+		})
+	}
+
 	// 删除实体
 	g.EntityFightPropUpdateNotifyBroadcast(scene, entity)
 	g.RemoveSceneEntityNotifyBroadcast(scene, proto.VisionType_VISION_DIE, []uint32{entity.GetId()}, 0)
@@ -969,8 +1001,12 @@ func (g *Game) KillEntity(player *model.Player, scene *Scene, entityId uint32, d
 	case *MonsterEntity:
 		// 随机掉落
 		g.monsterDrop(player, MonsterDropTypeKill, 0, entity)
+		// 世界boss记录复活时间
+		g.WorldBossKill(scene.GetWorld(), entity.(*MonsterEntity))
 		// 怪物死亡触发器检测
 		g.MonsterDieTriggerCheck(player, group, entity)
+		// 地牢通关检测 在死亡触发器之后 触发器可能会刷出下一波怪物
+		g.DungeonMonsterKill(scene, entity.(*MonsterEntity))
 	case IGadgetEntity:
 		iGadgetEntity := entity.(IGadgetEntity)
 		// 物件死亡触发器检测
@@ -1342,12 +1378,8 @@ func (g *Game) CreateConfigEntity(scene *Scene, groupId uint32, entityConfig any
 	switch entityConfig.(type) {
 	case *gdconf.Monster:
 		monster := entityConfig.(*gdconf.Monster)
-		// TODO 怪物等级与世界等级的关联
-		worldLevel := owner.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL]
-		monsterLevel := uint8(monster.Level) * uint8(worldLevel+1)
-		if monsterLevel > 100 {
-			monsterLevel = 100
-		}
+		// 多人世界按房主的世界等级修正
+		monsterLevel := GetWorldLevelEntityLevel(monster.Level, owner.PropMap[constant.PLAYER_PROP_PLAYER_WORLD_LEVEL])
 		monsterEntity := scene.CreateEntityMonster(
 			&model.Vector{X: float64(monster.Pos.X), Y: float64(monster.Pos.Y), Z: float64(monster.Pos.Z)},
 			&model.Vector{X: float64(monster.Rot.X), Y: float64(monster.Rot.Y), Z: float64(monster.Rot.Z)},
@@ -1624,21 +1656,6 @@ func (g *Game) GetPlayerInWeatherAreaId(player *model.Player, newPos *model.Vect
 	return
 }
 
-// WeatherClimateRandom 随机天气气象
-func (g *Game) WeatherClimateRandom(player *model.Player, weatherAreaId uint32) {
-	// 天气气象锁定则跳过
-	if player.PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] == 1 {
-		return
-	}
-	// 获取天气气象
-	climateType := g.GetWeatherAreaClimate(weatherAreaId)
-	// 跳过相同的天气
-	if climateType == player.WeatherInfo.ClimateType {
-		return
-	}
-	g.SetPlayerWeather(player, player.WeatherInfo.WeatherAreaId, climateType, true)
-}
-
 // SceneWeatherAreaCheck 场景天气区域变更检测
 func (g *Game) SceneWeatherAreaCheck(player *model.Player, oldPos *model.Vector, newPos *model.Vector) {
 	// 如果玩家没移动就不检测变更
@@ -1659,8 +1676,12 @@ func (g *Game) SceneWeatherAreaCheck(player *model.Player, oldPos *model.Vector,
 	if player.WeatherInfo.WeatherAreaId == weatherAreaId {
 		return
 	}
-	// 随机天气气象
-	g.WeatherClimateRandom(player, weatherAreaId)
+	// 天气气象锁定则保持当前天气区域
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil || g.IsWorldWeatherLocked(world) {
+		return
+	}
+	g.SetPlayerWeatherArea(player, weatherAreaId, true)
 }
 
 // GetWeatherAreaClimate 获取天气气象
@@ -1711,6 +1732,10 @@ func (g *Game) GetWeatherAreaClimate(weatherAreaId uint32) uint32 {
 		weightAll += weight
 	}
 	// logger.Debug("weather climate weightMap: %v, weightAll: %v", climateWeightMap, weightAll)
+	if weightAll <= 0 {
+		logger.Error("weather template weight error, templateName: %v, weather: %v", weatherData.TemplateName, weather)
+		return 0
+	}
 	randNum := random.GetRandomInt32(0, weightAll-1)
 	sumWeight := int32(0)
 	for climate, weight := range climateWeightMap {
@@ -1722,21 +1747,85 @@ func (g *Game) GetWeatherAreaClimate(weatherAreaId uint32) uint32 {
 	return 0
 }
 
-// SetPlayerWeather 设置玩家天气
-func (g *Game) SetPlayerWeather(player *model.Player, weatherAreaId uint32, climateType uint32, sendNotify bool) {
+// IsWorldWeatherLocked 天气气象是否锁定 以世界房主为准
+func (g *Game) IsWorldWeatherLocked(world *World) bool {
+	return world.GetOwner().PropMap[constant.PLAYER_PROP_IS_WEATHER_LOCKED] == 1
+}
+
+// GetWeatherRefreshInterval 获取天气气象随机间隔 毫秒
+func GetWeatherRefreshInterval() int64 {
+	interval := config.GetConfig().Hk4e.WeatherRefreshInterval
+	if interval <= 0 {
+		interval = WeatherRefreshIntervalDefault
+	}
+	return int64(interval) * 1000
+}
+
+// GetSceneWeatherClimate 获取场景天气区域的气象 首次获取时随机
+func (g *Game) GetSceneWeatherClimate(scene *Scene, weatherAreaId uint32) uint32 {
+	if weatherAreaId == 0 {
+		return 0
+	}
+	weather := scene.GetWeather(weatherAreaId)
+	if weather != nil {
+		return weather.GetClimateType()
+	}
+	climateType := g.GetWeatherAreaClimate(weatherAreaId)
+	scene.SetWeather(weatherAreaId, climateType, clock.Now().UnixMilli()+GetWeatherRefreshInterval())
+	return climateType
+}
+
+// SetSceneWeather 设置场景天气区域的气象 并通知该天气区域内的玩家
+func (g *Game) SetSceneWeather(scene *Scene, weatherAreaId uint32, climateType uint32) {
+	weather := scene.GetWeather(weatherAreaId)
+	nextRefreshTime := clock.Now().UnixMilli() + GetWeatherRefreshInterval()
+	if weather != nil && weather.GetClimateType() == climateType {
+		scene.SetWeather(weatherAreaId, climateType, nextRefreshTime)
+		return
+	}
+	logger.Debug("scene weather change, climateType: %v, weatherAreaId: %v, sceneId: %v", climateType, weatherAreaId, scene.GetId())
+	scene.SetWeather(weatherAreaId, climateType, nextRefreshTime)
+	g.SceneAreaWeatherNotifyBroadcast(scene, weatherAreaId)
+}
+
+// SceneWeatherRefresh 场景天气区域的气象到期重新随机 天气锁定时只推迟下次随机时间
+func (g *Game) SceneWeatherRefresh(world *World, now int64, force bool) {
+	locked := g.IsWorldWeatherLocked(world)
+	for _, scene := range world.GetAllScene() {
+		for weatherAreaId, weather := range scene.GetAllWeather() {
+			if !force && weather.GetNextRefreshTime() > now {
+				continue
+			}
+			if locked {
+				scene.SetWeather(weatherAreaId, weather.GetClimateType(), now+GetWeatherRefreshInterval())
+				continue
+			}
+			g.SetSceneWeather(scene, weatherAreaId, g.GetWeatherAreaClimate(weatherAreaId))
+		}
+	}
+}
+
+// SetPlayerWeatherArea 设置玩家所在的天气区域 气象使用场景内该天气区域的气象
+func (g *Game) SetPlayerWeatherArea(player *model.Player, weatherAreaId uint32, sendNotify bool) {
 	// 获取天气数据配置表
 	weatherData := gdconf.GetWeatherDataByWeatherAreaId(int32(weatherAreaId))
 	if weatherData == nil {
 		logger.Error("weather data config not exist, weatherAreaId: %v", weatherAreaId)
 		return
 	}
-
-	logger.Debug("weather climateType: %v, weatherAreaId: %v, jsonWeatherAreaId: %v, uid: %v", climateType, weatherAreaId, weatherData.JsonWeatherAreaId, player.PlayerId)
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
 
 	// 记录数据
 	player.WeatherInfo.WeatherAreaId = weatherAreaId
 	player.WeatherInfo.JsonWeatherAreaId = uint32(weatherData.JsonWeatherAreaId)
-	player.WeatherInfo.ClimateType = climateType
+	climateType := g.GetSceneWeatherClimate(scene, weatherAreaId)
+
+	logger.Debug("player weather area, climateType: %v, weatherAreaId: %v, jsonWeatherAreaId: %v, uid: %v", climateType, weatherAreaId, weatherData.JsonWeatherAreaId, player.PlayerId)
 
 	if !sendNotify {
 		return
@@ -1752,6 +1841,24 @@ func (g *Game) SetPlayerWeather(player *model.Player, weatherAreaId uint32, clim
 	}
 }
 
+// SceneAreaWeatherNotifyBroadcast 通知场景内处于该天气区域的全部玩家
+func (g *Game) SceneAreaWeatherNotifyBroadcast(scene *Scene, weatherAreaId uint32) {
+	weather := scene.GetWeather(weatherAreaId)
+	if weather == nil {
+		return
+	}
+	sceneAreaWeatherNotify := &proto.SceneAreaWeatherNotify{
+		WeatherAreaId: weatherAreaId,
+		ClimateType:   weather.GetClimateType(),
+	}
+	for _, scenePlayer := range scene.GetAllPlayer() {
+		if scenePlayer.WeatherInfo.WeatherAreaId != weatherAreaId {
+			continue
+		}
+		g.SendMsg(cmd.SceneAreaWeatherNotify, scenePlayer.PlayerId, 0, sceneAreaWeatherNotify)
+	}
+}
+
 /************************************************** 打包封装 **************************************************/
 
 func (g *Game) PacketPlayerEnterSceneNotifyLogin(player *model.Player) *proto.PlayerEnterSceneNotify {
@@ -2150,7 +2257,7 @@ func (g *Game) PacketSceneAvatarInfo(scene *Scene, player *model.Player, avatarI
 		WearingFlycloakId:      avatar.FlyCloak,
 		CostumeId:              avatar.Costume,
 		BornTime:               uint32(avatar.BornTime),
-		TeamResonanceList:      make([]uint32, 0), // 队伍元素共鸣
+		TeamResonanceList:      avatar.TeamResonanceList, // 队伍元素共鸣
 	}
 	return sceneAvatarInfo
 }
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -27,7 +28,7 @@ func (g *Game) GetShopReq(player *model.Player, payloadMsg pb.Message) {
 		return
 	}
 
-	nextRefreshTime := uint32(time.Now().Add(time.Hour * 24 * 30).Unix())
+	nextRefreshTime := uint32(clock.Now().Add(time.Hour * 24 * 30).Unix())
 
 	getShopRsp := &proto.GetShopRsp{
 		Shop: &proto.Shop{
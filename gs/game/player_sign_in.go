@@ -0,0 +1,210 @@
+package game
+
+import (
+	"sort"
+	"time"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/pkg/clock"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+/************************************************** 接口请求 **************************************************/
+
+func (g *Game) SignInInfoReq(player *model.Player, payloadMsg pb.Message) {
+	now := clock.Now()
+	g.CheckPlayerSignInReset(player, now)
+
+	rsp := &proto.SignInInfoRsp{
+		SignInInfoList: []*proto.SignInInfo{g.PacketSignInInfo(player, now)},
+	}
+	g.SendMsg(cmd.SignInInfoRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+func (g *Game) GetSignInRewardReq(player *model.Player, payloadMsg pb.Message) {
+	req := payloadMsg.(*proto.GetSignInRewardReq)
+
+	now := clock.Now()
+	g.CheckPlayerSignInReset(player, now)
+	dbSignIn := player.GetDbSignIn()
+	if req.ScheduleId != dbSignIn.ScheduleId {
+		g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{}, proto.Retcode_RET_SIGN_IN_RECORD_NOT_FOUND)
+		return
+	}
+	if !g.IsSignInCondSatisfied(player, dbSignIn.ScheduleId) {
+		g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{}, proto.Retcode_RET_SIGN_IN_COND_NOT_SATISFIED)
+		return
+	}
+	if dbSignIn.IsRewardDayTaken(req.RewardDay) {
+		g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{}, proto.Retcode_RET_SIGN_IN_REWARDED)
+		return
+	}
+	ok, isMakeup := dbSignIn.CheckSignIn(now, req.RewardDay)
+	if !ok {
+		if isMakeup {
+			g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{}, proto.Retcode_RET_SIGN_IN_COND_NOT_SATISFIED)
+		} else {
+			g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{}, proto.Retcode_RET_ALREADY_HAVE_SIGNED_IN)
+		}
+		return
+	}
+	signInDataConfig := g.GetSignInDataConfig(dbSignIn.ScheduleId, req.RewardDay)
+	if signInDataConfig == nil {
+		logger.Error("get sign in data config is nil, scheduleId: %v, rewardDay: %v", dbSignIn.ScheduleId, req.RewardDay)
+		g.SendError(cmd.GetSignInRewardRsp, player, &proto.GetSignInRewardRsp{})
+		return
+	}
+	dbSignIn.SignIn(now, req.RewardDay, isMakeup)
+	changeItemList := make([]*ChangeItem, 0)
+	for itemId, count := range signInDataConfig.RewardItemMap {
+		changeItemList = append(changeItemList, &ChangeItem{
+			ItemId:      itemId,
+			ChangeCount: count,
+		})
+	}
+	g.AddPlayerItem(player.PlayerId, changeItemList, proto.ActionReasonType_ACTION_REASON_SIGN_IN_REWARD)
+
+	rsp := &proto.GetSignInRewardRsp{
+		SignInInfo: g.PacketSignInInfo(player, now),
+	}
+	g.SendMsg(cmd.GetSignInRewardRsp, player.PlayerId, player.ClientSeq, rsp)
+}
+
+/************************************************** 游戏功能 **************************************************/
+
+// GetSignInScheduleId 获取某个时间点所在签到周期使用的排期 按月份轮换
+func (g *Game) GetSignInScheduleId(now time.Time) uint32 {
+	scheduleIdList := make([]int32, 0)
+	for scheduleId := range gdconf.GetSignInCondConfigDataMap() {
+		scheduleIdList = append(scheduleIdList, scheduleId)
+	}
+	if len(scheduleIdList) == 0 {
+		return 0
+	}
+	sort.Slice(scheduleIdList, func(i, j int) bool {
+		return scheduleIdList[i] < scheduleIdList[j]
+	})
+	index := (now.Year()*12 + int(now.Month()) - 1) % len(scheduleIdList)
+	return uint32(scheduleIdList[index])
+}
+
+// GetSignInDataConfig 获取某一天的签到奖励配置 排期天数不足一个月时循环使用
+func (g *Game) GetSignInDataConfig(scheduleId uint32, rewardDay uint32) *gdconf.SignInData {
+	signInCondConfig := gdconf.GetSignInCondConfigDataById(int32(scheduleId))
+	if signInCondConfig == nil || signInCondConfig.TotalDay <= 0 || rewardDay == 0 {
+		return nil
+	}
+	day := (int32(rewardDay)-1)%signInCondConfig.TotalDay + 1
+	return gdconf.GetSignInDataByScheduleIdAndDay(int32(scheduleId), day)
+}
+
+func (g *Game) IsSignInCondSatisfied(player *model.Player, scheduleId uint32) bool {
+	signInCondConfig := gdconf.GetSignInCondConfigDataById(int32(scheduleId))
+	if signInCondConfig == nil {
+		return false
+	}
+	switch signInCondConfig.CondType1 {
+	case 1:
+		// 玩家等级
+		return player.PropMap[constant.PLAYER_PROP_PLAYER_LEVEL] >= uint32(signInCondConfig.CondParam11)
+	default:
+		return true
+	}
+}
+
+// CheckPlayerSignInReset 检查玩家签到周期 跨月则重置签到数据
+func (g *Game) CheckPlayerSignInReset(player *model.Player, now time.Time) {
+	dbSignIn := player.GetDbSignIn()
+	if dbSignIn.CheckReset(now, g.GetSignInScheduleId(now)) {
+		logger.Debug("player sign in reset, scheduleId: %v, cycle: %v, uid: %v", dbSignIn.ScheduleId, dbSignIn.Cycle, player.PlayerId)
+	}
+}
+
+// CheckBirthdayMail 检查并发放生日邮件
+// 邮件不落库 所以领取附件后才记录发放年份 未领取前下线会在下次登录时重新发放
+func (g *Game) CheckBirthdayMail(player *model.Player, now time.Time) {
+	dbSocial := player.GetDbSocial()
+	if !dbSocial.CheckBirthdayMail(now) {
+		return
+	}
+	for _, mail := range player.MailMap {
+		if g.IsBirthdayMail(mail) && !mail.IsAttachmentGot {
+			// 本次在线期间已经发放过且未领取
+			return
+		}
+	}
+	birthdayMailDataConfig := gdconf.GetBirthdayMailDataByTime(now.Unix())
+	if birthdayMailDataConfig == nil {
+		return
+	}
+	itemList := make([]*model.MailItem, 0)
+	rewardConfig := gdconf.GetRewardDataById(birthdayMailDataConfig.RewardId)
+	if rewardConfig != nil {
+		for itemId, count := range rewardConfig.RewardItemMap {
+			itemList = append(itemList, &model.MailItem{ItemId: itemId, Count: count})
+		}
+	}
+	if len(itemList) == 0 {
+		// 没有附件 发放即视为送达
+		dbSocial.MarkBirthdayMail(now)
+	}
+	g.AddPlayerMailWithItem(player.PlayerId, "生日快乐", "祝你生日快乐，这是为你准备的生日礼物。", uint32(birthdayMailDataConfig.MailId), itemList)
+}
+
+// IsBirthdayMail 是否为生日邮件
+func (g *Game) IsBirthdayMail(mail *model.Mail) bool {
+	if mail.ConfigId == 0 {
+		return false
+	}
+	for _, birthdayMailData := range gdconf.GetBirthdayMailDataMap() {
+		if uint32(birthdayMailData.MailId) == mail.ConfigId {
+			return true
+		}
+	}
+	return false
+}
+
+/************************************************** 打包封装 **************************************************/
+
+func (g *Game) PacketSignInInfo(player *model.Player, now time.Time) *proto.SignInInfo {
+	dbSignIn := player.GetDbSignIn()
+	rewardDayList := dbSignIn.GetRewardDayList()
+	sort.Slice(rewardDayList, func(i, j int) bool {
+		return rewardDayList[i] < rewardDayList[j]
+	})
+	dayCount := model.GetSignInCycleDayCount(now)
+	signInDataList := make([]*proto.SignInData, 0, dayCount)
+	for day := uint32(1); day <= dayCount; day++ {
+		signInDataConfig := g.GetSignInDataConfig(dbSignIn.ScheduleId, day)
+		if signInDataConfig == nil {
+			continue
+		}
+		itemList := make([]*proto.ItemParam, 0, len(signInDataConfig.RewardItemMap))
+		for itemId, count := range signInDataConfig.RewardItemMap {
+			itemList = append(itemList, &proto.ItemParam{ItemId: itemId, Count: count})
+		}
+		signInDataList = append(signInDataList, &proto.SignInData{
+			DayCount:       day,
+			RewardItemList: itemList,
+		})
+	}
+	beginTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	endTime := beginTime.AddDate(0, 1, 0)
+	return &proto.SignInInfo{
+		IsCondSatisfied: g.IsSignInCondSatisfied(player, dbSignIn.ScheduleId),
+		RewardDayList:   rewardDayList,
+		SigninDataList:  signInDataList,
+		ConfigId:        dbSignIn.ScheduleId,
+		SignInCount:     dbSignIn.SignInCount,
+		ScheduleId:      dbSignIn.ScheduleId,
+		EndTime:         uint32(endTime.Unix()),
+		LastSignInTime:  dbSignIn.LastSignInTime,
+		BeginTime:       uint32(beginTime.Unix()),
+	}
+}
@@ -0,0 +1,57 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+func TestBirthdayMail(t *testing.T) {
+	newTestLuaPluginManager(t, 0)
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		BirthdayMailDataMap: map[int32]*gdconf.BirthdayMailData{1: {Id: 1, MailId: 1004, RewardId: 1}},
+		RewardDataMap:       map[int32]*gdconf.RewardData{1: {RewardItemMap: map[uint32]uint32{201: 10}}},
+		MailDataMap:         make(map[int32]*gdconf.MailData),
+		ItemDataMap:         make(map[int32]*gdconf.ItemData),
+	})
+	player := &model.Player{PlayerId: 10001, Online: true, MailMap: make(map[uint32]*model.Mail), PropMap: make(map[uint32]uint32)}
+	oldUserManager := USER_MANAGER
+	USER_MANAGER = &UserManager{playerMap: map[uint32]*model.Player{player.PlayerId: player}}
+	t.Cleanup(func() {
+		USER_MANAGER = oldUserManager
+	})
+	dbSocial := player.GetDbSocial()
+	dbSocial.SetBirthday(6, 1)
+	now := time.Date(2023, 6, 2, 12, 0, 0, 0, time.Local)
+	g := &Game{endlessLoopCounter: make(map[int]uint64)}
+
+	// 发放后附件未领取前不记录年份 同一次在线不重复发放
+	g.CheckBirthdayMail(player, now)
+	g.CheckBirthdayMail(player, now)
+	if len(player.MailMap) != 1 || dbSocial.BirthdayMailYear != 0 {
+		t.Fatalf("birthday mail send error, mail count: %v, year: %v", len(player.MailMap), dbSocial.BirthdayMailYear)
+	}
+	// 邮件不落库 下线后重新登录需要补发
+	player.MailMap = make(map[uint32]*model.Mail)
+	g.CheckBirthdayMail(player, now)
+	if len(player.MailMap) != 1 {
+		t.Fatalf("birthday mail should resend after relogin, mail count: %v", len(player.MailMap))
+	}
+	var mailId uint32 = 0
+	for _, mail := range player.MailMap {
+		mailId = mail.MailId
+	}
+	// 领取附件后记录年份 之后不再发放
+	g.GetMailItemReq(player, &proto.GetMailItemReq{MailIdList: []uint32{mailId}})
+	if dbSocial.BirthdayMailYear != uint32(time.Now().Year()) {
+		t.Fatalf("birthday mail year should mark after claim, year: %v", dbSocial.BirthdayMailYear)
+	}
+	player.MailMap = make(map[uint32]*model.Mail)
+	g.CheckBirthdayMail(player, now.AddDate(0, 0, 1))
+	if len(player.MailMap) != 0 {
+		t.Fatalf("birthday mail should not resend after claim")
+	}
+}
@@ -3,12 +3,12 @@ package game
 import (
 	"fmt"
 	"regexp"
-	"time"
 	"unicode/utf8"
 
 	"hk4e/common/constant"
 	"hk4e/common/mq"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/object"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -56,7 +56,7 @@ func (g *Game) SetPlayerBirthdayReq(player *model.Player, payloadMsg pb.Message)
 		return
 	}
 	birthday := req.Birthday
-	dbSocial.SetBirthday(birthday.Month, birthday.Day)
+	dbSocial.SetBirthdayWithTime(birthday.Month, birthday.Day, clock.Now())
 	g.SendMsg(cmd.SetPlayerBirthdayRsp, player.PlayerId, player.ClientSeq, &proto.SetPlayerBirthdayRsp{Birthday: req.Birthday})
 }
 
@@ -81,6 +81,8 @@ func (g *Game) SetPlayerSignatureReq(player *model.Player, payloadMsg pb.Message
 		setPlayerSignatureRsp.Retcode = int32(proto.Retcode_RET_SIGNATURE_ILLEGAL)
 	} else if utf8.RuneCountInString(signature) > 50 {
 		setPlayerSignatureRsp.Retcode = int32(proto.Retcode_RET_SIGNATURE_ILLEGAL)
+	} else if g.HasSensitiveWord(signature) {
+		setPlayerSignatureRsp.Retcode = int32(proto.Retcode_RET_SIGNATURE_ILLEGAL)
 	} else {
 		player.Signature = signature
 		setPlayerSignatureRsp.Signature = player.Signature
@@ -101,6 +103,8 @@ func (g *Game) SetPlayerNameReq(player *model.Player, payloadMsg pb.Message) {
 		setPlayerNameRsp.Retcode = int32(proto.Retcode_RET_NICKNAME_TOO_LONG)
 	} else if len(regexp.MustCompile(`\d`).FindAllString(nickName, -1)) > 6 {
 		setPlayerNameRsp.Retcode = int32(proto.Retcode_RET_NICKNAME_TOO_MANY_DIGITS)
+	} else if g.HasSensitiveWord(nickName) {
+		setPlayerNameRsp.Retcode = int32(proto.Retcode_RET_NICKNAME_WORD_ILLEGAL)
 	} else {
 		player.NickName = nickName
 		setPlayerNameRsp.NickName = player.NickName
@@ -161,7 +165,7 @@ func (g *Game) GetPlayerFriendListReq(player *model.Player, payloadMsg pb.Messag
 			IsMpModeAvailable: true,
 			LastActiveTime:    player.OfflineTime,
 			NameCardId:        friendPlayer.GetDbSocial().NameCard,
-			Param:             (uint32(time.Now().Unix()) - player.OfflineTime) / 3600 / 24,
+			Param:             (uint32(clock.Now().Unix()) - player.OfflineTime) / 3600 / 24,
 			IsGameSource:      true,
 			PlatformType:      proto.PlatformType_PC,
 		}
@@ -206,7 +210,7 @@ func (g *Game) GetPlayerAskFriendListReq(player *model.Player, payloadMsg pb.Mes
 			IsMpModeAvailable: true,
 			LastActiveTime:    player.OfflineTime,
 			NameCardId:        friendPlayer.GetDbSocial().NameCard,
-			Param:             (uint32(time.Now().Unix()) - player.OfflineTime) / 3600 / 24,
+			Param:             (uint32(clock.Now().Unix()) - player.OfflineTime) / 3600 / 24,
 			IsGameSource:      true,
 			PlatformType:      proto.PlatformType_PC,
 		}
@@ -292,7 +296,7 @@ func (g *Game) AskAddFriendReq(player *model.Player, payloadMsg pb.Message) {
 		IsMpModeAvailable: true,
 		LastActiveTime:    player.OfflineTime,
 		NameCardId:        player.GetDbSocial().NameCard,
-		Param:             (uint32(time.Now().Unix()) - player.OfflineTime) / 3600 / 24,
+		Param:             (uint32(clock.Now().Unix()) - player.OfflineTime) / 3600 / 24,
 		IsGameSource:      true,
 		PlatformType:      proto.PlatformType_PC,
 	}
@@ -374,7 +378,7 @@ func (g *Game) GetOnlinePlayerListReq(player *model.Player, payloadMsg pb.Messag
 		roomNumber := aiGsId - 1
 		startMinute := roomNumber % 6 * 10
 		name := fmt.Sprintf("房间：%v", roomNumber)
-		sign := fmt.Sprintf("开启时间：%02d:%02d。", time.Now().Hour(), startMinute)
+		sign := fmt.Sprintf("开启时间：%02d:%02d。", clock.Now().Hour(), startMinute)
 		rsp.PlayerInfoList = append(rsp.PlayerInfoList, &proto.OnlinePlayerInfo{
 			Uid:                 aiUid,
 			Nickname:            name,
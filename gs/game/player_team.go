@@ -33,6 +33,19 @@ func (g *Game) ChangeAvatarReq(player *model.Player, payloadMsg pb.Message) {
 
 func (g *Game) SetUpAvatarTeamReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.SetUpAvatarTeamReq)
+	// 触发事件
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreSetUpAvatarTeam, &PluginEventPreSetUpAvatarTeam{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		Req:         req,
+	}) {
+		g.SendError(cmd.SetUpAvatarTeamRsp, player, &proto.SetUpAvatarTeamRsp{})
+		return
+	}
+	if len(player.GetDbAvatar().GetTrialAvatarMap()) != 0 {
+		g.SendError(cmd.SetUpAvatarTeamRsp, player, &proto.SetUpAvatarTeamRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	teamId := req.TeamId
 	avatarIdList := make([]uint32, 0)
 	for _, avatarGuid := range req.AvatarTeamGuidList {
@@ -98,6 +111,10 @@ func (g *Game) SetUpAvatarTeamReq(player *model.Player, payloadMsg pb.Message) {
 
 func (g *Game) ChooseCurAvatarTeamReq(player *model.Player, payloadMsg pb.Message) {
 	req := payloadMsg.(*proto.ChooseCurAvatarTeamReq)
+	if len(player.GetDbAvatar().GetTrialAvatarMap()) != 0 {
+		g.SendError(cmd.ChooseCurAvatarTeamRsp, player, &proto.ChooseCurAvatarTeamRsp{}, proto.Retcode_RET_IS_USING_TRIAL_AVATAR)
+		return
+	}
 	teamId := req.TeamId
 	world := WORLD_MANAGER.GetWorldById(player.WorldId)
 	if world == nil {
@@ -126,6 +143,7 @@ func (g *Game) ChooseCurAvatarTeamReq(player *model.Player, payloadMsg pb.Messag
 	world.SetPlayerActiveAvatarId(player, dbTeam.GetActiveAvatarId())
 	world.UpdateMultiplayerTeam()
 	world.UpdatePlayerWorldAvatar(player)
+	g.UpdateWorldTeamResonance(world, true)
 
 	sceneTeamUpdateNotify := g.PacketSceneTeamUpdateNotify(world, player)
 	g.SendMsg(cmd.SceneTeamUpdateNotify, player.PlayerId, player.ClientSeq, sceneTeamUpdateNotify)
@@ -196,6 +214,7 @@ func (g *Game) ChangeMpTeamAvatarReq(player *model.Player, payloadMsg pb.Message
 	world.SetPlayerActiveAvatarId(player, currAvatarId)
 	world.UpdateMultiplayerTeam()
 	world.UpdatePlayerWorldAvatar(player)
+	g.UpdateWorldTeamResonance(world, true)
 
 	sceneTeamUpdateNotify := g.PacketSceneTeamUpdateNotify(world, player)
 	g.SendToWorldA(world, cmd.SceneTeamUpdateNotify, player.ClientSeq, sceneTeamUpdateNotify, 0)
@@ -249,7 +268,7 @@ func (g *Game) AvatarDieAnimationEndReq(player *model.Player, payloadMsg pb.Mess
 		// 设置玩家耐力为一半
 		g.SetPlayerStamina(player, maxStamina/2)
 		// 传送玩家至安全位置
-		g.TeleportPlayer(
+		ok := g.TeleportPlayer(
 			player,
 			proto.EnterReason_ENTER_REASON_REVIVAL,
 			player.GetSceneId(),
@@ -258,6 +277,10 @@ func (g *Game) AvatarDieAnimationEndReq(player *model.Player, payloadMsg pb.Mess
 			0,
 			0,
 		)
+		if !ok {
+			g.SendError(cmd.AvatarDieAnimationEndRsp, player, &proto.AvatarDieAnimationEndRsp{})
+			return
+		}
 	} else {
 		targetAvatarId := uint32(0)
 		for _, worldAvatar := range world.GetPlayerWorldAvatarList(player) {
@@ -294,7 +317,7 @@ func (g *Game) WorldPlayerReviveReq(player *model.Player, payloadMsg pb.Message)
 		return
 	}
 
-	g.TeleportPlayer(
+	ok := g.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_REVIVAL,
 		player.GetSceneId(),
@@ -303,6 +326,10 @@ func (g *Game) WorldPlayerReviveReq(player *model.Player, payloadMsg pb.Message)
 		0,
 		0,
 	)
+	if !ok {
+		g.SendError(cmd.WorldPlayerReviveRsp, player, &proto.WorldPlayerReviveRsp{})
+		return
+	}
 	g.SendMsg(cmd.WorldPlayerReviveRsp, player.PlayerId, player.ClientSeq, new(proto.WorldPlayerReviveRsp))
 }
 
@@ -351,6 +378,14 @@ func (g *Game) ChangeAvatar(player *model.Player, targetAvatarId uint32) {
 		EntityList: []*proto.SceneEntityInfo{newAvatarEntity},
 	}
 	g.SendToSceneA(scene, cmd.SceneEntityAppearNotify, player.ClientSeq, sceneEntityAppearNotify, 0)
+
+	// 触发事件
+	PLUGIN_MANAGER.TriggerEvent(PluginEventIdChangeAvatar, &PluginEventChangeAvatar{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		OldAvatarId: oldAvatarId,
+		NewAvatarId: targetAvatarId,
+	})
 }
 
 func (g *Game) ChangeTeam(player *model.Player, teamId uint32, avatarIdList []uint32, currAvatarId uint32) {
@@ -387,6 +422,7 @@ func (g *Game) ChangeTeam(player *model.Player, teamId uint32, avatarIdList []ui
 		world.SetPlayerActiveAvatarId(player, currAvatarId)
 		world.UpdateMultiplayerTeam()
 		world.UpdatePlayerWorldAvatar(player)
+		g.UpdateWorldTeamResonance(world, true)
 
 		currAvatarIndex := world.GetPlayerAvatarIndexByAvatarId(player, currAvatarId)
 		dbTeam.CurrAvatarIndex = uint8(currAvatarIndex)
@@ -396,6 +432,82 @@ func (g *Game) ChangeTeam(player *model.Player, teamId uint32, avatarIdList []ui
 	}
 }
 
+// UpdateWorldTeamResonance 根据世界队伍重新计算元素共鸣并更新角色战斗属性
+// 多人世界中世界队伍由所有玩家的角色组成 所有玩家共享同一组元素共鸣
+func (g *Game) UpdateWorldTeamResonance(world *World, notify bool) {
+	if WORLD_MANAGER.IsAiWorld(world) {
+		return
+	}
+	elementTypeList := make([]int, 0)
+	for _, worldAvatar := range world.GetWorldAvatarList() {
+		worldPlayer := USER_MANAGER.GetOnlineUser(worldAvatar.GetUid())
+		if worldPlayer == nil {
+			logger.Error("player is nil, uid: %v", worldAvatar.GetUid())
+			continue
+		}
+		avatar := worldPlayer.GetDbAvatar().GetAvatarById(worldAvatar.GetAvatarId())
+		if avatar == nil {
+			logger.Error("get avatar is nil, avatarId: %v", worldAvatar.GetAvatarId())
+			continue
+		}
+		elementType := constant.ELEMENT_TYPE_NONE
+		avatarSkillDataConfig := gdconf.GetAvatarEnergySkillConfig(avatar.SkillDepotId)
+		if avatarSkillDataConfig != nil {
+			elementType = int(avatarSkillDataConfig.CostElemType)
+		}
+		elementTypeList = append(elementTypeList, elementType)
+	}
+	teamResonanceDataList := make([]*gdconf.TeamResonanceData, 0)
+	for _, teamResonanceData := range gdconf.GetTeamResonanceDataMap() {
+		teamResonanceDataList = append(teamResonanceDataList, teamResonanceData)
+	}
+	teamResonanceIdList := model.CalcTeamResonance(elementTypeList, teamResonanceDataList)
+
+	teamResonanceChangeNotify := &proto.TeamResonanceChangeNotify{
+		InfoList: make([]*proto.AvatarTeamResonanceInfo, 0),
+	}
+	for _, worldPlayer := range world.GetAllPlayer() {
+		worldPlayer.GetDbTeam().SetTeamResonance(teamResonanceIdList)
+		dbAvatar := worldPlayer.GetDbAvatar()
+		for _, avatar := range dbAvatar.GetEffectiveAvatarList() {
+			// 只有在世界队伍中的角色享受元素共鸣
+			newTeamResonanceIdList := make([]uint32, 0)
+			entityId := world.GetPlayerWorldAvatarEntityId(worldPlayer, avatar.AvatarId)
+			if world.GetPlayerWorldAvatar(worldPlayer, avatar.AvatarId) != nil {
+				newTeamResonanceIdList = teamResonanceIdList
+			}
+			addList, delList := model.DiffTeamResonance(avatar.TeamResonanceList, newTeamResonanceIdList)
+			if len(addList) == 0 && len(delList) == 0 {
+				continue
+			}
+			avatar.TeamResonanceList = newTeamResonanceIdList
+			if notify {
+				g.UpdatePlayerAvatarFightProp(worldPlayer.PlayerId, avatar.AvatarId)
+			} else {
+				dbAvatar.UpdateAvatarFightProp(avatar)
+				if entityId != 0 {
+					entity := world.GetSceneById(worldPlayer.GetSceneId()).GetEntity(entityId)
+					if entity != nil {
+						entity.SetFightProp(avatar.FightPropMap)
+					}
+				}
+			}
+			if entityId == 0 {
+				continue
+			}
+			teamResonanceChangeNotify.InfoList = append(teamResonanceChangeNotify.InfoList, &proto.AvatarTeamResonanceInfo{
+				AddTeamResonanceIdList: addList,
+				EntityId:               entityId,
+				AvatarGuid:             avatar.Guid,
+				DelTeamResonanceIdList: delList,
+			})
+		}
+	}
+	if notify && len(teamResonanceChangeNotify.InfoList) > 0 {
+		g.SendToWorldA(world, cmd.TeamResonanceChangeNotify, 0, teamResonanceChangeNotify, 0)
+	}
+}
+
 /************************************************** 打包封装 **************************************************/
 
 func (g *Game) PacketSceneTeamUpdateNotify(world *World, player *model.Player) *proto.SceneTeamUpdateNotify {
@@ -0,0 +1,293 @@
+package game
+
+import (
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+)
+
+// 试用角色 由任务或地牢发放 只存在于内存中 离开场景时自动移除
+
+// GrantPlayerTrialAvatar 发放试用角色并加入当前队伍
+func (g *Game) GrantPlayerTrialAvatar(player *model.Player, trialAvatarIdList []uint32, lockTeam bool) proto.Retcode {
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
+		return proto.Retcode_RET_FAIL
+	}
+	oldAvatarEntity := world.GetPlayerActiveAvatarEntity(player)
+	ret := g.SetPlayerTrialAvatarTeam(player, world, trialAvatarIdList, lockTeam)
+	if ret != proto.Retcode_RET_SUCC {
+		return ret
+	}
+	g.UpdateWorldTeamResonance(world, true)
+	dbAvatar := player.GetDbAvatar()
+	for _, trialAvatarId := range trialAvatarIdList {
+		for _, trialAvatar := range dbAvatar.GetTrialAvatarMap() {
+			if trialAvatar.TrialAvatarId != trialAvatarId {
+				continue
+			}
+			avatarAddNotify := &proto.AvatarAddNotify{
+				Avatar:   g.PacketAvatarInfo(trialAvatar),
+				IsInTeam: true,
+			}
+			g.SendMsg(cmd.AvatarAddNotify, player.PlayerId, player.ClientSeq, avatarAddNotify)
+		}
+	}
+	g.TrialAvatarTeamUpdateNotify(player, world, oldAvatarEntity)
+	return proto.Retcode_RET_SUCC
+}
+
+// RemovePlayerTrialAvatar 移除试用角色 试用角色全部移除后恢复玩家原本的队伍
+func (g *Game) RemovePlayerTrialAvatar(player *model.Player, trialAvatarIdList []uint32) {
+	world := WORLD_MANAGER.GetWorldById(player.WorldId)
+	if world == nil {
+		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
+		return
+	}
+	oldAvatarEntity := world.GetPlayerActiveAvatarEntity(player)
+	removeAvatarList := g.RestorePlayerTrialAvatarTeam(player, world, trialAvatarIdList)
+	if len(removeAvatarList) == 0 {
+		return
+	}
+	g.UpdateWorldTeamResonance(world, true)
+	avatarDelNotify := &proto.AvatarDelNotify{
+		AvatarGuidList: make([]uint64, 0, len(removeAvatarList)),
+	}
+	for _, avatar := range removeAvatarList {
+		avatarDelNotify.AvatarGuidList = append(avatarDelNotify.AvatarGuidList, avatar.Guid)
+	}
+	g.SendMsg(cmd.AvatarDelNotify, player.PlayerId, player.ClientSeq, avatarDelNotify)
+	g.TrialAvatarTeamUpdateNotify(player, world, oldAvatarEntity)
+}
+
+// RemovePlayerAllTrialAvatarOnSceneJump 场景跳跃时移除全部试用角色 新场景的队伍信息会在进入场景时下发
+func (g *Game) RemovePlayerAllTrialAvatarOnSceneJump(player *model.Player, world *World) {
+	removeAvatarList := g.RestorePlayerTrialAvatarTeam(player, world, nil)
+	if len(removeAvatarList) == 0 {
+		return
+	}
+	g.UpdateWorldTeamResonance(world, false)
+	avatarDelNotify := &proto.AvatarDelNotify{
+		AvatarGuidList: make([]uint64, 0, len(removeAvatarList)),
+	}
+	for _, avatar := range removeAvatarList {
+		avatarDelNotify.AvatarGuidList = append(avatarDelNotify.AvatarGuidList, avatar.Guid)
+	}
+	g.SendMsg(cmd.AvatarDelNotify, player.PlayerId, player.ClientSeq, avatarDelNotify)
+}
+
+// GrantPlayerDungeonTrialAvatarOnSceneJump 进入试用角色地牢时发放试用角色并锁定队伍 新场景的队伍信息会在进入场景时下发
+// 需要在移除旧场景的试用角色并加入新场景之后调用
+func (g *Game) GrantPlayerDungeonTrialAvatarOnSceneJump(player *model.Player, world *World, dungeonId uint32) {
+	if dungeonId == 0 {
+		return
+	}
+	trialAvatarDungeonDataConfig := gdconf.GetTrialAvatarDungeonDataByDungeonId(int32(dungeonId))
+	if trialAvatarDungeonDataConfig == nil {
+		return
+	}
+	trialAvatarIdList := make([]uint32, 0, len(trialAvatarDungeonDataConfig.TrialAvatarIdList))
+	for _, trialAvatarId := range trialAvatarDungeonDataConfig.TrialAvatarIdList {
+		trialAvatarIdList = append(trialAvatarIdList, uint32(trialAvatarId))
+	}
+	ret := g.SetPlayerTrialAvatarTeam(player, world, trialAvatarIdList, true)
+	if ret != proto.Retcode_RET_SUCC {
+		logger.Error("grant dungeon trial avatar error, ret: %v, dungeonId: %v, uid: %v", ret, dungeonId, player.PlayerId)
+		return
+	}
+	g.UpdateWorldTeamResonance(world, false)
+	for _, trialAvatar := range player.GetDbAvatar().GetTrialAvatarMap() {
+		avatarAddNotify := &proto.AvatarAddNotify{
+			Avatar:   g.PacketAvatarInfo(trialAvatar),
+			IsInTeam: true,
+		}
+		g.SendMsg(cmd.AvatarAddNotify, player.PlayerId, player.ClientSeq, avatarAddNotify)
+	}
+}
+
+// SetPlayerTrialAvatarTeam 创建试用角色并设置玩家本地队伍
+func (g *Game) SetPlayerTrialAvatarTeam(player *model.Player, world *World, trialAvatarIdList []uint32, lockTeam bool) proto.Retcode {
+	if world.IsMultiplayerWorld() {
+		return proto.Retcode_RET_MP_IN_MP_MODE
+	}
+	if len(trialAvatarIdList) == 0 {
+		return proto.Retcode_RET_FAIL
+	}
+	dbAvatar := player.GetDbAvatar()
+	trialAvatarAvatarIdList := make([]uint32, 0, len(trialAvatarIdList))
+	for _, trialAvatarId := range trialAvatarIdList {
+		trialAvatar := dbAvatar.AddTrialAvatar(player, trialAvatarId)
+		if trialAvatar == nil {
+			// 回滚本次已创建的试用角色
+			for _, avatarId := range trialAvatarAvatarIdList {
+				dbAvatar.RemoveTrialAvatar(player, avatarId)
+			}
+			return proto.Retcode_RET_FAIL
+		}
+		trialAvatarAvatarIdList = append(trialAvatarAvatarIdList, trialAvatar.AvatarId)
+	}
+	teamAvatarIdList := make([]uint32, 0)
+	for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+		teamAvatarIdList = append(teamAvatarIdList, worldAvatar.GetAvatarId())
+	}
+	newTeamAvatarIdList := BuildTrialAvatarTeam(teamAvatarIdList, trialAvatarAvatarIdList, lockTeam)
+	activeAvatarId := world.GetPlayerActiveAvatarId(player)
+	g.ResetPlayerWorldAvatarEntity(player, world, trialAvatarAvatarIdList)
+	world.SetPlayerLocalTeam(player, newTeamAvatarIdList)
+	world.SetPlayerActiveAvatarId(player, activeAvatarId)
+	if lockTeam || world.GetPlayerAvatarIndexByAvatarId(player, activeAvatarId) == -1 {
+		world.SetPlayerActiveAvatarId(player, trialAvatarAvatarIdList[0])
+	}
+	world.UpdateMultiplayerTeam()
+	world.UpdatePlayerWorldAvatar(player)
+	return proto.Retcode_RET_SUCC
+}
+
+// RestorePlayerTrialAvatarTeam 移除试用角色并恢复玩家本地队伍 trialAvatarIdList为空时移除全部试用角色
+func (g *Game) RestorePlayerTrialAvatarTeam(player *model.Player, world *World, trialAvatarIdList []uint32) []*model.Avatar {
+	dbAvatar := player.GetDbAvatar()
+	removeAvatarList := make([]*model.Avatar, 0)
+	for avatarId, trialAvatar := range dbAvatar.GetTrialAvatarMap() {
+		if len(trialAvatarIdList) != 0 {
+			exist := false
+			for _, trialAvatarId := range trialAvatarIdList {
+				if trialAvatar.TrialAvatarId == trialAvatarId {
+					exist = true
+					break
+				}
+			}
+			if !exist {
+				continue
+			}
+		}
+		removeAvatarList = append(removeAvatarList, dbAvatar.RemoveTrialAvatar(player, avatarId))
+	}
+	if len(removeAvatarList) == 0 {
+		return removeAvatarList
+	}
+	removeAvatarIdList := make([]uint32, 0, len(removeAvatarList))
+	for _, avatar := range removeAvatarList {
+		removeAvatarIdList = append(removeAvatarIdList, avatar.AvatarId)
+	}
+	dbTeam := player.GetDbTeam()
+	newTeamAvatarIdList := make([]uint32, 0)
+	activeAvatarId := world.GetPlayerActiveAvatarId(player)
+	if len(dbAvatar.GetTrialAvatarMap()) == 0 {
+		// 试用角色全部移除 恢复原本的队伍
+		newTeamAvatarIdList = append(newTeamAvatarIdList, dbTeam.GetActiveTeam().GetAvatarIdList()...)
+		activeAvatarId = dbTeam.GetActiveAvatarId()
+	} else {
+		for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+			avatarId := worldAvatar.GetAvatarId()
+			if dbAvatar.GetAvatarById(avatarId) == nil {
+				continue
+			}
+			newTeamAvatarIdList = append(newTeamAvatarIdList, avatarId)
+		}
+		if len(newTeamAvatarIdList) == 0 {
+			newTeamAvatarIdList = append(newTeamAvatarIdList, dbTeam.GetActiveTeam().GetAvatarIdList()...)
+		}
+	}
+	g.ResetPlayerWorldAvatarEntity(player, world, removeAvatarIdList)
+	world.SetPlayerLocalTeam(player, newTeamAvatarIdList)
+	world.SetPlayerActiveAvatarId(player, activeAvatarId)
+	if world.GetPlayerAvatarIndexByAvatarId(player, world.GetPlayerActiveAvatarId(player)) == -1 {
+		world.SetPlayerActiveAvatarId(player, newTeamAvatarIdList[0])
+	}
+	world.UpdateMultiplayerTeam()
+	world.UpdatePlayerWorldAvatar(player)
+	return removeAvatarList
+}
+
+// ResetPlayerWorldAvatarEntity 试用角色与自有角色相互替换时销毁原有的角色实体 以便按新的角色数据重新创建
+func (g *Game) ResetPlayerWorldAvatarEntity(player *model.Player, world *World, avatarIdList []uint32) {
+	scene := world.GetSceneById(player.GetSceneId())
+	for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+		for _, avatarId := range avatarIdList {
+			if worldAvatar.avatarId != avatarId {
+				continue
+			}
+			scene.DestroyEntity(worldAvatar.avatarEntityId)
+			scene.DestroyEntity(worldAvatar.weaponEntityId)
+			worldAvatar.avatarEntityId = 0
+			worldAvatar.weaponEntityId = 0
+		}
+	}
+}
+
+// BuildTrialAvatarTeam 计算加入试用角色后的队伍
+// 锁定队伍时只保留试用角色 否则试用角色优先替换同名角色 其次补充空位 队伍已满时从后往前替换非试用角色
+func BuildTrialAvatarTeam(teamAvatarIdList []uint32, trialAvatarIdList []uint32, lockTeam bool) []uint32 {
+	newTeamAvatarIdList := make([]uint32, 0, 4)
+	if lockTeam {
+		for _, avatarId := range trialAvatarIdList {
+			if len(newTeamAvatarIdList) >= 4 {
+				break
+			}
+			newTeamAvatarIdList = append(newTeamAvatarIdList, avatarId)
+		}
+		return newTeamAvatarIdList
+	}
+	newTeamAvatarIdList = append(newTeamAvatarIdList, teamAvatarIdList...)
+	isTrial := func(avatarId uint32) bool {
+		for _, trialAvatarId := range trialAvatarIdList {
+			if trialAvatarId == avatarId {
+				return true
+			}
+		}
+		return false
+	}
+	for _, trialAvatarId := range trialAvatarIdList {
+		exist := false
+		for _, avatarId := range newTeamAvatarIdList {
+			if avatarId == trialAvatarId {
+				exist = true
+				break
+			}
+		}
+		if exist {
+			continue
+		}
+		if len(newTeamAvatarIdList) < 4 {
+			newTeamAvatarIdList = append(newTeamAvatarIdList, trialAvatarId)
+			continue
+		}
+		for index := len(newTeamAvatarIdList) - 1; index >= 0; index-- {
+			if isTrial(newTeamAvatarIdList[index]) {
+				continue
+			}
+			newTeamAvatarIdList[index] = trialAvatarId
+			break
+		}
+	}
+	return newTeamAvatarIdList
+}
+
+// TrialAvatarTeamUpdateNotify 试用角色变化后的队伍更新通知
+func (g *Game) TrialAvatarTeamUpdateNotify(player *model.Player, world *World, oldAvatarEntity IEntity) {
+	sceneTeamUpdateNotify := g.PacketSceneTeamUpdateNotify(world, player)
+	g.SendMsg(cmd.SceneTeamUpdateNotify, player.PlayerId, player.ClientSeq, sceneTeamUpdateNotify)
+
+	newAvatarEntity := world.GetPlayerActiveAvatarEntity(player)
+	if player.ClientVersion < 400 || oldAvatarEntity == nil || newAvatarEntity == nil || oldAvatarEntity.GetId() == newAvatarEntity.GetId() {
+		return
+	}
+	scene := world.GetSceneById(player.GetSceneId())
+	sceneEntityDisappearNotify := &proto.SceneEntityDisappearNotify{
+		DisappearType: proto.VisionType_VISION_REPLACE,
+		EntityList:    []uint32{oldAvatarEntity.GetId()},
+	}
+	g.SendToSceneA(scene, cmd.SceneEntityDisappearNotify, player.ClientSeq, sceneEntityDisappearNotify, 0)
+
+	sceneEntityInfo := g.PacketSceneEntityInfoAvatar(scene, player, newAvatarEntity.(*AvatarEntity).GetAvatarId())
+	sceneEntityAppearNotify := &proto.SceneEntityAppearNotify{
+		AppearType: proto.VisionType_VISION_REPLACE,
+		Param:      oldAvatarEntity.GetId(),
+		EntityList: []*proto.SceneEntityInfo{sceneEntityInfo},
+	}
+	g.SendToSceneA(scene, cmd.SceneEntityAppearNotify, player.ClientSeq, sceneEntityAppearNotify, 0)
+}
@@ -0,0 +1,207 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+	"hk4e/gs/model"
+	"hk4e/protocol/proto"
+)
+
+func TestBuildTrialAvatarTeam(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		team     []uint32
+		trial    []uint32
+		lockTeam bool
+		want     []uint32
+	}{
+		{"append", []uint32{1, 2}, []uint32{3}, false, []uint32{1, 2, 3}},
+		{"same avatar keep index", []uint32{1, 2, 3}, []uint32{2}, false, []uint32{1, 2, 3}},
+		{"full team replace last", []uint32{1, 2, 3, 4}, []uint32{5}, false, []uint32{1, 2, 3, 5}},
+		{"full team skip trial slot", []uint32{1, 2, 3, 4}, []uint32{5, 6}, false, []uint32{1, 2, 6, 5}},
+		{"lock team", []uint32{1, 2, 3, 4}, []uint32{5, 6}, true, []uint32{5, 6}},
+	}
+	for _, testCase := range testCaseList {
+		got := BuildTrialAvatarTeam(testCase.team, testCase.trial, testCase.lockTeam)
+		if !reflect.DeepEqual(got, testCase.want) {
+			t.Fatalf("%v: got %v, want %v", testCase.name, got, testCase.want)
+		}
+	}
+}
+
+// 玩家拥有10000021和10000032 队伍为10000032和10000021 试用角色1为10000021 试用角色2为10000015
+func newTestTrialAvatarScene(t *testing.T) *testAbilityScene {
+	s := newTestAbilityScene(t)
+	gdconf.CONF.AvatarDataMap = map[int32]*gdconf.AvatarData{
+		10000021: {AvatarId: 10000021, SkillDepotId: 2101},
+		10000015: {AvatarId: 10000015, SkillDepotId: 1501},
+	}
+	gdconf.CONF.AvatarSkillDepotDataMap = map[int32]*gdconf.AvatarSkillDepotData{
+		2101: {AvatarSkillDepotId: 2101},
+		1501: {AvatarSkillDepotId: 1501},
+	}
+	gdconf.CONF.ItemDataMap = map[int32]*gdconf.ItemData{
+		15201: {ItemId: 15201},
+		11201: {ItemId: 11201},
+	}
+	gdconf.CONF.TrialAvatarDataMap = map[int32]*gdconf.TrialAvatarData{
+		1: {TrialAvatarId: 1, AvatarId: 10000021, Level: 5, WeaponId: 15201, WeaponLevel: 5},
+		2: {TrialAvatarId: 2, AvatarId: 10000015, Level: 5, WeaponId: 11201, WeaponLevel: 5},
+	}
+	player := s.player
+	player.SceneId = s.scene.id
+	player.Pos, player.Rot = new(model.Vector), new(model.Vector)
+	player.GameObjectGuidMap = make(map[uint64]model.GameObject)
+	dbAvatar := player.GetDbAvatar()
+	for _, avatarId := range []uint32{10000021, 10000032} {
+		dbAvatar.AvatarMap[avatarId] = &model.Avatar{AvatarId: avatarId, EquipWeapon: &model.Weapon{}, FightPropMap: make(map[uint32]float32)}
+	}
+	dbTeam := player.GetDbTeam()
+	dbTeam.GetActiveTeam().SetAvatarIdList([]uint32{10000032, 10000021})
+	world := s.scene.world
+	world.playerMap = map[uint32]*model.Player{player.PlayerId: player}
+	world.peerList = []*model.Player{player}
+	world.sceneMap = map[uint32]*Scene{s.scene.id: s.scene}
+	world.multiplayerTeam = CreateMultiplayerTeam()
+	world.SetPlayerLocalTeam(player, dbTeam.GetActiveTeam().GetAvatarIdList())
+	world.SetPlayerActiveAvatarId(player, 10000032)
+	world.UpdateMultiplayerTeam()
+	world.UpdatePlayerWorldAvatar(player)
+	return s
+}
+
+func TestTrialAvatarTeam(t *testing.T) {
+	s := newTestTrialAvatarScene(t)
+	player := s.player
+	dbAvatar := player.GetDbAvatar()
+	dbTeam := player.GetDbTeam()
+	world := s.scene.world
+	ownEntityId := world.GetPlayerWorldAvatarEntityId(player, 10000021)
+	g := new(Game)
+
+	getTeam := func() []uint32 {
+		team := make([]uint32, 0)
+		for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+			team = append(team, worldAvatar.GetAvatarId())
+		}
+		return team
+	}
+
+	// 同名角色被试用角色替换 并重新创建实体
+	ret := g.SetPlayerTrialAvatarTeam(player, world, []uint32{1, 2}, false)
+	if ret != proto.Retcode_RET_SUCC {
+		t.Fatalf("set trial avatar team error, ret: %v", ret)
+	}
+	if !reflect.DeepEqual(getTeam(), []uint32{10000032, 10000021, 10000015}) || world.GetPlayerActiveAvatarId(player) != 10000032 {
+		t.Fatalf("trial avatar team error, team: %v", getTeam())
+	}
+	trialEntityId := world.GetPlayerWorldAvatarEntityId(player, 10000021)
+	if trialEntityId == ownEntityId || s.scene.GetEntity(ownEntityId) != nil {
+		t.Fatalf("trial avatar entity not recreated")
+	}
+	trialAvatar := dbAvatar.GetAvatarById(10000021)
+	if !trialAvatar.IsTrial() || s.scene.GetEntity(trialEntityId).GetFightProp()[constant.FIGHT_PROP_CUR_HP] != trialAvatar.FightPropMap[constant.FIGHT_PROP_CUR_HP] {
+		t.Fatalf("trial avatar entity should use trial avatar data")
+	}
+	if len(dbAvatar.GetAvatarMap()) != 2 || dbTeam.GetActiveTeam().GetAvatarIdList()[1] != 10000021 {
+		t.Fatalf("trial avatar should not change persistent data")
+	}
+
+	// 移除部分试用角色
+	removeAvatarList := g.RestorePlayerTrialAvatarTeam(player, world, []uint32{2})
+	if len(removeAvatarList) != 1 || !reflect.DeepEqual(getTeam(), []uint32{10000032, 10000021}) {
+		t.Fatalf("remove trial avatar error, team: %v", getTeam())
+	}
+
+	// 锁定队伍
+	ret = g.SetPlayerTrialAvatarTeam(player, world, []uint32{2}, true)
+	if ret != proto.Retcode_RET_SUCC || !reflect.DeepEqual(getTeam(), []uint32{10000015}) || world.GetPlayerActiveAvatarId(player) != 10000015 {
+		t.Fatalf("lock trial avatar team error, team: %v", getTeam())
+	}
+
+	// 全部移除后恢复原队伍
+	removeAvatarList = g.RestorePlayerTrialAvatarTeam(player, world, nil)
+	if len(removeAvatarList) != 2 || len(dbAvatar.GetTrialAvatarMap()) != 0 {
+		t.Fatalf("remove all trial avatar error, remove: %v", len(removeAvatarList))
+	}
+	if !reflect.DeepEqual(getTeam(), []uint32{10000032, 10000021}) || world.GetPlayerActiveAvatarId(player) != 10000032 {
+		t.Fatalf("restore team error, team: %v", getTeam())
+	}
+	if dbAvatar.GetAvatarById(10000021).IsTrial() {
+		t.Fatalf("own avatar not restored")
+	}
+
+	// 多人世界不允许发放
+	world.multiplayer = true
+	if g.SetPlayerTrialAvatarTeam(player, world, []uint32{1}, false) != proto.Retcode_RET_MP_IN_MP_MODE {
+		t.Fatalf("trial avatar should be refused in multiplayer world")
+	}
+}
+
+func TestDungeonTrialAvatarOnSceneJump(t *testing.T) {
+	s := newTestTrialAvatarScene(t)
+	gdconf.CONF.TrialAvatarDungeonDataMap = map[int32]*gdconf.TrialAvatarActivityData{
+		6000: {Id: 51001, DungeonId: 6000, TrialAvatarIdList: []int32{1, 2}},
+	}
+	player := s.player
+	dbAvatar := player.GetDbAvatar()
+	world := s.scene.world
+	bigWorldScene := s.scene
+	dungeonScene := &Scene{
+		id:        20008,
+		world:     world,
+		playerMap: make(map[uint32]*model.Player),
+		entityMap: make(map[uint32]IEntity),
+	}
+	world.sceneMap[dungeonScene.id] = dungeonScene
+	g := new(Game)
+
+	getTeam := func() []uint32 {
+		team := make([]uint32, 0)
+		for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+			team = append(team, worldAvatar.GetAvatarId())
+		}
+		return team
+	}
+
+	// 进入地牢前已有任务发放的试用角色
+	if g.SetPlayerTrialAvatarTeam(player, world, []uint32{2}, false) != proto.Retcode_RET_SUCC {
+		t.Fatal("set trial avatar team error")
+	}
+	// 进入地牢 旧场景的试用角色先被移除 然后发放地牢的试用角色并锁定队伍
+	g.PlayerSceneJump(player, world, bigWorldScene, dungeonScene, &EnterSceneContext{
+		OldSceneId: bigWorldScene.id,
+		NewSceneId: dungeonScene.id,
+		NewPos:     new(model.Vector),
+		NewRot:     new(model.Vector),
+		DungeonId:  6000,
+	})
+	if !reflect.DeepEqual(getTeam(), []uint32{10000021, 10000015}) || world.GetPlayerActiveAvatarId(player) != 10000021 {
+		t.Fatalf("dungeon trial avatar team error, team: %v", getTeam())
+	}
+	if len(dbAvatar.GetTrialAvatarMap()) != 2 || !dbAvatar.GetAvatarById(10000021).IsTrial() {
+		t.Fatalf("dungeon trial avatar not granted, trial: %v", len(dbAvatar.GetTrialAvatarMap()))
+	}
+	for _, worldAvatar := range world.GetPlayerLocalTeam(player) {
+		if dungeonScene.GetEntity(worldAvatar.GetAvatarEntityId()) == nil {
+			t.Fatalf("trial avatar entity not in dungeon scene, avatarId: %v", worldAvatar.GetAvatarId())
+		}
+	}
+	if len(bigWorldScene.entityMap) != 0 {
+		t.Fatalf("avatar entity left in old scene, count: %v", len(bigWorldScene.entityMap))
+	}
+
+	// 离开地牢 试用角色全部移除 恢复原队伍
+	g.PlayerSceneJump(player, world, dungeonScene, bigWorldScene, &EnterSceneContext{
+		OldSceneId: dungeonScene.id,
+		NewSceneId: bigWorldScene.id,
+		NewPos:     new(model.Vector),
+		NewRot:     new(model.Vector),
+	})
+	if len(dbAvatar.GetTrialAvatarMap()) != 0 || !reflect.DeepEqual(getTeam(), []uint32{10000032, 10000021}) {
+		t.Fatalf("restore team after dungeon error, team: %v", getTeam())
+	}
+}
@@ -1,10 +1,9 @@
 package game
 
 import (
-	"time"
-
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
 
@@ -28,7 +27,7 @@ func (g *Game) CreateVehicleReq(player *model.Player, payloadMsg pb.Message) {
 
 	// 创建载具冷却时间
 	createVehicleCd := int64(5000) // TODO 冷却时间读取配置表
-	if time.Now().UnixMilli()-player.VehicleInfo.LastCreateTime < createVehicleCd {
+	if clock.Now().UnixMilli()-player.VehicleInfo.LastCreateTime < createVehicleCd {
 		g.SendError(cmd.VehicleInteractRsp, player, &proto.VehicleInteractRsp{}, proto.Retcode_RET_CREATE_VEHICLE_IN_CD)
 		return
 	}
@@ -52,7 +51,7 @@ func (g *Game) CreateVehicleReq(player *model.Player, payloadMsg pb.Message) {
 	GAME.AddSceneEntityNotify(player, proto.VisionType_VISION_BORN, []uint32{gadgetVehicleEntity.GetId()}, true, false)
 	// 记录创建的载具信息
 	player.VehicleInfo.CreateEntityIdMap[req.VehicleId] = gadgetVehicleEntity.GetId()
-	player.VehicleInfo.LastCreateTime = time.Now().UnixMilli()
+	player.VehicleInfo.LastCreateTime = clock.Now().UnixMilli()
 
 	// PacketCreateVehicleRsp
 	createVehicleRsp := &proto.CreateVehicleRsp{
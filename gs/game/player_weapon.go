@@ -481,6 +481,8 @@ func (g *Game) AddPlayerWeapon(userId uint32, itemId uint32) uint64 {
 		return 0
 	}
 	g.SendMsg(cmd.StoreItemChangeNotify, userId, player.ClientSeq, g.PacketStoreItemChangeNotifyByWeapon(weapon))
+	// 图鉴解锁
+	g.CodexObtainItem(player, itemId)
 	return weaponId
 }
 
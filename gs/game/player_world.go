@@ -7,6 +7,7 @@ import (
 	"hk4e/common/constant"
 	"hk4e/gdconf"
 	"hk4e/gs/model"
+	"hk4e/pkg/clock"
 	"hk4e/pkg/random"
 	"hk4e/protocol/cmd"
 	"hk4e/protocol/proto"
@@ -51,7 +52,7 @@ func (g *Game) SceneTransToPointReq(player *model.Player, payloadMsg pb.Message)
 	}
 
 	// 传送玩家
-	g.TeleportPlayer(
+	ok := g.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_TRANS_POINT,
 		req.SceneId,
@@ -60,6 +61,10 @@ func (g *Game) SceneTransToPointReq(player *model.Player, payloadMsg pb.Message)
 		0,
 		0,
 	)
+	if !ok {
+		g.SendError(cmd.SceneTransToPointRsp, player, &proto.SceneTransToPointRsp{})
+		return
+	}
 
 	rsp := &proto.SceneTransToPointRsp{
 		PointId: req.PointId,
@@ -145,7 +150,7 @@ func (g *Game) MarkMapReq(player *model.Player, payloadMsg pb.Message) {
 			logger.Error("parse pos y error: %v", err)
 			posY = 300
 		}
-		g.TeleportPlayer(
+		ok := g.TeleportPlayer(
 			player,
 			proto.EnterReason_ENTER_REASON_GM,
 			req.Mark.SceneId,
@@ -154,6 +159,10 @@ func (g *Game) MarkMapReq(player *model.Player, payloadMsg pb.Message) {
 			0,
 			0,
 		)
+		if !ok {
+			g.SendError(cmd.MarkMapRsp, player, &proto.MarkMapRsp{})
+			return
+		}
 		g.SendMsg(cmd.MarkMapRsp, player.PlayerId, player.ClientSeq, &proto.MarkMapRsp{MarkList: g.PacketMapMarkPointList(player)})
 		return
 	}
@@ -279,11 +288,16 @@ func (g *Game) ChangeGameTimeReq(player *model.Player, payloadMsg pb.Message) {
 		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
 		return
 	}
+	// 只有房主可以修改世界的游戏时间
+	if world.GetOwner().PlayerId != player.PlayerId {
+		g.SendError(cmd.ChangeGameTimeRsp, player, &proto.ChangeGameTimeRsp{})
+		return
+	}
 	logger.Debug("change game time, gameTime: %v, uid: %v", gameTime, player.PlayerId)
 	g.ChangeGameTime(world, gameTime)
 
 	// 天气气象随机
-	g.WeatherClimateRandom(player, player.WeatherInfo.WeatherAreaId)
+	g.SceneWeatherRefresh(world, clock.Now().UnixMilli(), true)
 
 	rsp := &proto.ChangeGameTimeRsp{
 		CurGameTime: world.GetGameTime(),
@@ -342,7 +356,7 @@ func (g *Game) PlayerEnterDungeonReq(player *model.Player, payloadMsg pb.Message
 		return
 	}
 	sceneConfig := sceneLuaConfig.SceneConfig
-	g.TeleportPlayer(
+	ok := g.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_DUNGEON_ENTER,
 		uint32(dungeonDataConfig.SceneId),
@@ -351,6 +365,10 @@ func (g *Game) PlayerEnterDungeonReq(player *model.Player, payloadMsg pb.Message
 		req.DungeonId,
 		req.PointId,
 	)
+	if !ok {
+		g.SendError(cmd.PlayerEnterDungeonRsp, player, &proto.PlayerEnterDungeonRsp{})
+		return
+	}
 
 	rsp := &proto.PlayerEnterDungeonRsp{
 		DungeonId: req.DungeonId,
@@ -376,7 +394,7 @@ func (g *Game) PlayerQuitDungeonReq(player *model.Player, payloadMsg pb.Message)
 	if pointDataConfig == nil {
 		return
 	}
-	g.TeleportPlayer(
+	ok := g.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_DUNGEON_QUIT,
 		ctx.OldSceneId,
@@ -385,6 +403,10 @@ func (g *Game) PlayerQuitDungeonReq(player *model.Player, payloadMsg pb.Message)
 		0,
 		0,
 	)
+	if !ok {
+		g.SendError(cmd.PlayerQuitDungeonRsp, player, &proto.PlayerQuitDungeonRsp{})
+		return
+	}
 
 	rsp := &proto.PlayerQuitDungeonRsp{
 		PointId: req.PointId,
@@ -432,22 +454,43 @@ func (g *Game) GadgetInteractReq(player *model.Player, payloadMsg pb.Message) {
 			interactType = proto.InteractType_INTERACT_PICK_ITEM
 			gadgetTrifleItemEntity := entity.(*GadgetTrifleItemEntity)
 			itemList := []*ChangeItem{{ItemId: gadgetTrifleItemEntity.GetItemId(), ChangeCount: gadgetTrifleItemEntity.GetCount()}}
-			g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP)
+			if !g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP) {
+				// 被插件取消时保留掉落物
+				g.SendError(cmd.GadgetInteractRsp, player, &proto.GadgetInteractRsp{})
+				return
+			}
 			g.KillEntity(player, scene, entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
 		case constant.GADGET_TYPE_GATHER_OBJECT:
 			// 采集物摘取
 			interactType = proto.InteractType_INTERACT_GATHER
 			gadgetGatherEntity := entity.(*GadgetGatherEntity)
 			itemList := []*ChangeItem{{ItemId: gadgetGatherEntity.GetItemId(), ChangeCount: gadgetGatherEntity.GetCount()}}
-			g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_GATHER)
+			if !g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_GATHER) {
+				// 被插件取消时保留采集物
+				g.SendError(cmd.GadgetInteractRsp, player, &proto.GadgetInteractRsp{})
+				return
+			}
+			g.TriggerActivityWatcher(player, constant.NEW_ACTIVITY_WATCHER_TRIGGER_TYPE_GATHER, int32(gadgetGatherEntity.GetGadgetId()), 1)
 			g.KillEntity(player, scene, entity.GetId(), proto.PlayerDieType_PLAYER_DIE_NONE)
 		case constant.GADGET_TYPE_CHEST:
 			// 宝箱开启
 			interactType = proto.InteractType_INTERACT_OPEN_CHEST
 			// 宝箱交互结束 开启宝箱
 			if req.OpType == proto.InterOpType_INTER_OP_FINISH {
-				// 随机掉落
-				g.chestDrop(player, entity)
+				// 地脉之花奖励宝箱
+				if g.GetBlossomByChest(world, entity) != nil {
+					retCode := g.BlossomChestReward(player, scene, entity)
+					if retCode != proto.Retcode_RET_SUCC {
+						g.SendError(cmd.GadgetInteractRsp, player, &proto.GadgetInteractRsp{}, retCode)
+						return
+					}
+					break
+				}
+				// 随机掉落 被插件取消时不开启宝箱
+				if !g.chestDrop(player, entity) {
+					g.SendError(cmd.GadgetInteractRsp, player, &proto.GadgetInteractRsp{})
+					return
+				}
 				// 更新宝箱状态
 				g.SendMsg(cmd.WorldChestOpenNotify, player.PlayerId, player.ClientSeq, &proto.WorldChestOpenNotify{
 					GroupId:  entity.GetGroupId(),
@@ -649,7 +692,7 @@ func (g *Game) PersonalSceneJumpReq(player *model.Player, payloadMsg pb.Message)
 		return
 	}
 	destSceneId := uint32(pointDataConfig.TranSceneId)
-	g.TeleportPlayer(
+	ok := g.TeleportPlayer(
 		player,
 		proto.EnterReason_ENTER_REASON_TRANS_POINT,
 		destSceneId,
@@ -658,6 +701,10 @@ func (g *Game) PersonalSceneJumpReq(player *model.Player, payloadMsg pb.Message)
 		0,
 		0,
 	)
+	if !ok {
+		g.SendError(cmd.PersonalSceneJumpRsp, player, &proto.PersonalSceneJumpRsp{})
+		return
+	}
 
 	g.SendMsg(cmd.PersonalSceneJumpRsp, player.PlayerId, player.ClientSeq, &proto.PersonalSceneJumpRsp{
 		DestSceneId: destSceneId,
@@ -726,6 +773,104 @@ func (g *Game) ChangeGameTime(world *World, gameTime uint32) {
 	g.PlayerGameTimeNotify(world)
 }
 
+// DungeonMonsterKill 地牢内怪物死亡 记录击杀并检查通关条件
+func (g *Game) DungeonMonsterKill(scene *Scene, monsterEntity *MonsterEntity) {
+	dungeon := scene.GetDungeon()
+	if dungeon == nil || dungeon.IsSettled() {
+		return
+	}
+	dungeon.AddKill(monsterEntity.GetMonsterId())
+	if g.IsDungeonPassCondSatisfied(scene) {
+		g.DungeonSettle(scene, true)
+	}
+}
+
+// IsDungeonPassCondSatisfied 地牢通关条件是否满足 目前只支持击杀类条件
+func (g *Game) IsDungeonPassCondSatisfied(scene *Scene) bool {
+	dungeon := scene.GetDungeon()
+	dungeonDataConfig := gdconf.GetDungeonDataById(int32(dungeon.GetDungeonId()))
+	if dungeonDataConfig == nil {
+		return false
+	}
+	dungeonPassDataConfig := gdconf.GetDungeonPassDataById(dungeonDataConfig.PassCond)
+	if dungeonPassDataConfig == nil || len(dungeonPassDataConfig.CondList) == 0 {
+		return false
+	}
+	for _, cond := range dungeonPassDataConfig.CondList {
+		ok := false
+		switch cond.Type {
+		case constant.DUNGEON_COND_KILL_MONSTER:
+			// 击杀指定怪物
+			if len(cond.Param) < 1 {
+				break
+			}
+			ok = dungeon.GetKillCount(uint32(cond.Param[0])) > 0
+		case constant.DUNGEON_COND_KILL_GROUP_MONSTER:
+			// 击杀group内的全部怪物
+			if len(cond.Param) < 1 {
+				break
+			}
+			group := scene.GetGroupById(uint32(cond.Param[0]))
+			if group == nil {
+				break
+			}
+			ok = true
+			for _, entity := range group.GetAllEntity() {
+				if _, isMonster := entity.(*MonsterEntity); isMonster {
+					ok = false
+					break
+				}
+			}
+		case constant.DUNGEON_COND_KILL_MONSTER_COUNT:
+			// 击杀怪物数量
+			if len(cond.Param) < 1 {
+				break
+			}
+			ok = dungeon.GetTotalKillCount() >= uint32(cond.Param[0])
+		default:
+			logger.Debug("not support dungeon pass cond type: %v, dungeonId: %v", cond.Type, dungeon.GetDungeonId())
+		}
+		if dungeonPassDataConfig.CondCompose == constant.QUEST_LOGIC_TYPE_OR {
+			if ok {
+				return true
+			}
+		} else {
+			if !ok {
+				return false
+			}
+		}
+	}
+	return dungeonPassDataConfig.CondCompose != constant.QUEST_LOGIC_TYPE_OR
+}
+
+// DungeonSettle 地牢结算 每次进入只结算一次 只对地牢场景内的玩家生效
+func (g *Game) DungeonSettle(scene *Scene, isSuccess bool) {
+	dungeon := scene.GetDungeon()
+	if dungeon == nil || dungeon.IsSettled() {
+		return
+	}
+	dungeon.Settle()
+	logger.Debug("dungeon settle, dungeonId: %v, isSuccess: %v, sceneId: %v", dungeon.GetDungeonId(), isSuccess, scene.GetId())
+	closeTime := uint32(0)
+	dungeonDataConfig := gdconf.GetDungeonDataById(int32(dungeon.GetDungeonId()))
+	if dungeonDataConfig != nil {
+		closeTime = uint32(dungeonDataConfig.SettleCountdownTime)
+	}
+	for _, player := range scene.GetAllPlayer() {
+		if isSuccess {
+			// 通关时在地牢内的玩家当前队伍中的角色增加好感度经验
+			g.AddPlayerTeamFetterExp(player, FetterExpDungeonSuccess)
+		}
+		g.SendMsg(cmd.DungeonSettleNotify, player.PlayerId, player.ClientSeq, &proto.DungeonSettleNotify{
+			DungeonId:       dungeon.GetDungeonId(),
+			IsSuccess:       isSuccess,
+			UseTime:         uint32(scene.GetSceneTime() / 1000),
+			CloseTime:       closeTime,
+			CreatePlayerUid: scene.GetWorld().GetOwner().PlayerId,
+		})
+	}
+}
+
 const (
 	MonsterDropTypeHp = iota
 	MonsterDropTypeKill
@@ -760,7 +905,7 @@ func (g *Game) monsterDrop(player *model.Player, monsterDropType int, hpDropId i
 				}
 				dropTag = gdconf.GetDropModelByMonsterModel(monsterDataConfig.Name)
 			}
-			monsterDropDataConfig := gdconf.GetMonsterDropDataByDropTagAndLevel(dropTag, monsterConfig.Level)
+			monsterDropDataConfig := gdconf.GetMonsterDropDataByDropTagAndLevel(dropTag, int32(entity.GetLevel()))
 			if monsterDropDataConfig == nil {
 				logger.Error("get monster drop data config is nil, monsterConfig: %+v, uid: %v", monsterConfig, player.PlayerId)
 				return
@@ -775,25 +920,17 @@ func (g *Game) monsterDrop(player *model.Player, monsterDropType int, hpDropId i
 		return
 	}
 	totalItemMap := g.doRandDropFullTimes(dropDataConfig, int(dropCount))
-	for itemId, count := range totalItemMap {
-		itemDataConfig := gdconf.GetItemDataById(int32(itemId))
-		if itemDataConfig == nil {
-			logger.Error("get item data config is nil, itemId: %v, uid: %v", itemId, player.PlayerId)
-			continue
-		}
-		if itemDataConfig.GadgetId != 0 {
-			g.CreateDropGadget(player, entity.GetPos(), uint32(itemDataConfig.GadgetId), itemId, count)
-		} else {
-			g.AddPlayerItem(player.PlayerId, []*ChangeItem{{ItemId: itemId, ChangeCount: count}}, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP)
-		}
+	if !g.dropItem(player, entity, totalItemMap) {
+		logger.Debug("monster drop cancel, dropId: %v, uid: %v", dropId, player.PlayerId)
 	}
 }
 
-func (g *Game) chestDrop(player *model.Player, entity IEntity) {
+// 宝箱掉落 掉落被插件取消时返回false 配置错误时不掉落
+func (g *Game) chestDrop(player *model.Player, entity IEntity) bool {
 	sceneGroupConfig := gdconf.GetSceneGroup(int32(entity.GetGroupId()))
 	if sceneGroupConfig == nil {
 		logger.Error("get scene group config is nil, groupId: %v, uid: %v", entity.GetGroupId(), player.PlayerId)
-		return
+		return true
 	}
 	gadgetConfig := sceneGroupConfig.GadgetMap[int32(entity.GetConfigId())]
 	dropId := int32(0)
@@ -802,10 +939,15 @@ func (g *Game) chestDrop(player *model.Player, entity IEntity) {
 		dropId = gadgetConfig.ChestDropId
 		dropCount = 1
 	} else {
-		chestDropDataConfig := gdconf.GetChestDropDataByDropTagAndLevel(gadgetConfig.DropTag, gadgetConfig.Level)
+		chestLevel := gadgetConfig.Level
+		world := WORLD_MANAGER.GetWorldById(player.WorldId)
+		if world != nil {
+			chestLevel = int32(GetWorldLevelEntityLevel(gadgetConfig.Level, uint32(world.GetWorldLevel())))
+		}
+		chestDropDataConfig := gdconf.GetChestDropDataByDropTagAndLevel(gadgetConfig.DropTag, chestLevel)
 		if chestDropDataConfig == nil {
 			logger.Error("get chest drop data config is nil, gadgetConfig: %+v, uid: %v", gadgetConfig, player.PlayerId)
-			return
+			return true
 		}
 		dropId = chestDropDataConfig.DropId
 		dropCount = chestDropDataConfig.DropCount
@@ -813,9 +955,17 @@ func (g *Game) chestDrop(player *model.Player, entity IEntity) {
 	dropDataConfig := gdconf.GetDropDataById(dropId)
 	if dropDataConfig == nil {
 		logger.Error("get drop data config is nil, dropId: %v, uid: %v", dropId, player.PlayerId)
-		return
+		return true
 	}
 	totalItemMap := g.doRandDropFullTimes(dropDataConfig, int(dropCount))
+	return g.dropItem(player, entity, totalItemMap)
+}
+
+// 有掉落物物件的物品生成掉落物 其余物品直接进入背包 进入背包被插件取消时不生成任何掉落物并返回false
+func (g *Game) dropItem(player *model.Player, entity IEntity, totalItemMap map[uint32]uint32) bool {
+	itemList := make([]*ChangeItem, 0)
+	gadgetItemMap := make(map[uint32]uint32)
+	gadgetIdMap := make(map[uint32]uint32)
 	for itemId, count := range totalItemMap {
 		itemDataConfig := gdconf.GetItemDataById(int32(itemId))
 		if itemDataConfig == nil {
@@ -823,11 +973,19 @@ func (g *Game) chestDrop(player *model.Player, entity IEntity) {
 			continue
 		}
 		if itemDataConfig.GadgetId != 0 {
-			g.CreateDropGadget(player, entity.GetPos(), uint32(itemDataConfig.GadgetId), itemId, count)
+			gadgetItemMap[itemId] = count
+			gadgetIdMap[itemId] = uint32(itemDataConfig.GadgetId)
 		} else {
-			g.AddPlayerItem(player.PlayerId, []*ChangeItem{{ItemId: itemId, ChangeCount: count}}, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP)
+			itemList = append(itemList, &ChangeItem{ItemId: itemId, ChangeCount: count})
 		}
 	}
+	if len(itemList) != 0 && !g.AddPlayerItem(player.PlayerId, itemList, proto.ActionReasonType_ACTION_REASON_SUBFIELD_DROP) {
+		return false
+	}
+	for itemId, count := range gadgetItemMap {
+		g.CreateDropGadget(player, entity.GetPos(), gadgetIdMap[itemId], itemId, count)
+	}
+	return true
 }
 
 func (g *Game) doRandDropFullTimes(dropDataConfig *gdconf.DropData, times int) map[uint32]uint32 {
@@ -903,20 +1061,33 @@ func (g *Game) doRandDropOnce(dropDataConfig *gdconf.DropData) map[int32]int32 {
 	return dropMap
 }
 
-// TeleportPlayer 传送玩家通用接口
+// TeleportPlayer 传送玩家通用接口 传送被拒绝或被插件取消时返回false 调用方需要自行返回错误
 func (g *Game) TeleportPlayer(
 	player *model.Player, enterReason proto.EnterReason,
 	sceneId uint32, pos, rot *model.Vector,
 	dungeonId, dungeonPointId uint32,
-) {
+) bool {
 	world := WORLD_MANAGER.GetWorldById(player.WorldId)
 	if world == nil {
 		logger.Error("get world is nil, worldId: %v, uid: %v", player.WorldId, player.PlayerId)
-		return
+		return false
 	}
 	if CommandPerm(player.CmdPerm) != CommandPermGM && WORLD_MANAGER.IsAiWorld(world) {
-		return
+		return false
+	}
+
+	// 触发事件
+	preTeleportEvent := &PluginEventPreTeleport{
+		PluginEvent: NewPluginEvent(),
+		Player:      player,
+		EnterReason: enterReason,
+		SceneId:     sceneId,
+		Pos:         pos,
+	}
+	if PLUGIN_MANAGER.TriggerEvent(PluginEventIdPreTeleport, preTeleportEvent) {
+		return false
 	}
+	sceneId, pos = preTeleportEvent.SceneId, preTeleportEvent.Pos
 
 	oldSceneId := player.GetSceneId()
 	oldPos := g.GetPlayerPos(player)
@@ -956,6 +1127,7 @@ func (g *Game) TeleportPlayer(
 
 	playerEnterSceneNotify := g.PacketPlayerEnterSceneNotifyTp(player, enterType, newSceneId, newPos, dungeonId, enterSceneToken)
 	g.SendMsg(cmd.PlayerEnterSceneNotify, player.PlayerId, player.ClientSeq, playerEnterSceneNotify)
+	return true
 }
 
 /************************************************** 打包封装 **************************************************/
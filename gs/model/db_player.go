@@ -49,6 +49,12 @@ type Player struct {
 	DbGacha         *DbGacha           // 卡池
 	DbQuest         *DbQuest           // 任务
 	DbWorld         *DbWorld           // 大世界
+	DbSignIn        *DbSignIn          // 签到
+	DbCodex         *DbCodex           // 图鉴
+	DbActivity      *DbActivity        // 活动
+	DbRecharge      *DbRecharge        // 充值
+	DbReunion       *DbReunion         // 回归
+	DbLogin         *DbLogin           // 登录
 	// 在线数据 请随意 记得加忽略字段的tag
 	LastSaveTime          uint32                                   `bson:"-" msgpack:"-"` // 上一次存档保存时间
 	DbState               int                                      `bson:"-" msgpack:"-"` // 数据库存档状态
@@ -0,0 +1,100 @@
+package model
+
+type DbActivity struct {
+	ActivityMap map[uint32]*Activity // key:活动id
+}
+
+func (p *Player) GetDbActivity() *DbActivity {
+	if p.DbActivity == nil {
+		p.DbActivity = new(DbActivity)
+	}
+	if p.DbActivity.ActivityMap == nil {
+		p.DbActivity.ActivityMap = make(map[uint32]*Activity)
+	}
+	return p.DbActivity
+}
+
+type Activity struct {
+	ActivityId uint32                      // 活动id
+	ScheduleId uint32                      // 排期id
+	WatcherMap map[uint32]*ActivityWatcher // 进度监听 key:监听id
+	Salesman   *ActivitySalesman           // 商人活动数据 其他类型的活动为空
+}
+
+type ActivityWatcher struct {
+	WatcherId     uint32 // 监听id
+	CurProgress   uint32 // 当前进度
+	TotalProgress uint32 // 目标进度
+	IsTakenReward bool   // 是否已领奖
+}
+
+// ActivitySalesman 商人活动数据
+type ActivitySalesman struct {
+	DeliverCount          uint32 // 累计交付次数
+	LastDeliverTime       uint32 // 上次交付时间
+	DayRewardId           uint32 // 上次交付获得的奖励id
+	IsSpecialRewardUnlock bool   // 是否已解锁特殊奖励
+	IsTakenSpecialReward  bool   // 是否已领取特殊奖励
+}
+
+func (a *DbActivity) GetActivity(activityId uint32) *Activity {
+	return a.ActivityMap[activityId]
+}
+
+// StartActivity 开启活动 排期变化时视为新一期活动 重置活动数据
+// watcherProgressMap key:监听id value:目标进度
+func (a *DbActivity) StartActivity(activityId uint32, scheduleId uint32, watcherProgressMap map[uint32]uint32) (*Activity, bool) {
+	activity, exist := a.ActivityMap[activityId]
+	if exist && activity.ScheduleId == scheduleId {
+		return activity, false
+	}
+	activity = &Activity{
+		ActivityId: activityId,
+		ScheduleId: scheduleId,
+		WatcherMap: make(map[uint32]*ActivityWatcher),
+	}
+	for watcherId, totalProgress := range watcherProgressMap {
+		activity.WatcherMap[watcherId] = &ActivityWatcher{
+			WatcherId:     watcherId,
+			CurProgress:   0,
+			TotalProgress: totalProgress,
+			IsTakenReward: false,
+		}
+	}
+	a.ActivityMap[activityId] = activity
+	return activity, true
+}
+
+// EndActivity 结束活动 删除活动数据
+func (a *DbActivity) EndActivity(activityId uint32) {
+	delete(a.ActivityMap, activityId)
+}
+
+func (a *Activity) GetWatcher(watcherId uint32) *ActivityWatcher {
+	return a.WatcherMap[watcherId]
+}
+
+// AddWatcherProgress 增加监听进度 不超过目标进度 返回进度是否发生变化
+func (a *Activity) AddWatcherProgress(watcherId uint32, count uint32) bool {
+	watcher, exist := a.WatcherMap[watcherId]
+	if !exist {
+		return false
+	}
+	return watcher.AddProgress(count)
+}
+
+func (w *ActivityWatcher) IsFinish() bool {
+	return w.CurProgress >= w.TotalProgress
+}
+
+// AddProgress 增加进度 不超过目标进度 返回进度是否发生变化
+func (w *ActivityWatcher) AddProgress(count uint32) bool {
+	if w.IsFinish() {
+		return false
+	}
+	w.CurProgress += count
+	if w.CurProgress > w.TotalProgress {
+		w.CurProgress = w.TotalProgress
+	}
+	return true
+}
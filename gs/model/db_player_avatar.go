@@ -1,10 +1,9 @@
 package model
 
 import (
-	"time"
-
 	"hk4e/common/constant"
 	"hk4e/gdconf"
+	"hk4e/pkg/clock"
 
 	"github.com/flswld/halo/logger"
 )
@@ -14,6 +13,7 @@ type DbAvatar struct {
 	MainCharAvatarId uint32             // 主角id
 	FlyCloakList     []uint32           // 风之翼列表
 	CostumeList      []uint32           // 角色衣装列表
+	TrialAvatarMap   map[uint32]*Avatar `bson:"-" msgpack:"-"` // 试用角色列表 不保存
 }
 
 func (p *Player) GetDbAvatar() *DbAvatar {
@@ -32,6 +32,9 @@ func (p *Player) GetDbAvatar() *DbAvatar {
 	if p.DbAvatar.CostumeList == nil {
 		p.DbAvatar.CostumeList = make([]uint32, 0)
 	}
+	if p.DbAvatar.TrialAvatarMap == nil {
+		p.DbAvatar.TrialAvatarMap = make(map[uint32]*Avatar)
+	}
 	return p.DbAvatar
 }
 
@@ -54,15 +57,23 @@ type Avatar struct {
 	BornTime          int64                // 获得时间
 	FetterLevel       uint8                // 好感度等级
 	FetterExp         uint32               // 好感度经验
+	FetterRewardList  []uint32             // 已领取奖励的好感度等级
 	PromoteRewardMap  map[uint32]bool      // 突破奖励 map[突破等级]是否已被领取
 	Guid              uint64               `bson:"-" msgpack:"-"`
 	EquipGuidMap      map[uint64]uint64    `bson:"-" msgpack:"-"`
 	EquipWeapon       *Weapon              `bson:"-" msgpack:"-"`
 	EquipReliquaryMap map[uint8]*Reliquary `bson:"-" msgpack:"-"`
 	FightPropMap      map[uint32]float32   `bson:"-" msgpack:"-"`
+	TeamResonanceList []uint32             `bson:"-" msgpack:"-"` // 当前生效的队伍元素共鸣
+	TrialAvatarId     uint32               `bson:"-" msgpack:"-"` // 试用角色id 非试用角色为0
 }
 
+// GetAvatarById 获取角色 试用角色优先
 func (a *DbAvatar) GetAvatarById(avatarId uint32) *Avatar {
+	trialAvatar, exist := a.TrialAvatarMap[avatarId]
+	if exist {
+		return trialAvatar
+	}
 	return a.AvatarMap[avatarId]
 }
 
@@ -178,6 +189,17 @@ func (a *DbAvatar) UpdateAvatarFightProp(avatar *Avatar) {
 			avatar.FightPropMap[uint32(reliquaryAffixConfig.PropType)] += reliquaryAffixConfig.AppendPropValue
 		}
 	}
+	// 队伍元素共鸣属性加成
+	for _, teamResonanceId := range avatar.TeamResonanceList {
+		teamResonanceConfig := gdconf.GetTeamResonanceDataById(int32(teamResonanceId))
+		if teamResonanceConfig == nil {
+			logger.Error("teamResonanceConfig is nil, teamResonanceId: %v", teamResonanceId)
+			continue
+		}
+		for k, v := range teamResonanceConfig.FightPropMap {
+			avatar.FightPropMap[k] += v
+		}
+	}
 	// 攻防血绿字计算
 	fpm := avatar.FightPropMap
 	fpm[constant.FIGHT_PROP_CUR_ATTACK] = fpm[constant.FIGHT_PROP_BASE_ATTACK]*(1.0+fpm[constant.FIGHT_PROP_ATTACK_PERCENT]) + fpm[constant.FIGHT_PROP_ATTACK]
@@ -207,9 +229,10 @@ func (a *DbAvatar) AddAvatar(player *Player, avatarId uint32) {
 		SkillDepotId:      0,
 		FlyCloak:          140001,
 		Costume:           0,
-		BornTime:          time.Now().Unix(),
+		BornTime:          clock.Now().Unix(),
 		FetterLevel:       1,
 		FetterExp:         0,
+		FetterRewardList:  make([]uint32, 0),
 		Guid:              0,
 		EquipGuidMap:      nil,
 		EquipWeapon:       nil,
@@ -294,7 +317,7 @@ func (a *DbAvatar) TakeOffWeapon(avatarId uint32, weapon *Weapon) {
 }
 
 func (a *DbAvatar) GetAvatarElementType(avatarId uint32) int {
-	avatar := a.AvatarMap[avatarId]
+	avatar := a.GetAvatarById(avatarId)
 	avatarSkillDepotDataConfig := gdconf.GetAvatarSkillDepotDataById(int32(avatar.SkillDepotId))
 	if avatarSkillDepotDataConfig == nil {
 		return 0
@@ -305,3 +328,166 @@ func (a *DbAvatar) GetAvatarElementType(avatarId uint32) int {
 	}
 	return int(avatarSkillDataConfig.CostElemType)
 }
+
+func (a *Avatar) IsFetterRewarded(fetterLevel uint32) bool {
+	for _, level := range a.FetterRewardList {
+		if level == fetterLevel {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Avatar) IsTrial() bool {
+	return a.TrialAvatarId != 0
+}
+
+func (a *DbAvatar) GetTrialAvatarMap() map[uint32]*Avatar {
+	return a.TrialAvatarMap
+}
+
+// GetEffectiveAvatarList 获取所有生效中的角色 被试用角色覆盖的自有角色不包含在内
+func (a *DbAvatar) GetEffectiveAvatarList() []*Avatar {
+	avatarList := make([]*Avatar, 0, len(a.AvatarMap)+len(a.TrialAvatarMap))
+	for avatarId, avatar := range a.AvatarMap {
+		_, exist := a.TrialAvatarMap[avatarId]
+		if exist {
+			continue
+		}
+		avatarList = append(avatarList, avatar)
+	}
+	for _, trialAvatar := range a.TrialAvatarMap {
+		avatarList = append(avatarList, trialAvatar)
+	}
+	return avatarList
+}
+
+// AddTrialAvatar 根据试用角色配置创建试用角色 只存在于内存中 不进入角色列表
+func (a *DbAvatar) AddTrialAvatar(player *Player, trialAvatarId uint32) *Avatar {
+	trialAvatarDataConfig := gdconf.GetTrialAvatarDataById(int32(trialAvatarId))
+	if trialAvatarDataConfig == nil {
+		logger.Error("trial avatar data config is nil, trialAvatarId: %v", trialAvatarId)
+		return nil
+	}
+	avatarId := uint32(trialAvatarDataConfig.AvatarId)
+	avatarDataConfig := gdconf.GetAvatarDataById(int32(avatarId))
+	if avatarDataConfig == nil {
+		logger.Error("avatar data config is nil, avatarId: %v", avatarId)
+		return nil
+	}
+	skillDepotId := trialAvatarDataConfig.SkillDepotId
+	if skillDepotId == 0 {
+		skillDepotId = avatarDataConfig.SkillDepotId
+		// 主角沿用当前元素
+		ownAvatar, exist := a.AvatarMap[avatarId]
+		if exist && ownAvatar.SkillDepotId != 0 {
+			skillDepotId = int32(ownAvatar.SkillDepotId)
+		}
+	}
+	avatarSkillDepotDataConfig := gdconf.GetAvatarSkillDepotDataById(skillDepotId)
+	if avatarSkillDepotDataConfig == nil {
+		logger.Error("avatar skill depot data config is nil, skillDepotId: %v", skillDepotId)
+		return nil
+	}
+	weaponItemConfig := gdconf.GetItemDataById(trialAvatarDataConfig.WeaponId)
+	if weaponItemConfig == nil {
+		logger.Error("weapon item config is nil, itemId: %v", trialAvatarDataConfig.WeaponId)
+		return nil
+	}
+	a.RemoveTrialAvatar(player, avatarId)
+	level := uint8(trialAvatarDataConfig.Level)
+	promote := uint8(gdconf.GetAvatarPromoteLevelByLevel(avatarDataConfig.PromoteId, trialAvatarDataConfig.Level))
+	avatar := &Avatar{
+		AvatarId:          avatarId,
+		LifeState:         constant.LIFE_STATE_ALIVE,
+		Level:             level,
+		Exp:               0,
+		Promote:           promote,
+		FetterList:        make([]uint32, 0),
+		SkillLevelMap:     make(map[uint32]uint32),
+		TalentIdList:      make([]uint32, 0),
+		SkillDepotId:      uint32(skillDepotId),
+		FlyCloak:          140001,
+		Costume:           uint32(trialAvatarDataConfig.CostumeId),
+		BornTime:          clock.Now().Unix(),
+		FetterLevel:       1,
+		FetterRewardList:  make([]uint32, 0),
+		PromoteRewardMap:  make(map[uint32]bool),
+		EquipGuidMap:      make(map[uint64]uint64),
+		EquipReliquaryMap: make(map[uint8]*Reliquary),
+		FightPropMap:      make(map[uint32]float32),
+		TrialAvatarId:     trialAvatarId,
+	}
+	// 技能等级
+	skillLevel := uint32(trialAvatarDataConfig.SkillLevel)
+	avatar.SkillLevelMap[uint32(avatarSkillDepotDataConfig.EnergySkill)] = skillLevel
+	for _, skillId := range avatarSkillDepotDataConfig.Skills {
+		avatar.SkillLevelMap[uint32(skillId)] = skillLevel
+	}
+	// 命座
+	for _, talentIdx := range trialAvatarDataConfig.TalentIdxList {
+		if talentIdx < 1 || int(talentIdx) > len(avatarSkillDepotDataConfig.Talents) {
+			continue
+		}
+		avatar.TalentIdList = append(avatar.TalentIdList, uint32(avatarSkillDepotDataConfig.Talents[talentIdx-1]))
+	}
+	avatar.Guid = player.GetNextGameObjectGuid()
+	player.GameObjectGuidMap[avatar.Guid] = GameObject(avatar)
+	// 武器 试用装备不注册guid映射 避免被穿戴到自有角色身上
+	weapon := &Weapon{
+		WeaponId:    0,
+		ItemId:      uint32(trialAvatarDataConfig.WeaponId),
+		Level:       uint8(trialAvatarDataConfig.WeaponLevel),
+		AffixIdList: make([]uint32, 0),
+		AvatarId:    avatarId,
+		Guid:        player.GetNextGameObjectGuid(),
+	}
+	for _, skillAffix := range weaponItemConfig.SkillAffix {
+		weapon.AffixIdList = append(weapon.AffixIdList, uint32(skillAffix))
+	}
+	avatar.EquipWeapon = weapon
+	avatar.EquipGuidMap[weapon.Guid] = weapon.Guid
+	// 圣遗物
+	for _, trialReliquaryId := range trialAvatarDataConfig.ReliquaryList {
+		trialReliquaryDataConfig := gdconf.GetTrialReliquaryDataById(trialReliquaryId)
+		if trialReliquaryDataConfig == nil {
+			continue
+		}
+		reliquaryItemConfig := gdconf.GetItemDataById(trialReliquaryDataConfig.ReliquaryId)
+		if reliquaryItemConfig == nil {
+			continue
+		}
+		reliquary := &Reliquary{
+			ReliquaryId:      0,
+			ItemId:           uint32(trialReliquaryDataConfig.ReliquaryId),
+			Level:            uint8(trialReliquaryDataConfig.Level),
+			Promote:          uint8(trialReliquaryDataConfig.Promote),
+			AppendPropIdList: make([]uint32, 0),
+			MainPropId:       uint32(trialReliquaryDataConfig.MainPropId),
+			AvatarId:         avatarId,
+			Guid:             player.GetNextGameObjectGuid(),
+		}
+		for _, appendPropId := range trialReliquaryDataConfig.AppendPropIdList {
+			reliquary.AppendPropIdList = append(reliquary.AppendPropIdList, uint32(appendPropId))
+		}
+		avatar.EquipReliquaryMap[uint8(reliquaryItemConfig.ReliquaryType)] = reliquary
+		avatar.EquipGuidMap[reliquary.Guid] = reliquary.Guid
+	}
+	a.TrialAvatarMap[avatarId] = avatar
+	// 满血进入
+	a.UpdateAvatarFightProp(avatar)
+	avatar.FightPropMap[constant.FIGHT_PROP_CUR_HP] = avatar.FightPropMap[constant.FIGHT_PROP_MAX_HP]
+	avatar.CurrHP = float64(avatar.FightPropMap[constant.FIGHT_PROP_MAX_HP])
+	return avatar
+}
+
+// RemoveTrialAvatar 移除试用角色
+func (a *DbAvatar) RemoveTrialAvatar(player *Player, avatarId uint32) *Avatar {
+	avatar, exist := a.TrialAvatarMap[avatarId]
+	if !exist {
+		return nil
+	}
+	delete(player.GameObjectGuidMap, avatar.Guid)
+	delete(a.TrialAvatarMap, avatarId)
+	return avatar
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+)
+
+const (
+	testTrialAvatarId = 1
+	testAvatarId      = 10000021
+	testSkillDepotId  = 2101
+	testWeaponItemId  = 15201
+)
+
+func newTestTrialAvatarConfig(t *testing.T) {
+	gdconf.SetTestConf(t, &gdconf.GameDataConfig{
+		AvatarDataMap: map[int32]*gdconf.AvatarData{
+			testAvatarId: {
+				AvatarId:      testAvatarId,
+				SkillDepotId:  testSkillDepotId,
+				PromoteId:     1,
+				FightPropList: []*gdconf.FightProp{{FightPropId: constant.FIGHT_PROP_BASE_HP, FightPropValue: 1000}},
+			},
+		},
+		AvatarPromoteDataMap: map[int32]map[int32]*gdconf.AvatarPromoteData{
+			1: {
+				0: {PromoteId: 1, PromoteLevel: 0, LevelLimit: 20},
+				1: {PromoteId: 1, PromoteLevel: 1, LevelLimit: 40},
+				2: {PromoteId: 1, PromoteLevel: 2, LevelLimit: 50},
+			},
+		},
+		AvatarSkillDepotDataMap: map[int32]*gdconf.AvatarSkillDepotData{
+			testSkillDepotId: {
+				AvatarSkillDepotId: testSkillDepotId,
+				EnergySkill:        10213,
+				Skills:             []int32{10211, 10212},
+				Talents:            []int32{211, 212, 213, 214, 215, 216},
+			},
+		},
+		ItemDataMap: map[int32]*gdconf.ItemData{
+			testWeaponItemId: {ItemId: testWeaponItemId, SkillAffix: []int32{115201}},
+		},
+		TrialAvatarDataMap: map[int32]*gdconf.TrialAvatarData{
+			testTrialAvatarId: {
+				TrialAvatarId: testTrialAvatarId,
+				AvatarId:      testAvatarId,
+				Level:         45,
+				WeaponId:      testWeaponItemId,
+				WeaponLevel:   40,
+				TalentIdxList: []int32{1, 2, 7},
+				SkillLevel:    2,
+			},
+		},
+	})
+}
+
+func TestGetAvatarPromoteLevelByLevel(t *testing.T) {
+	newTestTrialAvatarConfig(t)
+	for level, want := range map[int32]int32{1: 0, 20: 0, 21: 1, 40: 1, 45: 2, 90: 2} {
+		got := gdconf.GetAvatarPromoteLevelByLevel(1, level)
+		if got != want {
+			t.Fatalf("promote level error, level: %v, got: %v, want: %v", level, got, want)
+		}
+	}
+}
+
+func TestTrialAvatar(t *testing.T) {
+	newTestTrialAvatarConfig(t)
+	player := &Player{PlayerId: 10001, GameObjectGuidMap: make(map[uint64]GameObject)}
+	dbAvatar := player.GetDbAvatar()
+	dbAvatar.AvatarMap[testAvatarId] = &Avatar{AvatarId: testAvatarId, Level: 90}
+
+	trialAvatar := dbAvatar.AddTrialAvatar(player, testTrialAvatarId)
+	if trialAvatar == nil || !trialAvatar.IsTrial() {
+		t.Fatalf("add trial avatar error, trialAvatar: %+v", trialAvatar)
+	}
+	if trialAvatar.Level != 45 || trialAvatar.Promote != 2 || trialAvatar.EquipWeapon.Level != 40 {
+		t.Fatalf("trial avatar level error, level: %v, promote: %v, weapon level: %v", trialAvatar.Level, trialAvatar.Promote, trialAvatar.EquipWeapon.Level)
+	}
+	// 超出技能库的命座下标忽略
+	if !reflect.DeepEqual(trialAvatar.TalentIdList, []uint32{211, 212}) {
+		t.Fatalf("trial avatar talent error, talent: %v", trialAvatar.TalentIdList)
+	}
+	if !reflect.DeepEqual(trialAvatar.SkillLevelMap, map[uint32]uint32{10211: 2, 10212: 2, 10213: 2}) {
+		t.Fatalf("trial avatar skill level error, skill level: %v", trialAvatar.SkillLevelMap)
+	}
+	if trialAvatar.FightPropMap[constant.FIGHT_PROP_CUR_HP] != 1000 {
+		t.Fatalf("trial avatar hp error, fight prop: %v", trialAvatar.FightPropMap)
+	}
+	// 试用角色覆盖同名自有角色 但不进入角色列表
+	if dbAvatar.GetAvatarById(testAvatarId) != trialAvatar || dbAvatar.GetAvatarMap()[testAvatarId].Level != 90 {
+		t.Fatalf("trial avatar should shadow own avatar")
+	}
+	if len(dbAvatar.GetEffectiveAvatarList()) != 1 || dbAvatar.GetEffectiveAvatarList()[0] != trialAvatar {
+		t.Fatalf("effective avatar list error")
+	}
+	if _, exist := player.GameObjectGuidMap[trialAvatar.EquipWeapon.Guid]; exist {
+		t.Fatalf("trial weapon should not be registered")
+	}
+
+	dbAvatar.RemoveTrialAvatar(player, testAvatarId)
+	if _, exist := player.GameObjectGuidMap[trialAvatar.Guid]; exist {
+		t.Fatalf("trial avatar guid not removed")
+	}
+	if dbAvatar.GetAvatarById(testAvatarId).IsTrial() {
+		t.Fatalf("own avatar not restored")
+	}
+}
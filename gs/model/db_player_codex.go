@@ -0,0 +1,59 @@
+package model
+
+type DbCodex struct {
+	CodexMap       map[uint32]map[uint32]bool // 已解锁的图鉴 key1:图鉴类型 key2:图鉴id value:是否已查看
+	MonsterKillMap map[uint32]uint32          // 怪物图鉴击杀数量 key:图鉴id value:击杀数量
+}
+
+func (p *Player) GetDbCodex() *DbCodex {
+	if p.DbCodex == nil {
+		p.DbCodex = new(DbCodex)
+	}
+	if p.DbCodex.CodexMap == nil {
+		p.DbCodex.CodexMap = make(map[uint32]map[uint32]bool)
+	}
+	if p.DbCodex.MonsterKillMap == nil {
+		p.DbCodex.MonsterKillMap = make(map[uint32]uint32)
+	}
+	return p.DbCodex
+}
+
+func (c *DbCodex) IsCodexUnlock(codexType uint32, codexId uint32) bool {
+	codexIdMap, exist := c.CodexMap[codexType]
+	if !exist {
+		return false
+	}
+	_, exist = codexIdMap[codexId]
+	return exist
+}
+
+// UnlockCodex 解锁图鉴 返回是否为新解锁
+func (c *DbCodex) UnlockCodex(codexType uint32, codexId uint32) bool {
+	if c.IsCodexUnlock(codexType, codexId) {
+		return false
+	}
+	codexIdMap, exist := c.CodexMap[codexType]
+	if !exist {
+		codexIdMap = make(map[uint32]bool)
+		c.CodexMap[codexType] = codexIdMap
+	}
+	codexIdMap[codexId] = false
+	return true
+}
+
+// ViewCodex 查看图鉴 返回是否成功
+func (c *DbCodex) ViewCodex(codexType uint32, codexId uint32) bool {
+	if !c.IsCodexUnlock(codexType, codexId) {
+		return false
+	}
+	c.CodexMap[codexType][codexId] = true
+	return true
+}
+
+func (c *DbCodex) AddMonsterKill(codexId uint32) {
+	c.MonsterKillMap[codexId]++
+}
+
+func (c *DbCodex) GetMonsterKill(codexId uint32) uint32 {
+	return c.MonsterKillMap[codexId]
+}
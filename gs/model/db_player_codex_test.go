@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestCodex(t *testing.T) {
+	player := new(Player)
+	dbCodex := player.GetDbCodex()
+	if dbCodex.ViewCodex(3, 20011201) {
+		t.Fatal("view locked codex")
+	}
+	if !dbCodex.UnlockCodex(3, 20011201) {
+		t.Fatal("first unlock fail")
+	}
+	if dbCodex.UnlockCodex(3, 20011201) {
+		t.Fatal("unlock twice")
+	}
+	if dbCodex.IsCodexUnlock(2, 20011201) {
+		t.Fatal("codex type mix")
+	}
+	if dbCodex.CodexMap[3][20011201] {
+		t.Fatal("new codex viewed")
+	}
+	if !dbCodex.ViewCodex(3, 20011201) || !dbCodex.CodexMap[3][20011201] {
+		t.Fatal("view codex fail")
+	}
+	dbCodex.AddMonsterKill(20011201)
+	dbCodex.AddMonsterKill(20011201)
+	if dbCodex.GetMonsterKill(20011201) != 2 || dbCodex.GetMonsterKill(20011301) != 0 {
+		t.Fatal("monster kill count error")
+	}
+}
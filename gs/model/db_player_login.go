@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+type DbLogin struct {
+	LastLoginDay   uint32 // 上一次登录的日期序号
+	LoginStreak    uint32 // 当前连续登录天数
+	MaxLoginStreak uint32 // 历史最大连续登录天数
+	TotalLoginDay  uint32 // 累计登录天数
+}
+
+func (p *Player) GetDbLogin() *DbLogin {
+	if p.DbLogin == nil {
+		p.DbLogin = new(DbLogin)
+	}
+	return p.DbLogin
+}
+
+// GetDayIndex 获取某个时间点所在自然日的序号 相邻两天的序号相差1
+func GetDayIndex(now time.Time) uint32 {
+	return uint32(time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// LoginDay 记录某一天的登录 同一天只计一次 返回是否为当天首次登录
+func (l *DbLogin) LoginDay(now time.Time) bool {
+	day := GetDayIndex(now)
+	if day == l.LastLoginDay {
+		return false
+	}
+	if l.LastLoginDay != 0 && day == l.LastLoginDay+1 {
+		l.LoginStreak++
+	} else {
+		// 中断后重新计数
+		l.LoginStreak = 1
+	}
+	if l.LoginStreak > l.MaxLoginStreak {
+		l.MaxLoginStreak = l.LoginStreak
+	}
+	l.TotalLoginDay++
+	l.LastLoginDay = day
+	return true
+}
+
+// GetLoginStreak 获取截止某个时间点的连续登录天数 昨天及今天都没有登录则视为已中断
+func (l *DbLogin) GetLoginStreak(now time.Time) uint32 {
+	day := GetDayIndex(now)
+	if l.LastLoginDay == 0 || day > l.LastLoginDay+1 {
+		return 0
+	}
+	return l.LoginStreak
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginStreak(t *testing.T) {
+	player := new(Player)
+	dbLogin := player.GetDbLogin()
+	now := time.Date(2023, 2, 27, 23, 0, 0, 0, time.Local)
+	if dbLogin.GetLoginStreak(now) != 0 {
+		t.Fatal("login streak should be zero before login")
+	}
+	if !dbLogin.LoginDay(now) || dbLogin.LoginStreak != 1 {
+		t.Fatalf("first login error, streak: %v", dbLogin.LoginStreak)
+	}
+	// 同一天多次登录只计一次
+	if dbLogin.LoginDay(now.Add(time.Minute*30)) || dbLogin.TotalLoginDay != 1 {
+		t.Fatal("login twice in one day")
+	}
+	// 跨月连续登录
+	dbLogin.LoginDay(time.Date(2023, 2, 28, 0, 10, 0, 0, time.Local))
+	dbLogin.LoginDay(time.Date(2023, 3, 1, 8, 0, 0, 0, time.Local))
+	if dbLogin.LoginStreak != 3 || dbLogin.GetLoginStreak(time.Date(2023, 3, 2, 20, 0, 0, 0, time.Local)) != 3 {
+		t.Fatalf("login streak error, streak: %v", dbLogin.LoginStreak)
+	}
+	// 断签后连续天数归零 历史最大值保留
+	if dbLogin.GetLoginStreak(time.Date(2023, 3, 3, 0, 0, 0, 0, time.Local)) != 0 {
+		t.Fatal("login streak should break")
+	}
+	dbLogin.LoginDay(time.Date(2023, 3, 5, 8, 0, 0, 0, time.Local))
+	if dbLogin.LoginStreak != 1 || dbLogin.MaxLoginStreak != 3 || dbLogin.TotalLoginDay != 4 {
+		t.Fatalf("login streak reset error, dbLogin: %+v", dbLogin)
+	}
+}
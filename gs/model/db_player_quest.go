@@ -1,10 +1,9 @@
 package model
 
 import (
-	"time"
-
 	"hk4e/common/constant"
 	"hk4e/gdconf"
+	"hk4e/pkg/clock"
 
 	"github.com/flswld/halo/logger"
 )
@@ -69,7 +68,7 @@ func (q *DbQuest) AddQuest(questId uint32) {
 	q.QuestMap[questId] = &Quest{
 		QuestId:         uint32(questDataConfig.QuestId),
 		State:           constant.QUEST_STATE_UNSTARTED,
-		AcceptTime:      uint32(time.Now().Unix()),
+		AcceptTime:      uint32(clock.Now().Unix()),
 		StartTime:       0,
 		FinishCountList: make([]uint32, len(questDataConfig.FinishCondList)),
 	}
@@ -88,7 +87,7 @@ func (q *DbQuest) StartQuest(questId uint32) {
 		return
 	}
 	quest.State = constant.QUEST_STATE_UNFINISHED
-	quest.StartTime = uint32(time.Now().Unix())
+	quest.StartTime = uint32(clock.Now().Unix())
 }
 
 // DeleteQuest 删除一个任务
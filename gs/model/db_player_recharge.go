@@ -0,0 +1,127 @@
+package model
+
+import (
+	"sort"
+)
+
+const (
+	OrderStateCreate = iota + 1 // 已下单 等待支付
+	OrderStatePaid              // 已支付 等待发货
+	OrderStateFinish            // 已发货
+)
+
+const (
+	OrderFinishKeepTime = 3600 * 24 * 30 // 已发货订单的保留时长 单位秒
+)
+
+type DbRecharge struct {
+	NextOrderId  uint32            // 下一个订单id
+	OrderMap     map[uint32]*Order // 订单 key:订单id
+	BoughtNumMap map[uint32]uint32 // 各充值档位已购买次数 key:档位配置id
+}
+
+func (p *Player) GetDbRecharge() *DbRecharge {
+	if p.DbRecharge == nil {
+		p.DbRecharge = new(DbRecharge)
+	}
+	if p.DbRecharge.OrderMap == nil {
+		p.DbRecharge.OrderMap = make(map[uint32]*Order)
+	}
+	if p.DbRecharge.BoughtNumMap == nil {
+		p.DbRecharge.BoughtNumMap = make(map[uint32]uint32)
+	}
+	return p.DbRecharge
+}
+
+type Order struct {
+	OrderId    uint32 // 订单id
+	ProductId  string // 商品id
+	ConfigId   uint32 // 充值档位配置id
+	PriceTier  string // 支付档位
+	TradeNo    string // 支付渠道交易号
+	State      uint8  // 订单状态
+	AddMcoin   uint32 // 实际发放的创世结晶数量
+	IsFirstBuy bool   // 是否为首充
+	CreateTime uint32 // 下单时间点
+	PayTime    uint32 // 支付时间点
+	FinishTime uint32 // 发货时间点
+}
+
+// CreateOrder 创建待支付订单 顺便清理过期的已发货订单
+func (r *DbRecharge) CreateOrder(productId string, configId uint32, priceTier string, now uint32) *Order {
+	for orderId, order := range r.OrderMap {
+		if order.State == OrderStateFinish && now-order.FinishTime > OrderFinishKeepTime {
+			delete(r.OrderMap, orderId)
+		}
+	}
+	r.NextOrderId++
+	order := &Order{
+		OrderId:    r.NextOrderId,
+		ProductId:  productId,
+		ConfigId:   configId,
+		PriceTier:  priceTier,
+		State:      OrderStateCreate,
+		CreateTime: now,
+	}
+	r.OrderMap[order.OrderId] = order
+	return order
+}
+
+func (r *DbRecharge) GetOrder(orderId uint32) *Order {
+	return r.OrderMap[orderId]
+}
+
+// DeleteOrder 删除未支付的订单 下单失败时使用
+func (r *DbRecharge) DeleteOrder(orderId uint32) {
+	order, exist := r.OrderMap[orderId]
+	if !exist || order.State != OrderStateCreate {
+		return
+	}
+	delete(r.OrderMap, orderId)
+}
+
+// PayOrder 订单确认支付 重复的支付通知直接忽略 返回是否发生状态变化
+func (r *DbRecharge) PayOrder(orderId uint32, tradeNo string, now uint32) bool {
+	order, exist := r.OrderMap[orderId]
+	if !exist || order.State != OrderStateCreate {
+		return false
+	}
+	order.State = OrderStatePaid
+	order.TradeNo = tradeNo
+	order.PayTime = now
+	return true
+}
+
+// IsFirstBuy 某个充值档位是否还未购买过
+func (r *DbRecharge) IsFirstBuy(configId uint32) bool {
+	return r.BoughtNumMap[configId] == 0
+}
+
+// FinishOrder 已支付订单发货 每个订单只会成功一次
+func (r *DbRecharge) FinishOrder(orderId uint32, addMcoin uint32, isFirstBuy bool, now uint32) bool {
+	order, exist := r.OrderMap[orderId]
+	if !exist || order.State != OrderStatePaid {
+		return false
+	}
+	order.State = OrderStateFinish
+	order.AddMcoin = addMcoin
+	order.IsFirstBuy = isFirstBuy
+	order.FinishTime = now
+	r.BoughtNumMap[order.ConfigId]++
+	return true
+}
+
+// GetOrderListByState 获取某个状态的订单列表 按订单id排序
+func (r *DbRecharge) GetOrderListByState(state uint8) []*Order {
+	orderList := make([]*Order, 0)
+	for _, order := range r.OrderMap {
+		if order.State != state {
+			continue
+		}
+		orderList = append(orderList, order)
+	}
+	sort.Slice(orderList, func(i, j int) bool {
+		return orderList[i].OrderId < orderList[j].OrderId
+	})
+	return orderList
+}
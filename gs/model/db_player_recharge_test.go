@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestRechargeOrder(t *testing.T) {
+	player := &Player{PlayerId: 10001}
+	dbRecharge := player.GetDbRecharge()
+
+	order := dbRecharge.CreateOrder("ys_chn_primogem_tier_1", 1, "Tier_1", 1000)
+	if order.OrderId != 1 || order.State != OrderStateCreate {
+		t.Fatalf("create order error, order: %+v", order)
+	}
+	// 未支付的订单不能发货
+	if dbRecharge.FinishOrder(order.OrderId, 120, true, 1001) {
+		t.Fatalf("unpaid order should not finish")
+	}
+	if !dbRecharge.PayOrder(order.OrderId, "trade_1", 1002) {
+		t.Fatalf("pay order error")
+	}
+	// 重复的支付通知不改变订单
+	if dbRecharge.PayOrder(order.OrderId, "trade_2", 1003) || order.TradeNo != "trade_1" {
+		t.Fatalf("repeat pay should be ignored, order: %+v", order)
+	}
+	if !dbRecharge.IsFirstBuy(1) {
+		t.Fatalf("config should be first buy")
+	}
+	if !dbRecharge.FinishOrder(order.OrderId, 120, true, 1004) {
+		t.Fatalf("finish order error")
+	}
+	// 每个订单只发货一次
+	if dbRecharge.FinishOrder(order.OrderId, 120, true, 1005) || dbRecharge.PayOrder(order.OrderId, "trade_1", 1005) {
+		t.Fatalf("finished order should not change")
+	}
+	if dbRecharge.IsFirstBuy(1) || dbRecharge.BoughtNumMap[1] != 1 || !dbRecharge.IsFirstBuy(2) {
+		t.Fatalf("bought num error, boughtNumMap: %v", dbRecharge.BoughtNumMap)
+	}
+	// 下单失败的订单删除 已支付的订单不能删除
+	order2 := dbRecharge.CreateOrder("ys_chn_primogem_tier_2", 2, "Tier_2", 1006)
+	order3 := dbRecharge.CreateOrder("ys_chn_primogem_tier_2", 2, "Tier_2", 1006)
+	dbRecharge.PayOrder(order3.OrderId, "trade_3", 1007)
+	dbRecharge.DeleteOrder(order2.OrderId)
+	dbRecharge.DeleteOrder(order3.OrderId)
+	if dbRecharge.GetOrder(order2.OrderId) != nil || dbRecharge.GetOrder(order3.OrderId) == nil {
+		t.Fatalf("delete order error")
+	}
+	if orderList := dbRecharge.GetOrderListByState(OrderStatePaid); len(orderList) != 1 || orderList[0].OrderId != order3.OrderId {
+		t.Fatalf("paid order list error: %v", orderList)
+	}
+	// 过期的已发货订单在下单时清理 订单id不复用
+	order4 := dbRecharge.CreateOrder("ys_chn_primogem_tier_1", 1, "Tier_1", 1004+OrderFinishKeepTime+1)
+	if dbRecharge.GetOrder(order.OrderId) != nil || order4.OrderId != 4 {
+		t.Fatalf("expired order clean error, order4: %+v", order4)
+	}
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"time"
+)
+
+type DbReunion struct {
+	ScheduleId          uint32                      // 回归排期id 为0表示不在回归期间
+	StartTime           uint32                      // 回归开始时间点
+	FinishTime          uint32                      // 回归结束时间点
+	ReunionCount        uint32                      // 累计回归次数
+	IsTakenFirstGift    bool                        // 是否已领取见面礼
+	SignInCount         uint32                      // 回归签到次数
+	LastSignInTime      uint32                      // 上一次回归签到时间点
+	MissionScore        uint32                      // 回归任务积分
+	WatcherMap          map[uint32]*ActivityWatcher // 回归任务进度监听 key:监听id
+	MissionRewardMap    map[uint32]bool             // 已领取的回归任务分档奖励 key:分档序号
+	PrivilegeDayCount   uint32                      // 回归特权当天已使用次数
+	PrivilegeTotalCount uint32                      // 回归特权累计已使用次数
+	PrivilegeRefreshDay uint32                      // 回归特权当天次数的刷新日期序号
+}
+
+func (p *Player) GetDbReunion() *DbReunion {
+	if p.DbReunion == nil {
+		p.DbReunion = new(DbReunion)
+	}
+	if p.DbReunion.WatcherMap == nil {
+		p.DbReunion.WatcherMap = make(map[uint32]*ActivityWatcher)
+	}
+	if p.DbReunion.MissionRewardMap == nil {
+		p.DbReunion.MissionRewardMap = make(map[uint32]bool)
+	}
+	return p.DbReunion
+}
+
+// IsActive 某个时间点是否位于回归期间
+func (r *DbReunion) IsActive(now time.Time) bool {
+	return r.ScheduleId != 0 && uint32(now.Unix()) < r.FinishTime
+}
+
+// Start 开启回归 重置上一次回归的全部数据
+// watcherProgressMap key:监听id value:目标进度
+func (r *DbReunion) Start(scheduleId uint32, now time.Time, duration time.Duration, watcherProgressMap map[uint32]uint32) {
+	r.ScheduleId = scheduleId
+	r.StartTime = uint32(now.Unix())
+	r.FinishTime = uint32(now.Add(duration).Unix())
+	r.ReunionCount++
+	r.IsTakenFirstGift = false
+	r.SignInCount = 0
+	r.LastSignInTime = 0
+	r.MissionScore = 0
+	r.WatcherMap = make(map[uint32]*ActivityWatcher)
+	for watcherId, totalProgress := range watcherProgressMap {
+		r.WatcherMap[watcherId] = &ActivityWatcher{
+			WatcherId:     watcherId,
+			CurProgress:   0,
+			TotalProgress: totalProgress,
+			IsTakenReward: false,
+		}
+	}
+	r.MissionRewardMap = make(map[uint32]bool)
+	r.PrivilegeDayCount = 0
+	r.PrivilegeTotalCount = 0
+	r.PrivilegeRefreshDay = GetDayIndex(now)
+}
+
+// Finish 结束回归
+func (r *DbReunion) Finish() {
+	r.ScheduleId = 0
+}
+
+// GetDay 获取某个时间点是回归的第几天 从1开始
+func (r *DbReunion) GetDay(now time.Time) uint32 {
+	return GetDayIndex(now) - GetDayIndex(time.Unix(int64(r.StartTime), 0)) + 1
+}
+
+// GetDayStartTime 获取回归第几天的开始时间点 第一天为回归开始时间点
+func (r *DbReunion) GetDayStartTime(day uint32) uint32 {
+	if day <= 1 {
+		return r.StartTime
+	}
+	startTime := time.Unix(int64(r.StartTime), 0)
+	dayStartTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day()+int(day)-1, 0, 0, 0, 0, startTime.Location())
+	return uint32(dayStartTime.Unix())
+}
+
+// CanSignIn 检查是否可以签到 每天只能签到一次 按顺序领取
+func (r *DbReunion) CanSignIn(now time.Time, dayCount uint32) bool {
+	if r.SignInCount >= dayCount {
+		return false
+	}
+	return r.LastSignInTime == 0 || GetDayIndex(time.Unix(int64(r.LastSignInTime), 0)) != GetDayIndex(now)
+}
+
+// SignIn 回归签到 返回领取的是第几天的奖励 调用前需要先检查
+func (r *DbReunion) SignIn(now time.Time) uint32 {
+	r.SignInCount++
+	r.LastSignInTime = uint32(now.Unix())
+	return r.SignInCount
+}
+
+func (r *DbReunion) GetWatcher(watcherId uint32) *ActivityWatcher {
+	return r.WatcherMap[watcherId]
+}
+
+// AddWatcherProgress 增加回归任务监听进度 返回进度是否发生变化
+func (r *DbReunion) AddWatcherProgress(watcherId uint32, count uint32) bool {
+	watcher, exist := r.WatcherMap[watcherId]
+	if !exist {
+		return false
+	}
+	return watcher.AddProgress(count)
+}
+
+// TakeWatcherReward 领取回归任务监听奖励并获得积分 调用前需要先检查
+func (r *DbReunion) TakeWatcherReward(watcherId uint32, score uint32) {
+	watcher, exist := r.WatcherMap[watcherId]
+	if !exist {
+		return
+	}
+	watcher.IsTakenReward = true
+	r.MissionScore += score
+}
+
+// GetPrivilegeDayCount 获取某个时间点回归特权当天已使用次数
+func (r *DbReunion) GetPrivilegeDayCount(now time.Time) uint32 {
+	if r.PrivilegeRefreshDay != GetDayIndex(now) {
+		return 0
+	}
+	return r.PrivilegeDayCount
+}
+
+// UsePrivilege 使用一次回归特权 超出每日次数或总次数时返回false
+func (r *DbReunion) UsePrivilege(now time.Time, dailyLimit uint32, totalLimit uint32) bool {
+	day := GetDayIndex(now)
+	if r.PrivilegeRefreshDay != day {
+		r.PrivilegeRefreshDay = day
+		r.PrivilegeDayCount = 0
+	}
+	if r.PrivilegeDayCount >= dailyLimit || r.PrivilegeTotalCount >= totalLimit {
+		return false
+	}
+	r.PrivilegeDayCount++
+	r.PrivilegeTotalCount++
+	return true
+}
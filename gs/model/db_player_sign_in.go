@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	SignInMakeupLimit = 3 // 每个签到周期最大补签次数
+)
+
+type DbSignIn struct {
+	ScheduleId     uint32          // 当前签到周期的排期id
+	Cycle          uint32          // 当前签到周期 年*100+月
+	SignInCount    uint32          // 本周期已签到次数
+	MakeupCount    uint32          // 本周期已补签次数
+	LastSignInTime uint32          // 上一次签到时间点
+	RewardDayMap   map[uint32]bool // 本周期已领取奖励的日期
+}
+
+func (p *Player) GetDbSignIn() *DbSignIn {
+	if p.DbSignIn == nil {
+		p.DbSignIn = new(DbSignIn)
+	}
+	if p.DbSignIn.RewardDayMap == nil {
+		p.DbSignIn.RewardDayMap = make(map[uint32]bool)
+	}
+	return p.DbSignIn
+}
+
+// GetSignInCycle 获取某个时间点所在的签到周期 每个自然月为一个周期
+func GetSignInCycle(now time.Time) uint32 {
+	return uint32(now.Year()*100 + int(now.Month()))
+}
+
+// GetSignInCycleDayCount 获取某个时间点所在的签到周期的总天数
+func GetSignInCycleDayCount(now time.Time) uint32 {
+	return uint32(time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Day())
+}
+
+// CheckReset 检查签到周期是否已经过去 过去了则重置签到数据
+func (s *DbSignIn) CheckReset(now time.Time, scheduleId uint32) bool {
+	cycle := GetSignInCycle(now)
+	if s.Cycle == cycle {
+		return false
+	}
+	s.ScheduleId = scheduleId
+	s.Cycle = cycle
+	s.SignInCount = 0
+	s.MakeupCount = 0
+	s.RewardDayMap = make(map[uint32]bool)
+	return true
+}
+
+func (s *DbSignIn) GetRewardDayList() []uint32 {
+	rewardDayList := make([]uint32, 0, len(s.RewardDayMap))
+	for day := range s.RewardDayMap {
+		rewardDayList = append(rewardDayList, day)
+	}
+	return rewardDayList
+}
+
+func (s *DbSignIn) IsRewardDayTaken(day uint32) bool {
+	_, exist := s.RewardDayMap[day]
+	return exist
+}
+
+// IsSignInToday 今天是否已经签到
+func (s *DbSignIn) IsSignInToday(now time.Time) bool {
+	return s.IsRewardDayTaken(uint32(now.Day()))
+}
+
+// CheckSignIn 检查是否可以领取某一天的签到奖励 今天之前的日期视为补签
+func (s *DbSignIn) CheckSignIn(now time.Time, rewardDay uint32) (ok bool, isMakeup bool) {
+	if s.Cycle != GetSignInCycle(now) {
+		return false, false
+	}
+	today := uint32(now.Day())
+	if rewardDay == 0 || rewardDay > today {
+		return false, false
+	}
+	if s.IsRewardDayTaken(rewardDay) {
+		return false, false
+	}
+	if rewardDay == today {
+		return true, false
+	}
+	// 补签需要先完成当天的签到
+	if !s.IsSignInToday(now) {
+		return false, true
+	}
+	if s.MakeupCount >= SignInMakeupLimit {
+		return false, true
+	}
+	return true, true
+}
+
+// SignIn 领取某一天的签到奖励 调用前需要先检查
+func (s *DbSignIn) SignIn(now time.Time, rewardDay uint32, isMakeup bool) {
+	s.RewardDayMap[rewardDay] = true
+	s.SignInCount++
+	if isMakeup {
+		s.MakeupCount++
+	}
+	s.LastSignInTime = uint32(now.Unix())
+}
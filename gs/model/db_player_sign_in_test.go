@@ -0,0 +1,76 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignIn(t *testing.T) {
+	player := new(Player)
+	dbSignIn := player.GetDbSignIn()
+	now := time.Date(2023, 3, 5, 10, 0, 0, 0, time.Local)
+	if !dbSignIn.CheckReset(now, 1001) {
+		t.Fatal("first cycle should reset")
+	}
+	// 未来的日期不能签到
+	if ok, _ := dbSignIn.CheckSignIn(now, 6); ok {
+		t.Fatal("sign in future day")
+	}
+	// 当天未签到不能补签
+	if ok, isMakeup := dbSignIn.CheckSignIn(now, 1); ok || !isMakeup {
+		t.Fatal("makeup before sign in today")
+	}
+	ok, isMakeup := dbSignIn.CheckSignIn(now, 5)
+	if !ok || isMakeup {
+		t.Fatal("sign in today fail")
+	}
+	dbSignIn.SignIn(now, 5, isMakeup)
+	// 一天只能签到一次
+	if ok, _ := dbSignIn.CheckSignIn(now.Add(time.Hour), 5); ok {
+		t.Fatal("sign in twice in one day")
+	}
+	// 补签次数限制
+	for day := uint32(1); day <= SignInMakeupLimit; day++ {
+		ok, isMakeup := dbSignIn.CheckSignIn(now, day)
+		if !ok || !isMakeup {
+			t.Fatalf("makeup fail, day: %v", day)
+		}
+		dbSignIn.SignIn(now, day, isMakeup)
+	}
+	if ok, _ := dbSignIn.CheckSignIn(now, SignInMakeupLimit+1); ok {
+		t.Fatal("makeup over limit")
+	}
+	if dbSignIn.SignInCount != SignInMakeupLimit+1 || dbSignIn.MakeupCount != SignInMakeupLimit {
+		t.Fatalf("count error, signInCount: %v, makeupCount: %v", dbSignIn.SignInCount, dbSignIn.MakeupCount)
+	}
+	// 同一个月不重置
+	if dbSignIn.CheckReset(time.Date(2023, 3, 31, 23, 59, 59, 0, time.Local), 1002) {
+		t.Fatal("reset in same cycle")
+	}
+	// 跨月重置
+	nextMonth := time.Date(2023, 4, 1, 0, 0, 0, 0, time.Local)
+	if ok, _ := dbSignIn.CheckSignIn(nextMonth, 1); ok {
+		t.Fatal("sign in before reset")
+	}
+	if !dbSignIn.CheckReset(nextMonth, 1002) {
+		t.Fatal("month change not reset")
+	}
+	if dbSignIn.ScheduleId != 1002 || dbSignIn.SignInCount != 0 || dbSignIn.MakeupCount != 0 || len(dbSignIn.GetRewardDayList()) != 0 {
+		t.Fatal("reset data error")
+	}
+	if ok, _ := dbSignIn.CheckSignIn(nextMonth, 1); !ok {
+		t.Fatal("sign in after reset fail")
+	}
+}
+
+func TestSignInCycleDayCount(t *testing.T) {
+	if GetSignInCycleDayCount(time.Date(2024, 2, 10, 0, 0, 0, 0, time.Local)) != 29 {
+		t.Fatal("leap year february day count error")
+	}
+	if GetSignInCycleDayCount(time.Date(2023, 2, 10, 0, 0, 0, 0, time.Local)) != 28 {
+		t.Fatal("february day count error")
+	}
+	if GetSignInCycleDayCount(time.Date(2023, 12, 31, 0, 0, 0, 0, time.Local)) != 31 {
+		t.Fatal("december day count error")
+	}
+}
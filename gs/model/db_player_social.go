@@ -2,14 +2,17 @@ package model
 
 import (
 	"time"
+
+	"hk4e/pkg/clock"
 )
 
 type DbSocial struct {
-	Birthday        []uint8           // 生日
-	NameCard        uint32            // 当前名片
-	NameCardList    []uint32          // 已解锁名片列表
-	FriendList      map[uint32]uint32 // 好友uid列表
-	FriendApplyList map[uint32]uint32 // 好友申请uid列表
+	Birthday         []uint8           // 生日
+	BirthdayMailYear uint32            // 上一次发放生日邮件的年份
+	NameCard         uint32            // 当前名片
+	NameCardList     []uint32          // 已解锁名片列表
+	FriendList       map[uint32]uint32 // 好友uid列表
+	FriendApplyList  map[uint32]uint32 // 好友申请uid列表
 }
 
 func (p *Player) GetDbSocial() *DbSocial {
@@ -54,6 +57,36 @@ func (s *DbSocial) SetBirthday(month uint32, day uint32) {
 	s.Birthday[1] = uint8(day)
 }
 
+// SetBirthdayWithTime 设置生日 今年的生日已经过去了则今年不再补发生日邮件
+func (s *DbSocial) SetBirthdayWithTime(month uint32, day uint32, now time.Time) {
+	s.SetBirthday(month, day)
+	birthday := time.Date(now.Year(), time.Month(month), int(day), 0, 0, 0, 0, now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if birthday.Before(today) {
+		s.BirthdayMailYear = uint32(now.Year())
+	}
+}
+
+// CheckBirthdayMail 检查是否需要发放今年的生日邮件 当天或生日之后的首次登录发放 每年一次
+func (s *DbSocial) CheckBirthdayMail(now time.Time) bool {
+	if !s.IsSetBirthday() {
+		return false
+	}
+	if s.BirthdayMailYear >= uint32(now.Year()) {
+		return false
+	}
+	// 非闰年的2月29日生日会顺延到3月1日
+	birthday := time.Date(now.Year(), time.Month(s.GetBirthdayMonth()), int(s.GetBirthdayDay()), 0, 0, 0, 0, now.Location())
+	if now.Before(birthday) {
+		return false
+	}
+	return true
+}
+
+func (s *DbSocial) MarkBirthdayMail(now time.Time) {
+	s.BirthdayMailYear = uint32(now.Year())
+}
+
 func (s *DbSocial) UnlockNameCard(nameCardId uint32) {
 	for _, v := range s.NameCardList {
 		if v == nameCardId {
@@ -78,11 +111,11 @@ func (s *DbSocial) UseNameCard(nameCardId uint32) bool {
 }
 
 func (s *DbSocial) AddFriend(uid uint32) {
-	s.FriendList[uid] = uint32(time.Now().Unix())
+	s.FriendList[uid] = uint32(clock.Now().Unix())
 }
 
 func (s *DbSocial) AddFriendApply(uid uint32) {
-	s.FriendApplyList[uid] = uint32(time.Now().Unix())
+	s.FriendApplyList[uid] = uint32(clock.Now().Unix())
 }
 
 func (s *DbSocial) DelFriend(uid uint32) {
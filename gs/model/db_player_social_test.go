@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBirthdayMail(t *testing.T) {
+	player := new(Player)
+	dbSocial := player.GetDbSocial()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local)
+	if dbSocial.CheckBirthdayMail(now) {
+		t.Fatal("birthday not set")
+	}
+	dbSocial.SetBirthdayWithTime(6, 10, now)
+	if dbSocial.CheckBirthdayMail(now) {
+		t.Fatal("before birthday")
+	}
+	// 生日当天之后的首次登录发放
+	now = time.Date(2023, 6, 12, 8, 0, 0, 0, time.Local)
+	if !dbSocial.CheckBirthdayMail(now) {
+		t.Fatal("after birthday not send")
+	}
+	dbSocial.MarkBirthdayMail(now)
+	if dbSocial.CheckBirthdayMail(now.Add(time.Hour)) {
+		t.Fatal("send twice in one year")
+	}
+	// 第二年生日当天发放
+	if !dbSocial.CheckBirthdayMail(time.Date(2024, 6, 10, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("next year birthday not send")
+	}
+}
+
+func TestBirthdayMailSetAfterBirthday(t *testing.T) {
+	player := new(Player)
+	dbSocial := player.GetDbSocial()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local)
+	// 设置生日时今年的生日已经过去 不补发
+	dbSocial.SetBirthdayWithTime(3, 1, now)
+	if dbSocial.CheckBirthdayMail(now) {
+		t.Fatal("send passed birthday")
+	}
+	// 设置生日为当天 当天发放
+	player = new(Player)
+	dbSocial = player.GetDbSocial()
+	dbSocial.SetBirthdayWithTime(6, 1, now)
+	if !dbSocial.CheckBirthdayMail(now) {
+		t.Fatal("birthday today not send")
+	}
+}
+
+func TestBirthdayMailLeapDay(t *testing.T) {
+	player := new(Player)
+	dbSocial := player.GetDbSocial()
+	dbSocial.SetBirthdayWithTime(2, 29, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	// 非闰年顺延到3月1日
+	if dbSocial.CheckBirthdayMail(time.Date(2025, 2, 28, 23, 0, 0, 0, time.Local)) {
+		t.Fatal("leap day birthday send early")
+	}
+	if !dbSocial.CheckBirthdayMail(time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("leap day birthday not send")
+	}
+}
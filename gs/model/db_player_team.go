@@ -1,5 +1,15 @@
 package model
 
+import (
+	"sort"
+
+	"hk4e/gdconf"
+)
+
+const (
+	TeamResonanceAvatarNum = 4 // 触发元素共鸣需要的队伍角色数量
+)
+
 type Team struct {
 	Name         string
 	AvatarIdList []uint32
@@ -81,3 +91,97 @@ func (t *DbTeam) GetActiveAvatarId() uint32 {
 	}
 	return team.AvatarIdList[t.CurrAvatarIndex]
 }
+
+// SetTeamResonance 设置当前生效的元素共鸣
+func (t *DbTeam) SetTeamResonance(teamResonanceIdList []uint32) {
+	t.TeamResonances = make(map[uint16]bool)
+	for _, teamResonanceId := range teamResonanceIdList {
+		t.TeamResonances[uint16(teamResonanceId)] = true
+	}
+}
+
+// GetTeamResonanceList 获取当前生效的元素共鸣
+func (t *DbTeam) GetTeamResonanceList() []uint32 {
+	teamResonanceIdList := make([]uint32, 0, len(t.TeamResonances))
+	for teamResonanceId := range t.TeamResonances {
+		teamResonanceIdList = append(teamResonanceIdList, uint32(teamResonanceId))
+	}
+	sort.Slice(teamResonanceIdList, func(i, j int) bool {
+		return teamResonanceIdList[i] < teamResonanceIdList[j]
+	})
+	return teamResonanceIdList
+}
+
+// CalcTeamResonance 根据队伍中所有角色的元素类型计算生效的元素共鸣
+// 队伍满员时 同元素角色数量满足要求触发对应的元素共鸣 所有角色元素各不相同触发全元素共鸣
+// 同一个共鸣组只保留等级最高的元素共鸣
+func CalcTeamResonance(elementTypeList []int, teamResonanceDataList []*gdconf.TeamResonanceData) []uint32 {
+	teamResonanceIdList := make([]uint32, 0)
+	if len(elementTypeList) < TeamResonanceAvatarNum {
+		return teamResonanceIdList
+	}
+	elementCountMap := make(map[int]int32)
+	for _, elementType := range elementTypeList {
+		elementCountMap[elementType]++
+	}
+	allDifferent := len(elementCountMap) == len(elementTypeList)
+	groupMap := make(map[int32]*gdconf.TeamResonanceData)
+	for _, teamResonanceData := range teamResonanceDataList {
+		if teamResonanceData.AllDifferent {
+			if !allDifferent {
+				continue
+			}
+		} else {
+			if len(teamResonanceData.ElementCountMap) == 0 {
+				continue
+			}
+			match := true
+			for elementType, count := range teamResonanceData.ElementCountMap {
+				if elementCountMap[elementType] < count {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		old, exist := groupMap[teamResonanceData.GroupId]
+		if exist && old.Level >= teamResonanceData.Level {
+			continue
+		}
+		groupMap[teamResonanceData.GroupId] = teamResonanceData
+	}
+	for _, teamResonanceData := range groupMap {
+		teamResonanceIdList = append(teamResonanceIdList, uint32(teamResonanceData.TeamResonanceId))
+	}
+	sort.Slice(teamResonanceIdList, func(i, j int) bool {
+		return teamResonanceIdList[i] < teamResonanceIdList[j]
+	})
+	return teamResonanceIdList
+}
+
+// DiffTeamResonance 对比新旧元素共鸣列表 返回新增和移除的元素共鸣
+func DiffTeamResonance(oldList []uint32, newList []uint32) (addList []uint32, delList []uint32) {
+	oldMap := make(map[uint32]bool)
+	for _, teamResonanceId := range oldList {
+		oldMap[teamResonanceId] = true
+	}
+	newMap := make(map[uint32]bool)
+	for _, teamResonanceId := range newList {
+		newMap[teamResonanceId] = true
+	}
+	addList = make([]uint32, 0)
+	for _, teamResonanceId := range newList {
+		if !oldMap[teamResonanceId] {
+			addList = append(addList, teamResonanceId)
+		}
+	}
+	delList = make([]uint32, 0)
+	for _, teamResonanceId := range oldList {
+		if !newMap[teamResonanceId] {
+			delList = append(delList, teamResonanceId)
+		}
+	}
+	return addList, delList
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"hk4e/common/constant"
+	"hk4e/gdconf"
+)
+
+var testTeamResonanceDataList = []*gdconf.TeamResonanceData{
+	{TeamResonanceId: 10101, GroupId: 101, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_FIRE: 2}},
+	{TeamResonanceId: 10201, GroupId: 102, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_WATER: 2}},
+	{TeamResonanceId: 10301, GroupId: 103, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_WIND: 2}},
+	{TeamResonanceId: 10401, GroupId: 104, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_ELEC: 2}},
+	{TeamResonanceId: 10501, GroupId: 105, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_GRASS: 2}},
+	{TeamResonanceId: 10601, GroupId: 106, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_ICE: 2}},
+	{TeamResonanceId: 10701, GroupId: 107, Level: 1, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_ROCK: 2}},
+	{TeamResonanceId: 10801, GroupId: 108, Level: 1, ElementCountMap: map[int]int32{}, AllDifferent: true},
+}
+
+func TestCalcTeamResonance(t *testing.T) {
+	const (
+		fire  = constant.ELEMENT_TYPE_FIRE
+		water = constant.ELEMENT_TYPE_WATER
+		grass = constant.ELEMENT_TYPE_GRASS
+		elec  = constant.ELEMENT_TYPE_ELEC
+		ice   = constant.ELEMENT_TYPE_ICE
+		wind  = constant.ELEMENT_TYPE_WIND
+		rock  = constant.ELEMENT_TYPE_ROCK
+	)
+	testCaseList := []struct {
+		name            string
+		elementTypeList []int
+		want            []uint32
+	}{
+		{"empty team", []int{}, []uint32{}},
+		{"team not full", []int{fire, fire, water}, []uint32{}},
+		{"team not full all different", []int{fire, water, ice}, []uint32{}},
+		{"two fire", []int{fire, fire, water, ice}, []uint32{10101}},
+		{"two fire two water", []int{fire, water, fire, water}, []uint32{10101, 10201}},
+		{"three rock", []int{rock, rock, rock, wind}, []uint32{10701}},
+		{"four grass", []int{grass, grass, grass, grass}, []uint32{10501}},
+		{"two wind two elec", []int{wind, elec, elec, wind}, []uint32{10301, 10401}},
+		{"four unique", []int{fire, water, ice, rock}, []uint32{10801}},
+		{"four unique other", []int{grass, elec, wind, ice}, []uint32{10801}},
+		{"one pair no unique", []int{ice, ice, wind, rock}, []uint32{10601}},
+	}
+	for _, testCase := range testCaseList {
+		got := CalcTeamResonance(testCase.elementTypeList, testTeamResonanceDataList)
+		if !reflect.DeepEqual(got, testCase.want) {
+			t.Fatalf("%v: got %v, want %v", testCase.name, got, testCase.want)
+		}
+	}
+}
+
+func TestCalcTeamResonanceGroupLevel(t *testing.T) {
+	teamResonanceDataList := append([]*gdconf.TeamResonanceData{
+		{TeamResonanceId: 10102, GroupId: 101, Level: 2, ElementCountMap: map[int]int32{constant.ELEMENT_TYPE_FIRE: 3}},
+	}, testTeamResonanceDataList...)
+	testCaseList := []struct {
+		name            string
+		elementTypeList []int
+		want            []uint32
+	}{
+		{"level 1", []int{constant.ELEMENT_TYPE_FIRE, constant.ELEMENT_TYPE_FIRE, constant.ELEMENT_TYPE_ICE, constant.ELEMENT_TYPE_ROCK}, []uint32{10101}},
+		{"level 2", []int{constant.ELEMENT_TYPE_FIRE, constant.ELEMENT_TYPE_FIRE, constant.ELEMENT_TYPE_FIRE, constant.ELEMENT_TYPE_ROCK}, []uint32{10102}},
+	}
+	for _, testCase := range testCaseList {
+		got := CalcTeamResonance(testCase.elementTypeList, teamResonanceDataList)
+		if !reflect.DeepEqual(got, testCase.want) {
+			t.Fatalf("%v: got %v, want %v", testCase.name, got, testCase.want)
+		}
+	}
+}
+
+func TestDiffTeamResonance(t *testing.T) {
+	addList, delList := DiffTeamResonance([]uint32{10101, 10201}, []uint32{10201, 10801})
+	if !reflect.DeepEqual(addList, []uint32{10801}) || !reflect.DeepEqual(delList, []uint32{10101}) {
+		t.Fatalf("diff error, add: %v, del: %v", addList, delList)
+	}
+	player := new(Player)
+	dbTeam := player.GetDbTeam()
+	dbTeam.SetTeamResonance([]uint32{10801, 10101})
+	if !reflect.DeepEqual(dbTeam.GetTeamResonanceList(), []uint32{10101, 10801}) {
+		t.Fatalf("team resonance list error: %v", dbTeam.GetTeamResonanceList())
+	}
+}
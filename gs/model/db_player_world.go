@@ -5,10 +5,13 @@ import (
 )
 
 type DbWorld struct {
-	SceneMap      map[uint32]*DbScene
-	MapMarkList   []*MapMark
-	GameTime      uint32 // 游戏内提瓦特大陆的时间
-	WidgetSlotMap map[uint8]*Widget
+	SceneMap            map[uint32]*DbScene
+	MapMarkList         []*MapMark
+	GameTime            uint32 // 游戏内提瓦特大陆的时间
+	WidgetSlotMap       map[uint8]*Widget
+	BlossomMap          map[uint32]*Blossom // 地脉之花 key:循环营地刷新id
+	BlossomRewardMap    map[uint32]int64    // 地脉之花领奖冷却 按领奖玩家记录 key:循环营地刷新id value:冷却结束时间 单位毫秒
+	WorldBossRespawnMap map[uint32]int64    // 世界boss复活时间 key:场景组id value:复活时间 单位毫秒
 }
 
 type DbScene struct {
@@ -31,6 +34,15 @@ type Widget struct {
 	MaterialId uint32
 }
 
+type Blossom struct {
+	RefreshId       uint32 // 循环营地刷新id
+	CampId          uint32 // 当前所在的营地点id
+	State           uint8  // 循环玩法进度状态
+	Progress        uint32 // 挑战进度
+	ChestConfigId   uint32 // 奖励宝箱配置id
+	NextRefreshTime int64  // 领奖后的下次刷新时间 单位毫秒 为0则当前可挑战
+}
+
 func (p *Player) GetDbWorld() *DbWorld {
 	if p.DbWorld == nil {
 		p.DbWorld = new(DbWorld)
@@ -45,6 +57,15 @@ func (p *Player) GetDbWorld() *DbWorld {
 	if p.DbWorld.WidgetSlotMap == nil {
 		p.DbWorld.WidgetSlotMap = make(map[uint8]*Widget)
 	}
+	if p.DbWorld.BlossomMap == nil {
+		p.DbWorld.BlossomMap = make(map[uint32]*Blossom)
+	}
+	if p.DbWorld.BlossomRewardMap == nil {
+		p.DbWorld.BlossomRewardMap = make(map[uint32]int64)
+	}
+	if p.DbWorld.WorldBossRespawnMap == nil {
+		p.DbWorld.WorldBossRespawnMap = make(map[uint32]int64)
+	}
 	return p.DbWorld
 }
 
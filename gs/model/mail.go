@@ -5,13 +5,21 @@ import (
 )
 
 type Mail struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	MailId     uint32             `bson:"mail_id"`
-	Title      string             `bson:"title"`
-	Content    string             `bson:"content"`
-	Sender     string             `bson:"sender"`
-	SendTime   uint32             `bson:"send_time"`
-	ExpireTime uint32             `bson:"expire_time"`
-	IsRead     bool               `bson:"is_read"`
-	IsStar     bool               `bson:"is_star"`
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	MailId          uint32             `bson:"mail_id"`
+	Title           string             `bson:"title"`
+	Content         string             `bson:"content"`
+	Sender          string             `bson:"sender"`
+	SendTime        uint32             `bson:"send_time"`
+	ExpireTime      uint32             `bson:"expire_time"`
+	IsRead          bool               `bson:"is_read"`
+	IsStar          bool               `bson:"is_star"`
+	ConfigId        uint32             `bson:"config_id"`
+	ItemList        []*MailItem        `bson:"item_list"`
+	IsAttachmentGot bool               `bson:"is_attachment_got"`
+}
+
+type MailItem struct {
+	ItemId uint32 `bson:"item_id"`
+	Count  uint32 `bson:"count"`
 }
@@ -14,6 +14,7 @@ import (
 	"hk4e/gdconf"
 	"hk4e/multi/handle"
 	"hk4e/node/api"
+	"hk4e/pkg/trace"
 
 	"github.com/flswld/halo/logger"
 )
@@ -44,6 +45,7 @@ func Run(ctx context.Context) error {
 			_, err := discoveryClient.KeepaliveServer(context.TODO(), &api.KeepaliveServerReq{
 				ServerType: api.MULTI,
 				AppId:      APPID,
+				AppVersion: APPVERSION,
 			})
 			if err != nil {
 				logger.Error("keepalive error: %v", err)
@@ -70,6 +72,17 @@ func Run(ctx context.Context) error {
 		defer func() {
 			logger.CloseLogger()
 		}()
+		err = trace.InitTracer(&trace.Config{
+			ServiceName: "multi_" + APPID,
+			Exporter:    config.GetConfig().Trace.Exporter,
+			FilePath:    config.GetConfig().Trace.FilePath,
+			SampleUid:   config.GetConfig().Trace.SampleUid,
+			SampleRate:  config.GetConfig().Trace.SampleRate,
+		})
+		if err != nil {
+			return err
+		}
+		defer trace.CloseTracer()
 	}
 	logger.Warn("multi start, appid: %v", APPID)
 	defer func() {
@@ -1,8 +1,11 @@
 package handle
 
 import (
+	"sync/atomic"
+
 	"hk4e/common/mq"
 	"hk4e/node/api"
+	"hk4e/pkg/trace"
 	"hk4e/protocol/cmd"
 
 	"github.com/flswld/halo/logger"
@@ -14,11 +17,15 @@ type Handle struct {
 	messageQueue   *mq.MessageQueue
 	playerAcCtxMap map[uint32]*AnticheatContext
 	worldStatic    *WorldStatic
+	curSpan        atomic.Pointer[trace.Span] // 当前正在处理的消息的链路
 }
 
 func NewHandle(messageQueue *mq.MessageQueue) (r *Handle) {
 	r = new(Handle)
 	r.messageQueue = messageQueue
+	r.messageQueue.SetTraceContextFunc(func() trace.SpanContext {
+		return r.curSpan.Load().Context()
+	})
 	r.playerAcCtxMap = make(map[uint32]*AnticheatContext)
 	r.worldStatic = NewWorldStatic()
 	r.worldStatic.InitTerrain()
@@ -39,6 +46,13 @@ func (h *Handle) run() {
 				continue
 			}
 			gameMsg := netMsg.GameMsg
+			var span *trace.Span = nil
+			if parent := netMsg.GetTrace(); parent.IsValid() {
+				span = trace.StartSpan("multi.route", trace.SpanKindServer, parent)
+				span.SetAttr("uid", gameMsg.UserId)
+				span.SetAttr("cmd_id", gameMsg.CmdId)
+				h.curSpan.Store(span)
+			}
 			switch gameMsg.CmdId {
 			case cmd.CombatInvocationsNotify:
 				h.CombatInvocationsNotify(gameMsg.UserId, netMsg.OriginServerAppId, gameMsg.PayloadMessage)
@@ -49,6 +63,8 @@ func (h *Handle) run() {
 			case cmd.ObstacleModifyNotify:
 				h.ObstacleModifyNotify(gameMsg.UserId, netMsg.OriginServerAppId, gameMsg.PayloadMessage)
 			}
+			h.curSpan.Store(nil)
+			span.End()
 		case mq.MsgTypeServer:
 			serverMsg := netMsg.ServerMsg
 			switch netMsg.EventId {
@@ -78,7 +78,7 @@ func NewDao() (*Dao, error) {
 			logger.Error("%v", err)
 			return nil, err
 		}
-		tableList := []any{new(RegionGorm)}
+		tableList := []any{new(RegionGorm), new(NodeLeaderGorm)}
 		for _, table := range tableList {
 			err := r.gormDb.AutoMigrate(table)
 			if err != nil {
@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NodeLeaderGorm struct {
+	ID         int32  `gorm:"column:id;type:bigint(20);primaryKey"`
+	NodeId     string `gorm:"column:node_id;type:varchar(64)"`
+	ExpireTime int64  `gorm:"column:expire_time;type:bigint(20)"`
+}
+
+func (n NodeLeaderGorm) TableName() string {
+	return "node_leader"
+}
+
+func (d *Dao) AcquireNodeLeaderGorm(nodeId string, now int64, leaseTime int64) (string, bool, error) {
+	leaderId := ""
+	created := false
+	err := d.gormDb.Transaction(func(tx *gorm.DB) error {
+		nodeLeader := new(NodeLeaderGorm)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", NodeLeaderId).First(nodeLeader).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			leaderId = nodeId
+			created = true
+			return tx.Create(&NodeLeaderGorm{
+				ID:         NodeLeaderId,
+				NodeId:     nodeId,
+				ExpireTime: now + leaseTime,
+			}).Error
+		}
+		if nodeLeader.NodeId != nodeId && nodeLeader.ExpireTime >= now {
+			leaderId = nodeLeader.NodeId
+			return nil
+		}
+		leaderId = nodeId
+		return tx.Model(nodeLeader).Updates(map[string]any{
+			"node_id":     nodeId,
+			"expire_time": now + leaseTime,
+		}).Error
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return leaderId, created, nil
+}
+
+func (d *Dao) ReleaseNodeLeaderGorm(nodeId string) error {
+	return d.gormDb.Model(&NodeLeaderGorm{}).
+		Where("id = ? AND node_id = ?", NodeLeaderId, nodeId).
+		Update("expire_time", 0).Error
+}
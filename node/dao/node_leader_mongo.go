@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const NodeLeaderId = 1
+
+type NodeLeader struct {
+	ID         int32  `bson:"_id"`
+	NodeId     string `bson:"node_id"`     // 主节点id
+	ExpireTime int64  `bson:"expire_time"` // 租约过期时间点 单位毫秒
+}
+
+// AcquireNodeLeader 尝试获取或续期主节点租约 返回当前的主节点id以及租约记录是否为本次新建
+func (d *Dao) AcquireNodeLeader(nodeId string, now int64, leaseTime int64) (string, bool, error) {
+	if d.mongo == nil {
+		return d.AcquireNodeLeaderGorm(nodeId, now, leaseTime)
+	}
+	db := d.mongoDb.Collection("node_leader")
+	result := db.FindOneAndUpdate(
+		context.TODO(),
+		bson.D{
+			{"_id", NodeLeaderId},
+			{"$or", bson.A{
+				bson.D{{"node_id", nodeId}},
+				bson.D{{"expire_time", bson.D{{"$lt", now}}}},
+			}},
+		},
+		bson.D{{"$set", bson.D{
+			{"node_id", nodeId},
+			{"expire_time", now + leaseTime},
+		}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	)
+	nodeLeader := new(NodeLeader)
+	err := result.Decode(nodeLeader)
+	if err == nil {
+		return nodeId, false, nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// 没有更新前的记录 说明是本次upsert新建的
+		return nodeId, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return "", false, err
+	}
+	// 租约被其他节点持有 条件不满足时upsert会与已有记录的主键冲突
+	err = db.FindOne(context.TODO(), bson.D{{"_id", NodeLeaderId}}).Decode(nodeLeader)
+	if err != nil {
+		return "", false, err
+	}
+	return nodeLeader.NodeId, false, nil
+}
+
+// ReleaseNodeLeader 主动释放主节点租约 其他节点可以立即接管
+func (d *Dao) ReleaseNodeLeader(nodeId string) error {
+	if d.mongo == nil {
+		return d.ReleaseNodeLeaderGorm(nodeId)
+	}
+	db := d.mongoDb.Collection("node_leader")
+	_, err := db.UpdateOne(
+		context.TODO(),
+		bson.D{{"_id", NodeLeaderId}, {"node_id", nodeId}},
+		bson.D{{"$set", bson.D{{"expire_time", 0}}}},
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/vmihailenco/msgpack/v5"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RegionGorm struct {
@@ -62,3 +63,54 @@ func (d *Dao) QueryRegionGorm() (*Region, error) {
 	}
 	return region, nil
 }
+
+// updateRegionGorm 在事务中读取并修改区服信息 避免并发写入互相覆盖
+func (d *Dao) updateRegionGorm(fn func(region *Region)) error {
+	return d.gormDb.Transaction(func(tx *gorm.DB) error {
+		regionGorm := new(RegionGorm)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(regionGorm).Error
+		if err != nil {
+			return err
+		}
+		region := new(Region)
+		err = msgpack.Unmarshal(regionGorm.Data, region)
+		if err != nil {
+			return err
+		}
+		fn(region)
+		data, err := msgpack.Marshal(region)
+		if err != nil {
+			return err
+		}
+		return tx.Updates(&RegionGorm{
+			ID:   regionGorm.ID,
+			Data: data,
+		}).Error
+	})
+}
+
+func (d *Dao) AllocUidBlockGorm(blockSize uint32) (uint32, error) {
+	var lastUid uint32 = 0
+	err := d.updateRegionGorm(func(region *Region) {
+		lastUid = region.NextUid
+		region.NextUid += blockSize
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lastUid, nil
+}
+
+func (d *Dao) UpdateRegionStopServerGorm(stopServer bool, startTime uint32, endTime uint32) error {
+	return d.updateRegionGorm(func(region *Region) {
+		region.StopServer = stopServer
+		region.StopServerStartTime = startTime
+		region.StopServerEndTime = endTime
+	})
+}
+
+func (d *Dao) UpdateRegionIpAddrWhiteListGorm(ipAddrWhiteList []string) error {
+	return d.updateRegionGorm(func(region *Region) {
+		region.IpAddrWhiteList = ipAddrWhiteList
+	})
+}
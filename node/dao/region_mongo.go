@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Region struct {
@@ -67,3 +68,59 @@ func (d *Dao) QueryRegion() (*Region, error) {
 	}
 	return region, nil
 }
+
+// AllocUidBlock 预留一段uid 返回预留前已分配的最大uid 预留的区间为(lastUid, lastUid+blockSize]
+func (d *Dao) AllocUidBlock(blockSize uint32) (uint32, error) {
+	if d.mongo == nil {
+		return d.AllocUidBlockGorm(blockSize)
+	}
+	db := d.mongoDb.Collection("region")
+	result := db.FindOneAndUpdate(
+		context.TODO(),
+		bson.D{},
+		bson.D{{"$inc", bson.D{{"next_uid", int64(blockSize)}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	)
+	region := new(Region)
+	err := result.Decode(region)
+	if err != nil {
+		return 0, err
+	}
+	return region.NextUid, nil
+}
+
+func (d *Dao) UpdateRegionStopServer(stopServer bool, startTime uint32, endTime uint32) error {
+	if d.mongo == nil {
+		return d.UpdateRegionStopServerGorm(stopServer, startTime, endTime)
+	}
+	db := d.mongoDb.Collection("region")
+	_, err := db.UpdateMany(
+		context.TODO(),
+		bson.D{},
+		bson.D{{"$set", bson.D{
+			{"stop_server", stopServer},
+			{"stop_server_start_time", startTime},
+			{"stop_server_end_time", endTime},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *Dao) UpdateRegionIpAddrWhiteList(ipAddrWhiteList []string) error {
+	if d.mongo == nil {
+		return d.UpdateRegionIpAddrWhiteListGorm(ipAddrWhiteList)
+	}
+	db := d.mongoDb.Collection("region")
+	_, err := db.UpdateMany(
+		context.TODO(),
+		bson.D{},
+		bson.D{{"$set", bson.D{{"ip_addr_white_list", ipAddrWhiteList}}}},
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
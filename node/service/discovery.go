@@ -21,6 +21,8 @@ import (
 const (
 	MaxGsId  = 1000
 	UidBegin = 100000000
+	// NodeLeaderRebuildTime 新主节点等待服务器心跳重建实例的时间 需要大于服务器心跳间隔
+	NodeLeaderRebuildTime = time.Second * 20
 )
 
 var _ api.DiscoveryNATSRPCServer = (*DiscoveryService)(nil)
@@ -47,17 +49,21 @@ type StopServerInfo struct {
 	startTime       uint32              // 停服开始时间
 	endTime         uint32              // 停服结束时间
 	ipAddrWhiteList map[string]struct{} // ip地址白名单
+	lock            sync.RWMutex        // 锁
 }
 
 type DiscoveryService struct {
 	db                *dao.Dao             // 数据库访问对象
 	regionEc2b        *random.Ec2b         // 区服密钥信息
-	nextUid           uint32               // 自增uid
+	uidAllocator      *UidAllocator        // 自增uid分配器
 	serverInstanceMap map[string]*sync.Map // 全部服务器实例集合 key:服务器类型 value:服务器实例集合 -> key:appid value:服务器实例
 	serverAppIdMap    *sync.Map            // 服务器appid集合 key:appid value:是否存在
 	globalGsOnlineMap *sync.Map            // 全服玩家在线集合 key:uid value:gsAppid
 	stopServerInfo    *StopServerInfo      // 停服信息
 	messageQueue      *mq.MessageQueue     // 消息队列实例
+	isLeader          atomic.Bool          // 本节点是否为主节点
+	rebuildTime       time.Duration        // 成为主节点后重建实例的时间
+	rebuildEndTime    atomic.Int64         // 重建实例结束时间点 单位毫秒
 }
 
 func NewDiscoveryService(db *dao.Dao, messageQueue *mq.MessageQueue) (*DiscoveryService, error) {
@@ -86,8 +92,12 @@ func NewDiscoveryService(db *dao.Dao, messageQueue *mq.MessageQueue) (*Discovery
 		}
 		err := r.db.InsertRegion(region)
 		if err != nil {
-			logger.Error("save region to db error: %v", err)
-			return nil, err
+			// 多个节点同时启动时 区服信息可能已经被其他节点创建
+			region, err = r.db.QueryRegion()
+			if err != nil || region == nil {
+				logger.Error("save region to db error: %v", err)
+				return nil, errors.New("init region error")
+			}
 		}
 	}
 	r.regionEc2b, err = random.LoadEc2bKey(region.Ec2bData)
@@ -96,7 +106,7 @@ func NewDiscoveryService(db *dao.Dao, messageQueue *mq.MessageQueue) (*Discovery
 		return nil, err
 	}
 	logger.Info("region ec2b load ok, seed: %v", r.regionEc2b.Seed())
-	r.nextUid = region.NextUid
+	r.uidAllocator = NewUidAllocator(r.db, GetUidLeaseSize())
 	r.stopServerInfo = &StopServerInfo{
 		stopServer:      region.StopServer,
 		startTime:       region.StopServerStartTime,
@@ -115,6 +125,7 @@ func NewDiscoveryService(db *dao.Dao, messageQueue *mq.MessageQueue) (*Discovery
 	r.serverAppIdMap = new(sync.Map)
 	r.globalGsOnlineMap = new(sync.Map)
 	r.messageQueue = messageQueue
+	r.rebuildTime = NodeLeaderRebuildTime
 	go r.removeDeadServer()
 	go r.broadcastReceiver()
 	go r.serverState()
@@ -122,22 +133,84 @@ func NewDiscoveryService(db *dao.Dao, messageQueue *mq.MessageQueue) (*Discovery
 }
 
 func (s *DiscoveryService) close() {
-	ipAddrWhiteList := make([]string, 0)
-	for ipAddr := range s.stopServerInfo.ipAddrWhiteList {
-		ipAddrWhiteList = append(ipAddrWhiteList, ipAddr)
+	// 区服信息在修改时已经立即写入数据库 这里只需要放弃剩余的预留uid
+	logger.Info("discard remain uid lease, count: %v", s.uidAllocator.Remain())
+}
+
+// onLeaderChange 本节点主从状态变化
+// 服务器实例只由主节点维护 成为主节点时从数据库重新加载区服信息 接管已有租约时等待各服务器心跳重建实例
+func (s *DiscoveryService) onLeaderChange(isLeader bool, takeover bool) {
+	for _, instMap := range s.serverInstanceMap {
+		instMap.Range(func(appid, inst any) bool {
+			instMap.Delete(appid)
+			return true
+		})
 	}
-	region := &dao.Region{
-		Ec2bData:            s.regionEc2b.Bytes(),
-		NextUid:             s.nextUid,
-		StopServer:          s.stopServerInfo.stopServer,
-		StopServerStartTime: s.stopServerInfo.startTime,
-		StopServerEndTime:   s.stopServerInfo.endTime,
-		IpAddrWhiteList:     ipAddrWhiteList,
+	s.serverAppIdMap.Range(func(appid, value any) bool {
+		s.serverAppIdMap.Delete(appid)
+		return true
+	})
+	if isLeader {
+		region, err := s.db.QueryRegion()
+		if err != nil || region == nil {
+			logger.Error("reload region from db error: %v", err)
+		} else {
+			s.stopServerInfo.lock.Lock()
+			s.stopServerInfo.stopServer = region.StopServer
+			s.stopServerInfo.startTime = region.StopServerStartTime
+			s.stopServerInfo.endTime = region.StopServerEndTime
+			s.stopServerInfo.ipAddrWhiteList = make(map[string]struct{})
+			for _, ipAddr := range region.IpAddrWhiteList {
+				s.stopServerInfo.ipAddrWhiteList[ipAddr] = struct{}{}
+			}
+			s.stopServerInfo.lock.Unlock()
+		}
+		if takeover && !config.GetConfig().Hk4e.StandaloneModeEnable {
+			s.rebuildEndTime.Store(time.Now().Add(s.rebuildTime).UnixMilli())
+			logger.Warn("become node leader, wait server keepalive to rebuild instance")
+		} else {
+			s.rebuildEndTime.Store(0)
+			logger.Warn("become node leader")
+		}
+	} else {
+		logger.Warn("become node follower")
 	}
-	err := s.db.UpdateRegion(region)
-	if err != nil {
-		logger.Error("save region to db error: %v", err)
+	s.isLeader.Store(isLeader)
+	s.updateMetrics()
+}
+
+// isRebuilding 是否处于成为主节点后的实例重建期间
+func (s *DiscoveryService) isRebuilding() bool {
+	return time.Now().UnixMilli() < s.rebuildEndTime.Load()
+}
+
+// restoreServerInstance 主从切换后根据服务器心跳恢复服务器实例
+func (s *DiscoveryService) restoreServerInstance(instMap *sync.Map, req *api.KeepaliveServerReq) *ServerInstance {
+	inst := &ServerInstance{
+		serverType:      req.ServerType,
+		appId:           req.AppId,
+		appVersion:      req.AppVersion,
+		gameVersionList: req.GameVersionList,
+		lastAliveTime:   time.Now().Unix(),
+		gsId:            req.GsId,
+		loadCount:       req.LoadCount,
+		dispatchCancel:  false,
+	}
+	if req.GateServerAddr != nil {
+		inst.gateServerKcpAddr = req.GateServerAddr.KcpAddr
+		inst.gateServerKcpPort = req.GateServerAddr.KcpPort
+		inst.gateServerMqAddr = req.GateServerAddr.MqAddr
+		inst.gateServerMqPort = req.GateServerAddr.MqPort
+	}
+	s.serverAppIdMap.Store(req.AppId, true)
+	value, _ := instMap.LoadOrStore(req.AppId, inst)
+	if req.ServerType == api.GS {
+		for _, uid := range req.OnlineUidList {
+			s.globalGsOnlineMap.Store(uid, req.AppId)
+		}
 	}
+	logger.Warn("restore server instance from keepalive, server type: %v, appid: %v, gsId: %v", req.ServerType, req.AppId, req.GsId)
+	return value.(*ServerInstance)
 }
 
 func (s *DiscoveryService) broadcastReceiver() {
@@ -168,6 +241,10 @@ func (s *DiscoveryService) RegisterServer(ctx context.Context, req *api.Register
 	if !exist {
 		return nil, errors.New("server type not exist")
 	}
+	if req.ServerType == api.GS && s.isRebuilding() {
+		// 重建期间无法确认已被使用的gs id
+		return nil, errors.New("node leader rebuilding")
+	}
 	var appId string
 	for {
 		appId = strings.ToLower(random.GetRandomStr(8))
@@ -195,6 +272,7 @@ func (s *DiscoveryService) RegisterServer(ctx context.Context, req *api.Register
 	}
 	instMap.Store(appId, inst)
 	logger.Info("new server appid is: %v", appId)
+	metricsRegisterServer.Inc(req.ServerType)
 	rsp := &api.RegisterServerRsp{
 		AppId: appId,
 	}
@@ -250,14 +328,20 @@ func (s *DiscoveryService) KeepaliveServer(ctx context.Context, req *api.Keepali
 	if !exist {
 		return nil, errors.New("server type not exist")
 	}
+	var serverInstance *ServerInstance = nil
 	inst, exist := instMap.Load(req.AppId)
-	if !exist {
-		logger.Error("recv not exist server keepalive, server type: %v, appid: %v", req.ServerType, req.AppId)
-		return nil, errors.New("server not exist")
+	if exist {
+		serverInstance = inst.(*ServerInstance)
+	} else {
+		if req.AppVersion == "" {
+			logger.Error("recv not exist server keepalive, server type: %v, appid: %v", req.ServerType, req.AppId)
+			return nil, errors.New("server not exist")
+		}
+		serverInstance = s.restoreServerInstance(instMap, req)
 	}
-	serverInstance := inst.(*ServerInstance)
 	serverInstance.lastAliveTime = time.Now().Unix()
 	serverInstance.loadCount = req.LoadCount
+	metricsKeepalive.Inc(req.ServerType)
 	logger.Debug("server instance: %+v", serverInstance)
 	return &api.NullMsg{}, nil
 }
@@ -397,6 +481,8 @@ func (s *DiscoveryService) GetGlobalGsOnlineMap(ctx context.Context, req *api.Nu
 
 // GetStopServerInfo 获取停服维护信息
 func (s *DiscoveryService) GetStopServerInfo(ctx context.Context, req *api.NullMsg) (*api.StopServerInfo, error) {
+	s.stopServerInfo.lock.RLock()
+	defer s.stopServerInfo.lock.RUnlock()
 	return &api.StopServerInfo{
 		StopServer: s.stopServerInfo.stopServer,
 		StartTime:  s.stopServerInfo.startTime,
@@ -406,6 +492,14 @@ func (s *DiscoveryService) GetStopServerInfo(ctx context.Context, req *api.NullM
 
 // SetStopServerInfo 修改停服维护信息
 func (s *DiscoveryService) SetStopServerInfo(ctx context.Context, req *api.StopServerInfo) (*api.NullMsg, error) {
+	s.stopServerInfo.lock.Lock()
+	// 先写入数据库 成功后再修改内存 保证进程崩溃重启后状态一致
+	err := s.db.UpdateRegionStopServer(req.StopServer, req.StartTime, req.EndTime)
+	if err != nil {
+		s.stopServerInfo.lock.Unlock()
+		logger.Error("save stop server info to db error: %v", err)
+		return nil, err
+	}
 	shutdown := false
 	if s.stopServerInfo.stopServer == false && req.StopServer == true {
 		shutdown = true
@@ -413,6 +507,7 @@ func (s *DiscoveryService) SetStopServerInfo(ctx context.Context, req *api.StopS
 	s.stopServerInfo.stopServer = req.StopServer
 	s.stopServerInfo.startTime = req.StartTime
 	s.stopServerInfo.endTime = req.EndTime
+	s.stopServerInfo.lock.Unlock()
 	if shutdown {
 		s.messageQueue.SendToAll(&mq.NetMsg{
 			MsgType: mq.MsgTypeServer,
@@ -424,6 +519,8 @@ func (s *DiscoveryService) SetStopServerInfo(ctx context.Context, req *api.StopS
 
 // GetWhiteList 获取停服维护白名单
 func (s *DiscoveryService) GetWhiteList(ctx context.Context, req *api.NullMsg) (*api.GetWhiteListRsp, error) {
+	s.stopServerInfo.lock.RLock()
+	defer s.stopServerInfo.lock.RUnlock()
 	ipAddrList := make([]string, 0)
 	for ipAddr := range s.stopServerInfo.ipAddrWhiteList {
 		ipAddrList = append(ipAddrList, ipAddr)
@@ -433,6 +530,26 @@ func (s *DiscoveryService) GetWhiteList(ctx context.Context, req *api.NullMsg) (
 
 // SetWhiteList 修改停服维护白名单
 func (s *DiscoveryService) SetWhiteList(ctx context.Context, req *api.SetWhiteListReq) (*api.NullMsg, error) {
+	s.stopServerInfo.lock.Lock()
+	defer s.stopServerInfo.lock.Unlock()
+	ipAddrWhiteList := make([]string, 0)
+	for ipAddr := range s.stopServerInfo.ipAddrWhiteList {
+		if !req.IsAdd && ipAddr == req.IpAddr {
+			continue
+		}
+		ipAddrWhiteList = append(ipAddrWhiteList, ipAddr)
+	}
+	if req.IsAdd {
+		if _, exist := s.stopServerInfo.ipAddrWhiteList[req.IpAddr]; !exist {
+			ipAddrWhiteList = append(ipAddrWhiteList, req.IpAddr)
+		}
+	}
+	// 先写入数据库 成功后再修改内存
+	err := s.db.UpdateRegionIpAddrWhiteList(ipAddrWhiteList)
+	if err != nil {
+		logger.Error("save ip addr white list to db error: %v", err)
+		return nil, err
+	}
 	if req.IsAdd {
 		s.stopServerInfo.ipAddrWhiteList[req.IpAddr] = struct{}{}
 	} else {
@@ -443,8 +560,12 @@ func (s *DiscoveryService) SetWhiteList(ctx context.Context, req *api.SetWhiteLi
 
 // GetNextUid 获取下一个自增uid
 func (s *DiscoveryService) GetNextUid(ctx context.Context, req *api.NullMsg) (*api.GetNextUidRsp, error) {
+	uid, err := s.uidAllocator.Next()
+	if err != nil {
+		return nil, err
+	}
 	return &api.GetNextUidRsp{
-		Uid: atomic.AddUint32(&s.nextUid, 1),
+		Uid: uid,
 	}, nil
 }
 
@@ -539,6 +660,18 @@ func (s *DiscoveryService) removeDeadServer() {
 				return true
 			})
 		}
+		if s.isLeader.Load() && !s.isRebuilding() {
+			// 清理重建期间未恢复的gs上残留的在线玩家
+			gsInstMap := s.serverInstanceMap[api.GS]
+			s.globalGsOnlineMap.Range(func(uid, gsAppid any) bool {
+				_, exist := gsInstMap.Load(gsAppid)
+				if !exist {
+					s.globalGsOnlineMap.Delete(uid)
+				}
+				return true
+			})
+		}
+		s.updateMetrics()
 	}
 }
 
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"hk4e/node/api"
+
+	"github.com/byebyebruce/natsrpc"
+	"github.com/nats-io/nats.go"
+)
+
+var _ api.DiscoveryNATSRPCServer = (*DiscoveryProxy)(nil)
+
+// DiscoveryProxy 注册发现服务代理 主节点直接处理请求 从节点转发给主节点处理
+type DiscoveryProxy struct {
+	local     *DiscoveryService // 本节点的注册发现服务
+	elector   *NodeElector      // 主节点选举
+	enc       *nats.EncodedConn // 转发请求的连接
+	clientMap *sync.Map         // 转发请求的客户端集合 key:主节点id value:客户端
+	forward   bool              // 是否转发 按节点id定向的请求不再转发 避免主从切换期间循环转发
+}
+
+func NewDiscoveryProxy(local *DiscoveryService, elector *NodeElector, enc *nats.EncodedConn, forward bool) *DiscoveryProxy {
+	r := new(DiscoveryProxy)
+	r.local = local
+	r.elector = elector
+	r.enc = enc
+	r.clientMap = new(sync.Map)
+	r.forward = forward
+	return r
+}
+
+// getLeaderClient 获取定向请求主节点的客户端
+func (p *DiscoveryProxy) getLeaderClient(leaderId string) (api.DiscoveryNATSRPCClient, error) {
+	value, exist := p.clientMap.Load(leaderId)
+	if exist {
+		return value.(api.DiscoveryNATSRPCClient), nil
+	}
+	client, err := api.NewDiscoveryNATSRPCClient(p.enc, natsrpc.WithClientID(leaderId))
+	if err != nil {
+		return nil, err
+	}
+	value, _ = p.clientMap.LoadOrStore(leaderId, client)
+	return value.(api.DiscoveryNATSRPCClient), nil
+}
+
+func proxyCall[Req any, Rsp any](
+	p *DiscoveryProxy, ctx context.Context, req Req,
+	local func(context.Context, Req) (Rsp, error),
+	remote func(api.DiscoveryNATSRPCClient, context.Context, Req, ...natsrpc.CallOption) (Rsp, error),
+) (Rsp, error) {
+	isLeader, leaderId := p.elector.GetLeader()
+	if isLeader {
+		return local(ctx, req)
+	}
+	var rsp Rsp
+	if !p.forward || leaderId == "" {
+		return rsp, errors.New("node leader not found")
+	}
+	client, err := p.getLeaderClient(leaderId)
+	if err != nil {
+		return rsp, err
+	}
+	return remote(client, ctx, req)
+}
+
+func (p *DiscoveryProxy) RegisterServer(ctx context.Context, req *api.RegisterServerReq) (*api.RegisterServerRsp, error) {
+	return proxyCall(p, ctx, req, p.local.RegisterServer, api.DiscoveryNATSRPCClient.RegisterServer)
+}
+
+func (p *DiscoveryProxy) CancelServer(ctx context.Context, req *api.CancelServerReq) (*api.NullMsg, error) {
+	return proxyCall(p, ctx, req, p.local.CancelServer, api.DiscoveryNATSRPCClient.CancelServer)
+}
+
+func (p *DiscoveryProxy) KeepaliveServer(ctx context.Context, req *api.KeepaliveServerReq) (*api.NullMsg, error) {
+	return proxyCall(p, ctx, req, p.local.KeepaliveServer, api.DiscoveryNATSRPCClient.KeepaliveServer)
+}
+
+func (p *DiscoveryProxy) GetServerAppId(ctx context.Context, req *api.GetServerAppIdReq) (*api.GetServerAppIdRsp, error) {
+	return proxyCall(p, ctx, req, p.local.GetServerAppId, api.DiscoveryNATSRPCClient.GetServerAppId)
+}
+
+func (p *DiscoveryProxy) GetRegionEc2B(ctx context.Context, req *api.NullMsg) (*api.RegionEc2B, error) {
+	// 区服密钥不会变化 所有节点都可以直接处理
+	return p.local.GetRegionEc2B(ctx, req)
+}
+
+func (p *DiscoveryProxy) GetGateServerAddr(ctx context.Context, req *api.GetGateServerAddrReq) (*api.GateServerAddr, error) {
+	return proxyCall(p, ctx, req, p.local.GetGateServerAddr, api.DiscoveryNATSRPCClient.GetGateServerAddr)
+}
+
+func (p *DiscoveryProxy) GetAllGateServerInfoList(ctx context.Context, req *api.NullMsg) (*api.GateServerInfoList, error) {
+	return proxyCall(p, ctx, req, p.local.GetAllGateServerInfoList, api.DiscoveryNATSRPCClient.GetAllGateServerInfoList)
+}
+
+func (p *DiscoveryProxy) GetMainGameServerAppId(ctx context.Context, req *api.NullMsg) (*api.GetMainGameServerAppIdRsp, error) {
+	return proxyCall(p, ctx, req, p.local.GetMainGameServerAppId, api.DiscoveryNATSRPCClient.GetMainGameServerAppId)
+}
+
+func (p *DiscoveryProxy) GetGlobalGsOnlineMap(ctx context.Context, req *api.NullMsg) (*api.GlobalGsOnlineMap, error) {
+	return proxyCall(p, ctx, req, p.local.GetGlobalGsOnlineMap, api.DiscoveryNATSRPCClient.GetGlobalGsOnlineMap)
+}
+
+func (p *DiscoveryProxy) GetStopServerInfo(ctx context.Context, req *api.NullMsg) (*api.StopServerInfo, error) {
+	return proxyCall(p, ctx, req, p.local.GetStopServerInfo, api.DiscoveryNATSRPCClient.GetStopServerInfo)
+}
+
+func (p *DiscoveryProxy) SetStopServerInfo(ctx context.Context, req *api.StopServerInfo) (*api.NullMsg, error) {
+	return proxyCall(p, ctx, req, p.local.SetStopServerInfo, api.DiscoveryNATSRPCClient.SetStopServerInfo)
+}
+
+func (p *DiscoveryProxy) GetWhiteList(ctx context.Context, req *api.NullMsg) (*api.GetWhiteListRsp, error) {
+	return proxyCall(p, ctx, req, p.local.GetWhiteList, api.DiscoveryNATSRPCClient.GetWhiteList)
+}
+
+func (p *DiscoveryProxy) SetWhiteList(ctx context.Context, req *api.SetWhiteListReq) (*api.NullMsg, error) {
+	return proxyCall(p, ctx, req, p.local.SetWhiteList, api.DiscoveryNATSRPCClient.SetWhiteList)
+}
+
+func (p *DiscoveryProxy) GetNextUid(ctx context.Context, req *api.NullMsg) (*api.GetNextUidRsp, error) {
+	return proxyCall(p, ctx, req, p.local.GetNextUid, api.DiscoveryNATSRPCClient.GetNextUid)
+}
+
+func (p *DiscoveryProxy) ServerDispatchCancel(ctx context.Context, req *api.ServerDispatchCancelReq) (*api.NullMsg, error) {
+	return proxyCall(p, ctx, req, p.local.ServerDispatchCancel, api.DiscoveryNATSRPCClient.ServerDispatchCancel)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"hk4e/pkg/metrics"
+)
+
+// 节点服务器监控指标
+
+var (
+	metricsRegisterServer = metrics.NewCounter("node_register_server_total", "node server register count", "server_type")
+	metricsKeepalive      = metrics.NewCounter("node_keepalive_total", "node server keepalive count", "server_type")
+	metricsServerInstNum  = metrics.NewGauge("node_server_instance_num", "node alive server instance num", "server_type")
+	metricsOnlinePlayer   = metrics.NewGauge("node_global_online_player_num", "node global gs online player num")
+	metricsIsLeader       = metrics.NewGauge("node_is_leader", "node is leader 1 or follower 0")
+	metricsRebuilding     = metrics.NewGauge("node_leader_rebuilding", "node leader is rebuilding server instance from keepalive")
+)
+
+func (s *DiscoveryService) updateMetrics() {
+	for serverType, instMap := range s.serverInstanceMap {
+		metricsServerInstNum.Set(float64(s.getServerInstanceMapLen(instMap)), serverType)
+	}
+	onlinePlayerNum := 0
+	s.globalGsOnlineMap.Range(func(key, value any) bool {
+		onlinePlayerNum++
+		return true
+	})
+	metricsOnlinePlayer.Set(float64(onlinePlayerNum))
+	isLeader := 0.0
+	if s.isLeader.Load() {
+		isLeader = 1.0
+	}
+	metricsIsLeader.Set(isLeader)
+	rebuilding := 0.0
+	if s.isRebuilding() {
+		rebuilding = 1.0
+	}
+	metricsRebuilding.Set(rebuilding)
+}
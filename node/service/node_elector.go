@@ -0,0 +1,121 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/node/dao"
+
+	"github.com/flswld/halo/logger"
+)
+
+const (
+	NodeLeaderLeaseTimeDefault = 10
+)
+
+func GetNodeLeaderLeaseTime() time.Duration {
+	leaseTime := config.GetConfig().Hk4e.NodeLeaderLeaseTime
+	if leaseTime <= 0 {
+		leaseTime = NodeLeaderLeaseTimeDefault
+	}
+	return time.Second * time.Duration(leaseTime)
+}
+
+// NodeElector 节点服务器主节点选举
+// 租约存储在数据库中 主节点定时续期 租约过期后其他节点可以抢占
+type NodeElector struct {
+	db         *dao.Dao                           // 数据库访问对象
+	nodeId     string                             // 本节点id
+	leaseTime  time.Duration                      // 租约时间
+	onChange   func(isLeader bool, takeover bool) // 本节点主从状态变化回调 takeover表示从已有的租约记录接管
+	lock       sync.RWMutex                       // 锁
+	isLeader   bool                               // 本节点是否为主节点
+	leaderId   string                             // 当前主节点id 为空表示未知
+	closeChan  chan struct{}                      // 关闭信号
+	closeOnce  sync.Once                          // 只关闭一次
+	finishChan chan struct{}                      // 选举协程退出信号
+}
+
+func NewNodeElector(db *dao.Dao, nodeId string, leaseTime time.Duration, onChange func(isLeader bool, takeover bool)) *NodeElector {
+	r := new(NodeElector)
+	r.db = db
+	r.nodeId = nodeId
+	r.leaseTime = leaseTime
+	r.onChange = onChange
+	r.closeChan = make(chan struct{})
+	r.finishChan = make(chan struct{})
+	return r
+}
+
+// GetLeader 获取本节点是否为主节点以及当前主节点id
+func (e *NodeElector) GetLeader() (bool, string) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.isLeader, e.leaderId
+}
+
+func (e *NodeElector) run() {
+	defer close(e.finishChan)
+	// 续期间隔为租约时间的三分之一 保证主节点在租约过期前至少有两次续期机会
+	ticker := time.NewTicker(e.leaseTime / 3)
+	defer ticker.Stop()
+	e.campaign()
+	for {
+		select {
+		case <-e.closeChan:
+			return
+		case <-ticker.C:
+			e.campaign()
+		}
+	}
+}
+
+func (e *NodeElector) campaign() {
+	leaderId, created, err := e.db.AcquireNodeLeader(e.nodeId, time.Now().UnixMilli(), e.leaseTime.Milliseconds())
+	if err != nil {
+		// 无法确认租约是否仍然有效 主节点立即降级 避免出现两个主节点
+		logger.Error("acquire node leader error: %v, nodeId: %v", err, e.nodeId)
+		leaderId = ""
+	}
+	e.setLeader(leaderId, !created)
+}
+
+func (e *NodeElector) setLeader(leaderId string, takeover bool) {
+	isLeader := leaderId == e.nodeId
+	e.lock.RLock()
+	oldIsLeader := e.isLeader
+	oldLeaderId := e.leaderId
+	e.lock.RUnlock()
+	if oldLeaderId != leaderId {
+		logger.Warn("node leader change, leaderId: %v -> %v, nodeId: %v", oldLeaderId, leaderId, e.nodeId)
+	}
+	if isLeader && !oldIsLeader {
+		// 成为主节点 先完成状态准备再对外提供服务
+		e.onChange(true, takeover)
+	}
+	e.lock.Lock()
+	e.isLeader = isLeader
+	e.leaderId = leaderId
+	e.lock.Unlock()
+	if !isLeader && oldIsLeader {
+		// 降级为从节点 先停止对外提供服务再清理状态
+		e.onChange(false, false)
+	}
+}
+
+// close 停止选举 release为true时主动释放租约
+func (e *NodeElector) close(release bool) {
+	e.closeOnce.Do(func() {
+		close(e.closeChan)
+		<-e.finishChan
+		isLeader, _ := e.GetLeader()
+		if release && isLeader {
+			err := e.db.ReleaseNodeLeader(e.nodeId)
+			if err != nil {
+				logger.Error("release node leader error: %v, nodeId: %v", err, e.nodeId)
+			}
+		}
+		e.setLeader("", false)
+	})
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/mq"
+	"hk4e/node/api"
+	"hk4e/node/dao"
+
+	"github.com/byebyebruce/natsrpc"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/encoders/protobuf"
+)
+
+type testNode struct {
+	conn         *nats.Conn
+	messageQueue *mq.MessageQueue
+	service      *Service
+}
+
+func startTestNatsServer(t *testing.T) string {
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(time.Second * 5) {
+		t.Fatal("nats server start error")
+	}
+	t.Cleanup(natsServer.Shutdown)
+	return natsServer.ClientURL()
+}
+
+func startTestNode(t *testing.T, db *dao.Dao) *testNode {
+	conn, err := nats.Connect(config.GetConfig().MQ.NatsUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &testNode{conn: conn}
+	node.messageQueue = mq.NewMessageQueue(api.NODE, "node", nil)
+	node.service, err = NewService(db, conn, node.messageQueue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+// crash 模拟进程崩溃 不释放租约直接断开
+func (n *testNode) crash() {
+	n.service.nodeElector.close(false)
+	n.service.svr.ClearAllSubscription()
+	n.conn.Close()
+}
+
+func (n *testNode) isLeader() bool {
+	isLeader, _ := n.service.nodeElector.GetLeader()
+	return isLeader
+}
+
+// waitLeader 等待存活节点中选出唯一的主节点 并且所有节点都认可该主节点
+func waitLeader(t *testing.T, nodeList []*testNode) *testNode {
+	deadline := time.Now().Add(time.Second * 10)
+	for time.Now().Before(deadline) {
+		var leader *testNode = nil
+		leaderCount := 0
+		for _, node := range nodeList {
+			if node.isLeader() {
+				leader = node
+				leaderCount++
+			}
+		}
+		if leaderCount == 1 {
+			agree := true
+			for _, node := range nodeList {
+				_, leaderId := node.service.nodeElector.GetLeader()
+				if leaderId != leader.service.nodeElector.nodeId {
+					agree = false
+				}
+			}
+			if agree {
+				return leader
+			}
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	t.Fatal("wait node leader timeout")
+	return nil
+}
+
+func TestNodeFailover(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "node.db")
+	db := newTestDao(t, dbPath+"?_pragma=busy_timeout(5000)")
+	config.CONF.Hk4e.NodeLeaderLeaseTime = 1
+	config.CONF.MQ.NatsUrl = startTestNatsServer(t)
+
+	nodeList := make([]*testNode, 0)
+	for i := 0; i < 3; i++ {
+		nodeList = append(nodeList, startTestNode(t, db))
+	}
+	leader := waitLeader(t, nodeList)
+
+	conn, err := nats.Connect(config.GetConfig().MQ.NatsUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	enc, err := nats.NewEncodedConn(conn, protobuf.PROTOBUF_ENCODER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := api.NewDiscoveryNATSRPCClient(enc, natsrpc.WithClientTimeout(time.Second*5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+
+	// 请求会随机落到任意节点 从节点转发给主节点处理
+	gsRsp, err := client.RegisterServer(ctx, &api.RegisterServerReq{ServerType: api.GS, AppVersion: "test"})
+	if err != nil || gsRsp.GsId != 1 {
+		t.Fatalf("register gs error: %v, rsp: %v", err, gsRsp)
+	}
+	gateAddr := &api.GateServerAddr{KcpAddr: "127.0.0.1", KcpPort: 22103, MqAddr: "127.0.0.1", MqPort: 33103}
+	gateRsp, err := client.RegisterServer(ctx, &api.RegisterServerReq{
+		ServerType:      api.GATE,
+		AppVersion:      "test",
+		GateServerAddr:  gateAddr,
+		GameVersionList: []string{"320"},
+	})
+	if err != nil {
+		t.Fatalf("register gate error: %v", err)
+	}
+	var lastUid uint32 = 0
+	for i := 0; i < 20; i++ {
+		uidRsp, err := client.GetNextUid(ctx, &api.NullMsg{})
+		if err != nil || uidRsp.Uid <= lastUid {
+			t.Fatalf("get next uid error: %v, rsp: %v, lastUid: %v", err, uidRsp, lastUid)
+		}
+		lastUid = uidRsp.Uid
+	}
+	_, err = client.SetWhiteList(ctx, &api.SetWhiteListReq{IsAdd: true, IpAddr: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 主节点崩溃 等待租约过期后其他节点接管
+	leader.crash()
+	aliveNodeList := make([]*testNode, 0)
+	for _, node := range nodeList {
+		if node != leader {
+			aliveNodeList = append(aliveNodeList, node)
+		}
+	}
+	newLeader := waitLeader(t, aliveNodeList)
+	if !newLeader.service.discoveryService.isRebuilding() {
+		t.Fatal("new leader should wait for rebuild")
+	}
+	// 重建期间不分配新的gs id
+	_, err = client.RegisterServer(ctx, &api.RegisterServerReq{ServerType: api.GS, AppVersion: "test"})
+	if err == nil {
+		t.Fatal("register gs should fail while rebuilding")
+	}
+	if _, err = client.GetServerAppId(ctx, &api.GetServerAppIdReq{ServerType: api.GS}); err == nil {
+		t.Fatal("server instance should be empty before keepalive")
+	}
+
+	// 服务器心跳重建实例
+	_, err = client.KeepaliveServer(ctx, &api.KeepaliveServerReq{
+		ServerType:    api.GS,
+		AppId:         gsRsp.AppId,
+		LoadCount:     1,
+		AppVersion:    "test",
+		GsId:          gsRsp.GsId,
+		OnlineUidList: []uint32{lastUid},
+	})
+	if err != nil {
+		t.Fatalf("gs keepalive error: %v", err)
+	}
+	_, err = client.KeepaliveServer(ctx, &api.KeepaliveServerReq{
+		ServerType:      api.GATE,
+		AppId:           gateRsp.AppId,
+		AppVersion:      "test",
+		GateServerAddr:  gateAddr,
+		GameVersionList: []string{"320"},
+	})
+	if err != nil {
+		t.Fatalf("gate keepalive error: %v", err)
+	}
+	mainGsRsp, err := client.GetMainGameServerAppId(ctx, &api.NullMsg{})
+	if err != nil || mainGsRsp.AppId != gsRsp.AppId {
+		t.Fatalf("main gs not rebuild, err: %v, rsp: %v", err, mainGsRsp)
+	}
+	gateAddrRsp, err := client.GetGateServerAddr(ctx, &api.GetGateServerAddrReq{GameVersion: "320"})
+	if err != nil || gateAddrRsp.KcpPort != gateAddr.KcpPort {
+		t.Fatalf("gate not rebuild, err: %v, rsp: %v", err, gateAddrRsp)
+	}
+	onlineRsp, err := client.GetGlobalGsOnlineMap(ctx, &api.NullMsg{})
+	if err != nil || onlineRsp.OnlineMap[lastUid] != gsRsp.AppId {
+		t.Fatalf("global gs online map not rebuild, err: %v, rsp: %v", err, onlineRsp)
+	}
+	// 停服白名单与uid从数据库恢复
+	whiteListRsp, err := client.GetWhiteList(ctx, &api.NullMsg{})
+	if err != nil || len(whiteListRsp.IpAddrList) != 1 {
+		t.Fatalf("white list not reload, err: %v, rsp: %v", err, whiteListRsp)
+	}
+	uidRsp, err := client.GetNextUid(ctx, &api.NullMsg{})
+	if err != nil || uidRsp.Uid <= lastUid {
+		t.Fatalf("uid reused after failover, err: %v, rsp: %v, lastUid: %v", err, uidRsp, lastUid)
+	}
+
+	// 跳过剩余的重建等待时间 新注册的gs不会与已恢复的gs id冲突
+	newLeader.service.discoveryService.rebuildEndTime.Store(0)
+	newGsRsp, err := client.RegisterServer(ctx, &api.RegisterServerReq{ServerType: api.GS, AppVersion: "test"})
+	if err != nil || newGsRsp.GsId != 2 {
+		t.Fatalf("register gs after rebuild error: %v, rsp: %v", err, newGsRsp)
+	}
+
+	// 主节点正常关闭时主动释放租约 剩余节点立即接管
+	newLeader.service.Close()
+	newLeader.conn.Close()
+	for _, node := range aliveNodeList {
+		if node != newLeader {
+			waitLeader(t, []*testNode{node})
+			node.service.Close()
+			node.conn.Close()
+		}
+	}
+}
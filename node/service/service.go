@@ -1,18 +1,26 @@
 package service
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"hk4e/common/mq"
 	"hk4e/node/api"
 	"hk4e/node/dao"
+	"hk4e/pkg/random"
 
 	"github.com/byebyebruce/natsrpc"
+	"github.com/flswld/halo/logger"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/encoders/protobuf"
 )
 
 type Service struct {
 	db               *dao.Dao
+	svr              *natsrpc.Server
 	discoveryService *DiscoveryService
+	nodeElector      *NodeElector
 }
 
 func NewService(db *dao.Dao, conn *nats.Conn, messageQueue *mq.MessageQueue) (*Service, error) {
@@ -28,17 +36,34 @@ func NewService(db *dao.Dao, conn *nats.Conn, messageQueue *mq.MessageQueue) (*S
 	if err != nil {
 		return nil, err
 	}
-	_, err = api.RegisterDiscoveryNATSRPCServer(svr, discoveryService)
+	nodeId := strings.ToLower(random.GetRandomStr(8))
+	nodeElector := NewNodeElector(db, nodeId, GetNodeLeaderLeaseTime(), discoveryService.onLeaderChange)
+	// 所有节点共同订阅注册发现服务 从节点收到的请求转发给主节点
+	_, err = api.RegisterDiscoveryNATSRPCServer(svr, NewDiscoveryProxy(discoveryService, nodeElector, enc, true))
+	if err != nil {
+		return nil, err
+	}
+	// 按节点id定向订阅 用于接收从节点转发的请求
+	_, err = api.RegisterDiscoveryNATSRPCServer(svr, NewDiscoveryProxy(discoveryService, nodeElector, enc, false), natsrpc.WithServiceID(nodeId))
 	if err != nil {
 		return nil, err
 	}
+	logger.Info("node start election, nodeId: %v", nodeId)
+	go nodeElector.run()
 	s := &Service{
 		db:               db,
+		svr:              svr,
 		discoveryService: discoveryService,
+		nodeElector:      nodeElector,
 	}
 	return s, nil
 }
 
 func (s *Service) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_ = s.svr.Close(ctx)
+	// 主动释放租约 其他节点可以立即接管
+	s.nodeElector.close(true)
 	s.discoveryService.close()
 }
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sync"
+
+	"hk4e/common/config"
+	"hk4e/node/dao"
+
+	"github.com/flswld/halo/logger"
+)
+
+const (
+	UidLeaseSizeDefault = 100
+)
+
+func GetUidLeaseSize() uint32 {
+	uidLeaseSize := config.GetConfig().Hk4e.UidLeaseSize
+	if uidLeaseSize <= 0 {
+		return UidLeaseSizeDefault
+	}
+	return uint32(uidLeaseSize)
+}
+
+// UidAllocator 自增uid分配器
+// 每次从数据库预留一段uid 预留写入数据库成功后才会分配出去 进程崩溃最多浪费未分配完的部分 不会重复分配
+type UidAllocator struct {
+	db        *dao.Dao   // 数据库访问对象
+	leaseSize uint32     // 每次预留的uid数量
+	lock      sync.Mutex // 锁
+	lastUid   uint32     // 最后分配出去的uid
+	leaseEnd  uint32     // 当前预留区间的最大uid
+}
+
+func NewUidAllocator(db *dao.Dao, leaseSize uint32) *UidAllocator {
+	r := new(UidAllocator)
+	r.db = db
+	r.leaseSize = leaseSize
+	return r
+}
+
+// Next 分配下一个uid
+func (u *UidAllocator) Next() (uint32, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.lastUid >= u.leaseEnd {
+		lastUid, err := u.db.AllocUidBlock(u.leaseSize)
+		if err != nil {
+			logger.Error("alloc uid block from db error: %v", err)
+			return 0, err
+		}
+		u.lastUid = lastUid
+		u.leaseEnd = lastUid + u.leaseSize
+		logger.Info("alloc uid block ok, begin: %v, end: %v", u.lastUid+1, u.leaseEnd)
+	}
+	u.lastUid++
+	return u.lastUid, nil
+}
+
+// Remain 当前预留区间内尚未分配的uid数量
+func (u *UidAllocator) Remain() uint32 {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.leaseEnd - u.lastUid
+}
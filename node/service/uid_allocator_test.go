@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hk4e/common/config"
+	"hk4e/node/api"
+	"hk4e/node/dao"
+	"hk4e/pkg/random"
+
+	"github.com/flswld/halo/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger(nil)
+	os.Exit(m.Run())
+}
+
+const uidAllocatorCrashDbEnv = "HK4E_UID_ALLOCATOR_CRASH_DB"
+
+func newTestDao(t testing.TB, dbPath string) *dao.Dao {
+	config.CONF = &config.Config{
+		Hk4e:     config.Hk4e{UidLeaseSize: 10},
+		Database: config.Database{Url: "sqlite://" + dbPath},
+	}
+	t.Cleanup(func() {
+		config.CONF = nil
+	})
+	db, err := dao.NewDao()
+	if err != nil {
+		t.Fatalf("new dao error: %v", err)
+	}
+	region, err := db.QueryRegion()
+	if err != nil {
+		t.Fatalf("query region error: %v", err)
+	}
+	if region == nil {
+		err = db.InsertRegion(&dao.Region{Ec2bData: random.NewEc2b().Bytes(), NextUid: UidBegin, IpAddrWhiteList: make([]string, 0)})
+		if err != nil {
+			t.Fatalf("insert region error: %v", err)
+		}
+	}
+	return db
+}
+
+// TestUidAllocatorCrashProcess 被崩溃测试拉起的子进程 持续分配uid并输出 直到被kill
+func TestUidAllocatorCrashProcess(t *testing.T) {
+	dbPath := os.Getenv(uidAllocatorCrashDbEnv)
+	if dbPath == "" {
+		t.Skip("only run as child process of TestUidAllocatorCrash")
+	}
+	uidAllocator := NewUidAllocator(newTestDao(t, dbPath), GetUidLeaseSize())
+	for {
+		uid, err := uidAllocator.Next()
+		if err != nil {
+			t.Fatalf("alloc uid error: %v", err)
+		}
+		fmt.Printf("uid:%v\n", uid)
+	}
+}
+
+// TestUidAllocatorCrash 反复kill -9分配uid的进程 重启后不会重复分配uid
+func TestUidAllocatorCrash(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "node.db")
+	uidMap := make(map[uint32]struct{})
+	var lastUid uint32 = 0
+	for round := 0; round < 5; round++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestUidAllocatorCrashProcess$")
+		cmd.Env = append(os.Environ(), uidAllocatorCrashDbEnv+"="+dbPath)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cmd.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 每轮分配的数量不是预留数量的整数倍 保证进程被杀时预留区间内有未分配的uid
+		count := 7 + round*13
+		scanner := bufio.NewScanner(stdout)
+		for count > 0 && scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "uid:") {
+				continue
+			}
+			uid, err := strconv.ParseUint(strings.TrimPrefix(line, "uid:"), 10, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, exist := uidMap[uint32(uid)]; exist {
+				t.Fatalf("uid reused, round: %v, uid: %v", round, uid)
+			}
+			if uint32(uid) <= lastUid {
+				t.Fatalf("uid not increase, round: %v, uid: %v, lastUid: %v", round, uid, lastUid)
+			}
+			uidMap[uint32(uid)] = struct{}{}
+			lastUid = uint32(uid)
+			count--
+		}
+		if count > 0 {
+			t.Fatalf("child process exit early, round: %v", round)
+		}
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+	if lastUid <= UidBegin {
+		t.Fatalf("uid should begin after %v, lastUid: %v", UidBegin, lastUid)
+	}
+}
+
+func TestRegionStateSaveImmediately(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "node.db")
+	s := &DiscoveryService{
+		db: newTestDao(t, dbPath),
+		stopServerInfo: &StopServerInfo{
+			ipAddrWhiteList: make(map[string]struct{}),
+		},
+	}
+	_, err := s.SetWhiteList(context.TODO(), &api.SetWhiteListReq{IsAdd: true, IpAddr: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.SetWhiteList(context.TODO(), &api.SetWhiteListReq{IsAdd: true, IpAddr: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.SetWhiteList(context.TODO(), &api.SetWhiteListReq{IsAdd: false, IpAddr: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.SetStopServerInfo(context.TODO(), &api.StopServerInfo{StopServer: false, StartTime: 100, EndTime: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不调用close 直接从数据库读取
+	region, err := newTestDao(t, dbPath).QueryRegion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(region.IpAddrWhiteList) != 1 || region.IpAddrWhiteList[0] != "10.0.0.1" {
+		t.Fatalf("white list not save, list: %v", region.IpAddrWhiteList)
+	}
+	if region.StopServer || region.StopServerStartTime != 100 || region.StopServerEndTime != 200 {
+		t.Fatalf("stop server info not save, region: %+v", region)
+	}
+}
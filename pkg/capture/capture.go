@@ -0,0 +1,288 @@
+// Package capture 抓包文件格式
+// 记录单个会话解密后 协议解码前的收发包 用于离线分析和回放
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+									抓包文件格式(大端序)
+文件头
++---------------------------------------------------------------------------------------+
+|			magic "HK4ECAP" + 0x00(8字节)			|	version(2)	|	flag(2)			|
++---------------------------------------------------------------------------------------+
+|		sessionId(4)		|		uid(4)		|			startTime(8) 毫秒			|
++---------------------------------------------------------------------------------------+
+帧
++---------------------------------------------------------------------------------------+
+|	0x4567	|	dir(1)	|	time(8) 相对开始时间微秒	|	cmdId(2)	|	headLen(2)	|
++---------------------------------------------------------------------------------------+
+|		payloadLen(4)		|		head		|		payload		|		0x89AB			|
++---------------------------------------------------------------------------------------+
+*/
+
+const (
+	Version = 1
+)
+
+const (
+	FlagClientProto = 1 << 0 // 帧数据为客户端协议(开启了客户端协议代理)
+)
+
+const (
+	DirRecv = 1 // 客户端->服务器
+	DirSend = 2 // 服务器->客户端
+)
+
+const (
+	fileHeadLen  = 28
+	frameHeadLen = 19
+	frameMaxLen  = 1024 * 1024
+)
+
+var fileMagic = []byte{'H', 'K', '4', 'E', 'C', 'A', 'P', 0x00}
+
+var (
+	ErrFormat    = errors.New("capture file format error")
+	ErrVersion   = errors.New("capture file version not support")
+	ErrSizeLimit = errors.New("capture file size limit")
+	ErrClosed    = errors.New("capture file closed")
+)
+
+type Head struct {
+	Version   uint16
+	Flag      uint16
+	SessionId uint32
+	Uid       uint32
+	StartTime int64 // 毫秒
+}
+
+type Frame struct {
+	Dir       uint8
+	Time      time.Duration // 相对开始时间
+	CmdId     uint16
+	HeadData  []byte
+	ProtoData []byte
+}
+
+// Writer 抓包写入 并发安全 收包协程和发包协程共用
+type Writer struct {
+	lock      sync.Mutex
+	head      *Head
+	startTime time.Time
+	file      io.WriteCloser
+	writer    *bufio.Writer
+	size      int64
+	maxSize   int64
+	closed    bool
+}
+
+// Create 创建抓包文件 maxSize为0则不限制文件大小
+func Create(filePath string, head *Head, startTime time.Time, maxSize int64) (*Writer, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(file, head, startTime, maxSize)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func NewWriter(file io.WriteCloser, head *Head, startTime time.Time, maxSize int64) (*Writer, error) {
+	w := new(Writer)
+	w.head = head
+	w.head.Version = Version
+	w.head.StartTime = startTime.UnixMilli()
+	w.startTime = startTime
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.maxSize = maxSize
+	data := make([]byte, fileHeadLen)
+	copy(data[0:8], fileMagic)
+	binary.BigEndian.PutUint16(data[8:10], head.Version)
+	binary.BigEndian.PutUint16(data[10:12], head.Flag)
+	binary.BigEndian.PutUint32(data[12:16], head.SessionId)
+	binary.BigEndian.PutUint32(data[16:20], head.Uid)
+	binary.BigEndian.PutUint64(data[20:28], uint64(head.StartTime))
+	_, err := w.writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	w.size = fileHeadLen
+	return w, nil
+}
+
+func (w *Writer) GetHead() *Head {
+	return w.head
+}
+
+func (w *Writer) GetSize() int64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.size
+}
+
+// WriteFrame 写入一帧 超出文件大小限制时返回ErrSizeLimit 调用方应关闭抓包
+func (w *Writer) WriteFrame(dir uint8, now time.Time, cmdId uint16, headData []byte, protoData []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	frameLen := int64(frameHeadLen + len(headData) + len(protoData) + 2)
+	if w.maxSize != 0 && w.size+frameLen > w.maxSize {
+		return ErrSizeLimit
+	}
+	data := make([]byte, frameHeadLen)
+	data[0] = 0x45
+	data[1] = 0x67
+	data[2] = dir
+	binary.BigEndian.PutUint64(data[3:11], uint64(now.Sub(w.startTime).Microseconds()))
+	binary.BigEndian.PutUint16(data[11:13], cmdId)
+	binary.BigEndian.PutUint16(data[13:15], uint16(len(headData)))
+	binary.BigEndian.PutUint32(data[15:19], uint32(len(protoData)))
+	_, err := w.writer.Write(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(headData)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(protoData)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write([]byte{0x89, 0xAB})
+	if err != nil {
+		return err
+	}
+	w.size += frameLen
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.writer.Flush()
+	closeErr := w.file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Reader 抓包读取
+type Reader struct {
+	head   *Head
+	reader *bufio.Reader
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := new(Reader)
+	reader.reader = bufio.NewReader(r)
+	data := make([]byte, fileHeadLen)
+	_, err := io.ReadFull(reader.reader, data)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	for i, b := range fileMagic {
+		if data[i] != b {
+			return nil, ErrFormat
+		}
+	}
+	head := new(Head)
+	head.Version = binary.BigEndian.Uint16(data[8:10])
+	if head.Version != Version {
+		return nil, ErrVersion
+	}
+	head.Flag = binary.BigEndian.Uint16(data[10:12])
+	head.SessionId = binary.BigEndian.Uint32(data[12:16])
+	head.Uid = binary.BigEndian.Uint32(data[16:20])
+	head.StartTime = int64(binary.BigEndian.Uint64(data[20:28]))
+	reader.head = head
+	return reader, nil
+}
+
+func (r *Reader) GetHead() *Head {
+	return r.head
+}
+
+// ReadFrame 读取下一帧 文件结束时返回io.EOF 末尾不完整的帧视为文件结束
+func (r *Reader) ReadFrame() (*Frame, error) {
+	data := make([]byte, frameHeadLen)
+	_, err := io.ReadFull(r.reader, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data[0] != 0x45 || data[1] != 0x67 {
+		return nil, ErrFormat
+	}
+	frame := new(Frame)
+	frame.Dir = data[2]
+	frame.Time = time.Duration(binary.BigEndian.Uint64(data[3:11])) * time.Microsecond
+	frame.CmdId = binary.BigEndian.Uint16(data[11:13])
+	headLen := int(binary.BigEndian.Uint16(data[13:15]))
+	protoLen := int(binary.BigEndian.Uint32(data[15:19]))
+	if headLen+protoLen > frameMaxLen {
+		return nil, ErrFormat
+	}
+	body := make([]byte, headLen+protoLen+2)
+	_, err = io.ReadFull(r.reader, body)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if body[headLen+protoLen] != 0x89 || body[headLen+protoLen+1] != 0xAB {
+		return nil, ErrFormat
+	}
+	frame.HeadData = body[:headLen]
+	frame.ProtoData = body[headLen : headLen+protoLen]
+	return frame, nil
+}
+
+// ReadAll 读取抓包文件的全部帧
+func ReadAll(filePath string) (*Head, []*Frame, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	reader, err := NewReader(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	frameList := make([]*Frame, 0)
+	for {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		frameList = append(frameList, frame)
+	}
+	return reader.GetHead(), frameList, nil
+}
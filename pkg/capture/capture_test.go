@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCaptureReadWrite(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.cap")
+	startTime := time.UnixMilli(1700000000000)
+	w, err := Create(filePath, &Head{Flag: FlagClientProto, SessionId: 1, Uid: 10001}, startTime, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteFrame(DirRecv, startTime.Add(time.Millisecond*5), 100, []byte{0x01}, []byte{0x02, 0x03})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteFrame(DirSend, startTime.Add(time.Second), 200, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	if w.WriteFrame(DirSend, startTime, 200, nil, nil) != ErrClosed {
+		t.Fatal("write after close should fail")
+	}
+	head, frameList, err := ReadAll(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Version != Version || head.Flag != FlagClientProto || head.SessionId != 1 || head.Uid != 10001 || head.StartTime != startTime.UnixMilli() {
+		t.Fatalf("head error: %+v", head)
+	}
+	if len(frameList) != 2 {
+		t.Fatalf("frame num error: %v", len(frameList))
+	}
+	frame := frameList[0]
+	if frame.Dir != DirRecv || frame.Time != time.Millisecond*5 || frame.CmdId != 100 ||
+		!bytes.Equal(frame.HeadData, []byte{0x01}) || !bytes.Equal(frame.ProtoData, []byte{0x02, 0x03}) {
+		t.Fatalf("frame error: %+v", frame)
+	}
+	frame = frameList[1]
+	if frame.Dir != DirSend || frame.Time != time.Second || frame.CmdId != 200 || len(frame.HeadData) != 0 || len(frame.ProtoData) != 0 {
+		t.Fatalf("frame error: %+v", frame)
+	}
+	// 进程异常退出导致末尾帧不完整时 读取到最后一个完整帧为止
+	data, _ := os.ReadFile(filePath)
+	reader, err := NewReader(bytes.NewReader(data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = reader.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = reader.ReadFrame()
+	if err != io.EOF {
+		t.Fatalf("truncated frame should eof: %v", err)
+	}
+	_, err = NewReader(bytes.NewReader([]byte("HK4E")))
+	if err != ErrFormat {
+		t.Fatal("invalid file should fail")
+	}
+}
+
+func TestCaptureSizeLimit(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.cap")
+	now := time.Now()
+	w, err := Create(filePath, &Head{}, now, fileHeadLen+frameHeadLen+2+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = w.Close()
+	}()
+	if w.WriteFrame(DirRecv, now, 1, nil, make([]byte, 10)) != nil {
+		t.Fatal("write frame should succ")
+	}
+	if w.WriteFrame(DirRecv, now, 1, nil, nil) != ErrSizeLimit {
+		t.Fatal("write frame should exceed size limit")
+	}
+}
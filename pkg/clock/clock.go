@@ -0,0 +1,81 @@
+// Package clock 可替换的全局时钟
+// 默认使用系统时间 回放等需要确定性时间的场景可以替换为手动推进的时钟
+package clock
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct {
+}
+
+func (s *systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock 手动推进的时钟 时间只会向前推进
+type ManualClock struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (m *ManualClock) Now() time.Time {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.now
+}
+
+// Set 设置当前时间 早于当前时间则忽略 返回是否设置成功
+func (m *ManualClock) Set(now time.Time) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if now.Before(m.now) {
+		return false
+	}
+	m.now = now
+	return true
+}
+
+// Reset 重置当前时间 允许回退
+func (m *ManualClock) Reset(now time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.now = now
+}
+
+type clockHolder struct {
+	clock Clock
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(&clockHolder{clock: new(systemClock)})
+}
+
+// SetClock 替换全局时钟 为空则恢复系统时间
+func SetClock(c Clock) {
+	if c == nil {
+		c = new(systemClock)
+	}
+	current.Store(&clockHolder{clock: c})
+}
+
+func GetClock() Clock {
+	return current.Load().(*clockHolder).clock
+}
+
+// Now 获取全局时钟的当前时间
+func Now() time.Time {
+	return GetClock().Now()
+}
@@ -0,0 +1,30 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	c := NewManualClock(start)
+	SetClock(c)
+	defer SetClock(nil)
+	if !Now().Equal(start) {
+		t.Fatalf("now not equal manual clock, now: %v", Now())
+	}
+	if !c.Set(start.Add(time.Second)) || !Now().Equal(start.Add(time.Second)) {
+		t.Fatalf("manual clock set fail, now: %v", Now())
+	}
+	if c.Set(start) || !Now().Equal(start.Add(time.Second)) {
+		t.Fatalf("manual clock go back, now: %v", Now())
+	}
+	c.Reset(start)
+	if !Now().Equal(start) {
+		t.Fatalf("manual clock reset fail, now: %v", Now())
+	}
+	SetClock(nil)
+	if Now().Sub(time.Now()) > time.Second || time.Now().Sub(Now()) > time.Second {
+		t.Fatalf("system clock not restore, now: %v", Now())
+	}
+}
@@ -0,0 +1,405 @@
+// Package metrics 监控指标
+// 以Prometheus文本格式在/metrics上暴露 支持计数器 仪表盘 直方图 以及按标签区分的指标
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultLatencyBuckets 默认的耗时直方图分档 单位秒
+var DefaultLatencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Collector 指标采集器
+type Collector interface {
+	Name() string
+	Write(buf *bytes.Buffer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	lock         sync.RWMutex
+	collectorMap map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	r := new(Registry)
+	r.collectorMap = make(map[string]Collector)
+	return r
+}
+
+// DefaultRegistry 进程内默认的注册表 单进程模式下所有服务器共用
+var DefaultRegistry = NewRegistry()
+
+// Register 注册指标 同名指标重复注册时返回已注册的指标
+func (r *Registry) Register(collector Collector) Collector {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	exist, ok := r.collectorMap[collector.Name()]
+	if ok {
+		return exist
+	}
+	r.collectorMap[collector.Name()] = collector
+	return collector
+}
+
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.collectorMap, name)
+}
+
+// Gather 按指标名排序输出全部指标
+func (r *Registry) Gather() []byte {
+	r.lock.RLock()
+	collectorList := make([]Collector, 0, len(r.collectorMap))
+	for _, collector := range r.collectorMap {
+		collectorList = append(collectorList, collector)
+	}
+	r.lock.RUnlock()
+	sort.Slice(collectorList, func(i, j int) bool {
+		return collectorList[i].Name() < collectorList[j].Name()
+	})
+	buf := new(bytes.Buffer)
+	for _, collector := range collectorList {
+		collector.Write(buf)
+	}
+	return buf.Bytes()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(r.Gather())
+}
+
+// Serve 在指定地址上暴露默认注册表的/metrics
+func Serve(addr string) error {
+	return http.ListenAndServe(addr, newServeMux())
+}
+
+// ServeAsync 同步监听后在协程中暴露/metrics 监听或服务出错时输出错误
+// 此时服务器的日志可能还未初始化 错误直接输出到标准输出
+func ServeAsync(addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Printf("metrics listen error: %v, addr: %v\n", err, addr)
+		return
+	}
+	go func() {
+		err := http.Serve(listener, newServeMux())
+		if err != nil {
+			fmt.Printf("metrics serve error: %v, addr: %v\n", err, addr)
+		}
+	}()
+}
+
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", DefaultRegistry)
+	return mux
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d *desc) Name() string {
+	return d.name
+}
+
+func (d *desc) writeHead(buf *bytes.Buffer) {
+	buf.WriteString("# HELP ")
+	buf.WriteString(d.name)
+	buf.WriteByte(' ')
+	buf.WriteString(strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(d.help))
+	buf.WriteString("\n# TYPE ")
+	buf.WriteString(d.name)
+	buf.WriteByte(' ')
+	buf.WriteString(d.typ)
+	buf.WriteByte('\n')
+}
+
+// writeSample 输出一行指标 extraName与extraValue为直方图分档等附加标签
+func (d *desc) writeSample(buf *bytes.Buffer, suffix string, labelValues []string, extraName string, extraValue string, value float64) {
+	buf.WriteString(d.name)
+	buf.WriteString(suffix)
+	if len(labelValues) != 0 || extraName != "" {
+		buf.WriteByte('{')
+		first := true
+		writeLabel := func(name string, value string) {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteString(name)
+			buf.WriteString(`="`)
+			buf.WriteString(strings.NewReplacer("\\", `\\`, "\n", `\n`, `"`, `\"`).Replace(value))
+			buf.WriteByte('"')
+		}
+		for index, labelValue := range labelValues {
+			writeLabel(d.labelNames[index], labelValue)
+		}
+		if extraName != "" {
+			writeLabel(extraName, extraValue)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// atomicFloat 原子浮点数
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (a *atomicFloat) Load() float64 {
+	return math.Float64frombits(a.bits.Load())
+}
+
+func (a *atomicFloat) Store(value float64) {
+	a.bits.Store(math.Float64bits(value))
+}
+
+func (a *atomicFloat) Add(delta float64) {
+	for {
+		oldBits := a.bits.Load()
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + delta)
+		if a.bits.CompareAndSwap(oldBits, newBits) {
+			return
+		}
+	}
+}
+
+// seriesMap 按标签值区分的指标序列集合
+type seriesMap[T any] struct {
+	lock      sync.RWMutex
+	seriesMap map[string]*series[T]
+	newValue  func() *T
+}
+
+type series[T any] struct {
+	labelValues []string
+	value       *T
+}
+
+func (s *seriesMap[T]) get(labelCount int, labelValues []string) *T {
+	if len(labelValues) != labelCount {
+		panic(errors.New(fmt.Sprintf("metrics label count not match, want: %v, got: %v", labelCount, len(labelValues))))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s.lock.RLock()
+	item, exist := s.seriesMap[key]
+	s.lock.RUnlock()
+	if exist {
+		return item.value
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, exist = s.seriesMap[key]
+	if !exist {
+		item = &series[T]{labelValues: append([]string(nil), labelValues...), value: s.newValue()}
+		s.seriesMap[key] = item
+	}
+	return item.value
+}
+
+func (s *seriesMap[T]) sortedList() []*series[T] {
+	s.lock.RLock()
+	list := make([]*series[T], 0, len(s.seriesMap))
+	for _, item := range s.seriesMap {
+		list = append(list, item)
+	}
+	s.lock.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].labelValues, "\xff") < strings.Join(list[j].labelValues, "\xff")
+	})
+	return list
+}
+
+func newSeriesMap[T any]() *seriesMap[T] {
+	return &seriesMap[T]{
+		seriesMap: make(map[string]*series[T]),
+		newValue:  func() *T { return new(T) },
+	}
+}
+
+// Counter 计数器 只增不减
+type Counter struct {
+	desc
+	seriesMap *seriesMap[atomicFloat]
+}
+
+// NewCounter 创建并注册到默认注册表
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:      desc{name: name, help: help, typ: TypeCounter, labelNames: labelNames},
+		seriesMap: newSeriesMap[atomicFloat](),
+	}
+	return DefaultRegistry.Register(c).(*Counter)
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.seriesMap.get(len(c.labelNames), labelValues).Add(1)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.seriesMap.get(len(c.labelNames), labelValues).Add(delta)
+}
+
+func (c *Counter) Get(labelValues ...string) float64 {
+	return c.seriesMap.get(len(c.labelNames), labelValues).Load()
+}
+
+func (c *Counter) Write(buf *bytes.Buffer) {
+	c.writeHead(buf)
+	for _, item := range c.seriesMap.sortedList() {
+		c.writeSample(buf, "", item.labelValues, "", "", item.value.Load())
+	}
+}
+
+// Gauge 仪表盘 可增可减
+type Gauge struct {
+	desc
+	seriesMap *seriesMap[atomicFloat]
+}
+
+// NewGauge 创建并注册到默认注册表
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	g := &Gauge{
+		desc:      desc{name: name, help: help, typ: TypeGauge, labelNames: labelNames},
+		seriesMap: newSeriesMap[atomicFloat](),
+	}
+	return DefaultRegistry.Register(g).(*Gauge)
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.seriesMap.get(len(g.labelNames), labelValues).Store(value)
+}
+
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.seriesMap.get(len(g.labelNames), labelValues).Add(delta)
+}
+
+func (g *Gauge) Get(labelValues ...string) float64 {
+	return g.seriesMap.get(len(g.labelNames), labelValues).Load()
+}
+
+func (g *Gauge) Write(buf *bytes.Buffer) {
+	g.writeHead(buf)
+	for _, item := range g.seriesMap.sortedList() {
+		g.writeSample(buf, "", item.labelValues, "", "", item.value.Load())
+	}
+}
+
+// GaugeFunc 采集时调用回调函数取值的仪表盘 回调函数需要可以在其他协程调用
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc 创建并注册到默认注册表
+func NewGaugeFunc(name string, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: desc{name: name, help: help, typ: TypeGauge},
+		fn:   fn,
+	}
+	return DefaultRegistry.Register(g).(*GaugeFunc)
+}
+
+func (g *GaugeFunc) Write(buf *bytes.Buffer) {
+	g.writeHead(buf)
+	g.writeSample(buf, "", nil, "", "", g.fn())
+}
+
+type histogramValue struct {
+	bucketCountList []atomic.Uint64
+	count           atomic.Uint64
+	sum             atomicFloat
+}
+
+// Histogram 直方图
+type Histogram struct {
+	desc
+	bucketList []float64
+	seriesMap  *seriesMap[histogramValue]
+}
+
+// NewHistogram 创建并注册到默认注册表 bucketList为各分档上限 需要升序
+func NewHistogram(name string, help string, bucketList []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		desc:       desc{name: name, help: help, typ: TypeHistogram, labelNames: labelNames},
+		bucketList: bucketList,
+		seriesMap:  newSeriesMap[histogramValue](),
+	}
+	h.seriesMap.newValue = func() *histogramValue {
+		return &histogramValue{bucketCountList: make([]atomic.Uint64, len(bucketList))}
+	}
+	return DefaultRegistry.Register(h).(*Histogram)
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	item := h.seriesMap.get(len(h.labelNames), labelValues)
+	index := sort.SearchFloat64s(h.bucketList, value)
+	if index < len(item.bucketCountList) {
+		item.bucketCountList[index].Add(1)
+	}
+	item.count.Add(1)
+	item.sum.Add(value)
+}
+
+// GetCount 获取观测次数
+func (h *Histogram) GetCount(labelValues ...string) uint64 {
+	return h.seriesMap.get(len(h.labelNames), labelValues).count.Load()
+}
+
+func (h *Histogram) Write(buf *bytes.Buffer) {
+	h.writeHead(buf)
+	for _, item := range h.seriesMap.sortedList() {
+		// 分档计数为累计值
+		cumulative := uint64(0)
+		for index, upperBound := range h.bucketList {
+			cumulative += item.value.bucketCountList[index].Load()
+			h.writeSample(buf, "_bucket", item.labelValues, "le", formatFloat(upperBound), float64(cumulative))
+		}
+		count := item.value.count.Load()
+		h.writeSample(buf, "_bucket", item.labelValues, "le", "+Inf", float64(count))
+		h.writeSample(buf, "_sum", item.labelValues, "", "", item.value.sum.Load())
+		h.writeSample(buf, "_count", item.labelValues, "", "", float64(count))
+	}
+}
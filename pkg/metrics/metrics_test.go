@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCounterGauge(t *testing.T) {
+	counter := NewCounter("test_counter_total", "test counter", "type")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				counter.Inc("a")
+			}
+		}()
+	}
+	wg.Wait()
+	counter.Add(0.5, "b")
+	counter.Add(-1, "b")
+	if counter.Get("a") != 1000 || counter.Get("b") != 0.5 {
+		t.Fatalf("counter value error, a: %v, b: %v", counter.Get("a"), counter.Get("b"))
+	}
+	// 同名指标重复创建返回已注册的指标
+	if NewCounter("test_counter_total", "test counter", "type") != counter {
+		t.Fatal("register same name counter twice")
+	}
+	gauge := NewGauge("test_gauge", "test gauge")
+	gauge.Set(10)
+	gauge.Add(-3)
+	if gauge.Get() != 7 {
+		t.Fatalf("gauge value error: %v", gauge.Get())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	histogram := NewHistogram("test_histogram_seconds", "test histogram", []float64{0.1, 1}, "cmd")
+	histogram.Observe(0.05, "PingReq")
+	histogram.Observe(0.1, "PingReq")
+	histogram.Observe(0.5, "PingReq")
+	histogram.Observe(3, "PingReq")
+	if histogram.GetCount("PingReq") != 4 {
+		t.Fatalf("histogram count error: %v", histogram.GetCount("PingReq"))
+	}
+	registry := NewRegistry()
+	registry.Register(histogram)
+	text := string(registry.Gather())
+	for _, line := range []string{
+		"# TYPE test_histogram_seconds histogram",
+		`test_histogram_seconds_bucket{cmd="PingReq",le="0.1"} 2`,
+		`test_histogram_seconds_bucket{cmd="PingReq",le="1"} 3`,
+		`test_histogram_seconds_bucket{cmd="PingReq",le="+Inf"} 4`,
+		`test_histogram_seconds_sum{cmd="PingReq"} 3.65`,
+		`test_histogram_seconds_count{cmd="PingReq"} 4`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("histogram output not contains: %v, output:\n%v", line, text)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	counter := NewCounter("test_handler_total", "test handler", "reason")
+	counter.Inc(`a"b`)
+	registry.Register(counter)
+	registry.Register(NewGaugeFunc("test_handler_gauge", "test handler gauge", func() float64 { return 42 }))
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	expect := "# HELP test_handler_gauge test handler gauge\n" +
+		"# TYPE test_handler_gauge gauge\n" +
+		"test_handler_gauge 42\n" +
+		"# HELP test_handler_total test handler\n" +
+		"# TYPE test_handler_total counter\n" +
+		"test_handler_total{reason=\"a\\\"b\"} 1\n"
+	if string(body) != expect {
+		t.Fatalf("handler output error:\n%v", string(body))
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("content type error: %v", recorder.Header().Get("Content-Type"))
+	}
+}
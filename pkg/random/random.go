@@ -3,21 +3,37 @@ package random
 import (
 	"encoding/hex"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// 包内独立的随机源 不受其他代码使用全局随机源的影响 设置种子后随机序列可以复现
+var (
+	randLock sync.Mutex
+	randSrc  = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// SetSeed 设置随机种子
+func SetSeed(seed int64) {
+	randLock.Lock()
+	randSrc.Seed(seed)
+	randLock.Unlock()
+}
+
 func GetTimeRand() *rand.Rand {
 	return rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
 func GetRandomStr(strLen int) (str string) {
 	baseStr := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	randLock.Lock()
+	defer randLock.Unlock()
 	for i := 0; i < strLen; i++ {
-		index := rand.Intn(len(baseStr))
+		index := randSrc.Intn(len(baseStr))
 		str += string(baseStr[index])
 	}
 	return str
@@ -25,8 +41,10 @@ func GetRandomStr(strLen int) (str string) {
 
 func GetRandomByte(len int) []byte {
 	ret := make([]byte, 0)
+	randLock.Lock()
+	defer randLock.Unlock()
 	for i := 0; i < len; i++ {
-		r := uint8(rand.Intn(256))
+		r := uint8(randSrc.Intn(256))
 		ret = append(ret, r)
 	}
 	return ret
@@ -40,7 +58,9 @@ func GetRandomInt32(min int32, max int32) int32 {
 	if max < min {
 		return 0
 	}
-	r := rand.Int31n(max-min+1) + min
+	randLock.Lock()
+	r := randSrc.Int31n(max-min+1) + min
+	randLock.Unlock()
 	return r
 }
 
@@ -48,7 +68,9 @@ func GetRandomFloat32(min float32, max float32) float32 {
 	if max < min {
 		return 0.0
 	}
-	r := rand.Float32()*(max-min) + min
+	randLock.Lock()
+	r := randSrc.Float32()*(max-min) + min
+	randLock.Unlock()
 	return r
 }
 
@@ -56,6 +78,8 @@ func GetRandomFloat64(min float64, max float64) float64 {
 	if max < min {
 		return 0.0
 	}
-	r := rand.Float64()*(max-min) + min
+	randLock.Lock()
+	r := randSrc.Float64()*(max-min) + min
+	randLock.Unlock()
 	return r
 }
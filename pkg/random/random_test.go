@@ -9,3 +9,14 @@ func TestGetRandomStr(t *testing.T) {
 	str := GetRandomStr(16)
 	fmt.Println(str)
 }
+
+func TestSetSeed(t *testing.T) {
+	SetSeed(1)
+	list := []int32{GetRandomInt32(0, 10000), GetRandomInt32(0, 10000), GetRandomInt32(0, 10000)}
+	SetSeed(1)
+	for i, want := range list {
+		if got := GetRandomInt32(0, 10000); got != want {
+			t.Fatalf("random not repeat after set seed, index: %v, got: %v, want: %v", i, got, want)
+		}
+	}
+}
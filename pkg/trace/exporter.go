@@ -0,0 +1,226 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	exportQueueSize     = 4096
+	exportBatchSize     = 256
+	exportFlushInterval = time.Second
+)
+
+// JsonExporter 按OTLP文件导出格式 每行一个ExportTraceServiceRequest的JSON
+// 队列满时丢弃Span 不阻塞业务协程
+type JsonExporter struct {
+	serviceName string
+	writer      io.Writer
+	closer      io.Closer
+	spanChan    chan *Span
+	closeChan   chan struct{}
+	finishChan  chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewStdoutExporter(serviceName string) *JsonExporter {
+	return NewJsonExporter(serviceName, os.Stdout, nil)
+}
+
+func NewFileExporter(serviceName string, filePath string) (*JsonExporter, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJsonExporter(serviceName, file, file), nil
+}
+
+func NewJsonExporter(serviceName string, writer io.Writer, closer io.Closer) *JsonExporter {
+	e := new(JsonExporter)
+	e.serviceName = serviceName
+	e.writer = writer
+	e.closer = closer
+	e.spanChan = make(chan *Span, exportQueueSize)
+	e.closeChan = make(chan struct{})
+	e.finishChan = make(chan struct{})
+	go e.run()
+	return e
+}
+
+func (e *JsonExporter) Export(span *Span) {
+	select {
+	case <-e.closeChan:
+	case e.spanChan <- span:
+	default:
+	}
+}
+
+func (e *JsonExporter) Close() {
+	e.closeOnce.Do(func() {
+		close(e.closeChan)
+		<-e.finishChan
+	})
+}
+
+func (e *JsonExporter) run() {
+	defer close(e.finishChan)
+	ticker := time.NewTicker(exportFlushInterval)
+	defer ticker.Stop()
+	spanList := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(spanList) == 0 {
+			return
+		}
+		e.writeBatch(spanList)
+		spanList = spanList[:0]
+	}
+	for {
+		select {
+		case span := <-e.spanChan:
+			spanList = append(spanList, span)
+			if len(spanList) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.closeChan:
+			// 导出队列中剩余的Span
+			for len(e.spanChan) != 0 {
+				spanList = append(spanList, <-e.spanChan)
+			}
+			flush()
+			if e.closer != nil {
+				_ = e.closer.Close()
+			}
+			return
+		}
+	}
+}
+
+// writeBatch 每批一次写入 多个进程追加写同一个文件时不会交错
+func (e *JsonExporter) writeBatch(spanList []*Span) {
+	data, err := json.Marshal(BuildOtlpRequest(e.serviceName, spanList))
+	if err != nil {
+		return
+	}
+	_, _ = e.writer.Write(append(data, '\n'))
+}
+
+// OTLP JSON结构 字段命名与opentelemetry-proto的JSON映射一致
+
+type OtlpRequest struct {
+	ResourceSpans []*OtlpResourceSpans `json:"resourceSpans"`
+}
+
+type OtlpResourceSpans struct {
+	Resource   *OtlpResource     `json:"resource"`
+	ScopeSpans []*OtlpScopeSpans `json:"scopeSpans"`
+}
+
+type OtlpResource struct {
+	Attributes []*OtlpKeyValue `json:"attributes"`
+}
+
+type OtlpScopeSpans struct {
+	Scope *OtlpScope  `json:"scope"`
+	Spans []*OtlpSpan `json:"spans"`
+}
+
+type OtlpScope struct {
+	Name string `json:"name"`
+}
+
+type OtlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*OtlpKeyValue `json:"attributes,omitempty"`
+}
+
+type OtlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value *OtlpAnyValue `json:"value"`
+}
+
+type OtlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func newOtlpKeyValue(key string, value any) *OtlpKeyValue {
+	anyValue := new(OtlpAnyValue)
+	switch v := value.(type) {
+	case string:
+		anyValue.StringValue = &v
+	case bool:
+		anyValue.BoolValue = &v
+	case int:
+		intValue := strconv.FormatInt(int64(v), 10)
+		anyValue.IntValue = &intValue
+	case int32:
+		intValue := strconv.FormatInt(int64(v), 10)
+		anyValue.IntValue = &intValue
+	case int64:
+		intValue := strconv.FormatInt(v, 10)
+		anyValue.IntValue = &intValue
+	case uint16:
+		intValue := strconv.FormatUint(uint64(v), 10)
+		anyValue.IntValue = &intValue
+	case uint32:
+		intValue := strconv.FormatUint(uint64(v), 10)
+		anyValue.IntValue = &intValue
+	case uint64:
+		intValue := strconv.FormatUint(v, 10)
+		anyValue.IntValue = &intValue
+	case float64:
+		anyValue.DoubleValue = &v
+	default:
+		stringValue := ""
+		if stringer, ok := v.(interface{ String() string }); ok {
+			stringValue = stringer.String()
+		}
+		anyValue.StringValue = &stringValue
+	}
+	return &OtlpKeyValue{Key: key, Value: anyValue}
+}
+
+// BuildOtlpRequest 将一批Span转换为OTLP导出请求
+func BuildOtlpRequest(serviceName string, spanList []*Span) *OtlpRequest {
+	otlpSpanList := make([]*OtlpSpan, 0, len(spanList))
+	for _, span := range spanList {
+		otlpSpan := &OtlpSpan{
+			TraceId:           span.TraceId,
+			SpanId:            span.SpanId,
+			ParentSpanId:      span.ParentSpanId,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime, 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime, 10),
+		}
+		for _, attr := range span.AttrList {
+			otlpSpan.Attributes = append(otlpSpan.Attributes, newOtlpKeyValue(attr.Key, attr.Value))
+		}
+		otlpSpanList = append(otlpSpanList, otlpSpan)
+	}
+	return &OtlpRequest{
+		ResourceSpans: []*OtlpResourceSpans{{
+			Resource: &OtlpResource{
+				Attributes: []*OtlpKeyValue{newOtlpKeyValue("service.name", serviceName)},
+			},
+			ScopeSpans: []*OtlpScopeSpans{{
+				Scope: &OtlpScope{Name: "hk4e"},
+				Spans: otlpSpanList,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,242 @@
+// Package trace 分布式链路追踪
+// 跨进程传递TraceId和SpanId 在本地记录Span并以OTLP JSON格式导出到文件或标准输出 便于离线查看
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+const DefaultFilePath = "./trace.jsonl"
+
+// SpanKind 与OTLP定义一致
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+	SpanKindProducer = 4
+	SpanKindConsumer = 5
+)
+
+// Config 链路追踪配置
+type Config struct {
+	ServiceName string  // 服务名
+	Exporter    string  // 导出方式 stdout为标准输出 file为写入文件 为空则不开启
+	FilePath    string  // 导出文件路径 为空则使用默认值
+	SampleUid   string  // 必定采样的玩家uid 多个以逗号分隔
+	SampleRate  float64 // 其余玩家的采样率 0到1之间
+}
+
+// SpanContext 跨进程传递的链路上下文
+type SpanContext struct {
+	TraceId string
+	SpanId  string
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceId != ""
+}
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span 一段被追踪的处理过程
+type Span struct {
+	tracer       *Tracer
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Name         string
+	Kind         int
+	StartTime    int64
+	EndTime      int64
+	AttrList     []Attribute
+	ended        bool
+}
+
+// Context 获取用于向下游传递的链路上下文 span为nil时返回空上下文
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceId: s.TraceId, SpanId: s.SpanId}
+}
+
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.AttrList = append(s.AttrList, Attribute{Key: key, Value: value})
+}
+
+// End 结束并导出 重复调用无效
+func (s *Span) End() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now().UnixNano()
+	s.tracer.exporter.Export(s)
+}
+
+// Exporter Span导出器
+type Exporter interface {
+	Export(span *Span)
+	Close()
+}
+
+// Tracer 链路追踪器
+type Tracer struct {
+	serviceName   string
+	exporter      Exporter
+	sampleUidMap  map[uint32]struct{}
+	sampleRateMax uint32
+}
+
+func NewTracer(serviceName string, exporter Exporter, sampleUid string, sampleRate float64) *Tracer {
+	t := new(Tracer)
+	t.serviceName = serviceName
+	t.exporter = exporter
+	t.sampleUidMap = make(map[uint32]struct{})
+	for _, uidStr := range strings.Split(sampleUid, ",") {
+		uid, err := strconv.ParseUint(strings.TrimSpace(uidStr), 10, 32)
+		if err != nil {
+			continue
+		}
+		t.sampleUidMap[uint32(uid)] = struct{}{}
+	}
+	if sampleRate < 0 {
+		sampleRate = 0
+	}
+	if sampleRate > 1 {
+		sampleRate = 1
+	}
+	t.sampleRateMax = uint32(sampleRate * 10000)
+	return t
+}
+
+// ShouldSample 按玩家uid采样 同一个uid的判定结果固定 保证一个玩家的链路完整
+func (t *Tracer) ShouldSample(uid uint32) bool {
+	if _, exist := t.sampleUidMap[uid]; exist {
+		return true
+	}
+	if t.sampleRateMax == 0 {
+		return false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.FormatUint(uint64(uid), 10)))
+	return h.Sum32()%10000 < t.sampleRateMax
+}
+
+// StartSpan 开始一个Span parent无效时作为新链路的根
+func (t *Tracer) StartSpan(name string, kind int, parent SpanContext) *Span {
+	span := &Span{
+		tracer:    t,
+		SpanId:    NewSpanId(),
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now().UnixNano(),
+	}
+	if parent.IsValid() {
+		span.TraceId = parent.TraceId
+		span.ParentSpanId = parent.SpanId
+	} else {
+		span.TraceId = NewTraceId()
+	}
+	return span
+}
+
+func (t *Tracer) Close() {
+	t.exporter.Close()
+}
+
+func NewTraceId() string {
+	return randomHex(16)
+}
+
+func NewSpanId() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	data := make([]byte, n)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+var (
+	TRACER     atomic.Pointer[Tracer]
+	tracerLock sync.Mutex
+)
+
+// InitTracer 初始化进程内全局的链路追踪器 未开启时所有接口均为空操作
+func InitTracer(cfg *Config) error {
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	if TRACER.Load() != nil {
+		return nil
+	}
+	var exporter Exporter = nil
+	switch cfg.Exporter {
+	case "":
+		return nil
+	case ExporterStdout:
+		exporter = NewStdoutExporter(cfg.ServiceName)
+	case ExporterFile:
+		filePath := cfg.FilePath
+		if filePath == "" {
+			filePath = DefaultFilePath
+		}
+		fileExporter, err := NewFileExporter(cfg.ServiceName, filePath)
+		if err != nil {
+			return err
+		}
+		exporter = fileExporter
+	default:
+		return errors.New("unknown trace exporter: " + cfg.Exporter)
+	}
+	TRACER.Store(NewTracer(cfg.ServiceName, exporter, cfg.SampleUid, cfg.SampleRate))
+	return nil
+}
+
+// CloseTracer 导出剩余的Span并关闭
+func CloseTracer() {
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	tracer := TRACER.Swap(nil)
+	if tracer != nil {
+		tracer.Close()
+	}
+}
+
+// ShouldSample 全局追踪器未开启时不采样
+func ShouldSample(uid uint32) bool {
+	tracer := TRACER.Load()
+	if tracer == nil {
+		return false
+	}
+	return tracer.ShouldSample(uid)
+}
+
+// StartSpan 使用全局追踪器开始一个Span 未开启时返回nil nil的Span可以安全调用所有方法
+func StartSpan(name string, kind int, parent SpanContext) *Span {
+	tracer := TRACER.Load()
+	if tracer == nil {
+		return nil
+	}
+	return tracer.StartSpan(name, kind, parent)
+}
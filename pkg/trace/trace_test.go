@@ -0,0 +1,120 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testExporter struct {
+	spanList []*Span
+}
+
+func (e *testExporter) Export(span *Span) {
+	e.spanList = append(e.spanList, span)
+}
+
+func (e *testExporter) Close() {
+}
+
+func TestShouldSample(t *testing.T) {
+	tracer := NewTracer("test", new(testExporter), "10001, 10002,abc", 0)
+	if !tracer.ShouldSample(10001) || !tracer.ShouldSample(10002) || tracer.ShouldSample(10003) {
+		t.Fatal("sample uid list error")
+	}
+	tracer = NewTracer("test", new(testExporter), "", 0.5)
+	sampleCount := 0
+	for uid := uint32(10000); uid < 20000; uid++ {
+		sample := tracer.ShouldSample(uid)
+		// 同一个uid的采样结果固定
+		if sample != tracer.ShouldSample(uid) {
+			t.Fatalf("sample result not stable, uid: %v", uid)
+		}
+		if sample {
+			sampleCount++
+		}
+	}
+	if sampleCount < 4000 || sampleCount > 6000 {
+		t.Fatalf("sample rate error, count: %v", sampleCount)
+	}
+	if NewTracer("test", new(testExporter), "", 1).ShouldSample(1) != true {
+		t.Fatal("sample rate 1 should sample all")
+	}
+}
+
+func TestSpan(t *testing.T) {
+	exporter := new(testExporter)
+	tracer := NewTracer("test", exporter, "", 0)
+	root := tracer.StartSpan("gate.forward", SpanKindProducer, SpanContext{})
+	child := tracer.StartSpan("gs.route", SpanKindServer, root.Context())
+	child.SetAttr("uid", uint32(10001))
+	child.End()
+	child.End()
+	root.End()
+	if len(exporter.spanList) != 2 {
+		t.Fatalf("span export count error: %v", len(exporter.spanList))
+	}
+	if len(root.TraceId) != 32 || len(root.SpanId) != 16 || root.ParentSpanId != "" {
+		t.Fatalf("root span id error: %+v", root)
+	}
+	if child.TraceId != root.TraceId || child.ParentSpanId != root.SpanId || child.SpanId == root.SpanId {
+		t.Fatalf("child span not link to parent, root: %+v, child: %+v", root, child)
+	}
+	// 未开启时nil的Span可以安全使用
+	var span *Span = StartSpan("disable", SpanKindInternal, SpanContext{})
+	span.SetAttr("key", "value")
+	span.End()
+	if span.Context().IsValid() || ShouldSample(10001) {
+		t.Fatal("tracer should be disable")
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "trace.jsonl")
+	err := InitTracer(&Config{ServiceName: "gs_1", Exporter: ExporterFile, FilePath: filePath, SampleUid: "10001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ShouldSample(10001) {
+		t.Fatal("uid should be sample")
+	}
+	span := StartSpan("gs.route.PingReq", SpanKindServer, SpanContext{TraceId: NewTraceId(), SpanId: NewSpanId()})
+	span.SetAttr("uid", uint32(10001))
+	span.SetAttr("origin_appid", "abc")
+	span.End()
+	CloseTracer()
+	if TRACER.Load() != nil {
+		t.Fatal("tracer not close")
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineCount := 0
+	for scanner.Scan() {
+		lineCount++
+		req := new(OtlpRequest)
+		err = json.Unmarshal(scanner.Bytes(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resourceSpans := req.ResourceSpans[0]
+		if *resourceSpans.Resource.Attributes[0].Value.StringValue != "gs_1" {
+			t.Fatalf("service name error: %s", scanner.Text())
+		}
+		otlpSpan := resourceSpans.ScopeSpans[0].Spans[0]
+		if otlpSpan.TraceId != span.TraceId || otlpSpan.ParentSpanId != span.ParentSpanId || otlpSpan.Kind != SpanKindServer {
+			t.Fatalf("span export error: %s", scanner.Text())
+		}
+		if otlpSpan.Attributes[0].Key != "uid" || *otlpSpan.Attributes[0].Value.IntValue != "10001" {
+			t.Fatalf("span attr export error: %s", scanner.Text())
+		}
+	}
+	if lineCount != 1 {
+		t.Fatalf("export line count error: %v", lineCount)
+	}
+}
@@ -0,0 +1,253 @@
+package wordfilter
+
+import (
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// 基于AC自动机的敏感词过滤器
+// 匹配前会对文本进行归一化 全角半角统一折叠 大写转小写 忽略空白和标点符号
+// 归一化时保留原文本的单词边界 字母数字组成的单词内的命中必须从单词边界开始并在单词边界结束
+// 相邻的命中可以互为边界 如shitshit 中日文字符之间总是视为单词边界
+// 白名单中的词完整覆盖的敏感词命中不计入
+
+type acNode struct {
+	childMap      map[rune]*acNode
+	fail          *acNode
+	outputLenList []int // 以当前节点结尾的词的长度
+}
+
+func newAcNode() *acNode {
+	return &acNode{
+		childMap:      make(map[rune]*acNode),
+		fail:          nil,
+		outputLenList: nil,
+	}
+}
+
+type acAutomaton struct {
+	root *acNode
+}
+
+func newAcAutomaton(wordList []string) *acAutomaton {
+	a := &acAutomaton{
+		root: newAcNode(),
+	}
+	for _, word := range wordList {
+		a.insert(word)
+	}
+	a.build()
+	return a
+}
+
+func (a *acAutomaton) insert(word string) {
+	runeList := Normalize(word)
+	if len(runeList) == 0 {
+		return
+	}
+	node := a.root
+	for _, r := range runeList {
+		child, exist := node.childMap[r]
+		if !exist {
+			child = newAcNode()
+			node.childMap[r] = child
+		}
+		node = child
+	}
+	for _, outputLen := range node.outputLenList {
+		if outputLen == len(runeList) {
+			return
+		}
+	}
+	node.outputLenList = append(node.outputLenList, len(runeList))
+}
+
+// 广度优先构建失配指针
+func (a *acAutomaton) build() {
+	queue := make([]*acNode, 0)
+	for _, child := range a.root.childMap {
+		child.fail = a.root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range node.childMap {
+			fail := node.fail
+			for fail != nil {
+				next, exist := fail.childMap[r]
+				if exist {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if child.fail == nil {
+				child.fail = a.root
+			}
+			child.outputLenList = append(child.outputLenList, child.fail.outputLenList...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// 查找全部命中 返回归一化后的文本中的闭区间
+func (a *acAutomaton) search(runeList []rune) [][2]int {
+	ret := make([][2]int, 0)
+	node := a.root
+	for index, r := range runeList {
+		for node != a.root {
+			if _, exist := node.childMap[r]; exist {
+				break
+			}
+			node = node.fail
+		}
+		next, exist := node.childMap[r]
+		if !exist {
+			continue
+		}
+		node = next
+		for _, outputLen := range node.outputLenList {
+			ret = append(ret, [2]int{index - outputLen + 1, index})
+		}
+	}
+	return ret
+}
+
+// NormalizeRune 单个字符归一化 返回0表示匹配时忽略该字符
+func NormalizeRune(r rune) rune {
+	if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsControl(r) {
+		return 0
+	}
+	folded := width.LookupRune(r).Folded()
+	if folded != 0 {
+		r = folded
+	}
+	return unicode.ToLower(r)
+}
+
+// Normalize 文本归一化 全角半角统一折叠 大写转小写 去除空白和标点符号
+func Normalize(text string) []rune {
+	runeList := make([]rune, 0, len(text))
+	for _, r := range text {
+		r = NormalizeRune(r)
+		if r == 0 {
+			continue
+		}
+		runeList = append(runeList, r)
+	}
+	return runeList
+}
+
+// 是否为需要空格分词的单词字符 中日文字符之间没有空格 每个字符都视为独立的单词
+func isWordRune(r rune) bool {
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return false
+	}
+	return !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+type Filter struct {
+	word  *acAutomaton
+	allow *acAutomaton
+}
+
+// NewFilter 创建过滤器 wordList为敏感词列表 allowList为白名单列表
+func NewFilter(wordList []string, allowList []string) *Filter {
+	return &Filter{
+		word:  newAcAutomaton(wordList),
+		allow: newAcAutomaton(allowList),
+	}
+}
+
+// 查找全部有效命中 返回原文本中的字符闭区间
+func (f *Filter) match(srcRuneList []rune) [][2]int {
+	runeList := make([]rune, 0, len(srcRuneList))
+	indexList := make([]int, 0, len(srcRuneList))
+	// boundaryList[i]表示归一化后的第i个字符之前是否为单词边界 最后一个元素表示文本末尾
+	boundaryList := make([]bool, 0, len(srcRuneList)+1)
+	skip := true
+	for index, r := range srcRuneList {
+		r = NormalizeRune(r)
+		if r == 0 {
+			skip = true
+			continue
+		}
+		boundary := skip
+		if !boundary && (!isWordRune(runeList[len(runeList)-1]) || !isWordRune(r)) {
+			boundary = true
+		}
+		runeList = append(runeList, r)
+		indexList = append(indexList, index)
+		boundaryList = append(boundaryList, boundary)
+		skip = false
+	}
+	boundaryList = append(boundaryList, true)
+	wordMatchList := f.word.search(runeList)
+	if len(wordMatchList) == 0 {
+		return nil
+	}
+	allowMatchList := f.allow.search(runeList)
+	candidateList := make([][2]int, 0, len(wordMatchList))
+	for _, wordMatch := range wordMatchList {
+		allow := false
+		for _, allowMatch := range allowMatchList {
+			if allowMatch[0] <= wordMatch[0] && wordMatch[1] <= allowMatch[1] {
+				allow = true
+				break
+			}
+		}
+		if allow {
+			continue
+		}
+		candidateList = append(candidateList, wordMatch)
+	}
+	// 反复剔除两端不在单词边界且没有相邻命中的候选 直到不再变化
+	for {
+		startMap := make(map[int]bool)
+		endMap := make(map[int]bool)
+		for _, candidate := range candidateList {
+			startMap[candidate[0]] = true
+			endMap[candidate[1]] = true
+		}
+		validList := make([][2]int, 0, len(candidateList))
+		for _, candidate := range candidateList {
+			leftOk := boundaryList[candidate[0]] || endMap[candidate[0]-1]
+			rightOk := boundaryList[candidate[1]+1] || startMap[candidate[1]+1]
+			if !leftOk || !rightOk {
+				continue
+			}
+			validList = append(validList, candidate)
+		}
+		if len(validList) == len(candidateList) {
+			break
+		}
+		candidateList = validList
+	}
+	ret := make([][2]int, 0, len(candidateList))
+	for _, candidate := range candidateList {
+		ret = append(ret, [2]int{indexList[candidate[0]], indexList[candidate[1]]})
+	}
+	return ret
+}
+
+// Contains 文本中是否含有敏感词
+func (f *Filter) Contains(text string) bool {
+	return len(f.match([]rune(text))) != 0
+}
+
+// Replace 将文本中的敏感词替换为掩码字符 被忽略的夹在敏感词中间的字符一并替换
+func (f *Filter) Replace(text string, mask rune) string {
+	runeList := []rune(text)
+	matchList := f.match(runeList)
+	if len(matchList) == 0 {
+		return text
+	}
+	for _, match := range matchList {
+		for index := match[0]; index <= match[1]; index++ {
+			runeList[index] = mask
+		}
+	}
+	return string(runeList)
+}
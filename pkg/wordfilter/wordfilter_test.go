@@ -0,0 +1,57 @@
+package wordfilter
+
+import (
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	filter := NewFilter([]string{"bad", "坏人", "shit", "he"}, []string{"shell", "badminton"})
+	testCaseList := []struct {
+		text     string
+		contains bool
+		replace  string
+	}{
+		{"good world", false, "good world"},
+		{"you are bad", true, "you are ***"},
+		{"YOU ARE BAD", true, "YOU ARE ***"},
+		{"ｙｏｕ ａｒｅ ｂａｄ", true, "ｙｏｕ ａｒｅ ＊＊＊"},
+		{"you are ｂad", true, "you are ***"},
+		{"b a-d", true, "*****"},
+		{"他是坏人", true, "他是**"},
+		{"他是坏 人!", true, "他是***!"},
+		{"play badminton", false, "play badminton"},
+		{"sea shell", false, "sea shell"},
+		{"the bad shell", true, "the *** shell"},
+		{"shitshit", true, "********"},
+		{"heshit", true, "******"},
+		{"shitty", false, "shitty"},
+		{"sub adult", false, "sub adult"},
+		{"bad坏人he", true, "*******"},
+		{"他说he坏人", true, "他说****"},
+	}
+	for _, testCase := range testCaseList {
+		if filter.Contains(testCase.text) != testCase.contains {
+			t.Fatalf("contains error, text: %v", testCase.text)
+		}
+		mask := '*'
+		for _, r := range testCase.replace {
+			if r == '＊' {
+				mask = '＊'
+				break
+			}
+		}
+		replace := filter.Replace(testCase.text, mask)
+		if replace != testCase.replace {
+			t.Fatalf("replace error, text: %v, replace: %v, expect: %v", testCase.text, replace, testCase.replace)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if string(Normalize("Ｈｅｌｌｏ，　Ｗｏｒｌｄ！")) != "helloworld" {
+		t.Fatal("full width normalize error")
+	}
+	if string(Normalize("ﾃｽﾄ")) != "テスト" {
+		t.Fatal("half width katakana normalize error")
+	}
+}
@@ -75,6 +75,8 @@ func (c *CmdProtoMap) registerMessage() {
 	c.regMsg(GmTalkNotify, func() any { return new(proto.GmTalkNotify) })                             // GM命令执行通知
 	c.regMsg(SetPlayerPropReq, func() any { return new(proto.SetPlayerPropReq) })                     // 设置玩家属性表请求
 	c.regMsg(SetPlayerPropRsp, func() any { return new(proto.SetPlayerPropRsp) })                     // 设置玩家属性表响应
+	c.regMsg(AdjustWorldLevelReq, func() any { return new(proto.AdjustWorldLevelReq) })               // 调整世界等级请求
+	c.regMsg(AdjustWorldLevelRsp, func() any { return new(proto.AdjustWorldLevelRsp) })               // 调整世界等级响应
 	c.regMsg(SetOpenStateReq, func() any { return new(proto.SetOpenStateReq) })                       // 设置功能开放状态请求
 	c.regMsg(SetOpenStateRsp, func() any { return new(proto.SetOpenStateRsp) })                       // 设置功能开放状态响应
 
@@ -156,6 +158,7 @@ func (c *CmdProtoMap) registerMessage() {
 	c.regMsg(PlayerQuitDungeonRsp, func() any { return new(proto.PlayerQuitDungeonRsp) })                     // 退出地牢响应
 	c.regMsg(DungeonDataNotify, func() any { return new(proto.DungeonDataNotify) })                           // 地牢数据通知
 	c.regMsg(DungeonWayPointNotify, func() any { return new(proto.DungeonWayPointNotify) })                   // 地牢路点通知
+	c.regMsg(DungeonSettleNotify, func() any { return new(proto.DungeonSettleNotify) })                       // 地牢结算通知
 	c.regMsg(GadgetInteractReq, func() any { return new(proto.GadgetInteractReq) })                           // 物件交互请求
 	c.regMsg(GadgetInteractRsp, func() any { return new(proto.GadgetInteractRsp) })                           // 物件交互响应
 	c.regMsg(GadgetStateNotify, func() any { return new(proto.GadgetStateNotify) })                           // 物件状态更新通知
@@ -220,6 +223,7 @@ func (c *CmdProtoMap) registerMessage() {
 	c.regMsg(ChangeMpTeamAvatarRsp, func() any { return new(proto.ChangeMpTeamAvatarRsp) })                 // 配置多人游戏队伍响应
 	c.regMsg(AvatarTeamUpdateNotify, func() any { return new(proto.AvatarTeamUpdateNotify) })               // 角色队伍更新通知 全部队伍的名字和其中中包含了哪些角色
 	c.regMsg(SceneTeamUpdateNotify, func() any { return new(proto.SceneTeamUpdateNotify) })                 // 场景队伍更新通知
+	c.regMsg(TeamResonanceChangeNotify, func() any { return new(proto.TeamResonanceChangeNotify) })         // 队伍元素共鸣变更通知
 	c.regMsg(SyncTeamEntityNotify, func() any { return new(proto.SyncTeamEntityNotify) })                   // 同步队伍实体通知
 	c.regMsg(DelTeamEntityNotify, func() any { return new(proto.DelTeamEntityNotify) })                     // 删除队伍实体通知
 	c.regMsg(SyncScenePlayTeamEntityNotify, func() any { return new(proto.SyncScenePlayTeamEntityNotify) }) // 同步场景玩家队伍实体通知
@@ -317,6 +321,7 @@ func (c *CmdProtoMap) registerMessage() {
 	// 角色
 	c.regMsg(AvatarDataNotify, func() any { return new(proto.AvatarDataNotify) })                         // 角色信息通知
 	c.regMsg(AvatarAddNotify, func() any { return new(proto.AvatarAddNotify) })                           // 角色新增通知
+	c.regMsg(AvatarDelNotify, func() any { return new(proto.AvatarDelNotify) })                           // 角色删除通知
 	c.regMsg(AvatarLifeStateChangeNotify, func() any { return new(proto.AvatarLifeStateChangeNotify) })   // 角色存活状态改变通知
 	c.regMsg(AvatarUpgradeReq, func() any { return new(proto.AvatarUpgradeReq) })                         // 角色升级请求
 	c.regMsg(AvatarUpgradeRsp, func() any { return new(proto.AvatarUpgradeRsp) })                         // 角色升级响应
@@ -341,6 +346,9 @@ func (c *CmdProtoMap) registerMessage() {
 	c.regMsg(UnlockAvatarTalentRsp, func() any { return new(proto.UnlockAvatarTalentRsp) })               // 角色命座解锁通知
 	c.regMsg(AvatarUnlockTalentNotify, func() any { return new(proto.AvatarUnlockTalentNotify) })         // 角色命座解锁通知
 	c.regMsg(AddNoGachaAvatarCardNotify, func() any { return new(proto.AddNoGachaAvatarCardNotify) })     // 获得非抽卡角色通知
+	c.regMsg(AvatarFetterDataNotify, func() any { return new(proto.AvatarFetterDataNotify) })             // 角色好感度数据通知
+	c.regMsg(AvatarFetterLevelRewardReq, func() any { return new(proto.AvatarFetterLevelRewardReq) })     // 角色好感度等级奖励领取请求
+	c.regMsg(AvatarFetterLevelRewardRsp, func() any { return new(proto.AvatarFetterLevelRewardRsp) })     // 角色好感度等级奖励领取响应
 
 	// 背包与道具
 	c.regMsg(PlayerStoreNotify, func() any { return new(proto.PlayerStoreNotify) })           // 玩家背包数据通知
@@ -474,6 +482,67 @@ func (c *CmdProtoMap) registerMessage() {
 	c.regMsg(GetMailItemRsp, func() any { return new(proto.GetMailItemRsp) })                 // 邮件领奖响应
 	c.regMsg(ReadMailNotify, func() any { return new(proto.ReadMailNotify) })                 // 邮件阅读通知
 	c.regMsg(ChangeMailStarNotify, func() any { return new(proto.ChangeMailStarNotify) })     // 邮件收藏通知
+
+	// 签到
+	c.regMsg(SignInInfoReq, func() any { return new(proto.SignInInfoReq) })           // 获取签到信息请求
+	c.regMsg(SignInInfoRsp, func() any { return new(proto.SignInInfoRsp) })           // 获取签到信息响应
+	c.regMsg(GetSignInRewardReq, func() any { return new(proto.GetSignInRewardReq) }) // 领取签到奖励请求
+	c.regMsg(GetSignInRewardRsp, func() any { return new(proto.GetSignInRewardRsp) }) // 领取签到奖励响应
+
+	// 图鉴
+	c.regMsg(CodexDataFullNotify, func() any { return new(proto.CodexDataFullNotify) })                         // 图鉴全量数据通知
+	c.regMsg(CodexDataUpdateNotify, func() any { return new(proto.CodexDataUpdateNotify) })                     // 图鉴解锁通知
+	c.regMsg(QueryCodexMonsterBeKilledNumReq, func() any { return new(proto.QueryCodexMonsterBeKilledNumReq) }) // 查询怪物击杀数量请求
+	c.regMsg(QueryCodexMonsterBeKilledNumRsp, func() any { return new(proto.QueryCodexMonsterBeKilledNumRsp) }) // 查询怪物击杀数量响应
+	c.regMsg(ViewCodexReq, func() any { return new(proto.ViewCodexReq) })                                       // 查看图鉴请求
+	c.regMsg(ViewCodexRsp, func() any { return new(proto.ViewCodexRsp) })                                       // 查看图鉴响应
+
+	// 活动
+	c.regMsg(GetActivityScheduleReq, func() any { return new(proto.GetActivityScheduleReq) })                       // 获取活动时间表请求
+	c.regMsg(GetActivityScheduleRsp, func() any { return new(proto.GetActivityScheduleRsp) })                       // 获取活动时间表响应
+	c.regMsg(GetActivityInfoReq, func() any { return new(proto.GetActivityInfoReq) })                               // 获取活动信息请求
+	c.regMsg(GetActivityInfoRsp, func() any { return new(proto.GetActivityInfoRsp) })                               // 获取活动信息响应
+	c.regMsg(ActivityTakeWatcherRewardReq, func() any { return new(proto.ActivityTakeWatcherRewardReq) })           // 领取活动目标奖励请求
+	c.regMsg(ActivityTakeWatcherRewardRsp, func() any { return new(proto.ActivityTakeWatcherRewardRsp) })           // 领取活动目标奖励响应
+	c.regMsg(ActivityTakeWatcherRewardBatchReq, func() any { return new(proto.ActivityTakeWatcherRewardBatchReq) }) // 批量领取活动目标奖励请求
+	c.regMsg(ActivityTakeWatcherRewardBatchRsp, func() any { return new(proto.ActivityTakeWatcherRewardBatchRsp) }) // 批量领取活动目标奖励响应
+	c.regMsg(ActivityInfoNotify, func() any { return new(proto.ActivityInfoNotify) })                               // 活动信息通知
+	c.regMsg(ActivityScheduleInfoNotify, func() any { return new(proto.ActivityScheduleInfoNotify) })               // 活动时间表通知
+	c.regMsg(ActivityUpdateWatcherNotify, func() any { return new(proto.ActivityUpdateWatcherNotify) })             // 活动目标进度更新通知
+	c.regMsg(SalesmanDeliverItemReq, func() any { return new(proto.SalesmanDeliverItemReq) })                       // 商人活动交付物品请求
+	c.regMsg(SalesmanDeliverItemRsp, func() any { return new(proto.SalesmanDeliverItemRsp) })                       // 商人活动交付物品响应
+	c.regMsg(SalesmanTakeSpecialRewardReq, func() any { return new(proto.SalesmanTakeSpecialRewardReq) })           // 商人活动领取特殊奖励请求
+	c.regMsg(SalesmanTakeSpecialRewardRsp, func() any { return new(proto.SalesmanTakeSpecialRewardRsp) })           // 商人活动领取特殊奖励响应
+
+	// 充值
+	c.regMsg(RechargeReq, func() any { return new(proto.RechargeReq) })                           // 充值下单请求
+	c.regMsg(RechargeRsp, func() any { return new(proto.RechargeRsp) })                           // 充值下单响应
+	c.regMsg(PlayerRechargeDataNotify, func() any { return new(proto.PlayerRechargeDataNotify) }) // 玩家充值数据通知
+	c.regMsg(OrderFinishNotify, func() any { return new(proto.OrderFinishNotify) })               // 订单完成通知
+	c.regMsg(OrderDisplayNotify, func() any { return new(proto.OrderDisplayNotify) })             // 订单展示通知
+
+	// 回归
+	c.regMsg(ReunionBriefInfoReq, func() any { return new(proto.ReunionBriefInfoReq) })                     // 获取回归概要信息请求
+	c.regMsg(ReunionBriefInfoRsp, func() any { return new(proto.ReunionBriefInfoRsp) })                     // 获取回归概要信息响应
+	c.regMsg(ReunionActivateNotify, func() any { return new(proto.ReunionActivateNotify) })                 // 回归激活通知
+	c.regMsg(ReunionDailyRefreshNotify, func() any { return new(proto.ReunionDailyRefreshNotify) })         // 回归每日刷新通知
+	c.regMsg(ReunionPrivilegeChangeNotify, func() any { return new(proto.ReunionPrivilegeChangeNotify) })   // 回归特权变化通知
+	c.regMsg(ReunionSettleNotify, func() any { return new(proto.ReunionSettleNotify) })                     // 回归结束通知
+	c.regMsg(TakeReunionFirstGiftRewardReq, func() any { return new(proto.TakeReunionFirstGiftRewardReq) }) // 领取回归首日礼包请求
+	c.regMsg(TakeReunionFirstGiftRewardRsp, func() any { return new(proto.TakeReunionFirstGiftRewardRsp) }) // 领取回归首日礼包响应
+	c.regMsg(GetReunionSignInInfoReq, func() any { return new(proto.GetReunionSignInInfoReq) })             // 获取回归签到信息请求
+	c.regMsg(GetReunionSignInInfoRsp, func() any { return new(proto.GetReunionSignInInfoRsp) })             // 获取回归签到信息响应
+	c.regMsg(TakeReunionSignInRewardReq, func() any { return new(proto.TakeReunionSignInRewardReq) })       // 领取回归签到奖励请求
+	c.regMsg(TakeReunionSignInRewardRsp, func() any { return new(proto.TakeReunionSignInRewardRsp) })       // 领取回归签到奖励响应
+	c.regMsg(GetReunionMissionInfoReq, func() any { return new(proto.GetReunionMissionInfoReq) })           // 获取回归任务信息请求
+	c.regMsg(GetReunionMissionInfoRsp, func() any { return new(proto.GetReunionMissionInfoRsp) })           // 获取回归任务信息响应
+	c.regMsg(TakeReunionWatcherRewardReq, func() any { return new(proto.TakeReunionWatcherRewardReq) })     // 领取回归任务目标奖励请求
+	c.regMsg(TakeReunionWatcherRewardRsp, func() any { return new(proto.TakeReunionWatcherRewardRsp) })     // 领取回归任务目标奖励响应
+	c.regMsg(TakeReunionMissionRewardReq, func() any { return new(proto.TakeReunionMissionRewardReq) })     // 领取回归任务奖励请求
+	c.regMsg(TakeReunionMissionRewardRsp, func() any { return new(proto.TakeReunionMissionRewardRsp) })     // 领取回归任务奖励响应
+	c.regMsg(UpdateReunionWatcherNotify, func() any { return new(proto.UpdateReunionWatcherNotify) })       // 回归任务目标进度更新通知
+	c.regMsg(GetReunionPrivilegeInfoReq, func() any { return new(proto.GetReunionPrivilegeInfoReq) })       // 获取回归特权信息请求
+	c.regMsg(GetReunionPrivilegeInfoRsp, func() any { return new(proto.GetReunionPrivilegeInfoRsp) })       // 获取回归特权信息响应
 }
 
 func (c *CmdProtoMap) regMsg(cmdId uint16, protoObjNewFunc func() any) {
@@ -2,22 +2,18 @@ package app
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/hex"
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"hk4e/common/config"
-	"hk4e/pkg/endec"
-	"hk4e/protocol/cmd"
-	"hk4e/protocol/proto"
 	"hk4e/robot/client"
 	"hk4e/robot/login"
+	"hk4e/robot/scenario"
 
 	"github.com/flswld/halo/logger"
 )
@@ -42,6 +38,11 @@ func Run(ctx context.Context) error {
 		logger.Warn("robot exit")
 	}()
 
+	if config.GetConfig().Hk4eRobot.ScenarioFile != "" {
+		// 场景测试执行完成后退出
+		return runScenario()
+	}
+
 	go runRobot()
 
 	c := make(chan os.Signal, 1)
@@ -65,6 +66,20 @@ func Run(ctx context.Context) error {
 	}
 }
 
+func runScenario() error {
+	report, err := scenario.Run(config.GetConfig().Hk4eRobot.ScenarioFile,
+		config.GetConfig().Hk4eRobot.Account,
+		int(config.GetConfig().Hk4eRobot.ScenarioRobotNum))
+	if err != nil {
+		return err
+	}
+	fmt.Print(report.String())
+	if !report.IsPass() {
+		return scenario.ErrScenarioFail
+	}
+	return nil
+}
+
 func runRobot() {
 	if config.GetConfig().Hk4eRobot.DosEnable {
 		dosBatchNum := int(config.GetConfig().Hk4eRobot.DosBatchNum)
@@ -122,27 +137,10 @@ func gateLogin(account string, dispatchInfo *login.DispatchInfo, accountInfo *lo
 		return
 	}
 	logger.Info("robot gate login ok, account: %v", account)
-	clientVersionHashData, err := hex.DecodeString(
-		endec.Sha1Str(config.GetConfig().Hk4eRobot.ClientVersion + session.ClientVersionRandomKey + "mhy2020"),
-	)
+	err = login.PlayerLogin(session, accountInfo, config.GetConfig().Hk4eRobot.ClientVersion)
 	if err != nil {
-		logger.Error("gen clientVersionHashData error: %v", err)
+		logger.Error("player login error: %v", err)
 		return
 	}
-	checksumClientVersion := strings.Split(config.GetConfig().Hk4eRobot.ClientVersion, "_")[0]
-	session.SendMsg(cmd.PlayerLoginReq, &proto.PlayerLoginReq{
-		AccountType:           1,
-		SubChannelId:          1,
-		LanguageType:          2,
-		PlatformType:          3,
-		Checksum:              "$008094416f86a051270e64eb0b405a38825",
-		ChecksumClientVersion: checksumClientVersion,
-		ClientDataVersion:     11793813,
-		ClientVerisonHash:     base64.StdEncoding.EncodeToString(clientVersionHashData),
-		ClientVersion:         config.GetConfig().Hk4eRobot.ClientVersion,
-		SecurityCmdReply:      session.SecurityCmdBuffer,
-		SecurityLibraryMd5:    "574a507ffee2eb6f997d11f71c8ae1fa",
-		Token:                 accountInfo.ComboToken,
-	})
 	client.Logic(account, session)
 }
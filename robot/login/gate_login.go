@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"hk4e/common/region"
 	"hk4e/pkg/endec"
@@ -131,3 +133,30 @@ func GateLogin(dispatchInfo *DispatchInfo, accountInfo *AccountInfo, keyId strin
 	session.SecurityCmdBuffer = getPlayerTokenRsp.SecurityCmdBuffer
 	return session, nil
 }
+
+// PlayerLogin 网关登录成功后发送gs登录请求
+func PlayerLogin(session *net.Session, accountInfo *AccountInfo, clientVersion string) error {
+	clientVersionHashData, err := hex.DecodeString(
+		endec.Sha1Str(clientVersion + session.ClientVersionRandomKey + "mhy2020"),
+	)
+	if err != nil {
+		logger.Error("gen clientVersionHashData error: %v", err)
+		return err
+	}
+	checksumClientVersion := strings.Split(clientVersion, "_")[0]
+	session.SendMsg(cmd.PlayerLoginReq, &proto.PlayerLoginReq{
+		AccountType:           1,
+		SubChannelId:          1,
+		LanguageType:          2,
+		PlatformType:          3,
+		Checksum:              "$008094416f86a051270e64eb0b405a38825",
+		ChecksumClientVersion: checksumClientVersion,
+		ClientDataVersion:     11793813,
+		ClientVerisonHash:     base64.StdEncoding.EncodeToString(clientVersionHashData),
+		ClientVersion:         clientVersion,
+		SecurityCmdReply:      session.SecurityCmdBuffer,
+		SecurityLibraryMd5:    "574a507ffee2eb6f997d11f71c8ae1fa",
+		Token:                 accountInfo.ComboToken,
+	})
+	return nil
+}
@@ -1,6 +1,9 @@
 package net
 
 import (
+	"encoding/binary"
+	"errors"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,7 +20,8 @@ import (
 )
 
 type Session struct {
-	Conn                   *kcp.UDPSession
+	Conn                   hk4egatenet.Conn
+	IsTcp                  bool
 	XorKey                 []byte
 	SendChan               chan *hk4egatenet.ProtoMsg
 	RecvChan               chan *hk4egatenet.ProtoMsg
@@ -31,19 +35,33 @@ type Session struct {
 	CloseOnce              sync.Once
 }
 
+// NewSession 连接网关 开启tcp模式时使用tcp连接
 func NewSession(gateAddr string, dispatchKey []byte) (*Session, error) {
-	conn, err := kcp.DialKCP(gateAddr)
-	if err != nil {
-		logger.Error("kcp client conn to server error: %v", err)
-		return nil, err
+	var conn hk4egatenet.Conn = nil
+	isTcp := config.GetConfig().Hk4e.TcpModeEnable
+	if isTcp {
+		tcpConn, err := net.DialTimeout("tcp4", gateAddr, time.Second*hk4egatenet.ConnSendTimeout)
+		if err != nil {
+			logger.Error("tcp client conn to server error: %v", err)
+			return nil, err
+		}
+		conn = hk4egatenet.NewTcpConn(tcpConn.(*net.TCPConn))
+	} else {
+		kcpConn, err := kcp.DialKCP(gateAddr)
+		if err != nil {
+			logger.Error("kcp client conn to server error: %v", err)
+			return nil, err
+		}
+		kcp.SetByteCheckMode(int(config.GetConfig().Hk4e.ByteCheckMode))
+		kcpConn.SetACKNoDelay(true)
+		kcpConn.SetWriteDelay(false)
+		kcpConn.SetWindowSize(256, 256)
+		kcpConn.SetMtu(1200)
+		conn = kcpConn
 	}
-	kcp.SetByteCheckMode(int(config.GetConfig().Hk4e.ByteCheckMode))
-	conn.SetACKNoDelay(true)
-	conn.SetWriteDelay(false)
-	conn.SetWindowSize(256, 256)
-	conn.SetMtu(1200)
 	r := &Session{
 		Conn:                   conn,
+		IsTcp:                  isTcp,
 		XorKey:                 dispatchKey,
 		SendChan:               make(chan *hk4egatenet.ProtoMsg, 1000),
 		RecvChan:               make(chan *hk4egatenet.ProtoMsg, 1000),
@@ -78,7 +96,7 @@ func (s *Session) SendMsg(cmdId uint16, msg pb.Message) {
 
 func (s *Session) Close() {
 	s.CloseOnce.Do(func() {
-		_ = s.Conn.Close(kcp.EnetClientClose)
+		_ = s.Conn.CloseReason(kcp.EnetClientClose)
 		close(s.DeadEvent)
 	})
 }
@@ -89,14 +107,28 @@ func (s *Session) recvHandle() {
 	convId := conn.GetConv()
 	recvBuf := make([]byte, hk4egatenet.PacketMaxLen)
 	for {
-		_ = conn.SetReadDeadline(time.Now().Add(time.Second * hk4egatenet.ConnRecvTimeout))
-		recvLen, err := conn.Read(recvBuf)
-		if err != nil {
-			logger.Error("exit recv loop, conn read err: %v, convId: %v", err, convId)
-			s.Close()
-			break
+		var recvData []byte = nil
+		if s.IsTcp {
+			data, err := s.tcpRead(recvBuf)
+			if err != nil {
+				logger.Error("exit recv loop, conn read err: %v, convId: %v", err, convId)
+				s.Close()
+				break
+			}
+			if data == nil {
+				continue
+			}
+			recvData = data
+		} else {
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second * hk4egatenet.ConnRecvTimeout))
+			recvLen, err := conn.Read(recvBuf)
+			if err != nil {
+				logger.Error("exit recv loop, conn read err: %v, convId: %v", err, convId)
+				s.Close()
+				break
+			}
+			recvData = recvBuf[:recvLen]
 		}
-		recvData := recvBuf[:recvLen]
 		kcpMsgList := make([]*hk4egatenet.KcpMsg, 0)
 		hk4egatenet.DecodeBinToPayload(recvData, convId, &kcpMsgList, s.XorKey)
 		for _, v := range kcpMsgList {
@@ -125,6 +157,12 @@ func (s *Session) sendHandle() {
 			continue
 		}
 		bin := hk4egatenet.EncodePayloadToBin(kcpMsg, s.XorKey)
+		if s.IsTcp {
+			// tcp流分割的4个字节payload长度头部
+			headLenData := make([]byte, 4)
+			binary.BigEndian.PutUint32(headLenData, uint32(len(bin)))
+			bin = append(headLenData, bin...)
+		}
 		_ = conn.SetWriteDeadline(time.Now().Add(time.Second * hk4egatenet.ConnSendTimeout))
 		_, err := conn.Write(bin)
 		if err != nil {
@@ -134,3 +172,44 @@ func (s *Session) sendHandle() {
 		}
 	}
 }
+
+// tcpRead 读取一个tcp分包 rtt探测包返回nil
+func (s *Session) tcpRead(buf []byte) ([]byte, error) {
+	header := make([]byte, 4)
+	err := s.tcpReadFull(header)
+	if err != nil {
+		return nil, err
+	}
+	msgLen := binary.BigEndian.Uint32(header)
+	switch msgLen {
+	case 0:
+		// 客户端rtt探测的回包
+		return nil, nil
+	case 0xffffffff:
+		// 服务器rtt探测 原样回包
+		_ = s.Conn.SetWriteDeadline(time.Now().Add(time.Second * hk4egatenet.ConnSendTimeout))
+		_, err = s.Conn.Write([]byte{0xff, 0xff, 0xff, 0xff})
+		return nil, err
+	}
+	if msgLen > hk4egatenet.PacketMaxLen {
+		return nil, errors.New("msg len too long")
+	}
+	err = s.tcpReadFull(buf[:msgLen])
+	if err != nil {
+		return nil, err
+	}
+	return buf[:msgLen], nil
+}
+
+func (s *Session) tcpReadFull(buf []byte) error {
+	recvLen := 0
+	for recvLen < len(buf) {
+		_ = s.Conn.SetReadDeadline(time.Now().Add(time.Second * hk4egatenet.ConnRecvTimeout))
+		n, err := s.Conn.Read(buf[recvLen:])
+		if err != nil {
+			return err
+		}
+		recvLen += n
+	}
+	return nil
+}
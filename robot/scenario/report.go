@@ -0,0 +1,127 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const reportFailMax = 20 // 报告中最多输出的失败机器人数量
+
+type StepResult struct {
+	Name string
+	Cost time.Duration
+	Err  error
+}
+
+type RobotResult struct {
+	Index    int
+	Account  string
+	StepList []*StepResult
+	Err      error
+}
+
+// StepStat 步骤统计 同名步骤合并统计
+type StepStat struct {
+	Name     string
+	Total    int
+	Fail     int
+	costList []time.Duration // 成功执行的耗时 已排序
+}
+
+// Percentile 耗时百分位 使用最近秩方法
+func (s *StepStat) Percentile(p float64) time.Duration {
+	if len(s.costList) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100.0 * float64(len(s.costList))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(s.costList) {
+		rank = len(s.costList)
+	}
+	return s.costList[rank-1]
+}
+
+type Report struct {
+	Name         string
+	RobotNum     int
+	PassNum      int
+	FailNum      int
+	Cost         time.Duration
+	StepStatList []*StepStat // 按步骤第一次出现的顺序
+	FailList     []string
+}
+
+func NewReport(name string, resultList []*RobotResult, cost time.Duration) *Report {
+	r := &Report{
+		Name:         name,
+		RobotNum:     len(resultList),
+		Cost:         cost,
+		StepStatList: make([]*StepStat, 0),
+		FailList:     make([]string, 0),
+	}
+	stepStatMap := make(map[string]*StepStat)
+	for _, result := range resultList {
+		if result.Err == nil {
+			r.PassNum++
+		} else {
+			r.FailNum++
+			if len(r.FailList) < reportFailMax {
+				r.FailList = append(r.FailList, fmt.Sprintf("%v: %v", result.Account, result.Err))
+			}
+		}
+		for _, stepResult := range result.StepList {
+			stepStat, exist := stepStatMap[stepResult.Name]
+			if !exist {
+				stepStat = &StepStat{Name: stepResult.Name}
+				stepStatMap[stepResult.Name] = stepStat
+				r.StepStatList = append(r.StepStatList, stepStat)
+			}
+			stepStat.Total++
+			if stepResult.Err != nil {
+				stepStat.Fail++
+				continue
+			}
+			stepStat.costList = append(stepStat.costList, stepResult.Cost)
+		}
+	}
+	for _, stepStat := range r.StepStatList {
+		sort.Slice(stepStat.costList, func(i, j int) bool {
+			return stepStat.costList[i] < stepStat.costList[j]
+		})
+	}
+	return r
+}
+
+func (r *Report) IsPass() bool {
+	return r.RobotNum > 0 && r.FailNum == 0
+}
+
+func (r *Report) String() string {
+	builder := new(strings.Builder)
+	result := "PASS"
+	if !r.IsPass() {
+		result = "FAIL"
+	}
+	_, _ = fmt.Fprintf(builder, "scenario: %v, result: %v, robot: %v, pass: %v, fail: %v, cost: %v\n",
+		r.Name, result, r.RobotNum, r.PassNum, r.FailNum, r.Cost.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(builder, "%-24v %8v %8v %10v %10v %10v %10v\n", "step", "total", "fail", "p50", "p90", "p99", "max")
+	for _, stepStat := range r.StepStatList {
+		_, _ = fmt.Fprintf(builder, "%-24v %8v %8v %10v %10v %10v %10v\n", stepStat.Name, stepStat.Total, stepStat.Fail,
+			stepStat.Percentile(50).Round(time.Millisecond),
+			stepStat.Percentile(90).Round(time.Millisecond),
+			stepStat.Percentile(99).Round(time.Millisecond),
+			stepStat.Percentile(100).Round(time.Millisecond))
+	}
+	for _, fail := range r.FailList {
+		_, _ = fmt.Fprintf(builder, "fail %v\n", fail)
+	}
+	if r.FailNum > len(r.FailList) {
+		_, _ = fmt.Fprintf(builder, "... %v more fail\n", r.FailNum-len(r.FailList))
+	}
+	return builder.String()
+}
@@ -0,0 +1,389 @@
+package scenario
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"hk4e/common/config"
+	hk4egatenet "hk4e/gate/net"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+	"hk4e/robot/login"
+	"hk4e/robot/net"
+
+	"github.com/flswld/halo/logger"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	robotMsgBufferMax = 1000            // 等待步骤可以匹配的历史消息最大数量
+	robotPingInterval = time.Second * 5 // 心跳间隔
+	robotBornAvatarId = 10000007
+)
+
+// Robot 场景测试机器人
+// 所有步骤在同一个协程中执行 等待消息的同时自动完成进入场景流程和心跳
+type Robot struct {
+	index          int
+	account        string
+	scenario       *Scenario
+	session        *net.Session
+	msgList        []*hk4egatenet.ProtoMsg // 执行步骤过程中收到的未被消费的消息
+	isBorn         bool
+	enterSceneDone bool
+	pingSeq        uint32
+	pingTime       time.Time
+}
+
+func NewRobot(index int, account string, scenario *Scenario) *Robot {
+	return &Robot{
+		index:    index,
+		account:  account,
+		scenario: scenario,
+		isBorn:   true,
+	}
+}
+
+// Run 按顺序执行全部步骤 任意步骤失败则停止
+func (r *Robot) Run() *RobotResult {
+	result := &RobotResult{
+		Index:    r.index,
+		Account:  r.account,
+		StepList: make([]*StepResult, 0),
+	}
+	for loop := 0; loop < r.scenario.Loop; loop++ {
+		for _, step := range r.scenario.StepList {
+			startTime := time.Now()
+			err := r.RunStep(step)
+			result.StepList = append(result.StepList, &StepResult{
+				Name: step.Name,
+				Cost: time.Since(startTime),
+				Err:  err,
+			})
+			if err != nil {
+				result.Err = fmt.Errorf("step %v: %w", step.Name, err)
+				logger.Error("robot scenario step fail, account: %v, step: %v, err: %v", r.account, step.Name, err)
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func (r *Robot) Close() {
+	r.logout()
+	r.scenario.Close()
+}
+
+func (r *Robot) RunStep(step *Step) error {
+	if step.Action == ActionSleep {
+		if r.session == nil {
+			time.Sleep(step.Time)
+			return nil
+		}
+		// 等待期间继续处理服务器消息和心跳
+		_, err := r.waitMsg(0, lua.LNil, false, step.Time)
+		if err == ErrStepTimeout {
+			return nil
+		}
+		return err
+	}
+	if step.Action == ActionLogin {
+		return r.login(step)
+	}
+	if r.session == nil {
+		return ErrNotLogin
+	}
+	switch step.Action {
+	case ActionLogout:
+		r.logout()
+		return nil
+	case ActionGm:
+		r.session.SendMsg(cmd.GmTalkReq, &proto.GmTalkReq{Msg: r.format(step.Text)})
+		return r.waitRsp(step, cmd.GmTalkRsp, true)
+	case ActionChat:
+		if step.ToUid != 0 {
+			r.session.SendMsg(cmd.PrivateChatReq, &proto.PrivateChatReq{
+				TargetUid: step.ToUid,
+				Content:   &proto.PrivateChatReq_Text{Text: r.format(step.Text)},
+			})
+			return r.waitRsp(step, cmd.PrivateChatRsp, true)
+		}
+		r.session.SendMsg(cmd.PlayerChatReq, &proto.PlayerChatReq{
+			ChannelId: step.ChannelId,
+			ChatInfo: &proto.ChatInfo{
+				Time:    uint32(time.Now().Unix()),
+				Uid:     r.session.Uid,
+				Content: &proto.ChatInfo_Text{Text: r.format(step.Text)},
+			},
+		})
+		return r.waitRsp(step, cmd.PlayerChatRsp, true)
+	case ActionTeleport:
+		return r.teleport(step)
+	case ActionGacha:
+		r.session.SendMsg(cmd.DoGachaReq, &proto.DoGachaReq{
+			GachaScheduleId: step.ScheduleId,
+			GachaTimes:      step.Times,
+		})
+		return r.waitRsp(step, cmd.DoGachaRsp, true)
+	case ActionSend:
+		req, err := luaValueToMessage(step.Req, r.session.ServerCmdProtoMap.GetProtoObjByCmdId(step.CmdId))
+		if err != nil {
+			return err
+		}
+		r.session.SendMsg(step.CmdId, req)
+		if step.RspCmdId == 0 {
+			return nil
+		}
+		return r.waitRsp(step, step.RspCmdId, false)
+	case ActionWait:
+		// 通知可能在之前的步骤执行时已经收到
+		msgTable, err := r.waitMsg(step.CmdId, step.Match, true, step.Timeout)
+		if err != nil {
+			return err
+		}
+		return r.assert(step, msgTable, false)
+	}
+	return fmt.Errorf("%w: unknown action %v", ErrScenarioFormat, step.Action)
+}
+
+func (r *Robot) login(step *Step) error {
+	if r.session != nil {
+		return ErrAlreadyLogin
+	}
+	robotConfig := config.GetConfig().Hk4eRobot
+	dispatchInfo, err := login.GetDispatchInfo(robotConfig.RegionListUrl,
+		robotConfig.RegionListParam,
+		robotConfig.CurRegionUrl,
+		robotConfig.CurRegionParam,
+		robotConfig.KeyId)
+	if err != nil {
+		return err
+	}
+	accountInfo, err := login.AccountLogin(robotConfig.LoginSdkUrl, r.account, robotConfig.Password)
+	if err != nil {
+		return err
+	}
+	session, err := login.GateLogin(dispatchInfo, accountInfo, robotConfig.KeyId)
+	if err != nil {
+		return err
+	}
+	r.session = session
+	r.isBorn = true
+	r.enterSceneDone = false
+	err = login.PlayerLogin(session, accountInfo, robotConfig.ClientVersion)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+	err = r.waitRsp(step, cmd.PlayerLoginRsp, true)
+	if err != nil {
+		return err
+	}
+	return r.waitEnterScene(step.Timeout - time.Since(startTime))
+}
+
+func (r *Robot) logout() {
+	if r.session == nil {
+		return
+	}
+	r.session.Close()
+	close(r.session.SendChan)
+	r.session = nil
+	r.msgList = nil
+}
+
+func (r *Robot) teleport(step *Step) error {
+	startTime := time.Now()
+	if step.PointId != 0 {
+		r.enterSceneDone = false
+		r.session.SendMsg(cmd.SceneTransToPointReq, &proto.SceneTransToPointReq{SceneId: step.SceneId, PointId: step.PointId})
+		err := r.waitRsp(step, cmd.SceneTransToPointRsp, true)
+		if err != nil {
+			return err
+		}
+		return r.waitEnterScene(step.Timeout - time.Since(startTime))
+	}
+	textList := make([]string, 0, 2)
+	if step.SceneId != 0 {
+		textList = append(textList, fmt.Sprintf("jump %v", step.SceneId))
+	}
+	if step.Pos != nil {
+		textList = append(textList, fmt.Sprintf("goto %v %v %v", step.Pos.X, step.Pos.Y, step.Pos.Z))
+	}
+	for _, text := range textList {
+		r.enterSceneDone = false
+		r.session.SendMsg(cmd.GmTalkReq, &proto.GmTalkReq{Msg: text})
+		err := r.waitEnterScene(step.Timeout - time.Since(startTime))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitRsp 等待响应并断言 响应不会早于请求到达 不查找历史消息
+func (r *Robot) waitRsp(step *Step, rspCmdId uint16, checkRetcode bool) error {
+	msgTable, err := r.waitMsg(rspCmdId, lua.LNil, false, step.Timeout)
+	if err != nil {
+		return err
+	}
+	return r.assert(step, msgTable, checkRetcode)
+}
+
+// waitEnterScene 进入场景流程由autoHandle推进 之前的步骤中已经完成则直接返回
+func (r *Robot) waitEnterScene(timeout time.Duration) error {
+	if r.enterSceneDone {
+		return nil
+	}
+	_, err := r.waitMsg(cmd.EnterSceneDoneRsp, lua.LNil, false, timeout)
+	return err
+}
+
+// assert 断言为空时请求类步骤检查retcode为0
+func (r *Robot) assert(step *Step, msgTable lua.LValue, checkRetcode bool) error {
+	if step.Assert == lua.LNil {
+		if !checkRetcode {
+			return nil
+		}
+		if table, ok := msgTable.(*lua.LTable); ok {
+			retcode := table.RawGetString("retcode")
+			if retcode != lua.LNil && retcode.String() != "0" {
+				return fmt.Errorf("%w: retcode %v", ErrAssertFail, retcode.String())
+			}
+		}
+		return nil
+	}
+	ok, reason, err := r.scenario.Check(step.Assert, msgTable, r.ctxTable())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrAssertFail, reason)
+	}
+	return nil
+}
+
+// waitMsg 等待指定协议号且满足条件的消息 返回消息转换后的表
+func (r *Robot) waitMsg(cmdId uint16, cond lua.LValue, useBuffer bool, timeout time.Duration) (lua.LValue, error) {
+	check := func(protoMsg *hk4egatenet.ProtoMsg) (lua.LValue, bool, error) {
+		if cmdId == 0 || protoMsg.CmdId != cmdId {
+			return lua.LNil, false, nil
+		}
+		msgTable := r.scenario.MessageToLuaTable(protoMsg.PayloadMessage)
+		ok, _, err := r.scenario.Check(cond, msgTable, r.ctxTable())
+		return msgTable, ok, err
+	}
+	if useBuffer {
+		for i, protoMsg := range r.msgList {
+			msgTable, ok, err := check(protoMsg)
+			if err != nil {
+				return lua.LNil, err
+			}
+			if ok {
+				r.msgList = append(r.msgList[:i], r.msgList[i+1:]...)
+				return msgTable, nil
+			}
+		}
+	}
+	if timeout <= 0 {
+		return lua.LNil, ErrStepTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case protoMsg := <-r.session.RecvChan:
+			r.autoHandle(protoMsg)
+			msgTable, ok, err := check(protoMsg)
+			if err != nil {
+				return lua.LNil, err
+			}
+			if ok {
+				return msgTable, nil
+			}
+			r.msgList = append(r.msgList, protoMsg)
+			if len(r.msgList) > robotMsgBufferMax {
+				r.msgList = r.msgList[1:]
+			}
+		case <-r.session.DeadEvent:
+			return lua.LNil, ErrSessionClose
+		case <-ticker.C:
+			r.ping()
+		case <-timer.C:
+			return lua.LNil, ErrStepTimeout
+		}
+	}
+}
+
+// autoHandle 自动处理新建角色 进入场景流程等不需要脚本关心的协议
+func (r *Robot) autoHandle(protoMsg *hk4egatenet.ProtoMsg) {
+	switch protoMsg.CmdId {
+	case cmd.DoSetPlayerBornDataNotify:
+		r.isBorn = false
+	case cmd.PlayerLoginRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.PlayerLoginRsp)
+		if rsp.Retcode == 0 && !r.isBorn {
+			r.session.SendMsg(cmd.SetPlayerBornDataReq, &proto.SetPlayerBornDataReq{
+				AvatarId: robotBornAvatarId,
+				NickName: r.account,
+			})
+		}
+	case cmd.PlayerEnterSceneNotify:
+		ntf := protoMsg.PayloadMessage.(*proto.PlayerEnterSceneNotify)
+		r.enterSceneDone = false
+		r.session.SendMsg(cmd.EnterSceneReadyReq, &proto.EnterSceneReadyReq{EnterSceneToken: ntf.EnterSceneToken})
+	case cmd.EnterSceneReadyRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.EnterSceneReadyRsp)
+		r.session.SendMsg(cmd.SceneInitFinishReq, &proto.SceneInitFinishReq{EnterSceneToken: rsp.EnterSceneToken})
+	case cmd.SceneInitFinishRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.SceneInitFinishRsp)
+		r.session.SendMsg(cmd.EnterSceneDoneReq, &proto.EnterSceneDoneReq{EnterSceneToken: rsp.EnterSceneToken})
+	case cmd.EnterSceneDoneRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.EnterSceneDoneRsp)
+		r.enterSceneDone = true
+		r.session.SendMsg(cmd.PostEnterSceneReq, &proto.PostEnterSceneReq{EnterSceneToken: rsp.EnterSceneToken})
+	}
+}
+
+func (r *Robot) ping() {
+	now := time.Now()
+	if now.Sub(r.pingTime) < robotPingInterval {
+		return
+	}
+	r.pingTime = now
+	r.pingSeq++
+	r.session.SendMsg(cmd.PingReq, &proto.PingReq{
+		ClientTime: uint32(now.Unix()),
+		Seq:        r.pingSeq,
+	})
+}
+
+// ctxTable 传给脚本函数的机器人信息
+func (r *Robot) ctxTable() lua.LValue {
+	uid := uint32(0)
+	if r.session != nil {
+		uid = r.session.Uid
+	}
+	return r.scenario.NewLuaTable(map[string]lua.LValue{
+		"index":   lua.LNumber(r.index),
+		"account": lua.LString(r.account),
+		"uid":     lua.LNumber(uid),
+	})
+}
+
+func (r *Robot) format(text string) string {
+	uid := uint32(0)
+	if r.session != nil {
+		uid = r.session.Uid
+	}
+	return strings.NewReplacer(
+		"{uid}", strconv.Itoa(int(uid)),
+		"{account}", r.account,
+		"{index}", strconv.Itoa(r.index),
+	).Replace(text)
+}
@@ -0,0 +1,58 @@
+package scenario
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"hk4e/protocol/cmd"
+
+	"github.com/flswld/halo/logger"
+)
+
+const batchInterval = time.Second // 每批机器人登录的间隔
+
+// Run 加载场景脚本并发运行机器人 帐号自动添加后缀编号 robotNum为0则使用脚本中的数量
+func Run(filePath string, account string, robotNum int) (*Report, error) {
+	cmdProtoMap := cmd.NewCmdProtoMap()
+	// 先加载一次检查脚本并读取场景配置
+	scenario, err := LoadScenario(filePath, cmdProtoMap)
+	if err != nil {
+		return nil, err
+	}
+	scenario.Close()
+	if robotNum <= 0 {
+		robotNum = scenario.RobotNum
+	}
+	logger.Warn("scenario start, name: %v, robot: %v, step: %v, loop: %v", scenario.Name, robotNum, len(scenario.StepList), scenario.Loop)
+	startTime := time.Now()
+	resultList := make([]*RobotResult, robotNum)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < robotNum; i += scenario.BatchNum {
+		if i > 0 {
+			time.Sleep(batchInterval)
+		}
+		for j := i; j < i+scenario.BatchNum && j < robotNum; j++ {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				resultList[index] = runRobot(filePath, cmdProtoMap, index, account+strconv.Itoa(index))
+			}(j)
+		}
+	}
+	wg.Wait()
+	report := NewReport(scenario.Name, resultList, time.Since(startTime))
+	logger.Warn("scenario finish, name: %v, pass: %v, fail: %v", scenario.Name, report.PassNum, report.FailNum)
+	return report, nil
+}
+
+// runRobot 每个机器人使用独立的脚本环境
+func runRobot(filePath string, cmdProtoMap *cmd.CmdProtoMap, index int, account string) *RobotResult {
+	scenario, err := LoadScenario(filePath, cmdProtoMap)
+	if err != nil {
+		return &RobotResult{Index: index, Account: account, Err: err}
+	}
+	robot := NewRobot(index, account, scenario)
+	defer robot.Close()
+	return robot.Run()
+}
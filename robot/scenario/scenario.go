@@ -0,0 +1,453 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// 场景测试
+// 场景脚本为lua文件 脚本返回一个描述场景的表 由多个机器人并发按顺序执行表中的步骤
+// 每个机器人使用独立的脚本环境 匹配条件和断言可以是字段子集表或者脚本函数
+// return {
+//     name = "basic", robot_num = 10, batch_num = 5, loop = 1,
+//     step_list = {
+//         { action = "login" },
+//         { action = "gm", msg = "player level 60" },
+//         { action = "wait", cmd = "PlayerPropNotify", match = function(msg, robot) return true end },
+//     },
+// }
+
+const (
+	StepTimeoutDefault = time.Second * 10
+	BatchNumDefault    = 10
+)
+
+const (
+	ActionLogin    = "login"    // 登录 {action="login"} http登录 网关登录 gs登录 并等待进入场景完成
+	ActionLogout   = "logout"   // 登出 {action="logout"}
+	ActionGm       = "gm"       // GM指令 {action="gm", msg="player level 60"}
+	ActionChat     = "chat"     // 聊天 {action="chat", text="hello", to_uid=10001} 没有to_uid时发送到channel_id频道
+	ActionTeleport = "teleport" // 传送 {action="teleport", scene_id=3, pos={x=0, y=0, z=0}} 或 {action="teleport", scene_id=3, point_id=1}
+	ActionGacha    = "gacha"    // 抽卡 {action="gacha", schedule_id=1, times=10}
+	ActionSend     = "send"     // 发送任意协议 {action="send", cmd="XxxReq", req={...}, rsp="XxxRsp"}
+	ActionWait     = "wait"     // 等待通知 {action="wait", cmd="XxxNotify", match={...}}
+	ActionSleep    = "sleep"    // 等待时间 {action="sleep", time=1000}
+)
+
+var (
+	ErrScenarioFormat = errors.New("scenario format error")
+	ErrStepTimeout    = errors.New("step timeout")
+	ErrNotLogin       = errors.New("robot not login")
+	ErrAlreadyLogin   = errors.New("robot already login")
+	ErrSessionClose   = errors.New("session closed")
+	ErrAssertFail     = errors.New("assert fail")
+	ErrScenarioFail   = errors.New("scenario fail")
+)
+
+// Step 场景步骤
+type Step struct {
+	Name       string // 统计名 为空则使用序号加动作名
+	Action     string
+	Timeout    time.Duration
+	Text       string // GM指令 聊天内容 支持{uid} {account} {index}占位符
+	ToUid      uint32
+	ChannelId  uint32
+	SceneId    uint32
+	PointId    uint32
+	Pos        *proto.Vector
+	ScheduleId uint32
+	Times      uint32
+	CmdId      uint16
+	Req        lua.LValue
+	RspCmdId   uint16
+	Match      lua.LValue // 匹配条件 表或函数
+	Assert     lua.LValue // 断言 表或函数 请求类步骤为空则断言retcode为0
+	Time       time.Duration
+}
+
+// Scenario 场景
+type Scenario struct {
+	Name     string
+	RobotNum int // 机器人数量
+	BatchNum int // 每批并发登录的机器人数量
+	Loop     int // 每个机器人重复执行全部步骤的次数
+	StepList []*Step
+	luaState *lua.LState
+}
+
+// LoadScenario 加载场景脚本
+func LoadScenario(filePath string, cmdProtoMap *cmd.CmdProtoMap) (*Scenario, error) {
+	s := &Scenario{
+		luaState: newScenarioLuaState(),
+	}
+	err := s.load(filePath, cmdProtoMap)
+	if err != nil {
+		s.luaState.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scenario) Close() {
+	s.luaState.Close()
+}
+
+func (s *Scenario) load(filePath string, cmdProtoMap *cmd.CmdProtoMap) error {
+	L := s.luaState
+	fn, err := L.LoadFile(filePath)
+	if err != nil {
+		return err
+	}
+	err = L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true})
+	if err != nil {
+		return err
+	}
+	table, ok := L.Get(-1).(*lua.LTable)
+	L.Pop(1)
+	if !ok {
+		return fmt.Errorf("%w: script should return a table", ErrScenarioFormat)
+	}
+	s.Name = luaGetString(table, "name")
+	s.RobotNum = int(luaGetUint(table, "robot_num"))
+	if s.RobotNum == 0 {
+		s.RobotNum = 1
+	}
+	s.BatchNum = int(luaGetUint(table, "batch_num"))
+	if s.BatchNum == 0 {
+		s.BatchNum = BatchNumDefault
+	}
+	s.Loop = int(luaGetUint(table, "loop"))
+	if s.Loop == 0 {
+		s.Loop = 1
+	}
+	stepTable, ok := table.RawGetString("step_list").(*lua.LTable)
+	if !ok || stepTable.MaxN() == 0 {
+		return fmt.Errorf("%w: step_list is empty", ErrScenarioFormat)
+	}
+	for i := 1; i <= stepTable.MaxN(); i++ {
+		stepInfo, ok := stepTable.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return fmt.Errorf("%w: step %v is not a table", ErrScenarioFormat, i)
+		}
+		step, err := parseStep(i, stepInfo, cmdProtoMap)
+		if err != nil {
+			return err
+		}
+		s.StepList = append(s.StepList, step)
+	}
+	return nil
+}
+
+func parseStep(index int, info *lua.LTable, cmdProtoMap *cmd.CmdProtoMap) (*Step, error) {
+	step := &Step{
+		Name:       luaGetString(info, "name"),
+		Action:     luaGetString(info, "action"),
+		Timeout:    time.Duration(luaGetUint(info, "timeout")) * time.Millisecond,
+		ToUid:      uint32(luaGetUint(info, "to_uid")),
+		ChannelId:  uint32(luaGetUint(info, "channel_id")),
+		SceneId:    uint32(luaGetUint(info, "scene_id")),
+		PointId:    uint32(luaGetUint(info, "point_id")),
+		ScheduleId: uint32(luaGetUint(info, "schedule_id")),
+		Times:      uint32(luaGetUint(info, "times")),
+		Req:        info.RawGetString("req"),
+		Match:      info.RawGetString("match"),
+		Assert:     info.RawGetString("assert"),
+		Time:       time.Duration(luaGetUint(info, "time")) * time.Millisecond,
+	}
+	if step.Name == "" {
+		step.Name = fmt.Sprintf("%v.%v", index, step.Action)
+	}
+	if step.Timeout == 0 {
+		step.Timeout = StepTimeoutDefault
+	}
+	if posTable, ok := info.RawGetString("pos").(*lua.LTable); ok {
+		step.Pos = &proto.Vector{
+			X: float32(lua.LVAsNumber(posTable.RawGetString("x"))),
+			Y: float32(lua.LVAsNumber(posTable.RawGetString("y"))),
+			Z: float32(lua.LVAsNumber(posTable.RawGetString("z"))),
+		}
+	}
+	for _, cond := range []lua.LValue{step.Match, step.Assert} {
+		if cond.Type() != lua.LTNil && cond.Type() != lua.LTTable && cond.Type() != lua.LTFunction {
+			return nil, fmt.Errorf("%w: step %v match and assert should be table or function", ErrScenarioFormat, index)
+		}
+	}
+	getCmdId := func(key string) (uint16, error) {
+		cmdName := luaGetString(info, key)
+		if cmdName == "" {
+			return 0, nil
+		}
+		cmdId := cmdProtoMap.GetCmdIdByCmdName(cmdName)
+		if cmdId == 0 {
+			return 0, fmt.Errorf("%w: step %v unknown cmd %v", ErrScenarioFormat, index, cmdName)
+		}
+		return cmdId, nil
+	}
+	var err error = nil
+	step.CmdId, err = getCmdId("cmd")
+	if err != nil {
+		return nil, err
+	}
+	step.RspCmdId, err = getCmdId("rsp")
+	if err != nil {
+		return nil, err
+	}
+	switch step.Action {
+	case ActionLogin, ActionLogout:
+	case ActionGm:
+		step.Text = luaGetString(info, "msg")
+		if step.Text == "" {
+			return nil, fmt.Errorf("%w: step %v gm msg is empty", ErrScenarioFormat, index)
+		}
+	case ActionChat:
+		step.Text = luaGetString(info, "text")
+		if step.Text == "" {
+			return nil, fmt.Errorf("%w: step %v chat text is empty", ErrScenarioFormat, index)
+		}
+	case ActionTeleport:
+		if step.SceneId == 0 && step.Pos == nil {
+			return nil, fmt.Errorf("%w: step %v teleport need scene_id or pos", ErrScenarioFormat, index)
+		}
+		if step.PointId != 0 && step.SceneId == 0 {
+			return nil, fmt.Errorf("%w: step %v teleport point need scene_id", ErrScenarioFormat, index)
+		}
+	case ActionGacha:
+		if step.ScheduleId == 0 {
+			return nil, fmt.Errorf("%w: step %v gacha schedule_id is empty", ErrScenarioFormat, index)
+		}
+		if step.Times == 0 {
+			step.Times = 1
+		}
+	case ActionSend:
+		if step.CmdId == 0 {
+			return nil, fmt.Errorf("%w: step %v send cmd is empty", ErrScenarioFormat, index)
+		}
+		// 提前检查请求能否转换为协议 避免运行时才发现脚本错误
+		_, err := luaValueToMessage(step.Req, cmdProtoMap.GetProtoObjByCmdId(step.CmdId))
+		if err != nil {
+			return nil, fmt.Errorf("%w: step %v send req error: %v", ErrScenarioFormat, index, err)
+		}
+	case ActionWait:
+		if step.CmdId == 0 {
+			return nil, fmt.Errorf("%w: step %v wait cmd is empty", ErrScenarioFormat, index)
+		}
+	case ActionSleep:
+		if step.Time == 0 {
+			return nil, fmt.Errorf("%w: step %v sleep time is empty", ErrScenarioFormat, index)
+		}
+	default:
+		return nil, fmt.Errorf("%w: step %v unknown action %v", ErrScenarioFormat, index, step.Action)
+	}
+	return step, nil
+}
+
+// newScenarioLuaState 创建沙箱环境 只开放基础库 表 字符串 数学库 并移除加载外部代码的函数
+func newScenarioLuaState() *lua.LState {
+	luaState := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       256,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		luaState.Push(luaState.NewFunction(lib.fn))
+		luaState.Push(lua.LString(lib.name))
+		luaState.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require", "collectgarbage", "newproxy", "_printregs", "getfenv", "setfenv"} {
+		luaState.SetGlobal(name, lua.LNil)
+	}
+	luaState.SetGlobal("print", luaState.NewFunction(func(L *lua.LState) int {
+		strList := make([]string, 0, L.GetTop())
+		for i := 1; i <= L.GetTop(); i++ {
+			strList = append(strList, L.ToStringMeta(L.Get(i)).String())
+		}
+		logger.Info("[SCENARIO] %v", strings.Join(strList, " "))
+		return 0
+	}))
+	return luaState
+}
+
+// Check 检查消息是否满足条件 表为字段子集匹配 函数返回真则满足 函数的第二个返回值为不满足的原因
+func (s *Scenario) Check(cond lua.LValue, msgTable lua.LValue, ctxTable lua.LValue) (bool, string, error) {
+	switch c := cond.(type) {
+	case *lua.LTable:
+		path, ok := tableMatch(c, msgTable, "")
+		return ok, path, nil
+	case *lua.LFunction:
+		L := s.luaState
+		err := L.CallByParam(lua.P{Fn: c, NRet: 2, Protect: true}, msgTable, ctxTable)
+		if err != nil {
+			return false, "", err
+		}
+		ok, reason := lua.LVAsBool(L.Get(-2)), L.Get(-1)
+		L.Pop(2)
+		if reason == lua.LNil {
+			return ok, "", nil
+		}
+		return ok, reason.String(), nil
+	}
+	return true, "", nil
+}
+
+// MessageToLuaTable 协议转换为表 字段名为协议字段名
+func (s *Scenario) MessageToLuaTable(msg pb.Message) lua.LValue {
+	if msg == nil {
+		return lua.LNil
+	}
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return lua.LNil
+	}
+	var obj any = nil
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return lua.LNil
+	}
+	return jsonValueToLuaValue(s.luaState, obj)
+}
+
+func (s *Scenario) NewLuaTable(fieldMap map[string]lua.LValue) *lua.LTable {
+	table := s.luaState.NewTable()
+	for key, value := range fieldMap {
+		table.RawSetString(key, value)
+	}
+	return table
+}
+
+// tableMatch 条件表中的每个字段都与消息中的字段相等 返回第一个不相等的字段路径
+func tableMatch(cond *lua.LTable, value lua.LValue, path string) (string, bool) {
+	valueTable, ok := value.(*lua.LTable)
+	if !ok {
+		return path, false
+	}
+	failPath := ""
+	cond.ForEach(func(key lua.LValue, condValue lua.LValue) {
+		if failPath != "" {
+			return
+		}
+		subPath := key.String()
+		if path != "" {
+			subPath = path + "." + subPath
+		}
+		subValue := valueTable.RawGet(key)
+		if subCond, ok := condValue.(*lua.LTable); ok {
+			if p, ok := tableMatch(subCond, subValue, subPath); !ok {
+				failPath = p
+			}
+			return
+		}
+		// 64位整数在协议json中为字符串 统一按字符串比较
+		if condValue.String() != subValue.String() {
+			failPath = subPath
+		}
+	})
+	return failPath, failPath == ""
+}
+
+func jsonValueToLuaValue(luaState *lua.LState, value any) lua.LValue {
+	switch v := value.(type) {
+	case map[string]any:
+		table := luaState.NewTable()
+		for key, subValue := range v {
+			table.RawSetString(key, jsonValueToLuaValue(luaState, subValue))
+		}
+		return table
+	case []any:
+		table := luaState.NewTable()
+		for _, subValue := range v {
+			table.Append(jsonValueToLuaValue(luaState, subValue))
+		}
+		return table
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case bool:
+		return lua.LBool(v)
+	}
+	return lua.LNil
+}
+
+func luaValueToJsonValue(value lua.LValue) any {
+	switch v := value.(type) {
+	case *lua.LTable:
+		if v.MaxN() > 0 {
+			arr := make([]any, 0, v.MaxN())
+			for i := 1; i <= v.MaxN(); i++ {
+				arr = append(arr, luaValueToJsonValue(v.RawGetInt(i)))
+			}
+			return arr
+		}
+		obj := make(map[string]any)
+		v.ForEach(func(key lua.LValue, subValue lua.LValue) {
+			obj[key.String()] = luaValueToJsonValue(subValue)
+		})
+		return obj
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		f := float64(v)
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case lua.LBool:
+		return bool(v)
+	}
+	return nil
+}
+
+// luaValueToMessage 表按协议字段名转换为协议
+func luaValueToMessage(value lua.LValue, msg pb.Message) (pb.Message, error) {
+	if msg == nil {
+		return nil, errors.New("proto object is nil")
+	}
+	if value == lua.LNil {
+		return msg, nil
+	}
+	data, err := json.Marshal(luaValueToJsonValue(value))
+	if err != nil {
+		return nil, err
+	}
+	err = protojson.Unmarshal(data, msg)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func luaGetString(table *lua.LTable, key string) string {
+	value := table.RawGetString(key)
+	if value == lua.LNil {
+		return ""
+	}
+	return value.String()
+}
+
+func luaGetUint(table *lua.LTable, key string) uint64 {
+	number := lua.LVAsNumber(table.RawGetString(key))
+	if number < 0 {
+		return 0
+	}
+	return uint64(number)
+}
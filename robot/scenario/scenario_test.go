@@ -0,0 +1,168 @@
+package scenario
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hk4e/common/config"
+	hk4egatenet "hk4e/gate/net"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+	"hk4e/robot/net"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger(nil)
+	config.CONF = &config.Config{}
+	m.Run()
+}
+
+func loadTestScenario(t *testing.T, script string) (*Scenario, error) {
+	filePath := filepath.Join(t.TempDir(), "test.lua")
+	err := os.WriteFile(filePath, []byte(script), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LoadScenario(filePath, cmd.NewCmdProtoMap())
+}
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("../../cmd/robot/scenario/basic.lua", cmd.NewCmdProtoMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scenario.Close()
+	if scenario.Name != "basic" || scenario.RobotNum != 10 || scenario.BatchNum != 5 || len(scenario.StepList) == 0 {
+		t.Fatalf("scenario error: %+v", scenario)
+	}
+	step := scenario.StepList[0]
+	if step.Action != ActionLogin || step.Name != "1.login" || step.Timeout != time.Second*30 {
+		t.Fatalf("step error: %+v", step)
+	}
+	// 脚本错误在加载时发现
+	for _, script := range []string{
+		`return 1`,
+		`return { step_list = {} }`,
+		`return { step_list = { { action = "fly" } } }`,
+		`return { step_list = { { action = "wait", cmd = "NotExistNotify" } } }`,
+		`return { step_list = { { action = "send", cmd = "PingReq", req = { seq = "abc" } } } }`,
+		`return { step_list = { { action = "wait", cmd = "PingRsp", match = 1 } } }`,
+	} {
+		_, err := loadTestScenario(t, script)
+		if !errors.Is(err, ErrScenarioFormat) {
+			t.Fatalf("script should load fail: %v, err: %v", script, err)
+		}
+	}
+}
+
+func newTestRobot(t *testing.T, script string) *Robot {
+	scenario, err := loadTestScenario(t, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	robot := NewRobot(0, "robot0", scenario)
+	robot.session = &net.Session{
+		SendChan:          make(chan *hk4egatenet.ProtoMsg, 100),
+		RecvChan:          make(chan *hk4egatenet.ProtoMsg, 100),
+		DeadEvent:         make(chan struct{}),
+		ServerCmdProtoMap: cmd.NewCmdProtoMap(),
+		Uid:               10001,
+	}
+	t.Cleanup(func() {
+		// 测试会话没有连接
+		robot.session = nil
+		robot.Close()
+	})
+	return robot
+}
+
+func recvTestMsg(robot *Robot, cmdId uint16, msg pb.Message) {
+	robot.session.RecvChan <- &hk4egatenet.ProtoMsg{CmdId: cmdId, PayloadMessage: msg}
+}
+
+func TestRobotStep(t *testing.T) {
+	robot := newTestRobot(t, `return { step_list = {
+		{ action = "gm", msg = "player level 60" },
+		{ action = "wait", cmd = "PlayerChatNotify", match = { chat_info = { uid = 10002 } }, assert = { channel_id = 1 } },
+		{ action = "wait", cmd = "PlayerChatNotify", timeout = 100,
+			match = function(msg, robot) return msg.chat_info.uid == robot.uid end,
+			assert = function(msg, robot) return msg.chat_info.text == "hi", "text error" end },
+		{ action = "send", cmd = "GetPlayerFriendListReq", req = {}, rsp = "GetPlayerFriendListRsp" },
+	} }`)
+	stepList := robot.scenario.StepList
+	// 请求类步骤默认断言retcode为0
+	recvTestMsg(robot, cmd.GmTalkRsp, &proto.GmTalkRsp{Retcode: -1})
+	if err := robot.RunStep(stepList[0]); !errors.Is(err, ErrAssertFail) {
+		t.Fatalf("gm step should assert fail: %v", err)
+	}
+	if (<-robot.session.SendChan).PayloadMessage.(*proto.GmTalkReq).Msg != "player level 60" {
+		t.Fatal("gm req error")
+	}
+	recvTestMsg(robot, cmd.GmTalkRsp, &proto.GmTalkRsp{})
+	if err := robot.RunStep(stepList[0]); err != nil {
+		t.Fatal(err)
+	}
+	<-robot.session.SendChan
+	// 之前步骤收到的通知也可以被等待步骤匹配 不匹配的消息继续保留
+	recvTestMsg(robot, cmd.PlayerChatNotify, &proto.PlayerChatNotify{ChannelId: 1, ChatInfo: &proto.ChatInfo{Uid: 10001, Content: &proto.ChatInfo_Text{Text: "hello"}}})
+	recvTestMsg(robot, cmd.PlayerChatNotify, &proto.PlayerChatNotify{ChannelId: 1, ChatInfo: &proto.ChatInfo{Uid: 10002}})
+	if err := robot.RunStep(stepList[1]); err != nil {
+		t.Fatal(err)
+	}
+	if len(robot.msgList) != 1 {
+		t.Fatalf("msg list error: %v", len(robot.msgList))
+	}
+	if err := robot.RunStep(stepList[2]); !errors.Is(err, ErrAssertFail) || err.Error() != "assert fail: text error" {
+		t.Fatalf("wait step should assert fail: %v", err)
+	}
+	if err := robot.RunStep(stepList[2]); !errors.Is(err, ErrStepTimeout) {
+		t.Fatalf("wait step should timeout: %v", err)
+	}
+	// 进入场景流程自动处理
+	recvTestMsg(robot, cmd.PlayerEnterSceneNotify, &proto.PlayerEnterSceneNotify{EnterSceneToken: 100})
+	recvTestMsg(robot, cmd.GetPlayerFriendListRsp, &proto.GetPlayerFriendListRsp{})
+	if err := robot.RunStep(stepList[3]); err != nil {
+		t.Fatal(err)
+	}
+	if (<-robot.session.SendChan).CmdId != cmd.GetPlayerFriendListReq {
+		t.Fatal("send req error")
+	}
+	if req, ok := (<-robot.session.SendChan).PayloadMessage.(*proto.EnterSceneReadyReq); !ok || req.EnterSceneToken != 100 {
+		t.Fatal("enter scene ready req error")
+	}
+}
+
+func TestReport(t *testing.T) {
+	resultList := make([]*RobotResult, 0)
+	for i := 1; i <= 100; i++ {
+		result := &RobotResult{Index: i, StepList: []*StepResult{
+			{Name: "login", Cost: time.Duration(i) * time.Millisecond},
+		}}
+		if i%50 == 0 {
+			result.StepList = append(result.StepList, &StepResult{Name: "gm", Err: ErrStepTimeout})
+			result.Err = ErrStepTimeout
+		} else {
+			result.StepList = append(result.StepList, &StepResult{Name: "gm", Cost: time.Millisecond})
+		}
+		resultList = append(resultList, result)
+	}
+	report := NewReport("test", resultList, time.Second)
+	if report.PassNum != 98 || report.FailNum != 2 || report.IsPass() || len(report.StepStatList) != 2 {
+		t.Fatalf("report error: %+v", report)
+	}
+	login := report.StepStatList[0]
+	if login.Name != "login" || login.Percentile(50) != time.Millisecond*50 || login.Percentile(99) != time.Millisecond*99 ||
+		login.Percentile(100) != time.Millisecond*100 || login.Percentile(0) != time.Millisecond {
+		t.Fatalf("login percentile error: %v, %v", login.Percentile(50), login.Percentile(99))
+	}
+	gm := report.StepStatList[1]
+	if gm.Total != 100 || gm.Fail != 2 || gm.Percentile(90) != time.Millisecond {
+		t.Fatalf("gm stat error: %+v", gm)
+	}
+}
@@ -0,0 +1,204 @@
+package testutil
+
+import (
+	"errors"
+	"time"
+
+	"hk4e/common/config"
+	hk4egatenet "hk4e/gate/net"
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+	"hk4e/robot/login"
+	"hk4e/robot/net"
+
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	WaitTimeoutDefault = time.Second * 10 // 等待消息的默认超时时间
+	clientMsgBufferMax = 1000
+	clientPingInterval = time.Second * 5
+	clientBornAvatarId = 10000007
+	clientPassword     = "hk4etest"
+)
+
+var (
+	ErrWaitTimeout  = errors.New("wait msg timeout")
+	ErrSessionClose = errors.New("session close")
+	ErrRetcode      = errors.New("rsp retcode not zero")
+)
+
+// Client 模拟客户端
+// 走完整的dispatch和网关登录流程 包括rsa密钥交换和xor密钥切换 连接方式跟随网关的tcp模式配置
+// 不是并发安全的 每个客户端只应在一个协程中使用
+type Client struct {
+	Account     string
+	AccountInfo *login.AccountInfo
+	Session     *net.Session
+	msgList     []*hk4egatenet.ProtoMsg // 等待消息过程中收到的未被取走的消息
+	isBorn      bool
+	pingSeq     uint32
+	pingTime    time.Time
+	closed      bool
+}
+
+// NewClient 登录sdk并连接网关 完成密钥交换 帐号不存在时自动注册
+// 帐号为6-20位字母和数字
+func (s *Server) NewClient(account string) (*Client, error) {
+	robotConfig := config.GetConfig().Hk4eRobot
+	dispatchInfo, err := login.GetDispatchInfo(robotConfig.RegionListUrl,
+		robotConfig.RegionListParam,
+		robotConfig.CurRegionUrl,
+		robotConfig.CurRegionParam,
+		robotConfig.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	// 密码无法rsa解密时使用帐号@@密码格式
+	accountInfo, err := login.AccountLogin(robotConfig.LoginSdkUrl, account+"@@"+clientPassword, "")
+	if err != nil {
+		return nil, err
+	}
+	session, err := login.GateLogin(dispatchInfo, accountInfo, robotConfig.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		Account:     account,
+		AccountInfo: accountInfo,
+		Session:     session,
+		msgList:     make([]*hk4egatenet.ProtoMsg, 0),
+		isBorn:      true,
+	}
+	return c, nil
+}
+
+// Login 玩家登录 新帐号自动完成创角 等待进入场景完成
+func (c *Client) Login() (*proto.PlayerLoginRsp, error) {
+	err := login.PlayerLogin(c.Session, c.AccountInfo, config.GetConfig().Hk4eRobot.ClientVersion)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.WaitMsg(cmd.PlayerLoginRsp, WaitTimeoutDefault)
+	if err != nil {
+		return nil, err
+	}
+	rsp := msg.(*proto.PlayerLoginRsp)
+	if rsp.Retcode != 0 {
+		return rsp, ErrRetcode
+	}
+	_, err = c.WaitMsg(cmd.EnterSceneDoneRsp, WaitTimeoutDefault)
+	if err != nil {
+		return rsp, err
+	}
+	return rsp, nil
+}
+
+// Close 断开连接 关闭后不能再发送消息
+func (c *Client) Close() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.Session.Close()
+	close(c.Session.SendChan)
+}
+
+// Uid 网关登录后分配的玩家uid
+func (c *Client) Uid() uint32 {
+	return c.Session.Uid
+}
+
+// SendMsg 发送消息
+func (c *Client) SendMsg(cmdId uint16, msg pb.Message) {
+	c.Session.SendMsg(cmdId, msg)
+}
+
+// Request 发送请求并等待响应
+func (c *Client) Request(cmdId uint16, req pb.Message, rspCmdId uint16) (pb.Message, error) {
+	c.SendMsg(cmdId, req)
+	return c.WaitMsg(rspCmdId, WaitTimeoutDefault)
+}
+
+// WaitMsg 等待指定消息 之前收到的未被取走的消息也可以匹配
+func (c *Client) WaitMsg(cmdId uint16, timeout time.Duration) (pb.Message, error) {
+	return c.WaitMsgMatch(cmdId, nil, timeout)
+}
+
+// WaitMsgMatch 等待满足条件的指定消息 match为nil则匹配任意该消息
+// 等待过程中自动完成创角 进入场景流程和心跳 不匹配的消息保留给之后的等待
+func (c *Client) WaitMsgMatch(cmdId uint16, match func(msg pb.Message) bool, timeout time.Duration) (pb.Message, error) {
+	check := func(protoMsg *hk4egatenet.ProtoMsg) bool {
+		return protoMsg.CmdId == cmdId && (match == nil || match(protoMsg.PayloadMessage))
+	}
+	for i, protoMsg := range c.msgList {
+		if check(protoMsg) {
+			c.msgList = append(c.msgList[:i], c.msgList[i+1:]...)
+			return protoMsg.PayloadMessage, nil
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case protoMsg := <-c.Session.RecvChan:
+			c.autoHandle(protoMsg)
+			if check(protoMsg) {
+				return protoMsg.PayloadMessage, nil
+			}
+			c.msgList = append(c.msgList, protoMsg)
+			if len(c.msgList) > clientMsgBufferMax {
+				c.msgList = c.msgList[1:]
+			}
+		case <-c.Session.DeadEvent:
+			return nil, ErrSessionClose
+		case <-ticker.C:
+			c.ping()
+		case <-timer.C:
+			return nil, ErrWaitTimeout
+		}
+	}
+}
+
+func (c *Client) autoHandle(protoMsg *hk4egatenet.ProtoMsg) {
+	switch protoMsg.CmdId {
+	case cmd.DoSetPlayerBornDataNotify:
+		c.isBorn = false
+	case cmd.PlayerLoginRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.PlayerLoginRsp)
+		if rsp.Retcode == 0 && !c.isBorn {
+			c.isBorn = true
+			c.SendMsg(cmd.SetPlayerBornDataReq, &proto.SetPlayerBornDataReq{
+				AvatarId: clientBornAvatarId,
+				NickName: c.Account,
+			})
+		}
+	case cmd.PlayerEnterSceneNotify:
+		ntf := protoMsg.PayloadMessage.(*proto.PlayerEnterSceneNotify)
+		c.SendMsg(cmd.EnterSceneReadyReq, &proto.EnterSceneReadyReq{EnterSceneToken: ntf.EnterSceneToken})
+	case cmd.EnterSceneReadyRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.EnterSceneReadyRsp)
+		c.SendMsg(cmd.SceneInitFinishReq, &proto.SceneInitFinishReq{EnterSceneToken: rsp.EnterSceneToken})
+	case cmd.SceneInitFinishRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.SceneInitFinishRsp)
+		c.SendMsg(cmd.EnterSceneDoneReq, &proto.EnterSceneDoneReq{EnterSceneToken: rsp.EnterSceneToken})
+	case cmd.EnterSceneDoneRsp:
+		rsp := protoMsg.PayloadMessage.(*proto.EnterSceneDoneRsp)
+		c.SendMsg(cmd.PostEnterSceneReq, &proto.PostEnterSceneReq{EnterSceneToken: rsp.EnterSceneToken})
+	}
+}
+
+func (c *Client) ping() {
+	now := time.Now()
+	if now.Sub(c.pingTime) < clientPingInterval {
+		return
+	}
+	c.pingTime = now
+	c.pingSeq++
+	c.SendMsg(cmd.PingReq, &proto.PingReq{
+		ClientTime: uint32(now.Unix()),
+		Seq:        c.pingSeq,
+	})
+}
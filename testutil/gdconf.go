@@ -0,0 +1,93 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+var (
+	// 体积大且登录和常规协议处理用不到的配置 以空目录代替
+	gameDataConfigEmptyDirList = []string{
+		"json/ability",
+		"json/gadget",
+		"lua/gadget",
+	}
+	// 只保留部分场景的配置
+	gameDataConfigSceneDir = "lua/scene"
+)
+
+// RepoPath 仓库根目录的绝对路径
+func RepoPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(filepath.Dir(file))
+}
+
+// NewGameDataConfig 在dir下生成裁剪后的配置表目录 返回配置表路径
+// 其余配置均软链接到仓库内的原始配置表 不会复制文件
+func NewGameDataConfig(dir string, sceneIdList []uint32) (string, error) {
+	srcPath := filepath.Join(RepoPath(), "gdconf", "game_data_config")
+	dstPath := filepath.Join(dir, "game_data_config")
+	sceneMap := make(map[string]bool)
+	for _, sceneId := range sceneIdList {
+		sceneMap[strconv.Itoa(int(sceneId))] = true
+	}
+	emptyDirMap := make(map[string]bool)
+	for _, emptyDir := range gameDataConfigEmptyDirList {
+		emptyDirMap[filepath.FromSlash(emptyDir)] = true
+	}
+	typeDirList, err := os.ReadDir(srcPath)
+	if err != nil {
+		return "", err
+	}
+	for _, typeDir := range typeDirList {
+		if !typeDir.IsDir() {
+			continue
+		}
+		err = os.MkdirAll(filepath.Join(dstPath, typeDir.Name()), 0755)
+		if err != nil {
+			return "", err
+		}
+		entryList, err := os.ReadDir(filepath.Join(srcPath, typeDir.Name()))
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entryList {
+			name := filepath.Join(typeDir.Name(), entry.Name())
+			switch {
+			case emptyDirMap[name]:
+				err = os.MkdirAll(filepath.Join(dstPath, name), 0755)
+			case name == filepath.FromSlash(gameDataConfigSceneDir):
+				err = linkSceneDir(filepath.Join(srcPath, name), filepath.Join(dstPath, name), sceneMap)
+			default:
+				err = os.Symlink(filepath.Join(srcPath, name), filepath.Join(dstPath, name))
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return dstPath, nil
+}
+
+func linkSceneDir(srcPath string, dstPath string, sceneMap map[string]bool) error {
+	err := os.MkdirAll(dstPath, 0755)
+	if err != nil {
+		return err
+	}
+	sceneDirList, err := os.ReadDir(srcPath)
+	if err != nil {
+		return err
+	}
+	for _, sceneDir := range sceneDirList {
+		if !sceneMap[sceneDir.Name()] {
+			continue
+		}
+		err = os.Symlink(filepath.Join(srcPath, sceneDir.Name()), filepath.Join(dstPath, sceneDir.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
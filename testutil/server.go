@@ -0,0 +1,387 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"hk4e/common/config"
+	"hk4e/common/rpc"
+	dispatchapp "hk4e/dispatch/app"
+	gateapp "hk4e/gate/app"
+	gsapi "hk4e/gs/api"
+	gsapp "hk4e/gs/app"
+	multiapp "hk4e/multi/app"
+	"hk4e/node/api"
+	nodeapp "hk4e/node/app"
+
+	"github.com/flswld/halo/logger"
+	"github.com/flswld/halo/protocol/kcp"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+const (
+	StartTimeoutDefault = time.Minute // 等待全部服务器启动完成的超时时间
+	readyCheckInterval  = time.Millisecond * 100
+	appStopTimeout      = time.Second * 10
+	mainGsId            = 1 // 进程内只启动一个gs 节点服务器分配的编号固定为1
+	clientVersion       = "CNRELWin3.2.0_R11611027_S11212885_D11793813"
+	clientKeyId         = "5"
+)
+
+var (
+	ErrAlreadyStart = errors.New("server already start")
+	ErrStartTimeout = errors.New("server start timeout")
+	ErrAppExit      = errors.New("app exit")
+)
+
+// 各服务器的app包都使用了全局单例 一个进程只能启动一次
+var startOnce sync.Once
+
+type Option struct {
+	TcpModeEnable bool          // 网关是否开启tcp模式 开启后客户端也使用tcp连接
+	SceneIdList   []uint32      // 配置表保留的场景 为空则只保留大世界
+	LoadSceneLua  bool          // 是否加载场景详情LUA配置 不加载时场景内没有实体和aoi 大世界加载较慢
+	DataDir       string        // 工作目录 存放数据库和配置表 为空则使用临时目录并在停止时删除
+	StartTimeout  time.Duration // 启动超时时间 为0则使用默认值
+}
+
+type appInst struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Server 进程内运行的全部服务器
+// 使用单进程模式启动 不依赖redis 数据库为工作目录下的sqlite文件
+type Server struct {
+	Dir          string // 工作目录
+	DispatchUrl  string // dispatch的http地址
+	GateAddr     string // 网关的kcp/tcp地址
+	option       *Option
+	oldWd        string
+	removeDir    bool
+	natsServer   *server.Server
+	appList      []*appInst
+	discovery    *rpc.DiscoveryClient
+	gmClient     *rpc.GMClient
+	startTimeout time.Duration
+}
+
+// Start 启动嵌入式nats和node dispatch gs multi gate服务器 等待全部就绪后返回
+// 会修改全局配置config.CONF和进程工作目录 Stop时恢复工作目录
+func Start(option *Option) (*Server, error) {
+	started := true
+	startOnce.Do(func() {
+		started = false
+	})
+	if started {
+		return nil, ErrAlreadyStart
+	}
+	if option == nil {
+		option = new(Option)
+	}
+	s := &Server{
+		option:       option,
+		appList:      make([]*appInst, 0),
+		startTimeout: option.StartTimeout,
+	}
+	if s.startTimeout == 0 {
+		s.startTimeout = StartTimeoutDefault
+	}
+	err := s.start()
+	if err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Server) start() error {
+	err := s.initDir()
+	if err != nil {
+		return err
+	}
+	err = s.initConfig()
+	if err != nil {
+		return err
+	}
+	// nats
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		return err
+	}
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(time.Second * 5) {
+		return errors.New("nats server start error")
+	}
+	s.natsServer = natsServer
+	config.CONF.MQ.NatsUrl = natsServer.ClientURL()
+	s.discovery, err = rpc.NewDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	s.gmClient, err = rpc.NewGMClient(mainGsId)
+	if err != nil {
+		return err
+	}
+	// gs和multi先于gate启动 gate启动时就能获取到gs
+	err = s.startApp("node", nodeapp.Run, s.nodeReady)
+	if err != nil {
+		return err
+	}
+	err = s.startApp("dispatch", dispatchapp.Run, s.dispatchReady)
+	if err != nil {
+		return err
+	}
+	err = s.startApp("gs", gsapp.Run, s.gsReady)
+	if err != nil {
+		return err
+	}
+	err = s.startApp("multi", multiapp.Run, s.multiReady)
+	if err != nil {
+		return err
+	}
+	err = s.startApp("gate", gateapp.Run, s.gateReady)
+	if err != nil {
+		return err
+	}
+	logger.Warn("test server start, dir: %v, dispatch: %v, gate: %v", s.Dir, s.DispatchUrl, s.GateAddr)
+	return nil
+}
+
+func (s *Server) initDir() error {
+	var err error
+	s.oldWd, err = os.Getwd()
+	if err != nil {
+		return err
+	}
+	s.Dir = s.option.DataDir
+	if s.Dir == "" {
+		s.Dir, err = os.MkdirTemp("", "hk4e_test_")
+		if err != nil {
+			return err
+		}
+		s.removeDir = true
+	}
+	s.Dir, err = filepath.Abs(s.Dir)
+	if err != nil {
+		return err
+	}
+	// 密钥文件使用单进程模式的密钥
+	keyPath := filepath.Join(s.Dir, "key")
+	_, err = os.Lstat(keyPath)
+	if os.IsNotExist(err) {
+		err = os.Symlink(filepath.Join(RepoPath(), "cmd", "standalone", "key"), keyPath)
+	}
+	if err != nil {
+		return err
+	}
+	// 服务器以相对路径读取密钥和数据库文件
+	return os.Chdir(s.Dir)
+}
+
+func (s *Server) initConfig() error {
+	sceneIdList := s.option.SceneIdList
+	if len(sceneIdList) == 0 {
+		sceneIdList = []uint32{3}
+	}
+	gameDataConfigPath := filepath.Join(s.Dir, "game_data_config")
+	_, err := os.Lstat(gameDataConfigPath)
+	if os.IsNotExist(err) {
+		gameDataConfigPath, err = NewGameDataConfig(s.Dir, sceneIdList)
+	}
+	if err != nil {
+		return err
+	}
+	dispatchPort, err := freePort(false)
+	if err != nil {
+		return err
+	}
+	gatePort, err := freePort(true)
+	if err != nil {
+		return err
+	}
+	gateTcpMqPort, err := freePort(false)
+	if err != nil {
+		return err
+	}
+	s.DispatchUrl = "http://127.0.0.1:" + strconv.Itoa(dispatchPort)
+	s.GateAddr = "127.0.0.1:" + strconv.Itoa(gatePort)
+	config.CONF = &config.Config{
+		Hk4e: config.Hk4e{
+			DispatchHttpPort:        int32(dispatchPort),
+			KcpAddr:                 "127.0.0.1",
+			KcpPort:                 int32(gatePort),
+			TcpModeEnable:           s.option.TcpModeEnable,
+			GameDataConfigPath:      gameDataConfigPath,
+			LoadSceneLuaConfig:      s.option.LoadSceneLua,
+			Version:                 "300,310,315,320",
+			GateTcpMqAddr:           "127.0.0.1",
+			GateTcpMqPort:           int32(gateTcpMqPort),
+			LoginSdkUrl:             s.DispatchUrl + "/gate/token/verify",
+			DispatchUrl:             s.DispatchUrl + "/query_cur_region",
+			RegisterAllProtoMessage: true,
+			ByteCheckMode:           -1,
+			StandaloneModeEnable:    true,
+			GateIpExemptList:        "127.0.0.1",
+		},
+		Hk4eRobot: config.Hk4eRobot{
+			RegionListUrl:  s.DispatchUrl + "/query_region_list",
+			CurRegionUrl:   s.DispatchUrl + "/query_cur_region",
+			CurRegionParam: "?version=OSRELWin3.2.0&key_id=" + clientKeyId,
+			KeyId:          clientKeyId,
+			LoginSdkUrl:    s.DispatchUrl,
+			ClientVersion:  clientVersion,
+		},
+		Logger: config.Logger{
+			Level: "info",
+		},
+		Database: config.Database{
+			Url: "sqlite://hk4e_go.db",
+		},
+	}
+	return nil
+}
+
+// freePort 获取一个空闲端口 udp为true时同时检查udp端口
+func freePort(udp bool) (int, error) {
+	for i := 0; i < 10; i++ {
+		listener, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		_ = listener.Close()
+		if !udp {
+			return port, nil
+		}
+		packetConn, err := net.ListenPacket("udp4", "0.0.0.0:"+strconv.Itoa(port))
+		if err != nil {
+			continue
+		}
+		_ = packetConn.Close()
+		return port, nil
+	}
+	return 0, errors.New("no free port")
+}
+
+// startApp 启动服务器并等待就绪
+func (s *Server) startApp(name string, run func(ctx context.Context) error, ready func() bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	inst := &appInst{
+		name:   name,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.appList = append(s.appList, inst)
+	go func() {
+		inst.err = run(ctx)
+		close(inst.done)
+	}()
+	timer := time.NewTimer(s.startTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(readyCheckInterval)
+	defer ticker.Stop()
+	for {
+		if ready() {
+			logger.Info("test server app ready, name: %v", name)
+			return nil
+		}
+		select {
+		case <-inst.done:
+			return fmt.Errorf("%w, name: %v, err: %v", ErrAppExit, name, inst.err)
+		case <-timer.C:
+			return fmt.Errorf("%w, name: %v", ErrStartTimeout, name)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) nodeReady() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := s.discovery.GetRegionEc2B(ctx, &api.NullMsg{})
+	return err == nil
+}
+
+func (s *Server) dispatchReady() bool {
+	conn, err := net.DialTimeout("tcp4", "127.0.0.1:"+strconv.Itoa(int(config.GetConfig().Hk4e.DispatchHttpPort)), time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// gsReady gs的gm服务在游戏核心初始化之后注册 能响应即可处理消息
+func (s *Server) gsReady() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := s.gmClient.Cmd(ctx, &gsapi.CmdRequest{})
+	return err == nil
+}
+
+// GmCmd 调用gs的gm函数 如GMAddItem
+func (s *Server) GmCmd(funcName string, paramList ...string) (*gsapi.CmdReply, error) {
+	return s.gmClient.Cmd(context.Background(), &gsapi.CmdRequest{
+		FuncName:  funcName,
+		ParamList: paramList,
+	})
+}
+
+func (s *Server) multiReady() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := s.discovery.GetServerAppId(ctx, &api.GetServerAppIdReq{ServerType: api.MULTI})
+	return err == nil
+}
+
+// gateReady 网关注册到节点服务器后才开始监听 以实际建立连接为准
+func (s *Server) gateReady() bool {
+	if s.option.TcpModeEnable {
+		conn, err := net.DialTimeout("tcp4", s.GateAddr, time.Second)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+	conn, err := kcp.DialKCP(s.GateAddr)
+	if err != nil {
+		return false
+	}
+	_ = conn.CloseReason(kcp.EnetClientClose)
+	return true
+}
+
+// Stop 按启动的逆序停止全部服务器 删除临时工作目录
+func (s *Server) Stop() {
+	for i := len(s.appList) - 1; i >= 0; i-- {
+		inst := s.appList[i]
+		inst.cancel()
+		select {
+		case <-inst.done:
+		case <-time.After(appStopTimeout):
+			logger.Error("test server app stop timeout, name: %v", inst.name)
+		}
+	}
+	s.appList = nil
+	if s.natsServer != nil {
+		s.natsServer.Shutdown()
+		s.natsServer = nil
+	}
+	if s.oldWd != "" {
+		_ = os.Chdir(s.oldWd)
+	}
+	if s.removeDir {
+		_ = os.RemoveAll(s.Dir)
+	}
+}
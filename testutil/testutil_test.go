@@ -0,0 +1,80 @@
+package testutil
+
+import (
+	"flag"
+	"strconv"
+	"testing"
+	"time"
+
+	"hk4e/protocol/cmd"
+	"hk4e/protocol/proto"
+
+	"github.com/flswld/halo/logger"
+	pb "google.golang.org/protobuf/proto"
+)
+
+var testServer *Server = nil
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	logger.InitLogger(nil)
+	defer logger.CloseLogger()
+	if !testing.Short() {
+		server, err := Start(nil)
+		if err != nil {
+			panic(err)
+		}
+		defer server.Stop()
+		testServer = server
+	}
+	m.Run()
+}
+
+func newTestClient(t *testing.T, account string) *Client {
+	if testServer == nil {
+		t.Skip("skip integration test in short mode")
+	}
+	client, err := testServer.NewClient(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	_, err = client.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPlayerLogin(t *testing.T) {
+	client := newTestClient(t, "testlogin")
+	if client.Uid() == 0 {
+		t.Fatal("uid is zero")
+	}
+	msg, err := client.Request(cmd.PingReq, &proto.PingReq{ClientTime: uint32(time.Now().Unix()), Seq: 100}, cmd.PingRsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.(*proto.PingRsp).Seq != 100 {
+		t.Fatalf("ping rsp seq error: %v", msg)
+	}
+}
+
+func TestGmAddItem(t *testing.T) {
+	client := newTestClient(t, "testgmitem")
+	reply, err := testServer.GmCmd("GMAddItem", strconv.Itoa(int(client.Uid())), "201", "100")
+	if err != nil || reply.Code != 0 {
+		t.Fatalf("gm cmd error: %v, reply: %v", err, reply)
+	}
+	_, err = client.WaitMsgMatch(cmd.StoreItemChangeNotify, func(msg pb.Message) bool {
+		for _, item := range msg.(*proto.StoreItemChangeNotify).ItemList {
+			if item.ItemId == 201 {
+				return true
+			}
+		}
+		return false
+	}, WaitTimeoutDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+}